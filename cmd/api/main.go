@@ -2,14 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/vijaynallagatla/vjvector/internal/api"
 	"github.com/vijaynallagatla/vjvector/internal/server"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding/providers"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
+	"github.com/vijaynallagatla/vjvector/pkg/security"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+	"github.com/vijaynallagatla/vjvector/pkg/webhook"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -20,6 +33,19 @@ func main() {
 	}
 	addr := ":" + port
 
+	// Get gRPC port from environment or use default
+	grpcPort := os.Getenv("VJVECTOR_GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+	grpcAddr := ":" + grpcPort
+
+	// Get data directory from environment or use default
+	dataDir := os.Getenv("VJVECTOR_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "/tmp/vjvector_api"
+	}
+
 	// Create server instance
 	srv, err := server.NewServer()
 	if err != nil {
@@ -27,8 +53,113 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Open the collection catalog, offloading cold segments to the object
+	// store configured by VJVECTOR_S3_*, if any
+	config := catalog.DefaultConfig(dataDir)
+	config.Storage.ObjectStore = storage.ObjectStoreConfigFromEnv()
+
+	// Encrypt the collections and the change log at rest when
+	// VJVECTOR_ENCRYPTION_KEY is set; it wraps the keys kept with the collections
+	encryption, err := openEncryption(dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open encryption keys: %v\n", err)
+		os.Exit(1)
+	}
+	if encryption != nil {
+		config.Encryption = encryption
+		defer func() { _ = encryption.Close() }()
+		srv.Logger().Info("Encryption at rest enabled")
+	}
+
+	collections, err := catalog.New(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open collection catalog: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Report expiry counters and delete expired vectors in the background
+	srv.Metrics().SetExpirySource(func() (uint64, uint64) {
+		stats := collections.ExpiryStats()
+		return stats.Reaped, stats.FilteredFromSearch
+	})
+	reaper, err := catalog.NewReaper(collections, catalog.DefaultReaperConfig(), srv.Logger())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create expiry reaper: %v\n", err)
+		closeCatalog(collections)
+		os.Exit(1)
+	}
+
+	// Verify stored records in the background
+	scrubber, err := catalog.NewScrubber(collections, catalog.DefaultScrubberConfig(), srv.Logger())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create scrubber: %v\n", err)
+		closeCatalog(collections)
+		os.Exit(1)
+	}
+
 	// Create API handlers
-	handlers := api.NewHandlers()
+	handlers := api.NewHandlers(collections)
+	handlers.SetServer(srv)
+
+	// Embed texts with the configured provider; without one, only requests
+	// with embeddings are served
+	provider, err := providers.ProviderFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to configure embedding provider: %v\n", err)
+		closeCatalog(collections)
+		os.Exit(1)
+	}
+	if provider != nil {
+		if err := handlers.SetEmbeddingProvider(provider); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create embedding service: %v\n", err)
+			closeCatalog(collections)
+			os.Exit(1)
+		}
+	}
+
+	// Keep the registered tenants with the collections, so that suspended
+	// tenants stay suspended across restarts
+	if err := handlers.OpenTenants(filepath.Join(dataDir, "tenants.json")); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open tenants: %v\n", err)
+		closeCatalog(collections)
+		os.Exit(1)
+	}
+
+	// Require API keys when an admin key is configured, keeping the issued
+	// keys with the collections, so that they keep working across restarts
+	if adminKey := os.Getenv("VJVECTOR_ADMIN_API_KEY"); adminKey != "" {
+		if err := handlers.OpenAPIKeys(filepath.Join(dataDir, "api_keys.json")); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open API keys: %v\n", err)
+			closeCatalog(collections)
+			os.Exit(1)
+		}
+		handlers.EnableAuth(adminKey)
+		srv.Logger().Info("API key authentication enabled")
+	}
+	if enabled, _ := strconv.ParseBool(os.Getenv("VJVECTOR_RATE_LIMITING")); enabled {
+		handlers.EnableRateLimiting(nil)
+		srv.Logger().Info("Rate limiting enabled")
+	}
+
+	// Keep the records of jobs with the collections, so that they can be
+	// polled across restarts
+	if err := handlers.OpenJobs(jobs.DefaultConfig(filepath.Join(dataDir, "jobs"))); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open job records: %v\n", err)
+		closeCatalog(collections)
+		os.Exit(1)
+	}
+
+	// Keep webhook dead letters with the collections, to be redelivered after
+	// a restart; the webhooks of the default tenant come from the environment
+	defaultWebhooks, err := envWebhooks()
+	if err == nil {
+		err = handlers.OpenWebhooks(webhook.DefaultConfig(filepath.Join(dataDir, "webhooks")), defaultWebhooks)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open webhooks: %v\n", err)
+		closeCatalog(collections)
+		os.Exit(1)
+	}
 
 	// Register API routes
 	handlers.RegisterRoutes(srv.Echo())
@@ -36,8 +167,35 @@ func main() {
 	// Start the server
 	if err := srv.Start(addr); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to start server: %v\n", err)
+		closeCatalog(collections)
+		os.Exit(1)
+	}
+
+	// Serve the gRPC API on its own port, sharing the handlers of the REST API
+	grpcListener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen for gRPC: %v\n", err)
+		closeCatalog(collections)
 		os.Exit(1)
 	}
+	grpcServer := handlers.NewGRPCServer()
+	go func() {
+		srv.Logger().Info("Starting gRPC Server", "address", grpcAddr)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			srv.Logger().Error("gRPC server error", "error", err)
+		}
+	}()
+
+	reaper.Start()
+	scrubber.Start()
+
+	// Build the collection indexes from storage; the server reports ready once done
+	rebuildCtx, cancelRebuild := context.WithCancel(context.Background())
+	rebuilt := make(chan struct{})
+	go func() {
+		defer close(rebuilt)
+		rebuildIndexes(rebuildCtx, collections, handlers, srv.Logger())
+	}()
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -51,4 +209,93 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Server shutdown error: %v\n", err)
 	}
+	stopGRPC(ctx, grpcServer)
+
+	cancelRebuild()
+	<-rebuilt
+
+	reaper.Stop()
+	scrubber.Stop()
+	if err := handlers.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to close handlers: %v\n", err)
+	}
+	closeCatalog(collections)
+}
+
+// envWebhooks returns the webhooks of the default tenant: the comma-separated
+// URLs of VJVECTOR_WEBHOOK_URLS, signed with VJVECTOR_WEBHOOK_SECRET
+func envWebhooks() ([]webhook.Target, error) {
+	var targets []webhook.Target
+	secret := os.Getenv("VJVECTOR_WEBHOOK_SECRET")
+	for _, url := range strings.Split(os.Getenv("VJVECTOR_WEBHOOK_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			targets = append(targets, webhook.Target{URL: url, Secret: secret})
+		}
+	}
+	if len(targets) > 0 && secret == "" {
+		return nil, fmt.Errorf("VJVECTOR_WEBHOOK_URLS needs VJVECTOR_WEBHOOK_SECRET to sign events")
+	}
+	return targets, nil
+}
+
+// rebuildIndexes bulk-builds the index of every collection from the vectors in
+// its storage, logging progress, and reports the outcome to the readiness check
+func rebuildIndexes(ctx context.Context, collections *catalog.Catalog, handlers *api.Handlers, logger *slog.Logger) {
+	started := time.Now()
+	logger.Info("Rebuilding collection indexes")
+
+	err := collections.RebuildIndexes(ctx, catalog.RebuildOptions{
+		Progress: func(progress catalog.RebuildProgress) {
+			handlers.RebuildProgress(progress)
+			if progress.Done {
+				logger.Info("Index rebuilt", "collection", progress.Collection, "vectors", progress.Indexed)
+				return
+			}
+			logger.Info("Rebuilding index", "collection", progress.Collection,
+				"indexed", progress.Indexed, "total", progress.Total)
+		},
+	})
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if err != nil {
+		logger.Error("Index rebuild failed", "error", err)
+	} else {
+		logger.Info("Collection indexes rebuilt", "duration", time.Since(started))
+	}
+	handlers.RebuildFinished(err)
+}
+
+// stopGRPC stops the gRPC server once its calls finish, or cancels them when
+// the context is done first
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+		<-stopped
+	}
+}
+
+// openEncryption opens the encryption keys of the data directory when
+// VJVECTOR_ENCRYPTION_KEY holds the master key, or returns nil
+func openEncryption(dataDir string) (*security.DefaultEncryptionService, error) {
+	config, err := security.EncryptionConfigFromEnv(filepath.Join(dataDir, "encryption_keys.json"))
+	if err != nil || config == nil {
+		return nil, err
+	}
+	return security.OpenEncryptionService(config)
+}
+
+// closeCatalog flushes and closes the collection catalog
+func closeCatalog(collections *catalog.Catalog) {
+	if err := collections.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to close collection catalog: %v\n", err)
+	}
 }
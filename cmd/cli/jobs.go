@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
+)
+
+// jobPollInterval is the time between two polls of a job being waited for
+const jobPollInterval = 500 * time.Millisecond
+
+// jobsClient calls the job endpoints of a VJVector server, since jobs run in
+// the server rather than against the local data directory
+type jobsClient struct {
+	server string
+	apiKey string
+	tenant string
+	http   *http.Client
+}
+
+// envOr returns the value of an environment variable, or fallback when it is unset
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newJobsClient creates a client for the server named by the flags of the
+// jobs command
+func newJobsClient(cmd *cobra.Command) *jobsClient {
+	server, _ := cmd.Flags().GetString("server")
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	tenant, _ := cmd.Flags().GetString("tenant")
+	return &jobsClient{
+		server: strings.TrimSuffix(server, "/"),
+		apiKey: apiKey,
+		tenant: tenant,
+		http:   &http.Client{Timeout: time.Minute},
+	}
+}
+
+// do sends a request to the server and decodes its response into out,
+// returning the error message of the server for failed requests
+func (c *jobsClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		request.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		request.Header.Set("X-Tenant-ID", c.tenant)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %v", c.server, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&failure); err != nil || failure.Error == "" {
+			return fmt.Errorf("server returned %s", response.Status)
+		}
+		return fmt.Errorf("server returned %d: %s", response.StatusCode, failure.Error)
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// jobParams builds the parameters of a job of a type from the flags of the
+// submit command
+func jobParams(cmd *cobra.Command, jobType string) (interface{}, error) {
+	collection, _ := cmd.Flags().GetString("collection")
+	file, _ := cmd.Flags().GetString("file")
+
+	switch jobType {
+	case "embed":
+		texts, _ := cmd.Flags().GetStringArray("text")
+		dimension, _ := cmd.Flags().GetInt("dimension")
+		if file != "" {
+			lines, err := readLines(file)
+			if err != nil {
+				return nil, err
+			}
+			texts = append(texts, lines...)
+		}
+		return models.EmbedJobParams{Texts: texts, Dimension: dimension}, nil
+	case "reindex":
+		return models.ReindexJobParams{Collection: collection}, nil
+	case "compact":
+		return models.CompactJobParams{Collection: collection}, nil
+	case "import":
+		if file == "" {
+			return nil, fmt.Errorf("an import job needs a --file of jsonl vector records")
+		}
+		chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+		embed, _ := cmd.Flags().GetBool("embed")
+		lines, err := readLines(file)
+		if err != nil {
+			return nil, err
+		}
+		records := make([]models.IngestRecord, len(lines))
+		for i, line := range lines {
+			if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+				return nil, fmt.Errorf("line %d of %s is not a vector record: %v", i+1, file, err)
+			}
+		}
+		return models.ImportJobParams{Collection: collection, Records: records, ChunkSize: chunkSize, Embed: embed}, nil
+	default:
+		return nil, fmt.Errorf("unknown job type %q: use embed, reindex, compact or import", jobType)
+	}
+}
+
+// readLines returns the lines of a file that are not blank
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	return lines, nil
+}
+
+// jobsSubmitCmd submits a job to the server, and waits for it with --wait
+func (cli *CLI) jobsSubmitCmd(cmd *cobra.Command, args []string) error {
+	jobType := args[0]
+	wait, _ := cmd.Flags().GetBool("wait")
+
+	params, err := jobParams(cmd, jobType)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	client := newJobsClient(cmd)
+	var job jobs.Job
+	if err := client.do(cmd.Context(), http.MethodPost, "/v1/jobs", models.SubmitJobRequest{Type: jobType, Params: data}, &job); err != nil {
+		return fmt.Errorf("failed to submit job: %v", err)
+	}
+	fmt.Printf("✅ Submitted %s job %s\n", job.Type, job.ID)
+	if !wait {
+		return nil
+	}
+	return cli.waitJob(cmd, client, job.ID)
+}
+
+// waitJob polls a job until it finished, reporting its progress on stderr
+func (cli *CLI) waitJob(cmd *cobra.Command, client *jobsClient, id string) error {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	reported := false
+	for {
+		var job jobs.Job
+		if err := client.do(cmd.Context(), http.MethodGet, "/v1/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+			return fmt.Errorf("failed to get job: %v", err)
+		}
+		if job.State.Finished() {
+			if reported {
+				fmt.Fprintln(os.Stderr)
+			}
+			printJob(&job)
+			if job.State != jobs.StateSucceeded {
+				return fmt.Errorf("job %s %s", job.ID, job.State)
+			}
+			return nil
+		}
+		if job.Progress.Total > 0 {
+			fmt.Fprintf(os.Stderr, "\r⏳ %s %d/%d (%.1f%%)", job.State, job.Progress.Processed, job.Progress.Total,
+				float64(job.Progress.Processed)*100/float64(job.Progress.Total))
+			reported = true
+		}
+
+		select {
+		case <-cmd.Context().Done():
+			return cmd.Context().Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobsListCmd lists the jobs of the tenant, newest first
+func (cli *CLI) jobsListCmd(cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	var response struct {
+		Jobs []*jobs.Job `json:"jobs"`
+	}
+	path := fmt.Sprintf("/v1/jobs?limit=%d", limit)
+	if err := newJobsClient(cmd).do(cmd.Context(), http.MethodGet, path, nil, &response); err != nil {
+		return fmt.Errorf("failed to list jobs: %v", err)
+	}
+
+	if len(response.Jobs) == 0 {
+		fmt.Println("📭 No jobs found")
+		return nil
+	}
+	fmt.Printf("📋 Found %d jobs:\n\n", len(response.Jobs))
+	for _, job := range response.Jobs {
+		fmt.Printf("🧾 %s  %-8s %-10s %d/%d  %s\n", job.ID, job.Type, job.State,
+			job.Progress.Processed, job.Progress.Total, job.CreatedAt.Local().Format(time.DateTime))
+	}
+	return nil
+}
+
+// jobsGetCmd shows a job with its progress and result
+func (cli *CLI) jobsGetCmd(cmd *cobra.Command, args []string) error {
+	var job jobs.Job
+	if err := newJobsClient(cmd).do(cmd.Context(), http.MethodGet, "/v1/jobs/"+url.PathEscape(args[0]), nil, &job); err != nil {
+		return fmt.Errorf("failed to get job: %v", err)
+	}
+	printJob(&job)
+	return nil
+}
+
+// jobsCancelCmd asks a job to stop
+func (cli *CLI) jobsCancelCmd(cmd *cobra.Command, args []string) error {
+	var job jobs.Job
+	if err := newJobsClient(cmd).do(cmd.Context(), http.MethodDelete, "/v1/jobs/"+url.PathEscape(args[0]), nil, &job); err != nil {
+		return fmt.Errorf("failed to cancel job: %v", err)
+	}
+	fmt.Printf("🛑 Cancellation of job %s requested; it is %s\n", job.ID, job.State)
+	return nil
+}
+
+// printJob prints the record of a job
+func printJob(job *jobs.Job) {
+	fmt.Printf("🧾 Job: %s\n", job.ID)
+	fmt.Printf("   🏷️  Type: %s\n", job.Type)
+	fmt.Printf("   🚦 State: %s\n", job.State)
+	fmt.Printf("   📊 Progress: %d/%d", job.Progress.Processed, job.Progress.Total)
+	if job.Progress.Elapsed != "" {
+		fmt.Printf(" in %s", job.Progress.Elapsed)
+	}
+	fmt.Println()
+	fmt.Printf("   🕒 Created: %s\n", job.CreatedAt.Local().Format(time.DateTime))
+	if job.Error != "" {
+		fmt.Printf("   ❌ Error: %s\n", job.Error)
+	}
+	if len(job.Result) > 0 {
+		var result bytes.Buffer
+		if err := json.Indent(&result, job.Result, "   ", "  "); err == nil {
+			fmt.Printf("   📦 Result: %s\n", result.String())
+		}
+	}
+}
@@ -1,49 +1,73 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vijaynallagatla/vjvector/pkg/backup"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
 	"github.com/vijaynallagatla/vjvector/pkg/core"
 	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/security"
 	"github.com/vijaynallagatla/vjvector/pkg/storage"
+	"github.com/vijaynallagatla/vjvector/pkg/vecio"
 )
 
 // CLI represents the VJVector command-line interface
 type CLI struct {
-	indexes map[string]index.VectorIndex
-	storage storage.StorageEngine
+	catalog     *catalog.Catalog
+	encryption  *security.DefaultEncryptionService
+	dataDir     string
+	storageType string
 }
 
 // NewCLI creates a new CLI instance
 func NewCLI() *CLI {
-	cli := &CLI{
-		indexes: make(map[string]index.VectorIndex),
-	}
+	return &CLI{}
+}
 
-	// Initialize storage
-	storageConfig := storage.StorageConfig{
-		Type:            storage.StorageTypeMemory,
-		DataPath:        "/tmp/vjvector_cli",
-		PageSize:        4096,
-		MaxFileSize:     1024 * 1024 * 1024, // 1GB
-		BatchSize:       100,
-		WriteBufferSize: 64 * 1024 * 1024, // 64MB
-		CacheSize:       32 * 1024 * 1024, // 32MB
-		MaxOpenFiles:    1000,
+// openCatalog opens the collection catalog in the configured data directory
+func (cli *CLI) openCatalog(cmd *cobra.Command, args []string) error {
+	config := catalog.DefaultConfig(cli.dataDir)
+	config.Storage.Type = storage.StorageType(cli.storageType)
+	config.Storage.ObjectStore = storage.ObjectStoreConfigFromEnv()
+
+	// A data directory encrypted by the server needs its master key
+	encryptionConfig, err := security.EncryptionConfigFromEnv(filepath.Join(cli.dataDir, "encryption_keys.json"))
+	if err != nil {
+		return err
+	}
+	if encryptionConfig != nil {
+		if cli.encryption, err = security.OpenEncryptionService(encryptionConfig); err != nil {
+			return fmt.Errorf("failed to open encryption keys: %v", err)
+		}
+		config.Encryption = cli.encryption
 	}
 
-	factory := &storage.DefaultStorageFactory{}
-	storageEngine, err := factory.CreateStorage(storageConfig)
+	collections, err := catalog.New(config)
 	if err != nil {
-		log.Fatalf("Failed to create storage: %v", err)
+		return fmt.Errorf("failed to open catalog: %v", err)
 	}
-	cli.storage = storageEngine
+	cli.catalog = collections
+
+	return nil
+}
 
-	return cli
+// closeCatalog persists and closes the collection catalog
+func (cli *CLI) closeCatalog(cmd *cobra.Command, args []string) error {
+	if cli.encryption != nil {
+		defer func() { _ = cli.encryption.Close() }()
+	}
+	if cli.catalog == nil {
+		return nil
+	}
+	return cli.catalog.Close()
 }
 
 // createIndexCmd creates a new vector index
@@ -88,14 +112,11 @@ func (cli *CLI) createIndexCmd(cmd *cobra.Command, args []string) error {
 		Normalize:      normalize,
 	}
 
-	factory := index.NewIndexFactory()
-	idx, err := factory.CreateIndex(config)
-	if err != nil {
+	collection := core.NewCollection(id, "", dimension, indexType)
+	if err := cli.catalog.CreateWithIndex(collection, config); err != nil {
 		return fmt.Errorf("failed to create index: %v", err)
 	}
 
-	cli.indexes[id] = idx
-
 	fmt.Printf("✅ Index '%s' created successfully\n", id)
 	fmt.Printf("   Type: %s\n", indexType)
 	fmt.Printf("   Dimension: %d\n", dimension)
@@ -106,16 +127,26 @@ func (cli *CLI) createIndexCmd(cmd *cobra.Command, args []string) error {
 
 // listIndexesCmd lists all available indexes
 func (cli *CLI) listIndexesCmd(cmd *cobra.Command, args []string) error {
-	if len(cli.indexes) == 0 {
+	collections, err := cli.catalog.List()
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %v", err)
+	}
+
+	if len(collections) == 0 {
 		fmt.Println("📭 No indexes found")
 		return nil
 	}
 
-	fmt.Printf("📚 Found %d indexes:\n\n", len(cli.indexes))
-	for id, idx := range cli.indexes {
+	fmt.Printf("📚 Found %d indexes:\n\n", len(collections))
+	for _, collection := range collections {
+		idx, err := cli.catalog.Index(collection.Name)
+		if err != nil {
+			return err
+		}
 		stats := idx.GetStats()
-		fmt.Printf("🔍 Index: %s\n", id)
-		fmt.Printf("   📊 Total Vectors: %d\n", stats.TotalVectors)
+		fmt.Printf("🔍 Index: %s\n", collection.Name)
+		fmt.Printf("   🏷️  Type: %s (%s, dimension %d)\n", collection.IndexType, collection.DistanceMetric, collection.Dimension)
+		fmt.Printf("   📊 Total Vectors: %d\n", collection.Count)
 		fmt.Printf("   💾 Memory Usage: %d bytes\n", stats.MemoryUsage)
 		fmt.Printf("   📏 Index Size: %d bytes\n", stats.IndexSize)
 		fmt.Printf("   ⏱️  Avg Search Time: %.2f ms\n", stats.AvgSearchTime)
@@ -126,60 +157,139 @@ func (cli *CLI) listIndexesCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// insertVectorsCmd inserts vectors into an index
+// insertVectorsCmd imports vectors from a file into an index
 func (cli *CLI) insertVectorsCmd(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("index ID is required")
+	id, path := args[0], args[1]
+	format, _ := cmd.Flags().GetString("format")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	idPrefix, _ := cmd.Flags().GetString("id-prefix")
+	limit, _ := cmd.Flags().GetInt64("limit")
+	ttl, _ := cmd.Flags().GetDuration("ttl")
+
+	if _, err := cli.catalog.Get(id); err != nil {
+		return fmt.Errorf("index '%s' not found", id)
+	}
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive")
+	}
+	if ttl < 0 {
+		return fmt.Errorf("ttl must not be negative")
 	}
 
-	id := args[0]
-	idx, exists := cli.indexes[id]
-	if !exists {
-		return fmt.Errorf("index '%s' not found", id)
+	reader, err := vecio.Open(path, vecio.Format(format), vecio.ReaderOptions{IDPrefix: idPrefix})
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
 	}
+	defer func() {
+		_ = reader.Close()
+	}()
 
-	// For CLI demo, create some sample vectors
-	count, _ := cmd.Flags().GetInt("count")
-	dimension, _ := cmd.Flags().GetInt("dimension")
+	start := time.Now()
+	progress := newProgress("Inserted", 0)
+	inserted := int64(0)
+	batch := make([]*core.Vector, 0, batchSize)
 
-	if count <= 0 {
-		count = 10
-	}
-	if dimension <= 0 {
-		dimension = 128
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := cli.catalog.Insert(cmd.Context(), id, batch); err != nil {
+			progress.done(inserted)
+			return fmt.Errorf("failed to insert vectors after %d: %v", inserted, err)
+		}
+		inserted += int64(len(batch))
+		progress.update(inserted)
+		batch = make([]*core.Vector, 0, batchSize)
+		return nil
 	}
 
-	vectors := make([]*core.Vector, count)
-	for i := 0; i < count; i++ {
-		embedding := make([]float64, dimension)
-		for j := 0; j < dimension; j++ {
-			embedding[j] = float64(i*j) * 0.001
+	for limit <= 0 || inserted+int64(len(batch)) < limit {
+		vector, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
 		}
-		vectors[i] = &core.Vector{
-			ID:         fmt.Sprintf("vector_%d", i),
-			Collection: "cli_demo",
-			Embedding:  embedding,
-			Metadata:   map[string]interface{}{"source": "cli", "index": i},
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
 		}
-	}
 
-	start := time.Now()
-	for _, vector := range vectors {
-		if err := idx.Insert(vector); err != nil {
-			return fmt.Errorf("failed to insert vector %s: %v", vector.ID, err)
+		if ttl > 0 {
+			vector.SetTTL(ttl)
+		}
+		batch = append(batch, vector)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
 		}
 	}
+	if err := flush(); err != nil {
+		return err
+	}
+	progress.done(inserted)
 	duration := time.Since(start)
 
+	idx, err := cli.catalog.Index(id)
+	if err != nil {
+		return err
+	}
 	stats := idx.GetStats()
-	fmt.Printf("✅ Inserted %d vectors into index '%s'\n", count, id)
+	collection, err := cli.catalog.Get(id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Inserted %d vectors from %s into index '%s'\n", inserted, path, id)
 	fmt.Printf("   ⏱️  Time: %s\n", duration)
-	fmt.Printf("   📊 Total Vectors: %d\n", stats.TotalVectors)
+	fmt.Printf("   📊 Total Vectors: %d\n", collection.Count)
 	fmt.Printf("   💾 Memory Usage: %d bytes\n", stats.MemoryUsage)
 
 	return nil
 }
 
+// exportVectorsCmd streams every vector of an index into a file
+func (cli *CLI) exportVectorsCmd(cmd *cobra.Command, args []string) error {
+	id, path := args[0], args[1]
+	format, _ := cmd.Flags().GetString("format")
+	float64Values, _ := cmd.Flags().GetBool("float64")
+
+	collection, err := cli.catalog.Get(id)
+	if err != nil {
+		return fmt.Errorf("index '%s' not found", id)
+	}
+
+	writer, err := vecio.Create(path, vecio.Format(format), vecio.WriterOptions{Float64: float64Values})
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", path, err)
+	}
+
+	start := time.Now()
+	progress := newProgress("Exported", collection.Count)
+	exported := int64(0)
+
+	err = cli.catalog.Snapshot(cmd.Context(), func(snapshot *catalog.Snapshot) error {
+		return snapshot.Scan(id, func(vector *core.Vector) error {
+			if err := writer.Write(vector); err != nil {
+				return err
+			}
+			exported++
+			progress.update(exported)
+			return nil
+		})
+	})
+	if closeErr := writer.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to export index '%s': %v", id, err)
+	}
+	progress.done(exported)
+
+	fmt.Printf("✅ Exported %d vectors from index '%s' to %s\n", exported, id, path)
+	fmt.Printf("   ⏱️  Time: %s\n", time.Since(start))
+
+	return nil
+}
+
 // searchVectorsCmd searches for similar vectors
 func (cli *CLI) searchVectorsCmd(cmd *cobra.Command, args []string) error {
 	if len(args) < 1 {
@@ -187,8 +297,8 @@ func (cli *CLI) searchVectorsCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	id := args[0]
-	idx, exists := cli.indexes[id]
-	if !exists {
+	collection, err := cli.catalog.Get(id)
+	if err != nil {
 		return fmt.Errorf("index '%s' not found", id)
 	}
 
@@ -197,11 +307,15 @@ func (cli *CLI) searchVectorsCmd(cmd *cobra.Command, args []string) error {
 		k = 5
 	}
 
-	dimension, _ := cmd.Flags().GetInt("dimension")
-	if dimension <= 0 {
-		dimension = 128
+	var asOf time.Time
+	if value, _ := cmd.Flags().GetString("as-of"); value != "" {
+		if asOf, err = time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("invalid --as-of time %q: %v", value, err)
+		}
 	}
 
+	dimension := collection.Dimension
+
 	// Create a sample query vector
 	query := make([]float64, dimension)
 	for i := 0; i < dimension; i++ {
@@ -211,8 +325,21 @@ func (cli *CLI) searchVectorsCmd(cmd *cobra.Command, args []string) error {
 	fmt.Printf("🔍 Searching index '%s' for %d similar vectors...\n", id, k)
 	fmt.Printf("   Query dimension: %d\n", dimension)
 
+	// The index starts empty when the catalog is opened
+	if asOf.IsZero() {
+		if err := cli.catalog.RebuildIndex(context.Background(), id, catalog.RebuildOptions{}); err != nil {
+			return fmt.Errorf("failed to build index: %v", err)
+		}
+	}
+
 	start := time.Now()
-	results, err := idx.Search(query, k)
+	var results []core.VectorSearchResult
+	if asOf.IsZero() {
+		results, err = cli.catalog.Search(context.Background(), id, query, k)
+	} else {
+		fmt.Printf("   As of: %s\n", asOf.Format(time.RFC3339))
+		results, err = cli.catalog.SearchAsOf(context.Background(), id, query, k, asOf)
+	}
 	if err != nil {
 		return fmt.Errorf("search failed: %v", err)
 	}
@@ -238,15 +365,19 @@ func (cli *CLI) getIndexStatsCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	id := args[0]
-	idx, exists := cli.indexes[id]
-	if !exists {
+	collection, err := cli.catalog.Get(id)
+	if err != nil {
 		return fmt.Errorf("index '%s' not found", id)
 	}
+	idx, err := cli.catalog.Index(id)
+	if err != nil {
+		return err
+	}
 
 	stats := idx.GetStats()
 	fmt.Printf("📊 Index Statistics: %s\n", id)
 	fmt.Printf("=====================================\n")
-	fmt.Printf("📈 Total Vectors: %d\n", stats.TotalVectors)
+	fmt.Printf("📈 Total Vectors: %d\n", collection.Count)
 	fmt.Printf("💾 Memory Usage: %d bytes (%.2f MB)\n", stats.MemoryUsage, float64(stats.MemoryUsage)/1024/1024)
 	fmt.Printf("📏 Index Size: %d bytes (%.2f MB)\n", stats.IndexSize, float64(stats.IndexSize)/1024/1024)
 	fmt.Printf("⏱️  Average Search Time: %.2f ms\n", stats.AvgSearchTime)
@@ -268,9 +399,51 @@ func (cli *CLI) getIndexStatsCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// getStorageStatsCmd shows storage statistics
+// getStorageStatsCmd shows storage statistics for one collection or all of them
 func (cli *CLI) getStorageStatsCmd(cmd *cobra.Command, args []string) error {
-	stats := cli.storage.GetStats()
+	names := args
+	if len(names) == 0 {
+		collections, err := cli.catalog.List()
+		if err != nil {
+			return fmt.Errorf("failed to list indexes: %v", err)
+		}
+		for _, collection := range collections {
+			names = append(names, collection.Name)
+		}
+	}
+
+	var stats storage.StorageStats
+	var tiers []*storage.TierStats
+	tierIndex := make(map[string]int)
+	for _, name := range names {
+		engine, err := cli.catalog.Storage(name)
+		if err != nil {
+			return fmt.Errorf("index '%s' not found", name)
+		}
+		collectionStats := engine.GetStats()
+		stats.TotalVectors += collectionStats.TotalVectors
+		stats.StorageSize += collectionStats.StorageSize
+		stats.MemoryUsage += collectionStats.MemoryUsage
+		stats.AvgWriteTime += collectionStats.AvgWriteTime / float64(len(names))
+		stats.AvgReadTime += collectionStats.AvgReadTime / float64(len(names))
+		stats.FileCount += collectionStats.FileCount
+		stats.PageSize = collectionStats.PageSize
+
+		for _, tier := range collectionStats.Tiers {
+			i, exists := tierIndex[tier.Tier]
+			if !exists {
+				i = len(tiers)
+				tierIndex[tier.Tier] = i
+				tiers = append(tiers, &storage.TierStats{Tier: tier.Tier})
+			}
+			total := tiers[i]
+			total.TotalVectors += tier.TotalVectors
+			total.MemoryUsage += tier.MemoryUsage
+			total.Lookups += tier.Lookups
+			total.Hits += tier.Hits
+		}
+	}
+
 	fmt.Printf("💾 Storage Statistics\n")
 	fmt.Printf("=====================\n")
 	fmt.Printf("📈 Total Vectors: %d\n", stats.TotalVectors)
@@ -281,9 +454,308 @@ func (cli *CLI) getStorageStatsCmd(cmd *cobra.Command, args []string) error {
 	fmt.Printf("📁 File Count: %d\n", stats.FileCount)
 	fmt.Printf("📄 Page Size: %d bytes\n", stats.PageSize)
 
+	for _, total := range tiers {
+		hitRate := 0.0
+		if total.Lookups > 0 {
+			hitRate = float64(total.Hits) / float64(total.Lookups) * 100
+		}
+		fmt.Printf("🗂️  %s tier: %d vectors, %d bytes, %.1f%% hit rate (%d/%d lookups)\n",
+			total.Tier, total.TotalVectors, total.MemoryUsage, hitRate, total.Hits, total.Lookups)
+	}
+
 	return nil
 }
 
+// objectStore opens the object store configured by the VJVECTOR_S3_* environment variables
+func (cli *CLI) objectStore() (storage.ObjectStore, error) {
+	config := storage.ObjectStoreConfigFromEnv()
+	if config == nil {
+		return nil, fmt.Errorf("no object store configured: set VJVECTOR_S3_ENDPOINT and VJVECTOR_S3_BUCKET")
+	}
+	store, err := storage.OpenObjectStore(*config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object store: %v", err)
+	}
+	return store, nil
+}
+
+// backupCmd writes a full or incremental backup of the data directory to a
+// file, or to the object store with --object-store
+func (cli *CLI) backupCmd(cmd *cobra.Command, args []string) error {
+	output := args[0]
+	incremental, _ := cmd.Flags().GetBool("incremental")
+	since, _ := cmd.Flags().GetUint64("since")
+	toObjectStore, _ := cmd.Flags().GetBool("object-store")
+
+	if toObjectStore {
+		store, err := cli.objectStore()
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		manifest, err := backup.Upload(cmd.Context(), cli.catalog, store, output, backup.Options{Incremental: incremental, Since: since})
+		if err != nil {
+			return fmt.Errorf("backup failed: %v", err)
+		}
+		fmt.Printf("✅ %s backup uploaded to object %s in %s\n", manifest.Kind, output, time.Since(start))
+		printBackupManifest(manifest)
+		return nil
+	}
+
+	// Write next to the destination and rename so a failed backup never leaves a partial archive
+	tmpPath := output + ".tmp"
+	file, err := os.OpenFile(filepath.Clean(tmpPath), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %v", err)
+	}
+
+	start := time.Now()
+	manifest, err := backup.Create(cmd.Context(), cli.catalog, file, backup.Options{Incremental: incremental, Since: since})
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("backup failed: %v", err)
+	}
+	if err := os.Rename(tmpPath, output); err != nil {
+		return fmt.Errorf("failed to write backup file: %v", err)
+	}
+
+	fmt.Printf("✅ %s backup written to %s in %s\n", manifest.Kind, output, time.Since(start))
+	printBackupManifest(manifest)
+
+	return nil
+}
+
+// printBackupManifest prints the log positions and collections of a backup
+func printBackupManifest(manifest *backup.Manifest) {
+	if manifest.Kind == backup.KindIncremental {
+		fmt.Printf("   📜 Log Positions: %d → %d\n", manifest.BasePosition, manifest.Position)
+	} else {
+		fmt.Printf("   📜 Log Position: %d\n", manifest.Position)
+		for _, collection := range manifest.Collections {
+			fmt.Printf("   📚 %s: %d vectors\n", collection.Name, collection.Vectors)
+		}
+	}
+	fmt.Printf("   ➡️  Next incremental backup: --incremental --since %d\n", manifest.Position)
+}
+
+// restoreCmd restores a full backup followed by any incremental backups
+func (cli *CLI) restoreCmd(cmd *cobra.Command, args []string) error {
+	verifyOnly, _ := cmd.Flags().GetBool("verify-only")
+	fromObjectStore, _ := cmd.Flags().GetBool("object-store")
+
+	if fromObjectStore {
+		return cli.restoreObjects(cmd, args, verifyOnly)
+	}
+	if verifyOnly {
+		for _, name := range args {
+			file, err := os.Open(filepath.Clean(name))
+			if err != nil {
+				return fmt.Errorf("failed to open backup: %v", err)
+			}
+			manifest, err := backup.Verify(file)
+			_ = file.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			fmt.Printf("✅ %s: valid %s backup, log positions %d → %d, %d files\n",
+				name, manifest.Kind, manifest.BasePosition, manifest.Position, len(manifest.Files))
+		}
+		return nil
+	}
+
+	files := make([]*os.File, 0, len(args))
+	archives := make([]io.Reader, 0, len(args))
+	closeFiles := func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}
+	for _, name := range args {
+		file, err := os.Open(filepath.Clean(name))
+		if err != nil {
+			closeFiles()
+			return fmt.Errorf("failed to open backup: %v", err)
+		}
+		files = append(files, file)
+		archives = append(archives, file)
+	}
+
+	start := time.Now()
+	manifests, err := backup.Restore(cmd.Context(), cli.catalog, archives...)
+	closeFiles()
+	if err != nil {
+		return fmt.Errorf("restore failed: %v", err)
+	}
+
+	last := manifests[len(manifests)-1]
+	fmt.Printf("✅ Restored %d backup(s) into %s in %s\n", len(manifests), cli.dataDir, time.Since(start))
+	fmt.Printf("   📜 Source Log Position: %d\n", last.Position)
+
+	return nil
+}
+
+// restoreObjects verifies or restores backups stored in the object store
+func (cli *CLI) restoreObjects(cmd *cobra.Command, keys []string, verifyOnly bool) error {
+	store, err := cli.objectStore()
+	if err != nil {
+		return err
+	}
+
+	if verifyOnly {
+		for _, key := range keys {
+			archive, err := store.Get(cmd.Context(), key)
+			if err != nil {
+				return fmt.Errorf("failed to open backup: %v", err)
+			}
+			manifest, err := backup.Verify(archive)
+			_ = archive.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			fmt.Printf("✅ %s: valid %s backup, log positions %d → %d, %d files\n",
+				key, manifest.Kind, manifest.BasePosition, manifest.Position, len(manifest.Files))
+		}
+		return nil
+	}
+
+	start := time.Now()
+	manifests, err := backup.RestoreObjects(cmd.Context(), cli.catalog, store, keys...)
+	if err != nil {
+		return fmt.Errorf("restore failed: %v", err)
+	}
+
+	last := manifests[len(manifests)-1]
+	fmt.Printf("✅ Restored %d backup(s) into %s in %s\n", len(manifests), cli.dataDir, time.Since(start))
+	fmt.Printf("   📜 Source Log Position: %d\n", last.Position)
+
+	return nil
+}
+
+// fsckCmd verifies the stored records of one or all collections and can
+// quarantine corrupt records or repair them from a backup or a replica
+func (cli *CLI) fsckCmd(cmd *cobra.Command, args []string) error {
+	quarantine, _ := cmd.Flags().GetBool("quarantine")
+	repairFrom, _ := cmd.Flags().GetString("repair-from")
+	ctx := cmd.Context()
+
+	names := args
+	if len(names) == 0 {
+		collections, err := cli.catalog.List()
+		if err != nil {
+			return fmt.Errorf("failed to list collections: %v", err)
+		}
+		for _, collection := range collections {
+			names = append(names, collection.Name)
+		}
+	}
+
+	var source *catalog.Catalog
+	if repairFrom != "" {
+		opened, cleanup, err := cli.openRepairSource(ctx, repairFrom)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		source = opened
+	}
+
+	unresolved := 0
+	for _, name := range names {
+		opts := storage.ScrubOptions{Quarantine: quarantine}
+		if source != nil {
+			if engine, err := source.Storage(name); err == nil {
+				opts.Repair = engine
+			} else {
+				fmt.Printf("⚠️  %s: no copy in %s, corrupt records will only be quarantined\n", name, repairFrom)
+			}
+		}
+
+		start := time.Now()
+		report, err := cli.catalog.Scrub(ctx, name, opts)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %v", name, err)
+		}
+
+		fmt.Printf("🔍 %s: %d records checked in %s, %d corrupt, %d quarantined, %d repaired\n",
+			name, report.Checked, time.Since(start), len(report.Corrupt), report.Quarantined, report.Repaired)
+		for _, record := range report.Corrupt {
+			id := record.ID
+			if id == "" {
+				id = "unknown vector"
+			}
+			if record.Version {
+				id += " (previous version)"
+			}
+			fmt.Printf("   ❌ %s at %s: %s [%s]\n", id, record.Location, record.Reason, record.Action)
+			if record.Action == storage.ScrubActionReported {
+				unresolved++
+			}
+		}
+	}
+
+	if unresolved > 0 {
+		return fmt.Errorf("%d corrupt records found; run again with --quarantine or --repair-from", unresolved)
+	}
+	return nil
+}
+
+// openRepairSource opens the catalog holding good copies of corrupt vectors:
+// the data directory of a replica, or a full backup restored into a temporary
+// in-memory catalog
+func (cli *CLI) openRepairSource(ctx context.Context, path string) (*catalog.Catalog, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open repair source: %v", err)
+	}
+
+	if info.IsDir() {
+		config := catalog.DefaultConfig(path)
+		config.Storage.Type = storage.StorageType(cli.storageType)
+		replica, err := catalog.New(config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open replica: %v", err)
+		}
+		return replica, func() { _ = replica.Close() }, nil
+	}
+
+	dir, err := os.MkdirTemp("", "vjvector-fsck-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create restore directory: %v", err)
+	}
+	config := catalog.DefaultConfig(dir)
+	config.Storage.Type = storage.StorageTypeMemory
+	if cli.encryption != nil {
+		// Backups of an encrypted catalog are opened with its keys
+		config.Encryption = cli.encryption
+	}
+	restored, err := catalog.New(config)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("failed to open restore catalog: %v", err)
+	}
+	cleanup := func() {
+		_ = restored.Close()
+		_ = os.RemoveAll(dir)
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to open backup: %v", err)
+	}
+	_, err = backup.Restore(ctx, restored, file)
+	_ = file.Close()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to restore backup: %v", err)
+	}
+
+	return restored, cleanup, nil
+}
+
 // benchmarkCmd runs performance benchmarks
 func (cli *CLI) benchmarkCmd(cmd *cobra.Command, args []string) error {
 	if len(args) < 1 {
@@ -291,8 +763,8 @@ func (cli *CLI) benchmarkCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	id := args[0]
-	idx, exists := cli.indexes[id]
-	if !exists {
+	collection, err := cli.catalog.Get(id)
+	if err != nil {
 		return fmt.Errorf("index '%s' not found", id)
 	}
 
@@ -301,10 +773,7 @@ func (cli *CLI) benchmarkCmd(cmd *cobra.Command, args []string) error {
 		iterations = 100
 	}
 
-	dimension, _ := cmd.Flags().GetInt("dimension")
-	if dimension <= 0 {
-		dimension = 128
-	}
+	dimension := collection.Dimension
 
 	fmt.Printf("🚀 Running benchmarks on index '%s'\n", id)
 	fmt.Printf("   Iterations: %d\n", iterations)
@@ -320,10 +789,10 @@ func (cli *CLI) benchmarkCmd(cmd *cobra.Command, args []string) error {
 		}
 		vector := &core.Vector{
 			ID:         fmt.Sprintf("bench_%d", i),
-			Collection: "benchmark",
+			Collection: id,
 			Embedding:  embedding,
 		}
-		if err := idx.Insert(vector); err != nil {
+		if err := cli.catalog.Insert(context.Background(), id, []*core.Vector{vector}); err != nil {
 			return fmt.Errorf("insertion failed: %v", err)
 		}
 	}
@@ -342,7 +811,7 @@ func (cli *CLI) benchmarkCmd(cmd *cobra.Command, args []string) error {
 
 	searchStart := time.Now()
 	for i := 0; i < iterations; i++ {
-		_, err := idx.Search(query, 5)
+		_, err := cli.catalog.Search(context.Background(), id, query, 5)
 		if err != nil {
 			return fmt.Errorf("search failed: %v", err)
 		}
@@ -354,6 +823,10 @@ func (cli *CLI) benchmarkCmd(cmd *cobra.Command, args []string) error {
 	fmt.Printf("   🚀 Rate: %.2f ops/sec\n", searchRate)
 
 	// Final stats
+	idx, err := cli.catalog.Index(id)
+	if err != nil {
+		return err
+	}
 	stats := idx.GetStats()
 	fmt.Printf("\n📊 Final Statistics:\n")
 	fmt.Printf("   📈 Total Vectors: %d\n", stats.TotalVectors)
@@ -386,7 +859,6 @@ func (cli *CLI) demoCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create HNSW index: %v", err)
 	}
-	cli.indexes["demo_hnsw"] = hnswIdx
 
 	// Create IVF index
 	fmt.Println("\n2️⃣ Creating IVF index...")
@@ -404,7 +876,6 @@ func (cli *CLI) demoCmd(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create IVF index: %v", err)
 	}
-	cli.indexes["demo_ivf"] = ivfIdx
 
 	// Insert vectors into both indexes
 	fmt.Println("\n3️⃣ Inserting test vectors...")
@@ -479,7 +950,11 @@ Features:
 - Performance benchmarking
 - Storage statistics
 - Interactive demos`,
+		PersistentPreRunE:  cli.openCatalog,
+		PersistentPostRunE: cli.closeCatalog,
 	}
+	rootCmd.PersistentFlags().StringVar(&cli.dataDir, "data-dir", "/tmp/vjvector_cli", "Directory holding the collection catalog and data")
+	rootCmd.PersistentFlags().StringVar(&cli.storageType, "storage-type", string(storage.StorageTypeLevelDB), "Storage engine for new collections (memory, mmap, leveldb, tiered, lsm)")
 
 	// Create index command
 	createCmd := &cobra.Command{
@@ -508,13 +983,26 @@ Features:
 
 	// Insert vectors command
 	insertCmd := &cobra.Command{
-		Use:   "insert [index-id]",
-		Short: "Insert vectors into an index",
-		Args:  cobra.ExactArgs(1),
+		Use:   "insert [index-id] [file]",
+		Short: "Insert vectors from an fvecs, bvecs, ivecs, npy or jsonl file",
+		Args:  cobra.ExactArgs(2),
 		RunE:  cli.insertVectorsCmd,
 	}
-	insertCmd.Flags().Int("count", 10, "Number of vectors to insert")
-	insertCmd.Flags().Int("dimension", 128, "Vector dimension")
+	insertCmd.Flags().String("format", "", "File format (fvecs, bvecs, ivecs, npy, jsonl); detected from the extension by default")
+	insertCmd.Flags().Int("batch-size", 1000, "Number of vectors inserted per batch")
+	insertCmd.Flags().String("id-prefix", "", "Prefix for the row-number IDs of formats without IDs")
+	insertCmd.Flags().Int64("limit", 0, "Maximum number of vectors to insert (0 for all)")
+	insertCmd.Flags().Duration("ttl", 0, "Expire the inserted vectors after this duration (0 keeps the expiry from the file, if any)")
+
+	// Export vectors command
+	exportCmd := &cobra.Command{
+		Use:   "export [index-id] [file]",
+		Short: "Export vectors to an fvecs, bvecs, ivecs, npy or jsonl file",
+		Args:  cobra.ExactArgs(2),
+		RunE:  cli.exportVectorsCmd,
+	}
+	exportCmd.Flags().String("format", "", "File format (fvecs, bvecs, ivecs, npy, jsonl); detected from the extension by default")
+	exportCmd.Flags().Bool("float64", false, "Write npy files as float64 instead of float32")
 
 	// Search command
 	searchCmd := &cobra.Command{
@@ -524,7 +1012,7 @@ Features:
 		RunE:  cli.searchVectorsCmd,
 	}
 	searchCmd.Flags().Int("k", 5, "Number of results to return")
-	searchCmd.Flags().Int("dimension", 128, "Query vector dimension")
+	searchCmd.Flags().String("as-of", "", "Search the vectors as they were at this RFC3339 time")
 
 	// Stats command
 	statsCmd := &cobra.Command{
@@ -536,8 +1024,9 @@ Features:
 
 	// Storage stats command
 	storageStatsCmd := &cobra.Command{
-		Use:   "storage",
+		Use:   "storage [index-id]",
 		Short: "Show storage statistics",
+		Args:  cobra.MaximumNArgs(1),
 		RunE:  cli.getStorageStatsCmd,
 	}
 
@@ -549,7 +1038,6 @@ Features:
 		RunE:  cli.benchmarkCmd,
 	}
 	benchmarkCmd.Flags().Int("iterations", 100, "Number of benchmark iterations")
-	benchmarkCmd.Flags().Int("dimension", 128, "Vector dimension")
 
 	// Demo command
 	demoCmd := &cobra.Command{
@@ -558,8 +1046,87 @@ Features:
 		RunE:  cli.demoCmd,
 	}
 
+	// Backup command
+	backupCmd := &cobra.Command{
+		Use:   "backup [file]",
+		Short: "Back up the data directory to an archive",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cli.backupCmd,
+	}
+	backupCmd.Flags().Bool("incremental", false, "Only back up changes after --since")
+	backupCmd.Flags().Uint64("since", 0, "Log position of the previous backup")
+	backupCmd.Flags().Bool("object-store", false, "Upload the archive to the object store configured by VJVECTOR_S3_*, using the argument as its key")
+
+	// Restore command
+	restoreCmd := &cobra.Command{
+		Use:   "restore [full-backup] [incremental-backup...]",
+		Short: "Restore backups into an empty data directory",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  cli.restoreCmd,
+	}
+	restoreCmd.Flags().Bool("verify-only", false, "Only validate the archives and their checksums")
+	restoreCmd.Flags().Bool("object-store", false, "Read the archives from the object store configured by VJVECTOR_S3_*, using the arguments as their keys")
+
+	// Fsck command
+	fsckCmd := &cobra.Command{
+		Use:   "fsck [index-id]",
+		Short: "Verify stored records and quarantine or repair corrupt ones",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cli.fsckCmd,
+	}
+	fsckCmd.Flags().Bool("quarantine", false, "Move corrupt records out of storage")
+	fsckCmd.Flags().String("repair-from", "", "Full backup archive or replica data directory to repair corrupt records from")
+
+	// Jobs command, whose subcommands call a server instead of opening the data directory
+	jobsCmd := &cobra.Command{
+		Use:                "jobs",
+		Short:              "Submit, follow and cancel long-running jobs of a server",
+		PersistentPreRunE:  func(cmd *cobra.Command, args []string) error { return nil },
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	jobsCmd.PersistentFlags().String("server", envOr("VJVECTOR_SERVER", "http://localhost:8080"), "URL of the VJVector server")
+	jobsCmd.PersistentFlags().String("api-key", os.Getenv("VJVECTOR_API_KEY"), "API key of the requests")
+	jobsCmd.PersistentFlags().String("tenant", os.Getenv("VJVECTOR_TENANT"), "Tenant of the requests")
+
+	jobsSubmitCmd := &cobra.Command{
+		Use:   "submit [embed|reindex|compact|import]",
+		Short: "Submit a job",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cli.jobsSubmitCmd,
+	}
+	jobsSubmitCmd.Flags().String("collection", "", "Index of the job; reindex and compact cover every index when unset")
+	jobsSubmitCmd.Flags().String("file", "", "jsonl vector records to import, or texts to embed, one per line")
+	jobsSubmitCmd.Flags().StringArray("text", nil, "Text to embed; may be repeated")
+	jobsSubmitCmd.Flags().Int("dimension", 128, "Dimension of the embeddings of an embed job")
+	jobsSubmitCmd.Flags().Int("chunk-size", 0, "Number of records an import job writes at once (0 for the server default)")
+	jobsSubmitCmd.Flags().Bool("embed", false, "Embed the text of imported records without an embedding")
+	jobsSubmitCmd.Flags().Bool("wait", false, "Wait for the job to finish, reporting its progress")
+
+	jobsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List jobs, newest first",
+		Args:  cobra.NoArgs,
+		RunE:  cli.jobsListCmd,
+	}
+	jobsListCmd.Flags().Int("limit", 20, "Maximum number of jobs to list")
+
+	jobsGetCmd := &cobra.Command{
+		Use:   "get [job-id]",
+		Short: "Show a job with its progress and result",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cli.jobsGetCmd,
+	}
+
+	jobsCancelCmd := &cobra.Command{
+		Use:   "cancel [job-id]",
+		Short: "Cancel a job",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cli.jobsCancelCmd,
+	}
+	jobsCmd.AddCommand(jobsSubmitCmd, jobsListCmd, jobsGetCmd, jobsCancelCmd)
+
 	// Add commands to root
-	rootCmd.AddCommand(createCmd, listCmd, insertCmd, searchCmd, statsCmd, storageStatsCmd, benchmarkCmd, demoCmd)
+	rootCmd.AddCommand(createCmd, listCmd, insertCmd, exportCmd, searchCmd, statsCmd, storageStatsCmd, benchmarkCmd, demoCmd, backupCmd, restoreCmd, fsckCmd, jobsCmd)
 
 	// Execute
 	if err := rootCmd.Execute(); err != nil {
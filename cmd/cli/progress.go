@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// progressInterval is the minimum time between two progress reports
+const progressInterval = 500 * time.Millisecond
+
+// progress reports the advance of a long-running command on stderr
+type progress struct {
+	label      string
+	total      int64
+	start      time.Time
+	lastReport time.Time
+}
+
+// newProgress starts a progress report; a total of zero means unknown
+func newProgress(label string, total int64) *progress {
+	now := time.Now()
+	return &progress{label: label, total: total, start: now, lastReport: now}
+}
+
+// update reports count if enough time has passed since the last report
+func (p *progress) update(count int64) {
+	if time.Since(p.lastReport) < progressInterval {
+		return
+	}
+	p.lastReport = time.Now()
+	p.print(count)
+}
+
+// done reports the final count and ends the progress line
+func (p *progress) done(count int64) {
+	if p.lastReport == p.start {
+		// Nothing was printed for a command that finished quickly
+		return
+	}
+	p.print(count)
+	fmt.Fprintln(os.Stderr)
+}
+
+// print writes the progress line
+func (p *progress) print(count int64) {
+	rate := float64(count) / time.Since(p.start).Seconds()
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "\r⏳ %s %d/%d vectors (%.1f%%, %.0f vectors/s)",
+			p.label, count, p.total, float64(count)*100/float64(p.total), rate)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r⏳ %s %d vectors (%.0f vectors/s)", p.label, count, rate)
+}
@@ -0,0 +1,245 @@
+package vjvector
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+)
+
+// CollectionOptions configures a new collection
+type CollectionOptions struct {
+	Description string
+	Metadata    map[string]interface{}
+
+	// IndexType selects the index of the collection; HNSW when empty
+	IndexType index.IndexType
+
+	// DistanceMetric is "cosine", "euclidean" or "dot"; cosine when empty
+	DistanceMetric string
+}
+
+// CreateCollection creates a collection of vectors of the given dimension
+func (db *DB) CreateCollection(name string, dimension int, opts *CollectionOptions) (*core.Collection, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	if opts == nil {
+		opts = &CollectionOptions{}
+	}
+
+	config := catalog.DefaultIndexConfig(opts.IndexType, dimension, opts.DistanceMetric)
+	collection := core.NewCollection(name, opts.Description, dimension, string(config.Type))
+	collection.DistanceMetric = config.DistanceMetric
+	if opts.Metadata != nil {
+		collection.Metadata = opts.Metadata
+	}
+
+	if err := db.catalog.CreateWithIndex(collection, config); err != nil {
+		return nil, err
+	}
+	return db.catalog.Get(name)
+}
+
+// Collection returns the named collection
+func (db *DB) Collection(name string) (*core.Collection, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	return db.catalog.Get(name)
+}
+
+// Collections returns every collection, in name order
+func (db *DB) Collections() ([]*core.Collection, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	return db.catalog.List()
+}
+
+// DropCollection deletes a collection and every vector in it
+func (db *DB) DropCollection(name string) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+
+	if err := db.catalog.Delete(name); err != nil {
+		return err
+	}
+	db.dropEngine(name)
+	return nil
+}
+
+// Insert adds vectors to a collection. It fails with catalog.ErrVersionConflict,
+// and inserts nothing, when a vector with one of their IDs is stored already.
+// Vectors without an ID are given one, and vectors with a text but no
+// embedding are embedded. The vectors are written atomically.
+func (db *DB) Insert(ctx context.Context, collection string, vectors ...*core.Vector) error {
+	return db.write(ctx, collection, vectors, true)
+}
+
+// Upsert adds vectors to a collection, replacing the stored vectors with the
+// same IDs. Vectors are prepared as by Insert and written atomically.
+func (db *DB) Upsert(ctx context.Context, collection string, vectors ...*core.Vector) error {
+	return db.write(ctx, collection, vectors, false)
+}
+
+// write prepares and writes vectors as Insert does when insert is set, and as
+// Upsert does otherwise
+func (db *DB) write(ctx context.Context, collection string, vectors []*core.Vector, insert bool) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+
+	if len(vectors) == 0 {
+		return nil
+	}
+	spec, err := db.catalog.Spec(collection)
+	if err != nil {
+		return err
+	}
+	if err := db.prepare(ctx, spec.Collection, vectors); err != nil {
+		return err
+	}
+
+	var absent uint64
+	writes := make([]catalog.Write, len(vectors))
+	for i, vector := range vectors {
+		writes[i] = catalog.Write{Kind: catalog.WritePut, Vector: vector}
+		if insert {
+			writes[i].ExpectedVersion = &absent
+		}
+	}
+	_, err = db.catalog.Write(ctx, collection, writes)
+	return err
+}
+
+// prepare gives vectors their ID, collection, timestamps and magnitude, and
+// embeds the texts of the vectors without an embedding
+func (db *DB) prepare(ctx context.Context, collection *core.Collection, vectors []*core.Vector) error {
+	var texts []string
+	var embed []*core.Vector
+	for i, vector := range vectors {
+		if vector == nil {
+			return fmt.Errorf("%w: vector %d is nil", catalog.ErrInvalidWrite, i)
+		}
+		if len(vector.Embedding) == 0 && vector.Text != "" {
+			texts = append(texts, vector.Text)
+			embed = append(embed, vector)
+		}
+	}
+
+	if len(texts) > 0 {
+		embeddings, err := db.embed(ctx, collection.Dimension, texts)
+		if err != nil {
+			return err
+		}
+		for i, vector := range embed {
+			vector.Embedding = embeddings[i]
+		}
+	}
+
+	now := time.Now()
+	for _, vector := range vectors {
+		if vector.ID == "" {
+			vector.ID = uuid.New().String()
+		}
+		vector.Collection = collection.Name
+		if vector.CreatedAt.IsZero() {
+			vector.CreatedAt = now
+		}
+		vector.UpdatedAt = now
+		vector.Dimension = len(vector.Embedding)
+		vector.Magnitude = magnitude(vector.Embedding)
+	}
+	return nil
+}
+
+// magnitude returns the Euclidean norm of an embedding
+func magnitude(embedding []float64) float64 {
+	sum := 0.0
+	for _, value := range embedding {
+		sum += value * value
+	}
+	return math.Sqrt(sum)
+}
+
+// Delete removes vectors from a collection; IDs that are not stored are skipped.
+// The vectors are deleted atomically.
+func (db *DB) Delete(ctx context.Context, collection string, ids ...string) error {
+	if err := db.acquire(); err != nil {
+		return err
+	}
+	defer db.release()
+
+	return db.catalog.DeleteVectors(ctx, collection, ids)
+}
+
+// Get returns the stored vectors of a collection with the given IDs, skipping
+// the IDs that are not stored and the vectors that expired
+func (db *DB) Get(ctx context.Context, collection string, ids ...string) ([]*core.Vector, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	engine, err := db.catalog.Storage(collection)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := engine.ReadWithContext(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	live := make([]*core.Vector, 0, len(vectors))
+	for _, vector := range vectors {
+		if !vector.Expired(now) {
+			live = append(live, vector)
+		}
+	}
+	return live, nil
+}
+
+// Search finds the k vectors of a collection most similar to query
+func (db *DB) Search(ctx context.Context, collection string, query []float64, k int) ([]core.VectorSearchResult, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	return db.catalog.Search(ctx, collection, query, k)
+}
+
+// SearchText finds the k vectors of a collection most similar to the
+// embedding of text
+func (db *DB) SearchText(ctx context.Context, collection, text string, k int) ([]core.VectorSearchResult, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	spec, err := db.catalog.Spec(collection)
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := db.embed(ctx, spec.Collection.Dimension, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return db.catalog.Search(ctx, collection, embeddings[0], k)
+}
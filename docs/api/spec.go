@@ -0,0 +1,10 @@
+// Package api embeds the OpenAPI specification of the VJVector HTTP API, so
+// that the server can serve it and validate requests against it
+package api
+
+import _ "embed" // embeds openapi.yaml
+
+// OpenAPI is the OpenAPI 3 specification of the API, in YAML
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
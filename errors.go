@@ -0,0 +1,12 @@
+package vjvector
+
+import "errors"
+
+// Database errors
+var (
+	ErrInvalidDirectory = errors.New("invalid database directory")
+	ErrLocked           = errors.New("database is locked by another process")
+	ErrClosed           = errors.New("database is closed")
+	ErrInvalidQuery     = errors.New("invalid query")
+	ErrReadOnlyIndex    = errors.New("collection index is read-only")
+)
@@ -0,0 +1,621 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	vjvectorv1 "github.com/vijaynallagatla/vjvector/api/vjvector/v1"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/enterprise"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Request headers carrying credentials; the gRPC API reads the same names
+// from the call metadata
+const (
+	apiKeyHeader   = "X-API-Key"
+	tenantIDHeader = "X-Tenant-ID"
+)
+
+// DefaultTenant is the tenant of requests that name none. It operates the
+// server: its collections keep unprefixed names and only it may use the
+// operator routes.
+const DefaultTenant = tenant.DefaultTenantID
+
+// adminKeyID is the ID of the bootstrap admin key, which is not stored by the
+// key service
+const adminKeyID = "admin"
+
+// defaultKeyListLimit is the number of keys listed by requests that set no limit
+const defaultKeyListLimit = 100
+
+// permission is the action on a resource that a request is authorized for. A
+// key is granted it by the permission "resource:action", "resource:*" or "*",
+// and needs the resource in its scopes as well.
+type permission struct {
+	resource string
+	action   string
+}
+
+// Resources and actions that permissions grant
+var (
+	apiResources = []string{"collections", "vectors", "rag", "storage", "changes", "metrics", "jobs", "webhooks", "admin"}
+	apiActions   = []string{"read", "write", "delete"}
+)
+
+// allPermissions is required by routes with no permission of their own, so
+// that only keys with every permission reach them
+var allPermissions = permission{"*", "*"}
+
+// publicRoutes are served without an API key
+var publicRoutes = map[string]bool{
+	"GET /health":       true,
+	"GET /ready":        true,
+	"GET /openapi.yaml": true,
+	"GET /docs":         true,
+}
+
+// routePermissions maps the other routes of the REST API to the permission
+// they require
+var routePermissions = map[string]permission{
+	"POST /v1/indexes":                                     {"collections", "write"},
+	"GET /v1/indexes":                                      {"collections", "read"},
+	"GET /v1/indexes/:indexId":                             {"collections", "read"},
+	"DELETE /v1/indexes/:indexId":                          {"collections", "delete"},
+	"POST /v1/indexes/:indexId/vectors":                    {"vectors", "write"},
+	"POST /v1/indexes/:indexId/batch":                      {"vectors", "write"},
+	"POST /v1/indexes/:indexId/ingest":                     {"vectors", "write"},
+	"POST /v1/indexes/:indexId/search":                     {"vectors", "read"},
+	"POST /v1/rag/query":                                   {"rag", "read"},
+	"POST /v1/rag/query/stream":                            {"rag", "read"},
+	"POST /v1/rag/batch":                                   {"rag", "read"},
+	"GET /v1/rag/capabilities":                             {"rag", "read"},
+	"GET /v1/rag/statistics":                               {"rag", "read"},
+	"POST /v1/storage/compact":                             {"storage", "write"},
+	"GET /v1/storage/stats":                                {"storage", "read"},
+	"GET /v1/changes":                                      {"changes", "read"},
+	"GET /v1/metrics":                                      {"metrics", "read"},
+	"POST /v1/jobs":                                        {"jobs", "write"},
+	"GET /v1/jobs":                                         {"jobs", "read"},
+	"GET /v1/jobs/:jobId":                                  {"jobs", "read"},
+	"DELETE /v1/jobs/:jobId":                               {"jobs", "delete"},
+	"GET /v1/webhooks/deliveries":                          {"webhooks", "read"},
+	"GET /v1/webhooks/dead-letters":                        {"webhooks", "read"},
+	"POST /v1/webhooks/dead-letters/:deliveryId/redeliver": {"webhooks", "write"},
+	"POST /v1/admin/backup":                                {"admin", "write"},
+	"POST /v1/admin/keys":                                  {"admin", "write"},
+	"GET /v1/admin/keys":                                   {"admin", "read"},
+	"GET /v1/admin/keys/:keyId":                            {"admin", "read"},
+	"DELETE /v1/admin/keys/:keyId":                         {"admin", "delete"},
+	"POST /v1/admin/keys/:keyId/revoke":                    {"admin", "write"},
+
+	"POST /v1/admin/tenants":                   {"admin", "write"},
+	"GET /v1/admin/tenants":                    {"admin", "read"},
+	"GET /v1/admin/tenants/:tenantId":          {"admin", "read"},
+	"PUT /v1/admin/tenants/:tenantId/settings": {"admin", "write"},
+	"PUT /v1/admin/tenants/:tenantId/status":   {"admin", "write"},
+}
+
+// methodPermissions maps the methods of the gRPC API to the permission they
+// require
+var methodPermissions = map[string]permission{
+	vjvectorv1.CollectionService_CreateCollection_FullMethodName: {"collections", "write"},
+	vjvectorv1.CollectionService_GetCollection_FullMethodName:    {"collections", "read"},
+	vjvectorv1.CollectionService_ListCollections_FullMethodName:  {"collections", "read"},
+	vjvectorv1.CollectionService_DeleteCollection_FullMethodName: {"collections", "delete"},
+	vjvectorv1.VectorService_Upsert_FullMethodName:               {"vectors", "write"},
+	vjvectorv1.VectorService_Delete_FullMethodName:               {"vectors", "write"},
+	vjvectorv1.VectorService_Get_FullMethodName:                  {"vectors", "read"},
+	vjvectorv1.VectorService_BulkInsert_FullMethodName:           {"vectors", "write"},
+	vjvectorv1.SearchService_Search_FullMethodName:               {"vectors", "read"},
+	vjvectorv1.SearchService_BatchSearch_FullMethodName:          {"vectors", "read"},
+	vjvectorv1.RAGService_Query_FullMethodName:                   {"rag", "read"},
+}
+
+// apiKeyContextKey is the context key of the API key a request was
+// authenticated with
+type apiKeyContextKey struct{}
+
+// withAPIKey returns a context carrying the API key of a request
+func withAPIKey(ctx context.Context, key *enterprise.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// apiKeyFrom returns the API key a request was authenticated with; nil when
+// authentication is disabled
+func apiKeyFrom(ctx context.Context) *enterprise.APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*enterprise.APIKey)
+	return key
+}
+
+// OpenAPIKeys keeps the API keys in the file at path, so that the keys issued
+// before a restart keep working, replacing the keys held in memory
+func (h *Handlers) OpenAPIKeys(path string) error {
+	keys, err := enterprise.OpenAPIKeyService(path)
+	if err != nil {
+		return err
+	}
+	if h.apiKeys != nil {
+		_ = h.apiKeys.Close()
+	}
+	h.apiKeys = keys
+	return nil
+}
+
+// EnableAuth requires an API key on every request but the health, readiness
+// and documentation routes, on both the REST and gRPC APIs. adminKey is a
+// bootstrap key with every permission, used to create the other keys.
+func (h *Handlers) EnableAuth(adminKey string) {
+	h.authEnabled = true
+	h.adminKey = adminKey
+}
+
+// authorize authenticates the key of a request and checks that it grants a
+// permission on a resource to the client of the request. A request that names
+// a tenant must use a key of that tenant; the admin key acts for the tenant
+// named, or the default one.
+func (h *Handlers) authorize(ctx context.Context, secret, tenantID string, required permission, resourceID, address, userAgent string) (*enterprise.APIKey, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("%w: an API key is required", ErrUnauthenticated)
+	}
+
+	var key *enterprise.APIKey
+	if h.adminKey != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(h.adminKey)) == 1 {
+		if tenantID == "" {
+			tenantID = DefaultTenant
+		}
+		key = &enterprise.APIKey{
+			ID:          adminKeyID,
+			TenantID:    tenantID,
+			Name:        "admin",
+			Permissions: []string{"*"},
+			Scopes:      []string{"*"},
+			Status:      enterprise.APIKeyStatusActive,
+		}
+	} else {
+		var err error
+		if key, err = h.apiKeys.ValidateAPIKey(ctx, secret, tenantID); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+	}
+
+	if err := admits(key, address, userAgent); err != nil {
+		return nil, err
+	}
+	if err := h.grants(ctx, key, required, resourceID); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// admits checks that a key may be used from a client address and user agent.
+// A key with IP restrictions is only used from the addresses and CIDR ranges
+// they list, and a key with user agent restrictions only by user agents that
+// start with one of them.
+func admits(key *enterprise.APIKey, address, userAgent string) error {
+	if len(key.IPRestrictions) > 0 {
+		ip, err := netip.ParseAddr(address)
+		if err != nil || !slices.ContainsFunc(key.IPRestrictions, func(allowed string) bool { return containsAddr(allowed, ip.Unmap()) }) {
+			return fmt.Errorf("%w: the API key is not allowed from %q", ErrPermissionDenied, address)
+		}
+	}
+	if len(key.UserAgentRestrictions) > 0 {
+		if !slices.ContainsFunc(key.UserAgentRestrictions, func(allowed string) bool { return strings.HasPrefix(userAgent, allowed) }) {
+			return fmt.Errorf("%w: the API key is not allowed for user agent %q", ErrPermissionDenied, userAgent)
+		}
+	}
+	return nil
+}
+
+// containsAddr reports whether an IP restriction, an address or a CIDR
+// range, contains an address
+func containsAddr(allowed string, ip netip.Addr) bool {
+	if prefix, err := netip.ParsePrefix(allowed); err == nil {
+		return prefix.Contains(ip)
+	}
+	if addr, err := netip.ParseAddr(allowed); err == nil {
+		return addr.Unmap() == ip
+	}
+	return false
+}
+
+// validIPRestriction reports whether an IP restriction is an address or a
+// CIDR range
+func validIPRestriction(allowed string) bool {
+	if _, err := netip.ParsePrefix(allowed); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(allowed)
+	return err == nil
+}
+
+// grants checks that a key grants a permission on a resource
+func (h *Handlers) grants(ctx context.Context, key *enterprise.APIKey, required permission, resourceID string) error {
+	if !h.apiKeys.CheckPermission(ctx, key, required.resource, required.action, resourceID) {
+		return fmt.Errorf("%w: the API key lacks the %s:%s permission", ErrPermissionDenied, required.resource, required.action)
+	}
+	if !h.apiKeys.ValidateScope(ctx, key, required.resource) {
+		return fmt.Errorf("%w: %s is outside the scopes of the API key", ErrPermissionDenied, required.resource)
+	}
+	return nil
+}
+
+// checkPermission checks that the key of a request grants a permission beyond
+// the one of its route; every request is granted it when authentication is
+// disabled
+func (h *Handlers) checkPermission(ctx context.Context, required permission, resourceID string) error {
+	key := apiKeyFrom(ctx)
+	if key == nil {
+		return nil
+	}
+	return h.grants(ctx, key, required, resourceID)
+}
+
+// trackUsage records a request made with a stored API key
+func (h *Handlers) trackUsage(ctx context.Context, key *enterprise.APIKey, endpoint, method, ipAddress, userAgent string) error {
+	if key.ID == adminKeyID {
+		return nil
+	}
+	return h.apiKeys.TrackAPIKeyUsage(ctx, key.ID, endpoint, method, ipAddress, userAgent)
+}
+
+// authenticate rejects requests without an API key granting the permission
+// of their route, with 401 for missing or invalid keys and 403 for keys that
+// lack the permission
+func (h *Handlers) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		request := c.Request()
+		route := request.Method + " " + c.Path()
+		if !h.authEnabled || publicRoutes[route] {
+			return next(c)
+		}
+		required, exists := routePermissions[route]
+		if !exists {
+			required = allPermissions
+		}
+
+		secret := request.Header.Get(apiKeyHeader)
+		if bearer, found := strings.CutPrefix(request.Header.Get(echo.HeaderAuthorization), "Bearer "); found {
+			secret = bearer
+		}
+		ctx := request.Context()
+		tenantID, err := h.namedTenant(ctx, request.Header.Get(tenantIDHeader), request.Host)
+		if err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+		key, err := h.authorize(ctx, secret, tenantID, required, c.Param("indexId"), c.RealIP(), request.UserAgent())
+		if err != nil {
+			if errors.Is(err, ErrUnauthenticated) {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="vjvector"`)
+			}
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+		if err := h.trackUsage(ctx, key, c.Path(), request.Method, c.RealIP(), request.UserAgent()); err != nil {
+			c.Logger().Warnf("failed to track API key usage: %v", err)
+		}
+
+		c.SetRequest(request.WithContext(withAPIKey(ctx, key)))
+		return next(c)
+	}
+}
+
+// authorizeCall authenticates a gRPC call with the API key of its metadata
+func (h *Handlers) authorizeCall(ctx context.Context, method string, req interface{}) (context.Context, error) {
+	if !h.authEnabled {
+		return ctx, nil
+	}
+	required, exists := methodPermissions[method]
+	if !exists {
+		required = allPermissions
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	value := func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	secret := value(strings.ToLower(apiKeyHeader))
+	if bearer, found := strings.CutPrefix(value("authorization"), "Bearer "); found {
+		secret = bearer
+	}
+	var resourceID string
+	if named, ok := req.(interface{ GetCollection() string }); ok {
+		resourceID = named.GetCollection()
+	}
+
+	tenantID, err := h.namedTenant(ctx, value(strings.ToLower(tenantIDHeader)), value(":authority"))
+	if err != nil {
+		return nil, err
+	}
+	var address string
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			address = host
+		}
+	}
+	key, err := h.authorize(ctx, secret, tenantID, required, resourceID, address, value("user-agent"))
+	if err != nil {
+		return nil, err
+	}
+	_ = h.trackUsage(ctx, key, method, "grpc", address, value("user-agent"))
+	return withAPIKey(ctx, key), nil
+}
+
+// authUnaryInterceptor authorizes unary calls
+func (h *Handlers) authUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := h.authorizeCall(ctx, info.FullMethod, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return handler(ctx, req)
+}
+
+// authStreamInterceptor authorizes streaming calls
+func (h *Handlers) authStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, err := h.authorizeCall(stream.Context(), info.FullMethod, nil)
+	if err != nil {
+		return grpcError(err)
+	}
+	return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorizedStream is a server stream whose context carries its API key and
+// tenant
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// validPermission reports whether a permission of a key names a known
+// resource and action
+func validPermission(granted string) bool {
+	if granted == "*" {
+		return true
+	}
+	resource, action, found := strings.Cut(granted, ":")
+	if !found {
+		return false
+	}
+	return slices.Contains(apiResources, resource) && (action == "*" || slices.Contains(apiActions, action))
+}
+
+// delegable checks that a key grants every permission and scope of a key it
+// creates, so that no key can create a key more powerful than itself
+func delegable(key *enterprise.APIKey, permissions, scopes []string) error {
+	for _, requested := range permissions {
+		if !slices.ContainsFunc(key.Permissions, func(held string) bool { return coversPermission(held, requested) }) {
+			return fmt.Errorf("%w: the API key cannot grant the %s permission it lacks", ErrPermissionDenied, requested)
+		}
+	}
+	for _, requested := range scopes {
+		if !slices.Contains(key.Scopes, "*") && !slices.Contains(key.Scopes, requested) {
+			return fmt.Errorf("%w: the API key cannot grant the %s scope it lacks", ErrPermissionDenied, requested)
+		}
+	}
+	return nil
+}
+
+// coversPermission reports whether a held permission grants everything a
+// requested one does: "*" covers every permission and "resource:*" every
+// action on the resource
+func coversPermission(held, requested string) bool {
+	if held == "*" || held == requested {
+		return true
+	}
+	resource, action, _ := strings.Cut(held, ":")
+	return action == "*" && strings.HasPrefix(requested, resource+":")
+}
+
+// keyTenant returns the tenant whose keys a request manages: its own tenant,
+// or the tenant it names when the request is of the default tenant
+func (h *Handlers) keyTenant(c echo.Context, tenantID string) (string, error) {
+	own := h.tenantOf(c.Request().Context())
+	if tenantID == "" || tenantID == own {
+		return own, nil
+	}
+	if own != DefaultTenant {
+		return "", fmt.Errorf("%w: only the default tenant manages the keys of other tenants", ErrPermissionDenied)
+	}
+	if !tenant.ValidTenantID(tenantID) {
+		return "", fmt.Errorf("%w: %q is not a valid tenant ID", tenant.ErrInvalidTenant, tenantID)
+	}
+	return tenantID, nil
+}
+
+// tenantKey returns an API key of the tenant of a request; the keys of other
+// tenants are not found unless the request is of the default tenant
+func (h *Handlers) tenantKey(c echo.Context, id string) (*enterprise.APIKey, error) {
+	ctx := c.Request().Context()
+	key, err := h.apiKeys.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if own := h.tenantOf(ctx); own != DefaultTenant && key.TenantID != own {
+		return nil, fmt.Errorf("%w: %s", enterprise.ErrAPIKeyNotFound, id)
+	}
+	return key, nil
+}
+
+// keyErrorResponse reports an error of the API key service
+func keyErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, enterprise.ErrAPIKeyNotFound):
+		return errorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrPermissionDenied), errors.Is(err, tenant.ErrInvalidTenant):
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return errorResponse(c, http.StatusInternalServerError, err.Error())
+}
+
+// pageParams returns the limit and offset of a list request
+func pageParams(c echo.Context) (int, int, error) {
+	limit, offset := defaultKeyListLimit, 0
+	var err error
+	if value := c.QueryParam("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			return 0, 0, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidRequest)
+		}
+	}
+	if value := c.QueryParam("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("%w: offset must not be negative", ErrInvalidRequest)
+		}
+	}
+	return limit, offset, nil
+}
+
+// createAPIKey creates an API key. The secret of the key is only returned by
+// this response. A key only grants permissions and scopes that the key of the
+// request grants; a key created without scopes or client restrictions gets
+// those of that key.
+func (h *Handlers) createAPIKey(c echo.Context) error {
+	var req models.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+	for _, granted := range req.Permissions {
+		if !validPermission(granted) {
+			return errorResponse(c, http.StatusBadRequest, fmt.Sprintf("unknown permission %q", granted))
+		}
+	}
+	for _, allowed := range req.IPRestrictions {
+		if !validIPRestriction(allowed) {
+			return errorResponse(c, http.StatusBadRequest, fmt.Sprintf("IP restriction %q is not an address or a CIDR range", allowed))
+		}
+	}
+	caller := apiKeyFrom(c.Request().Context())
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{"*"}
+		if caller != nil {
+			req.Scopes = caller.Scopes
+		}
+	}
+	if caller != nil {
+		if len(req.IPRestrictions) == 0 {
+			req.IPRestrictions = caller.IPRestrictions
+		}
+		if len(req.UserAgentRestrictions) == 0 {
+			req.UserAgentRestrictions = caller.UserAgentRestrictions
+		}
+	}
+	if caller != nil {
+		if err := delegable(caller, req.Permissions, req.Scopes); err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+	}
+
+	tenantID, err := h.keyTenant(c, req.TenantID)
+	if err != nil {
+		return keyErrorResponse(c, err)
+	}
+	key, secret, err := h.apiKeys.CreateAPIKey(c.Request().Context(), tenantID,
+		req.Name, req.Description, req.Permissions, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return keyErrorResponse(c, err)
+	}
+	if len(req.IPRestrictions) > 0 || len(req.UserAgentRestrictions) > 0 {
+		key.IPRestrictions = req.IPRestrictions
+		key.UserAgentRestrictions = req.UserAgentRestrictions
+		if err := h.apiKeys.UpdateAPIKey(c.Request().Context(), key); err != nil {
+			_ = h.apiKeys.DeleteAPIKey(c.Request().Context(), key.ID)
+			return keyErrorResponse(c, err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"key":     key,
+		"secret":  secret,
+		"message": "API key created; the secret is not shown again",
+	})
+}
+
+// listAPIKeys lists the API keys of a tenant, oldest first
+func (h *Handlers) listAPIKeys(c echo.Context) error {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	tenantID, err := h.keyTenant(c, c.QueryParam("tenant_id"))
+	if err != nil {
+		return keyErrorResponse(c, err)
+	}
+	keys, err := h.apiKeys.ListAPIKeys(c.Request().Context(), tenantID, limit, offset)
+	if err != nil {
+		return keyErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tenant_id": tenantID,
+		"keys":      keys,
+		"count":     len(keys),
+	})
+}
+
+// getAPIKey returns an API key
+func (h *Handlers) getAPIKey(c echo.Context) error {
+	key, err := h.tenantKey(c, c.Param("keyId"))
+	if err != nil {
+		return keyErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, key)
+}
+
+// revokeAPIKey revokes an API key, which then fails authentication
+func (h *Handlers) revokeAPIKey(c echo.Context) error {
+	var req models.RevokeAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	if _, err := h.tenantKey(c, c.Param("keyId")); err != nil {
+		return keyErrorResponse(c, err)
+	}
+	if err := h.apiKeys.RevokeAPIKey(c.Request().Context(), c.Param("keyId"), req.Reason); err != nil {
+		return keyErrorResponse(c, err)
+	}
+	key, err := h.tenantKey(c, c.Param("keyId"))
+	if err != nil {
+		return keyErrorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, key)
+}
+
+// deleteAPIKey deletes an API key
+func (h *Handlers) deleteAPIKey(c echo.Context) error {
+	id := c.Param("keyId")
+	if _, err := h.tenantKey(c, id); err != nil {
+		return keyErrorResponse(c, err)
+	}
+	if err := h.apiKeys.DeleteAPIKey(c.Request().Context(), id); err != nil {
+		return keyErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"status":  "deleted",
+		"message": "API key deleted successfully",
+	})
+}
@@ -0,0 +1,334 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	vjvectorv1 "github.com/vijaynallagatla/vjvector/api/vjvector/v1"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// testAdminKey is the bootstrap admin key of the authentication tests
+const testAdminKey = "test-admin-key"
+
+// serveAs sends a JSON request to the router with an API key and tenant
+func serveAs(e *echo.Echo, key, tenant, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	request := httptest.NewRequest(method, path, bytes.NewReader(data))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		request.Header.Set(apiKeyHeader, key)
+	}
+	if tenant != "" {
+		request.Header.Set(tenantIDHeader, tenant)
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// createTestKey creates an API key with the admin key, returning its ID and secret
+func createTestKey(t *testing.T, e *echo.Echo, tenant string, req models.CreateAPIKeyRequest) (string, string) {
+	t.Helper()
+
+	recorder := serveAs(e, testAdminKey, tenant, http.MethodPost, "/v1/admin/keys", req)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create API key: %s", recorder.Body.String())
+	}
+	var response struct {
+		Key struct {
+			ID string `json:"id"`
+		} `json:"key"`
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response.Key.ID, response.Secret
+}
+
+func TestAuthentication(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableAuth(testAdminKey)
+
+	// Public routes need no key
+	for _, path := range []string{"/health", "/ready", "/openapi.yaml"} {
+		if recorder := serveAs(e, "", "", http.MethodGet, path, nil); recorder.Code != http.StatusOK {
+			t.Errorf("Expected %s to be public, got %d", path, recorder.Code)
+		}
+	}
+
+	recorder := serveAs(e, "", "", http.MethodGet, "/v1/indexes", nil)
+	if recorder.Code != http.StatusUnauthorized || recorder.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+		t.Errorf("Expected 401 with a challenge without a key, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serveAs(e, "vk_wrong", "", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong key, got %d", recorder.Code)
+	}
+
+	recorder = serveAs(e, testAdminKey, "", http.MethodPost, "/v1/indexes", map[string]interface{}{
+		"id": "docs", "type": "hnsw", "dimension": contractDimension, "max_elements": 100,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected the admin key to create an index, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	readerID, reader := createTestKey(t, e, "", models.CreateAPIKeyRequest{
+		Name: "reader", Permissions: []string{"collections:read", "vectors:read"},
+	})
+	_, writer := createTestKey(t, e, "", models.CreateAPIKeyRequest{
+		Name: "writer", Permissions: []string{"vectors:*"}, Scopes: []string{"collections"},
+	})
+
+	// Keys reach the routes of their permissions, by header or bearer token
+	if recorder := serveAs(e, reader, "", http.MethodGet, "/v1/indexes/docs", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the reader to get the index, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	request := httptest.NewRequest(http.MethodPost, "/v1/indexes/docs/search",
+		strings.NewReader(`{"query": [1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0]}`))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	request.Header.Set(echo.HeaderAuthorization, "Bearer "+reader)
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected the reader to search with a bearer token, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	insert := map[string]interface{}{
+		"vectors": []interface{}{map[string]interface{}{"id": "raft", "embedding": embedText(t, "raft")}},
+	}
+	if recorder := serveAs(e, reader, "", http.MethodPost, "/v1/indexes/docs/vectors", insert); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for the reader to insert, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, reader, "", http.MethodGet, "/v1/admin/keys", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for the reader to list keys, got %d", recorder.Code)
+	}
+	// The writer has the permission but not the scope of vectors
+	if recorder := serveAs(e, writer, "", http.MethodPost, "/v1/indexes/docs/vectors", insert); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 outside the scopes of the writer, got %d", recorder.Code)
+	}
+
+	// Keys belong to their tenant
+	if recorder := serveAs(e, reader, "acme", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for the key of another tenant, got %d", recorder.Code)
+	}
+
+	// Usage is tracked
+	recorder = serveAs(e, testAdminKey, "", http.MethodGet, "/v1/admin/keys/"+readerID, nil)
+	var key struct {
+		UsageCount int64 `json:"usage_count"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &key); err != nil || key.UsageCount != 2 {
+		t.Errorf("Expected 2 uses of the reader, got %s", recorder.Body.String())
+	}
+
+	// Revoked keys fail authentication
+	recorder = serveAs(e, testAdminKey, "", http.MethodPost, "/v1/admin/keys/"+readerID+"/revoke", map[string]string{"reason": "rotated"})
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"revoked"`) {
+		t.Fatalf("Failed to revoke key: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := serveAs(e, reader, "", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a revoked key, got %d", recorder.Code)
+	}
+}
+
+func TestCreateAPIKeyEscalation(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableAuth(testAdminKey)
+
+	_, secret := createTestKey(t, e, "acme", models.CreateAPIKeyRequest{
+		Name:        "key-admin",
+		Permissions: []string{"admin:write", "collections:*"},
+		Scopes:      []string{"admin", "collections"},
+	})
+
+	// A key cannot grant permissions or scopes it lacks
+	for _, req := range []models.CreateAPIKeyRequest{
+		{Name: "everything", Permissions: []string{"*"}, Scopes: []string{"admin"}},
+		{Name: "wildcard", Permissions: []string{"admin:*"}, Scopes: []string{"admin"}},
+		{Name: "reader", Permissions: []string{"admin:read"}, Scopes: []string{"admin"}},
+		{Name: "all-scopes", Permissions: []string{"admin:write"}, Scopes: []string{"*"}},
+		{Name: "other-scope", Permissions: []string{"admin:write"}, Scopes: []string{"vectors"}},
+	} {
+		recorder := serveAs(e, secret, "acme", http.MethodPost, "/v1/admin/keys", req)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 creating key %s, got %d: %s", req.Name, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	// A subset of its own is allowed, with its scopes by default
+	recorder := serveAs(e, secret, "acme", http.MethodPost, "/v1/admin/keys", models.CreateAPIKeyRequest{
+		Name:        "collections-writer",
+		Permissions: []string{"collections:write", "admin:write"},
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating a key within its own permissions, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Key struct {
+			Scopes []string `json:"scopes"`
+		} `json:"key"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if strings.Join(response.Key.Scopes, ",") != "admin,collections" {
+		t.Errorf("Expected the scopes of the creating key, got %v", response.Key.Scopes)
+	}
+}
+
+func TestAPIKeyClientRestrictions(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableAuth(testAdminKey)
+
+	recorder := serveAs(e, testAdminKey, "", http.MethodPost, "/v1/admin/keys", models.CreateAPIKeyRequest{
+		Name: "bad-range", Permissions: []string{"collections:read"}, IPRestrictions: []string{"10.0.0.0/33"},
+	})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid IP restriction, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	_, secret := createTestKey(t, e, "", models.CreateAPIKeyRequest{
+		Name:                  "restricted",
+		Permissions:           []string{"collections:read", "admin:write"},
+		IPRestrictions:        []string{"10.0.0.0/8", "2001:db8::1"},
+		UserAgentRestrictions: []string{"vjvector-cli/"},
+	})
+
+	list := func(address, userAgent string) int {
+		request := httptest.NewRequest(http.MethodGet, "/v1/indexes", nil)
+		request.RemoteAddr = address
+		request.Header.Set(apiKeyHeader, secret)
+		request.Header.Set("User-Agent", userAgent)
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	for _, tc := range []struct {
+		address, userAgent string
+		status             int
+	}{
+		{"10.1.2.3:4000", "vjvector-cli/1.0", http.StatusOK},
+		{"[2001:db8::1]:4000", "vjvector-cli/1.0", http.StatusOK},
+		{"192.0.2.1:4000", "vjvector-cli/1.0", http.StatusForbidden},
+		{"10.1.2.3:4000", "curl/8.0", http.StatusForbidden},
+	} {
+		if status := list(tc.address, tc.userAgent); status != tc.status {
+			t.Errorf("Expected %d from %s as %s, got %d", tc.status, tc.address, tc.userAgent, status)
+		}
+	}
+
+	// Keys it creates stay within its restrictions
+	request := httptest.NewRequest(http.MethodPost, "/v1/admin/keys", strings.NewReader(`{"name": "child", "permissions": ["collections:read"]}`))
+	request.RemoteAddr = "10.1.2.3:4000"
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	request.Header.Set(apiKeyHeader, secret)
+	request.Header.Set("User-Agent", "vjvector-cli/1.0")
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating a key, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Key struct {
+			IPRestrictions []string `json:"ip_restrictions"`
+		} `json:"key"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if strings.Join(response.Key.IPRestrictions, ",") != "10.0.0.0/8,2001:db8::1" {
+		t.Errorf("Expected the IP restrictions of the creating key, got %v", response.Key.IPRestrictions)
+	}
+}
+
+func TestAPIKeysPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+
+	e, handlers := newTestRouter(t)
+	if err := handlers.OpenAPIKeys(path); err != nil {
+		t.Fatalf("Failed to open API keys: %v", err)
+	}
+	handlers.EnableAuth(testAdminKey)
+	_, secret := createTestKey(t, e, "acme", models.CreateAPIKeyRequest{
+		Name:        "reader",
+		Permissions: []string{"collections:read"},
+		Scopes:      []string{"collections"},
+	})
+	if err := handlers.Close(); err != nil {
+		t.Fatalf("Failed to close handlers: %v", err)
+	}
+
+	// The key works with a new server on the same file
+	e, handlers = newTestRouter(t)
+	if err := handlers.OpenAPIKeys(path); err != nil {
+		t.Fatalf("Failed to reopen API keys: %v", err)
+	}
+	handlers.EnableAuth(testAdminKey)
+	if recorder := serveAs(e, secret, "acme", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the key to work after a restart, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestRoutePermissions(t *testing.T) {
+	e, _ := newTestRouter(t)
+
+	// Every route is public or requires a permission of its own
+	for _, route := range e.Routes() {
+		name := route.Method + " " + route.Path
+		if _, exists := routePermissions[name]; !exists && !publicRoutes[name] {
+			t.Errorf("Route %s has no permission", name)
+		}
+	}
+	for name, required := range routePermissions {
+		if !validPermission(required.resource + ":" + required.action) {
+			t.Errorf("Route %s requires an unknown permission %v", name, required)
+		}
+	}
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	conn, handlers := newTestGRPC(t)
+	handlers.EnableAuth(testAdminKey)
+	collections := vjvectorv1.NewCollectionServiceClient(conn)
+
+	_, err := collections.ListCollections(context.Background(), &vjvectorv1.ListCollectionsRequest{})
+	expectCode(t, err, codes.Unauthenticated)
+
+	admin := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testAdminKey)
+	if _, err := collections.CreateCollection(admin, &vjvectorv1.CreateCollectionRequest{
+		Name: "docs", Type: "hnsw", Dimension: contractDimension,
+	}); err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+
+	_, secret, err := handlers.apiKeys.CreateAPIKey(context.Background(), DefaultTenant, "reader", "",
+		[]string{"collections:read"}, []string{"*"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	reader := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", secret)
+	if _, err := collections.GetCollection(reader, &vjvectorv1.GetCollectionRequest{Name: "docs"}); err != nil {
+		t.Errorf("Expected the reader to get the collection: %v", err)
+	}
+	_, err = collections.DeleteCollection(reader, &vjvectorv1.DeleteCollectionRequest{Name: "docs"})
+	expectCode(t, err, codes.PermissionDenied)
+
+	stream, err := vjvectorv1.NewVectorServiceClient(conn).BulkInsert(reader)
+	if err != nil {
+		t.Fatalf("BulkInsert failed: %v", err)
+	}
+	_, err = stream.CloseAndRecv()
+	expectCode(t, err, codes.PermissionDenied)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/pkg/backup"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+)
+
+// createBackup streams a full or incremental backup archive of the catalog,
+// with the collections of every tenant. The archive is staged in a temporary
+// file so that failures are reported with a proper error response instead of
+// a truncated download.
+func (h *Handlers) createBackup(c echo.Context) error {
+	opts := backup.Options{}
+	if value := c.QueryParam("incremental"); value != "" {
+		incremental, err := strconv.ParseBool(value)
+		if err != nil {
+			return errorResponse(c, http.StatusBadRequest, "incremental must be a boolean")
+		}
+		opts.Incremental = incremental
+	}
+	if value := c.QueryParam("since"); value != "" {
+		since, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return errorResponse(c, http.StatusBadRequest, "since must be a log position")
+		}
+		opts.Since = since
+	}
+
+	file, err := os.CreateTemp("", "vjvector-backup-*.tar.gz")
+	if err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	manifest, err := backup.Create(c.Request().Context(), h.catalog, file, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, catalog.ErrInvalidPosition):
+			status = http.StatusBadRequest
+		case errors.Is(err, catalog.ErrLogTruncated):
+			status = http.StatusGone
+		}
+		return errorResponse(c, status, err.Error())
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+
+	filename := fmt.Sprintf("vjvector-%s-%d.tar.gz", manifest.Kind, manifest.Position)
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	header.Set("X-Backup-Kind", string(manifest.Kind))
+	header.Set("X-Backup-Base-Position", strconv.FormatUint(manifest.BasePosition, 10))
+	header.Set("X-Backup-Position", strconv.FormatUint(manifest.Position, 10))
+
+	return c.Stream(http.StatusOK, "application/gzip", file)
+}
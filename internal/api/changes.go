@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+)
+
+// streamChanges streams the change events of the collections of the tenant
+// of a request as newline-delimited JSON, starting after the sequence given by
+// after. Unless follow is false the stream stays open and delivers new events
+// as mutations are made, until the client disconnects.
+func (h *Handlers) streamChanges(c echo.Context) error {
+	opts := catalog.ChangeOptions{Collection: c.QueryParam("collection")}
+	if value := c.QueryParam("after"); value != "" {
+		after, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return errorResponse(c, http.StatusBadRequest, "after must be a sequence number")
+		}
+		opts.After = after
+	}
+	follow := true
+	if value := c.QueryParam("follow"); value != "" {
+		var err error
+		if follow, err = strconv.ParseBool(value); err != nil {
+			return errorResponse(c, http.StatusBadRequest, "follow must be a boolean")
+		}
+	}
+
+	// Report what can be checked up front before the stream starts
+	ctx := c.Request().Context()
+	if opts.Collection != "" {
+		name, err := h.catalogName(ctx, opts.Collection)
+		if err == nil {
+			_, err = h.catalog.Get(name)
+		}
+		if err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+		opts.Collection = name
+	}
+	if position := h.catalog.Position(); opts.After > position {
+		message := fmt.Sprintf("%v: %d is beyond the last position %d", catalog.ErrInvalidPosition, opts.After, position)
+		return errorResponse(c, http.StatusBadRequest, message)
+	}
+	if base := h.catalog.LogBase(); opts.After < base {
+		message := fmt.Sprintf("%v: records up to position %d are gone", catalog.ErrLogTruncated, base)
+		return errorResponse(c, http.StatusGone, message)
+	}
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	encoder := json.NewEncoder(response)
+	tenantID := h.tenantOf(ctx)
+	emit := func(event *catalog.ChangeEvent) error {
+		event, owned := tenantEvent(event, tenantID)
+		if !owned {
+			return nil
+		}
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		response.Flush()
+		return nil
+	}
+
+	var err error
+	if follow {
+		err = h.catalog.Subscribe(ctx, opts, emit)
+	} else {
+		_, err = h.catalog.Changes(ctx, opts, emit)
+	}
+
+	// The status is already sent, so errors can only end the stream
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, catalog.ErrCatalogClosed) {
+		c.Logger().Errorf("change stream ended: %v", err)
+	}
+	return nil
+}
+
+// tenantEvent returns a change event of a collection of a tenant with the
+// collection named as the tenant knows it, reporting whether the tenant owns it
+func tenantEvent(event *catalog.ChangeEvent, tenantID string) (*catalog.ChangeEvent, bool) {
+	owner, name := tenant.SplitCollectionName(event.Collection)
+	if owner != tenantID {
+		return nil, false
+	}
+	if name == event.Collection {
+		return event, true
+	}
+
+	renamed := *event
+	renamed.Collection = name
+	if event.Vector != nil {
+		vector := *event.Vector
+		vector.Collection = name
+		renamed.Vector = &vector
+	}
+	return &renamed, true
+}
@@ -0,0 +1,484 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding/providers"
+)
+
+// contractDimension is the dimension of the collections of the contract tests
+const contractDimension = 16
+
+// contractDocuments are the texts stored in the contract test collection
+var contractDocuments = map[string]string{
+	"leveldb": "leveldb stores sorted keys on disk",
+	"hnsw":    "hnsw graphs find approximate nearest neighbors",
+	"raft":    "raft replicates a log across nodes",
+}
+
+// newTestRouter serves a fresh catalog with the API routes
+func newTestRouter(t *testing.T) (*echo.Echo, *Handlers) {
+	t.Helper()
+
+	collections, err := catalog.New(catalog.DefaultConfig(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create catalog: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := collections.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	})
+
+	handlers := NewHandlers(collections)
+	t.Cleanup(func() { _ = handlers.Close() })
+	provider, err := providers.NewHashProvider(contractDimension)
+	if err != nil {
+		t.Fatalf("Failed to create embedding provider: %v", err)
+	}
+	if err := handlers.SetEmbeddingProvider(provider); err != nil {
+		t.Fatalf("Failed to set embedding provider: %v", err)
+	}
+	handlers.RebuildFinished(nil)
+	e := echo.New()
+	handlers.RegisterRoutes(e)
+	return e, handlers
+}
+
+// serve sends a request to the router; a body that is not a string is
+// encoded as JSON
+func serve(e *echo.Echo, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	switch value := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(value))
+	default:
+		data, _ := json.Marshal(value)
+		reader = bytes.NewReader(data)
+	}
+
+	request := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// embedText returns the hash embedding of a text in the contract dimension
+func embedText(t *testing.T, text string) []float64 {
+	t.Helper()
+
+	provider, err := providers.NewHashProvider(contractDimension)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	response, err := provider.GenerateEmbeddings(context.Background(), &embedding.EmbeddingRequest{Texts: []string{text}})
+	if err != nil {
+		t.Fatalf("Failed to embed text: %v", err)
+	}
+	return response.Embeddings[0]
+}
+
+// matchOperation returns the operation of the specification that serves a
+// method and request path
+func matchOperation(spec *openAPISpec, method, path string) *operation {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	for _, op := range spec.operations {
+		if op.Method != method {
+			continue
+		}
+		pattern := strings.Split(op.Path, "/")
+		if len(pattern) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, segment := range pattern {
+			if !strings.HasPrefix(segment, ":") && segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return op
+		}
+	}
+	return nil
+}
+
+func TestContract(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	spec := handlers.spec
+
+	vectors := make([]map[string]interface{}, 0, len(contractDocuments))
+	for id, text := range contractDocuments {
+		vectors = append(vectors, map[string]interface{}{
+			"id":        id,
+			"embedding": embedText(t, text),
+			"metadata":  map[string]interface{}{"text": text},
+		})
+	}
+	sort.Slice(vectors, func(i, j int) bool { return vectors[i]["id"].(string) < vectors[j]["id"].(string) })
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"health", http.MethodGet, "/health", nil, http.StatusOK},
+		{"ready", http.MethodGet, "/ready", nil, http.StatusOK},
+		{"openapi", http.MethodGet, "/openapi.yaml", nil, http.StatusOK},
+		{"docs", http.MethodGet, "/docs", nil, http.StatusOK},
+
+		{"create index", http.MethodPost, "/v1/indexes", map[string]interface{}{
+			"id": "docs", "type": "hnsw", "dimension": contractDimension, "max_elements": 1000,
+		}, http.StatusCreated},
+		{"create index without max_elements", http.MethodPost, "/v1/indexes", map[string]interface{}{
+			"id": "other", "type": "hnsw", "dimension": contractDimension,
+		}, http.StatusBadRequest},
+		{"create existing index", http.MethodPost, "/v1/indexes", map[string]interface{}{
+			"id": "docs", "type": "hnsw", "dimension": contractDimension, "max_elements": 1000,
+		}, http.StatusConflict},
+		{"list indexes", http.MethodGet, "/v1/indexes", nil, http.StatusOK},
+		{"get index", http.MethodGet, "/v1/indexes/docs", nil, http.StatusOK},
+		{"get missing index", http.MethodGet, "/v1/indexes/missing", nil, http.StatusNotFound},
+
+		{"insert vectors", http.MethodPost, "/v1/indexes/docs/vectors", map[string]interface{}{
+			"vectors": vectors,
+		}, http.StatusOK},
+		{"insert vectors with a wrong embedding", http.MethodPost, "/v1/indexes/docs/vectors", map[string]interface{}{
+			"vectors": []interface{}{map[string]interface{}{"id": "x", "embedding": "not an array"}},
+		}, http.StatusBadRequest},
+		{"insert vectors into a missing index", http.MethodPost, "/v1/indexes/missing/vectors", map[string]interface{}{
+			"vectors": []interface{}{map[string]interface{}{"id": "x", "embedding": []float64{1}}},
+		}, http.StatusNotFound},
+		{"insert an existing vector", http.MethodPost, "/v1/indexes/docs/vectors", map[string]interface{}{
+			"vectors": []interface{}{map[string]interface{}{
+				"id": "raft", "embedding": embedText(t, "raft"), "expected_version": 0,
+			}},
+		}, http.StatusConflict},
+		{"create a small index", http.MethodPost, "/v1/indexes", map[string]interface{}{
+			"id": "small", "type": "hnsw", "dimension": contractDimension, "max_elements": 1,
+		}, http.StatusCreated},
+		{"insert vectors into a full index", http.MethodPost, "/v1/indexes/small/vectors", map[string]interface{}{
+			"vectors": vectors[:2],
+		}, http.StatusInsufficientStorage},
+		{"write a batch to a full index", http.MethodPost, "/v1/indexes/small/batch", map[string]interface{}{
+			"writes": []interface{}{
+				map[string]interface{}{"op": "put", "vector": vectors[0]},
+				map[string]interface{}{"op": "put", "vector": vectors[1]},
+			},
+		}, http.StatusInsufficientStorage},
+		{"write batch", http.MethodPost, "/v1/indexes/docs/batch", map[string]interface{}{
+			"writes": []interface{}{
+				map[string]interface{}{"op": "put", "vector": map[string]interface{}{
+					"id": "paxos", "embedding": embedText(t, "paxos agrees on a log"),
+					"metadata": map[string]interface{}{"text": "paxos agrees on a log"},
+				}},
+				map[string]interface{}{"op": "delete", "id": "missing"},
+			},
+		}, http.StatusOK},
+		{"write batch with an unknown op", http.MethodPost, "/v1/indexes/docs/batch", map[string]interface{}{
+			"writes": []interface{}{map[string]interface{}{"op": "merge", "id": "raft"}},
+		}, http.StatusBadRequest},
+		{"write a stale batch", http.MethodPost, "/v1/indexes/docs/batch", map[string]interface{}{
+			"writes": []interface{}{map[string]interface{}{"op": "delete", "id": "raft", "expected_version": 42}},
+		}, http.StatusConflict},
+		{"write batch to a missing index", http.MethodPost, "/v1/indexes/missing/batch", map[string]interface{}{
+			"writes": []interface{}{map[string]interface{}{"op": "delete", "id": "raft"}},
+		}, http.StatusNotFound},
+		{"ingest vectors", http.MethodPost, "/v1/indexes/docs/ingest?chunk_size=2&embed=true",
+			`{"id": "gossip", "text": "gossip spreads state between nodes"}` + "\n", http.StatusOK},
+		{"ingest with a bad chunk size", http.MethodPost, "/v1/indexes/docs/ingest?chunk_size=0", "", http.StatusBadRequest},
+		{"ingest into a missing index", http.MethodPost, "/v1/indexes/missing/ingest", "", http.StatusNotFound},
+		{"search", http.MethodPost, "/v1/indexes/docs/search", map[string]interface{}{
+			"query": embedText(t, "nearest neighbors"), "k": 2,
+		}, http.StatusOK},
+		{"search with k zero", http.MethodPost, "/v1/indexes/docs/search", map[string]interface{}{
+			"query": embedText(t, "nearest neighbors"), "k": 0,
+		}, http.StatusBadRequest},
+		{"search a missing index", http.MethodPost, "/v1/indexes/missing/search", map[string]interface{}{
+			"query": embedText(t, "nearest neighbors"),
+		}, http.StatusNotFound},
+
+		{"rag query expansion", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "query_expansion", "query": "fast search",
+		}, http.StatusOK},
+		{"rag batch search", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "batch_search", "query": "nearest neighbors", "collection": "docs",
+		}, http.StatusOK},
+		{"rag context retrieval", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "context_retrieval", "query": "replicated log", "collection": "docs",
+			"context": map[string]interface{}{"user_id": "u1", "domain": "technology"},
+		}, http.StatusOK},
+		{"rag result reranking", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "result_reranking", "query": "sorted keys", "collection": "docs",
+			"rag_config": map[string]interface{}{
+				"reranking_config": map[string]interface{}{"strategies": []string{"hybrid"}, "max_results": 2},
+			},
+		}, http.StatusOK},
+		{"rag end to end", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "end_to_end_rag", "query": "how to find nearest neighbors", "collection": "docs",
+			"rag_config": map[string]interface{}{
+				"enable_context_awareness": false,
+				"search_config":            map[string]interface{}{"max_results": 3, "index_type": "hnsw"},
+			},
+		}, http.StatusOK},
+		{"rag query without a collection", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "batch_search", "query": "nearest neighbors",
+		}, http.StatusBadRequest},
+		{"rag query with an unknown operation", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "summarize", "query": "nearest neighbors", "collection": "docs",
+		}, http.StatusBadRequest},
+		{"rag query of a missing collection", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+			"operation": "batch_search", "query": "nearest neighbors", "collection": "missing",
+		}, http.StatusNotFound},
+		{"stream rag query", http.MethodPost, "/v1/rag/query/stream", map[string]interface{}{
+			"operation": "end_to_end_rag", "query": "how to find nearest neighbors", "collection": "docs",
+		}, http.StatusOK},
+		{"stream rag query of a missing collection", http.MethodPost, "/v1/rag/query/stream", map[string]interface{}{
+			"operation": "end_to_end_rag", "query": "nearest neighbors", "collection": "missing",
+		}, http.StatusNotFound},
+		{"rag batch", http.MethodPost, "/v1/rag/batch", map[string]interface{}{
+			"operation": "end_to_end_rag", "collection": "docs", "batch_size": 2, "max_concurrent": 2, "timeout": "30s",
+			"queries": []string{"nearest neighbors", "sorted keys", "replicated log"},
+		}, http.StatusOK},
+		{"rag batch with a bad timeout", http.MethodPost, "/v1/rag/batch", map[string]interface{}{
+			"operation": "batch_search", "collection": "docs", "timeout": "soon", "queries": []string{"log"},
+		}, http.StatusBadRequest},
+		{"rag batch of a missing collection", http.MethodPost, "/v1/rag/batch", map[string]interface{}{
+			"operation": "batch_search", "collection": "missing", "queries": []string{"log"},
+		}, http.StatusNotFound},
+		{"rag capabilities", http.MethodGet, "/v1/rag/capabilities", nil, http.StatusOK},
+		{"rag statistics", http.MethodGet, "/v1/rag/statistics", nil, http.StatusOK},
+
+		{"storage stats", http.MethodGet, "/v1/storage/stats", nil, http.StatusOK},
+		{"compact storage", http.MethodPost, "/v1/storage/compact", nil, http.StatusOK},
+		{"changes", http.MethodGet, "/v1/changes?follow=false", nil, http.StatusOK},
+		{"changes after a bad sequence", http.MethodGet, "/v1/changes?after=soon", nil, http.StatusBadRequest},
+		{"changes of a missing collection", http.MethodGet, "/v1/changes?follow=false&collection=missing", nil, http.StatusNotFound},
+		{"backup", http.MethodPost, "/v1/admin/backup", nil, http.StatusOK},
+		{"backup with a bad flag", http.MethodPost, "/v1/admin/backup?incremental=maybe", nil, http.StatusBadRequest},
+		{"metrics", http.MethodGet, "/v1/metrics", nil, http.StatusOK},
+		{"create API key", http.MethodPost, "/v1/admin/keys", map[string]interface{}{
+			"name": "reader", "permissions": []string{"collections:read", "vectors:*"},
+		}, http.StatusCreated},
+		{"create API key with an unknown permission", http.MethodPost, "/v1/admin/keys", map[string]interface{}{
+			"name": "reader", "permissions": []string{"collections:own"},
+		}, http.StatusBadRequest},
+		{"list API keys", http.MethodGet, "/v1/admin/keys?limit=10", nil, http.StatusOK},
+		{"get missing API key", http.MethodGet, "/v1/admin/keys/missing", nil, http.StatusNotFound},
+		{"revoke missing API key", http.MethodPost, "/v1/admin/keys/missing/revoke", nil, http.StatusNotFound},
+		{"delete missing API key", http.MethodDelete, "/v1/admin/keys/missing", nil, http.StatusNotFound},
+		{"create tenant", http.MethodPost, "/v1/admin/tenants", map[string]interface{}{
+			"id": "acme", "name": "Acme", "domain": "acme.example.com", "plan": "pro",
+		}, http.StatusCreated},
+		{"create tenant with an invalid ID", http.MethodPost, "/v1/admin/tenants", map[string]interface{}{
+			"id": "acme.corp", "name": "Acme",
+		}, http.StatusBadRequest},
+		{"create existing tenant", http.MethodPost, "/v1/admin/tenants", map[string]interface{}{
+			"id": "acme", "name": "Acme",
+		}, http.StatusConflict},
+		{"list tenants", http.MethodGet, "/v1/admin/tenants?limit=10", nil, http.StatusOK},
+		{"get tenant", http.MethodGet, "/v1/admin/tenants/acme", nil, http.StatusOK},
+		{"get missing tenant", http.MethodGet, "/v1/admin/tenants/missing", nil, http.StatusNotFound},
+		{"update tenant settings", http.MethodPut, "/v1/admin/tenants/acme/settings", map[string]interface{}{
+			"api_rate_limit": 50, "enable_analytics": true,
+		}, http.StatusOK},
+		{"update settings of a missing tenant", http.MethodPut, "/v1/admin/tenants/missing/settings", map[string]interface{}{
+			"api_rate_limit": 50,
+		}, http.StatusNotFound},
+		{"suspend tenant", http.MethodPut, "/v1/admin/tenants/acme/status", map[string]interface{}{
+			"status": "suspended", "reason": "payment overdue",
+		}, http.StatusOK},
+		{"update tenant to an unknown status", http.MethodPut, "/v1/admin/tenants/acme/status", map[string]interface{}{
+			"status": "archived",
+		}, http.StatusBadRequest},
+		{"update status of a missing tenant", http.MethodPut, "/v1/admin/tenants/missing/status", map[string]interface{}{
+			"status": "active",
+		}, http.StatusNotFound},
+
+		{"submit job", http.MethodPost, "/v1/jobs", map[string]interface{}{
+			"type": "compact", "params": map[string]interface{}{"collection": "docs"},
+		}, http.StatusAccepted},
+		{"submit job of an unknown type", http.MethodPost, "/v1/jobs", map[string]interface{}{
+			"type": "vacuum",
+		}, http.StatusBadRequest},
+		{"submit job with unknown parameters", http.MethodPost, "/v1/jobs", map[string]interface{}{
+			"type": "reindex", "params": map[string]interface{}{"index": "docs"},
+		}, http.StatusBadRequest},
+		{"submit job of a missing collection", http.MethodPost, "/v1/jobs", map[string]interface{}{
+			"type": "import", "params": map[string]interface{}{"collection": "missing", "records": []interface{}{}},
+		}, http.StatusNotFound},
+		{"list jobs", http.MethodGet, "/v1/jobs?limit=10", nil, http.StatusOK},
+		{"get missing job", http.MethodGet, "/v1/jobs/missing", nil, http.StatusNotFound},
+		{"cancel missing job", http.MethodDelete, "/v1/jobs/missing", nil, http.StatusNotFound},
+
+		{"list webhook deliveries", http.MethodGet, "/v1/webhooks/deliveries?state=delivered&limit=10", nil, http.StatusOK},
+		{"list webhook deliveries in an unknown state", http.MethodGet, "/v1/webhooks/deliveries?state=lost", nil, http.StatusBadRequest},
+		{"list webhook dead letters", http.MethodGet, "/v1/webhooks/dead-letters", nil, http.StatusOK},
+		{"redeliver missing webhook", http.MethodPost, "/v1/webhooks/dead-letters/missing/redeliver", nil, http.StatusNotFound},
+
+		{"delete index", http.MethodDelete, "/v1/indexes/docs", nil, http.StatusOK},
+		{"delete missing index", http.MethodDelete, "/v1/indexes/docs", nil, http.StatusNotFound},
+	}
+
+	covered := make(map[*operation]bool)
+	for _, tc := range cases {
+		recorder := serve(e, tc.method, tc.path, tc.body)
+		if recorder.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tc.name, tc.status, recorder.Code, recorder.Body.String())
+			continue
+		}
+
+		op := matchOperation(spec, tc.method, tc.path)
+		if op == nil {
+			t.Errorf("%s: %s %s is not in the specification", tc.name, tc.method, tc.path)
+			continue
+		}
+		covered[op] = true
+
+		schema, documented := op.Responses[tc.status]
+		if !documented {
+			t.Errorf("%s: status %d of %s is not documented", tc.name, tc.status, op.ID)
+			continue
+		}
+		if schema == nil {
+			continue
+		}
+		body, err := decodeJSON(recorder.Body.Bytes())
+		if err != nil {
+			t.Errorf("%s: response is not JSON: %v", tc.name, err)
+			continue
+		}
+		if err := schema.validate("", body); err != nil {
+			t.Errorf("%s: response does not match the specification: %v\n%s", tc.name, err, recorder.Body.String())
+		}
+	}
+
+	// Every operation of the specification is served and exercised
+	for _, op := range spec.operations {
+		if !covered[op] {
+			t.Errorf("Operation %s (%s %s) was not exercised", op.ID, op.Method, op.Path)
+		}
+	}
+	for _, route := range e.Routes() {
+		if spec.operation(route.Method, route.Path) == nil {
+			t.Errorf("Route %s %s is not in the specification", route.Method, route.Path)
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	e, _ := newTestRouter(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    interface{}
+		message string
+	}{
+		{"invalid JSON", "/v1/indexes", "{", "request body must be valid JSON"},
+		{"missing property", "/v1/indexes", map[string]interface{}{
+			"id": "docs", "type": "hnsw", "dimension": 4,
+		}, "max_elements is required"},
+		{"pattern", "/v1/indexes", map[string]interface{}{
+			"id": "no spaces", "type": "hnsw", "dimension": 4, "max_elements": 10,
+		}, "id must match"},
+		{"enum", "/v1/indexes", map[string]interface{}{
+			"id": "docs", "type": "flat", "dimension": 4, "max_elements": 10,
+		}, "type must be one of hnsw, ivf"},
+		{"maximum", "/v1/indexes", map[string]interface{}{
+			"id": "docs", "type": "hnsw", "dimension": 20000, "max_elements": 10,
+		}, "dimension must be at most 10000"},
+		{"integer", "/v1/indexes", map[string]interface{}{
+			"id": "docs", "type": "hnsw", "dimension": 4.5, "max_elements": 10,
+		}, "dimension must be an integer"},
+		{"nested array item", "/v1/indexes/docs/vectors", map[string]interface{}{
+			"vectors": []interface{}{map[string]interface{}{"id": "x", "embedding": []interface{}{1, "two"}}},
+		}, "vectors[0].embedding[1] must be a number"},
+		{"min items", "/v1/indexes/docs/vectors", map[string]interface{}{
+			"vectors": []interface{}{},
+		}, "vectors must have at least 1 items"},
+		{"date-time", "/v1/indexes/docs/search", map[string]interface{}{
+			"query": []float64{1}, "as_of": "yesterday",
+		}, "as_of must be an RFC 3339 date-time"},
+		{"additional properties", "/v1/rag/query", map[string]interface{}{
+			"operation": "batch_search", "query": "log", "collection": "docs",
+			"rag_config": map[string]interface{}{"reranking_config": map[string]interface{}{
+				"weights": map[string]interface{}{"vector": "heavy"},
+			}},
+		}, "rag_config.reranking_config.weights.vector must be a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(e, http.MethodPost, tt.path, tt.body)
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", recorder.Code, recorder.Body.String())
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			message, _ := response["error"].(string)
+			if !strings.Contains(message, tt.message) {
+				t.Errorf("Expected an error containing %q, got %q", tt.message, message)
+			}
+			if response["success"] != false || response["status"] != float64(http.StatusBadRequest) {
+				t.Errorf("Expected the error envelope, got %v", response)
+			}
+		})
+	}
+
+	// Unknown routes are reported in the error envelope too
+	recorder := serve(e, http.MethodGet, "/v1/unknown", nil)
+	if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), `"success":false`) {
+		t.Errorf("Expected a 404 error envelope, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestRAGQuerySearchesCollection(t *testing.T) {
+	e, _ := newTestRouter(t)
+	createRAGIndex(t, e)
+
+	recorder := serve(e, http.MethodPost, "/v1/rag/query", map[string]interface{}{
+		"operation": "batch_search", "query": "approximate nearest neighbors", "collection": "docs",
+		"rag_config": map[string]interface{}{"search_config": map[string]interface{}{"max_results": 1}},
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to query: %s", recorder.Body.String())
+	}
+
+	var response struct {
+		Results []struct {
+			Vector struct {
+				ID string `json:"id"`
+			} `json:"vector"`
+			Rank int `json:"rank"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Vector.ID != "hnsw" || response.Results[0].Rank != 1 {
+		t.Errorf("Expected hnsw as the only result, got %+v", response.Results)
+	}
+}
@@ -0,0 +1,14 @@
+package api
+
+import "errors"
+
+// API-related errors
+var (
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrInvalidRAGRequest = errors.New("invalid RAG request")
+	ErrEmbeddingFailed   = errors.New("embedding failed")
+	ErrNoEmbeddingModel  = errors.New("no embedding model is configured")
+	ErrUnauthenticated   = errors.New("authentication required")
+	ErrPermissionDenied  = errors.New("permission denied")
+	ErrRateLimited       = errors.New("rate limit exceeded")
+)
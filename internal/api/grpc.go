@@ -0,0 +1,605 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	vjvectorv1 "github.com/vijaynallagatla/vjvector/api/vjvector/v1"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ragOperationNames maps the RAG operations of the gRPC API to those of the
+// REST API
+var ragOperationNames = map[vjvectorv1.RAGOperation]models.RAGOperation{
+	vjvectorv1.RAGOperation_RAG_OPERATION_QUERY_EXPANSION:   models.RAGOperationQueryExpansion,
+	vjvectorv1.RAGOperation_RAG_OPERATION_RESULT_RERANKING:  models.RAGOperationResultReranking,
+	vjvectorv1.RAGOperation_RAG_OPERATION_CONTEXT_RETRIEVAL: models.RAGOperationContextRetrieval,
+	vjvectorv1.RAGOperation_RAG_OPERATION_END_TO_END_RAG:    models.RAGOperationEndToEndRAG,
+	vjvectorv1.RAGOperation_RAG_OPERATION_BATCH_SEARCH:      models.RAGOperationBatchSearch,
+	vjvectorv1.RAGOperation_RAG_OPERATION_BATCH_RERANK:      models.RAGOperationBatchRerank,
+}
+
+// NewGRPCServer creates a gRPC server serving the collection, vector, search
+// and RAG services. The services perform the operations of the REST API,
+// authenticate, scope to their tenant and rate limit calls like it and count
+// their requests in its metrics.
+func (h *Handlers) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(h.unaryInterceptor, h.authUnaryInterceptor, h.tenantUnaryInterceptor, h.rateLimitUnaryInterceptor),
+		grpc.ChainStreamInterceptor(h.streamInterceptor, h.authStreamInterceptor, h.tenantStreamInterceptor, h.rateLimitStreamInterceptor),
+	}, opts...)
+
+	server := grpc.NewServer(opts...)
+	vjvectorv1.RegisterCollectionServiceServer(server, &collectionService{h: h})
+	vjvectorv1.RegisterVectorServiceServer(server, &vectorService{h: h})
+	vjvectorv1.RegisterSearchServiceServer(server, &searchService{h: h})
+	vjvectorv1.RegisterRAGServiceServer(server, &ragService{h: h})
+	return server
+}
+
+// unaryInterceptor counts unary calls and turns their panics into errors
+func (h *Handlers) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (response interface{}, err error) {
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = status.Errorf(codes.Internal, "panic in %s: %v", info.FullMethod, recovered)
+		}
+		h.recordRequest(time.Since(start), err == nil)
+	}()
+	return handler(ctx, req)
+}
+
+// streamInterceptor counts streaming calls and turns their panics into errors
+func (h *Handlers) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = status.Errorf(codes.Internal, "panic in %s: %v", info.FullMethod, recovered)
+		}
+		h.recordRequest(time.Since(start), err == nil)
+	}()
+	return handler(srv, stream)
+}
+
+// grpcError converts the errors of the API operations to gRPC status errors
+func grpcError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, catalog.ErrCollectionExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, catalog.ErrVersionConflict):
+		code = codes.Aborted
+	default:
+		switch errorStatus(err) {
+		case http.StatusBadRequest:
+			code = codes.InvalidArgument
+		case http.StatusUnauthorized:
+			code = codes.Unauthenticated
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusTooManyRequests:
+			code = codes.ResourceExhausted
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusGatewayTimeout:
+			code = codes.DeadlineExceeded
+		case http.StatusNotImplemented:
+			code = codes.FailedPrecondition
+		case http.StatusInsufficientStorage:
+			code = codes.ResourceExhausted
+		}
+	}
+	return status.Error(code, err.Error())
+}
+
+// collectionService serves the collection service of the gRPC API
+type collectionService struct {
+	vjvectorv1.UnimplementedCollectionServiceServer
+	h *Handlers
+}
+
+// CreateCollection creates a collection and its index
+func (s *collectionService) CreateCollection(ctx context.Context, req *vjvectorv1.CreateCollectionRequest) (*vjvectorv1.Collection, error) {
+	_, _, err := s.h.createCollection(ctx, &models.CreateIndexRequest{
+		ID:             req.Name,
+		Type:           req.Type,
+		Dimension:      int(req.Dimension),
+		MaxElements:    int(req.MaxElements),
+		M:              int(req.M),
+		EfConstruction: int(req.EfConstruction),
+		EfSearch:       int(req.EfSearch),
+		MaxLayers:      int(req.MaxLayers),
+		NumClusters:    int(req.NumClusters),
+		ClusterSize:    int(req.ClusterSize),
+		DistanceMetric: req.DistanceMetric,
+		Normalize:      req.Normalize,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	info, err := s.h.collectionInfo(ctx, req.Name)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return protoCollection(info), nil
+}
+
+// GetCollection returns a collection with its index statistics
+func (s *collectionService) GetCollection(ctx context.Context, req *vjvectorv1.GetCollectionRequest) (*vjvectorv1.Collection, error) {
+	info, err := s.h.collectionInfo(ctx, req.Name)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return protoCollection(info), nil
+}
+
+// ListCollections lists every collection of the tenant with its index statistics
+func (s *collectionService) ListCollections(ctx context.Context, _ *vjvectorv1.ListCollectionsRequest) (*vjvectorv1.ListCollectionsResponse, error) {
+	infos, err := s.h.listCollections(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	response := &vjvectorv1.ListCollectionsResponse{Collections: make([]*vjvectorv1.Collection, len(infos))}
+	for i, info := range infos {
+		response.Collections[i] = protoCollection(info)
+	}
+	return response, nil
+}
+
+// DeleteCollection deletes a collection together with its index and vectors
+func (s *collectionService) DeleteCollection(ctx context.Context, req *vjvectorv1.DeleteCollectionRequest) (*vjvectorv1.DeleteCollectionResponse, error) {
+	if err := s.h.deleteCollection(ctx, req.Name); err != nil {
+		return nil, grpcError(err)
+	}
+	return &vjvectorv1.DeleteCollectionResponse{Name: req.Name}, nil
+}
+
+// vectorService serves the vector service of the gRPC API
+type vectorService struct {
+	vjvectorv1.UnimplementedVectorServiceServer
+	h *Handlers
+}
+
+// Upsert writes vectors to a collection atomically
+func (s *vectorService) Upsert(ctx context.Context, req *vjvectorv1.UpsertRequest) (*vjvectorv1.UpsertResponse, error) {
+	outcome, err := s.put(ctx, req.Collection, req.Vectors)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &vjvectorv1.UpsertResponse{
+		Position:     outcome.result.Position,
+		Versions:     outcome.result.Versions,
+		TotalVectors: outcome.totalVectors,
+		WriteTime:    durationpb.New(outcome.elapsed),
+	}, nil
+}
+
+// Delete deletes vectors from a collection atomically
+func (s *vectorService) Delete(ctx context.Context, req *vjvectorv1.DeleteRequest) (*vjvectorv1.DeleteResponse, error) {
+	writes, err := deleteWrites(req.Ids)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	outcome, err := s.h.write(ctx, req.Collection, writes)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &vjvectorv1.DeleteResponse{
+		Position:     outcome.result.Position,
+		Deleted:      outcome.result.Deleted,
+		TotalVectors: outcome.totalVectors,
+	}, nil
+}
+
+// Get returns the stored vectors of a collection with the given IDs
+func (s *vectorService) Get(ctx context.Context, req *vjvectorv1.GetRequest) (*vjvectorv1.GetResponse, error) {
+	vectors, err := s.h.getVectors(ctx, req.Collection, req.Ids)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	response := &vjvectorv1.GetResponse{Vectors: make([]*vjvectorv1.Vector, len(vectors))}
+	for i, vector := range vectors {
+		converted, err := protoVector(vector.ID, vector.Embedding, vector.Metadata, vector.ExpiresAt)
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		converted.Version = vector.Version
+		response.Vectors[i] = converted
+	}
+	return response, nil
+}
+
+// BulkInsert writes the vectors of every request of a stream as it arrives,
+// each request atomically
+func (s *vectorService) BulkInsert(stream grpc.ClientStreamingServer[vjvectorv1.BulkInsertRequest, vjvectorv1.BulkInsertResponse]) error {
+	start := time.Now()
+	response := &vjvectorv1.BulkInsertResponse{}
+	var collection string
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case collection == "" && req.Collection == "":
+			return status.Error(codes.InvalidArgument, "the first request must name the collection")
+		case collection == "":
+			collection = req.Collection
+		case req.Collection != "" && req.Collection != collection:
+			return status.Errorf(codes.InvalidArgument, "request %d names collection %s, not %s",
+				response.Batches+1, req.Collection, collection)
+		}
+		if len(req.Vectors) == 0 {
+			continue
+		}
+
+		outcome, err := s.put(stream.Context(), collection, req.Vectors)
+		if err != nil {
+			return grpcError(fmt.Errorf("request %d: %w", response.Batches+1, err))
+		}
+		response.VectorsAdded += int64(len(req.Vectors))
+		response.Batches++
+		response.Position = outcome.result.Position
+		response.TotalVectors = outcome.totalVectors
+	}
+	if response.Batches == 0 {
+		return status.Error(codes.InvalidArgument, "at least one vector is required")
+	}
+
+	response.InsertTime = durationpb.New(time.Since(start))
+	s.h.publishBulkWrite(stream.Context(), models.VectorsBulkEvent{
+		Collection:   collection,
+		Operation:    "bulk_insert",
+		Written:      int(response.VectorsAdded),
+		TotalVectors: response.TotalVectors,
+	})
+	return stream.SendAndClose(response)
+}
+
+// put writes vectors to a collection atomically
+func (s *vectorService) put(ctx context.Context, collection string, vectors []*vjvectorv1.Vector) (*writeOutcome, error) {
+	converted := make([]*models.Vector, len(vectors))
+	for i, vector := range vectors {
+		v, err := modelVector(vector)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = v
+	}
+
+	writes, err := putWrites(collection, converted)
+	if err != nil {
+		return nil, err
+	}
+	return s.h.write(ctx, collection, writes)
+}
+
+// searchService serves the search service of the gRPC API
+type searchService struct {
+	vjvectorv1.UnimplementedSearchServiceServer
+	h *Handlers
+}
+
+// Search searches a collection for the vectors most similar to a query
+func (s *searchService) Search(ctx context.Context, req *vjvectorv1.SearchRequest) (*vjvectorv1.SearchResponse, error) {
+	response, err := s.search(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return response, nil
+}
+
+// BatchSearch performs the searches of a batch concurrently, failing with the
+// error of the first search that fails
+func (s *searchService) BatchSearch(ctx context.Context, req *vjvectorv1.BatchSearchRequest) (*vjvectorv1.BatchSearchResponse, error) {
+	if len(req.Searches) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one search is required")
+	}
+
+	responses := make([]*vjvectorv1.SearchResponse, len(req.Searches))
+	failures := make([]error, len(req.Searches))
+	semaphore := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, search := range req.Searches {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, search *vjvectorv1.SearchRequest) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			responses[i], failures[i] = s.search(ctx, search)
+		}(i, search)
+	}
+	wg.Wait()
+
+	for i, err := range failures {
+		if err != nil {
+			return nil, grpcError(fmt.Errorf("searches[%d]: %w", i, err))
+		}
+	}
+	return &vjvectorv1.BatchSearchResponse{Responses: responses}, nil
+}
+
+// search performs a search of the search service
+func (s *searchService) search(ctx context.Context, req *vjvectorv1.SearchRequest) (*vjvectorv1.SearchResponse, error) {
+	search := &models.SearchRequest{Query: float64s(req.Query), K: int(req.K)}
+	if req.AsOf != nil {
+		if err := req.AsOf.CheckValid(); err != nil {
+			return nil, fmt.Errorf("%w: as_of: %v", ErrInvalidRequest, err)
+		}
+		asOf := req.AsOf.AsTime()
+		search.AsOf = &asOf
+	}
+
+	start := time.Now()
+	results, err := s.h.search(ctx, req.Collection, search)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+
+	response := &vjvectorv1.SearchResponse{
+		Results:    make([]*vjvectorv1.SearchResult, 0, len(results)),
+		SearchTime: durationpb.New(elapsed),
+	}
+	for _, result := range results {
+		converted := &vjvectorv1.SearchResult{Score: result.Score, Distance: result.Distance}
+		if result.Vector != nil {
+			converted.VectorId = result.Vector.ID
+			if converted.Metadata, err = protoStruct(result.Vector.Metadata); err != nil {
+				return nil, err
+			}
+		}
+		response.Results = append(response.Results, converted)
+	}
+	return response, nil
+}
+
+// ragService serves the RAG service of the gRPC API
+type ragService struct {
+	vjvectorv1.UnimplementedRAGServiceServer
+	h *Handlers
+}
+
+// Query performs a RAG operation for a single query
+func (s *ragService) Query(ctx context.Context, req *vjvectorv1.RAGQueryRequest) (*vjvectorv1.RAGQueryResponse, error) {
+	operation, exists := ragOperationNames[req.Operation]
+	if !exists {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown RAG operation %s", req.Operation)
+	}
+
+	response, err := s.h.processRAG(ctx, &models.RAGRequest{
+		Operation:  operation,
+		Query:      req.Query,
+		Context:    req.Context.AsMap(),
+		Collection: req.Collection,
+		Options:    req.Options.AsMap(),
+		RAGConfig:  modelRAGConfig(req.RagConfig),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	converted := &vjvectorv1.RAGQueryResponse{
+		Operation:           req.Operation,
+		Query:               response.Query,
+		OriginalQuery:       response.OriginalQuery,
+		ExpandedQueries:     response.ExpandedQueries,
+		ContextEnhancements: response.ContextEnhancements,
+		ProcessingTime:      durationpb.New(response.ProcessingTime),
+		Confidence:          response.Confidence,
+	}
+	if converted.Results, err = protoRAGResults(response.Results); err != nil {
+		return nil, grpcError(err)
+	}
+	if converted.RerankedResults, err = protoRAGResults(response.RerankedResults); err != nil {
+		return nil, grpcError(err)
+	}
+	if converted.Metadata, err = protoStruct(response.Metadata); err != nil {
+		return nil, grpcError(err)
+	}
+	return converted, nil
+}
+
+// modelRAGConfig converts the RAG configuration of a gRPC request
+func modelRAGConfig(config *vjvectorv1.RAGConfig) models.RAGConfig {
+	if config == nil {
+		return models.RAGConfig{}
+	}
+
+	converted := models.RAGConfig{
+		EnableQueryExpansion:   config.EnableQueryExpansion,
+		EnableResultReranking:  config.EnableResultReranking,
+		EnableContextAwareness: config.EnableContextAwareness,
+	}
+	if expansion := config.QueryExpansionConfig; expansion != nil {
+		converted.QueryExpansionConfig = &models.QueryExpansionConfig{
+			Strategies:          expansion.Strategies,
+			MaxExpansions:       int(expansion.MaxExpansions),
+			SimilarityThreshold: expansion.SimilarityThreshold,
+		}
+		if len(expansion.DomainSynonyms) > 0 {
+			synonyms := make(map[string][]string, len(expansion.DomainSynonyms))
+			for word, terms := range expansion.DomainSynonyms {
+				synonyms[word] = terms.GetTerms()
+			}
+			converted.QueryExpansionConfig.DomainSynonyms = synonyms
+		}
+	}
+	if reranking := config.RerankingConfig; reranking != nil {
+		converted.RerankingConfig = &models.RerankingConfig{
+			Strategies:     reranking.Strategies,
+			Weights:        reranking.Weights,
+			MaxResults:     int(reranking.MaxResults),
+			SemanticWeight: reranking.SemanticWeight,
+			ContextWeight:  reranking.ContextWeight,
+			HybridWeight:   reranking.HybridWeight,
+		}
+	}
+	if contextual := config.ContextConfig; contextual != nil {
+		converted.ContextConfig = &models.ContextConfig{
+			UserContext:         contextual.UserContext,
+			DomainContext:       contextual.DomainContext,
+			TemporalContext:     contextual.TemporalContext,
+			LocationContext:     contextual.LocationContext,
+			ContextDecay:        contextual.ContextDecay,
+			ConfidenceThreshold: contextual.ConfidenceThreshold,
+		}
+	}
+	if search := config.SearchConfig; search != nil {
+		converted.SearchConfig = &models.SearchConfig{
+			SearchType:       search.SearchType,
+			IndexType:        search.IndexType,
+			SimilarityMetric: search.SimilarityMetric,
+			MaxResults:       int(search.MaxResults),
+			Threshold:        search.Threshold,
+			EnableFilters:    search.EnableFilters,
+		}
+		if search.Filters != nil {
+			converted.SearchConfig.Filters = search.Filters.AsMap()
+		}
+	}
+	return converted
+}
+
+// protoRAGResults converts ranked RAG results into those of the gRPC API
+func protoRAGResults(results []models.SearchResult) ([]*vjvectorv1.RAGResult, error) {
+	converted := make([]*vjvectorv1.RAGResult, len(results))
+	for i, result := range results {
+		metadata, err := protoStruct(result.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = &vjvectorv1.RAGResult{
+			Score:      result.Score,
+			Rank:       int32(result.Rank),
+			Similarity: result.Similarity,
+			Metadata:   metadata,
+		}
+		if vector := result.Vector; vector != nil {
+			if converted[i].Vector, err = protoVector(vector.ID, vector.Embedding, vector.Metadata, vector.ExpiresAt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return converted, nil
+}
+
+// protoCollection converts a collection into that of the gRPC API
+func protoCollection(info *models.IndexInfo) *vjvectorv1.Collection {
+	return &vjvectorv1.Collection{
+		Name:           info.ID,
+		Type:           info.Type,
+		Dimension:      int32(info.Dimension),
+		DistanceMetric: info.DistanceMetric,
+		TotalVectors:   info.TotalVectors,
+		MemoryUsage:    info.MemoryUsage,
+		IndexSize:      info.IndexSize,
+		AvgSearchTime:  info.AvgSearchTime,
+		AvgInsertTime:  info.AvgInsertTime,
+	}
+}
+
+// modelVector converts a vector of a gRPC request into a vector of a request
+func modelVector(vector *vjvectorv1.Vector) (*models.Vector, error) {
+	if vector == nil {
+		return nil, fmt.Errorf("%w: vectors must not be null", ErrInvalidRequest)
+	}
+
+	converted := &models.Vector{
+		ID:              vector.Id,
+		Embedding:       float64s(vector.Embedding),
+		TTLSeconds:      vector.TtlSeconds,
+		ExpectedVersion: vector.ExpectedVersion,
+	}
+	if vector.Metadata != nil {
+		converted.Metadata = vector.Metadata.AsMap()
+	}
+	if vector.ExpiresAt != nil {
+		if err := vector.ExpiresAt.CheckValid(); err != nil {
+			return nil, fmt.Errorf("%w: expires_at of vector %s: %v", ErrInvalidRequest, vector.Id, err)
+		}
+		expiresAt := vector.ExpiresAt.AsTime()
+		converted.ExpiresAt = &expiresAt
+	}
+	return converted, nil
+}
+
+// protoVector converts a vector into that of the gRPC API
+func protoVector(id string, embedding []float64, metadata map[string]interface{}, expiresAt *time.Time) (*vjvectorv1.Vector, error) {
+	converted := &vjvectorv1.Vector{Id: id, Embedding: make([]float32, len(embedding))}
+	for i, value := range embedding {
+		converted.Embedding[i] = float32(value)
+	}
+
+	var err error
+	if converted.Metadata, err = protoStruct(metadata); err != nil {
+		return nil, fmt.Errorf("metadata of vector %s: %w", id, err)
+	}
+	if expiresAt != nil {
+		converted.ExpiresAt = timestamppb.New(*expiresAt)
+	}
+	return converted, nil
+}
+
+// protoStruct converts a map into a struct, or nil when the map is empty.
+// Values a struct cannot hold, such as durations, are converted through
+// their JSON encoding.
+func protoStruct(values map[string]interface{}) (*structpb.Struct, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if converted, err := structpb.NewStruct(values); err == nil {
+		return converted, nil
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	converted := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// float64s converts the embeddings of the gRPC API, sent as 32-bit floats
+func float64s(values []float32) []float64 {
+	if values == nil {
+		return nil
+	}
+	converted := make([]float64, len(values))
+	for i, value := range values {
+		converted[i] = float64(value)
+	}
+	return converted
+}
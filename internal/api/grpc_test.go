@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	vjvectorv1 "github.com/vijaynallagatla/vjvector/api/vjvector/v1"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestGRPC serves a fresh catalog with the gRPC services over an in-memory
+// connection, returning a client connection to them and their handlers
+func newTestGRPC(t *testing.T) (*grpc.ClientConn, *Handlers) {
+	t.Helper()
+
+	_, handlers := newTestRouter(t)
+	listener := bufconn.Listen(1 << 20)
+	server := handlers.NewGRPCServer()
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, handlers
+}
+
+// float32s converts an embedding for the gRPC API
+func float32s(values []float64) []float32 {
+	converted := make([]float32, len(values))
+	for i, value := range values {
+		converted[i] = float32(value)
+	}
+	return converted
+}
+
+// expectCode fails the test unless err is a status error with code
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("Expected %s, got %v", code, err)
+	}
+}
+
+func TestGRPCServices(t *testing.T) {
+	conn, handlers := newTestGRPC(t)
+	ctx := context.Background()
+	collections := vjvectorv1.NewCollectionServiceClient(conn)
+	vectors := vjvectorv1.NewVectorServiceClient(conn)
+	search := vjvectorv1.NewSearchServiceClient(conn)
+
+	created, err := collections.CreateCollection(ctx, &vjvectorv1.CreateCollectionRequest{
+		Name: "docs", Type: "hnsw", Dimension: contractDimension, DistanceMetric: "cosine",
+	})
+	if err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+	if created.Name != "docs" || created.Dimension != contractDimension {
+		t.Errorf("Unexpected collection %v", created)
+	}
+	_, err = collections.CreateCollection(ctx, &vjvectorv1.CreateCollectionRequest{
+		Name: "docs", Type: "hnsw", Dimension: contractDimension,
+	})
+	expectCode(t, err, codes.AlreadyExists)
+
+	// Bulk insert streams the documents one request at a time
+	stream, err := vectors.BulkInsert(ctx)
+	if err != nil {
+		t.Fatalf("BulkInsert failed: %v", err)
+	}
+	collection := "docs"
+	for id, text := range contractDocuments {
+		metadata, _ := structpb.NewStruct(map[string]interface{}{"text": text})
+		err := stream.Send(&vjvectorv1.BulkInsertRequest{
+			Collection: collection,
+			Vectors:    []*vjvectorv1.Vector{{Id: id, Embedding: float32s(embedText(t, text)), Metadata: metadata}},
+		})
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		collection = ""
+	}
+	inserted, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("BulkInsert failed: %v", err)
+	}
+	if inserted.VectorsAdded != 3 || inserted.Batches != 3 || inserted.TotalVectors != 3 {
+		t.Errorf("Unexpected bulk insert response %v", inserted)
+	}
+
+	// Versions make upserts conditional
+	stale := uint64(0)
+	_, err = vectors.Upsert(ctx, &vjvectorv1.UpsertRequest{
+		Collection: "docs",
+		Vectors:    []*vjvectorv1.Vector{{Id: "raft", Embedding: float32s(embedText(t, "raft")), ExpectedVersion: &stale}},
+	})
+	expectCode(t, err, codes.Aborted)
+	upserted, err := vectors.Upsert(ctx, &vjvectorv1.UpsertRequest{
+		Collection: "docs",
+		Vectors:    []*vjvectorv1.Vector{{Id: "paxos", Embedding: float32s(embedText(t, "paxos"))}},
+	})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if upserted.Versions["paxos"] != 1 || upserted.TotalVectors != 4 {
+		t.Errorf("Unexpected upsert response %v", upserted)
+	}
+
+	got, err := vectors.Get(ctx, &vjvectorv1.GetRequest{Collection: "docs", Ids: []string{"hnsw", "missing"}})
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(got.Vectors) != 1 || got.Vectors[0].Id != "hnsw" || len(got.Vectors[0].Embedding) != contractDimension {
+		t.Fatalf("Unexpected vectors %v", got.Vectors)
+	}
+	if text := got.Vectors[0].Metadata.AsMap()["text"]; text != contractDocuments["hnsw"] {
+		t.Errorf("Expected the metadata of the vector, got %v", text)
+	}
+
+	// The REST and gRPC APIs serve the same collections
+	info, err := handlers.collectionInfo(context.Background(), "docs")
+	if err != nil || info.TotalVectors != 4 {
+		t.Errorf("Expected 4 vectors through the shared handlers, got %v (%v)", info, err)
+	}
+
+	found, err := search.Search(ctx, &vjvectorv1.SearchRequest{
+		Collection: "docs", Query: float32s(embedText(t, contractDocuments["raft"])), K: 2,
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(found.Results) != 2 || found.Results[0].VectorId != "raft" {
+		t.Errorf("Expected raft first of 2 results, got %v", found.Results)
+	}
+
+	batch, err := search.BatchSearch(ctx, &vjvectorv1.BatchSearchRequest{Searches: []*vjvectorv1.SearchRequest{
+		{Collection: "docs", Query: float32s(embedText(t, contractDocuments["leveldb"])), K: 1},
+		{Collection: "docs", Query: float32s(embedText(t, contractDocuments["hnsw"])), K: 1},
+	}})
+	if err != nil {
+		t.Fatalf("BatchSearch failed: %v", err)
+	}
+	if len(batch.Responses) != 2 || batch.Responses[0].Results[0].VectorId != "leveldb" ||
+		batch.Responses[1].Results[0].VectorId != "hnsw" {
+		t.Errorf("Unexpected batch search responses %v", batch.Responses)
+	}
+	_, err = search.BatchSearch(ctx, &vjvectorv1.BatchSearchRequest{Searches: []*vjvectorv1.SearchRequest{
+		{Collection: "docs", Query: float32s(embedText(t, "raft"))},
+		{Collection: "missing", Query: float32s(embedText(t, "raft"))},
+	}})
+	expectCode(t, err, codes.NotFound)
+
+	deleted, err := vectors.Delete(ctx, &vjvectorv1.DeleteRequest{Collection: "docs", Ids: []string{"paxos", "missing"}})
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(deleted.Deleted) != 1 || deleted.Deleted[0] != "paxos" || deleted.TotalVectors != 3 {
+		t.Errorf("Unexpected delete response %v", deleted)
+	}
+
+	listed, err := collections.ListCollections(ctx, &vjvectorv1.ListCollectionsRequest{})
+	if err != nil || len(listed.Collections) != 1 {
+		t.Fatalf("Expected 1 collection, got %v (%v)", listed, err)
+	}
+	if _, err := collections.DeleteCollection(ctx, &vjvectorv1.DeleteCollectionRequest{Name: "docs"}); err != nil {
+		t.Fatalf("DeleteCollection failed: %v", err)
+	}
+	_, err = collections.GetCollection(ctx, &vjvectorv1.GetCollectionRequest{Name: "docs"})
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestGRPCInvalidRequests(t *testing.T) {
+	conn, _ := newTestGRPC(t)
+	ctx := context.Background()
+	vectors := vjvectorv1.NewVectorServiceClient(conn)
+	search := vjvectorv1.NewSearchServiceClient(conn)
+	rag := vjvectorv1.NewRAGServiceClient(conn)
+
+	_, err := vectors.Upsert(ctx, &vjvectorv1.UpsertRequest{Collection: "docs"})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = vectors.Upsert(ctx, &vjvectorv1.UpsertRequest{Collection: "docs", Vectors: []*vjvectorv1.Vector{{}}})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = search.Search(ctx, &vjvectorv1.SearchRequest{Collection: "docs"})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = rag.Query(ctx, &vjvectorv1.RAGQueryRequest{Query: "raft"})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = rag.Query(ctx, &vjvectorv1.RAGQueryRequest{
+		Operation: vjvectorv1.RAGOperation_RAG_OPERATION_END_TO_END_RAG, Query: "raft",
+	})
+	expectCode(t, err, codes.InvalidArgument)
+
+	stream, err := vectors.BulkInsert(ctx)
+	if err != nil {
+		t.Fatalf("BulkInsert failed: %v", err)
+	}
+	if err := stream.Send(&vjvectorv1.BulkInsertRequest{Vectors: []*vjvectorv1.Vector{{Id: "a"}}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	_, err = stream.CloseAndRecv()
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+func TestGRPCRAGQuery(t *testing.T) {
+	conn, handlers := newTestGRPC(t)
+	ctx := context.Background()
+
+	if _, _, err := handlers.createCollection(context.Background(), &models.CreateIndexRequest{
+		ID: "docs", Type: "hnsw", Dimension: contractDimension,
+	}); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	vectors := vjvectorv1.NewVectorServiceClient(conn)
+	for id, text := range contractDocuments {
+		_, err := vectors.Upsert(ctx, &vjvectorv1.UpsertRequest{
+			Collection: "docs",
+			Vectors:    []*vjvectorv1.Vector{{Id: id, Embedding: float32s(embedText(t, text))}},
+		})
+		if err != nil {
+			t.Fatalf("Upsert failed: %v", err)
+		}
+	}
+
+	disabled := false
+	response, err := vjvectorv1.NewRAGServiceClient(conn).Query(ctx, &vjvectorv1.RAGQueryRequest{
+		Operation:  vjvectorv1.RAGOperation_RAG_OPERATION_END_TO_END_RAG,
+		Query:      contractDocuments["hnsw"],
+		Collection: "docs",
+		RagConfig: &vjvectorv1.RAGConfig{
+			EnableQueryExpansion:   &disabled,
+			EnableContextAwareness: &disabled,
+			SearchConfig:           &vjvectorv1.SearchConfig{MaxResults: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(response.Results) != 2 || response.Results[0].Vector.GetId() != "hnsw" || response.Results[0].Rank != 1 {
+		t.Errorf("Expected hnsw to rank first of 2 results, got %v", response.Results)
+	}
+	if len(response.RerankedResults) != 2 || response.ProcessingTime == nil {
+		t.Errorf("Expected reranked results and a processing time, got %v", response)
+	}
+	if response.Metadata.AsMap()["collection"] != "docs" {
+		t.Errorf("Expected the collection in the metadata, got %v", response.Metadata)
+	}
+}
@@ -1,34 +1,66 @@
 package api
 
 import (
-	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/vijaynallagatla/vjvector/pkg/batch"
-	"github.com/vijaynallagatla/vjvector/pkg/core"
+	apidocs "github.com/vijaynallagatla/vjvector/docs/api"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
 	"github.com/vijaynallagatla/vjvector/pkg/embedding"
-	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/enterprise"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
 	"github.com/vijaynallagatla/vjvector/pkg/metrics"
-	"github.com/vijaynallagatla/vjvector/pkg/rag"
-	"github.com/vijaynallagatla/vjvector/pkg/storage"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"github.com/vijaynallagatla/vjvector/pkg/webhook"
 )
 
-// simpleEmbeddingProvider moved to embedding_services.go
-
-// simpleVectorIndex moved to vector_index_impl.go
-
-// realEmbeddingService moved to embedding_services.go
-// realRAGEngine moved to embedding_services.go
-
 // Handlers represents the API handlers for VJVector
 type Handlers struct {
-	indexes        map[string]index.VectorIndex
-	storage        storage.StorageEngine
-	batchProcessor batch.BatchProcessor
-	ragEngine      rag.Engine
-	server         ServerInterface // Interface for accessing server metrics
+	catalog   *catalog.Catalog // Collections with their storage and indexes
+	spec      *openAPISpec     // Contract requests are validated against
+	server    ServerInterface  // Interface for accessing server metrics
+	readiness readiness        // Startup index rebuild reported by /ready
+	started   time.Time
+	requests  atomic.Int64
+
+	// embedding embeds the texts of RAG queries, ingested records and jobs
+	// with the model of embeddingProvider; texts cannot be embedded until
+	// SetEmbeddingProvider configures one
+	embedding         embedding.Service
+	embeddingProvider embedding.Provider
+
+	ragStats ragStats
+
+	// apiKeys holds the API keys requests are authenticated with once
+	// authentication is enabled, besides the bootstrap admin key
+	apiKeys     enterprise.APIKeyService
+	authEnabled bool
+	adminKey    string
+
+	// rateLimiter limits requests once rate limiting is enabled, with the
+	// API rate limits of the tenant settings held by tenants; tenantRates
+	// holds the limit last applied for each tenant
+	rateLimiter      enterprise.RateLimiter
+	tenants          tenant.TenantService
+	tenantRatesMutex sync.Mutex
+	tenantRates      map[string]int
+
+	// isolation scopes every request to its tenant, whose collections are
+	// stored in a namespace of their own
+	isolation tenant.TenantIsolationService
+
+	// jobs runs the long-running operations submitted as jobs
+	jobs *jobs.Manager
+
+	// webhooks sends the events of tenants to their webhooks, with
+	// defaultWebhooks those of the default tenant; quotaEvents holds when
+	// the last quota event of each tenant and endpoint was sent
+	webhooks         *webhook.Dispatcher
+	defaultWebhooks  []webhook.Target
+	quotaEventsMutex sync.Mutex
+	quotaEvents      map[string]time.Time
 }
 
 // ServerInterface defines methods for accessing server functionality
@@ -36,239 +68,52 @@ type ServerInterface interface {
 	Metrics() *metrics.PrometheusMetrics
 }
 
-// NewHandlers creates new API handlers
-func NewHandlers() *Handlers {
-	// Initialize storage
-	storageConfig := storage.StorageConfig{
-		Type:            storage.StorageTypeMemory,
-		DataPath:        "/tmp/vjvector_api",
-		PageSize:        4096,
-		MaxFileSize:     1024 * 1024 * 1024, // 1GB
-		BatchSize:       100,
-		WriteBufferSize: 64 * 1024 * 1024, // 64MB
-		CacheSize:       32 * 1024 * 1024, // 32MB
-		MaxOpenFiles:    1000,
-	}
-
-	factory := &storage.DefaultStorageFactory{}
-	storageEngine, err := factory.CreateStorage(storageConfig)
+// NewHandlers creates new API handlers serving the collections of the given catalog
+func NewHandlers(collections *catalog.Catalog) *Handlers {
+	spec, err := loadOpenAPISpec(apidocs.OpenAPI)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to create storage: %v", err))
+		panic(fmt.Sprintf("Failed to load OpenAPI specification: %v", err))
 	}
 
-	// Initialize real embedding service
-	embeddingConfig := &embedding.Config{
-		DefaultProvider: embedding.ProviderTypeLocal,
-		Timeout:         30 * time.Second,
-		MaxBatchSize:    100,
-		EnableFallback:  true,
-		FallbackOrder:   []embedding.ProviderType{embedding.ProviderTypeLocal},
-		Cache: embedding.CacheConfig{
-			Enabled: true,
-			TTL:     5 * time.Minute,
-			MaxSize: 1000,
-		},
-		RateLimiting: embedding.RateLimitConfig{
-			Enabled:           true,
-			RequestsPerMinute: 1000,
-			TokensPerMinute:   10000,
-			BurstSize:         100,
-		},
-		Retry: embedding.RetryConfig{
-			Enabled:       true,
-			MaxRetries:    3,
-			InitialDelay:  1 * time.Second,
-			MaxDelay:      10 * time.Second,
-			BackoffFactor: 2.0,
-		},
+	tenants := tenant.NewDefaultTenantManager()
+	h := &Handlers{
+		catalog:   collections,
+		spec:      spec,
+		started:   time.Now(),
+		apiKeys:   enterprise.NewDefaultAPIKeyService(),
+		tenants:   tenants,
+		isolation: tenant.NewDefaultTenantIsolation(tenants),
 	}
-
-	embeddingService, err := embedding.NewService(embeddingConfig)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create embedding service: %v", err))
+	if h.webhooks, err = webhook.NewDispatcher(webhook.DefaultConfig(""), h.webhookTargets, nil); err != nil {
+		panic(fmt.Sprintf("Failed to create webhook dispatcher: %v", err))
 	}
-
-	// Create a simple functional embedding provider for testing
-	simpleProvider := &simpleEmbeddingProvider{
-		dimension: 384,
+	if h.jobs, err = h.newJobManager(jobs.DefaultConfig("")); err != nil {
+		panic(fmt.Sprintf("Failed to create job manager: %v", err))
 	}
+	return h
+}
 
-	if err := embeddingService.RegisterProvider(simpleProvider); err != nil {
-		panic(fmt.Sprintf("Failed to register simple embedding provider: %v", err))
-	}
-
-	// Wrap the real service
-	embeddingServiceWrapper := &realEmbeddingService{service: embeddingService}
-
-	// Create a simple functional vector index for RAG operations
-	vectorIndex := &simpleVectorIndex{
-		vectors:   make([]*core.Vector, 0),
-		dimension: 384,
-		mu:        &sync.RWMutex{},
-	}
-
-	// Initialize real RAG engine with vector index
-	ragConfig := &rag.Config{
-		EnableQueryExpansion: true,
-		EnableReranking:      true,
-		EnableContextAware:   true,
-		MaxQueryLength:       1000,
-		MaxExpansionTerms:    5,
-		MaxConcurrentQueries: 10,
-		QueryTimeout:         30 * time.Second,
-		BatchSize:            100,
-		EnableCache:          true,
-		CacheTTL:             5 * time.Minute,
-		MaxCacheSize:         1000,
-	}
-
-	ragEngine, err := rag.NewEngine(ragConfig, embeddingServiceWrapper, vectorIndex)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create RAG engine: %v", err))
-	}
-
-	// Populate the vector index with some sample data for testing
-	ctx := context.Background()
-
-	// Generate embeddings for sample texts
-	req1 := &embedding.EmbeddingRequest{Texts: []string{"machine learning algorithms"}}
-	resp1, _ := simpleProvider.GenerateEmbeddings(ctx, req1)
-
-	req2 := &embedding.EmbeddingRequest{Texts: []string{"artificial intelligence systems"}}
-	resp2, _ := simpleProvider.GenerateEmbeddings(ctx, req2)
-
-	req3 := &embedding.EmbeddingRequest{Texts: []string{"deep learning neural networks"}}
-	resp3, _ := simpleProvider.GenerateEmbeddings(ctx, req3)
-
-	sampleVectors := []*core.Vector{
-		{
-			ID:         "vec1",
-			Collection: "sample",
-			Embedding:  resp1.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "machine learning algorithms", "category": "AI"},
-		},
-		{
-			ID:         "vec2",
-			Collection: "sample",
-			Embedding:  resp2.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "artificial intelligence systems", "category": "AI"},
-		},
-		{
-			ID:         "vec3",
-			Collection: "sample",
-			Embedding:  resp3.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "deep learning neural networks", "category": "AI"},
-		},
+// Close stops the background work of the handlers, canceling the jobs that
+// are not finished and dead-lettering the webhook deliveries not yet made
+func (h *Handlers) Close() error {
+	err := h.jobs.Close()
+	if closeErr := h.webhooks.Close(); err == nil {
+		err = closeErr
 	}
-
-	// Insert sample vectors into the index
-	for _, vec := range sampleVectors {
-		// Pad the embedding to 384 dimensions to match our index
-		paddedEmbedding := make([]float64, 384)
-		copy(paddedEmbedding, vec.Embedding)
-		// Fill remaining dimensions with small random values
-		for i := len(vec.Embedding); i < 384; i++ {
-			paddedEmbedding[i] = float64(i) / 1000.0
-		}
-
-		vec.Embedding = paddedEmbedding
-		if err := vectorIndex.Insert(vec); err != nil {
-			panic(fmt.Sprintf("Failed to insert sample vector: %v", err))
+	if h.rateLimiter != nil {
+		if closeErr := h.rateLimiter.Close(); err == nil {
+			err = closeErr
 		}
 	}
-
-	// Add more diverse sample vectors for better search results
-	req4 := &embedding.EmbeddingRequest{Texts: []string{"data science and analytics"}}
-	resp4, _ := simpleProvider.GenerateEmbeddings(ctx, req4)
-
-	req5 := &embedding.EmbeddingRequest{Texts: []string{"computer vision and image processing"}}
-	resp5, _ := simpleProvider.GenerateEmbeddings(ctx, req5)
-
-	req6 := &embedding.EmbeddingRequest{Texts: []string{"natural language processing"}}
-	resp6, _ := simpleProvider.GenerateEmbeddings(ctx, req6)
-
-	req7 := &embedding.EmbeddingRequest{Texts: []string{"web development and programming"}}
-	resp7, _ := simpleProvider.GenerateEmbeddings(ctx, req7)
-
-	req8 := &embedding.EmbeddingRequest{Texts: []string{"database management systems"}}
-	resp8, _ := simpleProvider.GenerateEmbeddings(ctx, req8)
-
-	req9 := &embedding.EmbeddingRequest{Texts: []string{"cloud computing infrastructure"}}
-	resp9, _ := simpleProvider.GenerateEmbeddings(ctx, req9)
-
-	additionalVectors := []*core.Vector{
-		{
-			ID:         "vec4",
-			Collection: "sample",
-			Embedding:  resp4.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "data science and analytics", "category": "AI"},
-		},
-		{
-			ID:         "vec5",
-			Collection: "sample",
-			Embedding:  resp5.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "computer vision and image processing", "category": "AI"},
-		},
-		{
-			ID:         "vec6",
-			Collection: "sample",
-			Embedding:  resp6.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "natural language processing", "category": "AI"},
-		},
-		// Add more diverse content for better semantic differentiation
-		{
-			ID:         "vec7",
-			Collection: "sample",
-			Embedding:  resp7.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "web development and programming", "category": "Software"},
-		},
-		{
-			ID:         "vec8",
-			Collection: "sample",
-			Embedding:  resp8.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "database management systems", "category": "Software"},
-		},
-		{
-			ID:         "vec9",
-			Collection: "sample",
-			Embedding:  resp9.Embeddings[0],
-			Metadata:   map[string]interface{}{"text": "cloud computing infrastructure", "category": "Infrastructure"},
-		},
+	if closeErr := h.apiKeys.Close(); err == nil {
+		err = closeErr
 	}
-
-	// Insert additional vectors
-	for _, vec := range additionalVectors {
-		paddedEmbedding := make([]float64, 384)
-		copy(paddedEmbedding, vec.Embedding)
-		for i := len(vec.Embedding); i < 384; i++ {
-			paddedEmbedding[i] = float64(i) / 1000.0
+	if h.embedding != nil {
+		if closeErr := h.embedding.Close(); err == nil {
+			err = closeErr
 		}
-
-		vec.Embedding = paddedEmbedding
-		if err := vectorIndex.Insert(vec); err != nil {
-			panic(fmt.Sprintf("Failed to insert additional vector: %v", err))
-		}
-	}
-
-	// Initialize batch processor
-	batchConfig := batch.GetDefaultConfig()
-	batchProcessor := batch.NewBatchProcessor(batchConfig, embeddingServiceWrapper, ragEngine)
-
-	// Wrap the real RAG engine
-	ragEngineWrapper := &realRAGEngine{engine: ragEngine}
-
-	// Create handlers with the RAG vector index
-	handlers := &Handlers{
-		indexes:        make(map[string]index.VectorIndex),
-		storage:        storageEngine,
-		batchProcessor: batchProcessor,
-		ragEngine:      ragEngineWrapper,
 	}
-
-	// Register the RAG vector index in the main indexes map
-	handlers.indexes["rag_index"] = vectorIndex
-
-	return handlers
+	return err
 }
 
 // SetServer sets the server interface for accessing metrics
@@ -0,0 +1,345 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/batch"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+)
+
+// Chunking and backpressure of ingest streams
+const (
+	defaultIngestChunkSize = 500
+
+	// maxIngestChunkSize is the most texts the batch processor embeds at once
+	maxIngestChunkSize = 1000
+
+	// maxIngestLineSize bounds the memory a single record can take
+	maxIngestLineSize = 16 << 20
+
+	// ingestQueueDepth is the number of chunks read ahead of the chunk being
+	// written. Once they are queued the body is not read any further, so a
+	// client sending faster than the indexes take the vectors is slowed down
+	// by flow control instead of being buffered.
+	ingestQueueDepth = 2
+
+	// ingestRebuildPoll is how often a stream waiting for the startup index
+	// rebuild checks whether it finished
+	ingestRebuildPoll = 100 * time.Millisecond
+)
+
+// ingestLine is a record of an ingest stream with the outcome of its line
+type ingestLine struct {
+	number  int
+	record  models.IngestRecord
+	vector  *core.Vector
+	version uint64
+	err     error
+}
+
+// ingestVectors ingests a stream of newline-delimited JSON vector records into
+// a collection. Records are read incrementally and written in chunks through
+// the batch processor, each chunk atomically unless one of its records fails
+// to write, and the result of every line is streamed back as it is known.
+// With embed set, the text of records without an embedding is embedded.
+func (h *Handlers) ingestVectors(c echo.Context) error {
+	id := c.Param("indexId")
+	chunkSize := defaultIngestChunkSize
+	if value := c.QueryParam("chunk_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > maxIngestChunkSize {
+			return errorResponse(c, http.StatusBadRequest, fmt.Sprintf("chunk_size must be between 1 and %d", maxIngestChunkSize))
+		}
+		chunkSize = size
+	}
+	embed := false
+	if value := c.QueryParam("embed"); value != "" {
+		var err error
+		if embed, err = strconv.ParseBool(value); err != nil {
+			return errorResponse(c, http.StatusBadRequest, "embed must be a boolean")
+		}
+	}
+
+	// Report what can be checked up front before the stream starts
+	collection, err := h.getCollection(c.Request().Context(), id)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	var service embedding.Service
+	if embed {
+		if service, err = h.embedder(); err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+	}
+	processor := batch.NewBatchProcessor(batch.GetDefaultConfig(), service, nil)
+	defer func() { _ = processor.Close() }()
+
+	// Results are streamed while the body is still being read, which HTTP/1
+	// connections must opt into; writers that cannot, such as test
+	// recorders, hold the whole body already
+	_ = http.NewResponseController(c.Response()).EnableFullDuplex()
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	defer cancel()
+	chunks := make(chan []*ingestLine, ingestQueueDepth)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(chunks)
+		readErr <- readIngestChunks(ctx, c.Request().Body, chunkSize, chunks)
+	}()
+
+	start := time.Now()
+	encoder := json.NewEncoder(response)
+	summary := models.IngestSummary{Done: true}
+	var streamErr error
+	for chunk := range chunks {
+		if streamErr = h.waitRebuilt(ctx); streamErr != nil {
+			break
+		}
+		if streamErr = h.ingestChunk(ctx, processor, collection, embed, chunk); streamErr != nil {
+			break
+		}
+
+		for _, line := range chunk {
+			result := models.IngestResult{Line: line.number, ID: line.record.ID}
+			if line.err != nil {
+				result.Error, result.Code = line.err.Error(), errorCode(line.err)
+				summary.Failed++
+			} else {
+				result.Version = line.version
+				summary.Ingested++
+			}
+			if streamErr = encoder.Encode(result); streamErr != nil {
+				break
+			}
+		}
+		if streamErr != nil {
+			break
+		}
+		response.Flush()
+		summary.Lines += len(chunk)
+		summary.Chunks++
+	}
+	if streamErr == nil {
+		streamErr = <-readErr
+	}
+	cancel()
+
+	// The status is already sent, so errors end the stream in its summary
+	if streamErr != nil {
+		summary.Error = streamErr.Error()
+	}
+	if stored, err := h.getCollection(c.Request().Context(), id); err == nil {
+		summary.TotalVectors = stored.Count
+	}
+	summary.IngestTime = time.Since(start).String()
+	if summary.Ingested+summary.Failed > 0 {
+		h.publishBulkWrite(c.Request().Context(), models.VectorsBulkEvent{
+			Collection:   id,
+			Operation:    "ingest",
+			Written:      summary.Ingested,
+			Failed:       summary.Failed,
+			TotalVectors: summary.TotalVectors,
+		})
+	}
+	if err := encoder.Encode(summary); err != nil {
+		c.Logger().Errorf("ingest stream ended: %v", err)
+	}
+	response.Flush()
+	return nil
+}
+
+// readIngestChunks reads the records of an ingest stream into chunks of up to
+// size lines, skipping blank lines. Lines that are not records are passed on
+// with their error so that their results keep the order of the stream.
+func readIngestChunks(ctx context.Context, body io.Reader, size int, chunks chan<- []*ingestLine) error {
+	send := func(chunk []*ingestLine) error {
+		select {
+		case chunks <- chunk:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxIngestLineSize)
+	chunk := make([]*ingestLine, 0, size)
+	number := 0
+	for scanner.Scan() {
+		number++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		line := &ingestLine{number: number}
+		if err := json.Unmarshal(data, &line.record); err != nil {
+			line.err = fmt.Errorf("%w: line is not a JSON vector record: %v", ErrInvalidRequest, err)
+		}
+		chunk = append(chunk, line)
+		if len(chunk) == size {
+			if err := send(chunk); err != nil {
+				return err
+			}
+			chunk = make([]*ingestLine, 0, size)
+		}
+	}
+	if len(chunk) > 0 {
+		if err := send(chunk); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line %d is longer than %d bytes", number+1, maxIngestLineSize)
+		}
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	return nil
+}
+
+// ingestChunk embeds, validates and writes the records of a chunk, recording
+// the outcome of every line. It fails only when the stream cannot go on.
+func (h *Handlers) ingestChunk(ctx context.Context, processor batch.BatchProcessor, collection *core.Collection,
+	embed bool, chunk []*ingestLine) error {
+	if embed {
+		if err := h.embedIngestChunk(ctx, processor, chunk); err != nil {
+			return err
+		}
+	}
+
+	// The batch processor checks the vectors before they are written
+	pending := make([]*ingestLine, 0, len(chunk))
+	vectors := make([]*core.Vector, 0, len(chunk))
+	for _, line := range chunk {
+		if line.err != nil {
+			continue
+		}
+		vector, err := newVector(collection.Name, &line.record.Vector)
+		if err != nil {
+			line.err = err
+			continue
+		}
+		vector.Text = line.record.Text
+		line.vector = vector
+		pending = append(pending, line)
+		vectors = append(vectors, vector)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	checked, err := processor.ProcessBatchVectors(ctx, &batch.BatchVectorRequest{
+		Operation:  batch.BatchOperationInsert,
+		Vectors:    vectors,
+		Collection: collection.Name,
+	})
+	if err != nil {
+		return err
+	}
+	for _, batchErr := range checked.Errors {
+		pending[batchErr.Index].err = fmt.Errorf("%w: %s", ErrInvalidRequest, batchErr.Message)
+	}
+
+	writes := make([]catalog.Write, 0, len(pending))
+	valid := pending[:0]
+	for _, line := range pending {
+		if line.err == nil && len(line.vector.Embedding) != collection.Dimension {
+			line.err = fmt.Errorf("%w: embedding has dimension %d, expected %d",
+				catalog.ErrDimensionMismatch, len(line.vector.Embedding), collection.Dimension)
+		}
+		if line.err != nil {
+			continue
+		}
+		writes = append(writes, catalog.Write{Kind: catalog.WritePut, Vector: line.vector, ExpectedVersion: line.record.ExpectedVersion})
+		valid = append(valid, line)
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	outcome, err := h.write(ctx, collection.Name, writes)
+	if err == nil {
+		for _, line := range valid {
+			line.version = outcome.result.Versions[line.vector.ID]
+		}
+		return nil
+	}
+	if ctx.Err() != nil || errors.Is(err, catalog.ErrCatalogClosed) || errors.Is(err, catalog.ErrCollectionNotFound) {
+		return err
+	}
+
+	// A record failed the chunk, so the records are written one by one to
+	// find it and keep the others
+	for i, line := range valid {
+		outcome, err := h.write(ctx, collection.Name, writes[i:i+1])
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			line.err = err
+			continue
+		}
+		line.version = outcome.result.Versions[line.vector.ID]
+	}
+	return nil
+}
+
+// embedIngestChunk embeds the text of the records of a chunk that have no
+// embedding
+func (h *Handlers) embedIngestChunk(ctx context.Context, processor batch.BatchProcessor, chunk []*ingestLine) error {
+	lines := make([]*ingestLine, 0, len(chunk))
+	texts := make([]string, 0, len(chunk))
+	for _, line := range chunk {
+		if line.err == nil && len(line.record.Embedding) == 0 && line.record.Text != "" {
+			lines = append(lines, line)
+			texts = append(texts, line.record.Text)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+
+	embedded, err := processor.ProcessBatchEmbeddings(ctx, &batch.BatchEmbeddingRequest{
+		Texts:    texts,
+		Provider: h.embeddingProvider.Type(),
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		for _, line := range lines {
+			line.err = fmt.Errorf("%w: %v", ErrEmbeddingFailed, err)
+		}
+		return nil
+	}
+	for _, batchErr := range embedded.Errors {
+		lines[batchErr.Index].err = fmt.Errorf("%w: %s", ErrEmbeddingFailed, batchErr.Message)
+	}
+	for i, line := range lines {
+		if line.err == nil {
+			line.record.Embedding = embedded.Embeddings[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/internal/models"
+)
+
+// ingestLines decodes the line results and the summary of an ingest response
+func ingestLines(t *testing.T, body string) ([]models.IngestResult, models.IngestSummary) {
+	t.Helper()
+
+	var results []models.IngestResult
+	var summary models.IngestSummary
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), `"done":true`) {
+			if err := json.Unmarshal(scanner.Bytes(), &summary); err != nil {
+				t.Fatalf("Failed to decode summary: %v", err)
+			}
+			continue
+		}
+		var result models.IngestResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("Failed to decode result: %v", err)
+		}
+		results = append(results, result)
+	}
+	if !summary.Done {
+		t.Fatalf("Expected the stream to end with a summary, got %s", body)
+	}
+	return results, summary
+}
+
+// embeddingJSON encodes the embedding of a text for an ingest record
+func embeddingJSON(t *testing.T, text string) string {
+	t.Helper()
+	data, _ := json.Marshal(embedText(t, text))
+	return string(data)
+}
+
+func TestIngestVectors(t *testing.T) {
+	e, _ := newTestRouter(t)
+	recorder := serve(e, http.MethodPost, "/v1/indexes", map[string]interface{}{
+		"id": "docs", "type": "hnsw", "dimension": contractDimension, "max_elements": 100,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create index: %s", recorder.Body.String())
+	}
+	recorder = serve(e, http.MethodPost, "/v1/indexes/docs/vectors", map[string]interface{}{
+		"vectors": []interface{}{map[string]interface{}{"id": "raft", "embedding": embedText(t, "raft")}},
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to insert vector: %s", recorder.Body.String())
+	}
+
+	body := strings.Join([]string{
+		fmt.Sprintf(`{"id": "a", "embedding": %s}`, embeddingJSON(t, "a")),
+		``,
+		`{"id": "broken"`,
+		`{"id": "b", "text": "embedded on ingest"}`,
+		fmt.Sprintf(`{"embedding": %s}`, embeddingJSON(t, "no id")),
+		`{"id": "c", "embedding": [1, 2]}`,
+		fmt.Sprintf(`{"id": "raft", "embedding": %s, "expected_version": 0}`, embeddingJSON(t, "raft")),
+		fmt.Sprintf(`{"id": "d", "embedding": %s}`, embeddingJSON(t, "d")),
+		`{"id": "e"}`,
+	}, "\n")
+	recorder = serve(e, http.MethodPost, "/v1/indexes/docs/ingest?chunk_size=3&embed=true", body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	results, summary := ingestLines(t, recorder.Body.String())
+
+	// Every line but the blank one has a result, in stream order
+	expected := []struct {
+		line int
+		code string
+	}{
+		{1, ""}, {3, "invalid_request"}, {4, ""}, {5, "invalid_request"},
+		{6, "invalid_request"}, {7, "conflict"}, {8, ""}, {9, "invalid_request"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %v", len(expected), results)
+	}
+	for i, want := range expected {
+		got := results[i]
+		if got.Line != want.line || got.Code != want.code {
+			t.Errorf("Expected line %d with code %q, got %+v", want.line, want.code, got)
+		}
+		if want.code == "" && got.Version != 1 {
+			t.Errorf("Expected line %d to be written at version 1, got %+v", want.line, got)
+		}
+	}
+
+	if summary.Lines != 8 || summary.Ingested != 3 || summary.Failed != 5 || summary.Chunks != 3 ||
+		summary.TotalVectors != 4 || summary.Error != "" {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
+
+func TestIngestWaitsForRebuild(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	if _, _, err := handlers.createCollection(context.Background(), &models.CreateIndexRequest{
+		ID: "docs", Type: "hnsw", Dimension: contractDimension,
+	}); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	handlers.readiness.mutex.Lock()
+	handlers.readiness.ready = false
+	handlers.readiness.mutex.Unlock()
+	rebuilt := time.Now().Add(3 * ingestRebuildPoll)
+	time.AfterFunc(time.Until(rebuilt), func() { handlers.RebuildFinished(nil) })
+
+	body := fmt.Sprintf(`{"id": "a", "embedding": %s}`, embeddingJSON(t, "a"))
+	recorder := serve(e, http.MethodPost, "/v1/indexes/docs/ingest", body)
+	if time.Now().Before(rebuilt) {
+		t.Errorf("Expected the stream to wait for the rebuild")
+	}
+	_, summary := ingestLines(t, recorder.Body.String())
+	if summary.Ingested != 1 || summary.TotalVectors != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
@@ -0,0 +1,454 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/batch"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+)
+
+// Job types of the API
+const (
+	jobTypeEmbed   = "embed"
+	jobTypeReindex = "reindex"
+	jobTypeCompact = "compact"
+	jobTypeImport  = "import"
+)
+
+// jobPermissions maps the job types to the permission their operation
+// requires, besides the permission to submit jobs
+var jobPermissions = map[string]permission{
+	jobTypeEmbed:   {"rag", "read"},
+	jobTypeReindex: {"collections", "write"},
+	jobTypeCompact: {"storage", "write"},
+	jobTypeImport:  {"vectors", "write"},
+}
+
+// reindexProgressInterval is the number of vectors indexed between progress
+// reports of a reindex job
+const reindexProgressInterval = 1000
+
+// OpenJobs replaces the job manager of the handlers, which keeps job records
+// in memory only, with one configured by config, such as one keeping its
+// records on disk. Jobs of the replaced manager are canceled.
+func (h *Handlers) OpenJobs(config jobs.Config) error {
+	manager, err := h.newJobManager(config)
+	if err != nil {
+		return err
+	}
+	if h.jobs != nil {
+		_ = h.jobs.Close()
+	}
+	h.jobs = manager
+	return nil
+}
+
+// newJobManager creates a job manager running the job types of the API
+func (h *Handlers) newJobManager(config jobs.Config) (*jobs.Manager, error) {
+	manager, err := jobs.NewManager(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	manager.Register(jobTypeEmbed, h.tenantJob(h.runEmbedJob))
+	manager.Register(jobTypeReindex, h.tenantJob(h.runReindexJob))
+	manager.Register(jobTypeCompact, h.tenantJob(h.runCompactJob))
+	manager.Register(jobTypeImport, h.tenantJob(h.runImportJob))
+	manager.OnFinish(h.publishJob)
+	return manager, nil
+}
+
+// tenantJob scopes the runs of a job type to the tenant that submitted them
+func (h *Handlers) tenantJob(run jobs.Runner) jobs.Runner {
+	return func(ctx context.Context, job *jobs.Job, progress batch.BatchProgressCallback) (interface{}, error) {
+		return run(h.isolation.ScopeToTenant(ctx, job.TenantID), job, progress)
+	}
+}
+
+// decodeJobParams decodes the parameters of a job, rejecting unknown fields
+func decodeJobParams(data json.RawMessage, params interface{}) error {
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(params); err != nil {
+		return fmt.Errorf("%w: invalid job parameters: %v", ErrInvalidRequest, err)
+	}
+	return nil
+}
+
+// checkJob checks the parameters of a job before it is submitted, so that
+// requests that cannot succeed are rejected up front, and returns them with
+// their defaults filled
+func (h *Handlers) checkJob(ctx context.Context, req *models.SubmitJobRequest) (json.RawMessage, error) {
+	var params interface{}
+	switch req.Type {
+	case jobTypeEmbed:
+		var embed models.EmbedJobParams
+		if err := decodeJobParams(req.Params, &embed); err != nil {
+			return nil, err
+		}
+		if len(embed.Texts) == 0 {
+			return nil, fmt.Errorf("%w: an embed job needs texts", ErrInvalidRequest)
+		}
+		if embed.Dimension <= 0 {
+			return nil, fmt.Errorf("%w: an embed job needs a positive dimension", ErrInvalidRequest)
+		}
+		params = embed
+	case jobTypeReindex:
+		var reindex models.ReindexJobParams
+		if err := decodeJobParams(req.Params, &reindex); err != nil {
+			return nil, err
+		}
+		if reindex.Collection != "" {
+			if _, err := h.getCollection(ctx, reindex.Collection); err != nil {
+				return nil, err
+			}
+		}
+		params = reindex
+	case jobTypeCompact:
+		var compact models.CompactJobParams
+		if err := decodeJobParams(req.Params, &compact); err != nil {
+			return nil, err
+		}
+		if compact.Collection != "" {
+			if _, err := h.getCollection(ctx, compact.Collection); err != nil {
+				return nil, err
+			}
+		}
+		params = compact
+	case jobTypeImport:
+		var imported models.ImportJobParams
+		if err := decodeJobParams(req.Params, &imported); err != nil {
+			return nil, err
+		}
+		if imported.Collection == "" {
+			return nil, fmt.Errorf("%w: an import job needs a collection", ErrInvalidRequest)
+		}
+		if _, err := h.getCollection(ctx, imported.Collection); err != nil {
+			return nil, err
+		}
+		if len(imported.Records) == 0 {
+			return nil, fmt.Errorf("%w: an import job needs records", ErrInvalidRequest)
+		}
+		if imported.ChunkSize == 0 {
+			imported.ChunkSize = defaultIngestChunkSize
+		}
+		if imported.ChunkSize < 1 || imported.ChunkSize > maxIngestChunkSize {
+			return nil, fmt.Errorf("%w: chunk_size must be between 1 and %d", ErrInvalidRequest, maxIngestChunkSize)
+		}
+		params = imported
+	default:
+		return nil, fmt.Errorf("%w: %s", jobs.ErrUnknownJobType, req.Type)
+	}
+
+	if err := h.checkPermission(ctx, jobPermissions[req.Type], ""); err != nil {
+		return nil, err
+	}
+	return json.Marshal(params)
+}
+
+// runEmbedJob embeds the texts of an embed job in chunks through the batch
+// processor
+func (h *Handlers) runEmbedJob(ctx context.Context, job *jobs.Job, progress batch.BatchProgressCallback) (interface{}, error) {
+	var params models.EmbedJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	service, err := h.embedder()
+	if err != nil {
+		return nil, err
+	}
+	processor := batch.NewBatchProcessor(batch.GetDefaultConfig(), service, nil)
+	defer func() { _ = processor.Close() }()
+
+	// The processor reports estimated progress within the chunk being embedded
+	total := len(params.Texts)
+	var done atomic.Int64
+	processor.SetProgressCallback(func(processed, _ int, elapsed time.Duration) {
+		progress(min(int(done.Load())+processed, total), total, elapsed)
+	})
+
+	start := time.Now()
+	embeddings := make([][]float64, 0, total)
+	for offset := 0; offset < total; offset += maxIngestChunkSize {
+		end := min(offset+maxIngestChunkSize, total)
+		embedded, err := processor.ProcessBatchEmbeddings(ctx, &batch.BatchEmbeddingRequest{
+			Texts:    params.Texts[offset:end],
+			Provider: h.embeddingProvider.Type(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(embedded.Errors) > 0 {
+			batchErr := embedded.Errors[0]
+			return nil, fmt.Errorf("%w: text %d: %s", ErrEmbeddingFailed, offset+batchErr.Index, batchErr.Message)
+		}
+		for i, vector := range embedded.Embeddings {
+			if len(vector) != params.Dimension {
+				return nil, fmt.Errorf("%w: text %d has an embedding of dimension %d, expected %d",
+					catalog.ErrDimensionMismatch, offset+i, len(vector), params.Dimension)
+			}
+		}
+		embeddings = append(embeddings, embedded.Embeddings...)
+
+		done.Store(int64(end))
+		progress(end, total, time.Since(start))
+	}
+
+	return models.EmbedJobResult{
+		Embeddings: embeddings,
+		Dimension:  params.Dimension,
+		Count:      len(embeddings),
+	}, nil
+}
+
+// jobCollections returns the collections a reindex or compact job covers:
+// the named one, or every collection of the tenant
+func (h *Handlers) jobCollections(ctx context.Context, name string) ([]*core.Collection, error) {
+	if name == "" {
+		return h.tenantCollections(ctx)
+	}
+	stored, err := h.catalogName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := h.catalog.Get(stored)
+	if err != nil {
+		return nil, err
+	}
+	return []*core.Collection{collection}, nil
+}
+
+// runReindexJob rebuilds the indexes of the collections of a reindex job one
+// after the other, reporting the vectors indexed
+func (h *Handlers) runReindexJob(ctx context.Context, job *jobs.Job, progress batch.BatchProgressCallback) (interface{}, error) {
+	var params models.ReindexJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	if err := h.waitRebuilt(ctx); err != nil {
+		return nil, err
+	}
+	collections, err := h.jobCollections(ctx, params.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, collection := range collections {
+		total += collection.Count
+	}
+
+	start := time.Now()
+	result := models.ReindexJobResult{Collections: make([]string, 0, len(collections))}
+	for _, collection := range collections {
+		indexed := result.Indexed
+		err := h.catalog.RebuildIndex(ctx, collection.Name, catalog.RebuildOptions{
+			ProgressInterval: reindexProgressInterval,
+			Progress: func(rebuilt catalog.RebuildProgress) {
+				progress(int(indexed+rebuilt.Indexed), int(max(total, indexed+rebuilt.Indexed)), time.Since(start))
+				if rebuilt.Done {
+					result.Indexed = indexed + rebuilt.Indexed
+					h.publishRebuilt(rebuilt, "reindex")
+				}
+			},
+		})
+		_, name := tenant.SplitCollectionName(collection.Name)
+		if err != nil {
+			if errors.Is(err, catalog.ErrCollectionNotFound) && params.Collection == "" {
+				continue
+			}
+			return nil, fmt.Errorf("failed to rebuild index of collection %s: %w", name, err)
+		}
+		result.Collections = append(result.Collections, name)
+	}
+	return result, nil
+}
+
+// runCompactJob compacts the storage of the collections of a compact job one
+// after the other
+func (h *Handlers) runCompactJob(ctx context.Context, job *jobs.Job, progress batch.BatchProgressCallback) (interface{}, error) {
+	var params models.CompactJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	collections, err := h.jobCollections(ctx, params.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result := models.CompactJobResult{Collections: make([]string, 0, len(collections))}
+	for i, collection := range collections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		_, name := tenant.SplitCollectionName(collection.Name)
+		engine, err := h.catalog.Storage(collection.Name)
+		if err != nil {
+			if errors.Is(err, catalog.ErrCollectionNotFound) && params.Collection == "" {
+				continue
+			}
+			return nil, err
+		}
+		if err := engine.Compact(); err != nil {
+			return nil, fmt.Errorf("failed to compact collection %s: %w", name, err)
+		}
+		result.Collections = append(result.Collections, name)
+		progress(i+1, len(collections), time.Since(start))
+	}
+	result.CompactTime = time.Since(start).String()
+	return result, nil
+}
+
+// runImportJob writes the records of an import job into its collection in
+// chunks, the way an ingest stream does
+func (h *Handlers) runImportJob(ctx context.Context, job *jobs.Job, progress batch.BatchProgressCallback) (interface{}, error) {
+	var params models.ImportJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return nil, err
+	}
+	if err := h.waitRebuilt(ctx); err != nil {
+		return nil, err
+	}
+	collection, err := h.getCollection(ctx, params.Collection)
+	if err != nil {
+		return nil, err
+	}
+	var service embedding.Service
+	if params.Embed {
+		if service, err = h.embedder(); err != nil {
+			return nil, err
+		}
+	}
+	processor := batch.NewBatchProcessor(batch.GetDefaultConfig(), service, nil)
+	defer func() { _ = processor.Close() }()
+
+	start := time.Now()
+	total := len(params.Records)
+	var result models.ImportJobResult
+	for offset := 0; offset < total; offset += params.ChunkSize {
+		end := min(offset+params.ChunkSize, total)
+		chunk := make([]*ingestLine, 0, end-offset)
+		for i := offset; i < end; i++ {
+			chunk = append(chunk, &ingestLine{number: i + 1, record: params.Records[i]})
+		}
+		if err := h.ingestChunk(ctx, processor, collection, params.Embed, chunk); err != nil {
+			return nil, err
+		}
+
+		for _, line := range chunk {
+			if line.err == nil {
+				result.Ingested++
+				continue
+			}
+			result.Failed++
+			result.Failures = append(result.Failures, models.IngestResult{
+				Line:  line.number,
+				ID:    line.record.ID,
+				Error: line.err.Error(),
+				Code:  errorCode(line.err),
+			})
+		}
+		progress(end, total, time.Since(start))
+	}
+
+	if stored, err := h.getCollection(ctx, params.Collection); err == nil {
+		result.TotalVectors = stored.Count
+	}
+	result.ImportTime = time.Since(start).String()
+	h.publishBulkWrite(ctx, models.VectorsBulkEvent{
+		Collection:   params.Collection,
+		Operation:    "import",
+		Written:      result.Ingested,
+		Failed:       result.Failed,
+		TotalVectors: result.TotalVectors,
+	})
+	return result, nil
+}
+
+// ownJob returns a job of the tenant of a request; the jobs of other
+// tenants are not found
+func (h *Handlers) ownJob(ctx context.Context, id string) (*jobs.Job, error) {
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.TenantID != h.tenantOf(ctx) {
+		return nil, fmt.Errorf("%w: %s", jobs.ErrJobNotFound, id)
+	}
+	return job, nil
+}
+
+// submitJob starts a long-running operation as a job and returns its record
+// right away, to be polled for its progress and result
+func (h *Handlers) submitJob(c echo.Context) error {
+	var req models.SubmitJobRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	ctx := c.Request().Context()
+	params, err := h.checkJob(ctx, &req)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	job, err := h.jobs.Submit(h.tenantOf(ctx), req.Type, params)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/v1/jobs/"+job.ID)
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// listJobs lists the jobs of the tenant of a request, newest first
+func (h *Handlers) listJobs(c echo.Context) error {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	list := h.jobs.List(h.tenantOf(c.Request().Context()), limit, offset)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"jobs":  list,
+		"count": len(list),
+	})
+}
+
+// getJob returns the record of a job, with its progress, and its result once
+// it finished
+func (h *Handlers) getJob(c echo.Context) error {
+	job, err := h.ownJob(c.Request().Context(), c.Param("jobId"))
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+// cancelJob asks a job to stop. The job is recorded as canceled once it has
+// stopped, which its record reports when polled.
+func (h *Handlers) cancelJob(c echo.Context) error {
+	job, err := h.ownJob(c.Request().Context(), c.Param("jobId"))
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	if job, err = h.jobs.Cancel(job.ID); err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
+)
+
+// submitTestJob submits a job, failing the test unless it is accepted
+func submitTestJob(t *testing.T, e *echo.Echo, key, tenantID string, req models.SubmitJobRequest) *jobs.Job {
+	t.Helper()
+
+	recorder := serveAs(e, key, tenantID, http.MethodPost, "/v1/jobs", req)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Failed to submit %s job: %d %s", req.Type, recorder.Code, recorder.Body.String())
+	}
+	var job jobs.Job
+	if err := json.Unmarshal(recorder.Body.Bytes(), &job); err != nil {
+		t.Fatalf("Failed to decode job: %v", err)
+	}
+	if location := recorder.Header().Get(echo.HeaderLocation); location != "/v1/jobs/"+job.ID {
+		t.Errorf("Unexpected location %q", location)
+	}
+	return &job
+}
+
+// waitJob polls a job until it finished
+func waitJob(t *testing.T, e *echo.Echo, key, tenantID, id string) *jobs.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		recorder := serveAs(e, key, tenantID, http.MethodGet, "/v1/jobs/"+id, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Failed to get job: %d %s", recorder.Code, recorder.Body.String())
+		}
+		var job jobs.Job
+		if err := json.Unmarshal(recorder.Body.Bytes(), &job); err != nil {
+			t.Fatalf("Failed to decode job: %v", err)
+		}
+		if job.State.Finished() {
+			return &job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return nil
+}
+
+// jobParams encodes the parameters of a job
+func jobParams(t *testing.T, params interface{}) json.RawMessage {
+	t.Helper()
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("Failed to encode job parameters: %v", err)
+	}
+	return data
+}
+
+func TestJobs(t *testing.T) {
+	e, _ := newTestRouter(t)
+	createTenantIndex(t, e, "", "acme", "raft")
+
+	// An import job writes its records in chunks and reports those that failed
+	records := []models.IngestRecord{
+		{Vector: models.Vector{ID: "gossip"}, Text: "gossip spreads state between nodes"},
+		{Vector: models.Vector{ID: "paxos", Embedding: embedText(t, "paxos agrees on a log")}},
+		{Vector: models.Vector{ID: "short", Embedding: []float64{1, 2}}},
+	}
+	imported := submitTestJob(t, e, "", "acme", models.SubmitJobRequest{
+		Type:   "import",
+		Params: jobParams(t, models.ImportJobParams{Collection: "docs", Records: records, ChunkSize: 2, Embed: true}),
+	})
+	if imported.TenantID != "acme" || imported.Type != "import" {
+		t.Errorf("Unexpected job %+v", imported)
+	}
+	job := waitJob(t, e, "", "acme", imported.ID)
+	if job.State != jobs.StateSucceeded {
+		t.Fatalf("Expected import to succeed, got %s: %s", job.State, job.Error)
+	}
+	var importResult models.ImportJobResult
+	if err := json.Unmarshal(job.Result, &importResult); err != nil {
+		t.Fatalf("Failed to decode import result: %v", err)
+	}
+	if importResult.Ingested != 2 || importResult.Failed != 1 || importResult.TotalVectors != 3 {
+		t.Errorf("Unexpected import result %+v", importResult)
+	}
+	if len(importResult.Failures) != 1 || importResult.Failures[0].Line != 3 || importResult.Failures[0].Code != "invalid_request" {
+		t.Errorf("Unexpected import failures %+v", importResult.Failures)
+	}
+	if job.Progress.Processed != 3 || job.Progress.Total != 3 {
+		t.Errorf("Expected progress 3/3, got %+v", job.Progress)
+	}
+	if ids := searchIDs(t, e, "acme", "gossip spreads state between nodes"); !slices.Contains(ids, "gossip") {
+		t.Errorf("Expected the imported vector to be searchable, got %v", ids)
+	}
+
+	// Reindex and embed jobs report their result the same way
+	reindexed := submitTestJob(t, e, "", "acme", models.SubmitJobRequest{Type: "reindex"})
+	job = waitJob(t, e, "", "acme", reindexed.ID)
+	var reindexResult models.ReindexJobResult
+	if err := json.Unmarshal(job.Result, &reindexResult); err != nil || job.State != jobs.StateSucceeded {
+		t.Fatalf("Expected reindex to succeed, got %s: %s", job.State, job.Error)
+	}
+	if !slices.Equal(reindexResult.Collections, []string{"docs"}) || reindexResult.Indexed != 3 {
+		t.Errorf("Unexpected reindex result %+v", reindexResult)
+	}
+
+	embedded := submitTestJob(t, e, "", "acme", models.SubmitJobRequest{
+		Type:   "embed",
+		Params: jobParams(t, models.EmbedJobParams{Texts: []string{"raft", "paxos"}, Dimension: contractDimension}),
+	})
+	job = waitJob(t, e, "", "acme", embedded.ID)
+	var embedResult models.EmbedJobResult
+	if err := json.Unmarshal(job.Result, &embedResult); err != nil || job.State != jobs.StateSucceeded {
+		t.Fatalf("Expected embed to succeed, got %s: %s", job.State, job.Error)
+	}
+	if embedResult.Count != 2 || len(embedResult.Embeddings[0]) != contractDimension {
+		t.Errorf("Unexpected embed result with %d embeddings", embedResult.Count)
+	}
+
+	// Finished jobs cannot be canceled
+	if recorder := serveAs(e, "", "acme", http.MethodDelete, "/v1/jobs/"+embedded.ID, nil); recorder.Code != http.StatusConflict {
+		t.Errorf("Expected 409 to cancel a finished job, got %d", recorder.Code)
+	}
+
+	// Jobs are only seen by their tenant
+	recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/jobs", nil)
+	var list struct {
+		Jobs  []jobs.Job `json:"jobs"`
+		Count int        `json:"count"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode jobs: %v", err)
+	}
+	if list.Count != 3 || list.Jobs[0].ID != embedded.ID {
+		t.Errorf("Expected the 3 jobs of acme newest first, got %+v", list.Jobs)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveAs(e, "", "", method, "/v1/jobs/"+imported.ID, nil); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for the default tenant to %s a job of acme, got %d", method, recorder.Code)
+		}
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	createTenantIndex(t, e, "", "", "raft")
+
+	// Imports wait for the startup index rebuild, which never finishes here
+	handlers.readiness.mutex.Lock()
+	handlers.readiness.ready = false
+	handlers.readiness.mutex.Unlock()
+
+	submitted := submitTestJob(t, e, "", "", models.SubmitJobRequest{
+		Type: "import",
+		Params: jobParams(t, models.ImportJobParams{Collection: "docs", Records: []models.IngestRecord{
+			{Vector: models.Vector{ID: "paxos", Embedding: embedText(t, "paxos")}},
+		}}),
+	})
+	recorder := serveAs(e, "", "", http.MethodDelete, "/v1/jobs/"+submitted.ID, nil)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Failed to cancel job: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if job := waitJob(t, e, "", "", submitted.ID); job.State != jobs.StateCanceled || !job.CancelRequested {
+		t.Errorf("Expected canceled job, got %s: %s", job.State, job.Error)
+	}
+	if ids := searchIDs(t, e, "", "paxos"); slices.Contains(ids, "paxos") {
+		t.Errorf("Expected the canceled import to write nothing, got %v", ids)
+	}
+}
+
+func TestJobPermissions(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableAuth(testAdminKey)
+	createTenantIndex(t, e, testAdminKey, "", "raft")
+
+	_, jobsOnly := createTestKey(t, e, "", models.CreateAPIKeyRequest{Name: "jobs", Permissions: []string{"jobs:*"}})
+	_, importer := createTestKey(t, e, "", models.CreateAPIKeyRequest{
+		Name: "importer", Permissions: []string{"jobs:*", "vectors:write"},
+	})
+
+	req := models.SubmitJobRequest{
+		Type: "import",
+		Params: jobParams(t, models.ImportJobParams{Collection: "docs", Records: []models.IngestRecord{
+			{Vector: models.Vector{ID: "paxos", Embedding: embedText(t, "paxos")}},
+		}}),
+	}
+	if recorder := serveAs(e, jobsOnly, "", http.MethodPost, "/v1/jobs", req); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a key without the permission of the job, got %d", recorder.Code)
+	}
+	submitted := submitTestJob(t, e, importer, "", req)
+	if job := waitJob(t, e, jobsOnly, "", submitted.ID); job.State != jobs.StateSucceeded {
+		t.Errorf("Expected import to succeed, got %s: %s", job.State, job.Error)
+	}
+}
+
+func TestJobsPersist(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	dir := t.TempDir()
+	if err := handlers.OpenJobs(jobs.DefaultConfig(dir)); err != nil {
+		t.Fatalf("Failed to open jobs: %v", err)
+	}
+	createTenantIndex(t, e, "", "", "raft")
+
+	submitted := submitTestJob(t, e, "", "", models.SubmitJobRequest{Type: "compact"})
+	waitJob(t, e, "", "", submitted.ID)
+
+	// The record outlives the job manager that ran the job
+	if err := handlers.OpenJobs(jobs.DefaultConfig(dir)); err != nil {
+		t.Fatalf("Failed to reopen jobs: %v", err)
+	}
+	if job := waitJob(t, e, "", "", submitted.ID); job.State != jobs.StateSucceeded || job.Type != "compact" {
+		t.Errorf("Unexpected reloaded job %+v", job)
+	}
+}
@@ -0,0 +1,510 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apidocs "github.com/vijaynallagatla/vjvector/docs/api"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec holds the operations of the OpenAPI specification, keyed by the
+// method and Echo route path that serve them
+type openAPISpec struct {
+	operations map[string]*operation
+}
+
+// operation is an operation of the specification
+type operation struct {
+	ID         string
+	Method     string
+	Path       string
+	Parameters []*parameter
+	Body       *schema
+
+	// BodyRequired is set when requests must have a body
+	BodyRequired bool
+
+	// Responses holds the JSON schema of every documented status; nil for
+	// responses that are not JSON
+	Responses map[int]*schema
+}
+
+// parameter is a query or path parameter of an operation
+type parameter struct {
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *schema `yaml:"schema"`
+}
+
+// schema is the subset of JSON Schema the specification uses
+type schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Required             []string           `yaml:"required"`
+	Properties           map[string]*schema `yaml:"properties"`
+	AdditionalProperties yaml.Node          `yaml:"additionalProperties"`
+	Items                *schema            `yaml:"items"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+	Pattern              string             `yaml:"pattern"`
+	Nullable             bool               `yaml:"nullable"`
+
+	// additional is the schema of the additional properties of an object,
+	// and closed is set when the object allows none
+	additional *schema
+	closed     bool
+	pattern    *regexp.Regexp
+}
+
+// specDocument is the part of an OpenAPI document the validator reads
+type specDocument struct {
+	Paths      map[string]map[string]yaml.Node `yaml:"paths"`
+	Components struct {
+		Schemas map[string]*schema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+// specOperation is an operation as written in the document
+type specOperation struct {
+	OperationID string       `yaml:"operationId"`
+	Parameters  []*parameter `yaml:"parameters"`
+	RequestBody *struct {
+		Required bool `yaml:"required"`
+		Content  map[string]struct {
+			Schema *schema `yaml:"schema"`
+		} `yaml:"content"`
+	} `yaml:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *schema `yaml:"schema"`
+		} `yaml:"content"`
+	} `yaml:"responses"`
+}
+
+// pathParameterPattern matches the {name} parameters of specification paths
+var pathParameterPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// loadOpenAPISpec parses an OpenAPI document and resolves its schema references
+func loadOpenAPISpec(data []byte) (*openAPISpec, error) {
+	var document specDocument
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI specification: %w", err)
+	}
+
+	components := document.Components.Schemas
+	for name, component := range components {
+		if err := component.resolve(components, map[*schema]bool{}); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+
+	spec := &openAPISpec{operations: make(map[string]*operation)}
+	for path, item := range document.Paths {
+		var shared []*parameter
+		if node, exists := item["parameters"]; exists {
+			if err := node.Decode(&shared); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		for method, node := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op specOperation
+			if err := node.Decode(&op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			compiled, err := compileOperation(strings.ToUpper(method), path, shared, &op, components)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			spec.operations[compiled.Method+" "+compiled.Path] = compiled
+		}
+	}
+	return spec, nil
+}
+
+// compileOperation resolves the parameters, body and responses of an operation
+func compileOperation(method, path string, shared []*parameter, op *specOperation, components map[string]*schema) (*operation, error) {
+	compiled := &operation{
+		ID:         op.OperationID,
+		Method:     method,
+		Path:       pathParameterPattern.ReplaceAllString(path, ":$1"),
+		Parameters: append(append([]*parameter{}, shared...), op.Parameters...),
+		Responses:  make(map[int]*schema),
+	}
+
+	for _, param := range compiled.Parameters {
+		if param.Schema == nil {
+			continue
+		}
+		if err := param.Schema.resolve(components, map[*schema]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	if op.RequestBody != nil {
+		if content, exists := op.RequestBody.Content[echo.MIMEApplicationJSON]; exists && content.Schema != nil {
+			if err := content.Schema.resolve(components, map[*schema]bool{}); err != nil {
+				return nil, err
+			}
+			compiled.Body = content.Schema
+			compiled.BodyRequired = op.RequestBody.Required
+		}
+	}
+	for code, response := range op.Responses {
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response status %q", code)
+		}
+		compiled.Responses[status] = nil
+		if content, exists := response.Content[echo.MIMEApplicationJSON]; exists && content.Schema != nil {
+			if err := content.Schema.resolve(components, map[*schema]bool{}); err != nil {
+				return nil, err
+			}
+			compiled.Responses[status] = content.Schema
+		}
+	}
+	return compiled, nil
+}
+
+// resolve replaces the references of a schema and its subschemas with the
+// component schemas they name, and compiles their patterns
+func (s *schema) resolve(components map[string]*schema, seen map[*schema]bool) error {
+	if s == nil || seen[s] {
+		return nil
+	}
+	seen[s] = true
+
+	if s.Ref != "" {
+		target, exists := components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if !exists {
+			return fmt.Errorf("unknown schema reference %s", s.Ref)
+		}
+		if err := target.resolve(components, seen); err != nil {
+			return err
+		}
+		// Keywords beside a reference, such as descriptions, are ignored
+		*s = *target
+		return nil
+	}
+
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+	switch s.AdditionalProperties.Kind {
+	case yaml.ScalarNode:
+		allowed, err := strconv.ParseBool(s.AdditionalProperties.Value)
+		if err != nil {
+			return fmt.Errorf("invalid additionalProperties %q", s.AdditionalProperties.Value)
+		}
+		s.closed = !allowed
+	case yaml.MappingNode:
+		s.additional = &schema{}
+		if err := s.AdditionalProperties.Decode(s.additional); err != nil {
+			return err
+		}
+		if err := s.additional.resolve(components, seen); err != nil {
+			return err
+		}
+	}
+
+	for _, property := range s.Properties {
+		if err := property.resolve(components, seen); err != nil {
+			return err
+		}
+	}
+	return s.Items.resolve(components, seen)
+}
+
+// validate checks a value decoded from JSON with UseNumber against the
+// schema, and names the offending field of the first violation
+func (s *schema) validate(field string, value interface{}) error {
+	if value == nil {
+		if s.Nullable {
+			return nil
+		}
+		return fieldError(field, "must not be null")
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fieldError(field, "must be an object")
+		}
+		return s.validateObject(field, object)
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fieldError(field, "must be an array")
+		}
+		if s.MinItems != nil && len(array) < *s.MinItems {
+			return fieldError(field, fmt.Sprintf("must have at least %d items", *s.MinItems))
+		}
+		if s.MaxItems != nil && len(array) > *s.MaxItems {
+			return fieldError(field, fmt.Sprintf("must have at most %d items", *s.MaxItems))
+		}
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range array {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fieldError(field, "must be a string")
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+				return fieldError(field, "must be an RFC 3339 date-time")
+			}
+		}
+		if s.pattern != nil && !s.pattern.MatchString(text) {
+			return fieldError(field, fmt.Sprintf("must match %s", s.Pattern))
+		}
+		return s.validateEnum(field, text)
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fieldError(field, "must be a "+s.Type)
+		}
+		if s.Type == "integer" {
+			if _, err := strconv.ParseInt(number.String(), 10, 64); err != nil {
+				return fieldError(field, "must be an integer")
+			}
+		}
+		parsed, err := number.Float64()
+		if err != nil {
+			return fieldError(field, "must be a number")
+		}
+		if s.Minimum != nil && parsed < *s.Minimum {
+			return fieldError(field, fmt.Sprintf("must be at least %v", *s.Minimum))
+		}
+		if s.Maximum != nil && parsed > *s.Maximum {
+			return fieldError(field, fmt.Sprintf("must be at most %v", *s.Maximum))
+		}
+		return nil
+	case "boolean":
+		flag, ok := value.(bool)
+		if !ok {
+			return fieldError(field, "must be a boolean")
+		}
+		return s.validateEnum(field, flag)
+	default:
+		return nil
+	}
+}
+
+// validateObject checks the required and known properties of an object
+func (s *schema) validateObject(field string, object map[string]interface{}) error {
+	for _, name := range s.Required {
+		if _, exists := object[name]; !exists {
+			return fieldError(joinField(field, name), "is required")
+		}
+	}
+
+	// Properties are checked in name order so that errors are deterministic
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, known := s.Properties[name]
+		switch {
+		case known:
+		case s.additional != nil:
+			property = s.additional
+		case s.closed:
+			return fieldError(joinField(field, name), "is not allowed")
+		default:
+			continue
+		}
+		if err := property.validate(joinField(field, name), object[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEnum checks that a value is one of the values the schema allows
+func (s *schema) validateEnum(field string, value interface{}) error {
+	if len(s.Enum) == 0 {
+		return nil
+	}
+	allowed := make([]string, len(s.Enum))
+	for i, option := range s.Enum {
+		if option == value {
+			return nil
+		}
+		allowed[i] = fmt.Sprint(option)
+	}
+	return fieldError(field, "must be one of "+strings.Join(allowed, ", "))
+}
+
+// fieldError describes a violation of the schema of a field
+func fieldError(field, message string) error {
+	if field == "" {
+		return errors.New("request body " + message)
+	}
+	return fmt.Errorf("%s %s", field, message)
+}
+
+// joinField returns the path of a property of a field
+func joinField(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// operation returns the operation served by a method and Echo route path
+func (s *openAPISpec) operation(method, path string) *operation {
+	return s.operations[method+" "+path]
+}
+
+// validateRequest checks the query parameters and JSON body of a request
+// against the operation; an optional body may be left out. The body is left
+// in place for the handler.
+func (op *operation) validateRequest(c echo.Context) error {
+	for _, param := range op.Parameters {
+		if param.In != "query" || param.Schema == nil {
+			continue
+		}
+		value := c.QueryParam(param.Name)
+		if value == "" {
+			if param.Required {
+				return fmt.Errorf("query parameter %s is required", param.Name)
+			}
+			continue
+		}
+		if err := param.Schema.validate(param.Name, queryValue(param.Schema, value)); err != nil {
+			return fmt.Errorf("query parameter %w", err)
+		}
+	}
+
+	if op.Body == nil {
+		return nil
+	}
+	request := c.Request()
+	if request.Body == nil {
+		return errors.New("request body is required")
+	}
+	data, err := io.ReadAll(request.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	request.Body = io.NopCloser(bytes.NewReader(data))
+	if len(bytes.TrimSpace(data)) == 0 {
+		if op.BodyRequired {
+			return errors.New("request body is required")
+		}
+		return nil
+	}
+
+	body, err := decodeJSON(data)
+	if err != nil {
+		return errors.New("request body must be valid JSON")
+	}
+	return op.Body.validate("", body)
+}
+
+// queryValue converts a query parameter to the JSON value its schema expects,
+// leaving values of the wrong type as strings for validate to reject
+func queryValue(s *schema, value string) interface{} {
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if flag, err := strconv.ParseBool(value); err == nil {
+			return flag
+		}
+	}
+	return value
+}
+
+// decodeJSON decodes a single JSON value, keeping numbers exact
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// validateRequest rejects requests whose query parameters or body do not
+// match the specification of their operation
+func (h *Handlers) validateRequest(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		op := h.spec.operation(c.Request().Method, c.Path())
+		if op == nil {
+			return next(c)
+		}
+		if err := op.validateRequest(c); err != nil {
+			return errorResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return next(c)
+	}
+}
+
+// getOpenAPI serves the OpenAPI specification
+func (h *Handlers) getOpenAPI(c echo.Context) error {
+	return c.Blob(http.StatusOK, "text/yaml", apidocs.OpenAPI)
+}
+
+// docsPage renders the specification with Swagger UI
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>VJVector API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.yaml", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// getDocs serves the interactive API documentation
+func (h *Handlers) getDocs(c echo.Context) error {
+	return c.HTML(http.StatusOK, docsPage)
+}
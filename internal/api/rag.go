@@ -0,0 +1,819 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding/providers"
+	"github.com/vijaynallagatla/vjvector/pkg/rag"
+)
+
+// ragOperations lists the RAG operations the API performs
+var ragOperations = []models.RAGOperation{
+	models.RAGOperationQueryExpansion,
+	models.RAGOperationResultReranking,
+	models.RAGOperationContextRetrieval,
+	models.RAGOperationEndToEndRAG,
+	models.RAGOperationBatchSearch,
+	models.RAGOperationBatchRerank,
+}
+
+// defaultRAGResults is the number of results searched when the search
+// configuration of a request sets none
+const defaultRAGResults = 10
+
+// ragStats counts the RAG queries served by the API
+type ragStats struct {
+	mutex        sync.Mutex
+	total        int64
+	successful   int64
+	failed       int64
+	totalLatency time.Duration
+	lastQuery    time.Time
+}
+
+// record counts a query that took latency and failed with err, if not nil
+func (s *ragStats) record(latency time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.total++
+	if err != nil {
+		s.failed++
+	} else {
+		s.successful++
+	}
+	s.totalLatency += latency
+	s.lastQuery = time.Now()
+}
+
+// processRAGQuery performs a RAG operation for a single query
+func (h *Handlers) processRAGQuery(c echo.Context) error {
+	var req models.RAGRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	response, err := h.processRAG(c.Request().Context(), &req)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// processRAGStream performs a RAG operation for a single query like
+// processRAGQuery, streaming each step as soon as it finishes: the expanded
+// queries, the candidates of the search and the reranked results, then the
+// response in a done event. Clients disconnecting cancel the operation at its
+// next step.
+func (h *Handlers) processRAGStream(c echo.Context) error {
+	var req models.RAGRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	defer cancel()
+	stream := &ragStream{
+		response: c.Response(),
+		sse:      strings.Contains(c.Request().Header.Get(echo.HeaderAccept), eventStreamMIME),
+		start:    time.Now(),
+		cancel:   cancel,
+	}
+	ctx = rag.WithQueryTrace(ctx, &rag.QueryTrace{
+		Expanded: func(terms []string) {
+			stream.send(models.RAGStreamEvent{Stage: models.RAGStageExpanded, ExpandedQueries: terms})
+		},
+		Candidates: func(results []*rag.QueryResult) {
+			stream.send(models.RAGStreamEvent{Stage: models.RAGStageCandidates, Results: searchResults(results, req.Collection)})
+		},
+		Reranked: func(results []*rag.QueryResult) {
+			stream.send(models.RAGStreamEvent{Stage: models.RAGStageReranked, Results: searchResults(results, req.Collection)})
+		},
+	})
+
+	response, err := h.processRAG(ctx, &req)
+	switch {
+	case err != nil && !stream.sent:
+		// Failures before the first step finished keep their status
+		return errorResponse(c, errorStatus(err), err.Error())
+	case err != nil:
+		stream.send(models.RAGStreamEvent{Stage: models.RAGStageError, Error: err.Error(), Code: errorCode(err)})
+	default:
+		stream.send(models.RAGStreamEvent{Stage: models.RAGStageDone, Response: response})
+	}
+	if stream.err != nil && !errors.Is(stream.err, context.Canceled) {
+		c.Logger().Errorf("rag stream ended: %v", stream.err)
+	}
+	return nil
+}
+
+// eventStreamMIME is the media type of server-sent events
+const eventStreamMIME = "text/event-stream"
+
+// ragStream writes the events of a streamed RAG operation, as server-sent
+// events named after their stage or as newline-delimited JSON. The status is
+// only sent with the first event, so that failures found before keep theirs.
+type ragStream struct {
+	response *echo.Response
+	sse      bool
+	start    time.Time
+	sent     bool
+	err      error
+
+	// cancel stops the operation once the client can no longer be written to
+	cancel context.CancelFunc
+}
+
+// send writes an event, unless writing an earlier one failed
+func (s *ragStream) send(event models.RAGStreamEvent) {
+	if s.err != nil {
+		return
+	}
+	event.ElapsedTime = time.Since(s.start)
+	data, err := json.Marshal(event)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	if !s.sent {
+		header := s.response.Header()
+		if s.sse {
+			header.Set(echo.HeaderContentType, eventStreamMIME)
+		} else {
+			header.Set(echo.HeaderContentType, "application/x-ndjson")
+		}
+		header.Set(echo.HeaderCacheControl, "no-cache")
+		s.response.WriteHeader(http.StatusOK)
+		s.sent = true
+	}
+	if s.sse {
+		_, err = fmt.Fprintf(s.response, "event: %s\ndata: %s\n\n", event.Stage, data)
+	} else {
+		_, err = fmt.Fprintf(s.response, "%s\n", data)
+	}
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	s.response.Flush()
+}
+
+// fail ends the stream with the error of a write
+func (s *ragStream) fail(err error) {
+	s.err = err
+	s.cancel()
+}
+
+// processBatchRAG performs a RAG operation for every query of a batch. The
+// queries run in batches of batch_size, max_concurrent at a time; queries that
+// fail, or do not finish within the timeout, are reported as batch errors.
+func (h *Handlers) processBatchRAG(c echo.Context) error {
+	var req models.BatchRAGRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+	if len(req.Queries) == 0 {
+		return errorResponse(c, http.StatusBadRequest, "at least one query is required")
+	}
+
+	ctx := c.Request().Context()
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil || timeout <= 0 {
+			return errorResponse(c, http.StatusBadRequest, "timeout must be a positive duration such as 30s")
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Requests every query would fail are rejected as a whole
+	if req.Operation != models.RAGOperationQueryExpansion {
+		if req.Collection == "" {
+			return errorResponse(c, http.StatusBadRequest, fmt.Sprintf("%v: %s requires a collection", ErrInvalidRAGRequest, req.Operation))
+		}
+		if _, err := h.getCollection(ctx, req.Collection); err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+	}
+
+	batchSize := req.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(req.Queries)
+	}
+	concurrency := req.MaxConcurrent
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	start := time.Now()
+	responses := make([]*models.RAGResponse, len(req.Queries))
+	failures := make([]error, len(req.Queries))
+	for first := 0; first < len(req.Queries); first += batchSize {
+		last := min(first+batchSize, len(req.Queries))
+		h.processRAGBatch(ctx, &req, first, last, concurrency, responses, failures)
+	}
+	end := time.Now()
+
+	batch := &models.BatchRAGResponse{
+		Operation:      req.Operation,
+		Results:        make([]models.RAGResponse, 0, len(req.Queries)),
+		ProcessingTime: end.Sub(start),
+	}
+	var latency, rerankingTime time.Duration
+	var expansions, reranked int
+	metrics := &batch.RAGMetrics
+	for i, response := range responses {
+		if failures[i] != nil {
+			batch.Errors = append(batch.Errors, models.BatchError{
+				Index:   i,
+				Message: failures[i].Error(),
+				Code:    errorCode(failures[i]),
+			})
+			continue
+		}
+		batch.Results = append(batch.Results, *response)
+		latency += response.ProcessingTime
+		expansions += len(response.ExpandedQueries)
+		if len(response.ExpandedQueries) > 0 {
+			metrics.QueryExpansionCount++
+		}
+		if len(response.ContextEnhancements) > 0 {
+			metrics.ContextEnhancementCount++
+		}
+		if elapsed, ok := response.Metadata["reranking_time"].(time.Duration); ok {
+			metrics.RerankingCount += len(response.RerankedResults)
+			rerankingTime += elapsed
+			reranked++
+		}
+	}
+
+	batch.ProcessedCount = len(batch.Results)
+	batch.ErrorCount = len(batch.Errors)
+	batch.Statistics = models.BatchStatistics{
+		StartTime:      start,
+		EndTime:        end,
+		TotalItems:     len(req.Queries),
+		ProcessedItems: batch.ProcessedCount,
+		FailedItems:    batch.ErrorCount,
+	}
+	if elapsed := end.Sub(start).Seconds(); elapsed > 0 {
+		batch.Statistics.Throughput = float64(batch.ProcessedCount) / elapsed
+	}
+	if batch.ProcessedCount > 0 {
+		batch.Statistics.AverageLatency = latency / time.Duration(batch.ProcessedCount)
+		metrics.AverageExpansionRatio = float64(expansions) / float64(batch.ProcessedCount)
+	}
+	if reranked > 0 {
+		metrics.AverageRerankingTime = rerankingTime / time.Duration(reranked)
+	}
+
+	return c.JSON(http.StatusOK, batch)
+}
+
+// processRAGBatch processes the queries of a batch between first and last,
+// concurrency at a time, storing their responses or failures at their index
+func (h *Handlers) processRAGBatch(ctx context.Context, req *models.BatchRAGRequest, first, last, concurrency int,
+	responses []*models.RAGResponse, failures []error) {
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := first; i < last; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			responses[i], failures[i] = h.processRAG(ctx, &models.RAGRequest{
+				Operation:  req.Operation,
+				Query:      req.Queries[i],
+				Context:    req.Context,
+				Collection: req.Collection,
+				Options:    req.Options,
+				RAGConfig:  req.RAGConfig,
+			})
+		}(i)
+	}
+	wg.Wait()
+}
+
+// processRAG performs a RAG operation for a query and records it in the RAG
+// statistics. Query expansion only rewrites the query; every other operation
+// searches a collection with the embedding of the processed query.
+// End-to-end RAG expands the query, adds the context of the query and reranks
+// the results, unless the configuration of the request disables a step.
+func (h *Handlers) processRAG(ctx context.Context, req *models.RAGRequest) (*models.RAGResponse, error) {
+	start := time.Now()
+	response, err := h.performRAG(ctx, req)
+	latency := time.Since(start)
+
+	h.ragStats.record(latency, err)
+	if h.server != nil && h.server.Metrics() != nil && err == nil {
+		h.server.Metrics().RecordRAGQuery(latency, len(response.ContextEnhancements))
+	}
+	if err != nil {
+		return nil, err
+	}
+	response.ProcessingTime = latency
+	return response, nil
+}
+
+// performRAG performs the steps of a RAG operation, reporting them to the
+// query trace of ctx, and stops between steps once ctx is done
+func (h *Handlers) performRAG(ctx context.Context, req *models.RAGRequest) (*models.RAGResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	trace := rag.ContextQueryTrace(ctx)
+
+	config := req.RAGConfig
+	operation := req.Operation
+	endToEnd := operation == models.RAGOperationEndToEndRAG
+	expand := operation == models.RAGOperationQueryExpansion || endToEnd && enabled(config.EnableQueryExpansion)
+	contextual := operation == models.RAGOperationContextRetrieval || endToEnd && enabled(config.EnableContextAwareness)
+	search := operation != models.RAGOperationQueryExpansion
+	rerank := operation == models.RAGOperationResultReranking || operation == models.RAGOperationBatchRerank ||
+		endToEnd && enabled(config.EnableResultReranking)
+
+	var collection *core.Collection
+	var service embedding.Service
+	if search || req.Collection != "" {
+		if req.Collection == "" {
+			return nil, fmt.Errorf("%w: %s requires a collection", ErrInvalidRAGRequest, operation)
+		}
+		var err error
+		if collection, err = h.getCollection(ctx, req.Collection); err != nil {
+			return nil, err
+		}
+		if err := checkSearchConfig(config.SearchConfig, collection); err != nil {
+			return nil, err
+		}
+		if service, err = h.embedder(); err != nil {
+			return nil, err
+		}
+	}
+
+	query := &rag.Query{
+		Text:    req.Query,
+		Type:    rag.QueryTypeSemantic,
+		Context: copyMap(req.Context),
+		Options: req.Options,
+	}
+	response := &models.RAGResponse{
+		Operation:     operation,
+		Query:         req.Query,
+		OriginalQuery: req.Query,
+		Results:       []models.SearchResult{},
+		Confidence:    1.0,
+		Metadata:      map[string]interface{}{},
+	}
+	terms := []string{req.Query}
+
+	if expand {
+		managerConfig, err := expansionConfig(config.QueryExpansionConfig)
+		if err != nil {
+			return nil, err
+		}
+		expanded, err := rag.NewQueryExpansionManager(managerConfig).ExpandQuery(ctx, query, service)
+		if err != nil {
+			return nil, fmt.Errorf("query expansion failed: %w", err)
+		}
+		response.ExpandedQueries = withDomainSynonyms(config.QueryExpansionConfig, req.Query, expanded)
+		terms = append(terms, response.ExpandedQueries...)
+		trace.Expanded(response.ExpandedQueries)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if contextual {
+		enhanced, err := rag.NewContextAwareRetrievalManager(contextConfig(config.ContextConfig)).
+			ProcessContextAwareQuery(ctx, query, service)
+		if err != nil {
+			return nil, fmt.Errorf("context-aware retrieval failed: %w", err)
+		}
+		if config.ContextConfig == nil || enhanced.Confidence >= config.ContextConfig.ConfidenceThreshold {
+			response.ContextEnhancements = enhanced.Enhancements
+			response.Confidence = enhanced.Confidence
+			for key, value := range enhanced.Context {
+				query.Context[key] = value
+			}
+			// The detected domain steers the search towards documents of the domain
+			if domain, ok := enhanced.Context["detected_domain"].(string); ok {
+				terms = append(terms, domain)
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	response.Query = strings.Join(terms, " ")
+	if !search {
+		return response, nil
+	}
+	response.Metadata["collection"] = collection.Name
+
+	results, err := h.ragSearch(ctx, collection, service, response.Query, config.SearchConfig)
+	if err != nil {
+		return nil, err
+	}
+	response.Results = searchResults(results, collection.Name)
+	best := results
+	trace.Candidates(results)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if rerank && len(results) > 0 {
+		managerConfig, err := rerankingConfig(config.RerankingConfig)
+		if err != nil {
+			return nil, err
+		}
+		rerankStart := time.Now()
+		reranked, err := rag.NewResultRerankingManager(managerConfig).RerankResults(ctx, copyResults(results), query, service)
+		if err != nil {
+			return nil, fmt.Errorf("result reranking failed: %w", err)
+		}
+		response.Metadata["reranking_time"] = time.Since(rerankStart)
+		response.RerankedResults = searchResults(reranked, collection.Name)
+		best = reranked
+		trace.Reranked(reranked)
+	}
+
+	// Confidence is the score of the best result, clamped to [0, 1]
+	if len(best) == 0 {
+		response.Confidence = 0
+	} else {
+		response.Confidence *= max(0, min(1, best[0].Score))
+	}
+	response.Metadata["total_results"] = len(response.Results)
+	return response, nil
+}
+
+// ragSearch embeds a query and searches a collection for it, keeping the
+// results that pass the threshold and filters of the search configuration
+func (h *Handlers) ragSearch(ctx context.Context, collection *core.Collection, service embedding.Service,
+	text string, config *models.SearchConfig) ([]*rag.QueryResult, error) {
+	k := defaultRAGResults
+	if config != nil && config.MaxResults > 0 {
+		k = config.MaxResults
+	}
+
+	embeddings, err := service.GenerateEmbeddings(ctx, &embedding.EmbeddingRequest{Texts: []string{text}})
+	if err != nil {
+		return nil, fmt.Errorf("embedding generation failed: %w", err)
+	}
+	if len(embeddings.Embeddings) != 1 {
+		return nil, fmt.Errorf("embedding generation returned %d embeddings for 1 text", len(embeddings.Embeddings))
+	}
+
+	found, err := h.search(ctx, collection.Name, &models.SearchRequest{Query: embeddings.Embeddings[0], K: k})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*rag.QueryResult, 0, len(found))
+	for _, result := range found {
+		if result.Vector == nil {
+			continue
+		}
+		if config != nil && result.Score < config.Threshold {
+			continue
+		}
+		if config != nil && config.EnableFilters && !matchesFilters(result.Vector.Metadata, config.Filters) {
+			continue
+		}
+		results = append(results, &rag.QueryResult{
+			Vector:    result.Vector,
+			Score:     result.Score,
+			Distance:  result.Distance,
+			Relevance: result.Score,
+			Metadata:  result.Vector.Metadata,
+		})
+	}
+	return results, nil
+}
+
+// checkSearchConfig rejects search configurations the index of the collection
+// cannot serve
+func checkSearchConfig(config *models.SearchConfig, collection *core.Collection) error {
+	if config == nil {
+		return nil
+	}
+	if config.IndexType != "" && config.IndexType != collection.IndexType {
+		return fmt.Errorf("%w: collection %s has a %s index, not %s",
+			ErrInvalidRAGRequest, collection.Name, collection.IndexType, config.IndexType)
+	}
+	if config.SimilarityMetric != "" && collection.DistanceMetric != "" && config.SimilarityMetric != collection.DistanceMetric {
+		return fmt.Errorf("%w: collection %s uses the %s metric, not %s",
+			ErrInvalidRAGRequest, collection.Name, collection.DistanceMetric, config.SimilarityMetric)
+	}
+	return nil
+}
+
+// matchesFilters reports whether metadata holds every filter value
+func matchesFilters(metadata, filters map[string]interface{}) bool {
+	for key, want := range filters {
+		value, exists := metadata[key]
+		if !exists || fmt.Sprint(value) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// expansionConfig converts the query expansion configuration of a request
+func expansionConfig(config *models.QueryExpansionConfig) (*rag.ExpansionConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	converted := &rag.ExpansionConfig{
+		MaxExpansionTerms:  5,
+		MinConfidence:      0.3,
+		EnableSemantic:     true,
+		EnableSynonym:      true,
+		EnableContextAware: true,
+		MaxExpansionDepth:  2,
+		SemanticThreshold:  0.7,
+		ContextWeight:      0.6,
+	}
+	if config.MaxExpansions > 0 {
+		converted.MaxExpansionTerms = config.MaxExpansions
+	}
+	if config.SimilarityThreshold > 0 {
+		converted.MinConfidence = config.SimilarityThreshold
+	}
+	if len(config.Strategies) > 0 {
+		converted.EnableSemantic, converted.EnableSynonym, converted.EnableContextAware = false, false, false
+		for _, strategy := range config.Strategies {
+			switch strategy {
+			case "semantic":
+				converted.EnableSemantic = true
+			case "synonym":
+				converted.EnableSynonym = true
+			case "context_aware":
+				converted.EnableContextAware = true
+			default:
+				return nil, fmt.Errorf("%w: unknown query expansion strategy %q", ErrInvalidRAGRequest, strategy)
+			}
+		}
+	}
+	return converted, nil
+}
+
+// withDomainSynonyms puts the domain synonyms of the words of a query before
+// its expansions, without duplicates and within the expansion limit
+func withDomainSynonyms(config *models.QueryExpansionConfig, text string, expanded []string) []string {
+	if config == nil || len(config.DomainSynonyms) == 0 {
+		return expanded
+	}
+
+	var synonyms []string
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		synonyms = append(synonyms, config.DomainSynonyms[word]...)
+	}
+
+	seen := make(map[string]bool)
+	merged := make([]string, 0, len(synonyms)+len(expanded))
+	for _, term := range append(synonyms, expanded...) {
+		if !seen[term] {
+			seen[term] = true
+			merged = append(merged, term)
+		}
+	}
+	if config.MaxExpansions > 0 && len(merged) > config.MaxExpansions {
+		merged = merged[:config.MaxExpansions]
+	}
+	return merged
+}
+
+// rerankingConfig converts the reranking configuration of a request
+func rerankingConfig(config *models.RerankingConfig) (*rag.RerankingConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	converted := &rag.RerankingConfig{
+		EnableSemanticReranking: true,
+		EnableContextReranking:  true,
+		EnableHybridScoring:     true,
+		SemanticWeight:          0.4,
+		ContextWeight:           0.3,
+		VectorWeight:            0.3,
+		MinRerankingConfidence:  0.5,
+		MaxRerankedResults:      100,
+	}
+	if config.MaxResults > 0 {
+		converted.MaxRerankedResults = config.MaxResults
+	}
+	if len(config.Strategies) > 0 {
+		converted.EnableSemanticReranking, converted.EnableContextReranking, converted.EnableHybridScoring = false, false, false
+		for _, strategy := range config.Strategies {
+			switch strategy {
+			case "semantic":
+				converted.EnableSemanticReranking = true
+			case "context_aware":
+				converted.EnableContextReranking = true
+			case "hybrid":
+				converted.EnableHybridScoring = true
+			default:
+				return nil, fmt.Errorf("%w: unknown reranking strategy %q", ErrInvalidRAGRequest, strategy)
+			}
+		}
+	}
+
+	// Weights set explicitly replace all three defaults
+	if config.SemanticWeight > 0 || config.ContextWeight > 0 || config.HybridWeight > 0 || len(config.Weights) > 0 {
+		converted.SemanticWeight = config.SemanticWeight
+		converted.ContextWeight = config.ContextWeight
+		converted.VectorWeight = config.HybridWeight
+		for name, weight := range config.Weights {
+			switch name {
+			case "semantic":
+				converted.SemanticWeight = weight
+			case "context":
+				converted.ContextWeight = weight
+			case "vector":
+				converted.VectorWeight = weight
+			default:
+				return nil, fmt.Errorf("%w: unknown reranking weight %q", ErrInvalidRAGRequest, name)
+			}
+		}
+	}
+	return converted, nil
+}
+
+// contextConfig converts the context configuration of a request
+func contextConfig(config *models.ContextConfig) *rag.ContextRetrievalConfig {
+	if config == nil {
+		return nil
+	}
+
+	converted := &rag.ContextRetrievalConfig{
+		EnableUserContext:     config.UserContext,
+		EnableDomainContext:   config.DomainContext,
+		EnableTemporalContext: config.TemporalContext,
+		EnableLocationContext: config.LocationContext,
+		UserContextWeight:     0.3,
+		DomainContextWeight:   0.25,
+		TemporalContextWeight: 0.25,
+		LocationContextWeight: 0.2,
+		ContextDecayRate:      0.1,
+		MaxContextDepth:       3,
+	}
+	if config.ContextDecay > 0 {
+		converted.ContextDecayRate = config.ContextDecay
+	}
+	return converted
+}
+
+// searchResults converts RAG results of a collection into the search results
+// of the API, ranked in order
+func searchResults(results []*rag.QueryResult, collection string) []models.SearchResult {
+	converted := make([]models.SearchResult, len(results))
+	for i, result := range results {
+		vector := result.Vector
+		converted[i] = models.SearchResult{
+			Vector: &models.Vector{
+				ID:         vector.ID,
+				Collection: collection,
+				Embedding:  vector.Embedding,
+				Metadata:   vector.Metadata,
+				ExpiresAt:  vector.ExpiresAt,
+			},
+			Score:      result.Score,
+			Rank:       i + 1,
+			Similarity: result.Relevance,
+			Metadata:   vector.Metadata,
+		}
+	}
+	return converted
+}
+
+// copyResults copies results, since reranking rescores them in place
+func copyResults(results []*rag.QueryResult) []*rag.QueryResult {
+	copied := make([]*rag.QueryResult, len(results))
+	for i, result := range results {
+		duplicate := *result
+		copied[i] = &duplicate
+	}
+	return copied
+}
+
+// copyMap returns a shallow copy of a map, never nil
+func copyMap(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
+
+// enabled reports whether an optional flag is set; unset flags are enabled
+func enabled(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+// SetEmbeddingProvider sets the provider that embeds the texts of RAG
+// queries, ingested records and jobs. Its embeddings must have the dimension
+// of the collections they are searched in or written to, and match the model
+// their vectors were embedded with.
+func (h *Handlers) SetEmbeddingProvider(provider embedding.Provider) error {
+	service, err := providers.NewProviderService(provider)
+	if err != nil {
+		return err
+	}
+	if h.embedding != nil {
+		_ = h.embedding.Close()
+	}
+	h.embedding, h.embeddingProvider = service, provider
+	return nil
+}
+
+// embedder returns the embedding service of the configured provider
+func (h *Handlers) embedder() (embedding.Service, error) {
+	if h.embedding == nil {
+		return nil, fmt.Errorf("%w: set VJVECTOR_EMBEDDING_PROVIDER, or send embeddings", ErrNoEmbeddingModel)
+	}
+	return h.embedding, nil
+}
+
+// embeddingModels names the model texts are embedded with, if any
+func (h *Handlers) embeddingModels() []string {
+	switch provider := h.embeddingProvider.(type) {
+	case nil:
+		return []string{}
+	case interface{ Model() string }:
+		return []string{provider.Model()}
+	default:
+		return []string{provider.Name()}
+	}
+}
+
+// getRAGCapabilities lists the RAG operations and features of the API
+func (h *Handlers) getRAGCapabilities(c echo.Context) error {
+	operations := make([]string, len(ragOperations))
+	for i, operation := range ragOperations {
+		operations[i] = string(operation)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"operations": operations,
+		"features": map[string]bool{
+			"query_expansion":   true,
+			"result_reranking":  true,
+			"context_awareness": true,
+			"batch_processing":  true,
+			"metadata_filters":  true,
+			"result_caching":    false,
+		},
+		// Queries are embedded by the configured model
+		"supported_models": h.embeddingModels(),
+	})
+}
+
+// getRAGStatistics reports the RAG queries served since the server started
+func (h *Handlers) getRAGStatistics(c echo.Context) error {
+	stats := &h.ragStats
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	var average time.Duration
+	if stats.total > 0 {
+		average = stats.totalLatency / time.Duration(stats.total)
+	}
+	lastQuery := stats.lastQuery
+	sinceLastQuery := "never"
+	if !lastQuery.IsZero() {
+		sinceLastQuery = time.Since(lastQuery).Round(time.Millisecond).String()
+	} else {
+		lastQuery = h.started
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"total_queries":      stats.total,
+		"successful_queries": stats.successful,
+		"failed_queries":     stats.failed,
+		"average_latency":    average.String(),
+		"cache_hits":         0,
+		"cache_misses":       stats.total,
+		"cache_hit_rate":     0.0,
+		"last_query_time":    lastQuery.UTC(),
+		"uptime":             sinceLastQuery,
+	})
+}
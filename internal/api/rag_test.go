@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/rag"
+)
+
+// createRAGIndex creates the docs index holding the contract documents
+func createRAGIndex(t *testing.T, e *echo.Echo) {
+	t.Helper()
+
+	recorder := serve(e, http.MethodPost, "/v1/indexes", map[string]interface{}{
+		"id": "docs", "type": "hnsw", "dimension": contractDimension, "max_elements": 100,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create index: %s", recorder.Body.String())
+	}
+	for id, text := range contractDocuments {
+		recorder = serve(e, http.MethodPost, "/v1/indexes/docs/vectors", map[string]interface{}{
+			"vectors": []interface{}{map[string]interface{}{"id": id, "embedding": embedText(t, text)}},
+		})
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Failed to insert %s: %s", id, recorder.Body.String())
+		}
+	}
+}
+
+// streamRAG sends a streamed RAG query, as server-sent events when sse is
+// set, and decodes the events of its response
+func streamRAG(t *testing.T, e *echo.Echo, body interface{}, sse bool) (*httptest.ResponseRecorder, []models.RAGStreamEvent) {
+	t.Helper()
+
+	data, _ := json.Marshal(body)
+	request := httptest.NewRequest(http.MethodPost, "/v1/rag/query/stream", bytes.NewReader(data))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if sse {
+		request.Header.Set(echo.HeaderAccept, eventStreamMIME)
+	}
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		return recorder, nil
+	}
+
+	var events []models.RAGStreamEvent
+	messages := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	if sse {
+		messages = strings.Split(strings.TrimSpace(recorder.Body.String()), "\n\n")
+	}
+	for _, message := range messages {
+		name := ""
+		if sse {
+			var found bool
+			name, message, found = strings.Cut(message, "\n")
+			if !found || !strings.HasPrefix(name, "event: ") || !strings.HasPrefix(message, "data: ") {
+				t.Fatalf("Expected a server-sent event, got %q", message)
+			}
+			name, message = strings.TrimPrefix(name, "event: "), strings.TrimPrefix(message, "data: ")
+		}
+
+		var event models.RAGStreamEvent
+		if err := json.Unmarshal([]byte(message), &event); err != nil {
+			t.Fatalf("Failed to decode event %q: %v", message, err)
+		}
+		if sse && name != string(event.Stage) {
+			t.Errorf("Expected the event to be named %s, got %s", event.Stage, name)
+		}
+		events = append(events, event)
+	}
+	return recorder, events
+}
+
+// stages returns the stages of stream events in order
+func stages(events []models.RAGStreamEvent) []models.RAGStage {
+	found := make([]models.RAGStage, len(events))
+	for i, event := range events {
+		found[i] = event.Stage
+	}
+	return found
+}
+
+func TestRAGQueryStream(t *testing.T) {
+	e, _ := newTestRouter(t)
+	createRAGIndex(t, e)
+
+	query := map[string]interface{}{
+		"operation": "end_to_end_rag", "query": "approximate nearest neighbors", "collection": "docs",
+		"rag_config": map[string]interface{}{"enable_context_awareness": false},
+	}
+	want := []models.RAGStage{models.RAGStageExpanded, models.RAGStageCandidates, models.RAGStageReranked, models.RAGStageDone}
+	for _, sse := range []bool{false, true} {
+		recorder, events := streamRAG(t, e, query, sse)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Failed to stream query: %s", recorder.Body.String())
+		}
+		contentType := "application/x-ndjson"
+		if sse {
+			contentType = eventStreamMIME
+		}
+		if recorder.Header().Get(echo.HeaderContentType) != contentType {
+			t.Errorf("Expected content type %s, got %s", contentType, recorder.Header().Get(echo.HeaderContentType))
+		}
+		if got := stages(events); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Expected stages %v, got %v", want, got)
+		}
+
+		// The done event holds the response of the steps streamed before it
+		response := events[3].Response
+		if response == nil || len(response.Results) == 0 {
+			t.Fatalf("Expected a response with results, got %+v", events[3])
+		}
+		if len(events[1].Results) != len(response.Results) || events[1].Results[0].Vector.ID != response.Results[0].Vector.ID {
+			t.Errorf("Expected the candidates to be the results of the response, got %+v", events[1].Results)
+		}
+		if len(events[2].Results) != len(response.RerankedResults) {
+			t.Errorf("Expected the reranked results of the response, got %+v", events[2].Results)
+		}
+		for i := 1; i < len(events); i++ {
+			if events[i].ElapsedTime < events[i-1].ElapsedTime {
+				t.Errorf("Expected elapsed times to grow, got %v", events)
+			}
+		}
+	}
+
+	// Steps the operation does not run are not streamed
+	_, events := streamRAG(t, e, map[string]interface{}{"operation": "query_expansion", "query": "fast search"}, false)
+	if got := stages(events); fmt.Sprint(got) != "[expanded done]" {
+		t.Errorf("Expected expanded and done stages, got %v", got)
+	}
+
+	// Failures before the first step keep their status
+	recorder, _ := streamRAG(t, e, map[string]interface{}{
+		"operation": "batch_search", "query": "nearest neighbors", "collection": "missing",
+	}, true)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing collection, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestRAGCancellation(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	createRAGIndex(t, e)
+
+	// Operations stop at the step after the one their context is done in
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	searched := false
+	ctx = rag.WithQueryTrace(ctx, &rag.QueryTrace{
+		Expanded:   func([]string) { cancel() },
+		Candidates: func([]*rag.QueryResult) { searched = true },
+	})
+	_, err := handlers.processRAG(ctx, &models.RAGRequest{
+		Operation: models.RAGOperationEndToEndRAG, Query: "nearest neighbors", Collection: "docs",
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if searched {
+		t.Error("Expected the collection not to be searched after the cancellation")
+	}
+	if handlers.ragStats.failed != 1 {
+		t.Errorf("Expected the query to be counted as failed, got %d failures", handlers.ragStats.failed)
+	}
+}
+
+func TestRAGWithoutEmbeddingModel(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	createRAGIndex(t, e)
+	handlers.embedding, handlers.embeddingProvider = nil, nil
+
+	// Texts are not embedded without a model, rather than embedded by another
+	recorder := serve(e, http.MethodPost, "/v1/rag/query", map[string]interface{}{
+		"operation": "batch_search", "query": "nearest neighbors", "collection": "docs",
+	})
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 for a RAG query, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	recorder = serve(e, http.MethodPost, "/v1/indexes/docs/ingest?embed=true", `{"id": "a", "text": "raft"}`)
+	if recorder.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 for an embedding ingest, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = serve(e, http.MethodGet, "/v1/rag/capabilities", nil)
+	if !strings.Contains(recorder.Body.String(), `"supported_models":[]`) {
+		t.Errorf("Expected no supported models, got %s", recorder.Body.String())
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/pkg/enterprise"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Response headers reporting the rate limit of a request; the gRPC API sends
+// the same names in the response header metadata
+const (
+	rateLimitLimitHeader     = "X-RateLimit-Limit"
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+	rateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// tenantRateWindow is the window of the API rate limit of tenant settings,
+// which counts requests per minute
+const tenantRateWindow = time.Minute
+
+// EnableRateLimiting limits the requests of each tenant, API key and route on
+// both the REST and gRPC APIs; a nil config selects the default limits. A
+// tenant is limited to the API rate limit of its settings in the tenant
+// service, and changes to the settings take effect on its next request.
+func (h *Handlers) EnableRateLimiting(config *enterprise.RateLimitConfig) {
+	h.rateLimiter = enterprise.NewDefaultRateLimiter(config)
+	h.tenantRates = make(map[string]int)
+}
+
+// takeRateLimit counts a request to an endpoint against the rate limits of its
+// tenant and API key, reporting denied requests to the webhooks of the tenant
+func (h *Handlers) takeRateLimit(ctx context.Context, endpoint string) enterprise.RateLimitDecision {
+	request := enterprise.RateLimitRequest{TenantID: h.tenantOf(ctx), Endpoint: endpoint}
+	if key := apiKeyFrom(ctx); key != nil {
+		request.KeyID = key.ID
+	}
+
+	h.syncTenantRate(ctx, request.TenantID)
+	decision := h.rateLimiter.Take(ctx, request)
+	if !decision.Allowed {
+		h.publishQuotaExceeded(request, decision)
+	}
+	return decision
+}
+
+// syncTenantRate applies the API rate limit of the settings of a tenant to the
+// rate limiter when it changed since the last request of the tenant. Tenants
+// without settings or a positive limit get the default tenant limit, also
+// once their limit is removed.
+func (h *Handlers) syncTenantRate(ctx context.Context, tenantID string) {
+	rate := 0
+	if settings, err := h.tenants.GetTenantSettings(ctx, tenantID); err == nil && settings != nil {
+		rate = max(settings.APIRateLimit, 0)
+	}
+
+	h.tenantRatesMutex.Lock()
+	defer h.tenantRatesMutex.Unlock()
+	if h.tenantRates[tenantID] == rate {
+		return
+	}
+	if rate == 0 {
+		if err := h.rateLimiter.ResetTenantLimit(ctx, tenantID); err == nil {
+			delete(h.tenantRates, tenantID)
+		}
+		return
+	}
+	if err := h.rateLimiter.SetTenantRate(ctx, tenantID, rate, tenantRateWindow); err == nil {
+		h.tenantRates[tenantID] = rate
+	}
+}
+
+// rateLimitError returns the error of a request denied by a decision
+func rateLimitError(decision enterprise.RateLimitDecision) error {
+	if decision.Allowed {
+		return nil
+	}
+	return fmt.Errorf("%w: retry after %ds", ErrRateLimited, retryAfterSeconds(decision))
+}
+
+// setRateLimitHeaders reports a decision in the rate limit headers, with
+// Retry-After when the request was denied
+func setRateLimitHeaders(set func(name, value string), decision enterprise.RateLimitDecision) {
+	set(rateLimitLimitHeader, strconv.Itoa(decision.Limit))
+	set(rateLimitRemainingHeader, strconv.Itoa(decision.Remaining))
+	set(rateLimitResetHeader, strconv.Itoa(ceilSeconds(decision.Reset)))
+	if !decision.Allowed {
+		set(echo.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(decision)))
+	}
+}
+
+// retryAfterSeconds returns the whole seconds to wait after a denied request
+func retryAfterSeconds(decision enterprise.RateLimitDecision) int {
+	return max(1, ceilSeconds(decision.RetryAfter))
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// limitRate rejects requests over the rate limit of their tenant, API key or
+// route with 429, reporting the limit that applies in the rate limit headers
+func (h *Handlers) limitRate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		request := c.Request()
+		if h.rateLimiter == nil || publicRoutes[request.Method+" "+c.Path()] {
+			return next(c)
+		}
+
+		decision := h.takeRateLimit(request.Context(), request.Method+":"+c.Path())
+		setRateLimitHeaders(c.Response().Header().Set, decision)
+		if err := rateLimitError(decision); err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+		return next(c)
+	}
+}
+
+// limitCall counts a gRPC call against the rate limits, returning the header
+// metadata reporting them
+func (h *Handlers) limitCall(ctx context.Context, method string) (metadata.MD, error) {
+	decision := h.takeRateLimit(ctx, method)
+	header := metadata.MD{}
+	setRateLimitHeaders(func(name, value string) { header.Set(name, value) }, decision)
+	return header, rateLimitError(decision)
+}
+
+// rateLimitUnaryInterceptor rate limits unary calls
+func (h *Handlers) rateLimitUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if h.rateLimiter == nil {
+		return handler(ctx, req)
+	}
+	header, err := h.limitCall(ctx, info.FullMethod)
+	_ = grpc.SetHeader(ctx, header)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return handler(ctx, req)
+}
+
+// rateLimitStreamInterceptor rate limits streaming calls
+func (h *Handlers) rateLimitStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if h.rateLimiter == nil {
+		return handler(srv, stream)
+	}
+	header, err := h.limitCall(stream.Context(), info.FullMethod)
+	_ = stream.SetHeader(header)
+	if err != nil {
+		return grpcError(err)
+	}
+	return handler(srv, stream)
+}
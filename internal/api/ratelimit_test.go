@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	vjvectorv1 "github.com/vijaynallagatla/vjvector/api/vjvector/v1"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/enterprise"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// testRateLimits are limits that only the tenant, key and endpoint under test
+// reach
+func testRateLimits() *enterprise.RateLimitConfig {
+	config := enterprise.DefaultRateLimitConfig()
+	config.GlobalRequestsPerSecond, config.GlobalBurstSize = 1000, 1000
+	config.DefaultTenantRequestsPerSecond, config.DefaultTenantBurstSize = 1, 5
+	config.KeyRequestsPerSecond, config.KeyBurstSize = 1, 3
+	config.EndpointLimits = map[string]enterprise.EndpointLimit{
+		"POST:/v1/indexes/:indexId/search": {RequestsPerSecond: 1, BurstSize: 2, Window: time.Second},
+	}
+	return config
+}
+
+func TestRateLimiting(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableRateLimiting(testRateLimits())
+
+	// The default tenant limit allows a burst of 5
+	for i := 4; i >= 0; i-- {
+		recorder := serveAs(e, "", "", http.MethodGet, "/v1/indexes", nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to be allowed, got %d", 5-i, recorder.Code)
+		}
+		if got := recorder.Header().Get(rateLimitLimitHeader); got != "5" {
+			t.Errorf("Expected limit 5, got %q", got)
+		}
+		if got := recorder.Header().Get(rateLimitRemainingHeader); got != strconv.Itoa(i) {
+			t.Errorf("Expected %d requests remaining, got %q", i, got)
+		}
+	}
+	recorder := serveAs(e, "", "", http.MethodGet, "/v1/indexes", nil)
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 over the limit, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") != "1" || recorder.Header().Get(rateLimitRemainingHeader) != "0" ||
+		recorder.Header().Get(rateLimitResetHeader) != "5" {
+		t.Errorf("Unexpected rate limit headers %v", recorder.Header())
+	}
+
+	// Public routes and other tenants are not limited by the tenant
+	if recorder := serveAs(e, "", "", http.MethodGet, "/health", nil); recorder.Code != http.StatusOK ||
+		recorder.Header().Get(rateLimitLimitHeader) != "" {
+		t.Errorf("Expected health checks not to be limited, got %d %v", recorder.Code, recorder.Header())
+	}
+	if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected another tenant to be allowed, got %d", recorder.Code)
+	}
+
+	// Routes with a limit of their own report it once it is the tightest
+	ctx := handlers.isolation.ScopeToTenant(context.Background(), "search")
+	if _, _, err := handlers.createCollection(ctx, &models.CreateIndexRequest{
+		ID: "docs", Type: "hnsw", Dimension: contractDimension,
+	}); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	search := map[string]interface{}{"query": embedText(t, "raft")}
+	for i := 0; i < 2; i++ {
+		if recorder := serveAs(e, "", "search", http.MethodPost, "/v1/indexes/docs/search", search); recorder.Code != http.StatusOK ||
+			recorder.Header().Get(rateLimitLimitHeader) != "2" {
+			t.Fatalf("Expected search %d to be allowed with limit 2, got %d %v", i+1, recorder.Code, recorder.Header())
+		}
+	}
+	if recorder := serveAs(e, "", "search", http.MethodPost, "/v1/indexes/docs/search", search); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 over the search limit, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, "", "search", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusOK ||
+		recorder.Header().Get(rateLimitRemainingHeader) != "2" {
+		t.Errorf("Expected denied searches to leave the tenant limit, got %d %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestTenantRateLimitSettings(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableRateLimiting(testRateLimits())
+	ctx := context.Background()
+
+	acme := &tenant.Tenant{ID: "acme", Name: "Acme"}
+	if err := handlers.tenants.CreateTenant(ctx, acme); err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	settings := *acme.Settings
+	settings.APIRateLimit = 2
+	if err := handlers.tenants.UpdateTenantSettings(ctx, "acme", &settings); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+
+	// The settings limit the tenant to 2 requests per minute
+	for i := 0; i < 2; i++ {
+		if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusOK {
+			t.Fatalf("Expected request %d to be allowed, got %d", i+1, recorder.Code)
+		}
+	}
+	recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes", nil)
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get(rateLimitLimitHeader) != "2" {
+		t.Fatalf("Expected 429 with limit 2, got %d %v", recorder.Code, recorder.Header())
+	}
+	if retry, _ := strconv.Atoi(recorder.Header().Get("Retry-After")); retry < 25 || retry > 30 {
+		t.Errorf("Expected to retry in about 30 seconds, got %q", recorder.Header().Get("Retry-After"))
+	}
+
+	// Raising the limit applies to the next request
+	raised := settings
+	raised.APIRateLimit = 100
+	if err := handlers.tenants.UpdateTenantSettings(ctx, "acme", &raised); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	recorder = serveAs(e, "", "acme", http.MethodGet, "/v1/indexes", nil)
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get(rateLimitLimitHeader) != "100" {
+		t.Errorf("Expected the raised limit without new tokens, got %d %v", recorder.Code, recorder.Header())
+	}
+
+	// Removing the limit restores the default tenant limit
+	removed := settings
+	removed.APIRateLimit = 0
+	if err := handlers.tenants.UpdateTenantSettings(ctx, "acme", &removed); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	recorder = serveAs(e, "", "acme", http.MethodGet, "/v1/indexes", nil)
+	if got := recorder.Header().Get(rateLimitLimitHeader); got != "5" {
+		t.Errorf("Expected the default limit 5 once the limit is removed, got %q", got)
+	}
+}
+
+func TestAPIKeyRateLimit(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableAuth(testAdminKey)
+	config := testRateLimits()
+	config.DefaultTenantBurstSize = 6
+	handlers.EnableRateLimiting(config)
+
+	// The admin key uses 2 of the 6 requests of the tenant
+	_, first := createTestKey(t, e, "", models.CreateAPIKeyRequest{Name: "first", Permissions: []string{"*"}})
+	_, second := createTestKey(t, e, "", models.CreateAPIKeyRequest{Name: "second", Permissions: []string{"*"}})
+
+	// Each key has a burst of 3 of its own
+	for i := 0; i < 3; i++ {
+		if recorder := serveAs(e, first, "", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusOK {
+			t.Fatalf("Expected request %d of the first key to be allowed, got %d", i+1, recorder.Code)
+		}
+	}
+	if recorder := serveAs(e, first, "", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusTooManyRequests ||
+		recorder.Header().Get(rateLimitLimitHeader) != "3" {
+		t.Errorf("Expected 429 with the limit of the first key, got %d %v", recorder.Code, recorder.Header())
+	}
+
+	// The second key gets the last request of the tenant
+	if recorder := serveAs(e, second, "", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the second key to be allowed, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, second, "", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusTooManyRequests ||
+		recorder.Header().Get(rateLimitLimitHeader) != "6" {
+		t.Errorf("Expected 429 with the limit of the tenant, got %d %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	conn, handlers := newTestGRPC(t)
+	handlers.EnableRateLimiting(testRateLimits())
+	collections := vjvectorv1.NewCollectionServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme")
+
+	for i := 0; i < 5; i++ {
+		var header metadata.MD
+		if _, err := collections.ListCollections(ctx, &vjvectorv1.ListCollectionsRequest{}, grpc.Header(&header)); err != nil {
+			t.Fatalf("ListCollections %d failed: %v", i+1, err)
+		}
+		if got := header.Get(rateLimitRemainingHeader); len(got) != 1 || got[0] != strconv.Itoa(4-i) {
+			t.Errorf("Expected %d calls remaining, got %v", 4-i, got)
+		}
+	}
+	_, err := collections.ListCollections(ctx, &vjvectorv1.ListCollectionsRequest{})
+	expectCode(t, err, codes.ResourceExhausted)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+)
+
+// readiness tracks the startup rebuild of the collection indexes, which must
+// finish before the server reports that it is ready
+type readiness struct {
+	mutex    sync.RWMutex
+	ready    bool
+	err      error
+	progress map[string]catalog.RebuildProgress
+}
+
+// RebuildProgress records the progress of the startup index rebuild of a
+// collection, reporting the rebuilt index to the webhooks of its tenant
+func (h *Handlers) RebuildProgress(progress catalog.RebuildProgress) {
+	h.readiness.mutex.Lock()
+	if h.readiness.progress == nil {
+		h.readiness.progress = make(map[string]catalog.RebuildProgress)
+	}
+	h.readiness.progress[progress.Collection] = progress
+	h.readiness.mutex.Unlock()
+
+	if progress.Done {
+		h.publishRebuilt(progress, "startup")
+	}
+}
+
+// RebuildFinished records the end of the startup index rebuild. The server is
+// ready from then on unless the rebuild failed.
+func (h *Handlers) RebuildFinished(err error) {
+	h.readiness.mutex.Lock()
+	defer h.readiness.mutex.Unlock()
+
+	h.readiness.ready = err == nil
+	h.readiness.err = err
+}
+
+// waitRebuilt waits until the startup index rebuild is over, so that writes
+// are not queued behind indexes that are still catching up with storage
+func (h *Handlers) waitRebuilt(ctx context.Context) error {
+	ticker := time.NewTicker(ingestRebuildPoll)
+	defer ticker.Stop()
+
+	for {
+		h.readiness.mutex.RLock()
+		rebuilt := h.readiness.ready || h.readiness.err != nil
+		h.readiness.mutex.RUnlock()
+		if rebuilt {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// readinessCheck reports whether the server is ready to serve collections
+func (h *Handlers) readinessCheck(c echo.Context) error {
+	h.readiness.mutex.RLock()
+	defer h.readiness.mutex.RUnlock()
+
+	collections := make([]catalog.RebuildProgress, 0, len(h.readiness.progress))
+	for _, progress := range h.readiness.progress {
+		collections = append(collections, progress)
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].Collection < collections[j].Collection
+	})
+
+	response := map[string]interface{}{
+		"status":      "ready",
+		"timestamp":   time.Now().UTC(),
+		"collections": collections,
+	}
+	status := http.StatusOK
+	switch {
+	case h.readiness.err != nil:
+		response["status"] = "failed"
+		response["error"] = h.readiness.err.Error()
+		status = http.StatusServiceUnavailable
+	case !h.readiness.ready:
+		response["status"] = "rebuilding"
+		status = http.StatusServiceUnavailable
+	}
+
+	return c.JSON(status, response)
+}
@@ -0,0 +1,409 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"github.com/vijaynallagatla/vjvector/pkg/webhook"
+)
+
+// RegisterRoutes registers all API routes on the Echo instance. Every
+// request is authenticated when that is enabled, scoped to its tenant, rate
+// limited when that is enabled and validated against the OpenAPI
+// specification before it reaches its handler, and every error is reported
+// in the standard error envelope.
+func (h *Handlers) RegisterRoutes(e *echo.Echo) {
+	e.HTTPErrorHandler = h.handleError
+	e.Use(h.countRequests, h.authenticate, h.resolveTenant, h.limitRate, h.validateRequest)
+
+	e.GET("/health", h.healthCheck)
+	e.GET("/ready", h.readinessCheck)
+
+	// Documentation
+	e.GET("/openapi.yaml", h.getOpenAPI)
+	e.GET("/docs", h.getDocs)
+
+	v1 := e.Group("/v1")
+
+	// Index management, backed by the collection catalog
+	v1.POST("/indexes", h.createIndex)
+	v1.GET("/indexes", h.listIndexes)
+	v1.GET("/indexes/:indexId", h.getIndex)
+	v1.DELETE("/indexes/:indexId", h.deleteIndex)
+
+	// Vector operations
+	v1.POST("/indexes/:indexId/vectors", h.insertVectors)
+	v1.POST("/indexes/:indexId/batch", h.writeBatch)
+	v1.POST("/indexes/:indexId/ingest", h.ingestVectors)
+	v1.POST("/indexes/:indexId/search", h.searchVectors)
+
+	// RAG operations over the collections
+	v1.POST("/rag/query", h.processRAGQuery)
+	v1.POST("/rag/query/stream", h.processRAGStream)
+	v1.POST("/rag/batch", h.processBatchRAG)
+	v1.GET("/rag/capabilities", h.getRAGCapabilities)
+	v1.GET("/rag/statistics", h.getRAGStatistics)
+
+	// Storage
+	v1.POST("/storage/compact", h.compactStorage)
+	v1.GET("/storage/stats", h.getStorageStats)
+
+	// Change data capture
+	v1.GET("/changes", h.streamChanges)
+
+	// Monitoring
+	v1.GET("/metrics", h.getMetrics)
+
+	// Long-running operations, run in the background as jobs
+	v1.POST("/jobs", h.submitJob)
+	v1.GET("/jobs", h.listJobs)
+	v1.GET("/jobs/:jobId", h.getJob)
+	v1.DELETE("/jobs/:jobId", h.cancelJob)
+
+	// Deliveries of the events sent to the webhooks of the tenant
+	v1.GET("/webhooks/deliveries", h.listWebhookDeliveries)
+	v1.GET("/webhooks/dead-letters", h.listWebhookDeadLetters)
+	v1.POST("/webhooks/dead-letters/:deliveryId/redeliver", h.redeliverWebhook)
+
+	// Administration
+	admin := v1.Group("/admin")
+	admin.POST("/backup", h.createBackup)
+	admin.POST("/keys", h.createAPIKey)
+	admin.GET("/keys", h.listAPIKeys)
+	admin.GET("/keys/:keyId", h.getAPIKey)
+	admin.DELETE("/keys/:keyId", h.deleteAPIKey)
+	admin.POST("/keys/:keyId/revoke", h.revokeAPIKey)
+	admin.POST("/tenants", h.createTenant)
+	admin.GET("/tenants", h.listTenants)
+	admin.GET("/tenants/:tenantId", h.getTenant)
+	admin.PUT("/tenants/:tenantId/settings", h.updateTenantSettings)
+	admin.PUT("/tenants/:tenantId/status", h.updateTenantStatus)
+}
+
+// errorResponse writes the standard error envelope
+func errorResponse(c echo.Context, status int, message string) error {
+	return c.JSON(status, map[string]interface{}{
+		"error":   message,
+		"status":  status,
+		"success": false,
+	})
+}
+
+// handleError reports the errors handlers return, such as unknown routes and
+// recovered panics, in the standard error envelope
+func (h *Handlers) handleError(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := err.Error()
+	var httpError *echo.HTTPError
+	if errors.As(err, &httpError) {
+		status = httpError.Code
+		message = fmt.Sprint(httpError.Message)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = errorResponse(c, status, message)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+// errorStatus maps the errors of the API operations to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrPermissionDenied),
+		errors.Is(err, tenant.ErrTenantSuspended),
+		errors.Is(err, tenant.ErrTenantInactive):
+		return http.StatusForbidden
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, jobs.ErrManagerClosed),
+		errors.Is(err, webhook.ErrDispatcherClosed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrNoEmbeddingModel):
+		return http.StatusNotImplemented
+	case errors.Is(err, catalog.ErrCollectionNotFound),
+		errors.Is(err, tenant.ErrTenantNotFound),
+		errors.Is(err, jobs.ErrJobNotFound),
+		errors.Is(err, webhook.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, catalog.ErrLogTruncated):
+		return http.StatusGone
+	case errors.Is(err, index.ErrIndexFull):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, catalog.ErrCollectionExists),
+		errors.Is(err, tenant.ErrTenantExists),
+		errors.Is(err, catalog.ErrVersionConflict),
+		errors.Is(err, jobs.ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidRequest),
+		errors.Is(err, jobs.ErrUnknownJobType),
+		errors.Is(err, ErrInvalidRAGRequest),
+		errors.Is(err, catalog.ErrInvalidCollectionName),
+		errors.Is(err, tenant.ErrInvalidTenant),
+		errors.Is(err, catalog.ErrInvalidDimension),
+		errors.Is(err, catalog.ErrDimensionMismatch),
+		errors.Is(err, catalog.ErrImmutableField),
+		errors.Is(err, catalog.ErrInvalidWrite),
+		errors.Is(err, index.ErrUnsupportedIndexType),
+		errors.Is(err, index.ErrInvalidMaxElements),
+		errors.Is(err, index.ErrInvalidHNSWParameter),
+		errors.Is(err, index.ErrInvalidIVFParameter),
+		errors.Is(err, index.ErrInvalidQuery):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorCode names the kind of an error reported per item, such as a failed
+// query of a RAG batch or a failed line of an ingest stream
+func errorCode(err error) string {
+	switch errorStatus(err) {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusInsufficientStorage:
+		return "index_full"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusGatewayTimeout:
+		return "timeout"
+	case http.StatusNotImplemented:
+		return "not_implemented"
+	default:
+		return "internal_error"
+	}
+}
+
+// healthCheck reports that the server is up
+func (h *Handlers) healthCheck(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":    "healthy",
+		"timestamp": time.Now().UTC(),
+		"service":   "VJVector API",
+		"version":   "1.0.0",
+		"uptime":    time.Since(h.started).Round(time.Second).String(),
+	})
+}
+
+// createIndex creates a collection and its index
+func (h *Handlers) createIndex(c echo.Context) error {
+	var req models.CreateIndexRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	collection, config, err := h.createCollection(c.Request().Context(), &req)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"id":      collection.Name,
+		"type":    collection.IndexType,
+		"status":  "created",
+		"config":  config,
+		"message": "Index created successfully",
+	})
+}
+
+// listIndexes lists every collection with its index statistics
+func (h *Handlers) listIndexes(c echo.Context) error {
+	indexes, err := h.listCollections(c.Request().Context())
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"indexes": indexes,
+		"count":   len(indexes),
+	})
+}
+
+// getIndex returns a single collection with its index statistics
+func (h *Handlers) getIndex(c echo.Context) error {
+	info, err := h.collectionInfo(c.Request().Context(), c.Param("indexId"))
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+// deleteIndex deletes a collection together with its index and vectors
+func (h *Handlers) deleteIndex(c echo.Context) error {
+	id := c.Param("indexId")
+	if err := h.deleteCollection(c.Request().Context(), id); err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"status":  "deleted",
+		"message": "Index deleted successfully",
+	})
+}
+
+// insertVectors inserts vectors into a collection
+func (h *Handlers) insertVectors(c echo.Context) error {
+	id := c.Param("indexId")
+
+	var req models.InsertVectorsRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	writes, err := putWrites(id, req.Vectors)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	outcome, err := h.write(c.Request().Context(), id, writes)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"index_id":      id,
+		"vectors_added": len(writes),
+		"versions":      outcome.result.Versions,
+		"total_vectors": outcome.totalVectors,
+		"insert_time":   outcome.elapsed.String(),
+		"message":       "Vectors inserted successfully",
+	})
+}
+
+// writeBatch applies a batch of puts and deletes to a collection atomically
+func (h *Handlers) writeBatch(c echo.Context) error {
+	id := c.Param("indexId")
+
+	var req models.WriteBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	writes, err := batchWrites(id, req.Writes)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	outcome, err := h.write(c.Request().Context(), id, writes)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	deleted := outcome.result.Deleted
+	if deleted == nil {
+		deleted = []string{}
+	}
+	h.publishBulkWrite(c.Request().Context(), models.VectorsBulkEvent{
+		Collection:   id,
+		Operation:    "batch",
+		Written:      len(writes) - countDeletes(writes),
+		Deleted:      len(deleted),
+		TotalVectors: outcome.totalVectors,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"index_id":      id,
+		"position":      outcome.result.Position,
+		"versions":      outcome.result.Versions,
+		"deleted":       deleted,
+		"total_vectors": outcome.totalVectors,
+		"write_time":    outcome.elapsed.String(),
+		"message":       "Batch applied successfully",
+	})
+}
+
+// searchVectors searches a collection for the vectors most similar to the query
+func (h *Handlers) searchVectors(c echo.Context) error {
+	id := c.Param("indexId")
+
+	var req models.SearchRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	start := time.Now()
+	results, err := h.search(c.Request().Context(), id, &req)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	elapsed := time.Since(start)
+
+	items := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		item := map[string]interface{}{
+			"score":    result.Score,
+			"distance": result.Distance,
+		}
+		if result.Vector != nil {
+			item["vector_id"] = result.Vector.ID
+		}
+		items = append(items, item)
+	}
+
+	response := map[string]interface{}{
+		"index_id":    id,
+		"query":       req.Query,
+		"k":           req.K,
+		"results":     items,
+		"search_time": elapsed.String(),
+		"count":       len(items),
+	}
+	if req.AsOf != nil {
+		response["as_of"] = req.AsOf
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// applyIndexDefaults fills unset index parameters with the catalog defaults
+func applyIndexDefaults(config *index.IndexConfig) {
+	defaults := catalog.DefaultIndexConfig(config.Type, config.Dimension, config.DistanceMetric)
+	if config.MaxElements == 0 {
+		config.MaxElements = defaults.MaxElements
+	}
+	if config.M == 0 {
+		config.M = defaults.M
+	}
+	if config.EfConstruction == 0 {
+		config.EfConstruction = defaults.EfConstruction
+	}
+	if config.EfSearch == 0 {
+		config.EfSearch = defaults.EfSearch
+	}
+	if config.MaxLayers == 0 {
+		config.MaxLayers = defaults.MaxLayers
+	}
+	if config.NumClusters == 0 {
+		config.NumClusters = defaults.NumClusters
+	}
+	if config.ClusterSize == 0 {
+		config.ClusterSize = defaults.ClusterSize
+	}
+	if config.DistanceMetric == "" {
+		config.DistanceMetric = defaults.DistanceMetric
+	}
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+)
+
+// The operations below are shared by the REST and gRPC APIs; the handlers of
+// each API only decode requests and encode the results.
+
+// defaultSearchResults is the number of results of searches that set no k
+const defaultSearchResults = 10
+
+// catalogName returns the name a collection of the tenant of a request is
+// stored under in the catalog. Names cannot contain the namespace separator,
+// so that no tenant can name a collection of another.
+func (h *Handlers) catalogName(ctx context.Context, name string) (string, error) {
+	if strings.Contains(name, tenant.NamespaceSeparator) {
+		return "", fmt.Errorf("%w: %q must not contain %q", catalog.ErrInvalidCollectionName, name, tenant.NamespaceSeparator)
+	}
+	return tenant.CollectionName(h.tenantOf(ctx), name), nil
+}
+
+// getCollection returns a collection of the tenant of a request, named as
+// the tenant knows it
+func (h *Handlers) getCollection(ctx context.Context, name string) (*core.Collection, error) {
+	stored, err := h.catalogName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := h.catalog.Get(stored)
+	if err != nil {
+		return nil, err
+	}
+	collection.Name = name
+	return collection, nil
+}
+
+// createCollection creates a collection and its index, filling unset index
+// parameters with the catalog defaults
+func (h *Handlers) createCollection(ctx context.Context, req *models.CreateIndexRequest) (*core.Collection, index.IndexConfig, error) {
+	config := index.IndexConfig{
+		Type:           index.IndexType(req.Type),
+		Dimension:      req.Dimension,
+		MaxElements:    req.MaxElements,
+		M:              req.M,
+		EfConstruction: req.EfConstruction,
+		EfSearch:       req.EfSearch,
+		MaxLayers:      req.MaxLayers,
+		NumClusters:    req.NumClusters,
+		ClusterSize:    req.ClusterSize,
+		DistanceMetric: req.DistanceMetric,
+		Normalize:      req.Normalize,
+	}
+	applyIndexDefaults(&config)
+
+	name, err := h.catalogName(ctx, req.ID)
+	if err != nil {
+		return nil, config, err
+	}
+	collection := core.NewCollection(name, "", req.Dimension, req.Type)
+	if err := h.catalog.CreateWithIndex(collection, config); err != nil {
+		return nil, config, err
+	}
+	collection.Name = req.ID
+	return collection, config, nil
+}
+
+// collectionInfo returns the named collection with its index statistics
+func (h *Handlers) collectionInfo(ctx context.Context, name string) (*models.IndexInfo, error) {
+	stored, err := h.catalogName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := h.catalog.Get(stored)
+	if err != nil {
+		return nil, err
+	}
+	return h.indexInfo(collection)
+}
+
+// listCollections returns every collection of the tenant of a request with
+// its index statistics
+func (h *Handlers) listCollections(ctx context.Context) ([]*models.IndexInfo, error) {
+	collections, err := h.tenantCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*models.IndexInfo, 0, len(collections))
+	for _, collection := range collections {
+		info, err := h.indexInfo(collection)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// tenantCollections returns the stored collections of the tenant of a request
+func (h *Handlers) tenantCollections(ctx context.Context) ([]*core.Collection, error) {
+	collections, err := h.catalog.List()
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID := h.tenantOf(ctx)
+	owned := collections[:0]
+	for _, collection := range collections {
+		if owner, _ := tenant.SplitCollectionName(collection.Name); owner == tenantID {
+			owned = append(owned, collection)
+		}
+	}
+	return owned, nil
+}
+
+// deleteCollection deletes a collection together with its index and vectors
+func (h *Handlers) deleteCollection(ctx context.Context, name string) error {
+	stored, err := h.catalogName(ctx, name)
+	if err != nil {
+		return err
+	}
+	return h.catalog.Delete(stored)
+}
+
+// indexInfo describes a stored collection with the statistics of its index,
+// named as its tenant knows it
+func (h *Handlers) indexInfo(collection *core.Collection) (*models.IndexInfo, error) {
+	idx, err := h.catalog.Index(collection.Name)
+	if err != nil {
+		return nil, err
+	}
+	stats := idx.GetStats()
+	_, name := tenant.SplitCollectionName(collection.Name)
+
+	return &models.IndexInfo{
+		ID:             name,
+		Type:           collection.IndexType,
+		Dimension:      collection.Dimension,
+		DistanceMetric: collection.DistanceMetric,
+		TotalVectors:   collection.Count,
+		MemoryUsage:    stats.MemoryUsage,
+		IndexSize:      stats.IndexSize,
+		AvgSearchTime:  stats.AvgSearchTime,
+		AvgInsertTime:  stats.AvgInsertTime,
+	}, nil
+}
+
+// writeOutcome reports writes applied to a collection
+type writeOutcome struct {
+	result       *catalog.WriteResult
+	totalVectors int64
+	elapsed      time.Duration
+}
+
+// write applies writes to a collection atomically and records them in the
+// vector operation metrics
+func (h *Handlers) write(ctx context.Context, collection string, writes []catalog.Write) (*writeOutcome, error) {
+	collection, err := h.catalogName(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := h.catalog.Write(ctx, collection, writes)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+
+	stored, err := h.catalog.Get(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.server != nil && h.server.Metrics() != nil {
+		h.server.Metrics().RecordVectorOperation("insert", len(result.Versions))
+		h.server.Metrics().RecordVectorOperation("delete", len(result.Deleted))
+	}
+
+	return &writeOutcome{result: result, totalVectors: stored.Count, elapsed: elapsed}, nil
+}
+
+// putWrites converts the vectors of a request into puts to a collection
+func putWrites(collection string, vectors []*models.Vector) ([]catalog.Write, error) {
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("%w: at least one vector is required", ErrInvalidRequest)
+	}
+
+	writes := make([]catalog.Write, len(vectors))
+	for i, v := range vectors {
+		vector, err := newVector(collection, v)
+		if err != nil {
+			return nil, err
+		}
+		writes[i] = catalog.Write{Kind: catalog.WritePut, Vector: vector, ExpectedVersion: v.ExpectedVersion}
+	}
+	return writes, nil
+}
+
+// batchWrites converts the writes of a batch request into writes to a collection
+func batchWrites(collection string, operations []*models.WriteOperation) ([]catalog.Write, error) {
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("%w: at least one write is required", ErrInvalidRequest)
+	}
+
+	writes := make([]catalog.Write, len(operations))
+	for i, w := range operations {
+		if w == nil {
+			return nil, fmt.Errorf("%w: writes must not be null", ErrInvalidRequest)
+		}
+		write := catalog.Write{ExpectedVersion: w.ExpectedVersion}
+		switch catalog.WriteKind(w.Op) {
+		case catalog.WritePut:
+			vector, err := newVector(collection, w.Vector)
+			if err != nil {
+				return nil, err
+			}
+			write.Kind, write.Vector = catalog.WritePut, vector
+		case catalog.WriteDelete:
+			if w.ID == "" {
+				return nil, fmt.Errorf("%w: every delete requires an id", ErrInvalidRequest)
+			}
+			write.Kind, write.ID = catalog.WriteDelete, w.ID
+		default:
+			return nil, fmt.Errorf("%w: op must be put or delete", ErrInvalidRequest)
+		}
+		writes[i] = write
+	}
+	return writes, nil
+}
+
+// countDeletes returns the number of deletes among writes
+func countDeletes(writes []catalog.Write) int {
+	deletes := 0
+	for _, write := range writes {
+		if write.Kind == catalog.WriteDelete {
+			deletes++
+		}
+	}
+	return deletes
+}
+
+// deleteWrites converts IDs into deletes
+func deleteWrites(ids []string) ([]catalog.Write, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: at least one id is required", ErrInvalidRequest)
+	}
+
+	writes := make([]catalog.Write, len(ids))
+	for i, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("%w: every delete requires an id", ErrInvalidRequest)
+		}
+		writes[i] = catalog.Write{Kind: catalog.WriteDelete, ID: id}
+	}
+	return writes, nil
+}
+
+// newVector converts a vector of a request into a vector of the collection
+func newVector(collection string, v *models.Vector) (*core.Vector, error) {
+	if v == nil || v.ID == "" {
+		return nil, fmt.Errorf("%w: every vector requires an id", ErrInvalidRequest)
+	}
+	if v.TTLSeconds < 0 {
+		return nil, fmt.Errorf("%w: ttl_seconds must not be negative", ErrInvalidRequest)
+	}
+	if v.TTLSeconds > 0 && v.ExpiresAt != nil {
+		return nil, fmt.Errorf("%w: ttl_seconds and expires_at are mutually exclusive", ErrInvalidRequest)
+	}
+
+	vector := core.NewVector(collection, v.Embedding, "", v.Metadata)
+	vector.ID = v.ID
+	vector.ExpiresAt = v.ExpiresAt
+	if v.TTLSeconds > 0 {
+		vector.SetTTL(time.Duration(v.TTLSeconds) * time.Second)
+	}
+	return vector, nil
+}
+
+// getVectors returns the stored vectors of a collection with the given IDs,
+// skipping the IDs that are not stored and the vectors that expired
+func (h *Handlers) getVectors(ctx context.Context, collection string, ids []string) ([]*core.Vector, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: at least one id is required", ErrInvalidRequest)
+	}
+
+	collection, err := h.catalogName(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := h.catalog.Storage(collection)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := engine.ReadWithContext(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	live := make([]*core.Vector, 0, len(vectors))
+	for _, vector := range vectors {
+		if !vector.Expired(now) {
+			live = append(live, vector)
+		}
+	}
+	return live, nil
+}
+
+// search searches a collection for the vectors most similar to the query of
+// a request, as of its time if set. An unset k is set to the default.
+func (h *Handlers) search(ctx context.Context, collection string, req *models.SearchRequest) ([]core.VectorSearchResult, error) {
+	if len(req.Query) == 0 {
+		return nil, fmt.Errorf("%w: query vector is required", ErrInvalidRequest)
+	}
+	if req.K <= 0 {
+		req.K = defaultSearchResults
+	}
+	collection, err := h.catalogName(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AsOf != nil {
+		return h.catalog.SearchAsOf(ctx, collection, req.Query, req.K, *req.AsOf)
+	}
+	return h.catalog.Search(ctx, collection, req.Query, req.K)
+}
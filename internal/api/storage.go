@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+)
+
+// getStorageStats sums the storage statistics of every collection of the
+// tenant of a request. Average times are weighted by the number of vectors of
+// each collection.
+func (h *Handlers) getStorageStats(c echo.Context) error {
+	collections, err := h.tenantCollections(c.Request().Context())
+	if err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+
+	var total storage.StorageStats
+	var writeTime, readTime float64
+	for _, collection := range collections {
+		engine, err := h.catalog.Storage(collection.Name)
+		if err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+		stats := engine.GetStats()
+		total.TotalVectors += stats.TotalVectors
+		total.StorageSize += stats.StorageSize
+		total.MemoryUsage += stats.MemoryUsage
+		total.FileCount += stats.FileCount
+		total.PageSize = max(total.PageSize, stats.PageSize)
+		writeTime += stats.AvgWriteTime * float64(stats.TotalVectors)
+		readTime += stats.AvgReadTime * float64(stats.TotalVectors)
+	}
+	if total.TotalVectors > 0 {
+		total.AvgWriteTime = writeTime / float64(total.TotalVectors)
+		total.AvgReadTime = readTime / float64(total.TotalVectors)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"total_vectors":  total.TotalVectors,
+		"storage_size":   total.StorageSize,
+		"memory_usage":   total.MemoryUsage,
+		"avg_write_time": total.AvgWriteTime,
+		"avg_read_time":  total.AvgReadTime,
+		"file_count":     total.FileCount,
+		"page_size":      total.PageSize,
+		"collections":    len(collections),
+	})
+}
+
+// compactStorage compacts the storage of every collection of the tenant of a
+// request
+func (h *Handlers) compactStorage(c echo.Context) error {
+	collections, err := h.tenantCollections(c.Request().Context())
+	if err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+
+	start := time.Now()
+	for _, collection := range collections {
+		engine, err := h.catalog.Storage(collection.Name)
+		if err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+		if err := engine.Compact(); err != nil {
+			_, name := tenant.SplitCollectionName(collection.Name)
+			message := fmt.Sprintf("failed to compact collection %s: %v", name, err)
+			return errorResponse(c, http.StatusInternalServerError, message)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"status":       "success",
+		"message":      fmt.Sprintf("Compacted %d collections", len(collections)),
+		"compact_time": time.Since(start).String(),
+	})
+}
+
+// getMetrics reports the collections of the tenant of a request, and the
+// uptime, memory and requests served
+func (h *Handlers) getMetrics(c echo.Context) error {
+	collections, err := h.tenantCollections(c.Request().Context())
+	if err != nil {
+		return errorResponse(c, http.StatusInternalServerError, err.Error())
+	}
+
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"indexes_count": len(collections),
+		"uptime":        time.Since(h.started).Round(time.Second).String(),
+		"memory_usage":  fmt.Sprintf("%.1f MiB", float64(memory.HeapAlloc)/(1<<20)),
+		"requests":      fmt.Sprintf("%d", h.requests.Load()),
+	})
+}
+
+// countRequests counts the requests served, for the metrics endpoint and
+// the request metrics of the server
+func (h *Handlers) countRequests(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		status := c.Response().Status
+		var httpError *echo.HTTPError
+		switch {
+		case errors.As(err, &httpError):
+			status = httpError.Code
+		case err != nil:
+			status = http.StatusInternalServerError
+		}
+		h.recordRequest(time.Since(start), status < http.StatusBadRequest)
+		return err
+	}
+}
+
+// recordRequest counts a request of the REST or gRPC API that took latency
+func (h *Handlers) recordRequest(latency time.Duration, success bool) {
+	h.requests.Add(1)
+	if h.server != nil && h.server.Metrics() != nil {
+		h.server.Metrics().RecordRequest(latency, success)
+	}
+}
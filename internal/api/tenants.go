@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// operatorRoutes are served to the default tenant only, since they reach
+// beyond the collections of a single tenant
+var operatorRoutes = map[string]bool{
+	"POST /v1/admin/backup":                    true,
+	"POST /v1/admin/tenants":                   true,
+	"GET /v1/admin/tenants":                    true,
+	"GET /v1/admin/tenants/:tenantId":          true,
+	"PUT /v1/admin/tenants/:tenantId/settings": true,
+	"PUT /v1/admin/tenants/:tenantId/status":   true,
+}
+
+// OpenTenants keeps the registered tenants in the file at path, so that they
+// keep their status, settings and quotas across restarts, replacing the
+// tenants held in memory
+func (h *Handlers) OpenTenants(path string) error {
+	tenants, err := tenant.OpenTenantManager(path)
+	if err != nil {
+		return err
+	}
+	if h.tenants != nil {
+		_ = h.tenants.Close()
+	}
+	h.tenants = tenants
+	h.isolation = tenant.NewDefaultTenantIsolation(tenants)
+	return nil
+}
+
+// tenantOf returns the tenant a request is scoped to
+func (h *Handlers) tenantOf(ctx context.Context) string {
+	if tenantID, err := h.isolation.GetTenantFromContext(ctx); err == nil {
+		return tenantID
+	}
+	return DefaultTenant
+}
+
+// namedTenant returns the tenant a request names in its tenant header, or
+// else the registered tenant of the domain it was sent to; empty when it
+// names none
+func (h *Handlers) namedTenant(ctx context.Context, header, host string) (string, error) {
+	if header != "" {
+		if !tenant.ValidTenantID(header) {
+			return "", fmt.Errorf("%w: %q is not a valid tenant ID", tenant.ErrInvalidTenant, header)
+		}
+		return header, nil
+	}
+
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	if host == "" {
+		return "", nil
+	}
+	if registered, err := h.tenants.GetTenantByDomain(ctx, host); err == nil {
+		return registered.ID, nil
+	}
+	return "", nil
+}
+
+// scopeTenant scopes a request to its tenant: the tenant of its API key when
+// authentication is enabled, else the tenant it names or the default one.
+// Registered tenants must be active; unregistered ones are served with the
+// default settings.
+func (h *Handlers) scopeTenant(ctx context.Context, header, host string) (context.Context, error) {
+	var tenantID string
+	if key := apiKeyFrom(ctx); key != nil {
+		tenantID = key.TenantID
+	} else {
+		var err error
+		if tenantID, err = h.namedTenant(ctx, header, host); err != nil {
+			return nil, err
+		}
+	}
+	if tenantID == "" {
+		tenantID = DefaultTenant
+	}
+
+	registered, err := h.tenants.GetTenant(ctx, tenantID)
+	switch {
+	case err == nil:
+		if err := registered.CheckStatus(); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, tenant.ErrTenantNotFound):
+		return nil, err
+	}
+	return h.isolation.ScopeToTenant(ctx, tenantID), nil
+}
+
+// resolveTenant scopes every request to its tenant, rejecting requests of
+// tenants that are not active with 403, as well as requests of other tenants
+// than the default one to the operator routes
+func (h *Handlers) resolveTenant(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		request := c.Request()
+		route := request.Method + " " + c.Path()
+		if publicRoutes[route] {
+			return next(c)
+		}
+
+		ctx, err := h.scopeTenant(request.Context(), request.Header.Get(tenantIDHeader), request.Host)
+		if err != nil {
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+		if operatorRoutes[route] && h.tenantOf(ctx) != DefaultTenant {
+			err := fmt.Errorf("%w: only the default tenant may use %s", ErrPermissionDenied, c.Path())
+			return errorResponse(c, errorStatus(err), err.Error())
+		}
+
+		c.SetRequest(request.WithContext(ctx))
+		return next(c)
+	}
+}
+
+// scopeCall scopes a gRPC call to the tenant of its metadata
+func (h *Handlers) scopeCall(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	value := func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return h.scopeTenant(ctx, value(strings.ToLower(tenantIDHeader)), value(":authority"))
+}
+
+// tenantUnaryInterceptor scopes unary calls to their tenant
+func (h *Handlers) tenantUnaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := h.scopeCall(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return handler(ctx, req)
+}
+
+// tenantStreamInterceptor scopes streaming calls to their tenant
+func (h *Handlers) tenantStreamInterceptor(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, err := h.scopeCall(stream.Context())
+	if err != nil {
+		return grpcError(err)
+	}
+	return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+// createTenant registers a tenant, with the default settings and quotas
+// unless the request sets them
+func (h *Handlers) createTenant(c echo.Context) error {
+	var req models.CreateTenantRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+	if req.ID == DefaultTenant {
+		return errorResponse(c, http.StatusConflict, fmt.Sprintf("%v: %s", tenant.ErrTenantExists, req.ID))
+	}
+
+	registered := &tenant.Tenant{
+		ID:          req.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Domain:      req.Domain,
+		Plan:        req.Plan,
+		ExpiresAt:   req.ExpiresAt,
+		Metadata:    req.Metadata,
+		Settings:    req.Settings,
+		Quotas:      req.Quotas,
+	}
+	ctx := c.Request().Context()
+	if err := h.tenants.CreateTenant(ctx, registered); err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	stored, err := h.tenants.GetTenant(ctx, registered.ID)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusCreated, stored)
+}
+
+// listTenants lists the registered tenants, oldest first
+func (h *Handlers) listTenants(c echo.Context) error {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	tenants, err := h.tenants.ListTenants(c.Request().Context(), limit, offset)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"tenants": tenants,
+		"count":   len(tenants),
+	})
+}
+
+// getTenant returns a registered tenant
+func (h *Handlers) getTenant(c echo.Context) error {
+	registered, err := h.tenants.GetTenant(c.Request().Context(), c.Param("tenantId"))
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusOK, registered)
+}
+
+// updateTenantSettings replaces the settings of a tenant, which apply from
+// its next request
+func (h *Handlers) updateTenantSettings(c echo.Context) error {
+	var settings tenant.TenantSettings
+	if err := c.Bind(&settings); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	ctx := c.Request().Context()
+	id := c.Param("tenantId")
+	if err := h.tenants.UpdateTenantSettings(ctx, id, &settings); err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return h.getTenant(c)
+}
+
+// updateTenantStatus activates, suspends or deactivates a tenant. The
+// requests of a tenant that is not active are rejected with 403.
+func (h *Handlers) updateTenantStatus(c echo.Context) error {
+	var req models.UpdateTenantStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return errorResponse(c, http.StatusBadRequest, "invalid request body")
+	}
+
+	ctx := c.Request().Context()
+	id := c.Param("tenantId")
+	var err error
+	switch tenant.TenantStatus(req.Status) {
+	case tenant.TenantStatusActive:
+		err = h.tenants.ActivateTenant(ctx, id)
+	case tenant.TenantStatusSuspended:
+		err = h.tenants.SuspendTenant(ctx, id, req.Reason)
+	case tenant.TenantStatusInactive:
+		err = h.tenants.DeactivateTenant(ctx, id)
+	default:
+		err = fmt.Errorf("%w: status must be active, suspended or inactive", ErrInvalidRequest)
+	}
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return h.getTenant(c)
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	vjvectorv1 "github.com/vijaynallagatla/vjvector/api/vjvector/v1"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// createTenantIndex creates the docs index of a tenant holding a document
+func createTenantIndex(t *testing.T, e *echo.Echo, key, tenantID, document string) {
+	t.Helper()
+
+	recorder := serveAs(e, key, tenantID, http.MethodPost, "/v1/indexes", map[string]interface{}{
+		"id": "docs", "type": "hnsw", "dimension": contractDimension, "max_elements": 100,
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create the index of %s: %s", tenantID, recorder.Body.String())
+	}
+	recorder = serveAs(e, key, tenantID, http.MethodPost, "/v1/indexes/docs/vectors", map[string]interface{}{
+		"vectors": []interface{}{map[string]interface{}{"id": document, "embedding": embedText(t, document)}},
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to insert the document of %s: %s", tenantID, recorder.Body.String())
+	}
+}
+
+// indexIDs returns the IDs of the indexes a tenant lists
+func indexIDs(t *testing.T, e *echo.Echo, key, tenantID string) []string {
+	t.Helper()
+
+	recorder := serveAs(e, key, tenantID, http.MethodGet, "/v1/indexes", nil)
+	var response struct {
+		Indexes []models.IndexInfo `json:"indexes"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode indexes: %v", err)
+	}
+	ids := make([]string, len(response.Indexes))
+	for i, info := range response.Indexes {
+		ids[i] = info.ID
+	}
+	return ids
+}
+
+// searchIDs returns the IDs of the vectors a search of the docs index of a
+// tenant finds
+func searchIDs(t *testing.T, e *echo.Echo, tenantID, text string) []string {
+	t.Helper()
+
+	recorder := serveAs(e, "", tenantID, http.MethodPost, "/v1/indexes/docs/search", map[string]interface{}{
+		"query": embedText(t, text), "k": 10,
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to search the index of %s: %s", tenantID, recorder.Body.String())
+	}
+	var response struct {
+		Results []struct {
+			VectorID string `json:"vector_id"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode results: %v", err)
+	}
+	ids := make([]string, len(response.Results))
+	for i, result := range response.Results {
+		ids[i] = result.VectorID
+	}
+	return ids
+}
+
+func TestTenantIsolation(t *testing.T) {
+	e, _ := newTestRouter(t)
+
+	// Tenants may use the same index IDs
+	createTenantIndex(t, e, "", "acme", "raft")
+	createTenantIndex(t, e, "", "globex", "paxos")
+
+	for tenantID, expected := range map[string]string{"acme": "raft", "globex": "paxos"} {
+		if ids := indexIDs(t, e, "", tenantID); len(ids) != 1 || ids[0] != "docs" {
+			t.Errorf("Expected %s to list its docs index, got %v", tenantID, ids)
+		}
+		if ids := searchIDs(t, e, tenantID, "raft"); len(ids) != 1 || ids[0] != expected {
+			t.Errorf("Expected %s to find only %s, got %v", tenantID, expected, ids)
+		}
+	}
+
+	// The default tenant sees neither, and no tenant reaches another by name
+	if ids := indexIDs(t, e, "", ""); len(ids) != 0 {
+		t.Errorf("Expected the default tenant to list no indexes, got %v", ids)
+	}
+	if recorder := serveAs(e, "", "", http.MethodGet, "/v1/indexes/docs", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for the default tenant, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, "", "globex", http.MethodGet, "/v1/indexes/acme~docs", nil); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a namespaced index ID, got %d", recorder.Code)
+	}
+	recorder := serveAs(e, "", "globex", http.MethodPost, "/v1/rag/query", map[string]interface{}{
+		"operation": "batch_search", "query": "raft", "collection": "acme~docs",
+	})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a RAG query of a namespaced collection, got %d", recorder.Code)
+	}
+
+	// Change streams carry the events of the tenant only, named as it knows them
+	recorder = serveAs(e, "", "globex", http.MethodGet, "/v1/changes?follow=false", nil)
+	scanner := bufio.NewScanner(strings.NewReader(recorder.Body.String()))
+	events := 0
+	for scanner.Scan() {
+		var event struct {
+			Collection string `json:"collection"`
+			ID         string `json:"id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if event.Collection != "docs" || event.ID == "raft" {
+			t.Errorf("Unexpected event of another tenant %s", scanner.Text())
+		}
+		events++
+	}
+	if events == 0 {
+		t.Error("Expected the events of globex")
+	}
+
+	// Deleting an index leaves the index of the same ID of other tenants
+	if recorder := serveAs(e, "", "globex", http.MethodDelete, "/v1/indexes/docs", nil); recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to delete the index of globex: %s", recorder.Body.String())
+	}
+	if ids := searchIDs(t, e, "acme", "raft"); len(ids) != 1 || ids[0] != "raft" {
+		t.Errorf("Expected acme to keep its index, got %v", ids)
+	}
+}
+
+func TestTenantNamespaceSeparator(t *testing.T) {
+	e, handlers := newTestRouter(t)
+
+	// A collection of the default tenant named like a tenant's stays its own
+	collection := core.NewCollection("acme.docs", "", contractDimension, "hnsw")
+	if err := handlers.catalog.Create(collection); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if ids := indexIDs(t, e, "", "acme"); len(ids) != 0 {
+		t.Errorf("Expected acme to list no indexes, got %v", ids)
+	}
+	if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes/docs", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for acme, got %d", recorder.Code)
+	}
+	if ids := indexIDs(t, e, "", ""); len(ids) != 1 || ids[0] != "acme.docs" {
+		t.Errorf("Expected the default tenant to list acme.docs, got %v", ids)
+	}
+}
+
+func TestTenantStatus(t *testing.T) {
+	e, _ := newTestRouter(t)
+
+	recorder := serveAs(e, "", "", http.MethodPost, "/v1/admin/tenants", map[string]interface{}{
+		"id": "acme", "name": "Acme", "domain": "acme.example.com",
+	})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create tenant: %s", recorder.Body.String())
+	}
+	createTenantIndex(t, e, "", "acme", "raft")
+
+	// Requests to the domain of a tenant are scoped to it
+	request := httptest.NewRequest(http.MethodGet, "/v1/indexes/docs", nil)
+	request.Host = "acme.example.com:8080"
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected the domain of acme to reach its index, got %d", recorder.Code)
+	}
+
+	// Only the default tenant may use the operator routes
+	if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/admin/tenants", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for acme to list tenants, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, "", "acme", http.MethodPost, "/v1/admin/backup", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for acme to back up, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, "", "bad.tenant", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid tenant ID, got %d", recorder.Code)
+	}
+
+	// Tenants that are not active are rejected until they are activated again
+	for _, status := range []string{"suspended", "inactive"} {
+		recorder := serveAs(e, "", "", http.MethodPut, "/v1/admin/tenants/acme/status", map[string]string{"status": status})
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"status":"`+status+`"`) {
+			t.Fatalf("Failed to set the status to %s: %s", status, recorder.Body.String())
+		}
+		if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes/docs", nil); recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a %s tenant, got %d", status, recorder.Code)
+		}
+	}
+	if recorder := serveAs(e, "", "", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the default tenant to be served, got %d", recorder.Code)
+	}
+	serveAs(e, "", "", http.MethodPut, "/v1/admin/tenants/acme/status", map[string]string{"status": "active"})
+	if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes/docs", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected an active tenant to be served, got %d", recorder.Code)
+	}
+}
+
+func TestTenantsPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+
+	e, handlers := newTestRouter(t)
+	if err := handlers.OpenTenants(path); err != nil {
+		t.Fatalf("Failed to open tenants: %v", err)
+	}
+	recorder := serveAs(e, "", "", http.MethodPost, "/v1/admin/tenants", map[string]interface{}{"id": "acme", "name": "Acme"})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create tenant: %s", recorder.Body.String())
+	}
+	recorder = serveAs(e, "", "", http.MethodPut, "/v1/admin/tenants/acme/status", map[string]string{"status": "suspended"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to suspend tenant: %s", recorder.Body.String())
+	}
+
+	// The tenant stays suspended with a new server on the same file
+	e, handlers = newTestRouter(t)
+	if err := handlers.OpenTenants(path); err != nil {
+		t.Fatalf("Failed to reopen tenants: %v", err)
+	}
+	if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a suspended tenant after a restart, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, "", "", http.MethodGet, "/v1/admin/tenants/acme", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the tenant to be registered after a restart, got %d", recorder.Code)
+	}
+}
+
+func TestTenantAPIKeys(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	handlers.EnableAuth(testAdminKey)
+
+	// The default tenant creates the keys of other tenants
+	acmeID, acme := createTestKey(t, e, "", models.CreateAPIKeyRequest{
+		TenantID: "acme", Name: "acme", Permissions: []string{"*"},
+	})
+	defaultID, _ := createTestKey(t, e, "", models.CreateAPIKeyRequest{Name: "operator", Permissions: []string{"*"}})
+
+	// Keys scope requests to their tenant without naming it
+	createTenantIndex(t, e, acme, "", "raft")
+	if recorder := serveAs(e, testAdminKey, "", http.MethodGet, "/v1/indexes/docs", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for the default tenant, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, testAdminKey, "acme", http.MethodGet, "/v1/indexes/docs", nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the admin key to act for acme, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, acme, "default", http.MethodGet, "/v1/indexes", nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for the key of acme naming another tenant, got %d", recorder.Code)
+	}
+
+	// Other tenants only manage their own keys
+	recorder := serveAs(e, acme, "", http.MethodPost, "/v1/admin/keys", models.CreateAPIKeyRequest{
+		TenantID: "globex", Name: "stolen", Permissions: []string{"*"},
+	})
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for acme to create a key of globex, got %d", recorder.Code)
+	}
+	recorder = serveAs(e, acme, "", http.MethodGet, "/v1/admin/keys", nil)
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), acmeID) ||
+		strings.Contains(recorder.Body.String(), defaultID) {
+		t.Errorf("Expected acme to list its own keys only, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveAs(e, acme, "", method, "/v1/admin/keys/"+defaultID, nil); recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for acme to %s a key of the default tenant, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveAs(e, acme, "", http.MethodPost, "/v1/admin/keys/"+defaultID+"/revoke", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for acme to revoke a key of the default tenant, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, testAdminKey, "", http.MethodGet, "/v1/admin/keys/"+acmeID, nil); recorder.Code != http.StatusOK {
+		t.Errorf("Expected the default tenant to get the key of acme, got %d", recorder.Code)
+	}
+}
+
+func TestGRPCTenantIsolation(t *testing.T) {
+	conn, handlers := newTestGRPC(t)
+	collections := vjvectorv1.NewCollectionServiceClient(conn)
+	acme := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "acme")
+	globex := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "globex")
+
+	if _, err := collections.CreateCollection(acme, &vjvectorv1.CreateCollectionRequest{
+		Name: "docs", Type: "hnsw", Dimension: contractDimension,
+	}); err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+	_, err := collections.GetCollection(globex, &vjvectorv1.GetCollectionRequest{Name: "docs"})
+	expectCode(t, err, codes.NotFound)
+	_, err = collections.GetCollection(globex, &vjvectorv1.GetCollectionRequest{Name: "acme~docs"})
+	expectCode(t, err, codes.InvalidArgument)
+	listed, err := collections.ListCollections(globex, &vjvectorv1.ListCollectionsRequest{})
+	if err != nil || len(listed.Collections) != 0 {
+		t.Errorf("Expected globex to list no collections, got %v, %v", listed, err)
+	}
+
+	// Calls of suspended tenants are denied
+	ctx := context.Background()
+	if err := handlers.tenants.CreateTenant(ctx, &tenant.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	if err := handlers.tenants.SuspendTenant(ctx, "acme", "payment overdue"); err != nil {
+		t.Fatalf("Failed to suspend tenant: %v", err)
+	}
+	_, err = collections.GetCollection(acme, &vjvectorv1.GetCollectionRequest{Name: "docs"})
+	expectCode(t, err, codes.PermissionDenied)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/enterprise"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"github.com/vijaynallagatla/vjvector/pkg/webhook"
+)
+
+// quotaEventInterval is the least time between two quota events of a tenant
+// for the same endpoint, so that a client retrying in a loop does not flood
+// the webhooks of its tenant
+const quotaEventInterval = time.Minute
+
+// OpenWebhooks replaces the webhook dispatcher of the handlers, which keeps
+// dead letters in memory only, with one configured by config, such as one
+// keeping them on disk. defaults are the webhooks of the default tenant, which
+// has no tenant settings to hold them.
+func (h *Handlers) OpenWebhooks(config webhook.Config, defaults []webhook.Target) error {
+	dispatcher, err := webhook.NewDispatcher(config, h.webhookTargets, nil)
+	if err != nil {
+		return err
+	}
+	if h.webhooks != nil {
+		_ = h.webhooks.Close()
+	}
+	h.webhooks = dispatcher
+	h.defaultWebhooks = defaults
+	return nil
+}
+
+// webhookTargets returns the webhooks of a tenant: those of its settings, or
+// the default webhooks for the default tenant
+func (h *Handlers) webhookTargets(tenantID string) ([]webhook.Target, error) {
+	if tenantID == DefaultTenant {
+		return h.defaultWebhooks, nil
+	}
+
+	settings, err := h.tenants.GetTenantSettings(context.Background(), tenantID)
+	if errors.Is(err, tenant.ErrTenantNotFound) {
+		return nil, nil
+	}
+	if err != nil || settings == nil {
+		return nil, err
+	}
+	targets := make([]webhook.Target, len(settings.WebhookURLs))
+	for i, url := range settings.WebhookURLs {
+		targets[i] = webhook.Target{URL: url, Secret: settings.WebhookSecret}
+	}
+	return targets, nil
+}
+
+// publish sends an event of a tenant to its webhooks. The operation it reports
+// already succeeded, so failures to queue the event are only logged.
+func (h *Handlers) publish(tenantID, eventType string, data interface{}) {
+	if _, err := h.webhooks.Publish(tenantID, eventType, data); err != nil {
+		slog.Warn("Failed to publish webhook event", "tenant_id", tenantID, "type", eventType, "error", err)
+	}
+}
+
+// publishBulkWrite reports a bulk write of vectors to a collection of the
+// tenant of ctx
+func (h *Handlers) publishBulkWrite(ctx context.Context, event models.VectorsBulkEvent) {
+	h.publish(h.tenantOf(ctx), webhook.EventVectorsBulk, event)
+}
+
+// publishRebuilt reports the index rebuild of a stored collection to the
+// tenant owning it
+func (h *Handlers) publishRebuilt(progress catalog.RebuildProgress, trigger string) {
+	tenantID, name := tenant.SplitCollectionName(progress.Collection)
+	h.publish(tenantID, webhook.EventIndexRebuilt, models.IndexRebuiltEvent{
+		Collection: name,
+		Indexed:    progress.Indexed,
+		Trigger:    trigger,
+	})
+}
+
+// publishJob reports a finished job to its tenant, without its parameters
+// and result, which are polled from the job itself
+func (h *Handlers) publishJob(job *jobs.Job) {
+	record := *job
+	record.Params, record.Result = nil, nil
+	h.publish(job.TenantID, webhook.EventJobCompleted, &record)
+}
+
+// publishQuotaExceeded reports a request denied by the rate limits, at most
+// once per quota event interval for each endpoint of a tenant
+func (h *Handlers) publishQuotaExceeded(request enterprise.RateLimitRequest, decision enterprise.RateLimitDecision) {
+	now := time.Now()
+	key := request.TenantID + " " + request.Endpoint
+
+	h.quotaEventsMutex.Lock()
+	if now.Sub(h.quotaEvents[key]) < quotaEventInterval {
+		h.quotaEventsMutex.Unlock()
+		return
+	}
+	if h.quotaEvents == nil {
+		h.quotaEvents = make(map[string]time.Time)
+	}
+	h.quotaEvents[key] = now
+	h.quotaEventsMutex.Unlock()
+
+	h.publish(request.TenantID, webhook.EventQuotaExceeded, models.QuotaExceededEvent{
+		Quota:             "rate_limit",
+		Endpoint:          request.Endpoint,
+		KeyID:             request.KeyID,
+		Limit:             decision.Limit,
+		RetryAfterSeconds: retryAfterSeconds(decision),
+	})
+}
+
+// listWebhookDeliveries lists the recent webhook deliveries of the tenant of a
+// request, newest first, optionally only those in a state
+func (h *Handlers) listWebhookDeliveries(c echo.Context) error {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	state := webhook.DeliveryState(c.QueryParam("state"))
+	switch state {
+	case "", webhook.DeliveryPending, webhook.DeliveryDelivered, webhook.DeliveryDeadLettered:
+	default:
+		return errorResponse(c, http.StatusBadRequest, "state must be pending, delivered or dead_lettered")
+	}
+
+	deliveries := h.webhooks.Deliveries(h.tenantOf(c.Request().Context()), state, limit, offset)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// listWebhookDeadLetters lists the webhook deliveries of the tenant of a
+// request that ran out of attempts, newest first
+func (h *Handlers) listWebhookDeadLetters(c echo.Context) error {
+	limit, offset, err := pageParams(c)
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+
+	deliveries := h.webhooks.DeadLetters(h.tenantOf(c.Request().Context()), limit, offset)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// redeliverWebhook takes a dead letter out of the dead-letter store and
+// attempts its delivery again
+func (h *Handlers) redeliverWebhook(c echo.Context) error {
+	delivery, err := h.webhooks.Redeliver(h.tenantOf(c.Request().Context()), c.Param("deliveryId"))
+	if err != nil {
+		return errorResponse(c, errorStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusAccepted, delivery)
+}
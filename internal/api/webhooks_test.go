@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/models"
+	"github.com/vijaynallagatla/vjvector/pkg/jobs"
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+	"github.com/vijaynallagatla/vjvector/pkg/webhook"
+)
+
+const testWebhookSecret = "whsec"
+
+// webhookReceiver is an httptest endpoint keeping the events it is sent,
+// rejecting them while reject is set
+type webhookReceiver struct {
+	*httptest.Server
+	reject atomic.Bool
+
+	mutex      sync.Mutex
+	events     []webhook.Event
+	deliveries []string
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	t.Helper()
+
+	r := &webhookReceiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		if err := webhook.Verify(testWebhookSecret, req.Header.Get(webhook.SignatureHeader), body, time.Minute); err != nil {
+			t.Errorf("Received an event with an invalid signature: %v", err)
+		}
+		if r.reject.Load() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+
+		var event webhook.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("Received an invalid event: %v", err)
+		}
+		r.mutex.Lock()
+		r.events = append(r.events, event)
+		r.deliveries = append(r.deliveries, req.Header.Get(webhook.DeliveryHeader))
+		r.mutex.Unlock()
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// waitEvent waits for an event of a type, decoding its data into data, and
+// returns the ID of its delivery
+func (r *webhookReceiver) waitEvent(t *testing.T, eventType string, data interface{}) string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mutex.Lock()
+		for i, event := range r.events {
+			if event.Type != eventType {
+				continue
+			}
+			delivery := r.deliveries[i]
+			r.events = append(r.events[:i], r.events[i+1:]...)
+			r.deliveries = append(r.deliveries[:i], r.deliveries[i+1:]...)
+			r.mutex.Unlock()
+
+			if event.TenantID != "acme" {
+				t.Errorf("Expected an event of acme, got %s", event.TenantID)
+			}
+			if err := json.Unmarshal(event.Data, data); err != nil {
+				t.Fatalf("Failed to decode %s event: %v", eventType, err)
+			}
+			return delivery
+		}
+		r.mutex.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("No %s event received", eventType)
+	return ""
+}
+
+// openTestWebhooks opens a webhook dispatcher retrying without delay, and
+// registers acme with a receiver as its webhook
+func openTestWebhooks(t *testing.T, e *echo.Echo, handlers *Handlers) *webhookReceiver {
+	t.Helper()
+
+	config := webhook.DefaultConfig(t.TempDir())
+	config.MaxAttempts = 2
+	config.InitialBackoff, config.MaxBackoff = time.Millisecond, time.Millisecond
+	if err := handlers.OpenWebhooks(config, nil); err != nil {
+		t.Fatalf("Failed to open webhooks: %v", err)
+	}
+
+	r := newWebhookReceiver(t)
+	settings := map[string]interface{}{"api_rate_limit": 100, "max_concurrent_users": 10, "webhook_urls": []string{r.URL}}
+	acme := map[string]interface{}{"id": "acme", "name": "Acme", "settings": settings}
+	if recorder := serveAs(e, "", "", http.MethodPost, "/v1/admin/tenants", acme); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for webhooks without a secret, got %d", recorder.Code)
+	}
+	settings["webhook_secret"] = testWebhookSecret
+	if recorder := serveAs(e, "", "", http.MethodPost, "/v1/admin/tenants", acme); recorder.Code != http.StatusCreated {
+		t.Fatalf("Failed to create tenant: %d %s", recorder.Code, recorder.Body.String())
+	}
+	return r
+}
+
+// webhookDeliveries lists the deliveries of a tenant from an endpoint
+func webhookDeliveries(t *testing.T, e *echo.Echo, tenantID, path string) []webhook.Delivery {
+	t.Helper()
+
+	recorder := serveAs(e, "", tenantID, http.MethodGet, path, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to list deliveries: %d %s", recorder.Code, recorder.Body.String())
+	}
+	var list struct {
+		Deliveries []webhook.Delivery `json:"deliveries"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode deliveries: %v", err)
+	}
+	return list.Deliveries
+}
+
+func TestWebhooks(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	r := openTestWebhooks(t, e, handlers)
+	createTenantIndex(t, e, "", "acme", "raft")
+	createTenantIndex(t, e, "", "", "raft")
+
+	// Batches report their puts and deletes
+	recorder := serveAs(e, "", "acme", http.MethodPost, "/v1/indexes/docs/batch", map[string]interface{}{
+		"writes": []interface{}{
+			map[string]interface{}{"op": "put", "vector": map[string]interface{}{"id": "paxos", "embedding": embedText(t, "paxos")}},
+			map[string]interface{}{"op": "delete", "id": "raft"},
+		},
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Failed to write batch: %s", recorder.Body.String())
+	}
+	var bulk models.VectorsBulkEvent
+	r.waitEvent(t, webhook.EventVectorsBulk, &bulk)
+	if bulk != (models.VectorsBulkEvent{Collection: "docs", Operation: "batch", Written: 1, Deleted: 1, TotalVectors: 1}) {
+		t.Errorf("Unexpected batch event %+v", bulk)
+	}
+
+	// Jobs report their completion, and reindex jobs the indexes they rebuilt
+	imported := submitTestJob(t, e, "", "acme", models.SubmitJobRequest{
+		Type: "import",
+		Params: jobParams(t, models.ImportJobParams{Collection: "docs", Records: []models.IngestRecord{
+			{Vector: models.Vector{ID: "gossip", Embedding: embedText(t, "gossip")}},
+		}}),
+	})
+	var job jobs.Job
+	r.waitEvent(t, webhook.EventJobCompleted, &job)
+	if job.ID != imported.ID || job.State != jobs.StateSucceeded || len(job.Params) != 0 {
+		t.Errorf("Unexpected job event %+v", job)
+	}
+	r.waitEvent(t, webhook.EventVectorsBulk, &bulk)
+	if bulk.Operation != "import" || bulk.Written != 1 || bulk.TotalVectors != 2 {
+		t.Errorf("Unexpected import event %+v", bulk)
+	}
+
+	submitTestJob(t, e, "", "acme", models.SubmitJobRequest{Type: "reindex"})
+	var rebuilt models.IndexRebuiltEvent
+	r.waitEvent(t, webhook.EventIndexRebuilt, &rebuilt)
+	if rebuilt != (models.IndexRebuiltEvent{Collection: "docs", Indexed: 2, Trigger: "reindex"}) {
+		t.Errorf("Unexpected rebuild event %+v", rebuilt)
+	}
+	r.waitEvent(t, webhook.EventJobCompleted, &job)
+
+	// The delivery log of a tenant holds its deliveries only; the last one is
+	// recorded as delivered once the receiver answered
+	deadline := time.Now().Add(5 * time.Second)
+	var delivered []webhook.Delivery
+	for len(delivered) < 5 && time.Now().Before(deadline) {
+		delivered = webhookDeliveries(t, e, "acme", "/v1/webhooks/deliveries?state=delivered")
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(delivered) != 5 || delivered[0].Attempts != 1 {
+		t.Errorf("Expected 5 events delivered at the first attempt, got %+v", delivered)
+	}
+	if deliveries := webhookDeliveries(t, e, "", "/v1/webhooks/deliveries"); len(deliveries) != 0 {
+		t.Errorf("Expected no deliveries for the default tenant, got %+v", deliveries)
+	}
+
+	// Rejected deliveries are dead-lettered until they are redelivered
+	r.reject.Store(true)
+	submitTestJob(t, e, "", "acme", models.SubmitJobRequest{Type: "compact"})
+	deadline = time.Now().Add(5 * time.Second)
+	var dead []webhook.Delivery
+	for len(dead) == 0 && time.Now().Before(deadline) {
+		dead = webhookDeliveries(t, e, "acme", "/v1/webhooks/dead-letters")
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(dead) != 1 || dead[0].EventType != webhook.EventJobCompleted || dead[0].StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected the rejected job event to be dead-lettered, got %+v", dead)
+	}
+	r.reject.Store(false)
+
+	path := "/v1/webhooks/dead-letters/" + dead[0].ID + "/redeliver"
+	if recorder := serveAs(e, "", "", http.MethodPost, path, nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 to redeliver a dead letter of another tenant, got %d", recorder.Code)
+	}
+	if recorder := serveAs(e, "", "acme", http.MethodPost, path, nil); recorder.Code != http.StatusAccepted {
+		t.Fatalf("Failed to redeliver: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if delivery := r.waitEvent(t, webhook.EventJobCompleted, &job); delivery != dead[0].ID || job.Type != "compact" {
+		t.Errorf("Expected the dead letter to be redelivered, got %s for %+v", delivery, job)
+	}
+	if dead := webhookDeliveries(t, e, "acme", "/v1/webhooks/dead-letters"); len(dead) != 0 {
+		t.Errorf("Expected no dead letters after redelivery, got %+v", dead)
+	}
+}
+
+func TestWebhookQuotaEvents(t *testing.T) {
+	e, handlers := newTestRouter(t)
+	r := openTestWebhooks(t, e, handlers)
+	handlers.EnableRateLimiting(testRateLimits())
+
+	settings, err := handlers.tenants.GetTenantSettings(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("Failed to get settings: %v", err)
+	}
+	settings.APIRateLimit = 1
+	if err := handlers.tenants.UpdateTenantSettings(context.Background(), "acme", settings); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+
+	// Only the first request denied within the interval is reported
+	for i, expected := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		if recorder := serveAs(e, "", "acme", http.MethodGet, "/v1/indexes", nil); recorder.Code != expected {
+			t.Fatalf("Expected request %d to get %d, got %d", i+1, expected, recorder.Code)
+		}
+	}
+	var quota models.QuotaExceededEvent
+	r.waitEvent(t, webhook.EventQuotaExceeded, &quota)
+	if quota.Quota != "rate_limit" || quota.Endpoint != "GET:/v1/indexes" || quota.Limit != 1 || quota.RetryAfterSeconds < 1 {
+		t.Errorf("Unexpected quota event %+v", quota)
+	}
+	if deliveries := handlers.webhooks.Deliveries("acme", "", 0, 0); len(deliveries) != 1 {
+		t.Errorf("Expected a single quota event, got %d", len(deliveries))
+	}
+
+	// Tenants can drop their webhooks
+	settings.WebhookURLs = nil
+	if err := handlers.tenants.UpdateTenantSettings(context.Background(), "acme", settings); err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	if targets, err := handlers.webhookTargets("acme"); err != nil || len(targets) != 0 {
+		t.Errorf("Expected no webhooks, got %v %v", targets, err)
+	}
+	if _, err := handlers.webhookTargets("missing"); err != nil {
+		t.Errorf("Expected no webhooks for a missing tenant, got %v", err)
+	}
+	settings.WebhookURLs = []string{"ftp://example.com"}
+	if err := handlers.tenants.UpdateTenantSettings(context.Background(), "acme", settings); !errors.Is(err, tenant.ErrInvalidTenant) {
+		t.Errorf("Expected an invalid webhook URL to be rejected, got %v", err)
+	}
+}
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/tenant"
+)
 
 // Vector represents a vector in the API layer
 type Vector struct {
@@ -8,6 +13,14 @@ type Vector struct {
 	Collection string                 `json:"collection,omitempty"`
 	Embedding  []float64              `json:"embedding,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	// TTLSeconds and ExpiresAt are alternative ways to make the vector expire
+	TTLSeconds int64      `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+
+	// ExpectedVersion makes the write conditional on the stored version of the
+	// vector; zero requires that the vector is not stored yet
+	ExpectedVersion *uint64 `json:"expected_version,omitempty"`
 }
 
 // CreateIndexRequest represents the request to create a new index
@@ -26,15 +39,215 @@ type CreateIndexRequest struct {
 	Normalize      bool   `json:"normalize"`
 }
 
+// IndexInfo describes a collection with the statistics of its index
+type IndexInfo struct {
+	ID             string  `json:"id"`
+	Type           string  `json:"type"`
+	Dimension      int     `json:"dimension"`
+	DistanceMetric string  `json:"distance_metric"`
+	TotalVectors   int64   `json:"total_vectors"`
+	MemoryUsage    int64   `json:"memory_usage"`
+	IndexSize      int64   `json:"index_size"`
+	AvgSearchTime  float64 `json:"avg_search_time"`
+	AvgInsertTime  float64 `json:"avg_insert_time"`
+}
+
 // InsertVectorsRequest represents the request to insert vectors
 type InsertVectorsRequest struct {
 	Vectors []*Vector `json:"vectors"`
 }
 
+// WriteBatchRequest represents a batch of writes applied atomically to a collection
+type WriteBatchRequest struct {
+	Writes []*WriteOperation `json:"writes"`
+}
+
+// WriteOperation is one write of a batch: "put" writes Vector, "delete" removes
+// the vector with ID
+type WriteOperation struct {
+	Op     string  `json:"op"`
+	Vector *Vector `json:"vector,omitempty"`
+	ID     string  `json:"id,omitempty"`
+
+	// ExpectedVersion makes the batch conditional on the stored version of the
+	// vector; zero requires that the vector is not stored yet
+	ExpectedVersion *uint64 `json:"expected_version,omitempty"`
+}
+
+// IngestRecord is a line of an NDJSON ingest stream: a vector whose embedding
+// may be left out for Text to be embedded instead
+type IngestRecord struct {
+	Vector
+	Text string `json:"text,omitempty"`
+}
+
+// IngestResult reports the outcome of a line of an NDJSON ingest stream
+type IngestResult struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id,omitempty"`
+	Version uint64 `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// IngestSummary ends the results of an NDJSON ingest stream. Error is set
+// when the stream ended before its last line was ingested.
+type IngestSummary struct {
+	Done         bool   `json:"done"`
+	Lines        int    `json:"lines"`
+	Ingested     int    `json:"ingested"`
+	Failed       int    `json:"failed"`
+	Chunks       int    `json:"chunks"`
+	TotalVectors int64  `json:"total_vectors"`
+	IngestTime   string `json:"ingest_time"`
+	Error        string `json:"error,omitempty"`
+}
+
+// CreateAPIKeyRequest represents the request to create an API key. Permissions
+// are "resource:action" pairs, "resource:*" or "*"; scopes default to "*".
+type CreateAPIKeyRequest struct {
+	TenantID    string     `json:"tenant_id,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Permissions []string   `json:"permissions"`
+	Scopes      []string   `json:"scopes,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	// IPRestrictions and UserAgentRestrictions limit the clients that may
+	// use the key
+	IPRestrictions        []string `json:"ip_restrictions,omitempty"`
+	UserAgentRestrictions []string `json:"user_agent_restrictions,omitempty"`
+}
+
+// RevokeAPIKeyRequest represents the request to revoke an API key
+type RevokeAPIKeyRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// CreateTenantRequest represents the request to register a tenant. Unset
+// settings and quotas select the defaults.
+type CreateTenantRequest struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Domain      string                 `json:"domain,omitempty"`
+	Plan        string                 `json:"plan,omitempty"`
+	ExpiresAt   *time.Time             `json:"expires_at,omitempty"`
+	Metadata    map[string]string      `json:"metadata,omitempty"`
+	Settings    *tenant.TenantSettings `json:"settings,omitempty"`
+	Quotas      *tenant.TenantQuotas   `json:"quotas,omitempty"`
+}
+
+// UpdateTenantStatusRequest represents the request to activate, suspend or
+// deactivate a tenant
+type UpdateTenantStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// SubmitJobRequest represents the request to run a long-running operation as
+// a job. Params are the EmbedJobParams, ReindexJobParams, CompactJobParams or
+// ImportJobParams of the job type.
+type SubmitJobRequest struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// EmbedJobParams are the parameters of an embed job, which embeds texts with
+// the embedding service of a dimension
+type EmbedJobParams struct {
+	Texts     []string `json:"texts"`
+	Dimension int      `json:"dimension"`
+}
+
+// EmbedJobResult is the result of an embed job, with an embedding per text
+type EmbedJobResult struct {
+	Embeddings [][]float64 `json:"embeddings"`
+	Dimension  int         `json:"dimension"`
+	Count      int         `json:"count"`
+}
+
+// ReindexJobParams are the parameters of a reindex job, which rebuilds the
+// index of a collection from its storage, or of every collection when
+// Collection is empty
+type ReindexJobParams struct {
+	Collection string `json:"collection,omitempty"`
+}
+
+// ReindexJobResult is the result of a reindex job
+type ReindexJobResult struct {
+	Collections []string `json:"collections"`
+	Indexed     int64    `json:"indexed"`
+}
+
+// CompactJobParams are the parameters of a compact job, which compacts the
+// storage of a collection, or of every collection when Collection is empty
+type CompactJobParams struct {
+	Collection string `json:"collection,omitempty"`
+}
+
+// CompactJobResult is the result of a compact job
+type CompactJobResult struct {
+	Collections []string `json:"collections"`
+	CompactTime string   `json:"compact_time"`
+}
+
+// ImportJobParams are the parameters of an import job, which writes records
+// into a collection in chunks the way an ingest stream does
+type ImportJobParams struct {
+	Collection string         `json:"collection"`
+	Records    []IngestRecord `json:"records"`
+	ChunkSize  int            `json:"chunk_size,omitempty"`
+	Embed      bool           `json:"embed,omitempty"`
+}
+
+// ImportJobResult is the result of an import job. Failures holds the result
+// of every record that failed, numbered from 1 in the order of the records.
+type ImportJobResult struct {
+	Ingested     int            `json:"ingested"`
+	Failed       int            `json:"failed"`
+	Failures     []IngestResult `json:"failures,omitempty"`
+	TotalVectors int64          `json:"total_vectors"`
+	ImportTime   string         `json:"import_time"`
+}
+
+// VectorsBulkEvent is the data of a webhook event reporting a bulk write of
+// vectors to a collection, by a batch, an ingest stream, a gRPC bulk insert
+// or an import job
+type VectorsBulkEvent struct {
+	Collection   string `json:"collection"`
+	Operation    string `json:"operation"`
+	Written      int    `json:"written"`
+	Deleted      int    `json:"deleted,omitempty"`
+	Failed       int    `json:"failed,omitempty"`
+	TotalVectors int64  `json:"total_vectors"`
+}
+
+// QuotaExceededEvent is the data of a webhook event reporting a request of
+// the tenant rejected by its rate limit
+type QuotaExceededEvent struct {
+	Quota             string `json:"quota"`
+	Endpoint          string `json:"endpoint"`
+	KeyID             string `json:"key_id,omitempty"`
+	Limit             int    `json:"limit"`
+	RetryAfterSeconds int    `json:"retry_after_seconds"`
+}
+
+// IndexRebuiltEvent is the data of a webhook event reporting a collection
+// whose index was rebuilt from storage, at startup or by a reindex job
+type IndexRebuiltEvent struct {
+	Collection string `json:"collection"`
+	Indexed    int64  `json:"indexed"`
+	Trigger    string `json:"trigger"`
+}
+
 // SearchRequest represents the request to search for similar vectors
 type SearchRequest struct {
 	Query []float64 `json:"query"`
 	K     int       `json:"k"`
+
+	// AsOf searches the vectors as they were at the given time
+	AsOf *time.Time `json:"as_of,omitempty"`
 }
 
 // RAG Operations Types
@@ -75,6 +288,30 @@ type RAGResponse struct {
 	Metadata            map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// RAGStage represents a step of a RAG operation reported by a RAG stream
+type RAGStage string
+
+const (
+	RAGStageExpanded   RAGStage = "expanded"
+	RAGStageCandidates RAGStage = "candidates"
+	RAGStageReranked   RAGStage = "reranked"
+	RAGStageDone       RAGStage = "done"
+	RAGStageError      RAGStage = "error"
+)
+
+// RAGStreamEvent represents a step of a streamed RAG operation, sent as soon
+// as the step finishes. The stream ends with the response of the operation in
+// a done event, or with an error event.
+type RAGStreamEvent struct {
+	Stage           RAGStage       `json:"stage"`
+	ExpandedQueries []string       `json:"expanded_queries,omitempty"`
+	Results         []SearchResult `json:"results,omitempty"`
+	Response        *RAGResponse   `json:"response,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	Code            string         `json:"code,omitempty"`
+	ElapsedTime     time.Duration  `json:"elapsed_time"`
+}
+
 // BatchRAGRequest represents a batch RAG operation request
 type BatchRAGRequest struct {
 	Operation     RAGOperation           `json:"operation"`
@@ -100,15 +337,17 @@ type BatchRAGResponse struct {
 	RAGMetrics     RAGMetrics      `json:"rag_metrics,omitempty"`
 }
 
-// RAGConfig represents configuration for RAG operations
+// RAGConfig represents configuration for RAG operations. The steps of
+// end-to-end RAG are enabled unless their flag is set to false, and unset
+// component configurations select the defaults of the component.
 type RAGConfig struct {
-	EnableQueryExpansion   bool                 `json:"enable_query_expansion"`
-	EnableResultReranking  bool                 `json:"enable_result_reranking"`
-	EnableContextAwareness bool                 `json:"enable_context_awareness"`
-	QueryExpansionConfig   QueryExpansionConfig `json:"query_expansion_config,omitempty"`
-	RerankingConfig        RerankingConfig      `json:"reranking_config,omitempty"`
-	ContextConfig          ContextConfig        `json:"context_config,omitempty"`
-	SearchConfig           SearchConfig         `json:"search_config,omitempty"`
+	EnableQueryExpansion   *bool                 `json:"enable_query_expansion,omitempty"`
+	EnableResultReranking  *bool                 `json:"enable_result_reranking,omitempty"`
+	EnableContextAwareness *bool                 `json:"enable_context_awareness,omitempty"`
+	QueryExpansionConfig   *QueryExpansionConfig `json:"query_expansion_config,omitempty"`
+	RerankingConfig        *RerankingConfig      `json:"reranking_config,omitempty"`
+	ContextConfig          *ContextConfig        `json:"context_config,omitempty"`
+	SearchConfig           *SearchConfig         `json:"search_config,omitempty"`
 }
 
 // QueryExpansionConfig represents configuration for query expansion
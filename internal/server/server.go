@@ -44,6 +44,9 @@ func NewServer() (*Server, error) {
 	// Configure Echo
 	e.HideBanner = true
 	e.HidePort = true
+	// Take client addresses from the connection; any client can send
+	// X-Forwarded-For, and keys can be restricted to addresses
+	e.IPExtractor = echo.ExtractIPDirect()
 
 	// Add middleware
 	e.Use(middleware.Logger())
@@ -0,0 +1,37 @@
+package vjvector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockFileName is the file of a database directory that its opener locks
+const lockFileName = "LOCK"
+
+// lockDirectory takes an exclusive lock on a database directory. The lock is
+// released by unlockDirectory, or by the operating system when the process
+// exits, so a crash leaves no stale lock behind.
+func lockDirectory(dir string) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, dir)
+		}
+		return nil, fmt.Errorf("failed to lock database directory: %w", err)
+	}
+	return file, nil
+}
+
+// unlockDirectory releases the lock taken by lockDirectory
+func unlockDirectory(file *os.File) {
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	_ = file.Close()
+}
@@ -0,0 +1,910 @@
+// Package backup creates and restores consistent archives of a VJVector catalog.
+// Full backups hold the collection definitions, every stored vector with its
+// version history and the change log records written while the vectors
+// were copied; incremental backups hold the change log records written
+// after a given position. The backups of an encrypted catalog hold every value
+// encrypted by the sealer of the catalog, and are restored into a catalog with
+// the same encryption keys.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+const (
+	formatVersion   = 1
+	manifestName    = "manifest.json"
+	collectionsName = "collections.json"
+	logName         = "wal.jsonl"
+	vectorsDir      = "vectors"
+
+	// restoreBatchSize is about the number of versions loaded per catalog call during restore
+	restoreBatchSize = 500
+
+	// maxManifestSize bounds the manifest read into memory
+	maxManifestSize = 16 * 1024 * 1024
+)
+
+// Kind identifies the type of a backup
+type Kind string
+
+// Kind constants define the available backup types
+const (
+	KindFull        Kind = "full"
+	KindIncremental Kind = "incremental"
+)
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	Version   int       `json:"version"`
+	Kind      Kind      `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// BasePosition is the log position an incremental backup starts after; zero for full backups
+	BasePosition uint64 `json:"base_position"`
+
+	// Position is the log position the catalog is at once the backup is restored
+	Position uint64 `json:"position"`
+
+	Collections []CollectionSummary `json:"collections,omitempty"`
+	Files       []File              `json:"files"`
+
+	// Sealed tells that the values of every file are encrypted, as the
+	// backup of an encrypted catalog
+	Sealed bool `json:"sealed,omitempty"`
+}
+
+// CollectionSummary records the size of a collection in a full backup
+type CollectionSummary struct {
+	Name    string `json:"name"`
+	Vectors int64  `json:"vectors"`
+
+	// Versions counts the versions archived, including previous versions and
+	// the tombstones of deleted vectors
+	Versions int64 `json:"versions,omitempty"`
+}
+
+// File records the size and SHA-256 checksum of a file in the archive
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Options controls how a backup is created
+type Options struct {
+	// Incremental selects an incremental backup of the log records after Since
+	Incremental bool
+
+	// Since is the log position of the previous backup in the chain
+	Since uint64
+}
+
+// Create writes a backup of the catalog to w as a gzip-compressed tar archive
+// and records its position with the catalog, whose checkpoints keep the log
+// records after it for the next incremental backup. A full backup copies the
+// vectors while writes go on, together with the log records of those writes,
+// and restores to the catalog as it was once the copy was complete.
+func Create(ctx context.Context, cat *catalog.Catalog, w io.Writer, opts Options) (*Manifest, error) {
+	manifest, err := create(ctx, cat, w, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cat.RecordBackup(manifest.Position); err != nil {
+		return manifest, fmt.Errorf("failed to record backup position: %w", err)
+	}
+	return manifest, nil
+}
+
+// create writes a backup of the catalog to w
+func create(ctx context.Context, cat *catalog.Catalog, w io.Writer, opts Options) (*Manifest, error) {
+	staging, err := os.MkdirTemp("", "vjvector-backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	sealer, err := cat.Sealer()
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{
+		Version:   formatVersion,
+		CreatedAt: time.Now().UTC(),
+		Sealed:    sealer != nil,
+	}
+	stage := &stage{dir: staging, manifest: manifest, sealer: sealer}
+
+	if opts.Incremental {
+		manifest.Kind = KindIncremental
+		manifest.BasePosition = opts.Since
+		err = stageLog(ctx, cat, stage)
+	} else {
+		manifest.Kind = KindFull
+		err = stageSnapshot(ctx, cat, stage)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeArchive(w, staging, manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// stageSnapshot copies the collection definitions, the versions of every
+// vector and the log records written meanwhile into the staging directory
+func stageSnapshot(ctx context.Context, cat *catalog.Catalog, stage *stage) error {
+	return cat.Snapshot(ctx, func(snapshot *catalog.Snapshot) error {
+		if err := stage.json(ctx, collectionsName, snapshot.Specs); err != nil {
+			return err
+		}
+
+		for _, spec := range snapshot.Specs {
+			summary := CollectionSummary{Name: spec.Collection.Name}
+
+			err := stage.lines(ctx, path.Join(vectorsDir, summary.Name+".jsonl"), func(encoder valueEncoder) error {
+				err := snapshot.ScanVersions(summary.Name, func(versions []*core.Vector) error {
+					if !versions[len(versions)-1].Deleted {
+						summary.Vectors++
+					}
+					for _, version := range versions {
+						summary.Versions++
+						if err := encoder.Encode(version); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if errors.Is(err, catalog.ErrCollectionNotFound) {
+					// The log records staged below delete the collection
+					return nil
+				}
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to back up collection %s: %w", summary.Name, err)
+			}
+
+			stage.manifest.Collections = append(stage.manifest.Collections, summary)
+		}
+
+		// The records of the writes made while the vectors were copied
+		return stage.lines(ctx, logName, func(encoder valueEncoder) error {
+			position, err := snapshot.ReadLog(func(record *catalog.Record) error {
+				return encoder.Encode(record)
+			})
+			stage.manifest.Position = position
+			return err
+		})
+	})
+}
+
+// stageLog copies the log records after the base position into the staging directory
+func stageLog(ctx context.Context, cat *catalog.Catalog, stage *stage) error {
+	return stage.lines(ctx, logName, func(encoder valueEncoder) error {
+		position, err := cat.ReadLog(ctx, stage.manifest.BasePosition, func(record *catalog.Record) error {
+			return encoder.Encode(record)
+		})
+		stage.manifest.Position = position
+		return err
+	})
+}
+
+// stage is the staging directory of an archive being created. With a sealer,
+// every staged value is encrypted.
+type stage struct {
+	dir      string
+	manifest *Manifest
+	sealer   *storage.Sealer
+}
+
+// json writes value as a single JSON document into the staging directory
+func (s *stage) json(ctx context.Context, name string, value interface{}) error {
+	return s.lines(ctx, name, func(encoder valueEncoder) error {
+		return encoder.Encode(value)
+	})
+}
+
+// lines creates a staged file, lets write fill it and records its checksum
+func (s *stage) lines(ctx context.Context, name string, write func(valueEncoder) error) error {
+	target := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Clean(target), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create staged file %s: %w", name, err)
+	}
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	buffered := bufio.NewWriter(io.MultiWriter(file, hash, counter))
+
+	var encoder valueEncoder = json.NewEncoder(buffered)
+	if s.sealer != nil {
+		encoder = &sealingEncoder{ctx: ctx, sealer: s.sealer, encoder: json.NewEncoder(buffered)}
+	}
+	writeErr := write(encoder)
+	if writeErr == nil {
+		writeErr = buffered.Flush()
+	}
+	if err := file.Close(); err != nil && writeErr == nil {
+		writeErr = fmt.Errorf("failed to close staged file %s: %w", name, err)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+
+	s.manifest.Files = append(s.manifest.Files, File{
+		Name:   name,
+		Size:   counter.n,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	})
+	return nil
+}
+
+// valueEncoder writes the JSON values of a staged file
+type valueEncoder interface {
+	Encode(value interface{}) error
+}
+
+// valueDecoder reads the JSON values of an extracted file
+type valueDecoder interface {
+	More() bool
+	Decode(value interface{}) error
+}
+
+// sealedValue is the form of a value encrypted by the sealer of a catalog
+type sealedValue struct {
+	KeyID  string `json:"key_id"`
+	Sealed []byte `json:"sealed"`
+}
+
+// sealingEncoder encrypts every value before writing it
+type sealingEncoder struct {
+	ctx     context.Context
+	sealer  *storage.Sealer
+	encoder *json.Encoder
+}
+
+// Encode encrypts the JSON form of value and writes it
+func (e *sealingEncoder) Encode(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	sealed, keyID, err := e.sealer.Seal(e.ctx, data)
+	if err != nil {
+		return err
+	}
+	return e.encoder.Encode(sealedValue{KeyID: keyID, Sealed: sealed})
+}
+
+// openingDecoder decrypts every value it reads
+type openingDecoder struct {
+	ctx     context.Context
+	sealer  *storage.Sealer
+	decoder *json.Decoder
+}
+
+// More reports whether there is another value to read
+func (d *openingDecoder) More() bool {
+	return d.decoder.More()
+}
+
+// Decode reads the next value, decrypts it and stores it in value
+func (d *openingDecoder) Decode(value interface{}) error {
+	var sealed sealedValue
+	if err := d.decoder.Decode(&sealed); err != nil {
+		return err
+	}
+	data, err := d.sealer.Open(d.ctx, sealed.Sealed, sealed.KeyID)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// writeArchive packs the staged files followed by the manifest into a tar.gz stream
+func writeArchive(w io.Writer, staging string, manifest *Manifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range manifest.Files {
+		if err := addFile(tw, staging, file); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	header := &tar.Header{
+		Name:    manifestName,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: manifest.CreatedAt,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write manifest header: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %w", err)
+	}
+
+	return nil
+}
+
+// addFile copies a staged file into the archive
+func addFile(tw *tar.Writer, staging string, file File) error {
+	source, err := os.Open(filepath.Join(staging, filepath.FromSlash(file.Name)))
+	if err != nil {
+		return fmt.Errorf("failed to open staged file %s: %w", file.Name, err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	header := &tar.Header{
+		Name:    file.Name,
+		Mode:    0600,
+		Size:    file.Size,
+		ModTime: time.Now().UTC(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write header for %s: %w", file.Name, err)
+	}
+	if _, err := io.Copy(tw, source); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", file.Name, err)
+	}
+
+	return nil
+}
+
+// Verify reads an archive and checks its manifest and every checksum
+func Verify(r io.Reader) (*Manifest, error) {
+	staging, err := os.MkdirTemp("", "vjvector-verify-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	return extract(r, staging)
+}
+
+// Restore validates each archive and restores it into a catalog staged next
+// to cat, which takes the place of the collections of cat once every archive
+// is restored; nothing is restored when an archive fails. Vectors keep their
+// versions and version history, and the log of cat moves on to the position of
+// the last archive. The first archive must be a full backup restored into an
+// empty catalog; every following archive must be an incremental backup
+// starting at the position of the one before it.
+func Restore(ctx context.Context, cat *catalog.Catalog, archives ...io.Reader) ([]*Manifest, error) {
+	if len(archives) == 0 {
+		return nil, fmt.Errorf("%w: no archives given", ErrInvalidArchive)
+	}
+
+	return restoreChain(ctx, cat, len(archives), func(i int) (io.ReadCloser, string, error) {
+		return io.NopCloser(archives[i]), fmt.Sprintf("archive %d", i+1), nil
+	})
+}
+
+// Upload writes a backup of the catalog to an object store under key, and
+// records its position with the catalog once it is stored, as Create does. The
+// archive is streamed to the store, in parts when it is large, and an archive
+// that fails part way is never stored.
+func Upload(ctx context.Context, cat *catalog.Catalog, store storage.ObjectStore, key string, opts Options) (*Manifest, error) {
+	reader, writer := io.Pipe()
+	var manifest *Manifest
+	created := make(chan error, 1)
+	go func() {
+		var err error
+		manifest, err = create(ctx, cat, writer, opts)
+		_ = writer.CloseWithError(err)
+		created <- err
+	}()
+
+	err := store.Put(ctx, key, reader)
+	_ = reader.CloseWithError(err)
+	if createErr := <-created; createErr != nil {
+		return nil, createErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload backup: %w", err)
+	}
+	if err := cat.RecordBackup(manifest.Position); err != nil {
+		return manifest, fmt.Errorf("failed to record backup position: %w", err)
+	}
+	return manifest, nil
+}
+
+// RestoreObjects restores the archives stored under keys in an object store,
+// in order, with the rules of Restore. Each archive is streamed from the store
+// while it is restored.
+func RestoreObjects(ctx context.Context, cat *catalog.Catalog, store storage.ObjectStore, keys ...string) ([]*Manifest, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no archives given", ErrInvalidArchive)
+	}
+
+	return restoreChain(ctx, cat, len(keys), func(i int) (io.ReadCloser, string, error) {
+		label := "archive " + keys[i]
+		archive, err := store.Get(ctx, keys[i])
+		return archive, label, err
+	})
+}
+
+// restoreChain restores count archives, opened in order by open together
+// with a label for errors, into a staged catalog that it swaps in at the end
+func restoreChain(ctx context.Context, cat *catalog.Catalog, count int,
+	open func(int) (io.ReadCloser, string, error)) ([]*Manifest, error) {
+	sealer, err := cat.Sealer()
+	if err != nil {
+		return nil, err
+	}
+	r := &restorer{target: cat, sealer: sealer, latest: make(map[string]map[string]restoredVersion)}
+	defer func() {
+		if r.staged != nil {
+			_ = r.staged.Discard()
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		archive, label, err := open(i)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+		manifest, err := r.restoreArchive(ctx, archive)
+		_ = archive.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+		r.manifests = append(r.manifests, manifest)
+	}
+
+	staged := r.staged
+	r.staged = nil
+	if err := cat.Swap(ctx, staged, r.manifests[len(r.manifests)-1].Position); err != nil {
+		_ = staged.Discard()
+		return nil, fmt.Errorf("failed to swap in the restored catalog: %w", err)
+	}
+	return r.manifests, nil
+}
+
+// restorer restores a chain of archives into a catalog staged next to the
+// target catalog
+type restorer struct {
+	target    *catalog.Catalog
+	staged    *catalog.Catalog
+	manifests []*Manifest
+
+	// sealer opens the values of sealed archives, and sealed tells whether
+	// the archive being restored is
+	sealer *storage.Sealer
+	sealed bool
+
+	// latest holds the newest version restored of every vector by collection,
+	// to tell the log records whose writes were already copied by a full
+	// backup from those it missed
+	latest map[string]map[string]restoredVersion
+}
+
+// restoredVersion is the version key of the newest version of a vector and
+// whether it is a tombstone
+type restoredVersion struct {
+	updated time.Time
+	deleted bool
+}
+
+// restoreArchive extracts, validates and applies a single archive
+func (r *restorer) restoreArchive(ctx context.Context, archive io.Reader) (*Manifest, error) {
+	staging, err := os.MkdirTemp("", "vjvector-restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	manifest, err := extract(archive, staging)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Sealed && r.sealer == nil {
+		return nil, ErrSealedArchive
+	}
+	r.sealed = manifest.Sealed
+
+	if len(r.manifests) == 0 {
+		if manifest.Kind != KindFull {
+			return nil, fmt.Errorf("%w: the first archive must be a full backup", ErrBrokenChain)
+		}
+		collections, err := r.target.List()
+		if err != nil {
+			return nil, err
+		}
+		if len(collections) > 0 {
+			return nil, ErrCatalogNotEmpty
+		}
+		if r.staged, err = r.target.Stage(); err != nil {
+			return nil, err
+		}
+		return manifest, r.restoreFull(ctx, staging, manifest)
+	}
+
+	last := r.manifests[len(r.manifests)-1]
+	if manifest.Kind != KindIncremental {
+		return nil, fmt.Errorf("%w: only the first archive may be a full backup", ErrBrokenChain)
+	}
+	if manifest.BasePosition != last.Position {
+		return nil, fmt.Errorf("%w: starts after position %d, previous backup ends at %d",
+			ErrBrokenChain, manifest.BasePosition, last.Position)
+	}
+
+	return manifest, r.restoreLog(ctx, staging)
+}
+
+// restoreFull recreates every collection, loads the versions of its vectors
+// and replays the log records written while they were copied
+func (r *restorer) restoreFull(ctx context.Context, staging string, manifest *Manifest) error {
+	var specs []catalog.Spec
+	err := r.readLines(ctx, filepath.Join(staging, collectionsName), func(decoder valueDecoder) error {
+		if err := decoder.Decode(&specs); err != nil {
+			return fmt.Errorf("%w: collection definitions: %v", ErrInvalidArchive, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[string]CollectionSummary, len(manifest.Collections))
+	for _, summary := range manifest.Collections {
+		expected[summary.Name] = summary
+	}
+
+	for _, spec := range specs {
+		if spec.Collection == nil {
+			return fmt.Errorf("%w: collection definition without a collection", ErrInvalidArchive)
+		}
+		name := spec.Collection.Name
+		summary, listed := expected[name]
+		if !listed {
+			return fmt.Errorf("%w: collection %s is missing from the manifest", ErrInvalidArchive, name)
+		}
+
+		if err := r.staged.CreateWithIndex(spec.Collection, spec.Index); err != nil {
+			return fmt.Errorf("failed to recreate collection %s: %w", name, err)
+		}
+
+		restored, err := r.restoreVectors(ctx, filepath.Join(staging, vectorsDir, name+".jsonl"), name)
+		if err != nil {
+			return fmt.Errorf("failed to restore collection %s: %w", name, err)
+		}
+		if restored.Vectors != summary.Vectors || summary.Versions > 0 && restored.Versions != summary.Versions {
+			return fmt.Errorf("%w: collection %s has %d vectors and %d versions, manifest lists %d and %d",
+				ErrInvalidArchive, name, restored.Vectors, restored.Versions, summary.Vectors, summary.Versions)
+		}
+	}
+
+	for _, file := range manifest.Files {
+		if file.Name == logName {
+			return r.restoreLog(ctx, staging)
+		}
+	}
+	return nil
+}
+
+// restoreVectors loads the archived versions of the vectors of a collection,
+// those of one vector after another, and counts them
+func (r *restorer) restoreVectors(ctx context.Context, name, collection string) (CollectionSummary, error) {
+	restored := CollectionSummary{Name: collection}
+	latest := r.collection(collection)
+
+	err := r.readLines(ctx, name, func(decoder valueDecoder) error {
+		batch := make([]*core.Vector, 0, restoreBatchSize)
+		for decoder.More() {
+			var version core.Vector
+			if err := decoder.Decode(&version); err != nil {
+				return fmt.Errorf("%w: vectors of %s: %v", ErrInvalidArchive, collection, err)
+			}
+
+			// A batch ends with the last version of a vector
+			if len(batch) >= restoreBatchSize && batch[len(batch)-1].ID != version.ID {
+				if err := r.staged.Load(ctx, collection, batch); err != nil {
+					return err
+				}
+				batch = make([]*core.Vector, 0, restoreBatchSize)
+			}
+			batch = append(batch, &version)
+
+			previous, seen := latest[version.ID]
+			if (!seen || previous.deleted) && !version.Deleted {
+				restored.Vectors++
+			} else if seen && !previous.deleted && version.Deleted {
+				restored.Vectors--
+			}
+			restored.Versions++
+			latest[version.ID] = restoredVersion{updated: version.UpdatedAt, deleted: version.Deleted}
+		}
+		return r.staged.Load(ctx, collection, batch)
+	})
+	return restored, err
+}
+
+// restoreLog replays the log records of an archive
+func (r *restorer) restoreLog(ctx context.Context, staging string) error {
+	return r.readLines(ctx, filepath.Join(staging, logName), func(decoder valueDecoder) error {
+		for decoder.More() {
+			var record catalog.Record
+			if err := decoder.Decode(&record); err != nil {
+				return fmt.Errorf("%w: log record: %v", ErrInvalidArchive, err)
+			}
+			if err := r.replay(ctx, &record); err != nil {
+				return fmt.Errorf("failed to replay log record %d: %w", record.Position, err)
+			}
+		}
+		return nil
+	})
+}
+
+// replay applies a log record to the staged catalog. Written vectors keep
+// their versions and deletions keep their times; writes older than the newest
+// version restored, which a full backup copied already, are skipped.
+func (r *restorer) replay(ctx context.Context, record *catalog.Record) error {
+	switch record.Op {
+	case catalog.OpInsert, catalog.OpDelete, catalog.OpWrite:
+	case catalog.OpDeleteCollection:
+		delete(r.latest, record.Collection)
+		return r.staged.Apply(ctx, record)
+	default:
+		return r.staged.Apply(ctx, record)
+	}
+
+	latest := r.collection(record.Collection)
+	versions := make([]*core.Vector, 0, len(record.Vectors)+len(record.IDs))
+	for _, vector := range record.Vectors {
+		if previous, seen := latest[vector.ID]; seen && !vector.UpdatedAt.After(previous.updated) {
+			continue
+		}
+		versions = append(versions, vector)
+		latest[vector.ID] = restoredVersion{updated: vector.UpdatedAt}
+	}
+	for _, id := range record.IDs {
+		previous, seen := latest[id]
+		if !seen || previous.deleted || !record.Timestamp.After(previous.updated) {
+			continue
+		}
+		versions = append(versions, &core.Vector{ID: id, UpdatedAt: record.Timestamp, Deleted: true})
+		latest[id] = restoredVersion{updated: record.Timestamp, deleted: true}
+	}
+	return r.staged.Load(ctx, record.Collection, versions)
+}
+
+// collection returns the newest versions restored of the vectors of a collection
+func (r *restorer) collection(name string) map[string]restoredVersion {
+	latest, exists := r.latest[name]
+	if !exists {
+		latest = make(map[string]restoredVersion)
+		r.latest[name] = latest
+	}
+	return latest
+}
+
+// readLines opens a staged file and hands a JSON decoder over its contents to
+// read, which decrypts the values of a sealed archive
+func (r *restorer) readLines(ctx context.Context, name string, read func(valueDecoder) error) error {
+	file, err := os.Open(filepath.Clean(name))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(name), err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	if r.sealed {
+		return read(&openingDecoder{ctx: ctx, sealer: r.sealer, decoder: decoder})
+	}
+	return read(decoder)
+}
+
+// extract unpacks an archive into staging and validates it against its manifest
+func extract(r io.Reader, staging string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+
+	tr := tar.NewReader(gz)
+	checksums := make(map[string]File)
+	var manifest *Manifest
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%w: unexpected entry %s", ErrInvalidArchive, header.Name)
+		}
+
+		if header.Name == manifestName {
+			if manifest != nil {
+				return nil, fmt.Errorf("%w: duplicate manifest", ErrInvalidArchive)
+			}
+			manifest, err = readManifest(tr)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if !validEntryName(header.Name) {
+			return nil, fmt.Errorf("%w: unexpected entry %s", ErrInvalidArchive, header.Name)
+		}
+		if _, duplicate := checksums[header.Name]; duplicate {
+			return nil, fmt.Errorf("%w: duplicate entry %s", ErrInvalidArchive, header.Name)
+		}
+
+		file, err := extractFile(tr, staging, header.Name)
+		if err != nil {
+			return nil, err
+		}
+		checksums[header.Name] = file
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing manifest", ErrInvalidArchive)
+	}
+	if err := validateManifest(manifest, checksums); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// readManifest decodes the manifest entry of an archive
+func readManifest(r io.Reader) (*Manifest, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxManifestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("%w: manifest too large", ErrInvalidArchive)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: manifest: %v", ErrInvalidArchive, err)
+	}
+	if manifest.Version != formatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedFormat, manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+// extractFile writes an archive entry into staging and computes its checksum
+func extractFile(r io.Reader, staging, name string) (File, error) {
+	target := filepath.Join(staging, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+		return File{}, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Clean(target), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+
+	hash := sha256.New()
+	size, copyErr := io.Copy(io.MultiWriter(file, hash), r)
+	if err := file.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return File{}, fmt.Errorf("failed to extract %s: %w", name, copyErr)
+	}
+
+	return File{Name: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// validateManifest checks that the archive holds exactly the files of the manifest
+func validateManifest(manifest *Manifest, extracted map[string]File) error {
+	switch manifest.Kind {
+	case KindFull:
+		if _, ok := extracted[collectionsName]; !ok {
+			return fmt.Errorf("%w: missing %s", ErrInvalidArchive, collectionsName)
+		}
+	case KindIncremental:
+		if _, ok := extracted[logName]; !ok {
+			return fmt.Errorf("%w: missing %s", ErrInvalidArchive, logName)
+		}
+		if manifest.Position < manifest.BasePosition {
+			return fmt.Errorf("%w: position %d precedes base position %d", ErrInvalidArchive, manifest.Position, manifest.BasePosition)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidArchive, manifest.Kind)
+	}
+
+	if len(manifest.Files) != len(extracted) {
+		return fmt.Errorf("%w: manifest lists %d files, archive holds %d", ErrInvalidArchive, len(manifest.Files), len(extracted))
+	}
+
+	for _, expected := range manifest.Files {
+		actual, ok := extracted[expected.Name]
+		if !ok {
+			return fmt.Errorf("%w: missing %s", ErrInvalidArchive, expected.Name)
+		}
+		if actual.Size != expected.Size || actual.SHA256 != expected.SHA256 {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, expected.Name)
+		}
+	}
+
+	return nil
+}
+
+// validEntryName reports whether name is a file this package writes into archives
+func validEntryName(name string) bool {
+	if name == collectionsName || name == logName {
+		return true
+	}
+
+	dir, file := path.Split(name)
+	if dir != vectorsDir+"/" || !strings.HasSuffix(file, ".jsonl") {
+		return false
+	}
+
+	collection := strings.TrimSuffix(file, ".jsonl")
+	return collection != "" && collection != "." && collection != ".." && !strings.ContainsAny(collection, `/\`)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+// Write counts p and never fails
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,386 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/security"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+func newTestCatalog(t *testing.T) *catalog.Catalog {
+	t.Helper()
+
+	cat, err := catalog.New(catalog.DefaultConfig(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to open catalog: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	})
+	return cat
+}
+
+func testVectors(prefix string, count, dimension int) []*core.Vector {
+	vectors := make([]*core.Vector, count)
+	for i := 0; i < count; i++ {
+		embedding := make([]float64, dimension)
+		for j := 0; j < dimension; j++ {
+			embedding[j] = float64(i+1) * float64(j+1) * 0.01
+		}
+		vectors[i] = &core.Vector{
+			ID:        fmt.Sprintf("%s_%d", prefix, i),
+			Embedding: embedding,
+			Metadata:  map[string]interface{}{"index": i},
+		}
+	}
+	return vectors
+}
+
+func TestBackup_FullAndIncrementalRestore(t *testing.T) {
+	ctx := context.Background()
+	source := newTestCatalog(t)
+
+	if err := source.Create(core.NewCollection("docs", "documents", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := source.Insert(ctx, "docs", testVectors("doc", 10, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	var full bytes.Buffer
+	fullManifest, err := Create(ctx, source, &full, Options{})
+	if err != nil {
+		t.Fatalf("Failed to create full backup: %v", err)
+	}
+	if fullManifest.Kind != KindFull || fullManifest.Position != source.Position() {
+		t.Errorf("Unexpected full manifest: %+v", fullManifest)
+	}
+
+	if err := source.Create(core.NewCollection("notes", "", 3, "ivf")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := source.Insert(ctx, "notes", testVectors("note", 4, 3)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	beforeDelete := time.Now()
+	if err := source.DeleteVectors(ctx, "docs", []string{"doc_0", "doc_1"}); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+
+	var incremental bytes.Buffer
+	incrementalManifest, err := Create(ctx, source, &incremental, Options{Incremental: true, Since: fullManifest.Position})
+	if err != nil {
+		t.Fatalf("Failed to create incremental backup: %v", err)
+	}
+	if incrementalManifest.BasePosition != fullManifest.Position || incrementalManifest.Position != source.Position() {
+		t.Errorf("Unexpected incremental manifest: %+v", incrementalManifest)
+	}
+
+	target := newTestCatalog(t)
+	manifests, err := Restore(ctx, target, bytes.NewReader(full.Bytes()), bytes.NewReader(incremental.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if len(manifests) != 2 {
+		t.Fatalf("Expected 2 restored manifests, got %d", len(manifests))
+	}
+
+	docs, err := target.Get("docs")
+	if err != nil {
+		t.Fatalf("Failed to get restored collection: %v", err)
+	}
+	if docs.Count != 8 || docs.Description != "documents" {
+		t.Errorf("Unexpected restored collection: %+v", docs)
+	}
+	notes, err := target.Get("notes")
+	if err != nil {
+		t.Fatalf("Failed to get restored collection: %v", err)
+	}
+	if notes.Count != 4 || notes.IndexType != "ivf" {
+		t.Errorf("Unexpected restored collection: %+v", notes)
+	}
+
+	if target.Position() != incrementalManifest.Position {
+		t.Errorf("Expected restored position %d, got %d", incrementalManifest.Position, target.Position())
+	}
+
+	// Deleted vectors keep their history as it was
+	restored, err := target.Storage("docs")
+	if err != nil {
+		t.Fatalf("Failed to get restored storage: %v", err)
+	}
+	past, err := restored.ReadAsOf([]string{"doc_0", "doc_2"}, beforeDelete)
+	if err != nil {
+		t.Fatalf("Failed to read restored history: %v", err)
+	}
+	if len(past) != 2 {
+		t.Errorf("Expected 2 vectors as of before the delete, got %d", len(past))
+	}
+	current, err := restored.Read([]string{"doc_0", "doc_2"})
+	if err != nil {
+		t.Fatalf("Failed to read restored vectors: %v", err)
+	}
+	if len(current) != 1 || current[0].ID != "doc_2" || current[0].Version != 1 {
+		t.Errorf("Unexpected restored vectors: %+v", current)
+	}
+
+	// The restored index must answer queries without further work.
+	// All test vectors point the same way, so any document is a best match.
+	results, err := target.Search(ctx, "docs", testVectors("query", 3, 4)[2].Embedding, 1)
+	if err != nil {
+		t.Fatalf("Failed to search restored collection: %v", err)
+	}
+	if len(results) != 1 || !strings.HasPrefix(results[0].Vector.ID, "doc_") {
+		t.Errorf("Unexpected search results: %+v", results)
+	}
+}
+
+func TestBackup_Encrypted(t *testing.T) {
+	ctx := context.Background()
+	keyConfig := security.DefaultEncryptionConfig()
+	keyConfig.KeyStorePath = filepath.Join(t.TempDir(), "keys.json")
+	keyConfig.MasterKey = bytes.Repeat([]byte{7}, 32)
+	service, err := security.OpenEncryptionService(keyConfig)
+	if err != nil {
+		t.Fatalf("Failed to open encryption service: %v", err)
+	}
+	t.Cleanup(func() { _ = service.Close() })
+	newEncryptedCatalog := func() *catalog.Catalog {
+		config := catalog.DefaultConfig(t.TempDir())
+		config.Encryption = service
+		cat, err := catalog.New(config)
+		if err != nil {
+			t.Fatalf("Failed to open catalog: %v", err)
+		}
+		t.Cleanup(func() { _ = cat.Close() })
+		return cat
+	}
+
+	source := newEncryptedCatalog()
+	if err := source.Create(core.NewCollection("docs", "confidential documents", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	vectors := testVectors("doc", 5, 4)
+	for _, vector := range vectors {
+		vector.Text = "confidential text"
+	}
+	if err := source.Insert(ctx, "docs", vectors[:3]); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	var full bytes.Buffer
+	fullManifest, err := Create(ctx, source, &full, Options{})
+	if err != nil {
+		t.Fatalf("Failed to create full backup: %v", err)
+	}
+	if err := source.Insert(ctx, "docs", vectors[3:]); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	var incremental bytes.Buffer
+	if _, err := Create(ctx, source, &incremental, Options{Incremental: true, Since: fullManifest.Position}); err != nil {
+		t.Fatalf("Failed to create incremental backup: %v", err)
+	}
+
+	// Nothing but the manifest is readable without the keys
+	for _, archive := range [][]byte{full.Bytes(), incremental.Bytes()} {
+		rewriteArchive(t, archive, func(name string, data []byte) []byte {
+			if name == manifestName {
+				if !strings.Contains(string(data), `"sealed": true`) {
+					t.Errorf("Expected a sealed manifest, got %s", data)
+				}
+			} else if strings.Contains(string(data), "confidential") || strings.Contains(string(data), "doc_") {
+				t.Errorf("Expected %s to be encrypted, got %s", name, data)
+			}
+			return data
+		})
+	}
+
+	if _, err := Restore(ctx, newTestCatalog(t), bytes.NewReader(full.Bytes())); !errors.Is(err, ErrSealedArchive) {
+		t.Errorf("Expected ErrSealedArchive restoring into an unencrypted catalog, got %v", err)
+	}
+
+	target := newEncryptedCatalog()
+	if _, err := Restore(ctx, target, bytes.NewReader(full.Bytes()), bytes.NewReader(incremental.Bytes())); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	docs, err := target.Get("docs")
+	if err != nil {
+		t.Fatalf("Failed to get restored collection: %v", err)
+	}
+	if docs.Count != 5 || docs.Description != "confidential documents" {
+		t.Errorf("Unexpected restored collection: %+v", docs)
+	}
+	restored, err := target.Storage("docs")
+	if err != nil {
+		t.Fatalf("Failed to get restored storage: %v", err)
+	}
+	read, err := restored.Read([]string{"doc_0", "doc_4"})
+	if err != nil {
+		t.Fatalf("Failed to read restored vectors: %v", err)
+	}
+	if len(read) != 2 || read[1].Text != "confidential text" {
+		t.Errorf("Unexpected restored vectors: %+v", read)
+	}
+}
+
+func TestBackup_RestoreValidation(t *testing.T) {
+	ctx := context.Background()
+	source := newTestCatalog(t)
+
+	if err := source.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := source.Insert(ctx, "docs", testVectors("doc", 3, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	var full bytes.Buffer
+	if _, err := Create(ctx, source, &full, Options{}); err != nil {
+		t.Fatalf("Failed to create full backup: %v", err)
+	}
+	var incremental bytes.Buffer
+	if _, err := Create(ctx, source, &incremental, Options{Incremental: true, Since: 1}); err != nil {
+		t.Fatalf("Failed to create incremental backup: %v", err)
+	}
+
+	if _, err := Restore(ctx, newTestCatalog(t), bytes.NewReader(incremental.Bytes())); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected ErrBrokenChain for a leading incremental backup, got %v", err)
+	}
+	if _, err := Restore(ctx, newTestCatalog(t), bytes.NewReader(full.Bytes()), bytes.NewReader(incremental.Bytes())); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected ErrBrokenChain for a gap in the chain, got %v", err)
+	}
+	if _, err := Restore(ctx, source, bytes.NewReader(full.Bytes())); !errors.Is(err, ErrCatalogNotEmpty) {
+		t.Errorf("Expected ErrCatalogNotEmpty, got %v", err)
+	}
+
+	tampered := rewriteArchive(t, full.Bytes(), func(name string, data []byte) []byte {
+		if name == "vectors/docs.jsonl" {
+			return bytes.Replace(data, []byte("doc_1"), []byte("doc_9"), 1)
+		}
+		return data
+	})
+	if _, err := Verify(bytes.NewReader(tampered)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+
+	if _, err := Verify(bytes.NewReader([]byte("not an archive"))); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("Expected ErrInvalidArchive, got %v", err)
+	}
+}
+
+func TestBackup_IncrementalInvalidPosition(t *testing.T) {
+	source := newTestCatalog(t)
+
+	var buf bytes.Buffer
+	_, err := Create(context.Background(), source, &buf, Options{Incremental: true, Since: 5})
+	if !errors.Is(err, catalog.ErrInvalidPosition) {
+		t.Errorf("Expected ErrInvalidPosition, got %v", err)
+	}
+}
+
+func TestBackup_ObjectStore(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryObjectStore()
+	source := newTestCatalog(t)
+
+	if err := source.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := source.Insert(ctx, "docs", testVectors("doc", 5, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	full, err := Upload(ctx, source, store, "backups/full.tar.gz", Options{})
+	if err != nil {
+		t.Fatalf("Failed to upload full backup: %v", err)
+	}
+	if err := source.DeleteVectors(ctx, "docs", []string{"doc_0"}); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if _, err := Upload(ctx, source, store, "backups/incremental.tar.gz", Options{Incremental: true, Since: full.Position}); err != nil {
+		t.Fatalf("Failed to upload incremental backup: %v", err)
+	}
+
+	// A backup that fails is not stored
+	if _, err := Upload(ctx, source, store, "backups/broken.tar.gz", Options{Incremental: true, Since: 99}); !errors.Is(err, catalog.ErrInvalidPosition) {
+		t.Errorf("Expected ErrInvalidPosition, got %v", err)
+	}
+	if _, err := store.Stat(ctx, "backups/broken.tar.gz"); !errors.Is(err, storage.ErrObjectNotFound) {
+		t.Errorf("Expected no archive for the failed backup, got %v", err)
+	}
+
+	target := newTestCatalog(t)
+	if _, err := RestoreObjects(ctx, target, store, "backups/missing.tar.gz"); !errors.Is(err, storage.ErrObjectNotFound) {
+		t.Errorf("Expected ErrObjectNotFound, got %v", err)
+	}
+	manifests, err := RestoreObjects(ctx, target, store, "backups/full.tar.gz", "backups/incremental.tar.gz")
+	if err != nil {
+		t.Fatalf("Failed to restore backups: %v", err)
+	}
+	if len(manifests) != 2 || target.Position() != source.Position() {
+		t.Errorf("Expected the restore to reach position %d, got %d", source.Position(), target.Position())
+	}
+	collection, err := target.Get("docs")
+	if err != nil || collection.Count != 4 {
+		t.Errorf("Expected 4 restored vectors, got %+v (%v)", collection, err)
+	}
+}
+
+// rewriteArchive copies an archive, passing every entry through modify
+func rewriteArchive(t *testing.T, archive []byte, modify func(name string, data []byte) []byte) []byte {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var out bytes.Buffer
+	gzOut := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzOut)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("Failed to read entry: %v", err)
+		}
+
+		data = modify(header.Name, data)
+		header.Size = int64(len(data))
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	if err := gzOut.Close(); err != nil {
+		t.Fatalf("Failed to close compression: %v", err)
+	}
+
+	return out.Bytes()
+}
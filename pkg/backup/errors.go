@@ -0,0 +1,13 @@
+package backup
+
+import "errors"
+
+// Backup-related errors
+var (
+	ErrInvalidArchive    = errors.New("invalid backup archive")
+	ErrChecksumMismatch  = errors.New("backup checksum mismatch")
+	ErrUnsupportedFormat = errors.New("unsupported backup format version")
+	ErrCatalogNotEmpty   = errors.New("full restore requires an empty catalog")
+	ErrBrokenChain       = errors.New("incremental backup does not follow the previous backup")
+	ErrSealedArchive     = errors.New("backup archive is encrypted and the catalog has no encryption service")
+)
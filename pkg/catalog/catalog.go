@@ -0,0 +1,1125 @@
+// Package catalog provides the collection catalog for the VJVector database.
+// It persists collection definitions, owns the storage and index of every collection
+// and records every applied mutation in a change log.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/security"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+const (
+	catalogFileName    = "catalog.json"
+	catalogFileVersion = 1
+	collectionsDirName = "collections"
+	mmapFileName       = "vectors.mmap"
+)
+
+// collectionNamePattern restricts names to values that are safe as a directory
+// name. A name may be qualified by a namespace, such as a tenant ID, and a
+// '~', which the rest of the name cannot contain.
+var collectionNamePattern = regexp.MustCompile(`^(?:[A-Za-z0-9][A-Za-z0-9_-]{0,63}~)?[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+// Config holds configuration parameters for the collection catalog
+type Config struct {
+	// DataPath is the directory holding the catalog file and the collection namespaces
+	DataPath string `json:"data_path"`
+
+	// Storage is the template for each collection's storage engine.
+	// Its DataPath is replaced with the namespace of the collection.
+	Storage storage.StorageConfig `json:"storage"`
+
+	// Encryption, when set, encrypts the vectors of every collection at rest with
+	// keys of the encryption policy named EncryptionScope, such as a tenant ID,
+	// or "collection:<name>" per collection when EncryptionScope is empty, and
+	// the records of the change log with keys of EncryptionScope, or of
+	// "catalog" when it is empty. The service holds the key material and must
+	// outlive the data it encrypted, as security.OpenEncryptionService does.
+	Encryption      security.EncryptionService `json:"-"`
+	EncryptionScope string                     `json:"encryption_scope,omitempty"`
+
+	// CheckpointSize is how many bytes the change log grows by before a
+	// checkpoint removes the records that are no longer needed; zero leaves
+	// checkpoints to Checkpoint
+	CheckpointSize int64 `json:"checkpoint_size,omitempty"`
+}
+
+// DefaultConfig returns a catalog configuration backed by LevelDB storage
+func DefaultConfig(dataPath string) Config {
+	return Config{
+		DataPath: dataPath,
+		Storage: storage.StorageConfig{
+			Type:            storage.StorageTypeLevelDB,
+			PageSize:        4096,
+			MaxFileSize:     1024 * 1024 * 1024, // 1GB
+			BatchSize:       100,
+			WriteBufferSize: 64 * 1024 * 1024, // 64MB
+			CacheSize:       32 * 1024 * 1024, // 32MB
+			MaxOpenFiles:    1000,
+
+			CompactionThreshold: 30,
+
+			MaxVersions:             5,
+			VersionRetentionSeconds: 7 * 24 * 60 * 60, // 7 days
+		},
+		CheckpointSize: 64 * 1024 * 1024, // 64MB
+	}
+}
+
+// DefaultIndexConfig returns the index configuration used when a collection
+// is created without an explicit one
+func DefaultIndexConfig(indexType index.IndexType, dimension int, distanceMetric string) index.IndexConfig {
+	if indexType == "" {
+		indexType = index.IndexTypeHNSW
+	}
+	if distanceMetric == "" {
+		distanceMetric = "cosine"
+	}
+
+	return index.IndexConfig{
+		Type:           indexType,
+		Dimension:      dimension,
+		MaxElements:    100000,
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       100,
+		MaxLayers:      16,
+		NumClusters:    50,
+		ClusterSize:    20,
+		DistanceMetric: distanceMetric,
+		Normalize:      true,
+	}
+}
+
+// Spec is the persisted definition of a collection
+type Spec struct {
+	Collection *core.Collection  `json:"collection"`
+	Index      index.IndexConfig `json:"index"`
+}
+
+// catalogFile is the on-disk representation of the catalog
+type catalogFile struct {
+	Version     int    `json:"version"`
+	Collections []Spec `json:"collections"`
+
+	// LogBase is the change log position of the last checkpoint, and
+	// BackupPosition the position of the last backup
+	LogBase        uint64 `json:"log_base,omitempty"`
+	BackupPosition uint64 `json:"backup_position,omitempty"`
+}
+
+// entry is an open collection together with the engines it owns
+type entry struct {
+	spec    Spec
+	storage storage.StorageEngine
+	index   index.VectorIndex
+
+	// Scans of the storage that run without the catalog mutex hold a
+	// reference; closing the entry cancels done and waits for them
+	scans  sync.WaitGroup
+	done   context.Context
+	cancel context.CancelFunc
+}
+
+// acquire lets a scan of the entry's storage run without the catalog mutex,
+// which the caller must hold. The returned context is cancelled once the
+// entry is being closed, which waits until the scan calls release.
+func (e *entry) acquire(ctx context.Context) (context.Context, func()) {
+	e.scans.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(e.done, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		e.scans.Done()
+	}
+}
+
+// Catalog keeps track of collections and owns their storage and index.
+// It implements core.CollectionRepository.
+type Catalog struct {
+	config         Config
+	storageFactory storage.StorageFactory
+	indexFactory   index.IndexFactory
+	entries        map[string]*entry
+	log            *changeLog
+	mutex          sync.RWMutex
+	closed         bool
+
+	// backupPosition is the log position of the last backup; checkpoints keep
+	// the records after it, and after every pinned position, for their readers
+	backupPosition uint64
+	pins           map[uint64]int
+	pinMutex       sync.Mutex
+
+	// Expiry counters, reported by ExpiryStats
+	reaped          atomic.Uint64
+	expiredFiltered atomic.Uint64
+}
+
+var _ core.CollectionRepository = (*Catalog)(nil)
+
+// New opens the catalog stored under config.DataPath, creating it if needed.
+// Every known collection has its storage reopened and an index created,
+// and the change log is reopened at its last position.
+func New(config Config) (*Catalog, error) {
+	if config.DataPath == "" {
+		return nil, ErrInvalidDataPath
+	}
+
+	if err := os.MkdirAll(config.DataPath, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+
+	c := &Catalog{
+		config:         config,
+		storageFactory: storage.NewStorageFactory(),
+		indexFactory:   index.NewIndexFactory(),
+		entries:        make(map[string]*entry),
+		pins:           make(map[uint64]int),
+	}
+
+	base, err := c.load()
+	if err != nil {
+		if closeErr := c.closeEntries(); closeErr != nil {
+			return nil, fmt.Errorf("failed to load catalog and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, err
+	}
+
+	var sealer *storage.Sealer
+	if config.Encryption != nil {
+		scope := config.EncryptionScope
+		if scope == "" {
+			scope = "catalog"
+		}
+		sealer, err = storage.NewSealer(config.Encryption, scope)
+	}
+	var log *changeLog
+	if err == nil {
+		log, err = openChangeLog(filepath.Join(config.DataPath, logFileName), base, config.Storage.SyncOnWrite, sealer)
+	}
+	if err != nil {
+		if closeErr := c.closeEntries(); closeErr != nil {
+			return nil, fmt.Errorf("failed to open change log and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, err
+	}
+	c.log = log
+
+	return c, nil
+}
+
+// Create registers a new collection using the default index configuration
+func (c *Catalog) Create(collection *core.Collection) error {
+	if collection == nil {
+		return ErrInvalidCollectionName
+	}
+	config := DefaultIndexConfig(index.IndexType(collection.IndexType), collection.Dimension, collection.DistanceMetric)
+	return c.CreateWithIndex(collection, config)
+}
+
+// CreateWithIndex registers a new collection with an explicit index configuration
+func (c *Catalog) CreateWithIndex(collection *core.Collection, config index.IndexConfig) error {
+	if collection == nil || !collectionNamePattern.MatchString(collection.Name) {
+		return ErrInvalidCollectionName
+	}
+	if collection.Dimension <= 0 {
+		return ErrInvalidDimension
+	}
+
+	// The collection definition is authoritative for the dimension
+	if config.Dimension == 0 {
+		config.Dimension = collection.Dimension
+	}
+	if config.Dimension != collection.Dimension {
+		return fmt.Errorf("%w: index dimension %d, collection dimension %d", ErrDimensionMismatch, config.Dimension, collection.Dimension)
+	}
+	if config.Type == "" {
+		config.Type = index.IndexType(collection.IndexType)
+	}
+	if config.Type == "" {
+		config.Type = index.IndexTypeHNSW
+	}
+	if config.DistanceMetric == "" {
+		config.DistanceMetric = collection.DistanceMetric
+	}
+	if config.DistanceMetric == "" {
+		config.DistanceMetric = "cosine"
+	}
+	if err := c.indexFactory.ValidateConfig(config); err != nil {
+		return fmt.Errorf("invalid index config: %w", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrCatalogClosed
+	}
+	if _, exists := c.entries[collection.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrCollectionExists, collection.Name)
+	}
+
+	now := time.Now()
+	stored := copyCollection(collection)
+	stored.IndexType = string(config.Type)
+	stored.DistanceMetric = config.DistanceMetric
+	stored.Count = 0
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = now
+	}
+	stored.UpdatedAt = now
+	if stored.Metadata == nil {
+		stored.Metadata = make(map[string]interface{})
+	}
+
+	spec := Spec{Collection: stored, Index: config}
+	e, err := c.openEntry(spec)
+	if err != nil {
+		return err
+	}
+
+	if err := c.log.append(&Record{Op: OpCreateCollection, Collection: stored.Name, Spec: &spec}); err != nil {
+		if closeErr := closeEntry(e); closeErr != nil {
+			return fmt.Errorf("failed to log collection and close: %w, close error: %v", err, closeErr)
+		}
+		return err
+	}
+	c.entries[stored.Name] = e
+
+	if err := c.save(); err != nil {
+		delete(c.entries, stored.Name)
+		if closeErr := closeEntry(e); closeErr != nil {
+			return fmt.Errorf("failed to save catalog and close collection: %w, close error: %v", err, closeErr)
+		}
+		return err
+	}
+
+	*collection = *copyCollection(stored)
+	return nil
+}
+
+// Get returns a copy of the named collection
+func (c *Catalog) Get(name string) (*core.Collection, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyCollection(e.spec.Collection), nil
+}
+
+// Update changes the description and metadata of a collection.
+// Dimension, index type and distance metric are fixed at creation time.
+func (c *Catalog) Update(collection *core.Collection) error {
+	if collection == nil {
+		return ErrInvalidCollectionName
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, err := c.lookup(collection.Name)
+	if err != nil {
+		return err
+	}
+
+	current := e.spec.Collection
+	if collection.Dimension != 0 && collection.Dimension != current.Dimension {
+		return fmt.Errorf("%w: dimension", ErrImmutableField)
+	}
+	if collection.IndexType != "" && collection.IndexType != current.IndexType {
+		return fmt.Errorf("%w: index_type", ErrImmutableField)
+	}
+	if collection.DistanceMetric != "" && collection.DistanceMetric != current.DistanceMetric {
+		return fmt.Errorf("%w: distance_metric", ErrImmutableField)
+	}
+
+	previous := copyCollection(current)
+	current.Description = collection.Description
+	if collection.Metadata != nil {
+		current.Metadata = copyMetadata(collection.Metadata)
+	}
+	current.UpdatedAt = time.Now()
+
+	logged := Spec{Collection: copyCollection(current), Index: e.spec.Index}
+	if err := c.log.append(&Record{Op: OpUpdateCollection, Collection: current.Name, Spec: &logged}); err != nil {
+		e.spec.Collection = previous
+		return err
+	}
+
+	if err := c.save(); err != nil {
+		e.spec.Collection = previous
+		return err
+	}
+
+	*collection = *copyCollection(current)
+	return nil
+}
+
+// Delete removes a collection together with its index and stored vectors
+func (c *Catalog) Delete(name string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if err := c.log.append(&Record{Op: OpDeleteCollection, Collection: name}); err != nil {
+		return err
+	}
+
+	delete(c.entries, name)
+	if err := c.save(); err != nil {
+		c.entries[name] = e
+		return err
+	}
+
+	if err := closeEntry(e); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(c.namespacePath(name)); err != nil {
+		return fmt.Errorf("failed to remove collection data: %w", err)
+	}
+	if err := c.removeObjects(name); err != nil {
+		return fmt.Errorf("failed to remove offloaded collection data: %w", err)
+	}
+
+	return nil
+}
+
+// removeObjects deletes the segments a collection offloaded to the object store
+func (c *Catalog) removeObjects(name string) error {
+	objects := c.storageConfig(name).ObjectStore
+	if objects == nil || c.config.Storage.Type != storage.StorageTypeLSM {
+		return nil
+	}
+	store, err := storage.OpenObjectStore(*objects)
+	if err != nil {
+		return err
+	}
+	return storage.RemoveObjects(context.Background(), store, "")
+}
+
+// List returns copies of all collections ordered by name
+func (c *Catalog) List() ([]*core.Collection, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.closed {
+		return nil, ErrCatalogClosed
+	}
+
+	collections := make([]*core.Collection, 0, len(c.entries))
+	for _, e := range c.entries {
+		collections = append(collections, copyCollection(e.spec.Collection))
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return collections[i].Name < collections[j].Name
+	})
+
+	return collections, nil
+}
+
+// Spec returns the persisted definition of the named collection
+func (c *Catalog) Spec(name string) (Spec, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return Spec{}, err
+	}
+
+	return Spec{Collection: copyCollection(e.spec.Collection), Index: e.spec.Index}, nil
+}
+
+// Index returns the vector index owned by the named collection
+func (c *Catalog) Index(name string) (index.VectorIndex, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.index, nil
+}
+
+// Storage returns the storage engine owned by the named collection
+func (c *Catalog) Storage(name string) (storage.StorageEngine, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.storage, nil
+}
+
+// Insert writes vectors to the collection's storage and index.
+// Every vector must match the collection dimension; existing IDs are replaced.
+// The vectors are written atomically, as by Write.
+func (c *Catalog) Insert(ctx context.Context, name string, vectors []*core.Vector) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	writes := make([]Write, len(vectors))
+	for i, vector := range vectors {
+		writes[i] = Write{Kind: WritePut, Vector: vector}
+	}
+
+	_, err := c.Write(ctx, name, writes)
+	return err
+}
+
+// DeleteVectors removes vectors from the collection's storage and index.
+// The vectors are deleted atomically, as by Write.
+func (c *Catalog) DeleteVectors(ctx context.Context, name string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	writes := make([]Write, len(ids))
+	for i, id := range ids {
+		writes[i] = Write{Kind: WriteDelete, ID: id}
+	}
+
+	_, err := c.Write(ctx, name, writes)
+	return err
+}
+
+// Search finds the k most similar vectors in the named collection.
+// Vectors that have expired but are not reaped yet are left out of the results,
+// as are those the index still holds after they were deleted or replaced in
+// storage.
+func (c *Catalog) Search(ctx context.Context, name string, query []float64, k int) ([]core.VectorSearchResult, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(query) != e.spec.Collection.Dimension {
+		return nil, fmt.Errorf("%w: query has dimension %d, collection %s expects %d",
+			ErrDimensionMismatch, len(query), name, e.spec.Collection.Dimension)
+	}
+
+	now := time.Now()
+	fetch := k
+	for {
+		results, err := e.index.SearchWithContext(ctx, query, fetch)
+		if errors.Is(err, index.ErrIndexNotInitialized) {
+			// An empty collection has no entry point yet
+			return []core.VectorSearchResult{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := currentVersions(ctx, e, results)
+		if err != nil {
+			return nil, err
+		}
+
+		live := make([]core.VectorSearchResult, 0, len(results))
+		expired := 0
+		for _, result := range results {
+			if result.Vector == nil {
+				live = append(live, result)
+				continue
+			}
+			if version, stored := current[result.Vector.ID]; !stored || version != storedVersion(result.Vector) {
+				continue
+			}
+			if result.Vector.Expired(now) {
+				expired++
+				continue
+			}
+			live = append(live, result)
+		}
+
+		// Fetch more candidates when dropped vectors pushed live ones out of the top k
+		if len(live) < len(results) && len(live) < k && len(results) == fetch && int64(fetch) < e.spec.Collection.Count {
+			fetch *= 2
+			continue
+		}
+
+		c.expiredFiltered.Add(uint64(expired))
+		if len(live) > k {
+			live = live[:k]
+		}
+		return live, nil
+	}
+}
+
+// currentVersions returns the stored versions of the vectors of search
+// results, leaving out those no longer stored
+func currentVersions(ctx context.Context, e *entry, results []core.VectorSearchResult) (map[string]uint64, error) {
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Vector != nil {
+			ids = append(ids, result.Vector.ID)
+		}
+	}
+	stored, err := e.storage.ReadWithContext(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection %s: %w", e.spec.Collection.Name, err)
+	}
+	versions := make(map[string]uint64, len(stored))
+	for _, vector := range stored {
+		versions[vector.ID] = storedVersion(vector)
+	}
+	return versions, nil
+}
+
+// Close persists the catalog and closes every collection
+func (c *Catalog) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	saveErr := c.save()
+	closeErr := c.closeEntries()
+	logErr := c.log.close()
+	c.closed = true
+
+	if saveErr != nil {
+		return saveErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return logErr
+}
+
+// Sealer returns the sealer that encrypts the change log records of an
+// encrypted catalog, which backups encrypt their contents with, or nil
+func (c *Catalog) Sealer() (*storage.Sealer, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.closed {
+		return nil, ErrCatalogClosed
+	}
+	return c.log.sealer, nil
+}
+
+// Position returns the position of the last record in the change log
+func (c *Catalog) Position() uint64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.log.position
+}
+
+// LogBase returns the change log position of the last checkpoint; the log
+// holds the records after it
+func (c *Catalog) LogBase() uint64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.log.base
+}
+
+// ReadLog calls fn for every change log record after the given position,
+// up to the position current when ReadLog was called, which it returns. It
+// fails with ErrLogTruncated when a checkpoint removed the records after the
+// given position.
+func (c *Catalog) ReadLog(ctx context.Context, after uint64, fn func(*Record) error) (uint64, error) {
+	c.mutex.RLock()
+	if c.closed {
+		c.mutex.RUnlock()
+		return 0, ErrCatalogClosed
+	}
+	until := c.log.position
+	log := c.log
+	c.mutex.RUnlock()
+
+	if after > until {
+		return 0, fmt.Errorf("%w: %d is beyond the last position %d", ErrInvalidPosition, after, until)
+	}
+
+	if err := log.read(ctx, after, until, fn); err != nil {
+		return 0, err
+	}
+
+	return until, nil
+}
+
+// Snapshot is a view of the catalog at a change log position. Its
+// collections are scanned while the catalog keeps taking writes, so scans may
+// see writes made after Position; ReadLog returns the records of those writes.
+type Snapshot struct {
+	// Position is the change log position the snapshot was taken at
+	Position uint64
+
+	// Specs are the definitions of every collection, ordered by name
+	Specs []Spec
+
+	catalog *Catalog
+	entries map[string]*entry
+	ctx     context.Context
+}
+
+// Scan calls fn for every vector stored in the named collection.
+// fn must not call back into the catalog.
+func (s *Snapshot) Scan(name string, fn func(*core.Vector) error) error {
+	return s.scan(name, func(ctx context.Context, e *entry) error {
+		scanner, ok := e.storage.(storage.Scanner)
+		if !ok {
+			return fmt.Errorf("collection %s: %w", name, storage.ErrScanNotSupported)
+		}
+		return scanner.Scan(ctx, fn)
+	})
+}
+
+// ScanVersions calls fn with the versions of every vector stored or deleted in
+// the named collection, as storage.VersionScanner describes, or with just the
+// stored vector when the storage keeps no version history.
+// fn must not call back into the catalog.
+func (s *Snapshot) ScanVersions(name string, fn func([]*core.Vector) error) error {
+	return s.scan(name, func(ctx context.Context, e *entry) error {
+		if scanner, ok := e.storage.(storage.VersionScanner); ok {
+			return scanner.ScanVersions(ctx, fn)
+		}
+		scanner, ok := e.storage.(storage.Scanner)
+		if !ok {
+			return fmt.Errorf("collection %s: %w", name, storage.ErrScanNotSupported)
+		}
+		return scanner.Scan(ctx, func(vector *core.Vector) error {
+			return fn([]*core.Vector{vector})
+		})
+	})
+}
+
+// scan runs a scan of the storage of the named collection without the catalog
+// mutex. It fails with ErrCollectionNotFound when the collection was deleted
+// since the snapshot was taken or while it is scanned.
+func (s *Snapshot) scan(name string, run func(context.Context, *entry) error) error {
+	c := s.catalog
+	c.mutex.RLock()
+	if c.closed {
+		c.mutex.RUnlock()
+		return ErrCatalogClosed
+	}
+	e, exists := s.entries[name]
+	if !exists || c.entries[name] != e {
+		c.mutex.RUnlock()
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	ctx, release := e.acquire(s.ctx)
+	c.mutex.RUnlock()
+	defer release()
+
+	err := run(ctx, e)
+	if err != nil && ctx.Err() != nil && s.ctx.Err() == nil {
+		return fmt.Errorf("%w: %s was deleted or closed during the scan", ErrCollectionNotFound, name)
+	}
+	return err
+}
+
+// ReadLog calls fn for every change log record after Position, up to the
+// position current when ReadLog was called, which it returns. Applied to the
+// vectors scanned before the call, the records give the state of the catalog
+// at that position.
+func (s *Snapshot) ReadLog(fn func(*Record) error) (uint64, error) {
+	return s.catalog.ReadLog(s.ctx, s.Position, fn)
+}
+
+// Snapshot calls fn with a view of the catalog at the current change log
+// position. Writes go on while fn runs, and checkpoints keep the log records
+// after the position until fn returns.
+func (c *Catalog) Snapshot(ctx context.Context, fn func(*Snapshot) error) error {
+	c.mutex.RLock()
+	if c.closed {
+		c.mutex.RUnlock()
+		return ErrCatalogClosed
+	}
+
+	snapshot := &Snapshot{
+		Position: c.log.position,
+		Specs:    make([]Spec, 0, len(c.entries)),
+		catalog:  c,
+		entries:  make(map[string]*entry, len(c.entries)),
+		ctx:      ctx,
+	}
+	for name, e := range c.entries {
+		snapshot.Specs = append(snapshot.Specs, Spec{Collection: copyCollection(e.spec.Collection), Index: e.spec.Index})
+		snapshot.entries[name] = e
+	}
+	sort.Slice(snapshot.Specs, func(i, j int) bool {
+		return snapshot.Specs[i].Collection.Name < snapshot.Specs[j].Collection.Name
+	})
+	unpin := c.pin(snapshot.Position)
+	c.mutex.RUnlock()
+	defer unpin()
+
+	return fn(snapshot)
+}
+
+// Checkpoint removes the change log records that are no longer needed, as
+// the catalog does by itself whenever the log grew by Config.CheckpointSize.
+// The records after the position recorded by RecordBackup are kept for the
+// next incremental backup; without a backup, every record is removed. Reading
+// changes from before a checkpoint fails with ErrLogTruncated.
+func (c *Catalog) Checkpoint() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrCatalogClosed
+	}
+	return c.checkpoint()
+}
+
+// RecordBackup records that a backup holds the catalog up to a change log
+// position, so that checkpoints keep the records after it for the next
+// incremental backup
+func (c *Catalog) RecordBackup(position uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrCatalogClosed
+	}
+	if position > c.log.position {
+		return fmt.Errorf("%w: %d is beyond the last position %d", ErrInvalidPosition, position, c.log.position)
+	}
+	if position <= c.backupPosition {
+		return nil
+	}
+
+	c.backupPosition = position
+	return c.save()
+}
+
+// checkpoint removes the log records up to the last backup position, or up
+// to the current one without a backup, keeping those after pinned positions;
+// the caller must hold the mutex
+func (c *Catalog) checkpoint() error {
+	through := c.log.position
+	if c.backupPosition > 0 {
+		through = min(through, c.backupPosition)
+	}
+	c.pinMutex.Lock()
+	for position := range c.pins {
+		through = min(through, position)
+	}
+	c.pinMutex.Unlock()
+	if through <= c.log.base {
+		return nil
+	}
+
+	// The catalog file records the new base first, for an empty log to reopen at
+	if err := c.writeFile(through); err != nil {
+		return err
+	}
+	return c.log.checkpoint(through)
+}
+
+// pin keeps checkpoints from removing the log records after position until
+// the returned function is called; the caller must hold the mutex
+func (c *Catalog) pin(position uint64) func() {
+	c.pinMutex.Lock()
+	defer c.pinMutex.Unlock()
+
+	c.pins[position]++
+	return func() {
+		c.pinMutex.Lock()
+		defer c.pinMutex.Unlock()
+
+		c.pins[position]--
+		if c.pins[position] == 0 {
+			delete(c.pins, position)
+		}
+	}
+}
+
+// Apply replays a change log record against the catalog.
+// The replayed mutation is recorded at a new position in this catalog's log.
+func (c *Catalog) Apply(ctx context.Context, record *Record) error {
+	switch record.Op {
+	case OpCreateCollection, OpUpdateCollection:
+		if record.Spec == nil || record.Spec.Collection == nil {
+			return fmt.Errorf("%w: %s record %d has no spec", ErrCorruptLog, record.Op, record.Position)
+		}
+		collection := copyCollection(record.Spec.Collection)
+		if record.Op == OpUpdateCollection {
+			return c.Update(collection)
+		}
+		return c.CreateWithIndex(collection, record.Spec.Index)
+	case OpDeleteCollection:
+		return c.Delete(record.Collection)
+	case OpInsert:
+		return c.Insert(ctx, record.Collection, record.Vectors)
+	case OpDelete:
+		return c.DeleteVectors(ctx, record.Collection, record.IDs)
+	case OpWrite:
+		writes := make([]Write, 0, len(record.Vectors)+len(record.IDs))
+		for _, vector := range record.Vectors {
+			writes = append(writes, Write{Kind: WritePut, Vector: vector})
+		}
+		for _, id := range record.IDs {
+			writes = append(writes, Write{Kind: WriteDelete, ID: id})
+		}
+		_, err := c.Write(ctx, record.Collection, writes)
+		return err
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedOp, record.Op)
+	}
+}
+
+// lookup returns the entry for name; the caller must hold the mutex
+func (c *Catalog) lookup(name string) (*entry, error) {
+	if c.closed {
+		return nil, ErrCatalogClosed
+	}
+
+	e, exists := c.entries[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+
+	return e, nil
+}
+
+// openEntry opens the storage namespace and creates the index of a collection
+func (c *Catalog) openEntry(spec Spec) (*entry, error) {
+	storageConfig := c.storageConfig(spec.Collection.Name)
+
+	if storageConfig.Type != storage.StorageTypeMemory {
+		if err := os.MkdirAll(c.namespacePath(spec.Collection.Name), 0750); err != nil {
+			return nil, fmt.Errorf("failed to create collection directory: %w", err)
+		}
+	}
+
+	storageEngine, err := c.storageFactory.CreateStorage(storageConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage for collection %s: %w", spec.Collection.Name, err)
+	}
+
+	if c.config.Encryption != nil {
+		scope := c.config.EncryptionScope
+		if scope == "" {
+			scope = "collection:" + spec.Collection.Name
+		}
+		encrypted, err := storage.NewEncryptedStorage(storageEngine, c.config.Encryption, scope)
+		if err != nil {
+			_ = storageEngine.Close()
+			return nil, fmt.Errorf("failed to encrypt storage for collection %s: %w", spec.Collection.Name, err)
+		}
+		storageEngine = encrypted
+	}
+
+	vectorIndex, err := c.indexFactory.CreateIndex(spec.Index)
+	if err != nil {
+		if closeErr := storageEngine.Close(); closeErr != nil {
+			return nil, fmt.Errorf("failed to create index for collection %s and close storage: %w, close error: %v",
+				spec.Collection.Name, err, closeErr)
+		}
+		return nil, fmt.Errorf("failed to create index for collection %s: %w", spec.Collection.Name, err)
+	}
+
+	done, cancel := context.WithCancel(context.Background())
+	return &entry{spec: spec, storage: storageEngine, index: vectorIndex, done: done, cancel: cancel}, nil
+}
+
+// namespacePath returns the directory owned by a collection
+func (c *Catalog) namespacePath(name string) string {
+	return filepath.Join(c.config.DataPath, collectionsDirName, name)
+}
+
+// storageConfig returns the storage configuration of a collection, with its
+// data path and object store prefix scoped to the collection
+func (c *Catalog) storageConfig(name string) storage.StorageConfig {
+	config := c.config.Storage
+	config.DataPath = c.storagePath(name)
+	if config.ObjectStore != nil {
+		objects := *config.ObjectStore
+		objects.Prefix = path.Join(objects.Prefix, collectionsDirName, name)
+		config.ObjectStore = &objects
+	}
+	return config
+}
+
+// storagePath returns the storage engine data path for a collection
+func (c *Catalog) storagePath(name string) string {
+	if c.config.Storage.Type == storage.StorageTypeMMap {
+		return filepath.Join(c.namespacePath(name), mmapFileName)
+	}
+	return c.namespacePath(name)
+}
+
+// filePath returns the path of the catalog file
+func (c *Catalog) filePath() string {
+	return filepath.Join(c.config.DataPath, catalogFileName)
+}
+
+// load reads the catalog file and opens every collection listed in it. It
+// returns the change log position of the last checkpoint.
+func (c *Catalog) load() (uint64, error) {
+	data, err := os.ReadFile(c.filePath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read catalog: %w", err)
+	}
+
+	var file catalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, fmt.Errorf("failed to parse catalog: %w", err)
+	}
+	if file.Version > catalogFileVersion {
+		return 0, fmt.Errorf("unsupported catalog version %d", file.Version)
+	}
+
+	for _, spec := range file.Collections {
+		if spec.Collection == nil || !collectionNamePattern.MatchString(spec.Collection.Name) {
+			return 0, fmt.Errorf("%w in catalog file", ErrInvalidCollectionName)
+		}
+
+		e, err := c.openEntry(spec)
+		if err != nil {
+			return 0, err
+		}
+		// Vector writes do not rewrite the catalog file, so the count it
+		// holds may predate the last writes
+		e.spec.Collection.Count = e.storage.GetStats().TotalVectors
+		c.entries[spec.Collection.Name] = e
+	}
+	c.backupPosition = file.BackupPosition
+
+	return file.LogBase, nil
+}
+
+// save atomically writes the catalog file, and checkpoints the change log
+// once it grew by Config.CheckpointSize; the caller must hold the mutex
+func (c *Catalog) save() error {
+	if err := c.writeFile(c.log.base); err != nil {
+		return err
+	}
+	c.checkpointIfDue()
+	return nil
+}
+
+// checkpointIfDue checkpoints the change log once it grew by
+// Config.CheckpointSize; the caller must hold the mutex
+func (c *Catalog) checkpointIfDue() {
+	if c.config.CheckpointSize > 0 && c.log.size-c.log.checkpointed >= c.config.CheckpointSize {
+		if err := c.checkpoint(); err != nil {
+			// The log is left as it was; try again once it grew as much again
+			c.log.checkpointed = c.log.size
+		}
+	}
+}
+
+// writeFile atomically writes the catalog file with the log position of the
+// last checkpoint; the caller must hold the mutex
+func (c *Catalog) writeFile(logBase uint64) error {
+	file := catalogFile{
+		Version:        catalogFileVersion,
+		Collections:    make([]Spec, 0, len(c.entries)),
+		LogBase:        logBase,
+		BackupPosition: c.backupPosition,
+	}
+	for _, e := range c.entries {
+		file.Collections = append(file.Collections, e.spec)
+	}
+	sort.Slice(file.Collections, func(i, j int) bool {
+		return file.Collections[i].Collection.Name < file.Collections[j].Collection.Name
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+
+	tmpPath := c.filePath() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.filePath()); err != nil {
+		return fmt.Errorf("failed to replace catalog: %w", err)
+	}
+
+	return nil
+}
+
+// closeEntries closes every open collection and returns the first error
+func (c *Catalog) closeEntries() error {
+	var firstErr error
+	for _, e := range c.entries {
+		if err := closeEntry(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// closeEntry closes the index and storage owned by a collection, once the
+// scans of its storage running without the catalog mutex have stopped
+func closeEntry(e *entry) error {
+	e.cancel()
+	e.scans.Wait()
+
+	indexErr := e.index.Close()
+	if err := e.storage.Close(); err != nil {
+		return fmt.Errorf("failed to close storage for collection %s: %w", e.spec.Collection.Name, err)
+	}
+	if indexErr != nil {
+		return fmt.Errorf("failed to close index for collection %s: %w", e.spec.Collection.Name, indexErr)
+	}
+	return nil
+}
+
+// copyCollection returns a copy of a collection that does not share its metadata map
+func copyCollection(collection *core.Collection) *core.Collection {
+	copied := *collection
+	copied.Metadata = copyMetadata(collection.Metadata)
+	return &copied
+}
+
+// copyMetadata returns a shallow copy of a metadata map
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,700 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/security"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+func newTestCatalog(t *testing.T, dataPath string) *Catalog {
+	t.Helper()
+
+	config := DefaultConfig(dataPath)
+	cat, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to open catalog: %v", err)
+	}
+	return cat
+}
+
+func testVectors(count, dimension int) []*core.Vector {
+	vectors := make([]*core.Vector, count)
+	for i := 0; i < count; i++ {
+		embedding := make([]float64, dimension)
+		for j := 0; j < dimension; j++ {
+			embedding[j] = float64(i+1) * float64(j+1) * 0.01
+		}
+		vectors[i] = &core.Vector{ID: fmt.Sprintf("vec_%d", i), Embedding: embedding}
+	}
+	return vectors
+}
+
+func TestCatalog_CreateGetList(t *testing.T) {
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "documents", 8, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := cat.Create(core.NewCollection("archive", "", 4, "")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	err := cat.Create(core.NewCollection("docs", "", 8, "hnsw"))
+	if !errors.Is(err, ErrCollectionExists) {
+		t.Errorf("Expected ErrCollectionExists, got %v", err)
+	}
+
+	collection, err := cat.Get("docs")
+	if err != nil {
+		t.Fatalf("Failed to get collection: %v", err)
+	}
+	if collection.Dimension != 8 || collection.DistanceMetric != "cosine" {
+		t.Errorf("Unexpected collection: %+v", collection)
+	}
+
+	collections, err := cat.List()
+	if err != nil {
+		t.Fatalf("Failed to list collections: %v", err)
+	}
+	if len(collections) != 2 || collections[0].Name != "archive" || collections[1].Name != "docs" {
+		t.Errorf("Unexpected collection list: %+v", collections)
+	}
+
+	if _, err := cat.Get("missing"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound, got %v", err)
+	}
+}
+
+func TestCatalog_InvalidCollections(t *testing.T) {
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	for _, name := range []string{"", "../escape", "a/b", ".hidden"} {
+		if err := cat.Create(core.NewCollection(name, "", 4, "hnsw")); !errors.Is(err, ErrInvalidCollectionName) {
+			t.Errorf("Expected ErrInvalidCollectionName for %q, got %v", name, err)
+		}
+	}
+
+	if err := cat.Create(core.NewCollection("zero", "", 0, "hnsw")); !errors.Is(err, ErrInvalidDimension) {
+		t.Errorf("Expected ErrInvalidDimension, got %v", err)
+	}
+}
+
+func TestCatalog_EnforcesDimension(t *testing.T) {
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	ctx := context.Background()
+	err := cat.Insert(ctx, "docs", testVectors(1, 3))
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch on insert, got %v", err)
+	}
+
+	_, err = cat.Search(ctx, "docs", []float64{1, 2}, 1)
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch on search, got %v", err)
+	}
+
+	collection, _ := cat.Get("docs")
+	if collection.Count != 0 {
+		t.Errorf("Expected count 0 after rejected insert, got %d", collection.Count)
+	}
+}
+
+func TestCatalog_InsertSearchDelete(t *testing.T) {
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	ctx := context.Background()
+	results, err := cat.Search(ctx, "docs", []float64{1, 1, 1, 1}, 3)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("Expected no results from empty collection, got %v, %v", results, err)
+	}
+
+	if err := cat.Insert(ctx, "docs", testVectors(5, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	// Re-inserting an existing ID replaces it without changing the count
+	if err := cat.Insert(ctx, "docs", testVectors(1, 4)); err != nil {
+		t.Fatalf("Failed to re-insert vector: %v", err)
+	}
+
+	collection, _ := cat.Get("docs")
+	if collection.Count != 5 {
+		t.Errorf("Expected count 5, got %d", collection.Count)
+	}
+
+	results, err = cat.Search(ctx, "docs", []float64{1, 2, 3, 4}, 3)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) == 0 {
+		t.Error("Expected search results")
+	}
+
+	if err := cat.DeleteVectors(ctx, "docs", []string{"vec_0", "vec_1", "unknown"}); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+
+	collection, _ = cat.Get("docs")
+	if collection.Count != 3 {
+		t.Errorf("Expected count 3 after delete, got %d", collection.Count)
+	}
+}
+
+func TestCatalog_SearchLeavesOutDeletedAndReplacedVectors(t *testing.T) {
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	ctx := context.Background()
+	if err := cat.Insert(ctx, "docs", []*core.Vector{
+		{ID: "a", Embedding: []float64{1, 0, 0, 0}},
+		{ID: "b", Embedding: []float64{0, 1, 0, 0}},
+		{ID: "c", Embedding: []float64{0, 0, 1, 0}},
+	}); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	if err := cat.DeleteVectors(ctx, "docs", []string{"a"}); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if err := cat.Insert(ctx, "docs", []*core.Vector{{ID: "b", Embedding: []float64{0, 0, 0, 1}}}); err != nil {
+		t.Fatalf("Failed to replace vector: %v", err)
+	}
+
+	// An index holding vectors storage no longer has must not return them
+	stale := cat.entries["docs"].index
+	if err := stale.Insert(&core.Vector{ID: "c", Embedding: []float64{0, 0.9, 0, 0}, Version: 7}); err != nil {
+		t.Fatalf("Failed to index stale vector: %v", err)
+	}
+	if err := stale.Insert(&core.Vector{ID: "gone", Embedding: []float64{1, 0.1, 0, 0}}); err != nil {
+		t.Fatalf("Failed to index stale vector: %v", err)
+	}
+
+	for _, query := range [][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}} {
+		results, err := cat.Search(ctx, "docs", query, 10)
+		if err != nil {
+			t.Fatalf("Failed to search: %v", err)
+		}
+		for _, result := range results {
+			switch {
+			case result.Vector.ID == "a" || result.Vector.ID == "gone":
+				t.Errorf("Expected deleted vector %s to be left out", result.Vector.ID)
+			case result.Vector.ID == "b" && result.Vector.Embedding[3] != 1:
+				t.Errorf("Expected b at its new embedding, got %v", result.Vector.Embedding)
+			case result.Vector.ID == "c" && result.Vector.Version != 1:
+				t.Errorf("Expected c at its stored version, got %d", result.Vector.Version)
+			}
+		}
+	}
+}
+
+func TestCatalog_Persistence(t *testing.T) {
+	dataPath := t.TempDir()
+
+	cat := newTestCatalog(t, dataPath)
+	collection := core.NewCollection("docs", "persistent", 4, "hnsw")
+	collection.Metadata["owner"] = "search"
+	if err := cat.Create(collection); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	saved, err := os.ReadFile(filepath.Join(dataPath, catalogFileName))
+	if err != nil {
+		t.Fatalf("Failed to read catalog file: %v", err)
+	}
+	if err := cat.Insert(context.Background(), "docs", testVectors(3, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dataPath, catalogFileName)); !bytes.Equal(data, saved) {
+		t.Error("Expected vector writes to leave the catalog file as it was")
+	}
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+
+	// The count comes from storage, as after a crash that left the catalog
+	// file from before the writes
+	if err := os.WriteFile(filepath.Join(dataPath, catalogFileName), saved, 0600); err != nil {
+		t.Fatalf("Failed to restore catalog file: %v", err)
+	}
+	reopened := newTestCatalog(t, dataPath)
+	defer func() {
+		if err := reopened.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	spec, err := reopened.Spec("docs")
+	if err != nil {
+		t.Fatalf("Failed to get spec after reopen: %v", err)
+	}
+	if spec.Collection.Description != "persistent" || spec.Collection.Count != 3 {
+		t.Errorf("Unexpected collection after reopen: %+v", spec.Collection)
+	}
+	if spec.Collection.Metadata["owner"] != "search" {
+		t.Errorf("Expected metadata to persist, got %v", spec.Collection.Metadata)
+	}
+	if spec.Index.Dimension != 4 || spec.Index.M != 16 {
+		t.Errorf("Unexpected index config after reopen: %+v", spec.Index)
+	}
+
+	stored, err := reopened.Storage("docs")
+	if err != nil {
+		t.Fatalf("Failed to get storage: %v", err)
+	}
+	vectors, err := stored.Read([]string{"vec_0", "vec_1", "vec_2"})
+	if err != nil || len(vectors) != 3 {
+		t.Errorf("Expected 3 stored vectors after reopen, got %d, %v", len(vectors), err)
+	}
+}
+
+func TestCatalog_UpdateAndDelete(t *testing.T) {
+	dataPath := t.TempDir()
+	cat := newTestCatalog(t, dataPath)
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	update := &core.Collection{Name: "docs", Description: "updated"}
+	if err := cat.Update(update); err != nil {
+		t.Fatalf("Failed to update collection: %v", err)
+	}
+	if update.Description != "updated" || update.Dimension != 4 {
+		t.Errorf("Unexpected updated collection: %+v", update)
+	}
+
+	err := cat.Update(&core.Collection{Name: "docs", Dimension: 8})
+	if !errors.Is(err, ErrImmutableField) {
+		t.Errorf("Expected ErrImmutableField, got %v", err)
+	}
+
+	if err := cat.Delete("docs"); err != nil {
+		t.Fatalf("Failed to delete collection: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataPath, collectionsDirName, "docs")); !os.IsNotExist(err) {
+		t.Errorf("Expected collection namespace to be removed, got %v", err)
+	}
+	if err := cat.Delete("docs"); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound, got %v", err)
+	}
+}
+
+func TestCatalog_MemoryStorage(t *testing.T) {
+	config := DefaultConfig(t.TempDir())
+	config.Storage.Type = storage.StorageTypeMemory
+
+	cat, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to open catalog: %v", err)
+	}
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "", 4, "ivf")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := cat.Insert(context.Background(), "docs", testVectors(2, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	collection, _ := cat.Get("docs")
+	if collection.Count != 2 || collection.IndexType != "ivf" {
+		t.Errorf("Unexpected collection: %+v", collection)
+	}
+}
+
+func TestCatalog_ObjectStore(t *testing.T) {
+	objects := storage.NewMemoryObjectStore()
+	config := DefaultConfig(t.TempDir())
+	config.Storage.Type = storage.StorageTypeLSM
+	config.Storage.ObjectStore = &storage.ObjectStoreConfig{Store: objects, Prefix: "cluster-1"}
+
+	cat, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to open catalog: %v", err)
+	}
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	// Each collection offloads its merged segments under its own prefix
+	ctx := context.Background()
+	for _, name := range []string{"docs", "docs2"} {
+		if err := cat.Create(core.NewCollection(name, "", 4, "hnsw")); err != nil {
+			t.Fatalf("Failed to create collection: %v", err)
+		}
+		engine, err := cat.Storage(name)
+		if err != nil {
+			t.Fatalf("Failed to get storage: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := cat.Insert(ctx, name, testVectors(3, 4)); err != nil {
+				t.Fatalf("Failed to insert vectors: %v", err)
+			}
+			if err := engine.Compact(); err != nil {
+				t.Fatalf("Failed to compact storage: %v", err)
+			}
+		}
+	}
+	count := func(prefix string) int {
+		t.Helper()
+		listed, err := objects.List(ctx, prefix)
+		if err != nil {
+			t.Fatalf("Failed to list objects: %v", err)
+		}
+		return len(listed)
+	}
+	if count("cluster-1/collections/docs/") != 1 || count("cluster-1/collections/docs2/") != 1 {
+		t.Errorf("Expected one offloaded segment per collection, got %d", count(""))
+	}
+
+	if err := cat.Delete("docs"); err != nil {
+		t.Fatalf("Failed to delete collection: %v", err)
+	}
+	if count("cluster-1/collections/docs/") != 0 || count("cluster-1/collections/docs2/") != 1 {
+		t.Errorf("Expected only the segments of the deleted collection to be removed, got %d", count(""))
+	}
+}
+
+func TestCatalog_ChangeLog(t *testing.T) {
+	dataPath := t.TempDir()
+	ctx := context.Background()
+
+	cat := newTestCatalog(t, dataPath)
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := cat.Insert(ctx, "docs", testVectors(3, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	if err := cat.DeleteVectors(ctx, "docs", []string{"vec_0"}); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+	if cat.Position() != 3 {
+		t.Errorf("Expected position 3, got %d", cat.Position())
+	}
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+
+	// Simulate a crash in the middle of an append
+	logFile, err := os.OpenFile(filepath.Join(dataPath, logFileName), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	if _, err := logFile.WriteString(`{"position":4,"op":"ins`); err != nil {
+		t.Fatalf("Failed to write torn record: %v", err)
+	}
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("Failed to close log: %v", err)
+	}
+
+	cat = newTestCatalog(t, dataPath)
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+	if cat.Position() != 3 {
+		t.Fatalf("Expected position 3 after reopening, got %d", cat.Position())
+	}
+
+	var ops []Op
+	position, err := cat.ReadLog(ctx, 1, func(record *Record) error {
+		ops = append(ops, record.Op)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if position != 3 || len(ops) != 2 || ops[0] != OpInsert || ops[1] != OpDelete {
+		t.Errorf("Unexpected log records %v up to position %d", ops, position)
+	}
+
+	if _, err := cat.ReadLog(ctx, 10, func(*Record) error { return nil }); !errors.Is(err, ErrInvalidPosition) {
+		t.Errorf("Expected ErrInvalidPosition, got %v", err)
+	}
+
+	if err := cat.Insert(ctx, "docs", testVectors(1, 4)); err != nil {
+		t.Fatalf("Failed to insert after recovery: %v", err)
+	}
+	if cat.Position() != 4 {
+		t.Errorf("Expected position 4, got %d", cat.Position())
+	}
+}
+
+func TestCatalog_Checkpoint(t *testing.T) {
+	dataPath := t.TempDir()
+	ctx := context.Background()
+
+	cat := newTestCatalog(t, dataPath)
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := cat.Insert(ctx, "docs", testVectors(3, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	if err := cat.RecordBackup(1); err != nil {
+		t.Fatalf("Failed to record backup: %v", err)
+	}
+	if err := cat.Insert(ctx, "docs", testVectors(1, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	// The records after the last backup stay for the next incremental one
+	if err := cat.Checkpoint(); err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	if cat.LogBase() != 1 {
+		t.Errorf("Expected log base 1, got %d", cat.LogBase())
+	}
+	if _, err := cat.ReadLog(ctx, 0, func(*Record) error { return nil }); !errors.Is(err, ErrLogTruncated) {
+		t.Errorf("Expected ErrLogTruncated, got %v", err)
+	}
+	count := 0
+	if _, err := cat.ReadLog(ctx, 1, func(*Record) error {
+		count++
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 records after the backup, got %d", count)
+	}
+
+	if err := cat.RecordBackup(3); err != nil {
+		t.Fatalf("Failed to record backup: %v", err)
+	}
+	if err := cat.Checkpoint(); err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+
+	cat = newTestCatalog(t, dataPath)
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+	if cat.Position() != 3 || cat.LogBase() != 3 {
+		t.Fatalf("Expected position and log base 3 after reopening, got %d and %d", cat.Position(), cat.LogBase())
+	}
+	if _, err := cat.Changes(ctx, ChangeOptions{After: 2}, func(*ChangeEvent) error { return nil }); !errors.Is(err, ErrLogTruncated) {
+		t.Errorf("Expected ErrLogTruncated, got %v", err)
+	}
+	if err := cat.Insert(ctx, "docs", testVectors(1, 4)); err != nil {
+		t.Fatalf("Failed to insert after checkpoint: %v", err)
+	}
+	if cat.Position() != 4 {
+		t.Errorf("Expected position 4, got %d", cat.Position())
+	}
+	collection, err := cat.Get("docs")
+	if err != nil {
+		t.Fatalf("Failed to get collection: %v", err)
+	}
+	if collection.Count != 3 {
+		t.Errorf("Expected 3 vectors, got %d", collection.Count)
+	}
+}
+
+func TestCatalog_SnapshotAndApply(t *testing.T) {
+	ctx := context.Background()
+
+	source := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := source.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+	if err := source.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := source.Insert(ctx, "docs", testVectors(5, 4)); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	err := source.Snapshot(ctx, func(snapshot *Snapshot) error {
+		if snapshot.Position != 2 || len(snapshot.Specs) != 1 {
+			t.Errorf("Unexpected snapshot at position %d with %d specs", snapshot.Position, len(snapshot.Specs))
+		}
+		count := 0
+		if err := snapshot.Scan("docs", func(*core.Vector) error {
+			count++
+			return nil
+		}); err != nil {
+			return err
+		}
+		if count != 5 {
+			t.Errorf("Expected 5 scanned vectors, got %d", count)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to take snapshot: %v", err)
+	}
+
+	replica := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := replica.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+	if _, err := source.ReadLog(ctx, 0, func(record *Record) error {
+		return replica.Apply(ctx, record)
+	}); err != nil {
+		t.Fatalf("Failed to replay log: %v", err)
+	}
+
+	collection, err := replica.Get("docs")
+	if err != nil {
+		t.Fatalf("Failed to get replayed collection: %v", err)
+	}
+	if collection.Count != 5 {
+		t.Errorf("Expected 5 replayed vectors, got %d", collection.Count)
+	}
+
+	if err := replica.Apply(ctx, &Record{Op: "truncate"}); !errors.Is(err, ErrUnsupportedOp) {
+		t.Errorf("Expected ErrUnsupportedOp, got %v", err)
+	}
+}
+
+func TestCatalog_Encryption(t *testing.T) {
+	dataPath := t.TempDir()
+	keyConfig := security.DefaultEncryptionConfig()
+	keyConfig.KeyStorePath = filepath.Join(t.TempDir(), "keys.json")
+	keyConfig.MasterKey = bytes.Repeat([]byte{7}, 32)
+
+	// Every open starts from the key store, as after a restart
+	open := func() *Catalog {
+		service, err := security.OpenEncryptionService(keyConfig)
+		if err != nil {
+			t.Fatalf("Failed to open encryption service: %v", err)
+		}
+		t.Cleanup(func() { _ = service.Close() })
+
+		config := DefaultConfig(dataPath)
+		config.Encryption = service
+		cat, err := New(config)
+		if err != nil {
+			t.Fatalf("Failed to open catalog: %v", err)
+		}
+		return cat
+	}
+
+	ctx := context.Background()
+	cat := open()
+	if err := cat.Create(core.NewCollection("records", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	vectors := testVectors(5, 4)
+	if err := cat.Insert(ctx, "records", vectors); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	results, err := cat.Search(ctx, "records", vectors[0].Embedding, 5)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 5 {
+		t.Errorf("Expected 5 results, got %d", len(results))
+	}
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+
+	cat = open()
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	store, err := cat.Storage("records")
+	if err != nil {
+		t.Fatalf("Failed to get storage: %v", err)
+	}
+	if _, ok := store.(*storage.EncryptedStorage); !ok {
+		t.Fatalf("Expected encrypted storage, got %T", store)
+	}
+	stored, err := store.Read([]string{vectors[2].ID})
+	if err != nil {
+		t.Fatalf("Failed to read vector: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Embedding[3] != vectors[2].Embedding[3] {
+		t.Errorf("Unexpected vector after reopen: %+v", stored)
+	}
+
+	// The log keeps nothing but positions in plaintext
+	data, err := os.ReadFile(filepath.Join(dataPath, logFileName))
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	for _, plaintext := range []string{"records", vectors[2].ID, "embedding"} {
+		if bytes.Contains(data, []byte(plaintext)) {
+			t.Errorf("Expected the log to be encrypted, found %q", plaintext)
+		}
+	}
+	var inserted int
+	if _, err := cat.ReadLog(ctx, 0, func(record *Record) error {
+		inserted += len(record.Vectors)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if inserted != len(vectors) {
+		t.Errorf("Expected %d logged vectors, got %d", len(vectors), inserted)
+	}
+}
@@ -0,0 +1,410 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// logFileName is the name of the change log file, kept from when the log was
+// called a write-ahead log so that existing data directories still open
+const logFileName = "wal.log"
+
+// Op identifies the kind of mutation recorded in the change log
+type Op string
+
+// Op constants define the mutations recorded in the change log
+const (
+	OpCreateCollection Op = "create_collection"
+	OpUpdateCollection Op = "update_collection"
+	OpDeleteCollection Op = "delete_collection"
+	OpInsert           Op = "insert"
+	OpDelete           Op = "delete"
+	OpWrite            Op = "write"
+)
+
+// Record is a single mutation in the change log.
+// Positions start at 1 and increase by one with every record.
+type Record struct {
+	Position   uint64         `json:"position"`
+	Op         Op             `json:"op"`
+	Collection string         `json:"collection"`
+	Spec       *Spec          `json:"spec,omitempty"`
+	Vectors    []*core.Vector `json:"vectors,omitempty"`
+	IDs        []string       `json:"ids,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+
+	// Replaced lists the IDs of an insert that replaced stored vectors, and
+	// Missing the IDs of a delete that were not stored; change events use them
+	// to tell updates from inserts and to skip deletes that changed nothing
+	Replaced []string `json:"replaced,omitempty"`
+	Missing  []string `json:"missing,omitempty"`
+}
+
+// sealedRecord is the form of a log record encrypted by the sealer of the
+// log; only its position stays in plaintext
+type sealedRecord struct {
+	Position uint64 `json:"position"`
+	KeyID    string `json:"key_id,omitempty"`
+	Sealed   []byte `json:"sealed,omitempty"`
+}
+
+// changeLog is an append-only log of catalog mutations stored as JSON lines.
+// A mutation is appended once it was applied, so the log feeds change streams
+// and incremental backups; it is not replayed on open, and a crash between
+// applying a mutation and appending it leaves the mutation out of the log.
+type changeLog struct {
+	file     *os.File
+	path     string
+	position uint64
+	sync     bool
+
+	// sealer, when set, encrypts the records appended to the log
+	sealer *storage.Sealer
+
+	// base is the position of the last record a checkpoint removed; the log
+	// holds the records after it
+	base uint64
+
+	// size is the length of the log file, and checkpointed its length after
+	// the last checkpoint
+	size         int64
+	checkpointed int64
+
+	// appended is closed and replaced after every append, and closed for good
+	// when the log is closed, to wake up change subscribers
+	appended chan struct{}
+}
+
+// openChangeLog opens the log at path, creating it if needed. An empty log continues
+// after base, the position the last checkpoint recorded in the catalog file; a
+// log holding records continues after its last one.
+// A torn record at the end of the file, left by a crash during an append, is
+// truncated. With a sealer, appended records are encrypted; records appended
+// without one stay readable.
+func openChangeLog(path string, base uint64, sync bool, sealer *storage.Sealer) (*changeLog, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open change log: %w", err)
+	}
+
+	w := &changeLog{file: file, path: path, sync: sync, sealer: sealer, base: base, position: base, appended: make(chan struct{})}
+	valid, err := w.recover()
+	if err != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			return nil, fmt.Errorf("failed to recover change log and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, err
+	}
+
+	if err := file.Truncate(valid); err != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			return nil, fmt.Errorf("failed to truncate change log and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, fmt.Errorf("failed to truncate change log: %w", err)
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			return nil, fmt.Errorf("failed to seek change log and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, fmt.Errorf("failed to seek change log: %w", err)
+	}
+	w.size, w.checkpointed = valid, valid
+
+	return w, nil
+}
+
+// recover scans the log for the last position and returns the length of its valid prefix
+func (w *changeLog) recover() (int64, error) {
+	reader := bufio.NewReader(w.file)
+	valid := int64(0)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A line without its terminator is a torn append
+			return valid, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read change log: %w", err)
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			if _, peekErr := reader.Peek(1); errors.Is(peekErr, io.EOF) {
+				return valid, nil
+			}
+			return 0, fmt.Errorf("%w: record after position %d: %v", ErrCorruptLog, w.position, err)
+		}
+		if valid == 0 && record.Position > 0 {
+			// The first record follows the last one a checkpoint removed
+			w.base, w.position = record.Position-1, record.Position-1
+		}
+		if record.Position != w.position+1 {
+			return 0, fmt.Errorf("%w: position %d follows %d", ErrCorruptLog, record.Position, w.position)
+		}
+
+		w.position = record.Position
+		valid += int64(len(line))
+	}
+}
+
+// append assigns the next position to record and writes it to the log
+func (w *changeLog) append(record *Record) error {
+	record.Position = w.position + 1
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	data, err := w.encode(record)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if _, err := w.file.Write(data); err != nil {
+		return fmt.Errorf("failed to append to change log: %w", err)
+	}
+	if w.sync {
+		if err := w.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync change log: %w", err)
+		}
+	}
+
+	w.position = record.Position
+	w.size += int64(len(data))
+	close(w.appended)
+	w.appended = make(chan struct{})
+	return nil
+}
+
+// encode returns the JSON form of a record, encrypted when the log has a sealer
+func (w *changeLog) encode(record *Record) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode log record: %w", err)
+	}
+	if w.sealer == nil {
+		return data, nil
+	}
+
+	sealed, keyID, err := w.sealer.Seal(context.Background(), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt log record: %w", err)
+	}
+	data, err = json.Marshal(sealedRecord{Position: record.Position, KeyID: keyID, Sealed: sealed})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode log record: %w", err)
+	}
+	return data, nil
+}
+
+// decode decodes a record read from the log, decrypting a sealed one
+func (w *changeLog) decode(ctx context.Context, line []byte, header *sealedRecord) (*Record, error) {
+	if header.Sealed != nil {
+		if w.sealer == nil {
+			return nil, fmt.Errorf("%w: record %d is encrypted and the catalog has no encryption service",
+				ErrCorruptLog, header.Position)
+		}
+		data, err := w.sealer.Open(ctx, header.Sealed, header.KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt log record %d: %w", header.Position, err)
+		}
+		line = data
+	}
+
+	var record Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, fmt.Errorf("%w: record %d: %v", ErrCorruptLog, header.Position, err)
+	}
+	if record.Position != header.Position {
+		return nil, fmt.Errorf("%w: record %d holds position %d", ErrCorruptLog, header.Position, record.Position)
+	}
+	return &record, nil
+}
+
+// read calls fn for every record with a position in (after, until]
+func (w *changeLog) read(ctx context.Context, after, until uint64, fn func(*Record) error) error {
+	_, err := w.readFrom(ctx, 0, after, until, fn)
+	return err
+}
+
+// readFrom calls fn for every record with a position in (after, until],
+// starting at a byte offset of the log where a record begins. It returns the
+// offset following the last record it read, from which a later call can
+// continue without reading the log from the start. It fails with
+// ErrLogTruncated when a checkpoint removed the records after after, and with
+// errStaleOffset when the offset is not where the record after after begins,
+// as happens once a checkpoint replaced the log.
+func (w *changeLog) readFrom(ctx context.Context, offset int64, after, until uint64, fn func(*Record) error) (int64, error) {
+	file, err := os.Open(filepath.Clean(w.path))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek change log: %w", err)
+	}
+
+	// missing reports that the first record read is not the one after after
+	missing := func() error {
+		if offset > 0 {
+			return errStaleOffset
+		}
+		return fmt.Errorf("%w: the records after position %d were removed", ErrLogTruncated, after)
+	}
+
+	reader := bufio.NewReader(file)
+	for first := true; ; first = false {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if first && after < until {
+				return 0, missing()
+			}
+			return offset, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read change log: %w", err)
+		}
+
+		// Skip records that cannot match before decoding their vectors
+		var header sealedRecord
+		if err := json.NewDecoder(bytes.NewReader(line)).Decode(&header); err != nil {
+			if first && offset > 0 {
+				return 0, errStaleOffset
+			}
+			return 0, fmt.Errorf("%w: %v", ErrCorruptLog, err)
+		}
+		if first && (header.Position > after+1 || offset > 0 && header.Position != after+1) {
+			return 0, missing()
+		}
+		if header.Position > until {
+			return offset, nil
+		}
+		offset += int64(len(line))
+		if header.Position <= after {
+			continue
+		}
+
+		record, err := w.decode(ctx, line, &header)
+		if err != nil {
+			return 0, err
+		}
+		if err := fn(record); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// checkpoint removes the records up to position through from the log. The
+// records after it are copied to a new file that replaces the log, so that
+// readers that opened the log before keep reading the file they opened.
+func (w *changeLog) checkpoint(through uint64) error {
+	if through <= w.base {
+		return nil
+	}
+
+	source, err := os.Open(filepath.Clean(w.path))
+	if err != nil {
+		return fmt.Errorf("failed to open change log: %w", err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	tmpPath := w.path + ".tmp"
+	file, err := os.OpenFile(filepath.Clean(tmpPath), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create change log: %w", err)
+	}
+	size, err := copyRecords(file, source, through)
+	if err == nil && w.sync {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, w.path)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to checkpoint change log: %w", err)
+	}
+
+	// The new file is the log now, whether or not the old one closes cleanly
+	previous := w.file
+	w.file, w.base, w.size, w.checkpointed = file, through, size, size
+	if err := previous.Close(); err != nil {
+		return fmt.Errorf("failed to close previous change log: %w", err)
+	}
+	return nil
+}
+
+// reset removes every record from the log and moves it on to position, which
+// must not precede the last one
+func (w *changeLog) reset(position uint64) error {
+	if err := w.checkpoint(position); err != nil {
+		return err
+	}
+	w.position = position
+	close(w.appended)
+	w.appended = make(chan struct{})
+	return nil
+}
+
+// copyRecords copies the records of a log after position through from source
+// to w and returns the number of bytes copied
+func copyRecords(w io.Writer, source io.Reader, through uint64) (int64, error) {
+	reader := bufio.NewReader(source)
+	writer := bufio.NewWriter(w)
+	size := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A torn record at the end is not copied
+			return size, writer.Flush()
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		var header struct {
+			Position uint64 `json:"position"`
+		}
+		if err := json.NewDecoder(bytes.NewReader(line)).Decode(&header); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrCorruptLog, err)
+		}
+		if header.Position <= through {
+			continue
+		}
+		if _, err := writer.Write(line); err != nil {
+			return 0, err
+		}
+		size += int64(len(line))
+	}
+}
+
+// close closes the log file
+func (w *changeLog) close() error {
+	close(w.appended)
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close change log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// ChangeType identifies the kind of change described by a change event
+type ChangeType string
+
+// ChangeType constants define the changes reported to subscribers
+const (
+	ChangeInsert           ChangeType = "insert"
+	ChangeUpdate           ChangeType = "update"
+	ChangeDelete           ChangeType = "delete"
+	ChangeDeleteCollection ChangeType = "delete_collection"
+)
+
+// ChangeEvent describes a change to a vector of a collection, or the deletion
+// of a whole collection. Events are derived from the change log: the
+// sequence of an event is the log position of the mutation that made it, so
+// the events of one mutation share a sequence and follow each other.
+type ChangeEvent struct {
+	Sequence   uint64     `json:"sequence"`
+	Type       ChangeType `json:"type"`
+	Collection string     `json:"collection"`
+	ID         string     `json:"id,omitempty"`
+
+	// Vector is the written vector of an insert or update
+	Vector *core.Vector `json:"vector,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ChangeOptions selects the change events delivered to a subscriber
+type ChangeOptions struct {
+	// After is the sequence to resume after; zero starts at the beginning of the log
+	After uint64
+
+	// Collection restricts the events to one collection; every collection when empty
+	Collection string
+}
+
+// Changes calls fn with the change events of every mutation after opts.After
+// that is in the change log, and returns the sequence it read up to, after
+// which a later call or Subscribe continues. It fails with ErrLogTruncated when
+// a checkpoint already removed the mutations after opts.After.
+func (c *Catalog) Changes(ctx context.Context, opts ChangeOptions, fn func(*ChangeEvent) error) (uint64, error) {
+	return c.ReadLog(ctx, opts.After, opts.emit(fn))
+}
+
+// Subscribe calls fn with the change events of every mutation after opts.After,
+// first from the change log and then as mutations are made, until ctx is
+// done, the catalog is closed or fn returns an error, which Subscribe returns.
+// To resume without missing or repeating events, subscribe again after the
+// sequence of the last mutation whose events were all processed. A subscriber
+// that falls behind a checkpoint fails with ErrLogTruncated.
+func (c *Catalog) Subscribe(ctx context.Context, opts ChangeOptions, fn func(*ChangeEvent) error) error {
+	emit := opts.emit(fn)
+	after := opts.After
+	var offset int64
+	for {
+		c.mutex.RLock()
+		if c.closed {
+			c.mutex.RUnlock()
+			return ErrCatalogClosed
+		}
+		log, position, appended := c.log, c.log.position, c.log.appended
+		c.mutex.RUnlock()
+
+		if after > position {
+			return fmt.Errorf("%w: %d is beyond the last position %d", ErrInvalidPosition, after, position)
+		}
+
+		if after < position {
+			// Continue reading where the previous pass stopped
+			next, err := log.readFrom(ctx, offset, after, position, emit)
+			if errors.Is(err, errStaleOffset) {
+				// A checkpoint replaced the log since the previous pass
+				offset = 0
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			offset, after = next, position
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-appended:
+		}
+	}
+}
+
+// emit returns a function that calls fn with the selected change events of a
+// change log record
+func (o ChangeOptions) emit(fn func(*ChangeEvent) error) func(*Record) error {
+	return func(record *Record) error {
+		if o.Collection != "" && record.Collection != o.Collection {
+			return nil
+		}
+		for _, event := range changeEvents(record) {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// changeEvents returns the change events of a change log record
+func changeEvents(record *Record) []*ChangeEvent {
+	event := func(changeType ChangeType, id string, vector *core.Vector) *ChangeEvent {
+		return &ChangeEvent{
+			Sequence:   record.Position,
+			Type:       changeType,
+			Collection: record.Collection,
+			ID:         id,
+			Vector:     vector,
+			Timestamp:  record.Timestamp,
+		}
+	}
+
+	switch record.Op {
+	case OpInsert, OpDelete, OpWrite:
+		// A write record holds the puts of a batch in Vectors and its deletes in IDs
+		events := make([]*ChangeEvent, 0, len(record.Vectors)+len(record.IDs))
+		stored := make(map[string]bool, len(record.Replaced))
+		for _, id := range record.Replaced {
+			stored[id] = true
+		}
+		for _, vector := range record.Vectors {
+			changeType := ChangeInsert
+			if stored[vector.ID] {
+				changeType = ChangeUpdate
+			}
+			// A later vector with the same ID in the record replaces this one
+			stored[vector.ID] = true
+			events = append(events, event(changeType, vector.ID, vector))
+		}
+
+		missing := make(map[string]bool, len(record.Missing))
+		for _, id := range record.Missing {
+			missing[id] = true
+		}
+		for _, id := range record.IDs {
+			if !missing[id] {
+				events = append(events, event(ChangeDelete, id, nil))
+				missing[id] = true
+			}
+		}
+		return events
+	case OpDeleteCollection:
+		return []*ChangeEvent{event(ChangeDeleteCollection, "", nil)}
+	default:
+		return nil
+	}
+}
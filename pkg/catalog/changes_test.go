@@ -0,0 +1,174 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// collectChanges returns the change events after a sequence that are in the log
+func collectChanges(t *testing.T, cat *Catalog, opts ChangeOptions) []*ChangeEvent {
+	t.Helper()
+
+	var events []*ChangeEvent
+	if _, err := cat.Changes(context.Background(), opts, func(event *ChangeEvent) error {
+		events = append(events, event)
+		return nil
+	}); err != nil {
+		t.Fatalf("Failed to read changes: %v", err)
+	}
+	return events
+}
+
+func TestCatalog_Changes(t *testing.T) {
+	ctx := context.Background()
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	for _, name := range []string{"docs", "notes"} {
+		if err := cat.Create(core.NewCollection(name, "", 4, "hnsw")); err != nil {
+			t.Fatalf("Failed to create collection: %v", err)
+		}
+	}
+	vectors := testVectors(3, 4)
+	if err := cat.Insert(ctx, "docs", vectors[:2]); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	if err := cat.Insert(ctx, "docs", vectors[1:]); err != nil {
+		t.Fatalf("Failed to upsert vectors: %v", err)
+	}
+	if err := cat.DeleteVectors(ctx, "docs", []string{"vec_0", "missing", "vec_0"}); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+	if err := cat.Insert(ctx, "notes", testVectors(1, 4)); err != nil {
+		t.Fatalf("Failed to insert notes: %v", err)
+	}
+	if err := cat.Delete("notes"); err != nil {
+		t.Fatalf("Failed to delete collection: %v", err)
+	}
+
+	events := collectChanges(t, cat, ChangeOptions{})
+	expected := []struct {
+		changeType ChangeType
+		collection string
+		id         string
+	}{
+		{ChangeInsert, "docs", "vec_0"},
+		{ChangeInsert, "docs", "vec_1"},
+		{ChangeUpdate, "docs", "vec_1"},
+		{ChangeInsert, "docs", "vec_2"},
+		{ChangeDelete, "docs", "vec_0"},
+		{ChangeInsert, "notes", "vec_0"},
+		{ChangeDeleteCollection, "notes", ""},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, want := range expected {
+		event := events[i]
+		if event.Type != want.changeType || event.Collection != want.collection || event.ID != want.id {
+			t.Errorf("Event %d: expected %s %s/%s, got %s %s/%s", i, want.changeType, want.collection, want.id,
+				event.Type, event.Collection, event.ID)
+		}
+		if i > 0 && event.Sequence < events[i-1].Sequence {
+			t.Errorf("Event %d: sequence %d is before %d", i, event.Sequence, events[i-1].Sequence)
+		}
+	}
+	if events[2].Vector == nil || events[2].Vector.ID != "vec_1" || events[4].Vector != nil {
+		t.Errorf("Expected writes to carry their vector and deletes not to, got %+v and %+v", events[2], events[4])
+	}
+	if events[1].Sequence != events[0].Sequence || events[2].Sequence == events[1].Sequence {
+		t.Errorf("Expected the events of one mutation to share a sequence, got %d, %d, %d",
+			events[0].Sequence, events[1].Sequence, events[2].Sequence)
+	}
+
+	// Resuming after a sequence skips the mutations up to it
+	resumed := collectChanges(t, cat, ChangeOptions{After: events[2].Sequence})
+	if len(resumed) != 3 || resumed[0].Type != ChangeDelete {
+		t.Errorf("Expected to resume at the delete, got %+v", resumed)
+	}
+
+	// A collection filter leaves out the other collections
+	notes := collectChanges(t, cat, ChangeOptions{Collection: "notes"})
+	if len(notes) != 2 || notes[0].Collection != "notes" || notes[1].Type != ChangeDeleteCollection {
+		t.Errorf("Expected the events of notes, got %+v", notes)
+	}
+
+	err := cat.Subscribe(ctx, ChangeOptions{After: cat.Position() + 1}, func(*ChangeEvent) error { return nil })
+	if !errors.Is(err, ErrInvalidPosition) {
+		t.Errorf("Expected ErrInvalidPosition, got %v", err)
+	}
+}
+
+func TestCatalog_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cat := newTestCatalog(t, t.TempDir())
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	vectors := testVectors(4, 4)
+	if err := cat.Insert(ctx, "docs", vectors[:1]); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	events := make(chan *ChangeEvent, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- cat.Subscribe(ctx, ChangeOptions{Collection: "docs"}, func(event *ChangeEvent) error {
+			events <- event
+			return nil
+		})
+	}()
+
+	receive := func() *ChangeEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a change event")
+			return nil
+		}
+	}
+
+	// Events already in the log are delivered first, then mutations as they are made
+	if event := receive(); event.Type != ChangeInsert || event.ID != "vec_0" {
+		t.Fatalf("Expected the logged insert, got %+v", event)
+	}
+	for i := 1; i < len(vectors); i++ {
+		if err := cat.Insert(ctx, "docs", vectors[i:i+1]); err != nil {
+			t.Fatalf("Failed to insert vector: %v", err)
+		}
+		if event := receive(); event.Type != ChangeInsert || event.ID != vectors[i].ID {
+			t.Fatalf("Expected the insert of %s, got %+v", vectors[i].ID, event)
+		}
+	}
+	if err := cat.DeleteVectors(ctx, "docs", []string{"vec_2"}); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if event := receive(); event.Type != ChangeDelete || event.ID != "vec_2" || event.Sequence != cat.Position() {
+		t.Fatalf("Expected the delete of vec_2 at %d, got %+v", cat.Position(), event)
+	}
+
+	// Closing the catalog ends the subscription
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCatalogClosed) {
+			t.Errorf("Expected ErrCatalogClosed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the subscription to end")
+	}
+}
@@ -0,0 +1,27 @@
+package catalog
+
+import "errors"
+
+// Catalog-related errors
+var (
+	ErrInvalidDataPath       = errors.New("invalid catalog data path")
+	ErrInvalidCollectionName = errors.New("invalid collection name")
+	ErrInvalidDimension      = errors.New("invalid collection dimension")
+	ErrCollectionExists      = errors.New("collection already exists")
+	ErrCollectionNotFound    = errors.New("collection not found")
+	ErrDimensionMismatch     = errors.New("vector dimension does not match collection")
+	ErrImmutableField        = errors.New("collection field cannot be changed")
+	ErrCatalogClosed         = errors.New("catalog is closed")
+	ErrCatalogNotEmpty       = errors.New("catalog is not empty")
+	ErrCorruptLog            = errors.New("change log is corrupt")
+	ErrInvalidPosition       = errors.New("invalid change log position")
+	ErrLogTruncated          = errors.New("change log records were removed by a checkpoint")
+	ErrUnsupportedOp         = errors.New("unsupported change log operation")
+	ErrInvalidReaperConfig   = errors.New("invalid expiry reaper configuration")
+	ErrInvalidScrubberConfig = errors.New("invalid scrubber configuration")
+	ErrInvalidWrite          = errors.New("invalid write")
+	ErrVersionConflict       = errors.New("vector version conflict")
+
+	// errStaleOffset reports a log offset kept from before a checkpoint replaced the log
+	errStaleOffset = errors.New("change log offset is stale")
+)
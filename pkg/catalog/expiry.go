@@ -0,0 +1,301 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// errScanLimit stops a storage scan once enough expired vectors were found
+var errScanLimit = errors.New("scan limit reached")
+
+// ExpiryStats counts expired vectors handled by the catalog since it was opened
+type ExpiryStats struct {
+	// Reaped is the number of expired vectors deleted from storage and indexes
+	Reaped uint64 `json:"reaped"`
+
+	// FilteredFromSearch is the number of expired search results that were dropped
+	// because the reaper had not deleted them yet
+	FilteredFromSearch uint64 `json:"filtered_from_search"`
+}
+
+// ExpiryStats returns the expiry counters of the catalog
+func (c *Catalog) ExpiryStats() ExpiryStats {
+	return ExpiryStats{
+		Reaped:             c.reaped.Load(),
+		FilteredFromSearch: c.expiredFiltered.Load(),
+	}
+}
+
+// ReapExpired deletes up to limit expired vectors from the named collection's
+// storage and index and returns how many were deleted. Finding expired vectors
+// scans the collection's storage, which must implement storage.Scanner; the
+// scan runs without the catalog lock, so writes go on meanwhile.
+func (c *Catalog) ReapExpired(ctx context.Context, name string, limit int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	candidates, err := c.expiredIDs(ctx, name, now, limit)
+	if err != nil {
+		return 0, err
+	}
+	return c.reap(ctx, name, candidates, now)
+}
+
+// reap deletes the vectors among candidates that are still expired at now
+// from the named collection's storage and index in one catalog write
+func (c *Catalog) reap(ctx context.Context, name string, candidates []string, now time.Time) (int, error) {
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	// A vector may have been rewritten with a new expiry since the scan
+	stored, err := e.storage.ReadWithContext(ctx, candidates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read collection %s: %w", name, err)
+	}
+
+	writes := make([]Write, 0, len(stored))
+	for _, vector := range stored {
+		if vector.Expired(now) {
+			writes = append(writes, Write{Kind: WriteDelete, ID: vector.ID})
+		}
+	}
+	if len(writes) == 0 {
+		return 0, nil
+	}
+
+	if _, err := c.applyWrites(ctx, e, writes); err != nil {
+		return 0, err
+	}
+
+	c.reaped.Add(uint64(len(writes)))
+	return len(writes), nil
+}
+
+// expiredIDs returns the IDs of up to limit vectors of a collection that
+// expired by now, or of all of them when limit is zero. The scan holds the
+// collection open but not the catalog mutex.
+func (c *Catalog) expiredIDs(ctx context.Context, name string, now time.Time, limit int) ([]string, error) {
+	c.mutex.RLock()
+	e, err := c.lookup(name)
+	if err != nil {
+		c.mutex.RUnlock()
+		return nil, err
+	}
+	scanner, ok := e.storage.(storage.Scanner)
+	if !ok {
+		c.mutex.RUnlock()
+		return nil, fmt.Errorf("collection %s: %w", name, storage.ErrScanNotSupported)
+	}
+	scanCtx, release := e.acquire(ctx)
+	c.mutex.RUnlock()
+	defer release()
+
+	var ids []string
+	err = scanner.Scan(scanCtx, func(vector *core.Vector) error {
+		if !vector.Expired(now) {
+			return nil
+		}
+		ids = append(ids, vector.ID)
+		if limit > 0 && len(ids) >= limit {
+			return errScanLimit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errScanLimit) {
+		if scanCtx.Err() != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("%w: %s was deleted or closed during the scan", ErrCollectionNotFound, name)
+		}
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// ReaperConfig holds configuration parameters for the expiry reaper
+type ReaperConfig struct {
+	// Interval is the time between reaper passes over every collection
+	Interval time.Duration `json:"interval"`
+
+	// BatchSize is the maximum number of vectors deleted in one catalog write
+	BatchSize int `json:"batch_size"`
+
+	// MaxDeletesPerSecond limits the deletion rate; zero means unlimited
+	MaxDeletesPerSecond float64 `json:"max_deletes_per_second"`
+}
+
+// DefaultReaperConfig returns the reaper configuration used by the API server
+func DefaultReaperConfig() ReaperConfig {
+	return ReaperConfig{
+		Interval:            time.Minute,
+		BatchSize:           500,
+		MaxDeletesPerSecond: 5000,
+	}
+}
+
+// Reaper periodically deletes expired vectors from every collection of a catalog
+type Reaper struct {
+	catalog *Catalog
+	config  ReaperConfig
+	limiter *rate.Limiter
+	logger  *slog.Logger
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewReaper creates a reaper for the catalog; it does nothing until Start is called
+func NewReaper(catalog *Catalog, config ReaperConfig, logger *slog.Logger) (*Reaper, error) {
+	if config.Interval <= 0 || config.BatchSize <= 0 || config.MaxDeletesPerSecond < 0 {
+		return nil, ErrInvalidReaperConfig
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	limit := rate.Inf
+	if config.MaxDeletesPerSecond > 0 {
+		limit = rate.Limit(config.MaxDeletesPerSecond)
+	}
+
+	return &Reaper{
+		catalog: catalog,
+		config:  config,
+		limiter: rate.NewLimiter(limit, config.BatchSize),
+		logger:  logger,
+	}, nil
+}
+
+// Start runs reaper passes in the background until Stop is called
+func (r *Reaper) Start() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.run(ctx, r.done)
+}
+
+// Stop stops the background passes and waits for a running pass to finish
+func (r *Reaper) Stop() {
+	r.mutex.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// run performs a pass every interval
+func (r *Reaper) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := r.RunOnce(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				r.logger.Error("Expired vector reaping failed", "error", err)
+			}
+			if reaped > 0 {
+				r.logger.Info("Reaped expired vectors", "count", reaped)
+			}
+		}
+	}
+}
+
+// RunOnce deletes the expired vectors of every collection in batches,
+// respecting the configured deletion rate, and returns how many were deleted.
+// A failing collection does not stop the pass; the first error is returned.
+func (r *Reaper) RunOnce(ctx context.Context) (int, error) {
+	collections, err := r.catalog.List()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	var firstErr error
+	for _, collection := range collections {
+		reaped, err := r.reapCollection(ctx, collection.Name)
+		total += reaped
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return total, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return total, firstErr
+}
+
+// reapCollection deletes the vectors of one collection that expired by the
+// start of the pass. A single scan finds them all; they are then deleted in
+// batches, each taking the catalog lock for its own write only.
+func (r *Reaper) reapCollection(ctx context.Context, name string) (int, error) {
+	now := time.Now()
+	candidates, err := r.catalog.expiredIDs(ctx, name, now, 0)
+	if err != nil {
+		if errors.Is(err, ErrCollectionNotFound) {
+			// Deleted since the pass started
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to reap collection %s: %w", name, err)
+	}
+
+	total := 0
+	for start := 0; start < len(candidates); start += r.config.BatchSize {
+		batch := candidates[start:min(start+r.config.BatchSize, len(candidates))]
+		reaped, err := r.catalog.reap(ctx, name, batch, now)
+		total += reaped
+		if err != nil {
+			if errors.Is(err, ErrCollectionNotFound) {
+				return total, nil
+			}
+			return total, fmt.Errorf("failed to reap collection %s: %w", name, err)
+		}
+
+		// Pay for the deleted batch before the next one
+		if err := r.limiter.WaitN(ctx, reaped); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,155 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func TestCatalog_ExpiredVectorsAreFilteredAndReaped(t *testing.T) {
+	dataPath := t.TempDir()
+	cat := newTestCatalog(t, dataPath)
+
+	if err := cat.Create(core.NewCollection("sessions", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	ctx := context.Background()
+	vectors := testVectors(10, 4)
+	past := time.Now().Add(-time.Minute)
+	for _, vector := range vectors[:4] {
+		vector.ExpiresAt = &past
+	}
+	vectors[4].SetTTL(time.Hour)
+	if err := cat.Insert(ctx, "sessions", vectors); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	// Expired vectors are hidden before they are reaped
+	results, err := cat.Search(ctx, "sessions", vectors[0].Embedding, 10)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 6 {
+		t.Errorf("Expected 6 live results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Vector.Expired(time.Now()) {
+			t.Errorf("Expired vector %s was returned", result.Vector.ID)
+		}
+	}
+	if stats := cat.ExpiryStats(); stats.FilteredFromSearch != 4 {
+		t.Errorf("Expected 4 filtered results, got %d", stats.FilteredFromSearch)
+	}
+
+	// The expiry survives a reopen of the storage
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+	cat = newTestCatalog(t, dataPath)
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	reaped, err := cat.ReapExpired(ctx, "sessions", 3)
+	if err != nil {
+		t.Fatalf("Failed to reap: %v", err)
+	}
+	if reaped != 3 {
+		t.Errorf("Expected the first batch to reap 3 vectors, got %d", reaped)
+	}
+	reaped, err = cat.ReapExpired(ctx, "sessions", 3)
+	if err != nil {
+		t.Fatalf("Failed to reap: %v", err)
+	}
+	if reaped != 1 {
+		t.Errorf("Expected the second batch to reap 1 vector, got %d", reaped)
+	}
+
+	collection, err := cat.Get("sessions")
+	if err != nil {
+		t.Fatalf("Failed to get collection: %v", err)
+	}
+	if collection.Count != 6 {
+		t.Errorf("Expected 6 vectors after reaping, got %d", collection.Count)
+	}
+	if stats := cat.ExpiryStats(); stats.Reaped != 4 {
+		t.Errorf("Expected 4 reaped vectors, got %d", stats.Reaped)
+	}
+
+	store, err := cat.Storage("sessions")
+	if err != nil {
+		t.Fatalf("Failed to get storage: %v", err)
+	}
+	stored, err := store.Read([]string{vectors[0].ID, vectors[4].ID})
+	if err != nil {
+		t.Fatalf("Failed to read storage: %v", err)
+	}
+	if len(stored) != 1 || stored[0].ID != vectors[4].ID || stored[0].ExpiresAt == nil {
+		t.Errorf("Expected only the vector with a future expiry to remain, got %+v", stored)
+	}
+}
+
+func TestReaper_RunOnce(t *testing.T) {
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	past := time.Now().Add(-time.Second)
+	for _, name := range []string{"news", "chat"} {
+		if err := cat.Create(core.NewCollection(name, "", 4, "hnsw")); err != nil {
+			t.Fatalf("Failed to create collection: %v", err)
+		}
+		vectors := testVectors(25, 4)
+		for _, vector := range vectors[:20] {
+			vector.ExpiresAt = &past
+		}
+		if err := cat.Insert(ctx, name, vectors); err != nil {
+			t.Fatalf("Failed to insert vectors: %v", err)
+		}
+	}
+
+	// A vector rewritten without an expiry must not be reaped
+	if err := cat.Insert(ctx, "chat", testVectors(1, 4)); err != nil {
+		t.Fatalf("Failed to rewrite vector: %v", err)
+	}
+
+	if _, err := NewReaper(cat, ReaperConfig{}, nil); !errors.Is(err, ErrInvalidReaperConfig) {
+		t.Errorf("Expected ErrInvalidReaperConfig, got %v", err)
+	}
+
+	reaper, err := NewReaper(cat, ReaperConfig{Interval: time.Hour, BatchSize: 7, MaxDeletesPerSecond: 1000}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create reaper: %v", err)
+	}
+
+	reaped, err := reaper.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("Failed to run reaper: %v", err)
+	}
+	if reaped != 39 {
+		t.Errorf("Expected 39 reaped vectors, got %d", reaped)
+	}
+
+	for name, expected := range map[string]int64{"news": 5, "chat": 6} {
+		collection, err := cat.Get(name)
+		if err != nil {
+			t.Fatalf("Failed to get collection: %v", err)
+		}
+		if collection.Count != expected {
+			t.Errorf("Collection %s: expected %d vectors, got %d", name, expected, collection.Count)
+		}
+	}
+
+	reaper.Start()
+	reaper.Stop()
+}
@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// SearchAsOf returns the k vectors of the named collection most similar to the
+// query as they were at asOf. Past versions are not indexed, so the search
+// reads the version history of every vector, including vectors deleted since,
+// from storage and compares the versions current at asOf exhaustively; the
+// collection's storage must implement storage.VersionScanner. Versions pruned
+// by the storage's retention settings are not found.
+func (c *Catalog) SearchAsOf(ctx context.Context, name string, query []float64, k int, asOf time.Time) ([]core.VectorSearchResult, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(query) != e.spec.Collection.Dimension {
+		return nil, fmt.Errorf("%w: query has dimension %d, collection %s expects %d",
+			ErrDimensionMismatch, len(query), name, e.spec.Collection.Dimension)
+	}
+
+	if k <= 0 {
+		return []core.VectorSearchResult{}, nil
+	}
+
+	scanner, ok := e.storage.(storage.VersionScanner)
+	if !ok {
+		return nil, fmt.Errorf("collection %s: %w", name, storage.ErrScanNotSupported)
+	}
+
+	metric := e.spec.Index.DistanceMetric
+	results := make([]core.VectorSearchResult, 0, k)
+	err = scanner.ScanVersions(ctx, func(versions []*core.Vector) error {
+		vector := storage.VersionAsOf(versions, asOf)
+		if vector == nil || vector.Expired(asOf) {
+			return nil
+		}
+		distance := index.Distance(metric, query, vector.Embedding)
+		results = append(results, core.VectorSearchResult{
+			Vector:   vector,
+			Distance: distance,
+			Score:    1.0 / (1.0 + distance),
+		})
+
+		// Keep only the best candidates as the scan goes
+		if len(results) > 2*k {
+			results = topResults(results, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection %s as of %s: %w", name, asOf.Format(time.RFC3339), err)
+	}
+
+	return topResults(results, k), nil
+}
+
+// topResults returns the k results with the smallest distance, closest first
+func topResults(results []core.VectorSearchResult, k int) []core.VectorSearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Distance < results[j].Distance
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func TestCatalog_SearchAsOf(t *testing.T) {
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "", 2, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	ctx := context.Background()
+	first := time.Now().Add(-2 * time.Hour)
+	second := time.Now().Add(-time.Hour)
+	insert := func(id string, embedding []float64, updated time.Time) {
+		t.Helper()
+		vector := &core.Vector{ID: id, Embedding: embedding, UpdatedAt: updated}
+		if err := cat.Insert(ctx, "docs", []*core.Vector{vector}); err != nil {
+			t.Fatalf("Failed to insert %s: %v", id, err)
+		}
+	}
+	insert("a", []float64{1, 0}, first)
+	insert("b", []float64{0, 1}, first)
+	insert("a", []float64{0.1, 1}, second)
+	insert("c", []float64{1, 0.1}, second)
+
+	query := []float64{1, 0}
+	search := func(asOf time.Time) []core.VectorSearchResult {
+		t.Helper()
+		results, err := cat.SearchAsOf(ctx, "docs", query, 3, asOf)
+		if err != nil {
+			t.Fatalf("Failed to search as of %v: %v", asOf, err)
+		}
+		return results
+	}
+
+	if results := search(first.Add(-time.Minute)); len(results) != 0 {
+		t.Errorf("Expected no results before the first insert, got %d", len(results))
+	}
+
+	results := search(first.Add(time.Minute))
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results as of the first insert, got %d", len(results))
+	}
+	if results[0].Vector.ID != "a" || results[0].Vector.Embedding[0] != 1 || results[0].Score != 1 {
+		t.Errorf("Expected the first version of a to match exactly, got %+v", results[0])
+	}
+
+	results = search(time.Now())
+	if len(results) != 3 || results[0].Vector.ID != "c" {
+		t.Fatalf("Expected c to be closest now, got %+v", results)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Distance < results[i-1].Distance {
+			t.Errorf("Results are not ordered by distance: %+v", results)
+		}
+	}
+
+	// Deleted vectors are still found as of the times before their deletion
+	if err := cat.DeleteVectors(ctx, "docs", []string{"b"}); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if results := search(first.Add(time.Minute)); len(results) != 2 || results[1].Vector.ID != "b" {
+		t.Errorf("Expected a and b as of the first insert after deleting b, got %+v", results)
+	}
+	for _, result := range search(time.Now()) {
+		if result.Vector.ID == "b" {
+			t.Errorf("Expected b to be gone now, got %+v", result)
+		}
+	}
+
+	if _, err := cat.SearchAsOf(ctx, "docs", []float64{1, 0, 0}, 3, time.Now()); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+}
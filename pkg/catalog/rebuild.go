@@ -0,0 +1,254 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// RebuildProgress reports how far the rebuild of a collection's index is
+type RebuildProgress struct {
+	Collection string `json:"collection"`
+	Indexed    int64  `json:"indexed"`
+
+	// Total is the number of vectors the collection held when the rebuild started
+	Total int64 `json:"total"`
+
+	Done bool `json:"done"`
+}
+
+// RebuildOptions controls a rebuild of collection indexes
+type RebuildOptions struct {
+	// Workers is the number of collections RebuildIndexes rebuilds at once;
+	// GOMAXPROCS when zero
+	Workers int
+
+	// ProgressInterval is the number of vectors indexed between progress
+	// reports of a collection; 10000 when zero
+	ProgressInterval int64
+
+	// Progress, when set, is called as collections are rebuilt, and once with
+	// Done set when a collection's index is in use. It is called from several
+	// goroutines at once, and must not call back into the catalog.
+	Progress func(RebuildProgress)
+}
+
+// RebuildIndexes builds the index of every collection from the vectors in its
+// storage, which must implement storage.Scanner, and replaces the index in use
+// once it is complete. Collections are rebuilt in parallel and can be searched
+// and written meanwhile; writes made during a rebuild are applied to the new
+// index from the change log, under the catalog mutex only for the last of
+// them, before it is put in use. A failing collection does not stop the others; the first
+// error is returned.
+func (c *Catalog) RebuildIndexes(ctx context.Context, opts RebuildOptions) error {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	c.mutex.RLock()
+	if c.closed {
+		c.mutex.RUnlock()
+		return ErrCatalogClosed
+	}
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	c.mutex.RUnlock()
+	sort.Strings(names)
+
+	work := make(chan string)
+	errs := make(chan error, len(names))
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers && i < len(names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				err := c.RebuildIndex(ctx, name, opts)
+				if err != nil && !errors.Is(err, ErrCollectionNotFound) {
+					errs <- fmt.Errorf("failed to rebuild index of collection %s: %w", name, err)
+				}
+			}
+		}()
+	}
+
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- name
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return <-errs
+}
+
+// RebuildIndex builds the index of the named collection from the vectors in
+// its storage and puts it in use, the way RebuildIndexes does for every collection
+func (c *Catalog) RebuildIndex(ctx context.Context, name string, opts RebuildOptions) error {
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = 10000
+	}
+
+	c.mutex.RLock()
+	e, err := c.lookup(name)
+	if err != nil {
+		c.mutex.RUnlock()
+		return err
+	}
+	config, total, position := e.spec.Index, e.spec.Collection.Count, c.log.position
+	scanCtx, release := e.acquire(ctx)
+	unpin := c.pin(position)
+	c.mutex.RUnlock()
+	defer unpin()
+
+	scanner, ok := e.storage.(storage.Scanner)
+	if !ok {
+		release()
+		return storage.ErrScanNotSupported
+	}
+	built, err := c.indexFactory.CreateIndex(config)
+	if err != nil {
+		release()
+		return err
+	}
+
+	// The version of every indexed vector tells the log records written during
+	// the scan that the scan already saw apart from those it missed
+	indexed := make(map[string]time.Time)
+	progress := RebuildProgress{Collection: name, Total: total}
+	now := time.Now()
+	err = scanner.Scan(scanCtx, func(vector *core.Vector) error {
+		if vector.Expired(now) {
+			return nil
+		}
+		if err := built.Insert(vector); err != nil {
+			return fmt.Errorf("failed to index vector %s: %w", vector.ID, err)
+		}
+		indexed[vector.ID] = vector.UpdatedAt
+
+		progress.Indexed++
+		if opts.Progress != nil && progress.Indexed%opts.ProgressInterval == 0 {
+			opts.Progress(progress)
+		}
+		return nil
+	})
+	if err != nil && scanCtx.Err() != nil && ctx.Err() == nil {
+		err = fmt.Errorf("%w: %s was deleted during the scan", ErrCollectionNotFound, name)
+	}
+	// Deleting the collection waits for the scan under the catalog mutex,
+	// which swapIndex takes
+	release()
+
+	// Catch up with most writes made during the scan before taking the
+	// catalog mutex, which holds off every read and write
+	if err == nil {
+		c.mutex.RLock()
+		log, until, closed := c.log, c.log.position, c.closed
+		c.mutex.RUnlock()
+		if closed {
+			err = ErrCatalogClosed
+		} else if err = applyLogged(ctx, log, name, built, position, until, indexed); err == nil {
+			position = until
+		}
+	}
+
+	var previous index.VectorIndex
+	if err == nil {
+		previous, err = c.swapIndex(ctx, e, built, position, indexed)
+	}
+	if err != nil {
+		_ = built.Close()
+		return err
+	}
+	if err := previous.Close(); err != nil {
+		return fmt.Errorf("failed to close previous index: %w", err)
+	}
+
+	if opts.Progress != nil {
+		progress.Done = true
+		opts.Progress(progress)
+	}
+	return nil
+}
+
+// swapIndex applies the writes logged after position to an index built from
+// a scan of the collection's storage and puts it in use in place of the index
+// of the entry. It returns the index that was in use, for the caller to close.
+func (c *Catalog) swapIndex(ctx context.Context, e *entry, built index.VectorIndex, position uint64,
+	indexed map[string]time.Time) (index.VectorIndex, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name := e.spec.Collection.Name
+	if c.closed {
+		return nil, ErrCatalogClosed
+	}
+	if c.entries[name] != e {
+		// The collection was deleted, and maybe created again, during the scan
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+
+	if err := applyLogged(ctx, c.log, name, built, position, c.log.position, indexed); err != nil {
+		return nil, err
+	}
+
+	previous := e.index
+	e.index = built
+	return previous, nil
+}
+
+// applyLogged applies the writes to the named collection logged in
+// (after, until] to an index built from a scan of its storage. indexed holds
+// the version of every vector in the index, so that writes the scan already
+// saw are skipped.
+func applyLogged(ctx context.Context, log *changeLog, name string, built index.VectorIndex, after, until uint64,
+	indexed map[string]time.Time) error {
+	return log.read(ctx, after, until, func(record *Record) error {
+		if record.Collection != name {
+			return nil
+		}
+		if record.Op != OpInsert && record.Op != OpDelete && record.Op != OpWrite {
+			return nil
+		}
+		for _, vector := range record.Vectors {
+			version, seen := indexed[vector.ID]
+			if seen && version.Equal(vector.UpdatedAt) {
+				continue
+			}
+			if seen {
+				if err := built.Delete(vector.ID); err != nil && !errors.Is(err, index.ErrVectorNotFound) {
+					return fmt.Errorf("failed to replace vector %s in index: %w", vector.ID, err)
+				}
+			}
+			if err := built.Insert(vector); err != nil {
+				return fmt.Errorf("failed to index vector %s: %w", vector.ID, err)
+			}
+			indexed[vector.ID] = vector.UpdatedAt
+		}
+		for _, id := range record.IDs {
+			if _, seen := indexed[id]; !seen {
+				continue
+			}
+			if err := built.Delete(id); err != nil && !errors.Is(err, index.ErrVectorNotFound) {
+				return fmt.Errorf("failed to remove vector %s from index: %w", id, err)
+			}
+			delete(indexed, id)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,96 @@
+package catalog
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func TestCatalog_RebuildIndexes(t *testing.T) {
+	ctx := context.Background()
+	dataPath := t.TempDir()
+
+	cat := newTestCatalog(t, dataPath)
+	counts := map[string]int{"docs": 5, "notes": 3}
+	for name, count := range counts {
+		if err := cat.Create(core.NewCollection(name, "", 4, "hnsw")); err != nil {
+			t.Fatalf("Failed to create collection: %v", err)
+		}
+		if err := cat.Insert(ctx, name, testVectors(count, 4)); err != nil {
+			t.Fatalf("Failed to insert vectors: %v", err)
+		}
+	}
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+
+	cat = newTestCatalog(t, dataPath)
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	// Indexes start empty after a reopen
+	query := testVectors(5, 4)[4].Embedding
+	if results, err := cat.Search(ctx, "docs", query, 1); err != nil || len(results) != 0 {
+		t.Fatalf("Expected an empty index before the rebuild, got %+v (%v)", results, err)
+	}
+
+	// A vector written while docs is scanned is indexed as well
+	added := &core.Vector{ID: "added", Embedding: []float64{-1, -1, -1, -1}}
+	var mutex sync.Mutex
+	final := make(map[string]RebuildProgress)
+	var once sync.Once
+	var writer sync.WaitGroup
+	err := cat.RebuildIndexes(ctx, RebuildOptions{
+		Workers:          2,
+		ProgressInterval: 1,
+		Progress: func(progress RebuildProgress) {
+			if progress.Collection == "docs" && !progress.Done {
+				once.Do(func() {
+					writer.Add(1)
+					go func() {
+						defer writer.Done()
+						if err := cat.Insert(ctx, "docs", []*core.Vector{added}); err != nil {
+							t.Errorf("Failed to insert during rebuild: %v", err)
+						}
+					}()
+				})
+			}
+			if progress.Done {
+				mutex.Lock()
+				final[progress.Collection] = progress
+				mutex.Unlock()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to rebuild indexes: %v", err)
+	}
+	writer.Wait()
+
+	for name, count := range counts {
+		if progress := final[name]; progress.Total != int64(count) || progress.Indexed < int64(count) {
+			t.Errorf("Unexpected final progress of %s: %+v", name, progress)
+		}
+	}
+
+	results, err := cat.Search(ctx, "docs", query, 1)
+	if err != nil || len(results) != 1 || results[0].Vector.ID == "added" {
+		t.Errorf("Expected to find a stored vector after the rebuild, got %+v (%v)", results, err)
+	}
+	results, err = cat.Search(ctx, "docs", added.Embedding, 1)
+	if err != nil || len(results) != 1 || results[0].Vector.ID != "added" {
+		t.Errorf("Expected to find the vector written during the rebuild, got %+v (%v)", results, err)
+	}
+	docs, err := cat.Index("docs")
+	if err != nil {
+		t.Fatalf("Failed to get index: %v", err)
+	}
+	if stats := docs.GetStats(); stats.TotalVectors != 6 {
+		t.Errorf("Expected 6 indexed vectors, got %d", stats.TotalVectors)
+	}
+}
@@ -0,0 +1,220 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// Stage opens an empty catalog in a new directory next to the data directory
+// of c, configured like c, for a restore to fill before Swap puts its
+// collections in place of those of c, or Discard drops it
+func (c *Catalog) Stage() (*Catalog, error) {
+	c.mutex.RLock()
+	closed, config := c.closed, c.config
+	c.mutex.RUnlock()
+	if closed {
+		return nil, ErrCatalogClosed
+	}
+
+	dataPath := filepath.Clean(config.DataPath)
+	dir, err := os.MkdirTemp(filepath.Dir(dataPath), filepath.Base(dataPath)+".restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	config.DataPath = dir
+
+	staged, err := New(config)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return staged, nil
+}
+
+// Discard closes a staged catalog and removes its data
+func (c *Catalog) Discard() error {
+	c.mutex.RLock()
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	c.mutex.RUnlock()
+
+	errs := []error{c.Close()}
+	if err := os.RemoveAll(c.config.DataPath); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove staging directory: %w", err))
+	}
+	for _, name := range names {
+		if err := c.removeObjects(name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove offloaded data of collection %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Load writes vectors with their version history to the storage of the named
+// collection as they are, keeping their versions, times and tombstones. The
+// versions of every vector follow the versions already stored, in the order
+// storage.VersionScanner returns them. Load fills a staged catalog: it records
+// nothing in the change log and leaves the index to Swap.
+func (c *Catalog) Load(ctx context.Context, name string, versions []*core.Vector) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	dimension := e.spec.Collection.Dimension
+	ids := make([]string, 0, len(versions))
+	seen := make(map[string]bool, len(versions))
+	for i, vector := range versions {
+		if vector == nil || vector.ID == "" {
+			return fmt.Errorf("%w: version %d has no ID", ErrInvalidWrite, i)
+		}
+		if !vector.Deleted && len(vector.Embedding) != dimension {
+			return fmt.Errorf("%w: vector %s has dimension %d, collection %s expects %d",
+				ErrDimensionMismatch, vector.ID, len(vector.Embedding), name, dimension)
+		}
+		vector.Collection = name
+		if !vector.Deleted {
+			vector.Dimension = dimension
+		}
+		if !seen[vector.ID] {
+			seen[vector.ID] = true
+			ids = append(ids, vector.ID)
+		}
+	}
+
+	before, err := e.storage.ReadWithContext(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to read collection %s: %w", name, err)
+	}
+	if err := e.storage.WriteWithContext(ctx, versions); err != nil {
+		return fmt.Errorf("failed to write vectors to collection %s: %w", name, err)
+	}
+	after, err := e.storage.ReadWithContext(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to read collection %s: %w", name, err)
+	}
+
+	e.spec.Collection.Count += int64(len(after) - len(before))
+
+	return c.save()
+}
+
+// Swap closes a staged catalog and puts its collections in place of those of
+// c, which must have none, with their indexes rebuilt from storage. The
+// change log of c drops its records and moves on to position, the
+// position of the backup the staged catalog was restored from, unless it is
+// past it already; the log of the staged catalog only recorded the restore and
+// is dropped.
+func (c *Catalog) Swap(ctx context.Context, staged *Catalog, position uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrCatalogClosed
+	}
+	if len(c.entries) > 0 {
+		return ErrCatalogNotEmpty
+	}
+
+	staged.mutex.Lock()
+	specs := make([]Spec, 0, len(staged.entries))
+	entries := staged.entries
+	for _, e := range entries {
+		specs = append(specs, e.spec)
+	}
+	if c.config.Storage.Type == storage.StorageTypeMemory {
+		// Memory storage lives as long as its engines, which move over as they are
+		staged.entries = make(map[string]*entry)
+	}
+	staged.mutex.Unlock()
+
+	if err := staged.Close(); err != nil {
+		return fmt.Errorf("failed to close staged catalog: %w", err)
+	}
+	if c.config.Storage.Type == storage.StorageTypeMemory {
+		c.entries = entries
+		for _, e := range entries {
+			if err := indexStored(ctx, e); err != nil {
+				return err
+			}
+		}
+	} else if err := c.swapCollections(ctx, staged.config.DataPath, specs); err != nil {
+		return err
+	}
+
+	base := max(position, c.log.position)
+	c.backupPosition = position
+	if err := c.writeFile(base); err != nil {
+		return err
+	}
+	if err := c.log.reset(base); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(staged.config.DataPath); err != nil {
+		return fmt.Errorf("failed to remove staging directory: %w", err)
+	}
+	return nil
+}
+
+// swapCollections moves the collection namespaces of a closed staged catalog
+// into the data directory of c, opens them and indexes their vectors; the
+// caller must hold the mutex
+func (c *Catalog) swapCollections(ctx context.Context, stagedPath string, specs []Spec) error {
+	current := filepath.Join(c.config.DataPath, collectionsDirName)
+	previous := filepath.Join(stagedPath, "previous")
+	if err := os.Rename(current, previous); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move collection data aside: %w", err)
+	}
+	if err := os.Rename(filepath.Join(stagedPath, collectionsDirName), current); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move restored collection data: %w", err)
+	}
+
+	for _, spec := range specs {
+		e, err := c.openEntry(spec)
+		if err == nil {
+			c.entries[spec.Collection.Name] = e
+			err = indexStored(ctx, e)
+		}
+		if err != nil {
+			closeErr := c.closeEntries()
+			c.entries = make(map[string]*entry)
+			return errors.Join(err, closeErr)
+		}
+	}
+	return nil
+}
+
+// indexStored inserts the vectors stored by an entry that have not expired into its index
+func indexStored(ctx context.Context, e *entry) error {
+	scanner, ok := e.storage.(storage.Scanner)
+	if !ok {
+		return fmt.Errorf("collection %s: %w", e.spec.Collection.Name, storage.ErrScanNotSupported)
+	}
+
+	now := time.Now()
+	return scanner.Scan(ctx, func(vector *core.Vector) error {
+		if vector.Expired(now) {
+			return nil
+		}
+		if err := e.index.Insert(vector); err != nil {
+			return fmt.Errorf("failed to index vector %s: %w", vector.ID, err)
+		}
+		return nil
+	})
+}
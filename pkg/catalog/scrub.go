@@ -0,0 +1,250 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// Scrub verifies every stored record of the named collection, whose storage
+// must implement storage.Scrubber. Vectors whose records were quarantined are
+// removed from the index and the collection count; repaired vectors are indexed
+// again from their new copies. Both are recorded in the change log, as a
+// delete and an insert. Writes to the catalog wait while a scrub that
+// quarantines or repairs runs.
+func (c *Catalog) Scrub(ctx context.Context, name string, opts storage.ScrubOptions) (*storage.ScrubReport, error) {
+	if !opts.Quarantine && opts.Repair == nil {
+		c.mutex.RLock()
+		defer c.mutex.RUnlock()
+
+		_, scrubber, err := c.scrubber(name)
+		if err != nil {
+			return nil, err
+		}
+		return scrubber.Scrub(ctx, opts)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, scrubber, err := c.scrubber(name)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := scrubber.Scrub(ctx, opts)
+	if report == nil {
+		return nil, err
+	}
+	if reindexErr := c.reindexScrubbed(ctx, e, report); reindexErr != nil && err == nil {
+		err = reindexErr
+	}
+	return report, err
+}
+
+// scrubber returns the entry of the named collection and its storage as a
+// storage.Scrubber; the caller must hold the lock
+func (c *Catalog) scrubber(name string) (*entry, storage.Scrubber, error) {
+	e, err := c.lookup(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scrubber, ok := e.storage.(storage.Scrubber)
+	if !ok {
+		return nil, nil, fmt.Errorf("collection %s: %w", name, storage.ErrScrubNotSupported)
+	}
+	return e, scrubber, nil
+}
+
+// reindexScrubbed brings the index and count of a collection in line with the
+// vectors a scrub quarantined or repaired; the caller must hold the write lock
+func (c *Catalog) reindexScrubbed(ctx context.Context, e *entry, report *storage.ScrubReport) error {
+	var quarantined, repaired []string
+	for _, record := range report.Corrupt {
+		if record.Version || record.ID == "" {
+			continue
+		}
+		switch record.Action {
+		case storage.ScrubActionQuarantined:
+			quarantined = append(quarantined, record.ID)
+		case storage.ScrubActionRepaired:
+			repaired = append(repaired, record.ID)
+		}
+	}
+	if len(quarantined) == 0 && len(repaired) == 0 {
+		return nil
+	}
+
+	name := e.spec.Collection.Name
+	if len(quarantined) > 0 {
+		if err := c.log.append(&Record{Op: OpDelete, Collection: name, IDs: quarantined}); err != nil {
+			return err
+		}
+	}
+	for _, id := range quarantined {
+		if err := e.index.Delete(id); err != nil && !errors.Is(err, index.ErrVectorNotFound) {
+			return fmt.Errorf("failed to remove vector %s from index: %w", id, err)
+		}
+	}
+
+	vectors, err := e.storage.ReadWithContext(ctx, repaired)
+	if err != nil {
+		return fmt.Errorf("failed to read repaired vectors of collection %s: %w", name, err)
+	}
+	if len(vectors) > 0 {
+		if err := c.log.append(&Record{Op: OpInsert, Collection: name, Vectors: vectors, Replaced: repaired}); err != nil {
+			return err
+		}
+	}
+	for _, vector := range vectors {
+		if err := e.index.Delete(vector.ID); err != nil && !errors.Is(err, index.ErrVectorNotFound) {
+			return fmt.Errorf("failed to replace vector %s in index: %w", vector.ID, err)
+		}
+		if err := e.index.Insert(vector); err != nil {
+			return fmt.Errorf("failed to index vector %s: %w", vector.ID, err)
+		}
+	}
+
+	e.spec.Collection.Count -= int64(len(quarantined))
+	if e.spec.Collection.Count < 0 {
+		e.spec.Collection.Count = 0
+	}
+	e.spec.Collection.UpdatedAt = time.Now()
+
+	return c.save()
+}
+
+// ScrubberConfig holds configuration parameters for the background scrubber
+type ScrubberConfig struct {
+	// Interval is the time between scrubs of every collection
+	Interval time.Duration `json:"interval"`
+
+	// Quarantine moves the corrupt records found by a scrub out of storage
+	Quarantine bool `json:"quarantine"`
+}
+
+// DefaultScrubberConfig returns the scrubber configuration used by the API server
+func DefaultScrubberConfig() ScrubberConfig {
+	return ScrubberConfig{
+		Interval:   24 * time.Hour,
+		Quarantine: false,
+	}
+}
+
+// Scrubber periodically verifies the stored records of every collection of a catalog
+type Scrubber struct {
+	catalog *Catalog
+	config  ScrubberConfig
+	logger  *slog.Logger
+
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewScrubber creates a scrubber for the catalog; it does nothing until Start is called
+func NewScrubber(catalog *Catalog, config ScrubberConfig, logger *slog.Logger) (*Scrubber, error) {
+	if config.Interval <= 0 {
+		return nil, ErrInvalidScrubberConfig
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Scrubber{
+		catalog: catalog,
+		config:  config,
+		logger:  logger,
+	}, nil
+}
+
+// Start runs scrubs in the background until Stop is called
+func (s *Scrubber) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.run(ctx, s.done)
+}
+
+// Stop stops the background scrubs and waits for a running scrub to finish
+func (s *Scrubber) Stop() {
+	s.mutex.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// run scrubs every interval
+func (s *Scrubber) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				s.logger.Error("Scrubbing failed", "error", err)
+			}
+		}
+	}
+}
+
+// RunOnce scrubs every collection whose storage supports it, logs the corrupt
+// records found and returns the reports by collection name. A failing
+// collection does not stop the pass; the first error is returned.
+func (s *Scrubber) RunOnce(ctx context.Context) (map[string]*storage.ScrubReport, error) {
+	collections, err := s.catalog.List()
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make(map[string]*storage.ScrubReport, len(collections))
+	var firstErr error
+	for _, collection := range collections {
+		name := collection.Name
+		report, err := s.catalog.Scrub(ctx, name, storage.ScrubOptions{Quarantine: s.config.Quarantine})
+		if report != nil {
+			reports[name] = report
+			for _, record := range report.Corrupt {
+				s.logger.Warn("Corrupt record found", "collection", name, "id", record.ID,
+					"location", record.Location, "reason", record.Reason, "action", record.Action)
+			}
+		}
+		if err == nil || errors.Is(err, ErrCollectionNotFound) || errors.Is(err, storage.ErrScrubNotSupported) {
+			continue
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return reports, err
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to scrub collection %s: %w", name, err)
+		}
+	}
+
+	return reports, firstErr
+}
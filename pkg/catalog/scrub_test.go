@@ -0,0 +1,138 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// corruptStoredVector changes the checksum of a vector record in the LevelDB
+// storage of a closed catalog
+func corruptStoredVector(t *testing.T, path, id string) {
+	t.Helper()
+
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	key := []byte("vector:" + id)
+	data, err := db.Get(key, nil)
+	if err != nil {
+		t.Fatalf("Failed to get vector %s: %v", id, err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Failed to decode vector %s: %v", id, err)
+	}
+	record["checksum"] = record["checksum"].(float64) + 1
+	if data, err = json.Marshal(record); err != nil {
+		t.Fatalf("Failed to encode vector %s: %v", id, err)
+	}
+	if err := db.Put(key, data, nil); err != nil {
+		t.Fatalf("Failed to put vector %s: %v", id, err)
+	}
+}
+
+func TestCatalog_Scrub(t *testing.T) {
+	ctx := context.Background()
+	dataPath := t.TempDir()
+	vectors := testVectors(5, 4)
+
+	cat := newTestCatalog(t, dataPath)
+	replica := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := replica.Close(); err != nil {
+			t.Errorf("Failed to close replica: %v", err)
+		}
+	}()
+	for _, c := range []*Catalog{cat, replica} {
+		if err := c.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+			t.Fatalf("Failed to create collection: %v", err)
+		}
+		if err := c.Insert(ctx, "docs", testVectors(5, 4)); err != nil {
+			t.Fatalf("Failed to insert vectors: %v", err)
+		}
+	}
+	path := cat.storagePath("docs")
+	if err := cat.Close(); err != nil {
+		t.Fatalf("Failed to close catalog: %v", err)
+	}
+
+	corruptStoredVector(t, path, "vec_1")
+	corruptStoredVector(t, path, "vec_3")
+	cat = newTestCatalog(t, dataPath)
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	report, err := cat.Scrub(ctx, "docs", storage.ScrubOptions{})
+	if err != nil {
+		t.Fatalf("Failed to scrub: %v", err)
+	}
+	if report.Checked != 5 || len(report.Corrupt) != 2 {
+		t.Fatalf("Expected 2 of 5 records to be corrupt, got %+v", report)
+	}
+
+	// vec_1 is repaired from the replica; vec_3 is quarantined after it is lost there too
+	if err := replica.DeleteVectors(ctx, "docs", []string{"vec_3"}); err != nil {
+		t.Fatalf("Failed to delete vector from replica: %v", err)
+	}
+	source, err := replica.Storage("docs")
+	if err != nil {
+		t.Fatalf("Failed to get replica storage: %v", err)
+	}
+	report, err = cat.Scrub(ctx, "docs", storage.ScrubOptions{Repair: source})
+	if err != nil {
+		t.Fatalf("Failed to repair: %v", err)
+	}
+	if report.Repaired != 1 || report.Quarantined != 2 {
+		t.Fatalf("Expected 1 repaired and 2 quarantined records, got %+v", report)
+	}
+
+	engine, err := cat.Storage("docs")
+	if err != nil {
+		t.Fatalf("Failed to get storage: %v", err)
+	}
+	stored, err := engine.Read([]string{"vec_1", "vec_3"})
+	if err != nil || len(stored) != 1 || stored[0].ID != "vec_1" || stored[0].Embedding[1] != vectors[1].Embedding[1] {
+		t.Fatalf("Expected only the repaired vector, got %+v (%v)", stored, err)
+	}
+
+	// The repaired vector is searchable; the quarantined one is gone from the count
+	results, err := cat.Search(ctx, "docs", vectors[1].Embedding, 1)
+	if err != nil || len(results) != 1 || results[0].Vector.ID != "vec_1" {
+		t.Errorf("Expected to find the repaired vector, got %+v (%v)", results, err)
+	}
+	if collection, _ := cat.Get("docs"); collection.Count != 4 {
+		t.Errorf("Expected 4 vectors after quarantine, got %d", collection.Count)
+	}
+
+	// The background scrubber finds nothing left to do
+	scrubber, err := NewScrubber(cat, ScrubberConfig{Interval: time.Hour, Quarantine: true}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create scrubber: %v", err)
+	}
+	reports, err := scrubber.RunOnce(ctx)
+	if err != nil || len(reports) != 1 || len(reports["docs"].Corrupt) != 0 || reports["docs"].Checked != 4 {
+		t.Errorf("Expected a clean scrub, got %+v (%v)", reports, err)
+	}
+
+	if _, err := NewScrubber(cat, ScrubberConfig{}, nil); !errors.Is(err, ErrInvalidScrubberConfig) {
+		t.Errorf("Expected ErrInvalidScrubberConfig, got %v", err)
+	}
+}
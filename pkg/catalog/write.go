@@ -0,0 +1,346 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+)
+
+// WriteKind identifies the kind of a write of a batch
+type WriteKind string
+
+// WriteKind constants define the writes a batch can hold
+const (
+	// WritePut inserts a vector, or replaces the stored vector with the same ID
+	WritePut WriteKind = "put"
+
+	// WriteDelete deletes the vector with an ID when it is stored
+	WriteDelete WriteKind = "delete"
+)
+
+// Write is one write of a batch applied by Catalog.Write
+type Write struct {
+	Kind WriteKind
+
+	// Vector is the vector written by a put
+	Vector *core.Vector
+
+	// ID is the ID of the vector removed by a delete
+	ID string
+
+	// ExpectedVersion, when set, is the version the vector must be at for the
+	// batch to apply: zero for a vector that must not be stored yet, or the
+	// version read before an update or delete
+	ExpectedVersion *uint64
+}
+
+// WriteResult reports a batch applied by Catalog.Write
+type WriteResult struct {
+	// Position is the change log position of the batch; zero when the
+	// batch changed nothing
+	Position uint64 `json:"position"`
+
+	// Versions holds the new version of every vector the batch put, by ID
+	Versions map[string]uint64 `json:"versions"`
+
+	// Deleted lists the IDs of the stored vectors the batch deleted
+	Deleted []string `json:"deleted"`
+}
+
+// VersionConflictError reports a write whose expected version did not match
+// the version of the vector. It matches ErrVersionConflict with errors.Is.
+type VersionConflictError struct {
+	ID       string
+	Expected uint64
+
+	// Actual is the version of the vector; zero when it is not stored
+	Actual uint64
+}
+
+// Error implements the error interface
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%v: vector %s is at version %d, expected %d", ErrVersionConflict, e.ID, e.Actual, e.Expected)
+}
+
+// Is reports whether target is ErrVersionConflict
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// Write applies a batch of puts and deletes to the named collection as a
+// unit: either every write reaches storage, the index and the change log, or
+// a failing batch is rolled back and none does. A crash while a batch is
+// applied can leave part of it in storage and all of it out of the change
+// log; the index is rebuilt from storage on open. Writes apply in order, so a
+// write sees the earlier writes of the batch to the same ID. Each put gives
+// its vector the next version; vectors stored before versions were kept are
+// at version 1. When an expected version does not match, nothing is applied
+// and the error is a *VersionConflictError.
+func (c *Catalog) Write(ctx context.Context, name string, writes []Write) (*WriteResult, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, err := c.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.applyWrites(ctx, e, writes)
+}
+
+// storedVersion returns the version of a stored vector, or zero for none
+func storedVersion(vector *core.Vector) uint64 {
+	if vector == nil {
+		return 0
+	}
+	if vector.Version == 0 {
+		return 1
+	}
+	return vector.Version
+}
+
+// applyWrites applies a batch of writes to a collection and undoes what it
+// applied when a step fails; the caller must hold the write lock
+func (c *Catalog) applyWrites(ctx context.Context, e *entry, writes []Write) (*WriteResult, error) {
+	name := e.spec.Collection.Name
+	dimension := e.spec.Collection.Dimension
+
+	// ids holds every ID of the batch once, in the order of their first write
+	var ids []string
+	seen := make(map[string]bool, len(writes))
+	for i, write := range writes {
+		id := write.ID
+		switch write.Kind {
+		case WritePut:
+			if write.Vector == nil || write.Vector.ID == "" {
+				return nil, fmt.Errorf("%w: put %d has no vector ID", ErrInvalidWrite, i)
+			}
+			id = write.Vector.ID
+			if write.Vector.Deleted {
+				return nil, fmt.Errorf("%w: put %d is a tombstone", ErrInvalidWrite, i)
+			}
+			if len(write.Vector.Embedding) != dimension {
+				return nil, fmt.Errorf("%w: vector %s has dimension %d, collection %s expects %d",
+					ErrDimensionMismatch, id, len(write.Vector.Embedding), name, dimension)
+			}
+		case WriteDelete:
+			if id == "" {
+				return nil, fmt.Errorf("%w: delete %d has no ID", ErrInvalidWrite, i)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidWrite, write.Kind)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return &WriteResult{Versions: map[string]uint64{}}, nil
+	}
+
+	stored, err := e.storage.ReadWithContext(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection %s: %w", name, err)
+	}
+	before := make(map[string]*core.Vector, len(stored))
+	for _, vector := range stored {
+		before[vector.ID] = vector
+	}
+
+	// Work out the state the batch leaves every vector in
+	now := time.Now()
+	after := make(map[string]*core.Vector, len(ids))
+	for id, vector := range before {
+		after[id] = vector
+	}
+	for _, write := range writes {
+		id := write.ID
+		if write.Kind == WritePut {
+			id = write.Vector.ID
+		}
+		version := storedVersion(after[id])
+		if write.ExpectedVersion != nil && *write.ExpectedVersion != version {
+			return nil, &VersionConflictError{ID: id, Expected: *write.ExpectedVersion, Actual: version}
+		}
+
+		if write.Kind == WriteDelete {
+			after[id] = nil
+			continue
+		}
+		vector := write.Vector
+		vector.Collection = name
+		vector.Dimension = dimension
+		if vector.UpdatedAt.IsZero() {
+			// UpdatedAt is the version key of the storage's version history
+			vector.UpdatedAt = now
+		}
+		vector.Version = version + 1
+		after[id] = vector
+	}
+
+	var puts []*core.Vector
+	var deletes, replaced, missing []string
+	for _, id := range ids {
+		switch vector, previous := after[id], before[id]; {
+		case vector != nil && vector != previous:
+			puts = append(puts, vector)
+			if previous != nil {
+				replaced = append(replaced, id)
+			}
+		case vector == nil && previous != nil:
+			deletes = append(deletes, id)
+		case vector == nil:
+			missing = append(missing, id)
+		}
+	}
+
+	result := &WriteResult{Versions: make(map[string]uint64, len(puts)), Deleted: deletes}
+	for _, vector := range puts {
+		result.Versions[vector.ID] = vector.Version
+	}
+	if len(puts) == 0 && len(deletes) == 0 {
+		return result, nil
+	}
+
+	undo := &writeUndo{e: e, before: before}
+	if err := applyToStorage(ctx, e, puts, deletes, undo); err != nil {
+		return nil, err
+	}
+	if err := applyToIndex(e, puts, deletes, before, undo); err != nil {
+		return nil, err
+	}
+
+	// Batches of one kind keep the records of plain inserts and deletes
+	record := &Record{Op: OpWrite, Collection: name, Vectors: puts, Replaced: replaced}
+	if len(deletes) > 0 {
+		record.IDs = append(append(record.IDs, deletes...), missing...)
+		record.Missing = missing
+	}
+	switch {
+	case len(deletes) == 0:
+		record.Op = OpInsert
+	case len(puts) == 0:
+		record.Op = OpDelete
+	}
+	if err := c.log.append(record); err != nil {
+		return nil, undo.rollback(ctx, err)
+	}
+	result.Position = record.Position
+
+	e.spec.Collection.Count += int64(len(puts)-len(replaced)) - int64(len(deletes))
+	if e.spec.Collection.Count < 0 {
+		e.spec.Collection.Count = 0
+	}
+	e.spec.Collection.UpdatedAt = time.Now()
+
+	// The counts reach the catalog file at the next checkpoint or on close,
+	// and are taken from storage on open
+	c.checkpointIfDue()
+	return result, nil
+}
+
+// applyToStorage writes puts and deletes to the storage of a collection
+func applyToStorage(ctx context.Context, e *entry, puts []*core.Vector, deletes []string, undo *writeUndo) error {
+	name := e.spec.Collection.Name
+	if len(puts) > 0 {
+		undo.stored = append(undo.stored, vectorIDs(puts)...)
+		if err := e.storage.WriteWithContext(ctx, puts); err != nil {
+			return undo.rollback(ctx, fmt.Errorf("failed to write vectors to collection %s: %w", name, err))
+		}
+	}
+	if len(deletes) > 0 {
+		undo.stored = append(undo.stored, deletes...)
+		if err := e.storage.DeleteWithContext(ctx, deletes); err != nil {
+			return undo.rollback(ctx, fmt.Errorf("failed to delete vectors from collection %s: %w", name, err))
+		}
+	}
+	return nil
+}
+
+// applyToIndex applies puts and deletes to the index of a collection
+func applyToIndex(e *entry, puts []*core.Vector, deletes []string, before map[string]*core.Vector, undo *writeUndo) error {
+	for _, vector := range puts {
+		undo.indexed = append(undo.indexed, vector.ID)
+		if before[vector.ID] != nil {
+			if err := e.index.Delete(vector.ID); err != nil && !errors.Is(err, index.ErrVectorNotFound) {
+				return undo.rollback(context.Background(), fmt.Errorf("failed to replace vector %s in index: %w", vector.ID, err))
+			}
+		}
+		if err := e.index.Insert(vector); err != nil {
+			return undo.rollback(context.Background(), fmt.Errorf("failed to index vector %s: %w", vector.ID, err))
+		}
+	}
+	for _, id := range deletes {
+		undo.indexed = append(undo.indexed, id)
+		if err := e.index.Delete(id); err != nil && !errors.Is(err, index.ErrVectorNotFound) {
+			return undo.rollback(context.Background(), fmt.Errorf("failed to remove vector %s from index: %w", id, err))
+		}
+	}
+	return nil
+}
+
+// writeUndo records what a batch applied so far, to put back the vectors it
+// changed when a later step fails
+type writeUndo struct {
+	e       *entry
+	before  map[string]*core.Vector
+	stored  []string // IDs written to or deleted from storage
+	indexed []string // IDs written to or deleted from the index
+}
+
+// rollback restores the vectors changed by the batch in storage and the index
+// and returns cause, together with any failure to restore them
+func (u *writeUndo) rollback(ctx context.Context, cause error) error {
+	// Restoring must not stop because the batch was cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	var restore []*core.Vector
+	var remove []string
+	for _, id := range u.stored {
+		if previous := u.before[id]; previous != nil {
+			restore = append(restore, previous)
+		} else {
+			remove = append(remove, id)
+		}
+	}
+
+	errs := []error{cause}
+	if len(restore) > 0 {
+		if err := u.e.storage.WriteWithContext(ctx, restore); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore vectors: %w", err))
+		}
+	}
+	if len(remove) > 0 {
+		if err := u.e.storage.DeleteWithContext(ctx, remove); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove written vectors: %w", err))
+		}
+	}
+
+	for _, id := range u.indexed {
+		if err := u.e.index.Delete(id); err != nil && !errors.Is(err, index.ErrVectorNotFound) {
+			errs = append(errs, fmt.Errorf("failed to remove vector %s from index: %w", id, err))
+			continue
+		}
+		if previous := u.before[id]; previous != nil {
+			if err := u.e.index.Insert(previous); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore vector %s in index: %w", id, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// vectorIDs returns the IDs of vectors
+func vectorIDs(vectors []*core.Vector) []string {
+	ids := make([]string, len(vectors))
+	for i, vector := range vectors {
+		ids[i] = vector.ID
+	}
+	return ids
+}
@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+)
+
+func expect(version uint64) *uint64 {
+	return &version
+}
+
+func TestCatalog_Write(t *testing.T) {
+	ctx := context.Background()
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	if err := cat.Create(core.NewCollection("docs", "", 4, "hnsw")); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	vectors := testVectors(4, 4)
+	if err := cat.Insert(ctx, "docs", vectors[:2]); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	if vectors[0].Version != 1 {
+		t.Fatalf("Expected inserted vectors at version 1, got %d", vectors[0].Version)
+	}
+
+	// Inserts, updates and deletes apply together
+	updated := &core.Vector{ID: "vec_0", Embedding: []float64{1, 1, 1, 1}}
+	result, err := cat.Write(ctx, "docs", []Write{
+		{Kind: WritePut, Vector: vectors[2], ExpectedVersion: expect(0)},
+		{Kind: WritePut, Vector: updated, ExpectedVersion: expect(1)},
+		{Kind: WriteDelete, ID: "vec_1", ExpectedVersion: expect(1)},
+		{Kind: WriteDelete, ID: "missing"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	if result.Versions["vec_2"] != 1 || result.Versions["vec_0"] != 2 || len(result.Deleted) != 1 || result.Position != cat.Position() {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	engine, err := cat.Storage("docs")
+	if err != nil {
+		t.Fatalf("Failed to get storage: %v", err)
+	}
+	stored, err := engine.Read([]string{"vec_0", "vec_1", "vec_2"})
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("Expected vec_0 and vec_2 to be stored, got %+v (%v)", stored, err)
+	}
+	for _, vector := range stored {
+		if vector.ID == "vec_0" && (vector.Version != 2 || vector.Embedding[0] != 1) {
+			t.Errorf("Expected the update of vec_0 at version 2, got %+v", vector)
+		}
+	}
+	if collection, _ := cat.Get("docs"); collection.Count != 2 {
+		t.Errorf("Expected 2 vectors, got %d", collection.Count)
+	}
+
+	// The batch is one mutation for change subscribers
+	events := collectChanges(t, cat, ChangeOptions{After: result.Position - 1})
+	if len(events) != 3 || events[0].Type != ChangeInsert || events[1].Type != ChangeUpdate || events[2].Type != ChangeDelete {
+		t.Errorf("Expected an insert, an update and a delete, got %+v", events)
+	}
+
+	// A stale expected version rejects the whole batch
+	position := cat.Position()
+	_, err = cat.Write(ctx, "docs", []Write{
+		{Kind: WritePut, Vector: vectors[3]},
+		{Kind: WritePut, Vector: &core.Vector{ID: "vec_0", Embedding: []float64{2, 2, 2, 2}}, ExpectedVersion: expect(1)},
+	})
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrVersionConflict) || conflict.ID != "vec_0" || conflict.Actual != 2 {
+		t.Fatalf("Expected a version conflict on vec_0, got %v", err)
+	}
+	if _, err := cat.Write(ctx, "docs", []Write{{Kind: WritePut, Vector: vectors[2], ExpectedVersion: expect(0)}}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected inserting a stored vector to conflict, got %v", err)
+	}
+	if stored, _ := engine.Read([]string{"vec_3"}); len(stored) != 0 || cat.Position() != position {
+		t.Errorf("Expected a rejected batch to change nothing, got %+v at %d", stored, cat.Position())
+	}
+
+	// Writes of a batch see the earlier writes to the same ID
+	result, err = cat.Write(ctx, "docs", []Write{
+		{Kind: WritePut, Vector: vectors[3], ExpectedVersion: expect(0)},
+		{Kind: WritePut, Vector: &core.Vector{ID: "vec_3", Embedding: []float64{3, 3, 3, 3}}, ExpectedVersion: expect(1)},
+	})
+	if err != nil || result.Versions["vec_3"] != 2 {
+		t.Errorf("Expected vec_3 at version 2, got %+v (%v)", result, err)
+	}
+
+	if _, err := cat.Write(ctx, "docs", []Write{{Kind: WriteDelete}}); !errors.Is(err, ErrInvalidWrite) {
+		t.Errorf("Expected ErrInvalidWrite, got %v", err)
+	}
+}
+
+func TestCatalog_WriteRollback(t *testing.T) {
+	ctx := context.Background()
+	cat := newTestCatalog(t, t.TempDir())
+	defer func() {
+		if err := cat.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	}()
+
+	config := DefaultIndexConfig(index.IndexTypeHNSW, 4, "")
+	config.MaxElements = 2
+	if err := cat.CreateWithIndex(core.NewCollection("docs", "", 4, "hnsw"), config); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	vectors := testVectors(3, 4)
+	if err := cat.Insert(ctx, "docs", vectors[:1]); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	position := cat.Position()
+
+	// The index fills up halfway through the batch
+	replacement := &core.Vector{ID: "vec_0", Embedding: []float64{9, 9, 9, 9}}
+	_, err := cat.Write(ctx, "docs", []Write{
+		{Kind: WritePut, Vector: vectors[1]},
+		{Kind: WritePut, Vector: vectors[2]},
+		{Kind: WritePut, Vector: replacement},
+	})
+	if !errors.Is(err, index.ErrIndexFull) {
+		t.Fatalf("Expected the batch to fail on a full index, got %v", err)
+	}
+
+	engine, err := cat.Storage("docs")
+	if err != nil {
+		t.Fatalf("Failed to get storage: %v", err)
+	}
+	stored, err := engine.Read([]string{"vec_0", "vec_1", "vec_2"})
+	if err != nil || len(stored) != 1 || stored[0].Embedding[0] != vectors[0].Embedding[0] || stored[0].Version != 1 {
+		t.Errorf("Expected storage to hold only the original vec_0, got %+v (%v)", stored, err)
+	}
+	if cat.Position() != position {
+		t.Errorf("Expected a failed batch not to be logged, position moved from %d to %d", position, cat.Position())
+	}
+	if collection, _ := cat.Get("docs"); collection.Count != 1 {
+		t.Errorf("Expected 1 vector after the rollback, got %d", collection.Count)
+	}
+}
@@ -0,0 +1,270 @@
+// Package client is the Go client of the vjvector REST API described by
+// docs/api/openapi.yaml. It authenticates with an API key, retries failed
+// requests with jittered exponential backoff and decodes the errors of the API
+// into *APIError values matching the sentinel errors of this package.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of the requests of a client
+const (
+	APIKeyHeader   = "X-API-Key"
+	TenantIDHeader = "X-Tenant-ID"
+)
+
+// Media types of the bodies of requests and responses
+const (
+	mimeJSON   = "application/json"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+// Config configures a client
+type Config struct {
+	// BaseURL is the URL of the server, such as http://localhost:8080
+	BaseURL string
+
+	// APIKey authenticates the requests; empty when the server does not
+	// require authentication
+	APIKey string
+
+	// TenantID names the tenant of the requests, for keys that act for several
+	// tenants such as the admin key; empty for the tenant of the key
+	TenantID string
+
+	// HTTPClient sends the requests; nil selects a client without timeout,
+	// since requests are bounded by their context
+	HTTPClient *http.Client
+
+	// MaxRetries is the number of times a failed request is retried; zero
+	// disables retries
+	MaxRetries int
+
+	// InitialBackoff is the wait before the first retry, doubled for every
+	// retry after it up to MaxBackoff. A random half of each wait is waited,
+	// so that clients failing together do not retry together.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// UserAgent is sent with every request
+	UserAgent string
+}
+
+// DefaultConfig returns the default configuration of a client of the server
+// at baseURL
+func DefaultConfig(baseURL string) Config {
+	return Config{
+		BaseURL:        baseURL,
+		MaxRetries:     3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		UserAgent:      "vjvector-go-client",
+	}
+}
+
+// Client is a client of the vjvector REST API. It is safe for concurrent use.
+type Client struct {
+	config  Config
+	baseURL *url.URL
+	http    *http.Client
+}
+
+// New creates a client
+func New(config Config) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(config.BaseURL, "/"))
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("%w: base URL %q is not an absolute URL", ErrInvalidConfig, config.BaseURL)
+	}
+	if config.MaxRetries < 0 || config.InitialBackoff < 0 || config.MaxBackoff < config.InitialBackoff {
+		return nil, fmt.Errorf("%w: retries and backoffs must not be negative, nor the maximum backoff below the initial one",
+			ErrInvalidConfig)
+	}
+
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &Client{config: config, baseURL: baseURL, http: httpClient}, nil
+}
+
+// Health reports the health of the server
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	var response HealthResponse
+	if err := c.do(ctx, http.MethodGet, "/health", nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// do sends a JSON request and decodes its JSON response into out, unless out
+// is nil
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}, idempotent bool) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	response, err := c.open(ctx, method, path, data, idempotent)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(response, out)
+}
+
+// open sends a JSON request and returns its response once the server accepted
+// it. Requests are retried when the server throttled or did not take them,
+// and idempotent requests also on network and server errors.
+func (c *Client) open(ctx context.Context, method, path string, data []byte, idempotent bool) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		response, err := c.send(ctx, method, path, body, mimeJSON)
+		if err == nil || attempt >= c.config.MaxRetries || !retryable(err, idempotent) {
+			return response, err
+		}
+		if err := c.wait(ctx, attempt+1, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send sends a request with the headers of the client. Responses with an
+// error status are closed and returned as *APIError.
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	request.Header.Set("Accept", mimeJSON)
+	if c.config.APIKey != "" {
+		request.Header.Set(APIKeyHeader, c.config.APIKey)
+	}
+	if c.config.TenantID != "" {
+		request.Header.Set(TenantIDHeader, c.config.TenantID)
+	}
+	if c.config.UserAgent != "" {
+		request.Header.Set("User-Agent", c.config.UserAgent)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+	defer response.Body.Close()
+	return nil, decodeError(response)
+}
+
+// decodeResponse decodes the JSON body of a response into out and closes it
+func decodeResponse(response *http.Response, out interface{}) error {
+	defer response.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, response.Body)
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeError decodes the error envelope of a response with an error status
+// into an *APIError
+func decodeError(response *http.Response) error {
+	apiErr := &APIError{StatusCode: response.StatusCode}
+	var envelope errorResponse
+	data, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Error != "" {
+		apiErr.Message = envelope.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// retryable reports whether a failed request is worth retrying: throttled
+// requests and those the server was not ready to take always are, while
+// network and other server errors are only for idempotent requests, since the
+// server may have applied them
+func retryable(err error, idempotent bool) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Cancellation is the choice of the caller; other errors are network errors
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		return idempotent
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusRequestTimeout, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// wait waits before a retry: the backoff of the retry, or longer when the
+// server asked for it, or until ctx is done
+func (c *Client) wait(ctx context.Context, retry int, err error) error {
+	wait := c.backoff(retry)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		wait = max(wait, apiErr.RetryAfter)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns the wait before a retry: the initial backoff doubled for
+// every earlier retry up to the maximum, of which a random half is waited
+func (c *Client) backoff(retry int) time.Duration {
+	wait := c.config.InitialBackoff
+	for i := 1; i < retry && wait < c.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, c.config.MaxBackoff)
+	if wait < 2 {
+		return wait
+	}
+	return wait/2 + rand.N(wait/2)
+}
+
+// indexPath returns the path of a resource of an index
+func indexPath(indexID string, resource ...string) string {
+	return "/v1/indexes/" + strings.Join(append([]string{url.PathEscape(indexID)}, resource...), "/")
+}
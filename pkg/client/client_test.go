@@ -0,0 +1,320 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vijaynallagatla/vjvector/internal/api"
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding/providers"
+)
+
+// testDimension is the dimension of the indexes of the tests
+const testDimension = 16
+
+// testAdminKey is the bootstrap admin key of servers requiring authentication
+const testAdminKey = "test-admin-key"
+
+// testDocuments are the texts stored in the index of the tests
+var testDocuments = map[string]string{
+	"leveldb": "leveldb stores sorted keys on disk",
+	"hnsw":    "hnsw graphs find approximate nearest neighbors",
+	"raft":    "raft replicates a log across nodes",
+}
+
+// newTestServer serves the API over a fresh catalog, requiring adminKey on
+// every request unless it is empty
+func newTestServer(t *testing.T, adminKey string) *httptest.Server {
+	t.Helper()
+
+	collections, err := catalog.New(catalog.DefaultConfig(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create catalog: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := collections.Close(); err != nil {
+			t.Errorf("Failed to close catalog: %v", err)
+		}
+	})
+
+	handlers := api.NewHandlers(collections)
+	t.Cleanup(func() { _ = handlers.Close() })
+	provider, err := providers.NewHashProvider(testDimension)
+	if err != nil {
+		t.Fatalf("Failed to create embedding provider: %v", err)
+	}
+	if err := handlers.SetEmbeddingProvider(provider); err != nil {
+		t.Fatalf("Failed to set embedding provider: %v", err)
+	}
+	handlers.RebuildFinished(nil)
+	if adminKey != "" {
+		handlers.EnableAuth(adminKey)
+	}
+	e := echo.New()
+	handlers.RegisterRoutes(e)
+
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestClient creates a client of a server that does not wait between
+// retries
+func newTestClient(t *testing.T, baseURL, apiKey string) *Client {
+	t.Helper()
+
+	config := DefaultConfig(baseURL)
+	config.APIKey = apiKey
+	config.InitialBackoff = time.Millisecond
+	config.MaxBackoff = time.Millisecond
+	client, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+// embedText embeds a text as the server embeds the queries of the tests
+func embedText(t *testing.T, text string) []float64 {
+	t.Helper()
+
+	provider, err := providers.NewHashProvider(testDimension)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	response, err := provider.GenerateEmbeddings(context.Background(), &embedding.EmbeddingRequest{Texts: []string{text}})
+	if err != nil {
+		t.Fatalf("Failed to embed text: %v", err)
+	}
+	return response.Embeddings[0]
+}
+
+// createDocsIndex creates the docs index holding the test documents
+func createDocsIndex(t *testing.T, client *Client) {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := client.CreateIndex(ctx, &CreateIndexRequest{
+		ID: "docs", Type: "hnsw", Dimension: testDimension, MaxElements: 100,
+	}); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	vectors := make([]*Vector, 0, len(testDocuments))
+	for id, text := range testDocuments {
+		vectors = append(vectors, &Vector{ID: id, Embedding: embedText(t, text), Metadata: map[string]interface{}{"text": text}})
+	}
+	if _, err := client.InsertVectors(ctx, "docs", vectors); err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	for name, config := range map[string]Config{
+		"relative URL":     DefaultConfig("localhost:8080"),
+		"negative retries": {BaseURL: "http://localhost:8080", MaxRetries: -1},
+		"backoffs":         {BaseURL: "http://localhost:8080", InitialBackoff: time.Second, MaxBackoff: time.Millisecond},
+	} {
+		if _, err := New(config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: expected ErrInvalidConfig, got %v", name, err)
+		}
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	server := newTestServer(t, "")
+	client := newTestClient(t, server.URL, "")
+	ctx := context.Background()
+
+	health, err := client.Health(ctx)
+	if err != nil || health.Status != "healthy" {
+		t.Fatalf("Expected a healthy server, got %+v, %v", health, err)
+	}
+
+	created, err := client.CreateIndex(ctx, &CreateIndexRequest{ID: "docs", Type: "hnsw", Dimension: testDimension, MaxElements: 100})
+	if err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+	if created.ID != "docs" || created.Config.Dimension != testDimension {
+		t.Errorf("Unexpected created index: %+v", created)
+	}
+
+	inserted, err := client.InsertVectors(ctx, "docs", []*Vector{
+		{ID: "leveldb", Embedding: embedText(t, testDocuments["leveldb"])},
+		{ID: "hnsw", Embedding: embedText(t, testDocuments["hnsw"])},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+	if inserted.VectorsAdded != 2 || inserted.Versions["leveldb"] == 0 {
+		t.Errorf("Unexpected insert: %+v", inserted)
+	}
+
+	version := inserted.Versions["hnsw"]
+	written, err := client.WriteBatch(ctx, "docs", []*WriteOperation{
+		{Op: WritePut, Vector: &Vector{ID: "raft", Embedding: embedText(t, testDocuments["raft"])}},
+		{Op: WriteDelete, ID: "hnsw", ExpectedVersion: &version},
+	})
+	if err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+	if written.TotalVectors != 2 || len(written.Deleted) != 1 {
+		t.Errorf("Unexpected batch: %+v", written)
+	}
+
+	search, err := client.Search(ctx, "docs", &SearchRequest{Query: embedText(t, testDocuments["raft"]), K: 1})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(search.Results) != 1 || search.Results[0].VectorID != "raft" {
+		t.Errorf("Expected raft to be nearest, got %+v", search.Results)
+	}
+
+	info, err := client.GetIndex(ctx, "docs")
+	if err != nil || info.TotalVectors != 2 {
+		t.Errorf("Expected 2 vectors, got %+v, %v", info, err)
+	}
+	list, err := client.ListIndexes(ctx)
+	if err != nil || list.Count != 1 {
+		t.Errorf("Expected 1 index, got %+v, %v", list, err)
+	}
+	if _, err := client.DeleteIndex(ctx, "docs"); err != nil {
+		t.Fatalf("Failed to delete index: %v", err)
+	}
+}
+
+func TestErrorsAreTyped(t *testing.T) {
+	server := newTestServer(t, "")
+	client := newTestClient(t, server.URL, "")
+	ctx := context.Background()
+
+	_, err := client.GetIndex(ctx, "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message == "" {
+		t.Errorf("Expected an API error with a message, got %#v", err)
+	}
+
+	createDocsIndex(t, client)
+	if _, err := client.CreateIndex(ctx, &CreateIndexRequest{ID: "docs", Type: "hnsw", Dimension: testDimension, MaxElements: 100}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for an existing index, got %v", err)
+	}
+	stale := uint64(0)
+	if _, err := client.WriteBatch(ctx, "docs", []*WriteOperation{
+		{Op: WritePut, Vector: &Vector{ID: "raft", Embedding: embedText(t, "raft")}, ExpectedVersion: &stale},
+	}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for a stale version, got %v", err)
+	}
+	if _, err := client.Search(ctx, "docs", &SearchRequest{Query: []float64{1}, K: 1}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected ErrInvalidRequest for a query of the wrong dimension, got %v", err)
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	server := newTestServer(t, testAdminKey)
+	ctx := context.Background()
+
+	if _, err := newTestClient(t, server.URL, "").ListIndexes(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized without a key, got %v", err)
+	}
+	if _, err := newTestClient(t, server.URL, "wrong").ListIndexes(ctx); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized with an unknown key, got %v", err)
+	}
+	if _, err := newTestClient(t, server.URL, testAdminKey).ListIndexes(ctx); err != nil {
+		t.Errorf("Expected the admin key to be accepted, got %v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"error":"slow down","status":429,"success":false}`, http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, `{"error":"rebuilding","status":503,"success":false}`, http.StatusServiceUnavailable)
+		default:
+			w.Header().Set("Content-Type", mimeJSON)
+			_, _ = w.Write([]byte(`{"indexes":[],"count":0}`))
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(t, server.URL, "")
+
+	if _, err := client.ListIndexes(context.Background()); err != nil {
+		t.Fatalf("Expected the request to succeed after retries, got %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("Expected 3 requests, got %d", got)
+	}
+}
+
+func TestRetriesSkipUnsafeRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, `{"error":"bad gateway","status":502,"success":false}`, http.StatusBadGateway)
+	}))
+	defer server.Close()
+	client := newTestClient(t, server.URL, "")
+	ctx := context.Background()
+
+	// The server may have applied an insert that failed with a server error
+	if _, err := client.InsertVectors(ctx, "docs", []*Vector{{ID: "a"}}); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Expected ErrServerError, got %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected the insert not to be retried, got %d requests", got)
+	}
+
+	requests.Store(0)
+	if _, err := client.GetIndex(ctx, "docs"); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Expected ErrServerError, got %v", err)
+	}
+	if got, want := requests.Load(), int32(1+client.config.MaxRetries); got != want {
+		t.Errorf("Expected %d requests of an idempotent request, got %d", want, got)
+	}
+}
+
+func TestRetriesStopWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, `{"error":"slow down","status":429,"success":false}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := newTestClient(t, server.URL, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.ListIndexes(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to stop the retries, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the retry to be abandoned, took %v", elapsed)
+	}
+}
+
+func TestBackoffIsBoundedAndJittered(t *testing.T) {
+	client, err := New(Config{BaseURL: "http://localhost", InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	for retry := 1; retry <= 8; retry++ {
+		ceiling := min(100*time.Millisecond<<(retry-1), time.Second)
+		for range 20 {
+			if wait := client.backoff(retry); wait < ceiling/2 || wait > ceiling {
+				t.Fatalf("Retry %d waits %v, outside [%v, %v]", retry, wait, ceiling/2, ceiling)
+			}
+		}
+	}
+}
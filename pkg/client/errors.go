@@ -0,0 +1,116 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrInvalidConfig is returned when the configuration of a client is invalid
+	ErrInvalidConfig = errors.New("invalid client config")
+
+	// ErrInvalidRequest is matched by API errors with status 400
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrUnauthorized is matched by API errors with status 401
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden is matched by API errors with status 403
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrNotFound is matched by API errors with status 404
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is matched by API errors with status 409
+	ErrConflict = errors.New("conflict")
+
+	// ErrRateLimited is matched by API errors with status 429
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrServerError is matched by API errors with a 5xx status
+	ErrServerError = errors.New("server error")
+
+	// ErrUnavailable is matched by API errors with status 503
+	ErrUnavailable = errors.New("service unavailable")
+
+	// ErrTimeout is matched by API errors with status 504
+	ErrTimeout = errors.New("server timeout")
+
+	// ErrStreamClosed is returned when writing to a stream that was closed
+	ErrStreamClosed = errors.New("stream closed")
+
+	// ErrIncompleteStream is returned when a stream ends without its last event
+	ErrIncompleteStream = errors.New("incomplete stream")
+)
+
+// APIError is an error reported by the API, decoded from the standard error
+// envelope. It matches the sentinel error of its status with errors.Is, such
+// as ErrNotFound for 404.
+type APIError struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+
+	// Message is the error message of the server
+	Message string
+
+	// Code names the kind of an error reported within a stream, such as
+	// not_found; it is empty for errors reported by status
+	Code string
+
+	// RetryAfter is how long the server asked to wait before retrying, from
+	// the Retry-After header of rate limited responses
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("vjvector: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("vjvector: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether target is the sentinel error of the status of e
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrInvalidRequest
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusForbidden:
+		return target == ErrForbidden
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusServiceUnavailable:
+		return target == ErrUnavailable || target == ErrServerError
+	case http.StatusGatewayTimeout:
+		return target == ErrTimeout || target == ErrServerError
+	}
+	return e.StatusCode >= 500 && target == ErrServerError
+}
+
+// codeStatuses maps the error codes of stream events to the statuses the API
+// reports the same errors with
+var codeStatuses = map[string]int{
+	"invalid_request": http.StatusBadRequest,
+	"not_found":       http.StatusNotFound,
+	"conflict":        http.StatusConflict,
+	"rate_limited":    http.StatusTooManyRequests,
+	"timeout":         http.StatusGatewayTimeout,
+	"internal_error":  http.StatusInternalServerError,
+}
+
+// streamError returns the API error of an error event of a stream
+func streamError(code, message string) *APIError {
+	status, known := codeStatuses[code]
+	if !known {
+		status = http.StatusInternalServerError
+	}
+	return &APIError{StatusCode: status, Message: message, Code: code}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateIndex creates an index. Creating an index is not retried on network
+// errors, since the index may have been created; retrying it then fails with
+// ErrConflict.
+func (c *Client) CreateIndex(ctx context.Context, req *CreateIndexRequest) (*CreateIndexResponse, error) {
+	var response CreateIndexResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/indexes", req, &response, false); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ListIndexes lists the indexes of the tenant of the client
+func (c *Client) ListIndexes(ctx context.Context) (*ListIndexesResponse, error) {
+	var response ListIndexesResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/indexes", nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetIndex returns an index with its statistics
+func (c *Client) GetIndex(ctx context.Context, indexID string) (*IndexInfo, error) {
+	var response IndexInfo
+	if err := c.do(ctx, http.MethodGet, indexPath(indexID), nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteIndex deletes an index with its vectors
+func (c *Client) DeleteIndex(ctx context.Context, indexID string) (*DeleteIndexResponse, error) {
+	var response DeleteIndexResponse
+	if err := c.do(ctx, http.MethodDelete, indexPath(indexID), nil, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// InsertVectors inserts vectors into an index, or replaces those with the
+// same IDs, all of them or none
+func (c *Client) InsertVectors(ctx context.Context, indexID string, vectors []*Vector) (*InsertVectorsResponse, error) {
+	var response InsertVectorsResponse
+	body := map[string]interface{}{"vectors": vectors}
+	if err := c.do(ctx, http.MethodPost, indexPath(indexID, "vectors"), body, &response, false); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// WriteBatch applies puts and deletes to an index atomically, in order. A
+// write whose expected version does not match fails the batch with
+// ErrConflict.
+func (c *Client) WriteBatch(ctx context.Context, indexID string, writes []*WriteOperation) (*WriteBatchResponse, error) {
+	var response WriteBatchResponse
+	body := map[string]interface{}{"writes": writes}
+	if err := c.do(ctx, http.MethodPost, indexPath(indexID, "batch"), body, &response, false); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Search finds the vectors of an index nearest to a query embedding
+func (c *Client) Search(ctx context.Context, indexID string, req *SearchRequest) (*SearchResponse, error) {
+	var response SearchResponse
+	if err := c.do(ctx, http.MethodPost, indexPath(indexID, "search"), req, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
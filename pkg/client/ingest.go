@@ -0,0 +1,139 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// IngestOptions configure an ingest stream
+type IngestOptions struct {
+	// ChunkSize is the number of records the server writes at once; zero
+	// selects the default of the server
+	ChunkSize int
+
+	// Embed has the server embed the text of records without an embedding
+	Embed bool
+
+	// OnResult is called with the result of every record as soon as the
+	// server reports it, on the goroutine reading the response
+	OnResult func(result IngestResult)
+}
+
+// IngestStream streams records into an index. Records are sent while the
+// server writes the earlier ones, and Send blocks while the index falls
+// behind, so that arbitrarily many records take bounded memory on both ends.
+// An IngestStream is not safe for concurrent use.
+type IngestStream struct {
+	writer  *io.PipeWriter
+	encoder *json.Encoder
+	done    chan struct{}
+
+	// summary and err are set by the goroutine reading the response before
+	// done is closed
+	summary *IngestSummary
+	err     error
+}
+
+// Ingest opens a stream of records into an index. Records are written in
+// chunks, each chunk atomically unless one of its records fails, in which case
+// the others are written one by one; a failed record does not end the stream.
+// Streams are not retried, since their records cannot be sent again.
+func (c *Client) Ingest(ctx context.Context, indexID string, options *IngestOptions) (*IngestStream, error) {
+	if options == nil {
+		options = &IngestOptions{}
+	}
+	query := url.Values{}
+	if options.ChunkSize > 0 {
+		query.Set("chunk_size", strconv.Itoa(options.ChunkSize))
+	}
+	if options.Embed {
+		query.Set("embed", "true")
+	}
+	path := indexPath(indexID, "ingest")
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	reader, writer := io.Pipe()
+	stream := &IngestStream{writer: writer, encoder: json.NewEncoder(writer), done: make(chan struct{})}
+	go func() {
+		defer close(stream.done)
+		stream.summary, stream.err = c.readIngest(ctx, path, reader, options.OnResult)
+
+		// The server takes no more records once it answered
+		if stream.err != nil {
+			_ = reader.CloseWithError(stream.err)
+		} else {
+			_ = reader.CloseWithError(ErrStreamClosed)
+		}
+	}()
+	return stream, nil
+}
+
+// readIngest sends the records of an ingest stream from body and reads the
+// results of the response until its summary
+func (c *Client) readIngest(ctx context.Context, path string, body io.Reader, onResult func(IngestResult)) (*IngestSummary, error) {
+	response, err := c.send(ctx, http.MethodPost, path, body, mimeNDJSON)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(nil, maxStreamLine)
+	for scanner.Scan() {
+		var line struct {
+			IngestResult
+			Done bool `json:"done"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		if !line.Done {
+			if onResult != nil {
+				onResult(line.IngestResult)
+			}
+			continue
+		}
+
+		var summary IngestSummary
+		if err := json.Unmarshal(scanner.Bytes(), &summary); err != nil {
+			return nil, fmt.Errorf("failed to decode summary: %w", err)
+		}
+		return &summary, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrIncompleteStream
+}
+
+// Send sends a record. It fails once the stream ended, such as with the
+// *APIError of the server when it did not accept the stream.
+func (s *IngestStream) Send(record *IngestRecord) error {
+	if err := s.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to send record: %w", err)
+	}
+	return nil
+}
+
+// Close ends the stream and returns its summary once the server wrote every
+// record. A stream the server ended early returns its summary together with
+// an error matching ErrIncompleteStream.
+func (s *IngestStream) Close() (*IngestSummary, error) {
+	_ = s.writer.Close()
+	<-s.done
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.summary.Error != "" {
+		return s.summary, fmt.Errorf("%w: %s", ErrIncompleteStream, s.summary.Error)
+	}
+	return s.summary, nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIngest(t *testing.T) {
+	server := newTestServer(t, "")
+	client := newTestClient(t, server.URL, "")
+	ctx := context.Background()
+	if _, err := client.CreateIndex(ctx, &CreateIndexRequest{ID: "docs", Type: "hnsw", Dimension: testDimension, MaxElements: 1000}); err != nil {
+		t.Fatalf("Failed to create index: %v", err)
+	}
+
+	var failed []IngestResult
+	stream, err := client.Ingest(ctx, "docs", &IngestOptions{
+		ChunkSize: 10,
+		Embed:     true,
+		OnResult: func(result IngestResult) {
+			if result.Error != "" {
+				failed = append(failed, result)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	for i := range 95 {
+		record := &IngestRecord{ID: fmt.Sprintf("doc-%d", i), Text: fmt.Sprintf("document number %d", i)}
+		if i%2 == 0 {
+			record = &IngestRecord{ID: record.ID, Embedding: embedText(t, record.Text)}
+		}
+		if err := stream.Send(record); err != nil {
+			t.Fatalf("Failed to send record %d: %v", i, err)
+		}
+	}
+	if err := stream.Send(&IngestRecord{ID: "bad", Embedding: []float64{1}}); err != nil {
+		t.Fatalf("Failed to send record: %v", err)
+	}
+
+	summary, err := stream.Close()
+	if err != nil {
+		t.Fatalf("Failed to close stream: %v", err)
+	}
+	if summary.Lines != 96 || summary.Ingested != 95 || summary.Failed != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if len(failed) != 1 || failed[0].ID != "bad" || failed[0].Line != 96 {
+		t.Errorf("Expected the bad record to fail, got %+v", failed)
+	}
+	if info, err := client.GetIndex(ctx, "docs"); err != nil || info.TotalVectors != 95 {
+		t.Errorf("Expected 95 vectors, got %+v, %v", info, err)
+	}
+}
+
+func TestIngestMissingIndex(t *testing.T) {
+	server := newTestServer(t, "")
+	client := newTestClient(t, server.URL, "")
+
+	stream, err := client.Ingest(context.Background(), "missing", nil)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	_ = stream.Send(&IngestRecord{ID: "a", Embedding: []float64{1}})
+	if _, err := stream.Close(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxStreamLine bounds the size of an event of a stream
+const maxStreamLine = 64 << 20
+
+// RAGQuery performs a RAG operation for a query
+func (c *Client) RAGQuery(ctx context.Context, req *RAGRequest) (*RAGResponse, error) {
+	var response RAGResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/rag/query", req, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// RAGQueryStream performs a RAG operation for a query, calling handle with
+// each step as soon as the server finished it: the expanded queries, the
+// candidates of the search and the reranked results. It returns the response
+// of the operation once it is done. An error of handle, or ctx being done,
+// closes the stream, which cancels the operation on the server, and is
+// returned.
+func (c *Client) RAGQueryStream(ctx context.Context, req *RAGRequest, handle func(event *RAGStreamEvent) error) (*RAGResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	response, err := c.open(ctx, http.MethodPost, "/v1/rag/query/stream", data, true)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(nil, maxStreamLine)
+	for scanner.Scan() {
+		var event RAGStreamEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode event: %w", err)
+		}
+		switch event.Stage {
+		case RAGStageDone:
+			if event.Response == nil {
+				return nil, fmt.Errorf("%w: done event without a response", ErrIncompleteStream)
+			}
+			return event.Response, nil
+		case RAGStageError:
+			return nil, streamError(event.Code, event.Error)
+		}
+		if handle != nil {
+			if err := handle(&event); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, ErrIncompleteStream
+}
+
+// RAGBatch performs a RAG operation for every query of a batch. Queries that
+// fail are reported in the errors of the response rather than failing the
+// batch.
+func (c *Client) RAGBatch(ctx context.Context, req *BatchRAGRequest) (*BatchRAGResponse, error) {
+	var response BatchRAGResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/rag/batch", req, &response, true); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
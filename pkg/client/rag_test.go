@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRAGQuery(t *testing.T) {
+	server := newTestServer(t, "")
+	client := newTestClient(t, server.URL, "")
+	createDocsIndex(t, client)
+
+	response, err := client.RAGQuery(context.Background(), &RAGRequest{
+		Operation: RAGOperationEndToEndRAG, Query: testDocuments["raft"], Collection: "docs",
+	})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(response.Results) == 0 {
+		t.Fatalf("Expected results, got none")
+	}
+	if _, err := client.RAGQuery(context.Background(), &RAGRequest{
+		Operation: RAGOperationEndToEndRAG, Query: "raft", Collection: "missing",
+	}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing collection, got %v", err)
+	}
+}
+
+func TestRAGQueryStream(t *testing.T) {
+	server := newTestServer(t, "")
+	client := newTestClient(t, server.URL, "")
+	createDocsIndex(t, client)
+
+	var stages []RAGStage
+	response, err := client.RAGQueryStream(context.Background(), &RAGRequest{
+		Operation: RAGOperationEndToEndRAG, Query: testDocuments["raft"], Collection: "docs",
+	}, func(event *RAGStreamEvent) error {
+		stages = append(stages, event.Stage)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream query: %v", err)
+	}
+	if len(response.Results) == 0 {
+		t.Errorf("Expected results, got none")
+	}
+	want := []RAGStage{RAGStageExpanded, RAGStageCandidates, RAGStageReranked}
+	if len(stages) != len(want) {
+		t.Fatalf("Expected stages %v, got %v", want, stages)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Fatalf("Expected stages %v, got %v", want, stages)
+		}
+	}
+
+	// An error of the handler ends the stream
+	stop := errors.New("stop")
+	if _, err := client.RAGQueryStream(context.Background(), &RAGRequest{
+		Operation: RAGOperationEndToEndRAG, Query: "raft", Collection: "docs",
+	}, func(*RAGStreamEvent) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("Expected the error of the handler, got %v", err)
+	}
+}
+
+func TestRAGBatch(t *testing.T) {
+	server := newTestServer(t, "")
+	client := newTestClient(t, server.URL, "")
+	createDocsIndex(t, client)
+
+	response, err := client.RAGBatch(context.Background(), &BatchRAGRequest{
+		Operation: RAGOperationEndToEndRAG, Queries: []string{testDocuments["raft"], testDocuments["hnsw"]}, Collection: "docs",
+	})
+	if err != nil {
+		t.Fatalf("Failed to query batch: %v", err)
+	}
+	if response.ProcessedCount != 2 || len(response.Results) != 2 {
+		t.Errorf("Expected 2 responses, got %+v", response)
+	}
+}
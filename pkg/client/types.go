@@ -0,0 +1,380 @@
+package client
+
+import "time"
+
+// The types of this file mirror the schemas of docs/api/openapi.yaml of the
+// same name; the tests of the package check that their fields stay in sync.
+
+// Vector is a vector with its embedding and metadata
+type Vector struct {
+	ID         string                 `json:"id"`
+	Collection string                 `json:"collection,omitempty"`
+	Embedding  []float64              `json:"embedding,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	// TTLSeconds and ExpiresAt are alternative ways to make the vector expire
+	TTLSeconds int64      `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+
+	// ExpectedVersion makes the write conditional on the stored version of the
+	// vector; zero requires that the vector is not stored yet
+	ExpectedVersion *uint64 `json:"expected_version,omitempty"`
+}
+
+// CreateIndexRequest is the configuration of an index to create. Unset index
+// parameters select the defaults of the server.
+type CreateIndexRequest struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	Dimension      int    `json:"dimension"`
+	MaxElements    int    `json:"max_elements"`
+	M              int    `json:"m,omitempty"`
+	EfConstruction int    `json:"ef_construction,omitempty"`
+	EfSearch       int    `json:"ef_search,omitempty"`
+	MaxLayers      int    `json:"max_layers,omitempty"`
+	NumClusters    int    `json:"num_clusters,omitempty"`
+	ClusterSize    int    `json:"cluster_size,omitempty"`
+	DistanceMetric string `json:"distance_metric,omitempty"`
+	Normalize      bool   `json:"normalize,omitempty"`
+}
+
+// IndexConfig is the configuration an index was created with
+type IndexConfig struct {
+	Type           string `json:"type"`
+	Dimension      int    `json:"dimension"`
+	MaxElements    int    `json:"max_elements"`
+	M              int    `json:"m"`
+	EfConstruction int    `json:"ef_construction"`
+	EfSearch       int    `json:"ef_search"`
+	MaxLayers      int    `json:"max_layers"`
+	NumClusters    int    `json:"num_clusters"`
+	ClusterSize    int    `json:"cluster_size"`
+	DistanceMetric string `json:"distance_metric"`
+	Normalize      bool   `json:"normalize"`
+}
+
+// CreateIndexResponse reports a created index
+type CreateIndexResponse struct {
+	ID      string      `json:"id"`
+	Type    string      `json:"type"`
+	Status  string      `json:"status"`
+	Config  IndexConfig `json:"config"`
+	Message string      `json:"message"`
+}
+
+// IndexInfo describes an index with its statistics
+type IndexInfo struct {
+	ID            string  `json:"id"`
+	TotalVectors  int64   `json:"total_vectors"`
+	MemoryUsage   int64   `json:"memory_usage"`
+	IndexSize     int64   `json:"index_size"`
+	AvgSearchTime float64 `json:"avg_search_time"`
+	AvgInsertTime float64 `json:"avg_insert_time"`
+}
+
+// ListIndexesResponse lists the indexes of the tenant of a client
+type ListIndexesResponse struct {
+	Indexes []IndexInfo `json:"indexes"`
+	Count   int         `json:"count"`
+}
+
+// DeleteIndexResponse reports a deleted index
+type DeleteIndexResponse struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// InsertVectorsResponse reports vectors inserted into an index, with the
+// version each vector was written at
+type InsertVectorsResponse struct {
+	IndexID      string            `json:"index_id"`
+	VectorsAdded int               `json:"vectors_added"`
+	TotalVectors int64             `json:"total_vectors"`
+	Versions     map[string]uint64 `json:"versions,omitempty"`
+	InsertTime   string            `json:"insert_time"`
+	Message      string            `json:"message"`
+}
+
+// Write operations of a batch
+const (
+	WritePut    = "put"
+	WriteDelete = "delete"
+)
+
+// WriteOperation is one write of a batch: WritePut writes Vector, WriteDelete
+// removes the vector with ID
+type WriteOperation struct {
+	Op     string  `json:"op"`
+	Vector *Vector `json:"vector,omitempty"`
+	ID     string  `json:"id,omitempty"`
+
+	// ExpectedVersion makes the batch conditional on the stored version of the
+	// vector; zero requires that the vector is not stored yet
+	ExpectedVersion *uint64 `json:"expected_version,omitempty"`
+}
+
+// WriteBatchResponse reports a batch applied to an index
+type WriteBatchResponse struct {
+	IndexID      string            `json:"index_id"`
+	Position     uint64            `json:"position"`
+	Versions     map[string]uint64 `json:"versions,omitempty"`
+	Deleted      []string          `json:"deleted"`
+	TotalVectors int64             `json:"total_vectors"`
+	WriteTime    string            `json:"write_time"`
+	Message      string            `json:"message"`
+}
+
+// IngestRecord is a record of an ingest stream: a vector whose embedding may
+// be left out for Text to be embedded instead
+type IngestRecord struct {
+	ID              string                 `json:"id"`
+	Embedding       []float64              `json:"embedding,omitempty"`
+	Text            string                 `json:"text,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	TTLSeconds      int64                  `json:"ttl_seconds,omitempty"`
+	ExpiresAt       *time.Time             `json:"expires_at,omitempty"`
+	ExpectedVersion *uint64                `json:"expected_version,omitempty"`
+}
+
+// IngestResult reports the outcome of a record of an ingest stream, numbered
+// from 1 in the order the records were sent
+type IngestResult struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id,omitempty"`
+	Version uint64 `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// IngestSummary ends the results of an ingest stream. Error is set when the
+// stream ended before its last record was ingested.
+type IngestSummary struct {
+	Done         bool   `json:"done"`
+	Lines        int    `json:"lines"`
+	Ingested     int    `json:"ingested"`
+	Failed       int    `json:"failed"`
+	Chunks       int    `json:"chunks"`
+	TotalVectors int64  `json:"total_vectors"`
+	IngestTime   string `json:"ingest_time"`
+	Error        string `json:"error,omitempty"`
+}
+
+// SearchRequest is a nearest neighbor search of an index
+type SearchRequest struct {
+	Query []float64 `json:"query"`
+	K     int       `json:"k"`
+
+	// AsOf searches the vectors as they were at the given time
+	AsOf *time.Time `json:"as_of,omitempty"`
+}
+
+// SearchResponse holds the results of a search, nearest first
+type SearchResponse struct {
+	IndexID    string         `json:"index_id"`
+	Query      []float64      `json:"query"`
+	K          int            `json:"k"`
+	AsOf       *time.Time     `json:"as_of,omitempty"`
+	Results    []SearchResult `json:"results"`
+	SearchTime string         `json:"search_time"`
+	Count      int            `json:"count"`
+}
+
+// SearchResult is a vector found by a search. Searches of an index set
+// VectorID and Distance; RAG operations set Vector, Rank and Similarity.
+type SearchResult struct {
+	VectorID   string                 `json:"vector_id,omitempty"`
+	Vector     *Vector                `json:"vector,omitempty"`
+	Score      float64                `json:"score"`
+	Distance   float64                `json:"distance,omitempty"`
+	Rank       int                    `json:"rank,omitempty"`
+	Similarity float64                `json:"similarity,omitempty"`
+	Context    map[string]interface{} `json:"context,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// RAGOperation is the kind of a RAG operation
+type RAGOperation string
+
+const (
+	RAGOperationQueryExpansion   RAGOperation = "query_expansion"
+	RAGOperationResultReranking  RAGOperation = "result_reranking"
+	RAGOperationContextRetrieval RAGOperation = "context_retrieval"
+	RAGOperationEndToEndRAG      RAGOperation = "end_to_end_rag"
+	RAGOperationBatchSearch      RAGOperation = "batch_search"
+	RAGOperationBatchRerank      RAGOperation = "batch_rerank"
+)
+
+// RAGRequest is a RAG operation for a query over the index named by
+// Collection
+type RAGRequest struct {
+	Operation  RAGOperation           `json:"operation"`
+	Query      string                 `json:"query"`
+	Context    map[string]interface{} `json:"context,omitempty"`
+	Collection string                 `json:"collection,omitempty"`
+	Options    map[string]interface{} `json:"options,omitempty"`
+	RAGConfig  *RAGConfig             `json:"rag_config,omitempty"`
+}
+
+// RAGConfig configures the steps of a RAG operation. The steps of end-to-end
+// RAG are enabled unless their flag is set to false, and unset component
+// configurations select the defaults of the server.
+type RAGConfig struct {
+	EnableQueryExpansion   *bool                 `json:"enable_query_expansion,omitempty"`
+	EnableResultReranking  *bool                 `json:"enable_result_reranking,omitempty"`
+	EnableContextAwareness *bool                 `json:"enable_context_awareness,omitempty"`
+	QueryExpansionConfig   *QueryExpansionConfig `json:"query_expansion_config,omitempty"`
+	RerankingConfig        *RerankingConfig      `json:"reranking_config,omitempty"`
+	ContextConfig          *ContextConfig        `json:"context_config,omitempty"`
+	SearchConfig           *SearchConfig         `json:"search_config,omitempty"`
+}
+
+// QueryExpansionConfig configures query expansion
+type QueryExpansionConfig struct {
+	Strategies          []string            `json:"strategies,omitempty"`
+	MaxExpansions       int                 `json:"max_expansions,omitempty"`
+	SimilarityThreshold float64             `json:"similarity_threshold,omitempty"`
+	DomainSynonyms      map[string][]string `json:"domain_synonyms,omitempty"`
+	CustomPatterns      []string            `json:"custom_patterns,omitempty"`
+}
+
+// RerankingConfig configures result reranking
+type RerankingConfig struct {
+	Strategies     []string           `json:"strategies,omitempty"`
+	Weights        map[string]float64 `json:"weights,omitempty"`
+	MaxResults     int                `json:"max_results,omitempty"`
+	SemanticWeight float64            `json:"semantic_weight,omitempty"`
+	ContextWeight  float64            `json:"context_weight,omitempty"`
+	HybridWeight   float64            `json:"hybrid_weight,omitempty"`
+}
+
+// ContextConfig configures context-aware retrieval
+type ContextConfig struct {
+	UserContext         bool    `json:"user_context,omitempty"`
+	DomainContext       bool    `json:"domain_context,omitempty"`
+	TemporalContext     bool    `json:"temporal_context,omitempty"`
+	LocationContext     bool    `json:"location_context,omitempty"`
+	ContextDecay        float64 `json:"context_decay,omitempty"`
+	ConfidenceThreshold float64 `json:"confidence_threshold,omitempty"`
+}
+
+// SearchConfig configures the search of a RAG operation
+type SearchConfig struct {
+	SearchType       string                 `json:"search_type,omitempty"`
+	IndexType        string                 `json:"index_type,omitempty"`
+	SimilarityMetric string                 `json:"similarity_metric,omitempty"`
+	MaxResults       int                    `json:"max_results,omitempty"`
+	Threshold        float64                `json:"threshold,omitempty"`
+	EnableFilters    bool                   `json:"enable_filters,omitempty"`
+	Filters          map[string]interface{} `json:"filters,omitempty"`
+}
+
+// RAGResponse is the outcome of a RAG operation
+type RAGResponse struct {
+	Operation           RAGOperation           `json:"operation"`
+	Query               string                 `json:"query"`
+	OriginalQuery       string                 `json:"original_query,omitempty"`
+	ExpandedQueries     []string               `json:"expanded_queries,omitempty"`
+	Results             []SearchResult         `json:"results"`
+	RerankedResults     []SearchResult         `json:"reranked_results,omitempty"`
+	ContextEnhancements []string               `json:"context_enhancements,omitempty"`
+	ProcessingTime      time.Duration          `json:"processing_time"`
+	Confidence          float64                `json:"confidence"`
+	Metadata            map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// RAGStage is a step of a RAG operation reported by a RAG stream
+type RAGStage string
+
+const (
+	RAGStageExpanded   RAGStage = "expanded"
+	RAGStageCandidates RAGStage = "candidates"
+	RAGStageReranked   RAGStage = "reranked"
+	RAGStageDone       RAGStage = "done"
+	RAGStageError      RAGStage = "error"
+)
+
+// RAGStreamEvent is a step of a streamed RAG operation, sent as soon as the
+// step finishes
+type RAGStreamEvent struct {
+	Stage           RAGStage       `json:"stage"`
+	ExpandedQueries []string       `json:"expanded_queries,omitempty"`
+	Results         []SearchResult `json:"results,omitempty"`
+	Response        *RAGResponse   `json:"response,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	Code            string         `json:"code,omitempty"`
+	ElapsedTime     time.Duration  `json:"elapsed_time"`
+}
+
+// BatchRAGRequest is a RAG operation for every query of a batch
+type BatchRAGRequest struct {
+	Operation     RAGOperation           `json:"operation"`
+	Queries       []string               `json:"queries"`
+	Context       map[string]interface{} `json:"context,omitempty"`
+	Collection    string                 `json:"collection,omitempty"`
+	BatchSize     int                    `json:"batch_size,omitempty"`
+	MaxConcurrent int                    `json:"max_concurrent,omitempty"`
+	Timeout       string                 `json:"timeout,omitempty"`
+	Options       map[string]interface{} `json:"options,omitempty"`
+	RAGConfig     *RAGConfig             `json:"rag_config,omitempty"`
+}
+
+// BatchRAGResponse holds the responses of the queries of a batch, and the
+// errors of those that failed by their index in the batch
+type BatchRAGResponse struct {
+	Operation      RAGOperation    `json:"operation"`
+	Results        []RAGResponse   `json:"results"`
+	ProcessingTime time.Duration   `json:"processing_time"`
+	ProcessedCount int             `json:"processed_count"`
+	ErrorCount     int             `json:"error_count"`
+	Errors         []BatchError    `json:"errors,omitempty"`
+	Statistics     BatchStatistics `json:"statistics"`
+	RAGMetrics     RAGMetrics      `json:"rag_metrics"`
+}
+
+// BatchError is the error of an item of a batch
+type BatchError struct {
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// BatchStatistics are the statistics of a batch
+type BatchStatistics struct {
+	StartTime      time.Time     `json:"start_time"`
+	EndTime        time.Time     `json:"end_time"`
+	TotalItems     int           `json:"total_items"`
+	ProcessedItems int           `json:"processed_items"`
+	FailedItems    int           `json:"failed_items"`
+	Throughput     float64       `json:"throughput"`
+	AverageLatency time.Duration `json:"average_latency"`
+	MemoryUsage    int64         `json:"memory_usage"`
+	CPUUsage       float64       `json:"cpu_usage"`
+}
+
+// RAGMetrics are the metrics of the RAG operations of a batch
+type RAGMetrics struct {
+	QueryExpansionCount     int           `json:"query_expansion_count"`
+	RerankingCount          int           `json:"reranking_count"`
+	ContextEnhancementCount int           `json:"context_enhancement_count"`
+	AverageExpansionRatio   float64       `json:"average_expansion_ratio"`
+	AverageRerankingTime    time.Duration `json:"average_reranking_time"`
+	CacheHitRate            float64       `json:"cache_hit_rate"`
+	AccuracyImprovement     float64       `json:"accuracy_improvement"`
+}
+
+// HealthResponse reports the health of the server
+type HealthResponse struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Service   string    `json:"service"`
+	Version   string    `json:"version"`
+	Uptime    string    `json:"uptime,omitempty"`
+}
+
+// errorResponse is the standard error envelope of the API
+type errorResponse struct {
+	Error   string `json:"error"`
+	Status  int    `json:"status"`
+	Success bool   `json:"success"`
+}
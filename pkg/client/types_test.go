@@ -0,0 +1,64 @@
+package client
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	spec "github.com/vijaynallagatla/vjvector/docs/api"
+	"gopkg.in/yaml.v3"
+)
+
+// specTypes are the types of the client mirroring the schemas of the
+// specification with the same name
+var specTypes = []interface{}{
+	Vector{}, CreateIndexRequest{}, IndexConfig{}, CreateIndexResponse{}, IndexInfo{}, ListIndexesResponse{},
+	DeleteIndexResponse{}, InsertVectorsResponse{}, WriteBatchResponse{}, IngestRecord{}, IngestResult{},
+	IngestSummary{}, SearchRequest{}, SearchResponse{}, SearchResult{}, RAGRequest{}, RAGConfig{},
+	QueryExpansionConfig{}, RerankingConfig{}, ContextConfig{}, SearchConfig{}, RAGResponse{}, RAGStreamEvent{},
+	BatchRAGRequest{}, BatchRAGResponse{}, BatchError{}, BatchStatistics{}, RAGMetrics{}, HealthResponse{},
+}
+
+// jsonFields returns the names of the JSON properties of a struct type
+func jsonFields(typ reflect.Type) []string {
+	var names []string
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestTypesMatchSpecification(t *testing.T) {
+	var document struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `yaml:"properties"`
+			} `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(spec.OpenAPI, &document); err != nil {
+		t.Fatalf("Failed to parse specification: %v", err)
+	}
+
+	for _, value := range specTypes {
+		typ := reflect.TypeOf(value)
+		schema, exists := document.Components.Schemas[typ.Name()]
+		if !exists {
+			t.Errorf("%s: no schema of the same name", typ.Name())
+			continue
+		}
+		want := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			want = append(want, name)
+		}
+		sort.Strings(want)
+		if got := jsonFields(typ); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: fields %v do not match the properties %v of the specification", typ.Name(), got, want)
+		}
+	}
+}
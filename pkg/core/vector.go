@@ -22,6 +22,16 @@ type Vector struct {
 	Dimension  int                    `json:"dimension"`
 	Magnitude  float64                `json:"magnitude"`
 	Normalized bool                   `json:"normalized"`
+
+	// ExpiresAt is when the vector stops being visible; nil means it never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Version counts the writes of the vector; the catalog sets it on every write
+	Version uint64 `json:"version,omitempty"`
+
+	// Deleted marks a tombstone: the version in a vector's history that records
+	// its deletion at UpdatedAt. Storage engines never return tombstones from reads.
+	Deleted bool `json:"deleted,omitempty"`
 }
 
 // NewVector creates a new vector with the given parameters
@@ -43,6 +53,21 @@ func NewVector(collection string, embedding []float64, text string, metadata map
 	}
 }
 
+// SetTTL makes the vector expire ttl from now; a non-positive ttl clears the expiry
+func (v *Vector) SetTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		v.ExpiresAt = nil
+		return
+	}
+	expiresAt := time.Now().Add(ttl)
+	v.ExpiresAt = &expiresAt
+}
+
+// Expired reports whether the vector has expired at the given time
+func (v *Vector) Expired(now time.Time) bool {
+	return v.ExpiresAt != nil && !now.Before(*v.ExpiresAt)
+}
+
 // Normalize normalizes the vector to unit length
 func (v *Vector) Normalize() {
 	if v.Normalized {
@@ -123,14 +148,15 @@ type SearchQuery struct {
 
 // Collection represents a collection of vectors
 type Collection struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	Dimension   int                    `json:"dimension"`
-	Count       int64                  `json:"count"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
-	IndexType   string                 `json:"index_type"`
+	Name           string                 `json:"name"`
+	Description    string                 `json:"description"`
+	Dimension      int                    `json:"dimension"`
+	Count          int64                  `json:"count"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	IndexType      string                 `json:"index_type"`
+	DistanceMetric string                 `json:"distance_metric,omitempty"`
 }
 
 // NewCollection creates a new collection
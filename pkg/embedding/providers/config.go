@@ -0,0 +1,38 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+)
+
+// ProviderFromEnv creates the embedding provider configured by the
+// environment, or returns nil when none is. VJVECTOR_EMBEDDING_PROVIDER names
+// the provider; "openai" embeds with the OpenAI API, or a server compatible
+// with it at VJVECTOR_EMBEDDING_BASE_URL, authenticated with
+// VJVECTOR_EMBEDDING_API_KEY or OPENAI_API_KEY and embedding with the model
+// of VJVECTOR_EMBEDDING_MODEL.
+func ProviderFromEnv() (embedding.Provider, error) {
+	name := strings.TrimSpace(os.Getenv("VJVECTOR_EMBEDDING_PROVIDER"))
+	switch embedding.ProviderType(strings.ToLower(name)) {
+	case "":
+		return nil, nil
+	case embedding.ProviderTypeOpenAI:
+		apiKey := os.Getenv("VJVECTOR_EMBEDDING_API_KEY")
+		if apiKey == "" {
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		config := &embedding.ProviderConfig{
+			APIKey:  apiKey,
+			BaseURL: os.Getenv("VJVECTOR_EMBEDDING_BASE_URL"),
+		}
+		if model := os.Getenv("VJVECTOR_EMBEDDING_MODEL"); model != "" {
+			config.Options = map[string]interface{}{"model": model}
+		}
+		return NewOpenAIProvider(config)
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider %q", name)
+	}
+}
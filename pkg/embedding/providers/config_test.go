@@ -0,0 +1,33 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+)
+
+func TestProviderFromEnv(t *testing.T) {
+	t.Setenv("VJVECTOR_EMBEDDING_PROVIDER", "")
+	provider, err := ProviderFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	t.Setenv("VJVECTOR_EMBEDDING_PROVIDER", "openai")
+	t.Setenv("VJVECTOR_EMBEDDING_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	_, err = ProviderFromEnv()
+	assert.Error(t, err)
+
+	t.Setenv("VJVECTOR_EMBEDDING_API_KEY", "test-key")
+	t.Setenv("VJVECTOR_EMBEDDING_MODEL", "text-embedding-3-small")
+	provider, err = ProviderFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, embedding.ProviderTypeOpenAI, provider.Type())
+	assert.Equal(t, "text-embedding-3-small", provider.(*OpenAIProvider).Model())
+
+	t.Setenv("VJVECTOR_EMBEDDING_PROVIDER", "hash")
+	_, err = ProviderFromEnv()
+	assert.Error(t, err)
+}
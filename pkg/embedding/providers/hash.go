@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+)
+
+// HashProvider generates deterministic bag-of-words embeddings by hashing every
+// token of a text into a fixed-size unit vector. It needs no model, so it lets
+// embedded databases and tests embed texts without an external service; texts
+// sharing words are similar, but synonyms are not, so its embeddings cannot be
+// mixed with those of a real model.
+type HashProvider struct {
+	dimension int
+}
+
+// NewHashProvider creates a hash embedding provider of the given dimension
+func NewHashProvider(dimension int) (*HashProvider, error) {
+	if dimension <= 0 {
+		return nil, fmt.Errorf("invalid embedding dimension %d", dimension)
+	}
+	return &HashProvider{dimension: dimension}, nil
+}
+
+// NewHashService creates an embedding service that embeds every text with a
+// hash provider of the given dimension
+func NewHashService(dimension int) (embedding.Service, error) {
+	provider, err := NewHashProvider(dimension)
+	if err != nil {
+		return nil, err
+	}
+	return NewProviderService(provider)
+}
+
+// NewProviderService creates an embedding service that embeds every text with
+// a single provider, whichever provider a request names
+func NewProviderService(provider embedding.Provider) (embedding.Service, error) {
+	service, err := embedding.NewService(&embedding.Config{
+		DefaultProvider: provider.Type(),
+		Timeout:         30 * time.Second,
+		MaxBatchSize:    provider.GetCapabilities().MaxBatchSize,
+		EnableFallback:  true,
+		FallbackOrder:   []embedding.ProviderType{provider.Type()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding service: %w", err)
+	}
+	if err := service.RegisterProvider(provider); err != nil {
+		return nil, fmt.Errorf("failed to register embedding provider: %w", err)
+	}
+	return service, nil
+}
+
+// Type returns the provider type
+func (p *HashProvider) Type() embedding.ProviderType {
+	return embedding.ProviderTypeLocal
+}
+
+// Name returns the provider name
+func (p *HashProvider) Name() string {
+	return "simple-local"
+}
+
+// Model returns the name of the model of the provider
+func (p *HashProvider) Model() string {
+	return p.Name()
+}
+
+// Dimension returns the dimension of the embeddings
+func (p *HashProvider) Dimension() int {
+	return p.dimension
+}
+
+// GenerateEmbeddings hashes every token of a text into a fixed-size unit vector
+func (p *HashProvider) GenerateEmbeddings(_ context.Context, req *embedding.EmbeddingRequest) (*embedding.EmbeddingResponse, error) {
+	start := time.Now()
+
+	embeddings := make([][]float64, len(req.Texts))
+	tokens := 0
+	for i, text := range req.Texts {
+		vector := make([]float64, p.dimension)
+		for _, token := range strings.Fields(strings.ToLower(text)) {
+			hash := fnv.New32a()
+			_, _ = hash.Write([]byte(token))
+			vector[int(hash.Sum32()%uint32(p.dimension))] += 1.0
+			tokens++
+		}
+
+		norm := 0.0
+		for _, value := range vector {
+			norm += value * value
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range vector {
+				vector[j] /= norm
+			}
+		}
+		embeddings[i] = vector
+	}
+
+	return &embedding.EmbeddingResponse{
+		Embeddings:     embeddings,
+		Model:          p.Name(),
+		Provider:       p.Type(),
+		Usage:          embedding.UsageStats{TotalTokens: tokens, PromptTokens: tokens, Provider: p.Name()},
+		ProcessingTime: time.Since(start),
+	}, nil
+}
+
+// GetModels returns the single model served by this provider
+func (p *HashProvider) GetModels(_ context.Context) ([]embedding.Model, error) {
+	return []embedding.Model{
+		{
+			ID:         p.Name(),
+			Name:       p.Name(),
+			Provider:   p.Type(),
+			Dimensions: p.dimension,
+			MaxTokens:  8192,
+			Supported:  true,
+		},
+	}, nil
+}
+
+// GetCapabilities returns provider capabilities
+func (p *HashProvider) GetCapabilities() embedding.Capabilities {
+	return embedding.Capabilities{
+		MaxBatchSize:  1000,
+		MaxTextLength: 8192,
+		Features:      []string{"deterministic"},
+	}
+}
+
+// HealthCheck always succeeds because the provider has no dependencies
+func (p *HashProvider) HealthCheck(_ context.Context) error {
+	return nil
+}
+
+// Close releases provider resources
+func (p *HashProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+)
+
+func TestHashProvider(t *testing.T) {
+	_, err := NewHashProvider(0)
+	assert.Error(t, err)
+
+	provider, err := NewHashProvider(32)
+	require.NoError(t, err)
+	assert.Equal(t, embedding.ProviderTypeLocal, provider.Type())
+	assert.Equal(t, 32, provider.Dimension())
+
+	resp, err := provider.GenerateEmbeddings(context.Background(), &embedding.EmbeddingRequest{
+		Texts: []string{"Vector search", "vector SEARCH", ""},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Embeddings, 3)
+
+	// Embeddings are unit vectors that ignore case
+	assert.Equal(t, resp.Embeddings[0], resp.Embeddings[1])
+	norm := 0.0
+	for _, value := range resp.Embeddings[0] {
+		norm += value * value
+	}
+	assert.InDelta(t, 1.0, math.Sqrt(norm), 1e-9)
+
+	// An empty text has a zero embedding
+	for _, value := range resp.Embeddings[2] {
+		assert.Zero(t, value)
+	}
+	assert.Equal(t, 4, resp.Usage.TotalTokens)
+}
+
+func TestNewHashService(t *testing.T) {
+	_, err := NewHashService(-1)
+	assert.Error(t, err)
+
+	service, err := NewHashService(16)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, service.Close()) }()
+
+	resp, err := service.GenerateEmbeddings(context.Background(), &embedding.EmbeddingRequest{
+		Texts: []string{"nearest neighbors"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Embeddings, 1)
+	assert.Len(t, resp.Embeddings[0], 16)
+}
@@ -13,12 +13,16 @@ import (
 	"github.com/vijaynallagatla/vjvector/pkg/utils/logger"
 )
 
+// defaultOpenAIModel is the model of OpenAI providers configured with none
+const defaultOpenAIModel = "text-embedding-ada-002"
+
 // OpenAIProvider implements the OpenAI embedding provider
 type OpenAIProvider struct {
 	config  *embedding.ProviderConfig
 	client  *http.Client
 	baseURL string
 	apiKey  string
+	model   string
 }
 
 // OpenAIEmbeddingRequest represents the OpenAI API request
@@ -62,11 +66,18 @@ func NewOpenAIProvider(config *embedding.ProviderConfig) (*OpenAIProvider, error
 		Timeout: timeout,
 	}
 
+	// The model of the "model" option embeds requests that name none
+	model, _ := config.Options["model"].(string)
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+
 	return &OpenAIProvider{
 		config:  config,
 		client:  client,
 		baseURL: baseURL,
 		apiKey:  config.APIKey,
+		model:   model,
 	}, nil
 }
 
@@ -80,6 +91,11 @@ func (p *OpenAIProvider) Name() string {
 	return "OpenAI"
 }
 
+// Model returns the model that embeds requests naming none
+func (p *OpenAIProvider) Model() string {
+	return p.model
+}
+
 // GenerateEmbeddings generates embeddings using OpenAI API
 func (p *OpenAIProvider) GenerateEmbeddings(ctx context.Context, req *embedding.EmbeddingRequest) (*embedding.EmbeddingResponse, error) {
 	if len(req.Texts) == 0 {
@@ -89,7 +105,7 @@ func (p *OpenAIProvider) GenerateEmbeddings(ctx context.Context, req *embedding.
 	// Use default model if not specified
 	model := req.Model
 	if model == "" {
-		model = "text-embedding-ada-002"
+		model = p.model
 	}
 
 	// Process texts in batches
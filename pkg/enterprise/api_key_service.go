@@ -6,6 +6,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -125,10 +127,16 @@ type SuspiciousActivity struct {
 	Action      string    `json:"action"` // "logged", "blocked", "alerted"
 }
 
-// DefaultAPIKeyService implements the API key management service
+// DefaultAPIKeyService implements the API key management service. It is safe
+// for concurrent use; the keys it returns are copies.
 type DefaultAPIKeyService struct {
-	// In a real implementation, this would use a database
+	mu   sync.RWMutex
 	keys map[string]*APIKey
+
+	// path is the file the keys are saved to, by OpenAPIKeyService; the keys
+	// only live in memory without one. usageChanged records usage not saved yet.
+	path         string
+	usageChanged bool
 }
 
 // NewDefaultAPIKeyService creates a new default API key service
@@ -172,60 +180,97 @@ func (s *DefaultAPIKeyService) CreateAPIKey(ctx context.Context, tenantID, name,
 	}
 
 	// Store the key
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.keys[apiKey.ID] = apiKey
+	if err := s.save(); err != nil {
+		delete(s.keys, apiKey.ID)
+		return nil, "", err
+	}
 
-	return apiKey, fullKey, nil
+	return apiKey.clone(), fullKey, nil
 }
 
 // GetAPIKey retrieves an API key by ID
 func (s *DefaultAPIKeyService) GetAPIKey(ctx context.Context, keyID string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	key, exists := s.keys[keyID]
 	if !exists {
-		return nil, fmt.Errorf("API key not found: %s", keyID)
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
 	}
-	return key, nil
+	return key.clone(), nil
 }
 
 // GetAPIKeyByHash retrieves an API key by its hash
 func (s *DefaultAPIKeyService) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	for _, key := range s.keys {
 		if key.KeyHash == keyHash {
-			return key, nil
+			return key.clone(), nil
 		}
 	}
-	return nil, fmt.Errorf("API key not found")
+	return nil, ErrAPIKeyNotFound
 }
 
 // UpdateAPIKey updates an existing API key
 func (s *DefaultAPIKeyService) UpdateAPIKey(ctx context.Context, key *APIKey) error {
-	if _, exists := s.keys[key.ID]; !exists {
-		return fmt.Errorf("API key not found: %s", key.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, exists := s.keys[key.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, key.ID)
 	}
 
 	key.UpdatedAt = time.Now()
-	s.keys[key.ID] = key
+	s.keys[key.ID] = key.clone()
+	if err := s.save(); err != nil {
+		s.keys[key.ID] = previous
+		return err
+	}
 	return nil
 }
 
 // DeleteAPIKey deletes an API key
 func (s *DefaultAPIKeyService) DeleteAPIKey(ctx context.Context, keyID string) error {
-	if _, exists := s.keys[keyID]; !exists {
-		return fmt.Errorf("API key not found: %s", keyID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, exists := s.keys[keyID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
 	}
 
 	delete(s.keys, keyID)
+	if err := s.save(); err != nil {
+		s.keys[keyID] = previous
+		return err
+	}
 	return nil
 }
 
-// ListAPIKeys lists API keys for a tenant
+// ListAPIKeys lists API keys for a tenant, oldest first
 func (s *DefaultAPIKeyService) ListAPIKeys(ctx context.Context, tenantID string, limit, offset int) ([]*APIKey, error) {
 	var tenantKeys []*APIKey
 
+	s.mu.RLock()
 	for _, key := range s.keys {
 		if key.TenantID == tenantID {
-			tenantKeys = append(tenantKeys, key)
+			tenantKeys = append(tenantKeys, key.clone())
 		}
 	}
+	s.mu.RUnlock()
+	sort.Slice(tenantKeys, func(i, j int) bool {
+		if !tenantKeys[i].CreatedAt.Equal(tenantKeys[j].CreatedAt) {
+			return tenantKeys[i].CreatedAt.Before(tenantKeys[j].CreatedAt)
+		}
+		return tenantKeys[i].ID < tenantKeys[j].ID
+	})
 
 	// Simple pagination (in production, use database pagination)
 	if offset >= len(tenantKeys) {
@@ -248,22 +293,22 @@ func (s *DefaultAPIKeyService) ValidateAPIKey(ctx context.Context, key string, t
 	// Find the key by hash
 	apiKey, err := s.GetAPIKeyByHash(ctx, keyHash)
 	if err != nil {
-		return nil, fmt.Errorf("invalid API key")
+		return nil, ErrInvalidAPIKey
 	}
 
-	// Check if the key belongs to the specified tenant
-	if apiKey.TenantID != tenantID {
-		return nil, fmt.Errorf("API key does not belong to tenant")
+	// Check if the key belongs to the specified tenant, if any
+	if tenantID != "" && apiKey.TenantID != tenantID {
+		return nil, fmt.Errorf("%w: API key does not belong to tenant", ErrInvalidAPIKey)
 	}
 
 	// Check if the key is active
 	if apiKey.Status != APIKeyStatusActive {
-		return nil, fmt.Errorf("API key is not active")
+		return nil, fmt.Errorf("%w: API key is not active", ErrInvalidAPIKey)
 	}
 
 	// Check if the key has expired
 	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
-		return nil, fmt.Errorf("API key has expired")
+		return nil, fmt.Errorf("%w: API key has expired", ErrInvalidAPIKey)
 	}
 
 	return apiKey, nil
@@ -271,11 +316,12 @@ func (s *DefaultAPIKeyService) ValidateAPIKey(ctx context.Context, key string, t
 
 // CheckPermission checks if an API key has permission for a specific action
 func (s *DefaultAPIKeyService) CheckPermission(ctx context.Context, key *APIKey, resource, action, resourceID string) bool {
-	// Check if the key has the required permission
+	// Check if the key has the required permission, or every action on the resource
 	requiredPermission := fmt.Sprintf("%s:%s", resource, action)
+	resourcePermission := resource + ":*"
 
 	for _, permission := range key.Permissions {
-		if permission == requiredPermission || permission == "*" {
+		if permission == requiredPermission || permission == resourcePermission || permission == "*" {
 			return true
 		}
 	}
@@ -297,71 +343,49 @@ func (s *DefaultAPIKeyService) ValidateScope(ctx context.Context, key *APIKey, s
 
 // ActivateAPIKey activates an API key
 func (s *DefaultAPIKeyService) ActivateAPIKey(ctx context.Context, keyID string) error {
-	key, err := s.GetAPIKey(ctx, keyID)
-	if err != nil {
-		return err
-	}
-
-	key.Status = APIKeyStatusActive
-	key.UpdatedAt = time.Now()
-
-	return s.UpdateAPIKey(ctx, key)
+	return s.update(keyID, func(key *APIKey) {
+		key.Status = APIKeyStatusActive
+	})
 }
 
 // DeactivateAPIKey deactivates an API key
 func (s *DefaultAPIKeyService) DeactivateAPIKey(ctx context.Context, keyID string) error {
-	key, err := s.GetAPIKey(ctx, keyID)
-	if err != nil {
-		return err
-	}
-
-	key.Status = APIKeyStatusInactive
-	key.UpdatedAt = time.Now()
-
-	return s.UpdateAPIKey(ctx, key)
+	return s.update(keyID, func(key *APIKey) {
+		key.Status = APIKeyStatusInactive
+	})
 }
 
 // RevokeAPIKey revokes an API key
 func (s *DefaultAPIKeyService) RevokeAPIKey(ctx context.Context, keyID string, reason string) error {
-	key, err := s.GetAPIKey(ctx, keyID)
-	if err != nil {
-		return err
-	}
-
-	key.Status = APIKeyStatusRevoked
-	key.UpdatedAt = time.Now()
-	key.Metadata["revocation_reason"] = reason
-	key.Metadata["revoked_at"] = time.Now().Format(time.RFC3339)
-
-	return s.UpdateAPIKey(ctx, key)
+	return s.update(keyID, func(key *APIKey) {
+		key.Status = APIKeyStatusRevoked
+		key.Metadata["revocation_reason"] = reason
+		key.Metadata["revoked_at"] = time.Now().Format(time.RFC3339)
+	})
 }
 
 // RenewAPIKey renews an API key with a new expiry date
 func (s *DefaultAPIKeyService) RenewAPIKey(ctx context.Context, keyID string, newExpiry time.Time) error {
-	key, err := s.GetAPIKey(ctx, keyID)
-	if err != nil {
-		return err
-	}
-
-	key.ExpiresAt = &newExpiry
-	key.UpdatedAt = time.Now()
-
-	return s.UpdateAPIKey(ctx, key)
+	return s.update(keyID, func(key *APIKey) {
+		key.ExpiresAt = &newExpiry
+	})
 }
 
-// TrackAPIKeyUsage tracks API key usage for analytics
+// TrackAPIKeyUsage tracks API key usage for analytics. The usage is saved
+// with the next change of a key, not on every request.
 func (s *DefaultAPIKeyService) TrackAPIKeyUsage(ctx context.Context, keyID string, endpoint, method, ipAddress, userAgent string) error {
-	key, err := s.GetAPIKey(ctx, keyID)
-	if err != nil {
-		return err
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	key, exists := s.keys[keyID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
+	}
 	now := time.Now()
 	key.LastUsedAt = &now
 	key.UsageCount++
-	key.UpdatedAt = now
-
-	return s.UpdateAPIKey(ctx, key)
+	s.usageChanged = true
+	return nil
 }
 
 // GetAPIKeyUsage retrieves usage statistics for an API key
@@ -386,20 +410,73 @@ func (s *DefaultAPIKeyService) GetAPIKeyUsage(ctx context.Context, keyID string,
 // HealthCheck performs a health check on the service
 func (s *DefaultAPIKeyService) HealthCheck(ctx context.Context) error {
 	// Simple health check - verify we can access our data
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	if s.keys == nil {
 		return fmt.Errorf("API key service not initialized")
 	}
 	return nil
 }
 
-// Close performs cleanup operations
+// Close saves the usage not saved yet
 func (s *DefaultAPIKeyService) Close() error {
-	// Clean up any resources if needed
-	return nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.usageChanged {
+		return nil
+	}
+	return s.save()
 }
 
 // Helper methods
 
+// update applies a change to a stored API key atomically
+func (s *DefaultAPIKeyService) update(keyID string, change func(key *APIKey)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.keys[keyID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, keyID)
+	}
+	previous := key.clone()
+	if key.Metadata == nil {
+		key.Metadata = make(map[string]string)
+	}
+	change(key)
+	key.UpdatedAt = time.Now()
+	if err := s.save(); err != nil {
+		s.keys[keyID] = previous
+		return err
+	}
+	return nil
+}
+
+// clone copies an API key so that callers cannot change the stored key
+func (k *APIKey) clone() *APIKey {
+	copied := *k
+	copied.Permissions = append([]string(nil), k.Permissions...)
+	copied.Scopes = append([]string(nil), k.Scopes...)
+	copied.IPRestrictions = append([]string(nil), k.IPRestrictions...)
+	copied.UserAgentRestrictions = append([]string(nil), k.UserAgentRestrictions...)
+	if k.ExpiresAt != nil {
+		expiresAt := *k.ExpiresAt
+		copied.ExpiresAt = &expiresAt
+	}
+	if k.LastUsedAt != nil {
+		lastUsedAt := *k.LastUsedAt
+		copied.LastUsedAt = &lastUsedAt
+	}
+	if k.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(k.Metadata))
+		for name, value := range k.Metadata {
+			copied.Metadata[name] = value
+		}
+	}
+	return &copied
+}
+
 // hashKey creates a SHA-256 hash of the API key
 func (s *DefaultAPIKeyService) hashKey(key string) string {
 	hash := sha256.Sum256([]byte(key))
@@ -0,0 +1,92 @@
+package enterprise
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// apiKeyFileVersion is the format version of the API key file
+const apiKeyFileVersion = 1
+
+// apiKeyFile is the on-disk form of the keys of an API key service
+type apiKeyFile struct {
+	Version int            `json:"version"`
+	Keys    []storedAPIKey `json:"keys"`
+}
+
+// storedAPIKey is an API key as it is saved, with the hash of its secret,
+// which the API never returns
+type storedAPIKey struct {
+	*APIKey
+	KeyHash string `json:"key_hash"`
+}
+
+// OpenAPIKeyService creates an API key service that saves its keys to the
+// file at path after every change, starting with the keys saved there.
+// Secrets are not saved, only their hashes. Usage counters are saved with the
+// next change or on Close.
+func OpenAPIKeyService(path string) (*DefaultAPIKeyService, error) {
+	s := NewDefaultAPIKeyService()
+	s.path = path
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	var file apiKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode API keys: %w", err)
+	}
+	if file.Version != apiKeyFileVersion {
+		return nil, fmt.Errorf("unsupported API key file version %d", file.Version)
+	}
+	for _, stored := range file.Keys {
+		if stored.APIKey == nil || stored.ID == "" {
+			return nil, fmt.Errorf("failed to decode API keys: a key has no ID")
+		}
+		stored.APIKey.KeyHash = stored.KeyHash
+		s.keys[stored.ID] = stored.APIKey
+	}
+	return s, nil
+}
+
+// save writes the keys to the file of the service, if it has one; the caller
+// must hold the lock
+func (s *DefaultAPIKeyService) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	file := apiKeyFile{Version: apiKeyFileVersion, Keys: make([]storedAPIKey, 0, len(s.keys))}
+	for _, key := range s.keys {
+		file.Keys = append(file.Keys, storedAPIKey{APIKey: key, KeyHash: key.KeyHash})
+	}
+	sort.Slice(file.Keys, func(i, j int) bool {
+		return file.Keys[i].ID < file.Keys[j].ID
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode API keys: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return fmt.Errorf("failed to create API key directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(filepath.Clean(tmpPath), data, 0600); err != nil {
+		return fmt.Errorf("failed to write API keys: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace API keys: %w", err)
+	}
+	s.usageChanged = false
+	return nil
+}
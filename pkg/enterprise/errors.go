@@ -0,0 +1,9 @@
+package enterprise
+
+import "errors"
+
+// Enterprise-related errors
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+)
@@ -3,6 +3,8 @@ package enterprise
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
 	"sync"
 	"time"
 )
@@ -17,7 +19,12 @@ type RateLimitConfig struct {
 	DefaultTenantRequestsPerSecond int `json:"default_tenant_requests_per_second"`
 	DefaultTenantBurstSize         int `json:"default_tenant_burst_size"`
 
-	// Per-endpoint limits
+	// Per-API key limits; zero disables them
+	KeyRequestsPerSecond int `json:"key_requests_per_second"`
+	KeyBurstSize         int `json:"key_burst_size"`
+
+	// Per-endpoint limits, keyed by "METHOD:route" and applied to each tenant
+	// separately
 	EndpointLimits map[string]EndpointLimit `json:"endpoint_limits"`
 
 	// IP-based limits
@@ -43,22 +50,24 @@ func DefaultRateLimitConfig() *RateLimitConfig {
 		GlobalBurstSize:                100,
 		DefaultTenantRequestsPerSecond: 100,
 		DefaultTenantBurstSize:         10,
+		KeyRequestsPerSecond:           50,
+		KeyBurstSize:                   10,
 		IPRequestsPerSecond:            50,
 		IPBurstSize:                    5,
 		CleanupInterval:                5 * time.Minute,
 		MaxEntries:                     10000,
 		EndpointLimits: map[string]EndpointLimit{
-			"POST:/collections": {
+			"POST:/v1/indexes": {
 				RequestsPerSecond: 10,
 				BurstSize:         2,
 				Window:            1 * time.Second,
 			},
-			"POST:/vectors": {
+			"POST:/v1/indexes/:indexId/vectors": {
 				RequestsPerSecond: 100,
 				BurstSize:         20,
 				Window:            1 * time.Second,
 			},
-			"GET:/search": {
+			"POST:/v1/indexes/:indexId/search": {
 				RequestsPerSecond: 200,
 				BurstSize:         50,
 				Window:            1 * time.Second,
@@ -74,9 +83,12 @@ type RateLimiter interface {
 	AllowTenant(ctx context.Context, tenantID string, endpoint string) bool
 	AllowIP(ctx context.Context, ipAddress string) bool
 	AllowGlobal(ctx context.Context) bool
+	Take(ctx context.Context, request RateLimitRequest) RateLimitDecision
 
 	// Configuration
 	SetTenantLimit(ctx context.Context, tenantID string, requestsPerSecond, burstSize int) error
+	SetTenantRate(ctx context.Context, tenantID string, requests int, window time.Duration) error
+	ResetTenantLimit(ctx context.Context, tenantID string) error
 	SetEndpointLimit(ctx context.Context, endpoint string, limit EndpointLimit) error
 	GetTenantLimit(ctx context.Context, tenantID string) (int, int, error)
 	GetEndpointLimit(ctx context.Context, endpoint string) (EndpointLimit, error)
@@ -100,6 +112,34 @@ type TokenBucket struct {
 	Window     time.Duration `json:"window"`
 }
 
+// RateLimitRequest identifies the buckets a request is counted against. KeyID
+// and Endpoint are optional.
+type RateLimitRequest struct {
+	TenantID string
+	KeyID    string
+	Endpoint string
+}
+
+// RateLimitDecision is the outcome of counting a request against its buckets.
+// Limit and Remaining describe the bucket that denied the request, or the one
+// with the fewest tokens left when it is allowed.
+type RateLimitDecision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// Reset is the time until the bucket is full again, and RetryAfter the
+	// time until it allows a request when the request was denied
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// bucketLimit is the capacity and refill rate of a bucket
+type bucketLimit struct {
+	capacity int
+	rate     float64 // tokens per second
+}
+
 // TenantRateUsage represents rate limiting usage for a tenant
 type TenantRateUsage struct {
 	TenantID       string         `json:"tenant_id"`
@@ -122,12 +162,18 @@ type IPRateUsage struct {
 type DefaultRateLimiter struct {
 	config          *RateLimitConfig
 	tenantBuckets   map[string]*TokenBucket
+	keyBuckets      map[string]*TokenBucket
 	ipBuckets       map[string]*TokenBucket
 	globalBucket    *TokenBucket
 	endpointBuckets map[string]*TokenBucket
-	mu              sync.RWMutex
-	cleanupTicker   *time.Ticker
-	done            chan bool
+
+	// tenantLimits holds the limits set for tenants, which outlive the
+	// buckets removed by the cleanup
+	tenantLimits map[string]bucketLimit
+
+	mu            sync.RWMutex
+	cleanupTicker *time.Ticker
+	done          chan bool
 }
 
 // NewDefaultRateLimiter creates a new default rate limiter
@@ -139,10 +185,15 @@ func NewDefaultRateLimiter(config *RateLimitConfig) *DefaultRateLimiter {
 	limiter := &DefaultRateLimiter{
 		config:          config,
 		tenantBuckets:   make(map[string]*TokenBucket),
+		keyBuckets:      make(map[string]*TokenBucket),
 		ipBuckets:       make(map[string]*TokenBucket),
 		endpointBuckets: make(map[string]*TokenBucket),
+		tenantLimits:    make(map[string]bucketLimit),
 		done:            make(chan bool),
 	}
+	if limiter.config.EndpointLimits == nil {
+		limiter.config.EndpointLimits = make(map[string]EndpointLimit)
+	}
 
 	// Initialize global bucket
 	limiter.globalBucket = &TokenBucket{
@@ -153,17 +204,6 @@ func NewDefaultRateLimiter(config *RateLimitConfig) *DefaultRateLimiter {
 		Window:     1 * time.Second,
 	}
 
-	// Initialize endpoint buckets
-	for endpoint, limit := range config.EndpointLimits {
-		limiter.endpointBuckets[endpoint] = &TokenBucket{
-			Tokens:     limit.BurstSize,
-			Capacity:   limit.BurstSize,
-			LastRefill: time.Now(),
-			RefillRate: float64(limit.RequestsPerSecond),
-			Window:     limit.Window,
-		}
-	}
-
 	// Start cleanup routine
 	limiter.startCleanup()
 
@@ -181,48 +221,7 @@ func (r *DefaultRateLimiter) Allow(ctx context.Context, key string, limit int, w
 
 // AllowTenant checks if a request is allowed for a tenant
 func (r *DefaultRateLimiter) AllowTenant(ctx context.Context, tenantID string, endpoint string) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Check global limit first
-	if !r.consumeToken(r.globalBucket) {
-		return false
-	}
-
-	// Check endpoint-specific limit
-	if endpointLimit, exists := r.config.EndpointLimits[endpoint]; exists {
-		endpointKey := fmt.Sprintf("endpoint:%s", endpoint)
-		bucket, exists := r.endpointBuckets[endpointKey]
-		if !exists {
-			bucket = &TokenBucket{
-				Tokens:     endpointLimit.BurstSize,
-				Capacity:   endpointLimit.BurstSize,
-				LastRefill: time.Now(),
-				RefillRate: float64(endpointLimit.RequestsPerSecond),
-				Window:     endpointLimit.Window,
-			}
-			r.endpointBuckets[endpointKey] = bucket
-		}
-		if !r.consumeToken(bucket) {
-			return false
-		}
-	}
-
-	// Check tenant limit
-	tenantKey := fmt.Sprintf("tenant:%s", tenantID)
-	bucket, exists := r.tenantBuckets[tenantKey]
-	if !exists {
-		bucket = &TokenBucket{
-			Tokens:     r.config.DefaultTenantBurstSize,
-			Capacity:   r.config.DefaultTenantBurstSize,
-			LastRefill: time.Now(),
-			RefillRate: float64(r.config.DefaultTenantRequestsPerSecond),
-			Window:     1 * time.Second,
-		}
-		r.tenantBuckets[tenantKey] = bucket
-	}
-
-	return r.consumeToken(bucket)
+	return r.Take(ctx, RateLimitRequest{TenantID: tenantID, Endpoint: endpoint}).Allowed
 }
 
 // AllowIP checks if a request is allowed for an IP address
@@ -254,21 +253,73 @@ func (r *DefaultRateLimiter) AllowGlobal(ctx context.Context) bool {
 	return r.consumeToken(r.globalBucket)
 }
 
+// Take counts a request against the global bucket and the buckets of its
+// tenant, API key and endpoint. The request takes a token from every bucket
+// or, when one of them is empty, from none, so that denied requests do not
+// drain the other buckets.
+func (r *DefaultRateLimiter) Take(ctx context.Context, request RateLimitRequest) RateLimitDecision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buckets := []*TokenBucket{r.globalBucket, r.tenantBucket(request.TenantID)}
+	if request.KeyID != "" && r.config.KeyBurstSize > 0 {
+		buckets = append(buckets, r.keyBucket(request.KeyID))
+	}
+	if bucket := r.endpointBucket(request.TenantID, request.Endpoint); bucket != nil {
+		buckets = append(buckets, bucket)
+	}
+
+	now := time.Now()
+	for _, bucket := range buckets {
+		r.refillBucket(bucket)
+		if bucket.Tokens <= 0 {
+			decision := bucketDecision(bucket, now)
+			decision.RetryAfter = untilNextToken(bucket, now)
+			return decision
+		}
+	}
+
+	tightest := buckets[0]
+	for _, bucket := range buckets {
+		bucket.Tokens--
+		if bucket.Tokens < tightest.Tokens || (bucket.Tokens == tightest.Tokens && bucket.Capacity < tightest.Capacity) {
+			tightest = bucket
+		}
+	}
+	decision := bucketDecision(tightest, now)
+	decision.Allowed = true
+	return decision
+}
+
 // SetTenantLimit sets rate limits for a specific tenant
 func (r *DefaultRateLimiter) SetTenantLimit(ctx context.Context, tenantID string, requestsPerSecond, burstSize int) error {
+	if requestsPerSecond <= 0 || burstSize <= 0 {
+		return fmt.Errorf("tenant rate limit must be positive")
+	}
+	r.setTenantLimit(tenantID, bucketLimit{capacity: burstSize, rate: float64(requestsPerSecond)})
+	return nil
+}
+
+// SetTenantRate limits a tenant to a number of requests per window, which it
+// may use up in a burst
+func (r *DefaultRateLimiter) SetTenantRate(ctx context.Context, tenantID string, requests int, window time.Duration) error {
+	if requests <= 0 || window <= 0 {
+		return fmt.Errorf("tenant rate limit must be positive")
+	}
+	r.setTenantLimit(tenantID, bucketLimit{capacity: requests, rate: float64(requests) / window.Seconds()})
+	return nil
+}
+
+// ResetTenantLimit removes the limit set for a tenant, which gets the default
+// tenant limit again
+func (r *DefaultRateLimiter) ResetTenantLimit(ctx context.Context, tenantID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	tenantKey := fmt.Sprintf("tenant:%s", tenantID)
-	bucket := &TokenBucket{
-		Tokens:     burstSize,
-		Capacity:   burstSize,
-		LastRefill: time.Now(),
-		RefillRate: float64(requestsPerSecond),
-		Window:     1 * time.Second,
+	delete(r.tenantLimits, tenantID)
+	if bucket, exists := r.tenantBuckets[fmt.Sprintf("tenant:%s", tenantID)]; exists {
+		resizeBucket(bucket, r.tenantLimit(tenantID))
 	}
-
-	r.tenantBuckets[tenantKey] = bucket
 	return nil
 }
 
@@ -277,17 +328,13 @@ func (r *DefaultRateLimiter) SetEndpointLimit(ctx context.Context, endpoint stri
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	endpointKey := fmt.Sprintf("endpoint:%s", endpoint)
-	bucket := &TokenBucket{
-		Tokens:     limit.BurstSize,
-		Capacity:   limit.BurstSize,
-		LastRefill: time.Now(),
-		RefillRate: float64(limit.RequestsPerSecond),
-		Window:     limit.Window,
-	}
-
-	r.endpointBuckets[endpointKey] = bucket
 	r.config.EndpointLimits[endpoint] = limit
+	prefix := fmt.Sprintf("endpoint:%s:", endpoint)
+	for key, bucket := range r.endpointBuckets {
+		if strings.HasPrefix(key, prefix) {
+			resizeBucket(bucket, bucketLimit{capacity: limit.BurstSize, rate: float64(limit.RequestsPerSecond)})
+		}
+	}
 	return nil
 }
 
@@ -296,13 +343,8 @@ func (r *DefaultRateLimiter) GetTenantLimit(ctx context.Context, tenantID string
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	tenantKey := fmt.Sprintf("tenant:%s", tenantID)
-	bucket, exists := r.tenantBuckets[tenantKey]
-	if !exists {
-		return r.config.DefaultTenantRequestsPerSecond, r.config.DefaultTenantBurstSize, nil
-	}
-
-	return int(bucket.RefillRate), bucket.Capacity, nil
+	limit := r.tenantLimit(tenantID)
+	return int(limit.rate), limit.capacity, nil
 }
 
 // GetEndpointLimit gets the current rate limits for an endpoint
@@ -320,14 +362,18 @@ func (r *DefaultRateLimiter) GetEndpointLimit(ctx context.Context, endpoint stri
 
 // GetRemaining gets the remaining tokens for a key
 func (r *DefaultRateLimiter) GetRemaining(ctx context.Context, key string) (int, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	// Try to find the bucket in different categories
 	if bucket, exists := r.tenantBuckets[key]; exists {
 		r.refillBucket(bucket)
 		return bucket.Tokens, nil
 	}
+	if bucket, exists := r.keyBuckets[key]; exists {
+		r.refillBucket(bucket)
+		return bucket.Tokens, nil
+	}
 	if bucket, exists := r.ipBuckets[key]; exists {
 		r.refillBucket(bucket)
 		return bucket.Tokens, nil
@@ -342,8 +388,8 @@ func (r *DefaultRateLimiter) GetRemaining(ctx context.Context, key string) (int,
 
 // GetTenantUsage gets the current usage for a tenant
 func (r *DefaultRateLimiter) GetTenantUsage(ctx context.Context, tenantID string) (*TenantRateUsage, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	tenantKey := fmt.Sprintf("tenant:%s", tenantID)
 	bucket, exists := r.tenantBuckets[tenantKey]
@@ -375,8 +421,8 @@ func (r *DefaultRateLimiter) GetTenantUsage(ctx context.Context, tenantID string
 
 // GetIPUsage gets the current usage for an IP address
 func (r *DefaultRateLimiter) GetIPUsage(ctx context.Context, ipAddress string) (*IPRateUsage, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	ipKey := fmt.Sprintf("ip:%s", ipAddress)
 	bucket, exists := r.ipBuckets[ipKey]
@@ -426,6 +472,102 @@ func (r *DefaultRateLimiter) Close() error {
 
 // Helper methods
 
+// tenantLimit returns the limit set for a tenant, or the default tenant limit
+func (r *DefaultRateLimiter) tenantLimit(tenantID string) bucketLimit {
+	if limit, exists := r.tenantLimits[tenantID]; exists {
+		return limit
+	}
+	return bucketLimit{capacity: r.config.DefaultTenantBurstSize, rate: float64(r.config.DefaultTenantRequestsPerSecond)}
+}
+
+// setTenantLimit sets the limit of a tenant, resizing its bucket
+func (r *DefaultRateLimiter) setTenantLimit(tenantID string, limit bucketLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tenantLimits[tenantID] = limit
+	if bucket, exists := r.tenantBuckets[fmt.Sprintf("tenant:%s", tenantID)]; exists {
+		resizeBucket(bucket, limit)
+	}
+}
+
+// tenantBucket gets or creates the bucket of a tenant
+func (r *DefaultRateLimiter) tenantBucket(tenantID string) *TokenBucket {
+	tenantKey := fmt.Sprintf("tenant:%s", tenantID)
+	bucket, exists := r.tenantBuckets[tenantKey]
+	if !exists {
+		bucket = newBucket(r.tenantLimit(tenantID))
+		r.tenantBuckets[tenantKey] = bucket
+	}
+	return bucket
+}
+
+// keyBucket gets or creates the bucket of an API key
+func (r *DefaultRateLimiter) keyBucket(keyID string) *TokenBucket {
+	keyKey := fmt.Sprintf("key:%s", keyID)
+	bucket, exists := r.keyBuckets[keyKey]
+	if !exists {
+		bucket = newBucket(bucketLimit{capacity: r.config.KeyBurstSize, rate: float64(r.config.KeyRequestsPerSecond)})
+		r.keyBuckets[keyKey] = bucket
+	}
+	return bucket
+}
+
+// endpointBucket gets or creates the bucket of a tenant for an endpoint; nil
+// when the endpoint has no limit of its own
+func (r *DefaultRateLimiter) endpointBucket(tenantID, endpoint string) *TokenBucket {
+	limit, exists := r.config.EndpointLimits[endpoint]
+	if !exists {
+		return nil
+	}
+	endpointKey := fmt.Sprintf("endpoint:%s:tenant:%s", endpoint, tenantID)
+	bucket, exists := r.endpointBuckets[endpointKey]
+	if !exists {
+		bucket = newBucket(bucketLimit{capacity: limit.BurstSize, rate: float64(limit.RequestsPerSecond)})
+		bucket.Window = limit.Window
+		r.endpointBuckets[endpointKey] = bucket
+	}
+	return bucket
+}
+
+// newBucket creates a full bucket
+func newBucket(limit bucketLimit) *TokenBucket {
+	return &TokenBucket{
+		Tokens:     limit.capacity,
+		Capacity:   limit.capacity,
+		LastRefill: time.Now(),
+		RefillRate: limit.rate,
+		Window:     1 * time.Second,
+	}
+}
+
+// resizeBucket applies a new limit to a bucket without adding tokens to it
+func resizeBucket(bucket *TokenBucket, limit bucketLimit) {
+	bucket.Capacity = limit.capacity
+	bucket.RefillRate = limit.rate
+	bucket.Tokens = min(bucket.Tokens, limit.capacity)
+}
+
+// untilNextToken returns the time until a bucket gains a token
+func untilNextToken(bucket *TokenBucket, now time.Time) time.Duration {
+	if bucket.Tokens >= bucket.Capacity {
+		return 0
+	}
+	if bucket.RefillRate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return max(0, time.Duration(float64(time.Second)/bucket.RefillRate)-now.Sub(bucket.LastRefill))
+}
+
+// bucketDecision describes the state of a bucket in a decision
+func bucketDecision(bucket *TokenBucket, now time.Time) RateLimitDecision {
+	decision := RateLimitDecision{Limit: bucket.Capacity, Remaining: max(0, bucket.Tokens)}
+	if missing := bucket.Capacity - bucket.Tokens; missing > 0 && bucket.RefillRate > 0 {
+		decision.Reset = untilNextToken(bucket, now) + time.Duration(float64(missing-1)/bucket.RefillRate*float64(time.Second))
+	}
+	return decision
+}
+
 // getOrCreateBucket gets an existing bucket or creates a new one
 func (r *DefaultRateLimiter) getOrCreateBucket(key string, limit int, window time.Duration) *TokenBucket {
 	bucket, exists := r.tenantBuckets[key]
@@ -456,12 +598,22 @@ func (r *DefaultRateLimiter) consumeToken(bucket *TokenBucket) bool {
 // refillBucket refills tokens in a bucket based on time elapsed
 func (r *DefaultRateLimiter) refillBucket(bucket *TokenBucket) {
 	now := time.Now()
+	if bucket.Tokens >= bucket.Capacity || bucket.RefillRate <= 0 {
+		bucket.LastRefill = now
+		return
+	}
 	elapsed := now.Sub(bucket.LastRefill).Seconds()
 
 	tokensToAdd := int(elapsed * bucket.RefillRate)
 	if tokensToAdd > 0 {
 		bucket.Tokens = min(bucket.Capacity, bucket.Tokens+tokensToAdd)
-		bucket.LastRefill = now
+		if bucket.Tokens == bucket.Capacity {
+			bucket.LastRefill = now
+		} else {
+			// Keep the part of a token accrued since the last one, which
+			// slow buckets such as per-minute limits would otherwise lose
+			bucket.LastRefill = bucket.LastRefill.Add(time.Duration(float64(tokensToAdd) / bucket.RefillRate * float64(time.Second)))
+		}
 	}
 }
 
@@ -494,10 +646,12 @@ func (r *DefaultRateLimiter) cleanup() {
 		}
 	}
 
-	// Clean up old IP buckets (older than 1 hour)
-	for key, bucket := range r.ipBuckets {
-		if bucket.LastRefill.Before(cutoff) {
-			delete(r.ipBuckets, key)
+	// Clean up old key, endpoint and IP buckets (older than 1 hour)
+	for _, buckets := range []map[string]*TokenBucket{r.keyBuckets, r.endpointBuckets, r.ipBuckets} {
+		for key, bucket := range buckets {
+			if bucket.LastRefill.Before(cutoff) {
+				delete(buckets, key)
+			}
 		}
 	}
 
@@ -507,11 +661,3 @@ func (r *DefaultRateLimiter) cleanup() {
 	// 	// In a real implementation, use a more sophisticated cleanup strategy
 	// }
 }
-
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
@@ -0,0 +1,9 @@
+package index
+
+// Distance calculates the distance between two vectors with the named metric
+// ("cosine", "euclidean" or "dot"), as the HNSW index does. Unknown metrics
+// fall back to cosine; vectors of different lengths are infinitely far apart.
+func Distance(metric string, a, b []float64) float64 {
+	h := &HNSWIndex{config: IndexConfig{DistanceMetric: metric}}
+	return h.calculateDistance(a, b)
+}
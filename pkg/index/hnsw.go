@@ -15,11 +15,17 @@ import (
 // for approximate nearest neighbor search
 type HNSWIndex struct {
 	config     IndexConfig
-	vectors    []*core.Vector
+	vectors    map[string]*core.Vector
 	layers     [][]*Node
 	entryPoint *Node
 	mutex      sync.RWMutex
 
+	// Deleted vectors stay in the graph as tombstones, so that searches still
+	// route through them, until tombstones outnumber the live vectors and the
+	// graph is rebuilt
+	nodes      map[string][]*Node
+	tombstones int
+
 	// Statistics
 	stats     IndexStats
 	startTime time.Time
@@ -31,6 +37,8 @@ type Node struct {
 	Vector  []float64 `json:"vector"`
 	Level   int       `json:"level"`
 	Friends [][]int   `json:"friends"` // Friends at each level
+
+	deleted bool
 }
 
 // NewHNSWIndex creates a new HNSW index with the given configuration
@@ -41,35 +49,51 @@ func NewHNSWIndex(config IndexConfig) (VectorIndex, error) {
 
 	index := &HNSWIndex{
 		config:    config,
-		vectors:   make([]*core.Vector, 0, config.MaxElements),
-		layers:    make([][]*Node, config.MaxLayers),
 		startTime: time.Now(),
 	}
-
-	// Initialize layers
-	for i := range index.layers {
-		index.layers[i] = make([]*Node, 0)
-	}
+	index.reset()
 
 	return index, nil
 }
 
-// Insert adds a vector to the HNSW index
+// reset empties the index
+func (h *HNSWIndex) reset() {
+	h.vectors = make(map[string]*core.Vector)
+	h.nodes = make(map[string][]*Node)
+	h.layers = make([][]*Node, h.config.MaxLayers)
+	for i := range h.layers {
+		h.layers[i] = make([]*Node, 0)
+	}
+	h.entryPoint = nil
+	h.tombstones = 0
+	h.stats.TotalVectors = 0
+}
+
+// Insert adds a vector to the HNSW index, replacing the vector with the same ID
 func (h *HNSWIndex) Insert(vector *core.Vector) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	if len(h.vectors) >= h.config.MaxElements {
-		return ErrIndexFull
-	}
-
 	// Validate vector dimension
 	if len(vector.Embedding) != h.config.Dimension {
 		return ErrInvalidDimension
 	}
 
+	_, replaced := h.vectors[vector.ID]
+	if !replaced && len(h.vectors) >= h.config.MaxElements {
+		return ErrIndexFull
+	}
+	if replaced {
+		h.remove(vector.ID)
+	}
+
+	return h.insert(vector)
+}
+
+// insert adds a vector that is not in the index to the graph
+func (h *HNSWIndex) insert(vector *core.Vector) error {
 	// Add vector to storage
-	h.vectors = append(h.vectors, vector)
+	h.vectors[vector.ID] = vector
 
 	// Create node
 	node := &Node{
@@ -83,6 +107,7 @@ func (h *HNSWIndex) Insert(vector *core.Vector) error {
 	for level := 0; level <= node.Level; level++ {
 		h.layers[level] = append(h.layers[level], node)
 	}
+	h.nodes[vector.ID] = append(h.nodes[vector.ID], node)
 
 	// Update entry point if this is the first node
 	if h.entryPoint == nil {
@@ -95,7 +120,7 @@ func (h *HNSWIndex) Insert(vector *core.Vector) error {
 	}
 
 	// Update statistics
-	h.stats.TotalVectors++
+	h.stats.TotalVectors = int64(len(h.vectors))
 
 	return nil
 }
@@ -127,25 +152,56 @@ func (h *HNSWIndex) SearchWithContext(_ context.Context, query []float64, k int)
 }
 
 // Delete removes a vector from the index by ID
-func (h *HNSWIndex) Delete(_ string) error {
+func (h *HNSWIndex) Delete(id string) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	// TODO: Implement HNSW deletion algorithm
-	// This is a placeholder - actual implementation will be added in Week 3-4
+	if _, exists := h.vectors[id]; !exists {
+		return ErrVectorNotFound
+	}
+	h.remove(id)
+
+	// Rebuilding costs as much as the inserts since the last rebuild, which
+	// keeps deletes amortized constant
+	if h.tombstones > len(h.vectors) {
+		return h.rebuild()
+	}
+	return nil
+}
 
-	return ErrVectorNotFound
+// remove turns the nodes of a vector into tombstones
+func (h *HNSWIndex) remove(id string) {
+	for _, node := range h.nodes[id] {
+		node.deleted = true
+		h.tombstones++
+	}
+	delete(h.nodes, id)
+	delete(h.vectors, id)
+	h.stats.TotalVectors = int64(len(h.vectors))
+}
+
+// rebuild builds the graph again from the live vectors, dropping tombstones
+func (h *HNSWIndex) rebuild() error {
+	vectors := h.vectors
+	h.reset()
+	for _, vector := range vectors {
+		if err := h.insert(vector); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// Optimize performs index optimization and maintenance
+// Optimize performs index optimization and maintenance: it rebuilds the
+// graph without the tombstones of deleted vectors
 func (h *HNSWIndex) Optimize() error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	// TODO: Implement HNSW optimization
-	// This is a placeholder - actual implementation will be added in Week 3-4
-
-	return nil
+	if h.tombstones == 0 {
+		return nil
+	}
+	return h.rebuild()
 }
 
 // GetStats returns index performance and structure statistics
@@ -170,6 +226,7 @@ func (h *HNSWIndex) Close() error {
 
 	// Clear data structures
 	h.vectors = nil
+	h.nodes = nil
 	h.layers = nil
 	h.entryPoint = nil
 
@@ -206,7 +263,7 @@ func validateHNSWConfig(config IndexConfig) error {
 // findNodeIndexInLayer finds the index of a node in a specific layer
 func (h *HNSWIndex) findNodeIndexInLayer(node *Node, level int) int {
 	for i, layerNode := range h.layers[level] {
-		if layerNode.ID == node.ID {
+		if layerNode == node {
 			return i
 		}
 	}
@@ -319,25 +376,21 @@ func (h *HNSWIndex) searchHNSW(query []float64, k int) ([]core.VectorSearchResul
 	}
 
 	// Search in the bottom layer (level 0) with full efSearch
-	results := h.searchLayer(query, []*Node{currentNode}, h.config.EfSearch, 0)
+	results := h.searchLayer(query, []*Node{currentNode}, max(h.config.EfSearch, k), 0)
 
-	// Convert to VectorSearchResult format
+	// Convert to VectorSearchResult format, leaving out tombstones
 	vectorResults := make([]core.VectorSearchResult, 0, k)
-	for i, result := range results {
-		if i >= k {
+	seen := make(map[string]bool, k)
+	for _, result := range results {
+		if len(vectorResults) >= k {
 			break
 		}
-
-		// Find the corresponding vector
-		var vector *core.Vector
-		for _, v := range h.vectors {
-			if v.ID == result.Node.ID {
-				vector = v
-				break
-			}
+		if result.Node.deleted || seen[result.Node.ID] {
+			continue
 		}
+		seen[result.Node.ID] = true
 
-		if vector != nil {
+		if vector := h.vectors[result.Node.ID]; vector != nil {
 			vectorResults = append(vectorResults, core.VectorSearchResult{
 				Vector:   vector,
 				Distance: result.Distance,
@@ -357,7 +410,7 @@ func (h *HNSWIndex) searchLayer(query []float64, entryPoints []*Node, ef int, le
 
 	// Initialize candidates and visited sets
 	candidates := make([]*SearchResult, 0, ef)
-	visited := make(map[string]bool)
+	visited := make(map[*Node]bool)
 
 	// Add entry points to candidates
 	for _, entry := range entryPoints {
@@ -366,7 +419,7 @@ func (h *HNSWIndex) searchLayer(query []float64, entryPoints []*Node, ef int, le
 			Node:     entry,
 			Distance: distance,
 		})
-		visited[entry.ID] = true
+		visited[entry] = true
 	}
 
 	// Sort candidates by distance
@@ -390,11 +443,11 @@ func (h *HNSWIndex) searchLayer(query []float64, entryPoints []*Node, ef int, le
 			}
 
 			friend := h.layers[level][friendIndex]
-			if friend == nil || visited[friend.ID] {
+			if friend == nil || visited[friend] {
 				continue
 			}
 
-			visited[friend.ID] = true
+			visited[friend] = true
 			distance := h.calculateDistance(query, friend.Vector)
 
 			// Add to candidates if it's better than worst candidate
@@ -441,6 +494,7 @@ func (h *HNSWIndex) insertHNSW(vector *core.Vector) error {
 	for l := 0; l <= level; l++ {
 		h.layers[l] = append(h.layers[l], newNode)
 	}
+	h.nodes[vector.ID] = append(h.nodes[vector.ID], newNode)
 
 	// If this is the first node, set it as entry point
 	if h.entryPoint == nil {
@@ -1,6 +1,7 @@
 package index
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/vijaynallagatla/vjvector/pkg/core"
@@ -179,11 +180,94 @@ func TestHNSWIndex_Delete(t *testing.T) {
 		t.Errorf("Expected 1 vector, got %d", stats.TotalVectors)
 	}
 
-	// Delete the vector (note: current implementation is placeholder)
-	_ = idx.Delete("test1") // Ignore error since delete is not fully implemented
+	if err := idx.Delete("test1"); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if stats := idx.GetStats(); stats.TotalVectors != 0 {
+		t.Errorf("Expected 0 vectors, got %d", stats.TotalVectors)
+	}
+	if err := idx.Delete("test1"); err != ErrVectorNotFound {
+		t.Errorf("Expected ErrVectorNotFound for a deleted vector, got %v", err)
+	}
+}
+
+func TestHNSWIndex_DeletedVectorsAreNotFound(t *testing.T) {
+	idx, err := NewHNSWIndex(IndexConfig{
+		Type:           IndexTypeHNSW,
+		Dimension:      4,
+		MaxElements:    100,
+		M:              4,
+		EfConstruction: 50,
+		EfSearch:       50,
+		MaxLayers:      4,
+		DistanceMetric: "euclidean",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create HNSW index: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		vector := &core.Vector{ID: fmt.Sprintf("v%d", i), Embedding: []float64{float64(i), 0, 0, 0}}
+		if err := idx.Insert(vector); err != nil {
+			t.Fatalf("Failed to insert vector: %v", err)
+		}
+	}
+
+	// A deleted vector is no longer found, even by its own embedding
+	if err := idx.Delete("v5"); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	results, err := idx.Search([]float64{5, 0, 0, 0}, 3)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	for _, result := range results {
+		if result.Vector.ID == "v5" {
+			t.Errorf("Expected v5 to be deleted, got %+v", results)
+		}
+	}
+
+	// A replaced vector is no longer found by its old embedding
+	if err := idx.Insert(&core.Vector{ID: "v7", Embedding: []float64{12.5, 0, 0, 0}}); err != nil {
+		t.Fatalf("Failed to replace vector: %v", err)
+	}
+	results, err = idx.Search([]float64{7, 0, 0, 0}, 20)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	for _, result := range results {
+		if result.Vector.ID == "v7" && result.Distance != 5.5 {
+			t.Errorf("Expected v7 at its new embedding, got distance %v", result.Distance)
+		}
+	}
+	for _, node := range idx.(*HNSWIndex).nodes["v7"] {
+		if node.deleted || node.Vector[0] != 12.5 {
+			t.Errorf("Expected the nodes of v7 to hold its new embedding, got %+v", node)
+		}
+	}
+	if stats := idx.GetStats(); stats.TotalVectors != 19 {
+		t.Errorf("Expected 19 vectors, got %d", stats.TotalVectors)
+	}
 
-	// Note: Current implementation is placeholder - in real implementation
-	// we would verify the vector count decreased
+	// Deleting most vectors rebuilds the graph without its tombstones
+	for i := 0; i < 18; i++ {
+		if i == 5 {
+			continue
+		}
+		if err := idx.Delete(fmt.Sprintf("v%d", i)); err != nil {
+			t.Fatalf("Failed to delete v%d: %v", i, err)
+		}
+	}
+	results, err = idx.Search([]float64{0, 0, 0, 0}, 10)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 2 || results[0].Vector.ID != "v18" || results[1].Vector.ID != "v19" {
+		t.Errorf("Expected v18 and v19, got %+v", results)
+	}
+	if hnsw := idx.(*HNSWIndex); hnsw.tombstones > len(hnsw.vectors) {
+		t.Errorf("Expected tombstones to be dropped, have %d for %d vectors", hnsw.tombstones, len(hnsw.vectors))
+	}
 }
 
 func TestHNSWIndex_RandomLevel(t *testing.T) {
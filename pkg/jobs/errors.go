@@ -0,0 +1,13 @@
+package jobs
+
+import "errors"
+
+// Job-related errors
+var (
+	ErrInvalidConfig  = errors.New("invalid job manager configuration")
+	ErrUnknownJobType = errors.New("unknown job type")
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobFinished    = errors.New("job already finished")
+	ErrInterrupted    = errors.New("job was interrupted by a shutdown")
+	ErrManagerClosed  = errors.New("job manager is closed")
+)
@@ -0,0 +1,472 @@
+// Package jobs runs long-running operations in the background. Every job has
+// a record of its state, progress and result, which is kept on disk so that
+// it can be polled long after the request that submitted the job, and across
+// restarts of the process.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/vijaynallagatla/vjvector/pkg/batch"
+)
+
+// State is the stage of its lifecycle a job is in
+type State string
+
+// Job states
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCanceled  State = "canceled"
+)
+
+// Finished reports whether a job in the state is done and will not change again
+func (s State) Finished() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCanceled
+}
+
+// Progress reports how many of the items of a job are processed
+type Progress struct {
+	Processed int `json:"processed"`
+	Total     int `json:"total"`
+
+	// Elapsed is the running time of the job when progress was last reported
+	Elapsed string `json:"elapsed,omitempty"`
+}
+
+// Job is the record of a job
+type Job struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	TenantID string          `json:"tenant_id"`
+	State    State           `json:"state"`
+	Progress Progress        `json:"progress"`
+	Params   json.RawMessage `json:"params,omitempty"`
+
+	// Result is what the job returned once it succeeded, and Error why it
+	// failed
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	// CancelRequested is set once the job is asked to stop before it finished
+	CancelRequested bool `json:"cancel_requested,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// clone returns a copy of the job that shares none of its mutable fields
+func (j *Job) clone() *Job {
+	copied := *j
+	copied.Params = append(json.RawMessage(nil), j.Params...)
+	copied.Result = append(json.RawMessage(nil), j.Result...)
+	if j.StartedAt != nil {
+		started := *j.StartedAt
+		copied.StartedAt = &started
+	}
+	if j.FinishedAt != nil {
+		finished := *j.FinishedAt
+		copied.FinishedAt = &finished
+	}
+	return &copied
+}
+
+// Listener is told about every job once it finished
+type Listener func(job *Job)
+
+// Runner runs the jobs of a type, reporting how far it got through progress,
+// and returns the result of the job, which must encode to JSON. It must
+// return once ctx is done, which it is when the job is canceled.
+type Runner func(ctx context.Context, job *Job, progress batch.BatchProgressCallback) (interface{}, error)
+
+// Config holds configuration parameters for the job manager
+type Config struct {
+	// Dir is the directory job records are kept in; they are only kept in
+	// memory when it is empty
+	Dir string `json:"dir"`
+
+	// Workers is the number of jobs run at once; the others wait their turn
+	Workers int `json:"workers"`
+
+	// Retention is how long the record of a finished job is kept
+	Retention time.Duration `json:"retention"`
+
+	// MaxFinished is the most records of finished jobs kept within the
+	// retention; the records of the jobs that finished first are deleted
+	// beyond it. Zero keeps them all.
+	MaxFinished int `json:"max_finished"`
+
+	// SaveInterval is the least time between writes of the record of a
+	// running job as it reports progress
+	SaveInterval time.Duration `json:"save_interval"`
+}
+
+// DefaultConfig returns the job manager configuration used by the API server,
+// keeping records in dir
+func DefaultConfig(dir string) Config {
+	return Config{
+		Dir:          dir,
+		Workers:      2,
+		Retention:    24 * time.Hour,
+		MaxFinished:  1000,
+		SaveInterval: time.Second,
+	}
+}
+
+// entry is a job with the state of its run
+type entry struct {
+	job    Job
+	cancel context.CancelFunc
+	saved  time.Time
+}
+
+// Manager runs jobs in the background and keeps their records
+type Manager struct {
+	config Config
+	logger *slog.Logger
+
+	// ctx is canceled when the manager is closed, which cancels every job
+	ctx   context.Context
+	stop  context.CancelFunc
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mutex     sync.Mutex
+	runners   map[string]Runner
+	listeners []Listener
+	jobs      map[string]*entry
+	closed    bool
+}
+
+// NewManager creates a job manager, loading the records kept in the
+// configured directory. Jobs that were not finished when the previous process
+// stopped cannot be resumed, and are recorded as failed.
+func NewManager(config Config, logger *slog.Logger) (*Manager, error) {
+	if config.Workers <= 0 || config.Retention <= 0 || config.MaxFinished < 0 || config.SaveInterval < 0 {
+		return nil, ErrInvalidConfig
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		config:  config,
+		logger:  logger,
+		ctx:     ctx,
+		stop:    stop,
+		slots:   make(chan struct{}, config.Workers),
+		runners: make(map[string]Runner),
+		jobs:    make(map[string]*entry),
+	}
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0700); err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create job directory: %w", err)
+		}
+		if err := m.load(); err != nil {
+			stop()
+			return nil, err
+		}
+	}
+	m.prune(time.Now())
+
+	m.wg.Add(1)
+	go m.janitor()
+	return m, nil
+}
+
+// Register sets the runner of the jobs of a type
+func (m *Manager) Register(jobType string, run Runner) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.runners[jobType] = run
+}
+
+// OnFinish adds a listener told about every job that finishes from now on;
+// listeners are called in turn and must not block
+func (m *Manager) OnFinish(listener Listener) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+// Submit records a job of a tenant and starts it once a worker is free
+func (m *Manager) Submit(tenantID, jobType string, params json.RawMessage) (*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	run, exists := m.runners[jobType]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobType, jobType)
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	e := &entry{
+		job: Job{
+			ID:        "job_" + uuid.NewString(),
+			Type:      jobType,
+			TenantID:  tenantID,
+			State:     StatePending,
+			Params:    append(json.RawMessage(nil), params...),
+			CreatedAt: time.Now().UTC(),
+		},
+		cancel: cancel,
+	}
+	if err := m.save(e); err != nil {
+		cancel()
+		return nil, err
+	}
+	m.jobs[e.job.ID] = e
+
+	m.wg.Add(1)
+	go m.run(ctx, e, run)
+	return e.job.clone(), nil
+}
+
+// Get returns the record of a job
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	e, exists := m.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return e.job.clone(), nil
+}
+
+// List returns the records of the jobs of a tenant, newest first
+func (m *Manager) List(tenantID string, limit, offset int) []*Job {
+	m.mutex.Lock()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		if e.job.TenantID == tenantID {
+			jobs = append(jobs, e.job.clone())
+		}
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+
+	if offset >= len(jobs) {
+		return []*Job{}
+	}
+	end := len(jobs)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return jobs[offset:end]
+}
+
+// Cancel asks a job to stop. A pending job never starts; a running job is
+// recorded as canceled once its runner returns.
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	e, exists := m.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if e.job.State.Finished() {
+		return nil, fmt.Errorf("%w: %s is %s", ErrJobFinished, id, e.job.State)
+	}
+
+	if !e.job.CancelRequested {
+		e.job.CancelRequested = true
+		e.cancel()
+		if err := m.save(e); err != nil {
+			m.logger.Error("Failed to save job", "job", id, "error", err)
+		}
+	}
+	return e.job.clone(), nil
+}
+
+// Close cancels every job, waits for their runners to return and stops the
+// manager. Jobs stopped this way are recorded as interrupted.
+func (m *Manager) Close() error {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.mutex.Unlock()
+
+	m.stop()
+	m.wg.Wait()
+	return nil
+}
+
+// run runs a job once a worker is free and records its outcome
+func (m *Manager) run(ctx context.Context, e *entry, run Runner) {
+	defer m.wg.Done()
+	defer e.cancel()
+
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		m.finish(e, nil, ctx.Err())
+		return
+	}
+	if err := ctx.Err(); err != nil {
+		m.finish(e, nil, err)
+		return
+	}
+
+	m.mutex.Lock()
+	started := time.Now().UTC()
+	e.job.State = StateRunning
+	e.job.StartedAt = &started
+	if err := m.save(e); err != nil {
+		m.logger.Error("Failed to save job", "job", e.job.ID, "error", err)
+	}
+	job := e.job.clone()
+	m.mutex.Unlock()
+
+	result, err := run(ctx, job, func(processed, total int, _ time.Duration) {
+		m.report(e, processed, total, time.Since(started))
+	})
+	m.finish(e, result, err)
+}
+
+// report records the progress of a running job, writing its record at most
+// once per save interval
+func (m *Manager) report(e *entry, processed, total int, elapsed time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if e.job.State != StateRunning {
+		return
+	}
+	e.job.Progress = Progress{
+		Processed: processed,
+		Total:     total,
+		Elapsed:   elapsed.Round(time.Millisecond).String(),
+	}
+	if time.Since(e.saved) < m.config.SaveInterval {
+		return
+	}
+	if err := m.save(e); err != nil {
+		m.logger.Error("Failed to save job", "job", e.job.ID, "error", err)
+	}
+}
+
+// finish records the outcome of a job and tells the listeners about it
+func (m *Manager) finish(e *entry, result interface{}, err error) {
+	m.mutex.Lock()
+
+	if err == nil {
+		e.job.Result, err = json.Marshal(result)
+		if err != nil {
+			err = fmt.Errorf("failed to encode job result: %w", err)
+		}
+	}
+	switch {
+	case err == nil:
+		e.job.State = StateSucceeded
+	case e.job.CancelRequested:
+		e.job.State = StateCanceled
+	case m.ctx.Err() != nil:
+		e.job.State, e.job.Error = StateFailed, ErrInterrupted.Error()
+	default:
+		e.job.State, e.job.Error = StateFailed, err.Error()
+	}
+	finished := time.Now().UTC()
+	e.job.FinishedAt = &finished
+	if e.job.StartedAt != nil {
+		e.job.Progress.Elapsed = finished.Sub(*e.job.StartedAt).Round(time.Millisecond).String()
+	}
+
+	if err := m.save(e); err != nil {
+		m.logger.Error("Failed to save job", "job", e.job.ID, "error", err)
+	}
+	job, listeners := e.job.clone(), m.listeners
+	m.mutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(job)
+	}
+}
+
+// janitor deletes the records of finished jobs once they are past retention
+// or over the most kept
+func (m *Manager) janitor() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(min(m.config.Retention, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.prune(now)
+		}
+	}
+}
+
+// prune deletes the records of jobs that finished longer than the retention
+// before now, then those of the jobs that finished first beyond the most
+// finished jobs kept
+func (m *Manager) prune(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cutoff := now.Add(-m.config.Retention)
+	var finished []*entry
+	for _, e := range m.jobs {
+		if !e.job.State.Finished() || e.job.FinishedAt == nil {
+			continue
+		}
+		if e.job.FinishedAt.After(cutoff) {
+			finished = append(finished, e)
+			continue
+		}
+		m.discard(e)
+	}
+
+	if m.config.MaxFinished == 0 || len(finished) <= m.config.MaxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		if !finished[i].job.FinishedAt.Equal(*finished[j].job.FinishedAt) {
+			return finished[i].job.FinishedAt.Before(*finished[j].job.FinishedAt)
+		}
+		return finished[i].job.ID < finished[j].job.ID
+	})
+	for _, e := range finished[:len(finished)-m.config.MaxFinished] {
+		m.discard(e)
+	}
+}
+
+// discard deletes the record of a finished job; the caller must hold the mutex
+func (m *Manager) discard(e *entry) {
+	if err := m.remove(e.job.ID); err != nil {
+		m.logger.Error("Failed to delete job record", "job", e.job.ID, "error", err)
+		return
+	}
+	delete(m.jobs, e.job.ID)
+}
@@ -0,0 +1,253 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/batch"
+)
+
+func newTestManager(t *testing.T, dir string) *Manager {
+	t.Helper()
+
+	config := DefaultConfig(dir)
+	config.SaveInterval = 0
+	m, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+	return m
+}
+
+// waitFinished polls a job until it finished
+func waitFinished(t *testing.T, m *Manager, id string) *Job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Failed to get job: %v", err)
+		}
+		if job.State.Finished() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return nil
+}
+
+func TestManager_RunsJobsAndKeepsRecords(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+
+	m.Register("count", func(ctx context.Context, job *Job, progress batch.BatchProgressCallback) (interface{}, error) {
+		var params struct {
+			N int `json:"n"`
+		}
+		if err := json.Unmarshal(job.Params, &params); err != nil {
+			return nil, err
+		}
+		for i := 1; i <= params.N; i++ {
+			progress(i, params.N, 0)
+		}
+		return map[string]int{"counted": params.N}, nil
+	})
+	m.Register("fail", func(context.Context, *Job, batch.BatchProgressCallback) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	finished := make(chan *Job, 4)
+	m.OnFinish(func(job *Job) { finished <- job })
+
+	if _, err := m.Submit("acme", "missing", nil); !errors.Is(err, ErrUnknownJobType) {
+		t.Errorf("Expected ErrUnknownJobType, got %v", err)
+	}
+
+	submitted, err := m.Submit("acme", "count", json.RawMessage(`{"n":3}`))
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	job := waitFinished(t, m, submitted.ID)
+	if job.State != StateSucceeded {
+		t.Fatalf("Expected job to succeed, got %s: %s", job.State, job.Error)
+	}
+	if job.Progress.Processed != 3 || job.Progress.Total != 3 {
+		t.Errorf("Expected progress 3/3, got %d/%d", job.Progress.Processed, job.Progress.Total)
+	}
+	if string(job.Result) != `{"counted":3}` {
+		t.Errorf("Unexpected result %s", job.Result)
+	}
+	if job.StartedAt == nil || job.FinishedAt == nil {
+		t.Error("Expected start and finish times")
+	}
+	if told := <-finished; told.ID != job.ID || told.State != StateSucceeded {
+		t.Errorf("Expected listeners to be told about the finished job, got %+v", told)
+	}
+
+	failed, err := m.Submit("other", "fail", nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	if job := waitFinished(t, m, failed.ID); job.State != StateFailed || job.Error != "boom" {
+		t.Errorf("Expected failed job, got %s: %s", job.State, job.Error)
+	}
+
+	// Jobs are listed per tenant
+	if jobs := m.List("acme", 10, 0); len(jobs) != 1 || jobs[0].ID != submitted.ID {
+		t.Errorf("Expected the job of acme only, got %v", jobs)
+	}
+	if _, err := m.Cancel(submitted.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Expected ErrJobFinished, got %v", err)
+	}
+
+	// Records survive a restart of the manager
+	if err := m.Close(); err != nil {
+		t.Fatalf("Failed to close job manager: %v", err)
+	}
+	if _, err := m.Submit("acme", "count", nil); !errors.Is(err, ErrManagerClosed) {
+		t.Errorf("Expected ErrManagerClosed, got %v", err)
+	}
+	m = newTestManager(t, dir)
+	defer func() { _ = m.Close() }()
+
+	reloaded, err := m.Get(submitted.ID)
+	if err != nil {
+		t.Fatalf("Failed to get reloaded job: %v", err)
+	}
+	if reloaded.State != StateSucceeded || string(reloaded.Result) != `{"counted":3}` {
+		t.Errorf("Unexpected reloaded job %+v", reloaded)
+	}
+}
+
+func TestManager_CancelAndInterrupt(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig(dir)
+	config.Workers = 1
+	m, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+
+	started := make(chan struct{}, 4)
+	m.Register("block", func(ctx context.Context, _ *Job, _ batch.BatchProgressCallback) (interface{}, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	running, err := m.Submit("default", "block", nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	<-started
+
+	// With one worker busy the next job waits, and is canceled before it starts
+	pending, err := m.Submit("default", "block", nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	if job, err := m.Cancel(pending.ID); err != nil || !job.CancelRequested {
+		t.Fatalf("Failed to cancel job: %v", err)
+	}
+	if job := waitFinished(t, m, pending.ID); job.State != StateCanceled || job.StartedAt != nil {
+		t.Errorf("Expected job canceled before it started, got %+v", job)
+	}
+
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatalf("Failed to cancel job: %v", err)
+	}
+	if job := waitFinished(t, m, running.ID); job.State != StateCanceled {
+		t.Errorf("Expected canceled job, got %s", job.State)
+	}
+	if _, err := m.Cancel("job_missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	// Jobs running when the manager closes are recorded as interrupted
+	interrupted, err := m.Submit("default", "block", nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	<-started
+	if err := m.Close(); err != nil {
+		t.Fatalf("Failed to close job manager: %v", err)
+	}
+	m = newTestManager(t, dir)
+	defer func() { _ = m.Close() }()
+
+	job, err := m.Get(interrupted.ID)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if job.State != StateFailed || job.Error != ErrInterrupted.Error() {
+		t.Errorf("Expected interrupted job, got %s: %s", job.State, job.Error)
+	}
+}
+
+func TestManager_PrunesFinishedJobs(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+	defer func() { _ = m.Close() }()
+
+	m.Register("noop", func(context.Context, *Job, batch.BatchProgressCallback) (interface{}, error) {
+		return nil, nil
+	})
+	submitted, err := m.Submit("default", "noop", nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	waitFinished(t, m, submitted.ID)
+
+	m.prune(time.Now())
+	if _, err := m.Get(submitted.ID); err != nil {
+		t.Errorf("Expected job to be retained, got %v", err)
+	}
+	m.prune(time.Now().Add(25 * time.Hour))
+	if _, err := m.Get(submitted.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected job to be pruned, got %v", err)
+	}
+}
+
+func TestManager_KeepsMostFinishedJobs(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultConfig(dir)
+	config.MaxFinished = 2
+	m, err := NewManager(config, nil)
+	if err != nil {
+		t.Fatalf("Failed to create job manager: %v", err)
+	}
+	defer func() { _ = m.Close() }()
+
+	m.Register("noop", func(context.Context, *Job, batch.BatchProgressCallback) (interface{}, error) {
+		return nil, nil
+	})
+	var ids []string
+	for range 3 {
+		submitted, err := m.Submit("default", "noop", nil)
+		if err != nil {
+			t.Fatalf("Failed to submit job: %v", err)
+		}
+		waitFinished(t, m, submitted.ID)
+		ids = append(ids, submitted.ID)
+	}
+
+	// The job that finished first is deleted, with its record
+	m.prune(time.Now())
+	if _, err := m.Get(ids[0]); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected the oldest job to be pruned, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ids[0]+recordSuffix)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the record of the oldest job to be deleted, got %v", err)
+	}
+	for _, id := range ids[1:] {
+		if _, err := m.Get(id); err != nil {
+			t.Errorf("Expected job %s to be kept, got %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// recordSuffix ends the file name of every job record
+const recordSuffix = ".json"
+
+// recordPath returns the path of the record of a job
+func (m *Manager) recordPath(id string) string {
+	return filepath.Join(m.config.Dir, id+recordSuffix)
+}
+
+// save atomically writes the record of a job; the caller must hold the mutex
+func (m *Manager) save(e *entry) error {
+	e.saved = time.Now()
+	if m.config.Dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(&e.job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	path := m.recordPath(e.job.ID)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace job: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the record of a job; the caller must hold the mutex
+func (m *Manager) remove(id string) error {
+	if m.config.Dir == "" {
+		return nil
+	}
+	if err := os.Remove(m.recordPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// load reads the job records of the directory, recording the jobs that were
+// not finished as interrupted. Records that cannot be read are skipped, and
+// the temporary files of writes that were cut short are deleted.
+func (m *Manager) load() error {
+	files, err := os.ReadDir(m.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read job directory: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, file := range files {
+		name := file.Name()
+		if !file.IsDir() && strings.HasSuffix(name, recordSuffix+".tmp") {
+			if err := os.Remove(filepath.Join(m.config.Dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				m.logger.Warn("Failed to delete temporary job record", "name", name, "error", err)
+			}
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(name, recordSuffix) {
+			continue
+		}
+
+		path := filepath.Join(m.config.Dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read job: %w", err)
+		}
+		e := &entry{cancel: func() {}}
+		if err := json.Unmarshal(data, &e.job); err != nil || e.job.ID+recordSuffix != name {
+			m.logger.Warn("Skipping unreadable job record", "path", path, "error", err)
+			continue
+		}
+
+		if !e.job.State.Finished() {
+			e.job.State, e.job.Error = StateFailed, ErrInterrupted.Error()
+			e.job.FinishedAt = &now
+			if err := m.save(e); err != nil {
+				return err
+			}
+		}
+		m.jobs[e.job.ID] = e
+	}
+	return nil
+}
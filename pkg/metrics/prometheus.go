@@ -32,6 +32,9 @@ type PrometheusMetrics struct {
 	vectorsDeleted  prometheus.Counter
 	vectorsSearched prometheus.Counter
 
+	// Expiry metrics are read from expirySource on every scrape
+	expirySource func() (reaped, filtered uint64)
+
 	// Storage metrics
 	storageBytesUsed  prometheus.Gauge
 	storageBytesTotal prometheus.Gauge
@@ -135,6 +138,23 @@ func (pm *PrometheusMetrics) initializeMetrics() {
 		Help: "Total number of vector searches performed",
 	})
 
+	// Expiry metrics
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "vjvector_vectors_expired_total",
+		Help: "Total number of expired vectors deleted by the reaper",
+	}, func() float64 {
+		reaped, _ := pm.expiryCounts()
+		return float64(reaped)
+	})
+
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "vjvector_vectors_expired_filtered_total",
+		Help: "Total number of expired vectors filtered out of search results before being reaped",
+	}, func() float64 {
+		_, filtered := pm.expiryCounts()
+		return float64(filtered)
+	})
+
 	// Storage metrics
 	pm.storageBytesUsed = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "vjvector_storage_bytes_used",
@@ -261,6 +281,26 @@ func (pm *PrometheusMetrics) RecordVectorOperation(operation string, count int)
 	}
 }
 
+// SetExpirySource sets the function reporting cumulative expiry counts
+func (pm *PrometheusMetrics) SetExpirySource(source func() (reaped, filtered uint64)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.expirySource = source
+}
+
+// expiryCounts returns the counts of the expiry source, or zero without one
+func (pm *PrometheusMetrics) expiryCounts() (reaped, filtered uint64) {
+	pm.mu.RLock()
+	source := pm.expirySource
+	pm.mu.RUnlock()
+
+	if source == nil {
+		return 0, 0
+	}
+	return source()
+}
+
 // SetVectorCount sets the total number of vectors
 func (pm *PrometheusMetrics) SetVectorCount(count int) {
 	pm.vectorsTotal.Set(float64(count))
@@ -73,13 +73,20 @@ func NewEngine(config *Config, embeddingService embedding.Service, vectorIndex i
 	return e, nil
 }
 
-// ProcessQuery processes a RAG query
+// ProcessQuery processes a RAG query. The query is expanded, embedded,
+// searched for and reranked in turn, reporting each stage to the query trace
+// of ctx; responses served from the cache report no stages. The query stops
+// between stages once ctx is done, failing with the error of ctx.
 func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse, error) {
 	start := time.Now()
 	e.mu.Lock()
 	e.stats.TotalQueries++
 	e.mu.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, e.fail(err)
+	}
+
 	// Check cache first
 	if e.cache != nil {
 		if cached, hit := e.cache.Get(query); hit {
@@ -96,14 +103,24 @@ func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse
 		ctx, cancel = context.WithTimeout(ctx, e.config.QueryTimeout)
 		defer cancel()
 	}
+	trace := ContextQueryTrace(ctx)
 
 	// Process query through processors
-	processedQuery, err := e.processQuery(query)
+	processedQuery, err := e.processQuery(ctx, query)
 	if err != nil {
-		e.mu.Lock()
-		e.stats.FailedQueries++
-		e.mu.Unlock()
-		return nil, fmt.Errorf("query processing failed: %w", err)
+		return nil, e.fail(fmt.Errorf("query processing failed: %w", err))
+	}
+
+	// Expansion only informs the response, but runs first so that the query
+	// trace reports the stages in the order of the pipeline
+	var expandedTerms []string
+	if e.config.EnableQueryExpansion {
+		if expandedTerms, err = e.ExpandQuery(ctx, processedQuery); err == nil {
+			trace.Expanded(expandedTerms)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, e.fail(err)
 	}
 
 	// Generate query embedding
@@ -115,17 +132,14 @@ func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse
 
 	embeddingResp, err := e.embeddingService.GenerateEmbeddings(ctx, embeddingReq)
 	if err != nil {
-		e.mu.Lock()
-		e.stats.FailedQueries++
-		e.mu.Unlock()
-		return nil, fmt.Errorf("embedding generation failed: %w", err)
+		return nil, e.fail(fmt.Errorf("embedding generation failed: %w", err))
 	}
 
 	if len(embeddingResp.Embeddings) == 0 {
-		e.mu.Lock()
-		e.stats.FailedQueries++
-		e.mu.Unlock()
-		return nil, fmt.Errorf("no embeddings generated")
+		return nil, e.fail(fmt.Errorf("no embeddings generated"))
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, e.fail(err)
 	}
 
 	// Search vector index
@@ -135,12 +149,9 @@ func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse
 		maxResults = 10
 	}
 
-	searchResults, err := e.vectorIndex.Search(queryVector, maxResults)
+	searchResults, err := e.vectorIndex.SearchWithContext(ctx, queryVector, maxResults)
 	if err != nil {
-		e.mu.Lock()
-		e.stats.FailedQueries++
-		e.mu.Unlock()
-		return nil, fmt.Errorf("vector search failed: %w", err)
+		return nil, e.fail(fmt.Errorf("vector search failed: %w", err))
 	}
 
 	// Convert to RAG results
@@ -153,6 +164,10 @@ func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse
 			Relevance: 1.0 - result.Distance, // Convert distance to relevance
 		}
 	}
+	trace.Candidates(ragResults)
+	if err := ctx.Err(); err != nil {
+		return nil, e.fail(err)
+	}
 
 	// Apply reranking if enabled
 	if e.config.EnableReranking && len(ragResults) > 1 {
@@ -161,8 +176,12 @@ func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse
 			e.logger.Warn("Reranking failed, using original results", "error", err)
 		} else {
 			ragResults = rerankedResults
+			trace.Reranked(ragResults)
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, e.fail(err)
+	}
 
 	// Create response
 	response := &QueryResponse{
@@ -170,17 +189,10 @@ func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse
 		Query:          processedQuery,
 		TotalResults:   len(ragResults),
 		ProcessingTime: time.Since(start),
+		QueryExpansion: expandedTerms,
 		Metadata:       make(map[string]interface{}),
 	}
 
-	// Add query expansion info if enabled
-	if e.config.EnableQueryExpansion {
-		expandedTerms, err := e.ExpandQuery(ctx, processedQuery)
-		if err == nil {
-			response.QueryExpansion = expandedTerms
-		}
-	}
-
 	// Cache the result
 	if e.cache != nil {
 		err := e.cache.Set(processedQuery, response)
@@ -206,6 +218,14 @@ func (e *engine) ProcessQuery(ctx context.Context, query *Query) (*QueryResponse
 	return response, nil
 }
 
+// fail counts a failed query and returns its error
+func (e *engine) fail(err error) error {
+	e.mu.Lock()
+	e.stats.FailedQueries++
+	e.mu.Unlock()
+	return err
+}
+
 // ProcessBatch processes multiple queries in batch
 func (e *engine) ProcessBatch(ctx context.Context, queries []*Query) ([]*QueryResponse, error) {
 	if len(queries) == 0 {
@@ -323,7 +343,7 @@ func (e *engine) Close() error {
 }
 
 // processQuery processes a query through all registered processors
-func (e *engine) processQuery(query *Query) (*Query, error) {
+func (e *engine) processQuery(ctx context.Context, query *Query) (*Query, error) {
 	processedQuery := query
 
 	// Sort processors by priority
@@ -334,7 +354,7 @@ func (e *engine) processQuery(query *Query) (*Query, error) {
 
 	// Process through each processor
 	for _, processor := range processors {
-		processed, err := processor.Process(context.Background(), processedQuery)
+		processed, err := processor.Process(ctx, processedQuery)
 		if err != nil {
 			return nil, fmt.Errorf("processor %s failed: %w", processor.Type(), err)
 		}
@@ -0,0 +1,108 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding/providers"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+)
+
+// stubIndex answers every search with the same results, counting searches
+type stubIndex struct {
+	index.VectorIndex
+	results  []core.VectorSearchResult
+	searches int
+}
+
+func (s *stubIndex) Search(query []float64, k int) ([]core.VectorSearchResult, error) {
+	return s.SearchWithContext(context.Background(), query, k)
+}
+
+func (s *stubIndex) SearchWithContext(_ context.Context, _ []float64, k int) ([]core.VectorSearchResult, error) {
+	s.searches++
+	return s.results[:min(k, len(s.results))], nil
+}
+
+func newTestEngine(t *testing.T) (Engine, *stubIndex) {
+	t.Helper()
+
+	service, err := providers.NewHashService(3)
+	require.NoError(t, err)
+	vectors := &stubIndex{results: []core.VectorSearchResult{
+		{Vector: &core.Vector{ID: "vec1", Embedding: []float64{1, 0, 0}}, Score: 0.9, Distance: 0.1},
+		{Vector: &core.Vector{ID: "vec2", Embedding: []float64{0, 1, 0}}, Score: 0.7, Distance: 0.3},
+		{Vector: &core.Vector{ID: "vec3", Embedding: []float64{0, 0, 1}}, Score: 0.5, Distance: 0.5},
+	}}
+
+	engine, err := NewEngine(&Config{
+		EnableQueryExpansion: true,
+		EnableReranking:      true,
+		MaxQueryLength:       1000,
+		MaxExpansionTerms:    5,
+		MaxConcurrentQueries: 1,
+		QueryTimeout:         time.Minute,
+	}, service, vectors)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = engine.Close() })
+	return engine, vectors
+}
+
+func TestEngine_ProcessQueryTracesStages(t *testing.T) {
+	engine, _ := newTestEngine(t)
+
+	var stages []string
+	var candidates int
+	ctx := WithQueryTrace(context.Background(), &QueryTrace{
+		Expanded: func([]string) { stages = append(stages, "expanded") },
+		Candidates: func(results []*QueryResult) {
+			stages = append(stages, "candidates")
+			candidates = len(results)
+		},
+		Reranked: func([]*QueryResult) { stages = append(stages, "reranked") },
+	})
+
+	response, err := engine.ProcessQuery(ctx, &Query{Text: "nearest neighbors", MaxResults: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"expanded", "candidates", "reranked"}, stages)
+	assert.Equal(t, 2, candidates)
+	assert.Equal(t, 2, response.TotalResults)
+
+	// Queries without a trace are processed alike
+	response, err = engine.ProcessQuery(context.Background(), &Query{Text: "other neighbors", MaxResults: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 3, response.TotalResults)
+}
+
+func TestEngine_ProcessQueryCancellation(t *testing.T) {
+	engine, vectors := newTestEngine(t)
+
+	// Queries of a context already done are not searched
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := engine.ProcessQuery(ctx, &Query{Text: "nearest neighbors"})
+	assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+	assert.Equal(t, 0, vectors.searches)
+
+	// Queries stop at the stage after the one their context is done in
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	reranked := false
+	ctx = WithQueryTrace(ctx, &QueryTrace{
+		Candidates: func([]*QueryResult) { cancel() },
+		Reranked:   func([]*QueryResult) { reranked = true },
+	})
+	_, err = engine.ProcessQuery(ctx, &Query{Text: "nearest neighbors"})
+	assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+	assert.Equal(t, 1, vectors.searches)
+	assert.False(t, reranked)
+
+	stats := engine.GetQueryStats()
+	assert.Equal(t, int64(2), stats.FailedQueries)
+	assert.Equal(t, int64(0), stats.SuccessfulQueries)
+}
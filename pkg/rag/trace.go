@@ -0,0 +1,48 @@
+package rag
+
+import "context"
+
+// QueryTrace is a set of hooks called as a query passes the stages of a RAG
+// pipeline, such as to stream its progress to a client. Hooks are called on
+// the goroutine processing the query, in the order of the stages, and must
+// not keep the results they are passed, which later stages may rescore.
+// Any hook may be nil.
+type QueryTrace struct {
+	// Expanded is called with the terms the query was expanded with
+	Expanded func(terms []string)
+
+	// Candidates is called with the results of the vector search, before
+	// they are reranked
+	Candidates func(results []*QueryResult)
+
+	// Reranked is called with the reranked results
+	Reranked func(results []*QueryResult)
+}
+
+// queryTraceKey is the context key of the query trace
+type queryTraceKey struct{}
+
+// WithQueryTrace returns a copy of ctx whose queries report their stages to
+// trace
+func WithQueryTrace(ctx context.Context, trace *QueryTrace) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, trace)
+}
+
+// ContextQueryTrace returns the query trace of ctx, with the hooks it does
+// not set, or all of them if ctx has no trace, doing nothing
+func ContextQueryTrace(ctx context.Context) *QueryTrace {
+	trace := &QueryTrace{}
+	if set, ok := ctx.Value(queryTraceKey{}).(*QueryTrace); ok && set != nil {
+		*trace = *set
+	}
+	if trace.Expanded == nil {
+		trace.Expanded = func([]string) {}
+	}
+	if trace.Candidates == nil {
+		trace.Candidates = func([]*QueryResult) {}
+	}
+	if trace.Reranked == nil {
+		trace.Reranked = func([]*QueryResult) {}
+	}
+	return trace
+}
@@ -20,6 +20,11 @@ type EncryptionConfig struct {
 	KeyExpiryDays    int           `json:"key_expiry_days"`
 	MaxKeyUsage      int64         `json:"max_key_usage"`
 	CleanupInterval  time.Duration `json:"cleanup_interval"`
+
+	// KeyStorePath is the file OpenEncryptionService keeps the policies and
+	// keys in, with their key material encrypted by MasterKey, an AES-256 key
+	KeyStorePath string `json:"key_store_path,omitempty"`
+	MasterKey    []byte `json:"-"`
 }
 
 // DefaultEncryptionConfig returns the default encryption configuration
@@ -43,6 +48,10 @@ type DefaultEncryptionService struct {
 	mu            sync.RWMutex
 	cleanupTicker *time.Ticker
 	done          chan bool
+
+	// keyStore is the file the policies and keys are saved to after every
+	// change; the keys only live in memory without one
+	keyStore string
 }
 
 // NewDefaultEncryptionService creates a new default encryption service
@@ -95,18 +104,77 @@ func (s *DefaultEncryptionService) Encrypt(ctx context.Context, data []byte, pol
 	}
 }
 
-// Decrypt decrypts data using the specified key
+// EncryptWithKeyID encrypts data using the specified policy and returns the ID
+// of the key that was used, which Decrypt needs to decrypt the data again
+func (s *DefaultEncryptionService) EncryptWithKeyID(ctx context.Context, data []byte, policyID string) ([]byte, string, error) {
+	s.mu.RLock()
+	policy, exists := s.policies[policyID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, "", fmt.Errorf("encryption policy not found: %s", policyID)
+	}
+
+	key, err := s.ActiveKey(ctx, policyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var encrypted []byte
+	switch policy.Algorithm {
+	case "AES-256":
+		encrypted, err = s.encryptAES(data, key, policy.Mode)
+	case "ChaCha20-Poly1305":
+		encrypted, err = s.encryptChaCha20(data, key)
+	default:
+		err = fmt.Errorf("unsupported encryption algorithm: %s", policy.Algorithm)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return encrypted, key.ID, nil
+}
+
+// ActiveKey returns the key currently used to encrypt data of a policy,
+// generating one when the policy has none
+func (s *DefaultEncryptionService) ActiveKey(ctx context.Context, policyID string) (*EncryptionKey, error) {
+	s.mu.RLock()
+	policy, exists := s.policies[policyID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("encryption policy not found: %s", policyID)
+	}
+
+	if policy.Status != EncryptionPolicyStatusActive {
+		return nil, fmt.Errorf("encryption policy is not active: %s", policyID)
+	}
+
+	key, err := s.getOrCreateKey(ctx, policyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	return key, nil
+}
+
+// Decrypt decrypts data using the specified key. Keys that were rotated out
+// remain usable for decryption so that existing data can be re-encrypted.
 func (s *DefaultEncryptionService) Decrypt(ctx context.Context, encryptedData []byte, keyID string) ([]byte, error) {
 	s.mu.RLock()
 	key, exists := s.keys[keyID]
+	var status EncryptionKeyStatus
+	if exists {
+		status = key.Status
+	}
 	s.mu.RUnlock()
 
 	if !exists {
 		return nil, fmt.Errorf("encryption key not found: %s", keyID)
 	}
 
-	if key.Status != EncryptionKeyStatusActive {
-		return nil, fmt.Errorf("encryption key is not active: %s", keyID)
+	if status != EncryptionKeyStatusActive && status != EncryptionKeyStatusInactive {
+		return nil, fmt.Errorf("encryption key is not usable: %s", keyID)
 	}
 
 	// Check if key has expired
@@ -136,6 +204,12 @@ func (s *DefaultEncryptionService) Decrypt(ctx context.Context, encryptedData []
 
 // GenerateKey generates a new encryption key for a policy
 func (s *DefaultEncryptionService) GenerateKey(ctx context.Context, policyID string) (*EncryptionKey, error) {
+	return s.generateKey(policyID, nil)
+}
+
+// generateKey generates a new encryption key for a policy with additional
+// metadata, which must be set before the key is shared
+func (s *DefaultEncryptionService) generateKey(policyID string, metadata map[string]string) (*EncryptionKey, error) {
 	s.mu.RLock()
 	policy, exists := s.policies[policyID]
 	s.mu.RUnlock()
@@ -166,12 +240,21 @@ func (s *DefaultEncryptionService) GenerateKey(ctx context.Context, policyID str
 		Metadata:   make(map[string]string),
 	}
 
+	for name, value := range metadata {
+		key.Metadata[name] = value
+	}
+
 	// Store key material securely (in production, use HSM or secure key storage)
 	key.Metadata["key_material"] = hex.EncodeToString(keyMaterial)
 
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.keys[key.ID] = key
-	s.mu.Unlock()
+	if err := s.saveKeys(); err != nil {
+		delete(s.keys, key.ID)
+		return nil, err
+	}
 
 	return key, nil
 }
@@ -179,26 +262,29 @@ func (s *DefaultEncryptionService) GenerateKey(ctx context.Context, policyID str
 // RotateKey rotates an existing encryption key
 func (s *DefaultEncryptionService) RotateKey(ctx context.Context, keyID string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	key, exists := s.keys[keyID]
 	if !exists {
+		s.mu.Unlock()
 		return fmt.Errorf("encryption key not found: %s", keyID)
 	}
 
 	// Mark old key as inactive
+	status := key.Status
 	key.Status = EncryptionKeyStatusInactive
+	s.mu.Unlock()
 
-	// Generate new key
-	newKey, err := s.GenerateKey(ctx, key.TenantID)
+	// Generate new key, which saves the old one as inactive
+	_, err := s.generateKey(key.TenantID, map[string]string{
+		"rotated_from":  keyID,
+		"rotation_date": time.Now().Format(time.RFC3339),
+	})
 	if err != nil {
+		s.mu.Lock()
+		key.Status = status
+		s.mu.Unlock()
 		return fmt.Errorf("failed to generate new key: %w", err)
 	}
 
-	// Update key metadata
-	newKey.Metadata["rotated_from"] = keyID
-	newKey.Metadata["rotation_date"] = time.Now().Format(time.RFC3339)
-
 	return nil
 }
 
@@ -233,7 +319,16 @@ func (s *DefaultEncryptionService) CreatePolicy(ctx context.Context, policy *Enc
 		return fmt.Errorf("invalid encryption policy: %w", err)
 	}
 
+	previous, exists := s.policies[policy.ID]
 	s.policies[policy.ID] = policy
+	if err := s.saveKeys(); err != nil {
+		if exists {
+			s.policies[policy.ID] = previous
+		} else {
+			delete(s.policies, policy.ID)
+		}
+		return err
+	}
 	return nil
 }
 
@@ -253,7 +348,16 @@ func (s *DefaultEncryptionService) UpdatePolicy(ctx context.Context, policy *Enc
 		return fmt.Errorf("invalid encryption policy: %w", err)
 	}
 
+	previous, exists := s.policies[policy.ID]
 	s.policies[policy.ID] = policy
+	if err := s.saveKeys(); err != nil {
+		if exists {
+			s.policies[policy.ID] = previous
+		} else {
+			delete(s.policies, policy.ID)
+		}
+		return err
+	}
 	return nil
 }
 
@@ -312,6 +416,7 @@ func (s *DefaultEncryptionService) getOrCreateKey(ctx context.Context, policyID
 			// 	// Key needs rotation, but continue using it for now
 			// 	// In production, implement proper key rotation
 			// }
+			s.mu.RUnlock()
 			return key, nil
 		}
 	}
@@ -554,7 +659,10 @@ func (s *DefaultEncryptionService) cleanup() {
 	defer s.mu.Unlock()
 
 	now := time.Now()
+	changed := false
 	for _, key := range s.keys {
+		status := key.Status
+
 		// Mark expired keys
 		if now.After(key.ExpiresAt) {
 			key.Status = EncryptionKeyStatusExpired
@@ -564,5 +672,11 @@ func (s *DefaultEncryptionService) cleanup() {
 		if key.UsageCount > s.config.MaxKeyUsage {
 			key.Status = EncryptionKeyStatusInactive
 		}
+		changed = changed || key.Status != status
+	}
+
+	// A failed save leaves the statuses to be saved with the next change
+	if changed {
+		_ = s.saveKeys()
 	}
 }
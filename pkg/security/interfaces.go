@@ -226,12 +226,14 @@ type ComplianceService interface {
 type EncryptionService interface {
 	// Encryption Operations
 	Encrypt(ctx context.Context, data []byte, policyID string) ([]byte, error)
+	EncryptWithKeyID(ctx context.Context, data []byte, policyID string) ([]byte, string, error)
 	Decrypt(ctx context.Context, encryptedData []byte, keyID string) ([]byte, error)
 
 	// Key Management
 	GenerateKey(ctx context.Context, policyID string) (*EncryptionKey, error)
 	RotateKey(ctx context.Context, keyID string) error
 	GetKey(ctx context.Context, keyID string) (*EncryptionKey, error)
+	ActiveKey(ctx context.Context, policyID string) (*EncryptionKey, error)
 
 	// Policy Management
 	CreatePolicy(ctx context.Context, policy *EncryptionPolicy) error
@@ -0,0 +1,165 @@
+package security
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// keyStoreVersion is the format version of the key store file
+const keyStoreVersion = 1
+
+// sealedKeyMaterialField holds the key material of a stored key, encrypted
+// with the master key, in place of key_material
+const sealedKeyMaterialField = "sealed_key_material"
+
+// keyStoreFile is the on-disk form of the policies and keys of an encryption service
+type keyStoreFile struct {
+	Version  int                 `json:"version"`
+	Policies []*EncryptionPolicy `json:"policies"`
+	Keys     []*EncryptionKey    `json:"keys"`
+}
+
+// EncryptionConfigFromEnv returns the default encryption configuration with
+// a key store at path and the master key of the VJVECTOR_ENCRYPTION_KEY
+// environment variable, 64 hex digits, or nil when the variable is not set
+func EncryptionConfigFromEnv(path string) (*EncryptionConfig, error) {
+	value := strings.TrimSpace(os.Getenv("VJVECTOR_ENCRYPTION_KEY"))
+	if value == "" {
+		return nil, nil
+	}
+	masterKey, err := hex.DecodeString(value)
+	if err != nil || len(masterKey) != 32 {
+		return nil, fmt.Errorf("VJVECTOR_ENCRYPTION_KEY must be 64 hex digits")
+	}
+
+	config := DefaultEncryptionConfig()
+	config.KeyStorePath = path
+	config.MasterKey = masterKey
+	return config, nil
+}
+
+// OpenEncryptionService creates an encryption service that keeps its
+// policies and keys in config.KeyStorePath, starting with those stored there.
+// Key material is stored encrypted with config.MasterKey, so data encrypted
+// with the keys stays readable across restarts as long as the master key is kept.
+func OpenEncryptionService(config *EncryptionConfig) (*DefaultEncryptionService, error) {
+	if config == nil || config.KeyStorePath == "" {
+		return nil, fmt.Errorf("key store path is required")
+	}
+	if len(config.MasterKey) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(config.MasterKey))
+	}
+
+	service := NewDefaultEncryptionService(config)
+	if err := service.loadKeys(); err != nil {
+		_ = service.Close()
+		return nil, err
+	}
+	service.keyStore = config.KeyStorePath
+	return service, nil
+}
+
+// loadKeys reads the policies and keys of the key store, if it exists
+func (s *DefaultEncryptionService) loadKeys() error {
+	data, err := os.ReadFile(filepath.Clean(s.config.KeyStorePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read key store: %w", err)
+	}
+
+	var file keyStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to decode key store: %w", err)
+	}
+	if file.Version != keyStoreVersion {
+		return fmt.Errorf("unsupported key store version %d", file.Version)
+	}
+
+	block, err := aes.NewCipher(s.config.MasterKey)
+	if err != nil {
+		return fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, policy := range file.Policies {
+		s.policies[policy.ID] = policy
+	}
+	for _, key := range file.Keys {
+		sealed, err := hex.DecodeString(key.Metadata[sealedKeyMaterialField])
+		if err != nil {
+			return fmt.Errorf("invalid key material of key %s: %w", key.ID, err)
+		}
+		material, err := s.decryptAESGCM(block, sealed)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt key material of key %s, is the master key right? %w", key.ID, err)
+		}
+		delete(key.Metadata, sealedKeyMaterialField)
+		key.Metadata["key_material"] = hex.EncodeToString(material)
+		s.keys[key.ID] = key
+	}
+	return nil
+}
+
+// saveKeys writes the policies and keys to the key store, if the service has
+// one; the caller must hold the lock
+func (s *DefaultEncryptionService) saveKeys() error {
+	if s.keyStore == "" {
+		return nil
+	}
+
+	block, err := aes.NewCipher(s.config.MasterKey)
+	if err != nil {
+		return fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	file := keyStoreFile{Version: keyStoreVersion}
+	for _, policy := range s.policies {
+		file.Policies = append(file.Policies, policy)
+	}
+	for _, key := range s.keys {
+		material, err := hex.DecodeString(key.Metadata["key_material"])
+		if err != nil {
+			return fmt.Errorf("invalid key material of key %s: %w", key.ID, err)
+		}
+		sealed, err := s.encryptAESGCM(block, material)
+		if err != nil {
+			return err
+		}
+
+		stored := *key
+		stored.Metadata = make(map[string]string, len(key.Metadata))
+		for name, value := range key.Metadata {
+			if name != "key_material" {
+				stored.Metadata[name] = value
+			}
+		}
+		stored.Metadata[sealedKeyMaterialField] = hex.EncodeToString(sealed)
+		file.Keys = append(file.Keys, &stored)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key store: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.keyStore), 0700); err != nil {
+		return fmt.Errorf("failed to create key store directory: %w", err)
+	}
+	tmpPath := s.keyStore + ".tmp"
+	if err := os.WriteFile(filepath.Clean(tmpPath), data, 0600); err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.keyStore); err != nil {
+		return fmt.Errorf("failed to replace key store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,419 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/security"
+)
+
+// Reserved metadata fields of the records written by EncryptedStorage
+const (
+	encryptionKeyField     = "_encryption_key_id"
+	encryptionPayloadField = "_encrypted_payload"
+)
+
+// encryptedPayload holds the vector fields that EncryptedStorage encrypts
+type encryptedPayload struct {
+	Embedding  []float64              `json:"embedding"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Text       string                 `json:"text,omitempty"`
+	Normalized bool                   `json:"normalized,omitempty"`
+}
+
+// EncryptedStorage encrypts the embedding, metadata and text of every vector
+// before handing it to another storage engine, which stores the ciphertext and
+// the ID of the key that produced it in two reserved metadata fields. IDs,
+// collections and timestamps stay in plaintext so that the engine underneath
+// can version and expire vectors as usual.
+//
+// Keys come from the encryption policy named after the scope, such as a tenant
+// or a collection; the policy is created when it does not exist. After a key
+// rotation, records are re-encrypted with the new key when they are read.
+type EncryptedStorage struct {
+	inner   StorageEngine
+	service security.EncryptionService
+	scope   string
+
+	// mutex keeps writes from interleaving with the re-encryption of records
+	// that a read found encrypted with a rotated key
+	mutex sync.RWMutex
+}
+
+// NewEncryptedStorage wraps a storage engine so that vectors are encrypted with
+// keys of the scope's encryption policy
+func NewEncryptedStorage(inner StorageEngine, service security.EncryptionService, scope string) (*EncryptedStorage, error) {
+	if err := ensureEncryptionPolicy(service, scope); err != nil {
+		return nil, err
+	}
+	return &EncryptedStorage{inner: inner, service: service, scope: scope}, nil
+}
+
+// ensureEncryptionPolicy creates the encryption policy named after the scope
+// when it does not exist
+func ensureEncryptionPolicy(service security.EncryptionService, scope string) error {
+	if service == nil || scope == "" {
+		return ErrInvalidEncryptionConfig
+	}
+
+	ctx := context.Background()
+	if _, err := service.GetPolicy(ctx, scope); err == nil {
+		return nil
+	}
+	policy := &security.EncryptionPolicy{
+		ID:          scope,
+		TenantID:    scope,
+		Name:        "storage:" + scope,
+		Description: "Encryption at rest of stored vectors",
+		Algorithm:   "AES-256",
+		KeySize:     256,
+		Mode:        "GCM",
+		KeyRotation: 90,
+		DataTypes:   []string{"general"},
+		Status:      security.EncryptionPolicyStatusActive,
+	}
+	if err := service.CreatePolicy(ctx, policy); err != nil {
+		return fmt.Errorf("failed to create encryption policy %s: %w", scope, err)
+	}
+	return nil
+}
+
+// Sealer encrypts opaque records, such as change log records, with keys
+// of a scope's encryption policy the way EncryptedStorage encrypts vectors
+type Sealer struct {
+	service security.EncryptionService
+	scope   string
+}
+
+// NewSealer creates a sealer for the scope, creating its encryption policy
+// when it does not exist
+func NewSealer(service security.EncryptionService, scope string) (*Sealer, error) {
+	if err := ensureEncryptionPolicy(service, scope); err != nil {
+		return nil, err
+	}
+	return &Sealer{service: service, scope: scope}, nil
+}
+
+// Seal encrypts data with the active key of the scope and returns the
+// ciphertext with the ID of the key, which Open needs
+func (s *Sealer) Seal(ctx context.Context, data []byte) ([]byte, string, error) {
+	sealed, keyID, err := s.service.EncryptWithKeyID(ctx, data, s.scope)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encrypt with a key of %s: %w", s.scope, err)
+	}
+	return sealed, keyID, nil
+}
+
+// Open decrypts data sealed with the given key
+func (s *Sealer) Open(ctx context.Context, sealed []byte, keyID string) ([]byte, error) {
+	data, err := s.service.Decrypt(ctx, sealed, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key %s: %w", keyID, err)
+	}
+	return data, nil
+}
+
+// Write stores multiple vectors to storage
+func (e *EncryptedStorage) Write(vectors []*core.Vector) error {
+	return e.WriteWithContext(context.Background(), vectors)
+}
+
+// WriteWithContext encrypts vectors and writes them to the engine underneath
+func (e *EncryptedStorage) WriteWithContext(ctx context.Context, vectors []*core.Vector) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	encrypted, err := e.encrypt(ctx, vectors)
+	if err != nil {
+		return err
+	}
+	return e.inner.WriteWithContext(ctx, encrypted)
+}
+
+// Read retrieves vectors by their IDs
+func (e *EncryptedStorage) Read(ids []string) ([]*core.Vector, error) {
+	return e.ReadWithContext(context.Background(), ids)
+}
+
+// ReadWithContext reads and decrypts vectors, re-encrypting the records that
+// were encrypted with a key that has since been rotated
+func (e *EncryptedStorage) ReadWithContext(ctx context.Context, ids []string) ([]*core.Vector, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	stored, err := e.inner.ReadWithContext(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := e.service.ActiveKey(ctx, e.scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key of %s: %w", e.scope, err)
+	}
+
+	vectors := make([]*core.Vector, len(stored))
+	var stale []*core.Vector
+	for i, record := range stored {
+		vector, keyID, err := e.decrypt(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+		if keyID != active.ID {
+			stale = append(stale, vector)
+		}
+	}
+
+	if len(stale) > 0 {
+		// Rewriting with the same UpdatedAt replaces the record without adding a version
+		reencrypted, err := e.encrypt(ctx, stale)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.inner.WriteWithContext(ctx, reencrypted); err != nil {
+			return nil, fmt.Errorf("failed to re-encrypt vectors: %w", err)
+		}
+	}
+
+	return vectors, nil
+}
+
+// Delete removes vectors by their IDs
+func (e *EncryptedStorage) Delete(ids []string) error {
+	return e.DeleteWithContext(context.Background(), ids)
+}
+
+// DeleteWithContext removes vectors with context support
+func (e *EncryptedStorage) DeleteWithContext(ctx context.Context, ids []string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.inner.DeleteWithContext(ctx, ids)
+}
+
+// ReadAsOf retrieves the versions of vectors that were current at the given time
+func (e *EncryptedStorage) ReadAsOf(ids []string, asOf time.Time) ([]*core.Vector, error) {
+	return e.ReadAsOfWithContext(context.Background(), ids, asOf)
+}
+
+// ReadAsOfWithContext reads and decrypts past versions of vectors; previous
+// versions keep the key they were written with
+func (e *EncryptedStorage) ReadAsOfWithContext(ctx context.Context, ids []string, asOf time.Time) ([]*core.Vector, error) {
+	stored, err := e.inner.ReadAsOfWithContext(ctx, ids, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return e.decryptAll(ctx, stored)
+}
+
+// Scan calls fn for every stored vector, decrypted, when the engine underneath
+// implements Scanner
+func (e *EncryptedStorage) Scan(ctx context.Context, fn func(*core.Vector) error) error {
+	scanner, ok := e.inner.(Scanner)
+	if !ok {
+		return ErrScanNotSupported
+	}
+	return scanner.Scan(ctx, func(record *core.Vector) error {
+		vector, _, err := e.decrypt(ctx, record)
+		if err != nil {
+			return err
+		}
+		return fn(vector)
+	})
+}
+
+// ScanVersions calls fn with the decrypted versions of every stored or deleted
+// vector when the engine underneath implements VersionScanner; tombstones are
+// not encrypted
+func (e *EncryptedStorage) ScanVersions(ctx context.Context, fn func([]*core.Vector) error) error {
+	scanner, ok := e.inner.(VersionScanner)
+	if !ok {
+		return ErrScanNotSupported
+	}
+	return scanner.ScanVersions(ctx, func(records []*core.Vector) error {
+		versions := make([]*core.Vector, len(records))
+		for i, record := range records {
+			if record.Deleted {
+				versions[i] = record
+				continue
+			}
+			vector, _, err := e.decrypt(ctx, record)
+			if err != nil {
+				return err
+			}
+			versions[i] = vector
+		}
+		return fn(versions)
+	})
+}
+
+// Scrub verifies the records of the engine underneath when it implements
+// Scrubber. Repair copies are read in plaintext from opts.Repair and encrypted
+// before they are written.
+func (e *EncryptedStorage) Scrub(ctx context.Context, opts ScrubOptions) (*ScrubReport, error) {
+	scrubber, ok := e.inner.(Scrubber)
+	if !ok {
+		return nil, ErrScrubNotSupported
+	}
+
+	if !opts.quarantines() {
+		e.mutex.RLock()
+		defer e.mutex.RUnlock()
+		return scrubber.Scrub(ctx, opts)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if opts.Repair != nil {
+		opts.Repair = &encryptingSource{storage: e, source: opts.Repair}
+	}
+	return scrubber.Scrub(ctx, opts)
+}
+
+// encryptingSource encrypts the vectors of a repair source for the engine
+// underneath an EncryptedStorage
+type encryptingSource struct {
+	storage *EncryptedStorage
+	source  VectorSource
+}
+
+// ReadWithContext reads vectors from the source and encrypts them
+func (s *encryptingSource) ReadWithContext(ctx context.Context, ids []string) ([]*core.Vector, error) {
+	vectors, err := s.source.ReadWithContext(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.encrypt(ctx, vectors)
+}
+
+// RotateKey replaces the active key of the scope; records encrypted with the
+// previous key are re-encrypted as they are read
+func (e *EncryptedStorage) RotateKey(ctx context.Context) error {
+	active, err := e.service.ActiveKey(ctx, e.scope)
+	if err != nil {
+		return fmt.Errorf("failed to get encryption key of %s: %w", e.scope, err)
+	}
+	return e.service.RotateKey(ctx, active.ID)
+}
+
+// Compact performs storage optimization and cleanup
+func (e *EncryptedStorage) Compact() error {
+	return e.inner.Compact()
+}
+
+// GetStats returns the statistics of the engine underneath
+func (e *EncryptedStorage) GetStats() StorageStats {
+	return e.inner.GetStats()
+}
+
+// Close closes the engine underneath
+func (e *EncryptedStorage) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.inner.Close()
+}
+
+// encrypt returns copies of vectors whose payload is replaced by its ciphertext
+func (e *EncryptedStorage) encrypt(ctx context.Context, vectors []*core.Vector) ([]*core.Vector, error) {
+	encrypted := make([]*core.Vector, len(vectors))
+	for i, vector := range vectors {
+		if vector.Deleted {
+			// A tombstone carries no payload
+			encrypted[i] = vector
+			continue
+		}
+		payload, err := json.Marshal(encryptedPayload{
+			Embedding:  vector.Embedding,
+			Metadata:   vector.Metadata,
+			Text:       vector.Text,
+			Normalized: vector.Normalized,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode vector %s: %w", vector.ID, err)
+		}
+
+		ciphertext, keyID, err := e.service.EncryptWithKeyID(ctx, payload, e.scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt vector %s: %w", vector.ID, err)
+		}
+
+		encrypted[i] = &core.Vector{
+			ID:         vector.ID,
+			Collection: vector.Collection,
+			Embedding:  []float64{},
+			Metadata: map[string]interface{}{
+				encryptionKeyField:     keyID,
+				encryptionPayloadField: base64.StdEncoding.EncodeToString(ciphertext),
+			},
+			CreatedAt: vector.CreatedAt,
+			UpdatedAt: vector.UpdatedAt,
+			ExpiresAt: vector.ExpiresAt,
+			Version:   vector.Version,
+		}
+	}
+	return encrypted, nil
+}
+
+// decrypt restores a vector from its stored record and returns the ID of the
+// key it was encrypted with
+func (e *EncryptedStorage) decrypt(ctx context.Context, record *core.Vector) (*core.Vector, string, error) {
+	keyID, _ := record.Metadata[encryptionKeyField].(string)
+	encoded, _ := record.Metadata[encryptionPayloadField].(string)
+	if keyID == "" || encoded == "" {
+		return nil, "", fmt.Errorf("%w: vector %s is not encrypted", ErrReadFailed, record.ID)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: vector %s: %v", ErrReadFailed, record.ID, err)
+	}
+	plaintext, err := e.service.Decrypt(ctx, ciphertext, keyID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: failed to decrypt vector %s: %v", ErrReadFailed, record.ID, err)
+	}
+
+	var payload encryptedPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return nil, "", fmt.Errorf("%w: vector %s: %v", ErrReadFailed, record.ID, err)
+	}
+
+	magnitude := 0.0
+	for _, value := range payload.Embedding {
+		magnitude += value * value
+	}
+
+	return &core.Vector{
+		ID:         record.ID,
+		Collection: record.Collection,
+		Embedding:  payload.Embedding,
+		Metadata:   payload.Metadata,
+		Text:       payload.Text,
+		CreatedAt:  record.CreatedAt,
+		UpdatedAt:  record.UpdatedAt,
+		ExpiresAt:  record.ExpiresAt,
+		Version:    record.Version,
+		Dimension:  len(payload.Embedding),
+		Magnitude:  math.Sqrt(magnitude),
+		Normalized: payload.Normalized,
+	}, keyID, nil
+}
+
+// decryptAll decrypts stored records
+func (e *EncryptedStorage) decryptAll(ctx context.Context, stored []*core.Vector) ([]*core.Vector, error) {
+	vectors := make([]*core.Vector, len(stored))
+	for i, record := range stored {
+		vector, _, err := e.decrypt(ctx, record)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/security"
+)
+
+func encryptionTestVectors(updated time.Time) []*core.Vector {
+	vectors := make([]*core.Vector, 3)
+	for i := range vectors {
+		vectors[i] = &core.Vector{
+			ID:         []string{"alpha", "beta", "gamma"}[i],
+			Collection: "secrets",
+			Embedding:  []float64{float64(i), 0.5, -1.25},
+			Metadata:   map[string]interface{}{"owner": "patient-1234"},
+			Text:       "confidential diagnosis",
+			CreatedAt:  updated,
+			UpdatedAt:  updated,
+		}
+	}
+	return vectors
+}
+
+// storedKeyID returns the key ID recorded by EncryptedStorage in the raw record of id
+func storedKeyID(t *testing.T, inner StorageEngine, id string) string {
+	t.Helper()
+
+	records, err := inner.Read([]string{id})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to read raw record %s: %v", id, err)
+	}
+	keyID, _ := records[0].Metadata[encryptionKeyField].(string)
+	return keyID
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	updated := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	for name, open := range versionedEngines(t, 2, 0) {
+		t.Run(string(name), func(t *testing.T) {
+			service := security.NewDefaultEncryptionService(nil)
+			defer func() { _ = service.Close() }()
+
+			inner := open(t)
+			encrypted, err := NewEncryptedStorage(inner, service, "tenant-a")
+			if err != nil {
+				t.Fatalf("Failed to create encrypted storage: %v", err)
+			}
+
+			if err := encrypted.Write(encryptionTestVectors(updated)); err != nil {
+				t.Fatalf("Failed to write vectors: %v", err)
+			}
+
+			// The engine underneath only sees ciphertext
+			records, err := inner.Read([]string{"alpha", "beta", "gamma"})
+			if err != nil {
+				t.Fatalf("Failed to read raw records: %v", err)
+			}
+			if len(records) != 3 {
+				t.Fatalf("Expected 3 raw records, got %d", len(records))
+			}
+			for _, record := range records {
+				payload, _ := record.Metadata[encryptionPayloadField].(string)
+				if len(record.Embedding) != 0 || record.Text != "" || payload == "" ||
+					strings.Contains(payload, "patient") || record.Metadata["owner"] != nil {
+					t.Errorf("Raw record %s is not encrypted: %+v", record.ID, record)
+				}
+			}
+
+			vectors, err := encrypted.Read([]string{"beta", "missing"})
+			if err != nil {
+				t.Fatalf("Failed to read vectors: %v", err)
+			}
+			if len(vectors) != 1 || vectors[0].Embedding[0] != 1 || vectors[0].Text != "confidential diagnosis" ||
+				vectors[0].Metadata["owner"] != "patient-1234" || vectors[0].Dimension != 3 || !vectors[0].UpdatedAt.Equal(updated) {
+				t.Errorf("Unexpected decrypted vector: %+v", vectors)
+			}
+
+			// After a rotation, records move to the new key as they are read
+			oldKey := storedKeyID(t, inner, "alpha")
+			if err := encrypted.RotateKey(context.Background()); err != nil {
+				t.Fatalf("Failed to rotate key: %v", err)
+			}
+			if _, err := encrypted.Read([]string{"alpha"}); err != nil {
+				t.Fatalf("Failed to read vector after rotation: %v", err)
+			}
+			newKey := storedKeyID(t, inner, "alpha")
+			if newKey == oldKey || newKey == "" {
+				t.Errorf("Expected alpha to be re-encrypted, key is still %s", newKey)
+			}
+			if storedKeyID(t, inner, "gamma") != oldKey {
+				t.Error("Expected unread records to keep the previous key")
+			}
+			if stats := inner.GetStats(); stats.VersionCount != 0 {
+				t.Errorf("Expected re-encryption not to add versions, got %d", stats.VersionCount)
+			}
+
+			if name != StorageTypeMemory {
+				if err := encrypted.Close(); err != nil {
+					t.Fatalf("Failed to close storage: %v", err)
+				}
+				inner = open(t)
+				if encrypted, err = NewEncryptedStorage(inner, service, "tenant-a"); err != nil {
+					t.Fatalf("Failed to reopen encrypted storage: %v", err)
+				}
+			}
+
+			vectors, err = encrypted.ReadAsOf([]string{"alpha", "gamma"}, time.Now())
+			if err != nil {
+				t.Fatalf("Failed to read as of now: %v", err)
+			}
+			if len(vectors) != 2 || vectors[1].Embedding[0] != 2 {
+				t.Errorf("Unexpected vectors as of now: %+v", vectors)
+			}
+
+			scanned := 0
+			err = encrypted.Scan(context.Background(), func(vector *core.Vector) error {
+				if vector.Text != "confidential diagnosis" {
+					t.Errorf("Scanned vector %s was not decrypted", vector.ID)
+				}
+				scanned++
+				return nil
+			})
+			if err != nil || scanned != 3 {
+				t.Errorf("Expected to scan 3 vectors, got %d (%v)", scanned, err)
+			}
+
+			if err := encrypted.Close(); err != nil {
+				t.Errorf("Failed to close storage: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedStorage_WrongKey(t *testing.T) {
+	service := security.NewDefaultEncryptionService(nil)
+	defer func() { _ = service.Close() }()
+
+	inner, err := NewMemoryStorage(StorageConfig{Type: StorageTypeMemory})
+	if err != nil {
+		t.Fatalf("Failed to create memory storage: %v", err)
+	}
+	encrypted, err := NewEncryptedStorage(inner, service, "tenant-a")
+	if err != nil {
+		t.Fatalf("Failed to create encrypted storage: %v", err)
+	}
+	if err := encrypted.Write(encryptionTestVectors(time.Now())); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+
+	// Another encryption service does not have the keys
+	other := security.NewDefaultEncryptionService(nil)
+	defer func() { _ = other.Close() }()
+	stranger, err := NewEncryptedStorage(inner, other, "tenant-a")
+	if err != nil {
+		t.Fatalf("Failed to create encrypted storage: %v", err)
+	}
+	if _, err := stranger.Read([]string{"alpha"}); !errors.Is(err, ErrReadFailed) {
+		t.Errorf("Expected ErrReadFailed, got %v", err)
+	}
+
+	if _, err := NewEncryptedStorage(inner, service, ""); !errors.Is(err, ErrInvalidEncryptionConfig) {
+		t.Errorf("Expected ErrInvalidEncryptionConfig, got %v", err)
+	}
+}
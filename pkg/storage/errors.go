@@ -4,17 +4,28 @@ import "errors"
 
 // Storage-related errors
 var (
-	ErrUnsupportedStorageType = errors.New("unsupported storage type")
-	ErrInvalidDataPath        = errors.New("invalid data path")
-	ErrInvalidMaxFileSize     = errors.New("invalid max file size")
-	ErrInvalidBatchSize       = errors.New("invalid batch size")
-	ErrInvalidPageSize        = errors.New("invalid page size")
-	ErrInvalidCacheSize       = errors.New("invalid cache size")
-	ErrInvalidWriteBufferSize = errors.New("invalid write buffer size")
-	ErrInvalidMaxOpenFiles    = errors.New("invalid max open files")
-	ErrStorageNotInitialized  = errors.New("storage not initialized")
-	ErrVectorNotFound         = errors.New("vector not found")
-	ErrWriteFailed            = errors.New("write operation failed")
-	ErrReadFailed             = errors.New("read operation failed")
-	ErrDeleteFailed           = errors.New("delete operation failed")
+	ErrUnsupportedStorageType     = errors.New("unsupported storage type")
+	ErrInvalidDataPath            = errors.New("invalid data path")
+	ErrInvalidMaxFileSize         = errors.New("invalid max file size")
+	ErrInvalidBatchSize           = errors.New("invalid batch size")
+	ErrInvalidPageSize            = errors.New("invalid page size")
+	ErrInvalidCompactionThreshold = errors.New("invalid compaction threshold")
+	ErrInvalidVersionRetention    = errors.New("invalid version retention")
+	ErrInvalidCacheSize           = errors.New("invalid cache size")
+	ErrInvalidWriteBufferSize     = errors.New("invalid write buffer size")
+	ErrInvalidMaxOpenFiles        = errors.New("invalid max open files")
+	ErrInvalidTierConfig          = errors.New("invalid tiered storage configuration")
+	ErrInvalidEncryptionConfig    = errors.New("invalid encryption configuration")
+	ErrInvalidLSMConfig           = errors.New("invalid LSM storage configuration")
+	ErrInvalidObjectStoreConfig   = errors.New("invalid object store configuration")
+	ErrObjectNotFound             = errors.New("object not found")
+	ErrObjectStoreRequest         = errors.New("object store request failed")
+	ErrStorageNotInitialized      = errors.New("storage not initialized")
+	ErrVectorNotFound             = errors.New("vector not found")
+	ErrWriteFailed                = errors.New("write operation failed")
+	ErrReadFailed                 = errors.New("read operation failed")
+	ErrDeleteFailed               = errors.New("delete operation failed")
+	ErrScanNotSupported           = errors.New("storage engine does not support scanning")
+	ErrScrubNotSupported          = errors.New("storage engine does not support scrubbing")
+	ErrCorruptRecord              = errors.New("corrupt record")
 )
@@ -4,6 +4,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/vijaynallagatla/vjvector/pkg/core"
 )
@@ -28,7 +29,10 @@ type StorageEngine interface {
 	// Write stores multiple vectors to storage
 	Write(vectors []*core.Vector) error
 
-	// WriteWithContext stores multiple vectors with context support
+	// WriteWithContext stores multiple vectors with context support. A
+	// tombstone, a vector with Deleted set, deletes the stored vector as of its
+	// UpdatedAt, keeping the history the way Delete does; restores use it to
+	// write the version history of a deleted vector as it was.
 	WriteWithContext(ctx context.Context, vectors []*core.Vector) error
 
 	// Read retrieves vectors by their IDs
@@ -43,6 +47,13 @@ type StorageEngine interface {
 	// DeleteWithContext removes vectors with context support
 	DeleteWithContext(ctx context.Context, ids []string) error
 
+	// ReadAsOf retrieves the versions of vectors that were current at the given time.
+	// Vectors written after that time, or whose version was pruned, are skipped.
+	ReadAsOf(ids []string, asOf time.Time) ([]*core.Vector, error)
+
+	// ReadAsOfWithContext retrieves past versions of vectors with context support
+	ReadAsOfWithContext(ctx context.Context, ids []string, asOf time.Time) ([]*core.Vector, error)
+
 	// Compact performs storage optimization and cleanup
 	Compact() error
 
@@ -53,10 +64,24 @@ type StorageEngine interface {
 	Close() error
 }
 
+// Scanner is implemented by storage engines that can iterate over every stored vector
+type Scanner interface {
+	// Scan calls fn for every stored vector and stops at the first error
+	Scan(ctx context.Context, fn func(*core.Vector) error) error
+}
+
+// Scrubber is implemented by storage engines that can verify every stored record
+type Scrubber interface {
+	// Scrub checks every record, including previous versions, and handles the
+	// corrupt ones as opts asks
+	Scrub(ctx context.Context, opts ScrubOptions) (*ScrubReport, error)
+}
+
 // StorageStats provides performance and usage information about storage
 type StorageStats struct {
 	// Basic statistics
 	TotalVectors int64 `json:"total_vectors"`
+	VersionCount int64 `json:"version_count"`
 	StorageSize  int64 `json:"storage_size_bytes"`
 	MemoryUsage  int64 `json:"memory_usage_bytes"`
 
@@ -72,6 +97,9 @@ type StorageStats struct {
 	// File system metrics
 	FileCount int `json:"file_count"`
 	PageSize  int `json:"page_size_bytes"`
+
+	// Tiers holds per-tier statistics of tiered storage
+	Tiers []TierStats `json:"tiers,omitempty"`
 }
 
 // StorageType represents the type of storage engine
@@ -82,6 +110,8 @@ const (
 	StorageTypeMemory  StorageType = "memory"  // In-memory storage
 	StorageTypeMMap    StorageType = "mmap"    // Memory-mapped file storage
 	StorageTypeLevelDB StorageType = "leveldb" // LevelDB-based storage
+	StorageTypeTiered  StorageType = "tiered"  // Memory tier over LevelDB storage
+	StorageTypeLSM     StorageType = "lsm"     // Log-structured columnar segment storage
 )
 
 // StorageConfig holds configuration parameters for storage creation
@@ -95,11 +125,40 @@ type StorageConfig struct {
 	Compression bool `json:"compression,omitempty"`
 	SyncOnWrite bool `json:"sync_on_write,omitempty"`
 
+	// CompactionThreshold is the fragmentation percentage at which mmap segments
+	// are compacted in the background; zero disables automatic compaction
+	CompactionThreshold float64 `json:"compaction_threshold_percent,omitempty"`
+
 	// LevelDB parameters
 	CacheSize       int64 `json:"cache_size,omitempty"`
 	WriteBufferSize int   `json:"write_buffer_size,omitempty"`
 	MaxOpenFiles    int   `json:"max_open_files,omitempty"`
 
+	// Tiered storage parameters. HotTierBudget is the memory budget of the hot
+	// tier in bytes; a vector is promoted once its access score, which counts
+	// reads and halves every AccessHalfLifeSeconds, reaches PromotionThreshold.
+	// Zero values select the defaults of 64MB, 2 reads and 5 minutes.
+	HotTierBudget         int64 `json:"hot_tier_budget_bytes,omitempty"`
+	PromotionThreshold    int   `json:"promotion_threshold,omitempty"`
+	AccessHalfLifeSeconds int64 `json:"access_half_life_seconds,omitempty"`
+
+	// LSM storage parameters. MemtableSize is the number of bytes of writes
+	// buffered in memory before they are flushed to a segment; once there are
+	// MergeThreshold segments they are merged into one in the background. Zero
+	// values select the defaults of 4MB and 4 segments.
+	MemtableSize   int64 `json:"memtable_size_bytes,omitempty"`
+	MergeThreshold int   `json:"merge_threshold,omitempty"`
+
+	// ObjectStore, when set, moves the merged segments of LSM storage to an
+	// S3-compatible object store; other engines keep all their data local
+	ObjectStore *ObjectStoreConfig `json:"object_store,omitempty"`
+
+	// Version history parameters. MaxVersions is the number of previous versions
+	// kept per vector, zero disables the history; VersionRetentionSeconds prunes
+	// versions replaced longer ago than that, zero keeps them regardless of age.
+	MaxVersions             int   `json:"max_versions,omitempty"`
+	VersionRetentionSeconds int64 `json:"version_retention_seconds,omitempty"`
+
 	// General parameters
 	BatchSize     int `json:"batch_size"`
 	FlushInterval int `json:"flush_interval_ms"`
@@ -135,6 +194,10 @@ func (f *DefaultStorageFactory) CreateStorage(config StorageConfig) (StorageEngi
 		return NewMMapStorage(config)
 	case StorageTypeLevelDB:
 		return NewLevelDBStorage(config)
+	case StorageTypeTiered:
+		return NewTieredStorage(config)
+	case StorageTypeLSM:
+		return NewLSMStorage(config)
 	default:
 		return nil, ErrUnsupportedStorageType
 	}
@@ -154,6 +217,10 @@ func (f *DefaultStorageFactory) ValidateConfig(config StorageConfig) error {
 		return ErrInvalidBatchSize
 	}
 
+	if config.MaxVersions < 0 || config.VersionRetentionSeconds < 0 {
+		return ErrInvalidVersionRetention
+	}
+
 	switch config.Type {
 	case StorageTypeMemory:
 		return f.validateMemoryConfig(config)
@@ -161,6 +228,10 @@ func (f *DefaultStorageFactory) ValidateConfig(config StorageConfig) error {
 		return f.validateMMapConfig(config)
 	case StorageTypeLevelDB:
 		return f.validateLevelDBConfig(config)
+	case StorageTypeTiered:
+		return f.validateTieredConfig(config)
+	case StorageTypeLSM:
+		return f.validateLSMConfig(config)
 	default:
 		return ErrUnsupportedStorageType
 	}
@@ -177,6 +248,9 @@ func (f *DefaultStorageFactory) validateMMapConfig(config StorageConfig) error {
 	if config.PageSize <= 0 {
 		return ErrInvalidPageSize
 	}
+	if config.CompactionThreshold < 0 || config.CompactionThreshold > 100 {
+		return ErrInvalidCompactionThreshold
+	}
 	return nil
 }
 
@@ -193,3 +267,24 @@ func (f *DefaultStorageFactory) validateLevelDBConfig(config StorageConfig) erro
 	}
 	return nil
 }
+
+// validateTieredConfig validates tiered storage configuration
+func (f *DefaultStorageFactory) validateTieredConfig(config StorageConfig) error {
+	if config.HotTierBudget < 0 || config.PromotionThreshold < 0 || config.AccessHalfLifeSeconds < 0 {
+		return ErrInvalidTierConfig
+	}
+	// The cold tier is a LevelDB store
+	return f.validateLevelDBConfig(config)
+}
+
+// validateLSMConfig validates LSM storage configuration
+func (f *DefaultStorageFactory) validateLSMConfig(config StorageConfig) error {
+	// A single segment would be merged into itself over and over
+	if config.MemtableSize < 0 || config.MergeThreshold < 0 || config.MergeThreshold == 1 {
+		return ErrInvalidLSMConfig
+	}
+	if config.ObjectStore != nil {
+		return validateObjectStoreConfig(*config.ObjectStore)
+	}
+	return nil
+}
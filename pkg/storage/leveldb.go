@@ -1,24 +1,54 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	leveldberrors "github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vijaynallagatla/vjvector/pkg/core"
 )
 
+const (
+	// vectorKeyPrefix is the key prefix of vector records in LevelDB
+	vectorKeyPrefix = "vector:"
+
+	// versionKeyPrefix is the key prefix of previous versions of vector records.
+	// A version key is the prefix, the vector ID, a zero byte and the big-endian
+	// UpdatedAt of the version in nanoseconds.
+	versionKeyPrefix = "version:"
+
+	// quarantineKeyPrefix is the key prefix under which Scrub keeps corrupt
+	// records, followed by their original key
+	quarantineKeyPrefix = "quarantine:"
+)
+
 // LevelDBStorage provides LevelDB-based storage for vectors
 type LevelDBStorage struct {
-	config StorageConfig
-	dbPath string
-	db     *leveldb.DB
-	mutex  sync.RWMutex
+	config   StorageConfig
+	dbPath   string
+	db       *leveldb.DB
+	versions versionPolicy
+	mutex    sync.RWMutex
+
+	// scans counts the scans reading a snapshot, which Close waits for, and
+	// closing stops new scans from starting once Close was called
+	scans   sync.WaitGroup
+	closing bool
 
 	// Statistics
 	stats     StorageStats
@@ -27,12 +57,105 @@ type LevelDBStorage struct {
 
 // VectorRecord represents a vector record in LevelDB
 type VectorRecord struct {
-	ID        string                 `json:"id"`
-	Dimension int                    `json:"dimension"`
-	Data      []float64              `json:"data"`
-	Metadata  map[string]interface{} `json:"metadata"`
-	Timestamp int64                  `json:"timestamp"`
-	Checksum  uint32                 `json:"checksum"`
+	ID         string                 `json:"id"`
+	Collection string                 `json:"collection,omitempty"`
+	Dimension  int                    `json:"dimension"`
+	Data       []float64              `json:"data"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Text       string                 `json:"text,omitempty"`
+	CreatedAt  int64                  `json:"created_at,omitempty"`
+	ExpiresAt  int64                  `json:"expires_at,omitempty"`
+	Version    uint64                 `json:"version,omitempty"`
+	Timestamp  int64                  `json:"timestamp"`
+	Checksum   uint32                 `json:"checksum"`
+
+	// Deleted marks the tombstone stored as the last previous version of a
+	// deleted vector
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// newVectorRecord converts a vector into its LevelDB record
+func newVectorRecord(vector *core.Vector) *VectorRecord {
+	record := &VectorRecord{
+		ID:         vector.ID,
+		Collection: vector.Collection,
+		Dimension:  len(vector.Embedding),
+		Data:       vector.Embedding,
+		Metadata:   vector.Metadata,
+		Text:       vector.Text,
+		CreatedAt:  vector.CreatedAt.UnixNano(),
+		Version:    vector.Version,
+		Timestamp:  vector.UpdatedAt.UnixNano(),
+		Checksum:   recordChecksum(vector.Embedding),
+		Deleted:    vector.Deleted,
+	}
+	if vector.ExpiresAt != nil {
+		record.ExpiresAt = vector.ExpiresAt.UnixNano()
+	}
+	return record
+}
+
+// toVector converts a LevelDB record back into a vector
+func (r *VectorRecord) toVector() *core.Vector {
+	magnitude := 0.0
+	for _, value := range r.Data {
+		magnitude += value * value
+	}
+
+	vector := &core.Vector{
+		ID:         r.ID,
+		Collection: r.Collection,
+		Embedding:  r.Data,
+		Metadata:   r.Metadata,
+		Text:       r.Text,
+		CreatedAt:  time.Unix(0, r.CreatedAt),
+		UpdatedAt:  time.Unix(0, r.Timestamp),
+		Version:    r.Version,
+		Dimension:  r.Dimension,
+		Magnitude:  math.Sqrt(magnitude),
+		Deleted:    r.Deleted,
+	}
+	if r.ExpiresAt != 0 {
+		expiresAt := time.Unix(0, r.ExpiresAt)
+		vector.ExpiresAt = &expiresAt
+	}
+	return vector
+}
+
+// recordChecksum computes the CRC32 of the little-endian encoding of a vector
+func recordChecksum(data []float64) uint32 {
+	buf := make([]byte, 8*len(data))
+	for i, value := range data {
+		binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(value))
+	}
+	return crc32.ChecksumIEEE(buf)
+}
+
+// decodeVectorRecord decodes the record stored at key and verifies its checksum
+func decodeVectorRecord(key, data []byte) (*VectorRecord, *CorruptionError) {
+	var record VectorRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, recordCorruption(key, fmt.Sprintf("undecodable record: %v", err))
+	}
+	if len(record.Data) != record.Dimension {
+		return nil, recordCorruption(key, fmt.Sprintf("truncated embedding: %d of %d components", len(record.Data), record.Dimension))
+	}
+	if recordChecksum(record.Data) != record.Checksum {
+		return nil, recordCorruption(key, "checksum mismatch")
+	}
+	return &record, nil
+}
+
+// recordCorruption describes a corrupt record by its key
+func recordCorruption(key []byte, reason string) *CorruptionError {
+	id := ""
+	switch {
+	case bytes.HasPrefix(key, []byte(vectorKeyPrefix)):
+		id = string(key[len(vectorKeyPrefix):])
+	case bytes.HasPrefix(key, []byte(versionKeyPrefix)) && len(key) >= len(versionKeyPrefix)+9:
+		id = versionKeyID(key)
+	}
+	return &CorruptionError{ID: id, Location: fmt.Sprintf("key %q", key), Reason: reason}
 }
 
 // NewLevelDBStorage creates a new LevelDB storage engine
@@ -56,50 +179,138 @@ func NewLevelDBStorage(config StorageConfig) (StorageEngine, error) {
 		config:    config,
 		dbPath:    config.DataPath,
 		db:        db,
+		versions:  newVersionPolicy(config),
 		startTime: time.Now(),
 	}
 
-	// Initialize statistics
-	storage.stats.TotalVectors = 0 // Will be updated on first use
+	// Initialize statistics from the records already on disk
+	count, err := storage.countRecords(vectorKeyPrefix)
+	if err == nil {
+		storage.stats.TotalVectors = count
+		count, err = storage.countRecords(versionKeyPrefix)
+		storage.stats.VersionCount = count
+	}
+	if err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			return nil, fmt.Errorf("failed to count records and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, err
+	}
 
 	return storage, nil
 }
 
+// countRecords counts the records stored in the database under a key prefix
+func (l *LevelDBStorage) countRecords(prefix string) (int64, error) {
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	count := int64(0)
+	for iter.Next() {
+		count++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("failed to count vectors: %w", err)
+	}
+
+	return count, nil
+}
+
+// Scan calls fn for every stored vector in key order. It reads a snapshot of
+// the storage taken when it starts, so writes are not blocked while it runs
+// and fn may write to the storage.
+func (l *LevelDBStorage) Scan(ctx context.Context, fn func(*core.Vector) error) error {
+	snapshot, err := l.snapshot()
+	if err != nil {
+		return err
+	}
+	defer l.release(snapshot)
+
+	iter := snapshot.NewIterator(util.BytesPrefix([]byte(vectorKeyPrefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record, corruption := decodeVectorRecord(iter.Key(), iter.Value())
+		if corruption != nil {
+			return corruption
+		}
+		if err := fn(record.toVector()); err != nil {
+			return err
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to scan vectors: %w", err)
+	}
+
+	return nil
+}
+
 // Write stores multiple vectors to LevelDB storage
 func (l *LevelDBStorage) Write(vectors []*core.Vector) error {
 	return l.WriteWithContext(context.Background(), vectors)
 }
 
-// WriteWithContext stores multiple vectors with context support
+// WriteWithContext stores multiple vectors with context support.
+// With version history enabled, a replaced record is kept as a previous version.
 func (l *LevelDBStorage) WriteWithContext(_ context.Context, vectors []*core.Vector) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	start := time.Now()
 	batch := new(leveldb.Batch)
+	pending := newVersionBatch(l, batch)
 
+	added := int64(0)
 	for _, vector := range vectors {
-		// Create a simple record (just store the vector as JSON for now)
-		record := map[string]interface{}{
-			"id":        vector.ID,
-			"embedding": vector.Embedding,
-			"metadata":  vector.Metadata,
+		if vector.Deleted {
+			removed, err := l.remove(pending, vector.ID, vector.UpdatedAt, start)
+			if err != nil {
+				return err
+			}
+			if removed {
+				added--
+			}
+			continue
+		}
+
+		record := newVectorRecord(vector)
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode vector %s: %w", vector.ID, err)
+		}
+
+		previous, err := pending.current(vector.ID)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			added++
+		} else if l.versions.enabled() && previous.Timestamp != record.Timestamp {
+			if err := pending.addVersion(previous); err != nil {
+				return err
+			}
 		}
 
-		// For now, use a simple string format instead of JSON to avoid import issues
-		// This is a simplified implementation for the demo
-		key := []byte("vector:" + vector.ID)
-		data := fmt.Sprintf("%v", record)
-		batch.Put(key, []byte(data))
+		batch.Put([]byte(vectorKeyPrefix+vector.ID), data)
+		pending.written[vector.ID] = record
+		if err := pending.prune(vector.ID, start); err != nil {
+			return err
+		}
 	}
 
 	// Write batch to LevelDB
-	if err := l.db.Write(batch, nil); err != nil {
+	if err := l.db.Write(batch, &opt.WriteOptions{Sync: l.config.SyncOnWrite}); err != nil {
 		return fmt.Errorf("failed to write vectors to LevelDB: %w", err)
 	}
 
 	// Update statistics
-	l.stats.TotalVectors += int64(len(vectors))
+	l.stats.TotalVectors += added
+	l.stats.VersionCount += pending.versionDelta
 	l.stats.AvgWriteTime = float64(time.Since(start).Microseconds()) / float64(len(vectors))
 
 	return nil
@@ -119,23 +330,14 @@ func (l *LevelDBStorage) ReadWithContext(_ context.Context, ids []string) ([]*co
 	vectors := make([]*core.Vector, 0, len(ids))
 
 	for _, id := range ids {
-		key := []byte("vector:" + id)
-		_, err := l.db.Get(key, nil)
+		record, err := l.readRecord([]byte(vectorKeyPrefix + id))
 		if err != nil {
-			if err == leveldb.ErrNotFound {
-				continue // Skip missing vectors
-			}
-			return nil, fmt.Errorf("failed to read vector %s: %w", id, err)
+			return nil, err
 		}
-
-		// For now, just create a placeholder vector since we're using simple string storage
-		// In a full implementation, we would properly deserialize the data
-		vector := &core.Vector{
-			ID:        id,
-			Embedding: make([]float64, 1536), // Placeholder embedding
-			Metadata:  make(map[string]interface{}),
+		if record == nil {
+			continue // Skip missing vectors
 		}
-		vectors = append(vectors, vector)
+		vectors = append(vectors, record.toVector())
 	}
 
 	// Update statistics
@@ -149,39 +351,506 @@ func (l *LevelDBStorage) Delete(ids []string) error {
 	return l.DeleteWithContext(context.Background(), ids)
 }
 
-// DeleteWithContext removes vectors with context support
+// DeleteWithContext removes vectors with context support. With version
+// history enabled, a deleted record and a tombstone join its previous versions;
+// otherwise the previous versions are removed as well.
 func (l *LevelDBStorage) DeleteWithContext(_ context.Context, ids []string) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	start := time.Now()
 	batch := new(leveldb.Batch)
+	pending := newVersionBatch(l, batch)
 
+	removed := int64(0)
 	for _, id := range ids {
-		key := []byte("vector:" + id)
-		batch.Delete(key)
+		deleted, err := l.remove(pending, id, start, start)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			removed++
+		}
 	}
 
 	// Write batch to LevelDB
-	if err := l.db.Write(batch, nil); err != nil {
+	if err := l.db.Write(batch, &opt.WriteOptions{Sync: l.config.SyncOnWrite}); err != nil {
 		return fmt.Errorf("failed to delete vectors from LevelDB: %w", err)
 	}
 
 	// Update statistics
-	l.stats.TotalVectors -= int64(len(ids))
+	l.stats.TotalVectors -= removed
+	l.stats.VersionCount += pending.versionDelta
 	l.stats.AvgDeleteTime = float64(time.Since(start).Microseconds()) / float64(len(ids))
 
 	return nil
 }
 
-// Compact performs storage optimization and cleanup
+// remove adds the deletion of a stored vector as of deleted to a batch,
+// keeping it in its history before a tombstone when versions are kept, and
+// reports whether the vector was stored
+func (l *LevelDBStorage) remove(pending *versionBatch, id string, deleted, now time.Time) (bool, error) {
+	current, err := pending.current(id)
+	if err != nil || current == nil {
+		return false, err
+	}
+	pending.batch.Delete([]byte(vectorKeyPrefix + id))
+	pending.written[id] = nil
+
+	if l.versions.enabled() {
+		stone := tombstone(id, time.Unix(0, current.Timestamp), deleted)
+		if err := pending.addVersion(current); err != nil {
+			return false, err
+		}
+		if err := pending.addVersion(newVectorRecord(stone)); err != nil {
+			return false, err
+		}
+	}
+	return true, pending.prune(id, now)
+}
+
+// ReadAsOf retrieves the versions of vectors that were current at the given time
+func (l *LevelDBStorage) ReadAsOf(ids []string, asOf time.Time) ([]*core.Vector, error) {
+	return l.ReadAsOfWithContext(context.Background(), ids, asOf)
+}
+
+// ReadAsOfWithContext retrieves past versions of vectors with context support
+func (l *LevelDBStorage) ReadAsOfWithContext(_ context.Context, ids []string, asOf time.Time) ([]*core.Vector, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	vectors := make([]*core.Vector, 0, len(ids))
+	for _, id := range ids {
+		current, err := l.readRecord([]byte(vectorKeyPrefix + id))
+		if err != nil {
+			return nil, err
+		}
+		keys, err := l.versionKeys(id)
+		if err != nil {
+			return nil, err
+		}
+
+		updated := make([]time.Time, len(keys), len(keys)+1)
+		for i, key := range keys {
+			updated[i] = time.Unix(0, versionKeyTimestamp(key))
+		}
+		if current != nil {
+			updated = append(updated, time.Unix(0, current.Timestamp))
+		}
+
+		i := versionAsOf(updated, asOf)
+		switch {
+		case i < 0:
+			continue
+		case i == len(keys):
+			vectors = append(vectors, current.toVector())
+		default:
+			version, err := l.readRecord(keys[i])
+			if err != nil {
+				return nil, err
+			}
+			if version != nil && !version.Deleted {
+				vectors = append(vectors, version.toVector())
+			}
+		}
+	}
+
+	return vectors, nil
+}
+
+// ScanVersions calls fn with the versions of every stored or deleted vector
+// in key order, from a snapshot of the storage like Scan
+func (l *LevelDBStorage) ScanVersions(ctx context.Context, fn func([]*core.Vector) error) error {
+	snapshot, err := l.snapshot()
+	if err != nil {
+		return err
+	}
+	defer l.release(snapshot)
+
+	currents := snapshot.NewIterator(util.BytesPrefix([]byte(vectorKeyPrefix)), nil)
+	defer currents.Release()
+	previous := snapshot.NewIterator(util.BytesPrefix([]byte(versionKeyPrefix)), nil)
+	defer previous.Release()
+
+	// Both key spaces are ordered by vector ID, and version keys by timestamp within an ID
+	hasCurrent, hasPrevious := currents.Next(), previous.Next()
+	for hasCurrent || hasPrevious {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		id := ""
+		if hasCurrent {
+			id = string(currents.Key()[len(vectorKeyPrefix):])
+		}
+		if hasPrevious {
+			if versionID := versionKeyID(previous.Key()); !hasCurrent || versionID < id {
+				id = versionID
+			}
+		}
+
+		var versions []*core.Vector
+		for hasPrevious && versionKeyID(previous.Key()) == id {
+			record, corruption := decodeVectorRecord(previous.Key(), previous.Value())
+			if corruption != nil {
+				return corruption
+			}
+			versions = append(versions, record.toVector())
+			hasPrevious = previous.Next()
+		}
+		if hasCurrent && string(currents.Key()[len(vectorKeyPrefix):]) == id {
+			record, corruption := decodeVectorRecord(currents.Key(), currents.Value())
+			if corruption != nil {
+				return corruption
+			}
+			versions = append(versions, record.toVector())
+			hasCurrent = currents.Next()
+		}
+
+		if err := fn(versions); err != nil {
+			return err
+		}
+	}
+
+	for _, iter := range []iterator.Iterator{currents, previous} {
+		if err := iter.Error(); err != nil {
+			return fmt.Errorf("failed to scan versions: %w", err)
+		}
+	}
+	return nil
+}
+
+// snapshot returns a consistent view of the storage for a scan, which must
+// hand it back to release. The mutex is only held while the snapshot is
+// taken, so that scans do not hold off writes.
+func (l *LevelDBStorage) snapshot() (*leveldb.Snapshot, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if l.closing {
+		return nil, fmt.Errorf("failed to take storage snapshot: %w", leveldb.ErrClosed)
+	}
+	snapshot, err := l.db.GetSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take storage snapshot: %w", err)
+	}
+	l.scans.Add(1)
+	return snapshot, nil
+}
+
+// release releases the snapshot of a finished scan
+func (l *LevelDBStorage) release(snapshot *leveldb.Snapshot) {
+	snapshot.Release()
+	l.scans.Done()
+}
+
+// readRecord reads, decodes and verifies the record at key, or returns nil
+// when it does not exist
+func (l *LevelDBStorage) readRecord(key []byte) (*VectorRecord, error) {
+	data, err := l.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if leveldberrors.IsCorrupted(err) {
+		return nil, recordCorruption(key, err.Error())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record %q: %w", key, err)
+	}
+
+	record, corruption := decodeVectorRecord(key, data)
+	if corruption != nil {
+		return nil, corruption
+	}
+	return record, nil
+}
+
+// versionKeys returns the keys of the previous versions of a vector, oldest first
+func (l *LevelDBStorage) versionKeys(id string) ([][]byte, error) {
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(versionKeyPrefix+id+"\x00")), nil)
+	defer iter.Release()
+
+	var keys [][]byte
+	for iter.Next() {
+		keys = append(keys, append([]byte(nil), iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to list versions of vector %s: %w", id, err)
+	}
+
+	sortVersionKeys(keys)
+	return keys, nil
+}
+
+// versionKey returns the key of a previous version of a vector
+func versionKey(id string, timestamp int64) []byte {
+	key := make([]byte, 0, len(versionKeyPrefix)+len(id)+9)
+	key = append(key, versionKeyPrefix...)
+	key = append(key, id...)
+	key = append(key, 0)
+	return binary.BigEndian.AppendUint64(key, uint64(timestamp))
+}
+
+// versionKeyID returns the vector ID encoded in a version key
+func versionKeyID(key []byte) string {
+	return string(key[len(versionKeyPrefix) : len(key)-9])
+}
+
+// versionKeyTimestamp returns the UpdatedAt nanoseconds encoded in a version key
+func versionKeyTimestamp(key []byte) int64 {
+	return int64(binary.BigEndian.Uint64(key[len(key)-8:]))
+}
+
+// sortVersionKeys orders version keys of one vector by their timestamps
+func sortVersionKeys(keys [][]byte) {
+	sort.Slice(keys, func(i, j int) bool {
+		return versionKeyTimestamp(keys[i]) < versionKeyTimestamp(keys[j])
+	})
+}
+
+// versionBatch tracks the current records and version keys changed by a write
+// batch that has not been applied yet
+type versionBatch struct {
+	storage      *LevelDBStorage
+	batch        *leveldb.Batch
+	written      map[string]*VectorRecord
+	keys         map[string][][]byte
+	versionDelta int64
+}
+
+// newVersionBatch creates the version tracking of a write batch
+func newVersionBatch(storage *LevelDBStorage, batch *leveldb.Batch) *versionBatch {
+	return &versionBatch{
+		storage: storage,
+		batch:   batch,
+		written: make(map[string]*VectorRecord),
+		keys:    make(map[string][][]byte),
+	}
+}
+
+// current returns the record of id as of the pending batch, or nil when it
+// does not exist or is deleted by the batch
+func (v *versionBatch) current(id string) (*VectorRecord, error) {
+	if record, exists := v.written[id]; exists {
+		return record, nil
+	}
+	return v.storage.readRecord([]byte(vectorKeyPrefix + id))
+}
+
+// versionKeys returns the version keys of id as of the pending batch
+func (v *versionBatch) versionKeys(id string) ([][]byte, error) {
+	if keys, exists := v.keys[id]; exists {
+		return keys, nil
+	}
+	keys, err := v.storage.versionKeys(id)
+	if err != nil {
+		return nil, err
+	}
+	v.keys[id] = keys
+	return keys, nil
+}
+
+// addVersion stores a replaced record as a previous version
+func (v *versionBatch) addVersion(record *VectorRecord) error {
+	keys, err := v.versionKeys(record.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode version of vector %s: %w", record.ID, err)
+	}
+
+	key := versionKey(record.ID, record.Timestamp)
+	for _, existing := range keys {
+		if bytes.Equal(existing, key) {
+			v.batch.Put(key, data)
+			return nil
+		}
+	}
+
+	v.batch.Put(key, data)
+	keys = append(keys, key)
+	sortVersionKeys(keys)
+	v.keys[record.ID] = keys
+	v.versionDelta++
+	return nil
+}
+
+// prune deletes the previous versions of id that fall outside the version
+// policy. Without a current record the last version is the tombstone of the
+// deletion; once the deletion is forgotten every version is deleted.
+func (v *versionBatch) prune(id string, now time.Time) error {
+	keys, err := v.versionKeys(id)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	current, err := v.current(id)
+	if err != nil {
+		return err
+	}
+
+	updated := make([]time.Time, len(keys), len(keys)+1)
+	for i, key := range keys {
+		updated[i] = time.Unix(0, versionKeyTimestamp(key))
+	}
+	if current != nil {
+		updated = append(updated, time.Unix(0, current.Timestamp))
+	}
+
+	drop := v.storage.versions.prunable(updated, now)
+	if current == nil && v.storage.versions.forgotten(updated[len(updated)-1], now) {
+		drop = len(keys)
+	}
+	for _, key := range keys[:drop] {
+		v.batch.Delete(key)
+	}
+	v.keys[id] = keys[drop:]
+	v.versionDelta -= int64(drop)
+	return nil
+}
+
+// Compact prunes previous versions that fell outside the retention period
 func (l *LevelDBStorage) Compact() error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	// Compact the entire database - simplified approach
-	// For a full implementation, we would iterate through keys and compact manually
-	return nil // LevelDB auto-compacts, so this is optional
+	// Collect the IDs with previous versions
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(versionKeyPrefix)), nil)
+	var ids []string
+	for iter.Next() {
+		id := versionKeyID(iter.Key())
+		if len(ids) == 0 || ids[len(ids)-1] != id {
+			ids = append(ids, id)
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	now := time.Now()
+	batch := new(leveldb.Batch)
+	pending := newVersionBatch(l, batch)
+	for _, id := range ids {
+		if err := pending.prune(id, now); err != nil {
+			return err
+		}
+	}
+
+	if batch.Len() == 0 {
+		return nil
+	}
+	if err := l.db.Write(batch, &opt.WriteOptions{Sync: l.config.SyncOnWrite}); err != nil {
+		return fmt.Errorf("failed to prune versions: %w", err)
+	}
+	l.stats.VersionCount += pending.versionDelta
+
+	return nil
+}
+
+// Scrub verifies the checksum of every current and previous record. Corrupt
+// records are moved under the quarantine key prefix when opts asks for it.
+func (l *LevelDBStorage) Scrub(ctx context.Context, opts ScrubOptions) (*ScrubReport, error) {
+	report := &ScrubReport{}
+	var keys [][]byte
+	for _, prefix := range []string{vectorKeyPrefix, versionKeyPrefix} {
+		found, err := l.scrubPrefix(ctx, prefix, report)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, found...)
+	}
+
+	if len(keys) == 0 || !opts.quarantines() {
+		return report, nil
+	}
+	if err := l.quarantine(keys, report); err != nil {
+		return nil, err
+	}
+	if opts.Repair != nil {
+		if err := repairRecords(ctx, l, opts.Repair, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// scrubPrefix verifies the records under a key prefix, adds the corrupt ones
+// to report and returns their keys
+func (l *LevelDBStorage) scrubPrefix(ctx context.Context, prefix string, report *ScrubReport) ([][]byte, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	var keys [][]byte
+	for iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		report.Checked++
+		if _, corruption := decodeVectorRecord(iter.Key(), iter.Value()); corruption != nil {
+			report.add(corruption, prefix == versionKeyPrefix)
+			keys = append(keys, append([]byte(nil), iter.Key()...))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to scrub records: %w", err)
+	}
+
+	return keys, nil
+}
+
+// quarantine moves the records at keys, which match the corrupt records of
+// report, under the quarantine key prefix unless they were rewritten since
+func (l *LevelDBStorage) quarantine(keys [][]byte, report *ScrubReport) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	batch := new(leveldb.Batch)
+	var moved []int
+	vectors, versions := int64(0), int64(0)
+	for i, key := range keys {
+		data, err := l.db.Get(key, nil)
+		switch {
+		case errors.Is(err, leveldb.ErrNotFound):
+			continue
+		case err == nil:
+			if _, corruption := decodeVectorRecord(key, data); corruption == nil {
+				continue
+			}
+		case !leveldberrors.IsCorrupted(err):
+			return fmt.Errorf("failed to read record %q: %w", key, err)
+		}
+
+		batch.Put(append([]byte(quarantineKeyPrefix), key...), data)
+		batch.Delete(key)
+		moved = append(moved, i)
+		if report.Corrupt[i].Version {
+			versions++
+		} else {
+			vectors++
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+
+	if err := l.db.Write(batch, &opt.WriteOptions{Sync: l.config.SyncOnWrite}); err != nil {
+		return fmt.Errorf("failed to quarantine records: %w", err)
+	}
+
+	l.stats.TotalVectors -= vectors
+	l.stats.VersionCount -= versions
+	for _, i := range moved {
+		report.Corrupt[i].Action = ScrubActionQuarantined
+	}
+	report.Quarantined += len(moved)
+
+	return nil
 }
 
 // GetStats returns storage performance and usage statistics
@@ -199,6 +868,13 @@ func (l *LevelDBStorage) GetStats() StorageStats {
 
 // Close performs cleanup and resource management
 func (l *LevelDBStorage) Close() error {
+	// LevelDB must not be closed under the iterators of running scans, which
+	// may write to the storage before they finish
+	l.mutex.Lock()
+	l.closing = true
+	l.mutex.Unlock()
+	l.scans.Wait()
+
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
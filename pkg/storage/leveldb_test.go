@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func TestLevelDBStorage_ScanDuringWrites(t *testing.T) {
+	factory := &DefaultStorageFactory{}
+	engine, err := factory.CreateStorage(StorageConfig{
+		Type:            StorageTypeLevelDB,
+		DataPath:        filepath.Join(t.TempDir(), "leveldb"),
+		MaxFileSize:     1024 * 1024,
+		BatchSize:       100,
+		CacheSize:       8 * 1024 * 1024,
+		WriteBufferSize: 4 * 1024 * 1024,
+		MaxOpenFiles:    100,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	l := engine.(*LevelDBStorage)
+
+	if err := l.Write(mmapTestVectors("v", 50, 1)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+
+	// Writes made during a scan neither wait for it nor show up in it
+	scanned := 0
+	err = l.Scan(context.Background(), func(vector *core.Vector) error {
+		scanned++
+		if scanned == 10 {
+			if err := l.Write(mmapTestVectors("w", 50, 2)); err != nil {
+				return err
+			}
+			return l.Delete([]string{"v0049"})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if scanned != 50 {
+		t.Errorf("Expected to scan the 50 vectors stored when the scan started, got %d", scanned)
+	}
+	if ids := scanIDs(t, l); len(ids) != 99 {
+		t.Errorf("Expected to scan 99 vectors after the writes, got %d", len(ids))
+	}
+
+	// Close waits for a scan in progress, and later scans fail
+	started, closed := make(chan struct{}), make(chan error, 1)
+	go func() {
+		<-started
+		closed <- l.Close()
+	}()
+	err = l.Scan(context.Background(), func(vector *core.Vector) error {
+		if vector.ID == "v0000" {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected the scan to finish before the storage closed, got %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("Failed to close storage: %v", err)
+	}
+	if err := l.Scan(context.Background(), func(*core.Vector) error { return nil }); err == nil {
+		t.Error("Expected a scan of closed storage to fail")
+	}
+}
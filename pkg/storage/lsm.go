@@ -0,0 +1,1612 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+const (
+	// lsmManifestVersion is the newest manifest format; version 2 lists the
+	// segments offloaded to an object store and is only written for stores
+	// that have some
+	lsmManifestVersion = 2
+
+	// defaultMemtableSize and defaultMergeThreshold apply when the configuration leaves them at zero
+	defaultMemtableSize   = 4 * 1024 * 1024
+	defaultMergeThreshold = 4
+
+	lsmManifestFile   = "MANIFEST"
+	lsmQuarantineFile = "QUARANTINE"
+
+	// lsmCacheDir is the directory of the local cache of offloaded segments
+	lsmCacheDir = "cache"
+)
+
+// lsmFilePattern matches the names of segment and write-ahead log files
+var lsmFilePattern = regexp.MustCompile(`^(\d{6,})\.(seg|wal)$`)
+
+// lsmManifest is the on-disk list of the segments that make up an LSM store
+type lsmManifest struct {
+	Version       int      `json:"version"`
+	Segments      []uint64 `json:"segments"`
+	Remote        []uint64 `json:"remote,omitempty"` // segments held by the object store
+	NextSegmentID uint64   `json:"next_segment_id"`
+}
+
+// LSMStorage provides log-structured storage built for vectors. Writes go to a
+// write-ahead log and an in-memory memtable, which is flushed in the background
+// to an immutable segment file once it holds MemtableSize bytes. A segment keeps
+// the components of all its vectors in one contiguous float64 array, apart from
+// their IDs and metadata, so that scans for index builds and exports read it
+// sequentially. Deletes write tombstones; once there are MergeThreshold
+// segments, a background merge rewrites them into one, dropping tombstones and
+// the versions that reads no longer see. With an object store configured,
+// merged segments are cold: they are uploaded to the object store instead of
+// kept on local disk and read through range reads and a local block cache.
+type LSMStorage struct {
+	config         StorageConfig
+	dir            string
+	versions       versionPolicy
+	memtableSize   int64
+	mergeThreshold int
+	objects        ObjectStore // holds merged segments when set
+
+	active   *lsmMemtable // receives writes; nil once closed
+	flushing *lsmMemtable // being written to a segment, if any
+	segments []*lsmSegment
+	nextID   uint64
+	sequence uint64
+	count    int64 // vectors whose newest version is not a tombstone
+	mutex    sync.RWMutex
+
+	// Flushes and merges run one at a time, in the background when triggered by writes
+	flushMutex   sync.Mutex
+	mergeMutex   sync.Mutex
+	flushRunning atomic.Bool
+	merging      atomic.Bool
+	closing      atomic.Bool
+	background   sync.WaitGroup
+
+	// Statistics; reads run concurrently, so their timing is kept outside stats
+	stats       StorageStats
+	avgReadTime atomic.Uint64 // float64 bits
+	startTime   time.Time
+}
+
+// lsmEntry is a version of a vector held by a memtable, or a tombstone. The
+// tombstone of a deletion is keyed by the time of the deletion and, with
+// version history enabled, keeps the versions before it readable as of earlier
+// times; a tombstone without that time hides every version before it.
+type lsmEntry struct {
+	record   []byte // write-ahead log record of the vector; nil for a tombstone
+	sequence uint64
+	updated  time.Time
+}
+
+// split returns the components and metadata of the record of the entry
+func (e *lsmEntry) split() ([]byte, []byte) {
+	idLen := int(binary.LittleEndian.Uint32(e.record[12:]))
+	dimension := int(binary.LittleEndian.Uint32(e.record[16:]))
+	start := recordHeaderSize + idLen
+	return e.record[start : start+8*dimension], e.record[start+8*dimension:]
+}
+
+// lsmMemtable buffers writes in memory until they are flushed to the segment
+// with the same ID. Its records are kept in write-ahead log files as well.
+type lsmMemtable struct {
+	id       uint64
+	entries  map[string][]*lsmEntry // versions of every vector, oldest first
+	size     int64
+	wal      *os.File // receives the records written to the memtable
+	walPaths []string
+	walSize  int64
+}
+
+// lsmMemtableView is a sorted copy of the entries of a memtable
+type lsmMemtableView struct {
+	ids      []string
+	versions [][]*lsmEntry
+}
+
+// newLSMMemtable creates an empty memtable logging to a new file at path
+func newLSMMemtable(path string, id uint64) (*lsmMemtable, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log: %w", err)
+	}
+	return &lsmMemtable{
+		id:       id,
+		entries:  make(map[string][]*lsmEntry),
+		wal:      file,
+		walPaths: []string{path},
+	}, nil
+}
+
+// log appends records to the write-ahead log of the memtable
+func (t *lsmMemtable) log(records []byte, sync bool) error {
+	if _, err := t.wal.Write(records); err != nil {
+		return fmt.Errorf("%w: failed to append to write-ahead log: %v", ErrWriteFailed, err)
+	}
+	t.walSize += int64(len(records))
+	if sync {
+		if err := t.wal.Sync(); err != nil {
+			return fmt.Errorf("%w: failed to sync write-ahead log: %v", ErrWriteFailed, err)
+		}
+	}
+	return nil
+}
+
+// put adds a version of vector id. A tombstone replaces every version the
+// memtable holds unless it keeps version history and the time of the deletion;
+// a version with the UpdatedAt of the newest one or, without version history,
+// any version replaces the newest one.
+func (t *lsmMemtable) put(id string, entry *lsmEntry, keepVersions bool) {
+	versions := t.entries[id]
+	last := len(versions) - 1
+	switch {
+	case last < 0 || entry.record == nil && (!keepVersions || entry.updated.IsZero()):
+		versions = []*lsmEntry{entry}
+	case entry.record != nil && versions[last].record != nil && (!keepVersions || versions[last].updated.Equal(entry.updated)):
+		versions[last] = entry
+	default:
+		versions = append(versions, entry)
+	}
+	t.entries[id] = versions
+	t.size += int64(len(id) + len(entry.record) + 64)
+}
+
+// view returns a sorted copy of the entries of the memtable
+func (t *lsmMemtable) view() *lsmMemtableView {
+	view := &lsmMemtableView{ids: make([]string, 0, len(t.entries))}
+	for id := range t.entries {
+		view.ids = append(view.ids, id)
+	}
+	sort.Strings(view.ids)
+	view.versions = make([][]*lsmEntry, len(view.ids))
+	for i, id := range view.ids {
+		view.versions[i] = append([]*lsmEntry(nil), t.entries[id]...)
+	}
+	return view
+}
+
+// closeLog syncs and closes the write-ahead log receiving the records of the memtable
+func (t *lsmMemtable) closeLog() error {
+	if t.wal == nil {
+		return nil
+	}
+	file := t.wal
+	t.wal = nil
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close write-ahead log: %w", err)
+	}
+	return nil
+}
+
+// removeLogs deletes the write-ahead log files of a flushed memtable
+func (t *lsmMemtable) removeLogs() error {
+	for _, path := range t.walPaths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove write-ahead log: %w", err)
+		}
+	}
+	return nil
+}
+
+// lsmVersion is a version of a vector, or a tombstone, held by a memtable
+// entry or a segment row
+type lsmVersion struct {
+	sequence uint64
+	updated  time.Time
+	deleted  bool
+	entry    *lsmEntry
+	segment  *lsmSegment
+	row      int
+}
+
+// entryVersion returns the version held by a memtable entry
+func entryVersion(entry *lsmEntry) lsmVersion {
+	return lsmVersion{sequence: entry.sequence, updated: entry.updated, deleted: entry.record == nil, entry: entry}
+}
+
+// rowVersion returns the version held by row i of a segment
+func rowVersion(segment *lsmSegment, i int) lsmVersion {
+	row := &segment.rows[i]
+	return lsmVersion{sequence: row.sequence, updated: row.updated, deleted: row.tombstone, segment: segment, row: i}
+}
+
+// raw returns the components and metadata of a version, verifying the
+// checksum of a segment row; readers, when set, hold the sequential readers of
+// the segments of a scan
+func (v lsmVersion) raw(readers map[*lsmSegment]*lsmSegmentReader) ([]byte, []byte, error) {
+	if v.entry != nil {
+		components, meta := v.entry.split()
+		return components, meta, nil
+	}
+	return v.segment.raw(v.row, segmentReader(readers, v.segment))
+}
+
+// load decodes a version
+func (v lsmVersion) load(readers map[*lsmSegment]*lsmSegmentReader) (*core.Vector, error) {
+	if v.entry != nil {
+		return decodeRecord(v.entry.record)
+	}
+	return v.segment.read(v.row, segmentReader(readers, v.segment))
+}
+
+// segmentReader returns the sequential reader of a segment from readers,
+// creating it when needed; it returns nil without readers
+func segmentReader(readers map[*lsmSegment]*lsmSegmentReader, segment *lsmSegment) *lsmSegmentReader {
+	if readers == nil {
+		return nil
+	}
+	reader, exists := readers[segment]
+	if !exists {
+		reader = newLSMSegmentReader(segment)
+		readers[segment] = reader
+	}
+	return reader
+}
+
+// newest returns the version with the highest sequence number
+func newest(versions []lsmVersion) (lsmVersion, bool) {
+	if len(versions) == 0 {
+		return lsmVersion{}, false
+	}
+	best := versions[0]
+	for _, version := range versions[1:] {
+		if version.sequence > best.sequence {
+			best = version
+		}
+	}
+	return best, true
+}
+
+// lsmSource walks the versions held by a memtable view or a segment in ID order
+type lsmSource struct {
+	view    *lsmMemtableView
+	segment *lsmSegment
+	next    int
+}
+
+// peek returns the next ID of the source
+func (s *lsmSource) peek() (string, bool) {
+	if s.view != nil {
+		if s.next < len(s.view.ids) {
+			return s.view.ids[s.next], true
+		}
+		return "", false
+	}
+	if s.next < len(s.segment.rows) {
+		return s.segment.rows[s.next].id, true
+	}
+	return "", false
+}
+
+// take appends the versions of id held by the source to versions and moves past them
+func (s *lsmSource) take(id string, versions []lsmVersion) []lsmVersion {
+	if s.view != nil {
+		if s.next < len(s.view.ids) && s.view.ids[s.next] == id {
+			for _, entry := range s.view.versions[s.next] {
+				versions = append(versions, entryVersion(entry))
+			}
+			s.next++
+		}
+		return versions
+	}
+	for s.next < len(s.segment.rows) && s.segment.rows[s.next].id == id {
+		versions = append(versions, rowVersion(s.segment, s.next))
+		s.next++
+	}
+	return versions
+}
+
+// eachVector calls fn in ID order with the versions of every vector held by
+// views and segments; fn must not keep versions
+func eachVector(views []*lsmMemtableView, segments []*lsmSegment, fn func(id string, versions []lsmVersion) error) error {
+	sources := make([]*lsmSource, 0, len(views)+len(segments))
+	for _, view := range views {
+		sources = append(sources, &lsmSource{view: view})
+	}
+	for _, segment := range segments {
+		sources = append(sources, &lsmSource{segment: segment})
+	}
+
+	var versions []lsmVersion
+	for {
+		id, found := "", false
+		for _, source := range sources {
+			if next, ok := source.peek(); ok && (!found || next < id) {
+				id, found = next, true
+			}
+		}
+		if !found {
+			return nil
+		}
+
+		versions = versions[:0]
+		for _, source := range sources {
+			versions = source.take(id, versions)
+		}
+		if err := fn(id, versions); err != nil {
+			return err
+		}
+	}
+}
+
+// NewLSMStorage creates a new LSM storage engine in the DataPath directory
+func NewLSMStorage(config StorageConfig) (StorageEngine, error) {
+	if err := os.MkdirAll(config.DataPath, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	l := &LSMStorage{
+		config:         config,
+		dir:            config.DataPath,
+		versions:       newVersionPolicy(config),
+		memtableSize:   config.MemtableSize,
+		mergeThreshold: config.MergeThreshold,
+		nextID:         1,
+		startTime:      time.Now(),
+	}
+	if l.memtableSize == 0 {
+		l.memtableSize = defaultMemtableSize
+	}
+	if l.mergeThreshold == 0 {
+		l.mergeThreshold = defaultMergeThreshold
+	}
+	if config.ObjectStore != nil {
+		objects, err := openLSMObjectStore(*config.ObjectStore, filepath.Join(config.DataPath, lsmCacheDir))
+		if err != nil {
+			return nil, err
+		}
+		l.objects = objects
+	}
+
+	if err := l.open(); err != nil {
+		if closeErr := l.closeFiles(); closeErr != nil {
+			return nil, fmt.Errorf("failed to open LSM storage and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// openLSMObjectStore opens the object store of config behind a block cache in dir
+func openLSMObjectStore(config ObjectStoreConfig, dir string) (ObjectStore, error) {
+	store, err := OpenObjectStore(config)
+	if err != nil {
+		return nil, err
+	}
+	capacity := config.CacheSize
+	if capacity == 0 {
+		capacity = defaultObjectCacheSize
+	}
+	return NewCachedObjectStore(store, dir, capacity)
+}
+
+// open loads the segments of the manifest and replays the write-ahead logs of
+// the writes that were not flushed into a new memtable
+func (l *LSMStorage) open() error {
+	manifest, err := l.loadManifest()
+	if err != nil {
+		return err
+	}
+	if manifest != nil {
+		l.nextID = manifest.NextSegmentID
+		remote := make(map[uint64]bool, len(manifest.Remote))
+		for _, id := range manifest.Remote {
+			remote[id] = true
+		}
+		for _, id := range manifest.Segments {
+			var segment *lsmSegment
+			switch {
+			case !remote[id]:
+				segment, err = openLSMSegment(l.segmentPath(id), id)
+			case l.objects == nil:
+				err = fmt.Errorf("segment %d is held by an object store, which is not configured", id)
+			default:
+				segment, err = openRemoteLSMSegment(l.objects, l.segmentPath(id), id)
+			}
+			if err != nil {
+				return err
+			}
+			l.segments = append(l.segments, segment)
+			for _, row := range segment.rows {
+				if row.sequence > l.sequence {
+					l.sequence = row.sequence
+				}
+			}
+		}
+	}
+
+	logs, err := l.removeOrphans()
+	if err != nil {
+		return err
+	}
+	if err := l.removeRemoteOrphans(); err != nil {
+		return err
+	}
+
+	active, err := newLSMMemtable(l.walPath(l.nextID), l.nextID)
+	if err != nil {
+		return err
+	}
+	l.nextID++
+	l.active = active
+
+	// Records of memtables whose flush completed are in the segments already
+	flushed := l.sequence
+	for _, id := range logs {
+		path := l.walPath(id)
+		size, err := replayLog(path, flushed, func(id string, entry *lsmEntry) {
+			if entry.sequence > l.sequence {
+				l.sequence = entry.sequence
+			}
+			active.put(id, entry, l.versions.enabled())
+		})
+		if err != nil {
+			return err
+		}
+		active.walPaths = append(active.walPaths, path)
+		active.walSize += size
+	}
+
+	return eachVector([]*lsmMemtableView{active.view()}, l.segments, func(_ string, versions []lsmVersion) error {
+		if version, _ := newest(versions); !version.deleted {
+			l.count++
+		}
+		return nil
+	})
+}
+
+// removeOrphans deletes segment files that are not part of the manifest, such
+// as the output of a flush or merge interrupted before it was installed or the
+// local copy of an offloaded segment, and returns the IDs of the write-ahead
+// logs left behind, in order
+func (l *LSMStorage) removeOrphans() ([]uint64, error) {
+	known := make(map[uint64]bool, len(l.segments))
+	for _, segment := range l.segments {
+		if segment.remote == nil {
+			known[segment.id] = true
+		}
+	}
+
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list segments: %w", err)
+	}
+	var logs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if filepath.Ext(name) == ".tmp" {
+			if err := os.Remove(filepath.Join(l.dir, name)); err != nil {
+				return nil, fmt.Errorf("failed to remove unfinished segment: %w", err)
+			}
+			continue
+		}
+		match := lsmFilePattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if id >= l.nextID {
+			l.nextID = id + 1
+		}
+
+		switch {
+		case match[2] == "wal":
+			logs = append(logs, id)
+		case !known[id]:
+			if err := os.Remove(filepath.Join(l.dir, name)); err != nil {
+				return nil, fmt.Errorf("failed to remove orphaned segment: %w", err)
+			}
+		}
+	}
+
+	sort.Slice(logs, func(i, j int) bool { return logs[i] < logs[j] })
+	return logs, nil
+}
+
+// removeRemoteOrphans deletes the segments of the object store that are not
+// part of the manifest, such as a segment uploaded by a merge interrupted
+// before it was installed
+func (l *LSMStorage) removeRemoteOrphans() error {
+	if l.objects == nil {
+		return nil
+	}
+	known := make(map[uint64]bool, len(l.segments))
+	for _, segment := range l.segments {
+		if segment.remote != nil {
+			known[segment.id] = true
+		}
+	}
+
+	ctx := context.Background()
+	objects, err := l.objects.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list offloaded segments: %w", err)
+	}
+	for _, object := range objects {
+		match := lsmFilePattern.FindStringSubmatch(object.Key)
+		if match == nil || match[2] != "seg" {
+			continue
+		}
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || known[id] {
+			continue
+		}
+		if id >= l.nextID {
+			l.nextID = id + 1
+		}
+		if err := l.objects.Delete(ctx, object.Key); err != nil {
+			return fmt.Errorf("failed to remove orphaned segment: %w", err)
+		}
+	}
+	return nil
+}
+
+// replayLog calls fn for every record of a write-ahead log with a sequence
+// number above after and returns the size of the file. Replay stops at the
+// first torn or corrupt record, which ends what was durably logged.
+func replayLog(path string, after uint64, fn func(id string, entry *lsmEntry)) (int64, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+
+	offset := 0
+	for offset+recordHeaderSize <= len(data) {
+		size := int(binary.LittleEndian.Uint32(data[offset:]))
+		if size < recordHeaderSize || offset+size > len(data) {
+			break
+		}
+		record := data[offset : offset+size]
+		if crc32.ChecksumIEEE(record[12:]) != binary.LittleEndian.Uint32(record[4:]) {
+			break
+		}
+		offset += size
+
+		sequence := binary.LittleEndian.Uint64(record[24:])
+		if sequence <= after {
+			continue
+		}
+		vector, err := decodeRecord(record)
+		if err != nil {
+			return 0, &CorruptionError{
+				ID:       recordID(record),
+				Location: fmt.Sprintf("%s offset %d", filepath.Base(path), offset-size),
+				Reason:   err.Error(),
+			}
+		}
+		entry := &lsmEntry{sequence: sequence, updated: vector.UpdatedAt}
+		if record[8] == recordFlagLive {
+			entry.record = record
+		}
+		fn(vector.ID, entry)
+	}
+
+	return int64(len(data)), nil
+}
+
+// Write stores multiple vectors to LSM storage
+func (l *LSMStorage) Write(vectors []*core.Vector) error {
+	return l.WriteWithContext(context.Background(), vectors)
+}
+
+// WriteWithContext stores multiple vectors with context support. The vectors
+// are logged and added to the memtable; they reach a segment when it is flushed.
+// A tombstone is added as DeleteWithContext adds one, even for a vector that is
+// not stored.
+func (l *LSMStorage) WriteWithContext(_ context.Context, vectors []*core.Vector) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active == nil {
+		return ErrStorageNotInitialized
+	}
+	start := time.Now()
+
+	ids := make([]string, len(vectors))
+	entries := make([]*lsmEntry, len(vectors))
+	written := make(map[string]time.Time, len(vectors))
+	var records []byte
+	for i, vector := range vectors {
+		l.sequence++
+		var record []byte
+		var err error
+		entry := &lsmEntry{sequence: l.sequence, updated: vector.UpdatedAt}
+		if vector.Deleted {
+			entry.updated = time.Time{}
+			if l.versions.enabled() {
+				previous, seen := written[vector.ID]
+				if version, found := newest(l.lookup(vector.ID)); !seen && found {
+					previous = version.updated
+				}
+				entry.updated = tombstone(vector.ID, previous, vector.UpdatedAt).UpdatedAt
+			}
+			record, err = tombstoneRecord(vector.ID, l.sequence, entry.updated)
+		} else {
+			record, err = encodeRecord(vector, l.sequence)
+			entry.record = record
+		}
+		if err != nil {
+			return err
+		}
+		records = append(records, record...)
+		ids[i] = vector.ID
+		entries[i] = entry
+		written[vector.ID] = entry.updated
+	}
+	if err := l.apply(ids, entries, records); err != nil {
+		return err
+	}
+
+	// Update statistics
+	l.stats.AvgWriteTime = float64(time.Since(start).Microseconds()) / float64(len(vectors))
+
+	return nil
+}
+
+// Read retrieves vectors by their IDs
+func (l *LSMStorage) Read(ids []string) ([]*core.Vector, error) {
+	return l.ReadWithContext(context.Background(), ids)
+}
+
+// ReadWithContext retrieves vectors with context support
+func (l *LSMStorage) ReadWithContext(_ context.Context, ids []string) ([]*core.Vector, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	start := time.Now()
+
+	vectors := make([]*core.Vector, 0, len(ids))
+	for _, id := range ids {
+		version, found := newest(l.lookup(id))
+		if !found || version.deleted {
+			continue
+		}
+		vector, err := version.load(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vector %s: %w", id, err)
+		}
+		vectors = append(vectors, vector)
+	}
+
+	// Update statistics
+	if len(ids) > 0 {
+		l.avgReadTime.Store(math.Float64bits(float64(time.Since(start).Microseconds()) / float64(len(ids))))
+	}
+
+	return vectors, nil
+}
+
+// Delete removes vectors by their IDs
+func (l *LSMStorage) Delete(ids []string) error {
+	return l.DeleteWithContext(context.Background(), ids)
+}
+
+// DeleteWithContext removes vectors with context support. Every stored vector
+// gets a tombstone. Without version history it hides the versions of the
+// vector until a merge drops them; with it, they stay readable as of the times
+// before the deletion.
+func (l *LSMStorage) DeleteWithContext(_ context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active == nil {
+		return ErrStorageNotInitialized
+	}
+	start := time.Now()
+
+	var deleted []string
+	var entries []*lsmEntry
+	var records []byte
+	for _, id := range ids {
+		version, found := newest(l.lookup(id))
+		if !found || version.deleted {
+			continue
+		}
+		var at time.Time
+		if l.versions.enabled() {
+			at = tombstone(id, version.updated, start).UpdatedAt
+		}
+		l.sequence++
+		record, err := tombstoneRecord(id, l.sequence, at)
+		if err != nil {
+			return err
+		}
+		records = append(records, record...)
+		deleted = append(deleted, id)
+		entries = append(entries, &lsmEntry{sequence: l.sequence, updated: at})
+	}
+	if len(entries) > 0 {
+		if err := l.apply(deleted, entries, records); err != nil {
+			return err
+		}
+	}
+
+	// Update statistics
+	l.stats.AvgDeleteTime = float64(time.Since(start).Microseconds()) / float64(len(ids))
+
+	return nil
+}
+
+// ReadAsOf retrieves the versions of vectors that were current at the given time
+func (l *LSMStorage) ReadAsOf(ids []string, asOf time.Time) ([]*core.Vector, error) {
+	return l.ReadAsOfWithContext(context.Background(), ids, asOf)
+}
+
+// ReadAsOfWithContext retrieves past versions of vectors with context support
+func (l *LSMStorage) ReadAsOfWithContext(_ context.Context, ids []string, asOf time.Time) ([]*core.Vector, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	now := time.Now()
+	vectors := make([]*core.Vector, 0, len(ids))
+	for _, id := range ids {
+		chain := l.chain(l.lookup(id), now)
+		updated := make([]time.Time, len(chain))
+		for i, version := range chain {
+			updated[i] = version.updated
+		}
+
+		i := versionAsOf(updated, asOf)
+		if i < 0 || chain[i].deleted {
+			continue
+		}
+		vector, err := chain[i].load(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vector %s: %w", id, err)
+		}
+		vectors = append(vectors, vector)
+	}
+
+	return vectors, nil
+}
+
+// tombstoneRecord returns the write-ahead log record of the deletion of id at
+// the given time, which is zero for a tombstone hiding every previous version
+func tombstoneRecord(id string, sequence uint64, deleted time.Time) ([]byte, error) {
+	record, err := encodeRecord(&core.Vector{ID: id, UpdatedAt: deleted}, sequence)
+	if err != nil {
+		return nil, err
+	}
+	record[8] = recordFlagDeleted
+	return record, nil
+}
+
+// apply logs records and adds entries, the versions of ids they hold, to the
+// active memtable; the caller must hold the write lock
+func (l *LSMStorage) apply(ids []string, entries []*lsmEntry, records []byte) error {
+	if err := l.active.log(records, l.config.SyncOnWrite); err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		version, found := newest(l.lookup(ids[i]))
+		live := found && !version.deleted
+		switch {
+		case entry.record != nil && !live:
+			l.count++
+		case entry.record == nil && live:
+			l.count--
+		}
+		l.active.put(ids[i], entry, l.versions.enabled())
+	}
+	l.stats.TotalVectors = l.count
+
+	l.maybeFlush()
+	return nil
+}
+
+// memtables returns the memtable being flushed, if any, and the active one;
+// the caller must hold the lock
+func (l *LSMStorage) memtables() []*lsmMemtable {
+	var memtables []*lsmMemtable
+	for _, memtable := range []*lsmMemtable{l.flushing, l.active} {
+		if memtable != nil {
+			memtables = append(memtables, memtable)
+		}
+	}
+	return memtables
+}
+
+// lookup returns every version of id held by the memtables and segments; the
+// caller must hold the lock
+func (l *LSMStorage) lookup(id string) []lsmVersion {
+	var versions []lsmVersion
+	for _, memtable := range l.memtables() {
+		for _, entry := range memtable.entries[id] {
+			versions = append(versions, entryVersion(entry))
+		}
+	}
+	for _, segment := range l.segments {
+		start, end := segment.find(id)
+		for i := start; i < end; i++ {
+			versions = append(versions, rowVersion(segment, i))
+		}
+	}
+	return versions
+}
+
+// chain returns the versions of a vector that reads see, oldest first: those
+// written since it was last hidden by a tombstone, without the versions replaced
+// by a rewrite with the same UpdatedAt or outside the version policy. The chain
+// of a deleted vector ends with the tombstone of its deletion until that is
+// forgotten. Without version history only the newest version is left. It
+// reorders versions in place.
+func (l *LSMStorage) chain(versions []lsmVersion, now time.Time) []lsmVersion {
+	sort.Slice(versions, func(i, j int) bool { return versions[i].sequence < versions[j].sequence })
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].deleted && (!l.versions.enabled() || versions[i].updated.IsZero()) {
+			versions = versions[i+1:]
+			break
+		}
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	if !l.versions.enabled() {
+		return versions[len(versions)-1:]
+	}
+	if last := versions[len(versions)-1]; last.deleted && l.versions.forgotten(last.updated, now) {
+		return nil
+	}
+
+	// Previous versions are ordered by their version key, and the latest write
+	// of a key replaces the others
+	previous := versions[:len(versions)-1]
+	sort.SliceStable(previous, func(i, j int) bool { return previous[i].updated.Before(previous[j].updated) })
+	kept := versions[:0]
+	for i, version := range versions {
+		if i+1 < len(versions) && version.updated.Equal(versions[i+1].updated) {
+			continue
+		}
+		kept = append(kept, version)
+	}
+
+	updated := make([]time.Time, len(kept))
+	for i, version := range kept {
+		updated[i] = version.updated
+	}
+	return kept[l.versions.prunable(updated, now):]
+}
+
+// Scan calls fn for every stored vector in ID order. It reads a snapshot of
+// the store taken when it starts, without holding up writes, and reads each
+// segment sequentially.
+func (l *LSMStorage) Scan(ctx context.Context, fn func(*core.Vector) error) error {
+	views, segments, err := l.snapshot()
+	if err != nil {
+		return err
+	}
+	defer l.releaseAll(segments)
+
+	readers := make(map[*lsmSegment]*lsmSegmentReader, len(segments))
+	return eachVector(views, segments, func(id string, versions []lsmVersion) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		version, _ := newest(versions)
+		if version.deleted {
+			return nil
+		}
+		vector, err := version.load(readers)
+		if err != nil {
+			return fmt.Errorf("failed to read vector %s: %w", id, err)
+		}
+		return fn(vector)
+	})
+}
+
+// ScanVersions calls fn with the versions of every stored or deleted vector in
+// ID order, from a snapshot of the store taken when it starts
+func (l *LSMStorage) ScanVersions(ctx context.Context, fn func([]*core.Vector) error) error {
+	views, segments, err := l.snapshot()
+	if err != nil {
+		return err
+	}
+	defer l.releaseAll(segments)
+
+	readers := make(map[*lsmSegment]*lsmSegmentReader, len(segments))
+	now := time.Now()
+	return eachVector(views, segments, func(id string, versions []lsmVersion) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chain := l.chain(versions, now)
+		if len(chain) == 0 {
+			return nil
+		}
+		vectors := make([]*core.Vector, len(chain))
+		for i, version := range chain {
+			if version.deleted {
+				vectors[i] = &core.Vector{ID: id, UpdatedAt: version.updated, Deleted: true}
+				continue
+			}
+			vector, err := version.load(readers)
+			if err != nil {
+				return fmt.Errorf("failed to read vector %s: %w", id, err)
+			}
+			vectors[i] = vector
+		}
+		return fn(vectors)
+	})
+}
+
+// snapshot returns views of the memtables and the segments, each of which
+// the caller must release with releaseAll
+func (l *LSMStorage) snapshot() ([]*lsmMemtableView, []*lsmSegment, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if l.active == nil {
+		return nil, nil, ErrStorageNotInitialized
+	}
+	var views []*lsmMemtableView
+	for _, memtable := range l.memtables() {
+		views = append(views, memtable.view())
+	}
+	segments := append([]*lsmSegment(nil), l.segments...)
+	for _, segment := range segments {
+		segment.acquire()
+	}
+	return views, segments, nil
+}
+
+// releaseAll releases the segments of a snapshot
+func (l *LSMStorage) releaseAll(segments []*lsmSegment) {
+	for _, segment := range segments {
+		_ = segment.release()
+	}
+}
+
+// lsmCorruptRow is a corrupt segment row found by Scrub
+type lsmCorruptRow struct {
+	id      string
+	segment *lsmSegment
+	row     int
+}
+
+// Scrub verifies the checksum of every segment row that reads can see,
+// including previous versions; shadowed rows are left to the next merge.
+// Corrupt rows are copied to the quarantine file when opts asks for it, and a
+// tombstone hides them: the good previous versions of the vector are written
+// again, unless the corrupt row is its current version.
+func (l *LSMStorage) Scrub(ctx context.Context, opts ScrubOptions) (*ScrubReport, error) {
+	views, segments, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ScrubReport{}
+	var found []lsmCorruptRow
+	readers := make(map[*lsmSegment]*lsmSegmentReader, len(segments))
+	now := time.Now()
+	err = eachVector(views, segments, func(id string, versions []lsmVersion) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chain := l.chain(versions, now)
+		for i, version := range chain {
+			if version.deleted {
+				continue
+			}
+			report.Checked++
+			if version.segment == nil {
+				continue
+			}
+			_, err := version.load(readers)
+			var corruption *CorruptionError
+			if !errors.As(err, &corruption) {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			report.add(corruption, i < len(chain)-1)
+			found = append(found, lsmCorruptRow{id: id, segment: version.segment, row: version.row})
+		}
+		return nil
+	})
+	l.releaseAll(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) == 0 || !opts.quarantines() {
+		return report, nil
+	}
+	if err := l.quarantine(found, report); err != nil {
+		return nil, err
+	}
+	if opts.Repair != nil {
+		if err := repairRecords(ctx, l, opts.Repair, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// quarantine copies the rows found by Scrub, which match the corrupt records
+// of report, to the quarantine file and hides them unless they changed since
+func (l *LSMStorage) quarantine(found []lsmCorruptRow, report *ScrubReport) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.active == nil {
+		return ErrStorageNotInitialized
+	}
+	file, err := os.OpenFile(filepath.Join(l.dir, lsmQuarantineFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open quarantine file: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+
+	now := time.Now()
+	for i, corrupt := range found {
+		chain := l.chain(l.lookup(corrupt.id), now)
+		at := -1
+		for j, version := range chain {
+			if version.segment == corrupt.segment && version.row == corrupt.row {
+				at = j
+			}
+		}
+		if at < 0 {
+			// Rewritten or deleted since the scrub
+			continue
+		}
+
+		components, meta, err := corrupt.segment.rawUnchecked(corrupt.row, nil)
+		if err == nil {
+			record := &report.Corrupt[i]
+			err = encoder.Encode(quarantinedRecord{
+				ID:            record.ID,
+				Location:      record.Location,
+				Reason:        record.Reason,
+				Data:          append(append([]byte(corrupt.id), components...), meta...),
+				QuarantinedAt: now,
+			})
+		}
+		if err == nil {
+			err = l.hide(corrupt.id, chain, at)
+		}
+		if err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to quarantine record: %w", err)
+		}
+		report.Corrupt[i].Action = ScrubActionQuarantined
+		report.Quarantined++
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync quarantine file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close quarantine file: %w", err)
+	}
+	return nil
+}
+
+// hide writes a tombstone for vector id followed by the versions of chain other
+// than the one at index at, unless that is the current version; versions that
+// cannot be read are left out. The caller must hold the write lock.
+func (l *LSMStorage) hide(id string, chain []lsmVersion, at int) error {
+	l.sequence++
+	records, err := tombstoneRecord(id, l.sequence, time.Time{})
+	if err != nil {
+		return err
+	}
+	ids := []string{id}
+	entries := []*lsmEntry{{sequence: l.sequence}}
+
+	if at < len(chain)-1 {
+		for i, version := range chain {
+			if i == at {
+				continue
+			}
+			if version.deleted {
+				l.sequence++
+				record, err := tombstoneRecord(id, l.sequence, version.updated)
+				if err != nil {
+					return err
+				}
+				records = append(records, record...)
+				ids = append(ids, id)
+				entries = append(entries, &lsmEntry{sequence: l.sequence, updated: version.updated})
+				continue
+			}
+			vector, err := version.load(nil)
+			if err != nil {
+				continue
+			}
+			l.sequence++
+			record, err := encodeRecord(vector, l.sequence)
+			if err != nil {
+				return err
+			}
+			records = append(records, record...)
+			ids = append(ids, id)
+			entries = append(entries, &lsmEntry{record: record, sequence: l.sequence, updated: vector.UpdatedAt})
+		}
+	}
+
+	return l.apply(ids, entries, records)
+}
+
+// Compact flushes every write to a segment and merges the segments into one
+func (l *LSMStorage) Compact() error {
+	if l.closing.Load() {
+		return ErrStorageNotInitialized
+	}
+	if err := l.flush(true); err != nil {
+		return err
+	}
+	return l.merge()
+}
+
+// maybeFlush starts a background flush once the active memtable is full, or
+// retries a flush that failed; the caller must hold the write lock
+func (l *LSMStorage) maybeFlush() {
+	if l.closing.Load() || (l.flushing == nil && l.active.size < l.memtableSize) {
+		return
+	}
+	if !l.flushRunning.CompareAndSwap(false, true) {
+		return
+	}
+
+	l.background.Add(1)
+	go func() {
+		defer l.background.Done()
+		defer l.flushRunning.Store(false)
+
+		_ = l.flush(false)
+	}()
+}
+
+// flush writes the memtable waiting to be flushed, or else the active one, to a
+// new segment; with all set, it goes on until every write is in a segment.
+// Writes continue into a new active memtable meanwhile.
+func (l *LSMStorage) flush(all bool) error {
+	l.flushMutex.Lock()
+	defer l.flushMutex.Unlock()
+
+	for {
+		l.mutex.Lock()
+		if l.flushing == nil {
+			if l.active == nil || len(l.active.entries) == 0 {
+				l.mutex.Unlock()
+				return nil
+			}
+			if err := l.rotate(); err != nil {
+				l.mutex.Unlock()
+				return err
+			}
+		}
+		memtable := l.flushing
+		l.mutex.Unlock()
+
+		if err := l.writeMemtable(memtable); err != nil {
+			return err
+		}
+		if !all {
+			return nil
+		}
+	}
+}
+
+// rotate makes the active memtable the one being flushed and starts a new one;
+// the caller must hold the write lock
+func (l *LSMStorage) rotate() error {
+	memtable, err := newLSMMemtable(l.walPath(l.nextID), l.nextID)
+	if err != nil {
+		return err
+	}
+	l.nextID++
+	l.flushing, l.active = l.active, memtable
+	return l.flushing.closeLog()
+}
+
+// writeMemtable writes the memtable being flushed to a segment and installs it
+func (l *LSMStorage) writeMemtable(memtable *lsmMemtable) error {
+	view := memtable.view()
+	segment, err := l.writeSegment(memtable.id, func(writer *lsmSegmentWriter) error {
+		for i, id := range view.ids {
+			for _, entry := range view.versions[i] {
+				var components, meta []byte
+				if entry.record != nil {
+					components, meta = entry.split()
+				}
+				if err := writer.add(id, entry.sequence, entry.updated, components, meta, entry.record == nil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to flush memtable: %w", err)
+	}
+
+	l.mutex.Lock()
+	l.segments = append(l.segments, segment)
+	if err := l.saveManifest(); err != nil {
+		l.segments = l.segments[:len(l.segments)-1]
+		l.mutex.Unlock()
+		segment.obsolete.Store(true)
+		_ = segment.release()
+		return err
+	}
+	l.flushing = nil
+	l.maybeMerge()
+	l.mutex.Unlock()
+
+	return memtable.removeLogs()
+}
+
+// merge rewrites every segment into one, keeping only the rows reads can see.
+// The segments are read while writes and flushes continue; the merged segment
+// replaces them once it is complete.
+func (l *LSMStorage) merge() error {
+	l.mergeMutex.Lock()
+	defer l.mergeMutex.Unlock()
+
+	l.mutex.Lock()
+	if l.closing.Load() {
+		l.mutex.Unlock()
+		return ErrStorageNotInitialized
+	}
+	// Flushes only append segments, so the victims stay the first segments
+	victims := append([]*lsmSegment(nil), l.segments...)
+	now := time.Now()
+	if len(victims) == 0 || (len(victims) == 1 && !l.garbage(victims[0], now)) {
+		l.mutex.Unlock()
+		return nil
+	}
+	id := l.nextID
+	l.nextID++
+	l.mutex.Unlock()
+
+	// The victims hold every version older than their newest rows, so tombstones
+	// can be dropped together with the versions they hide; the tombstones of
+	// deletions are kept with the versions before them until they are forgotten
+	readers := make(map[*lsmSegment]*lsmSegmentReader, len(victims))
+	merged, err := l.writeSegment(id, func(writer *lsmSegmentWriter) error {
+		return eachVector(nil, victims, func(id string, versions []lsmVersion) error {
+			for _, version := range l.chain(versions, now) {
+				var components, meta []byte
+				if !version.deleted {
+					var err error
+					if components, meta, err = version.raw(readers); err != nil {
+						return fmt.Errorf("failed to read vector %s: %w", id, err)
+					}
+				}
+				if err := writer.add(id, version.sequence, version.updated, components, meta, version.deleted); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge segments: %w", err)
+	}
+	local := merged
+	if l.objects != nil && len(merged.rows) > 0 {
+		merged, err = l.offload(local)
+		if err != nil {
+			local.obsolete.Store(true)
+			_ = local.release()
+			return fmt.Errorf("failed to offload merged segment: %w", err)
+		}
+	}
+
+	l.mutex.Lock()
+	previous := l.segments
+	segments := make([]*lsmSegment, 0, len(previous)-len(victims)+1)
+	if len(merged.rows) > 0 {
+		segments = append(segments, merged)
+	}
+	l.segments = append(segments, previous[len(victims):]...)
+	if err := l.saveManifest(); err != nil {
+		l.segments = previous
+		l.mutex.Unlock()
+		merged.obsolete.Store(true)
+		_ = merged.release()
+		if local != merged {
+			local.obsolete.Store(true)
+			_ = local.release()
+		}
+		return err
+	}
+	l.mutex.Unlock()
+
+	if len(merged.rows) == 0 {
+		victims = append(victims, merged)
+	}
+	if local != merged {
+		victims = append(victims, local)
+	}
+	var firstErr error
+	for _, victim := range victims {
+		victim.obsolete.Store(true)
+		if err := victim.release(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// garbage reports whether a segment holds rows that reads do not see
+func (l *LSMStorage) garbage(segment *lsmSegment, now time.Time) bool {
+	used := 0
+	_ = eachVector(nil, []*lsmSegment{segment}, func(_ string, versions []lsmVersion) error {
+		used += len(l.chain(versions, now))
+		return nil
+	})
+	return used < len(segment.rows)
+}
+
+// maybeMerge starts a background merge once there are enough segments; the
+// caller must hold the write lock
+func (l *LSMStorage) maybeMerge() {
+	if l.closing.Load() || len(l.segments) < l.mergeThreshold || !l.merging.CompareAndSwap(false, true) {
+		return
+	}
+
+	l.background.Add(1)
+	go func() {
+		defer l.background.Done()
+		defer l.merging.Store(false)
+
+		_ = l.merge()
+	}()
+}
+
+// writeSegment writes a new segment with the rows added by fill and opens it
+func (l *LSMStorage) writeSegment(id uint64, fill func(*lsmSegmentWriter) error) (*lsmSegment, error) {
+	path := l.segmentPath(id)
+	writer, err := createLSMSegment(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := fill(writer); err != nil {
+		writer.abort()
+		return nil, err
+	}
+	if err := writer.finish(); err != nil {
+		writer.abort()
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return nil, fmt.Errorf("failed to install segment: %w", err)
+	}
+
+	segment, err := openLSMSegment(path, id)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return segment, nil
+}
+
+// offload uploads a local segment to the object store and opens the uploaded
+// copy; the local segment is left to the caller
+func (l *LSMStorage) offload(segment *lsmSegment) (*lsmSegment, error) {
+	file, err := os.Open(filepath.Clean(segment.path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment: %w", err)
+	}
+	key := filepath.Base(segment.path)
+	err = l.objects.Put(context.Background(), key, file)
+	if closeErr := file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := openRemoteLSMSegment(l.objects, segment.path, segment.id)
+	if err != nil {
+		_ = l.objects.Delete(context.Background(), key)
+		return nil, err
+	}
+	return remote, nil
+}
+
+// GetStats returns storage performance and usage statistics. Fragmentation is
+// the share of segment rows that reads no longer see.
+func (l *LSMStorage) GetStats() StorageStats {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	stats := l.stats
+	stats.TotalVectors = l.count
+	stats.AvgReadTime = math.Float64frombits(l.avgReadTime.Load())
+
+	var views []*lsmMemtableView
+	for _, memtable := range l.memtables() {
+		views = append(views, memtable.view())
+		stats.MemoryUsage += memtable.size
+		stats.StorageSize += memtable.walSize
+		stats.FileCount += len(memtable.walPaths)
+	}
+	rows := 0
+	for _, segment := range l.segments {
+		rows += len(segment.rows)
+		stats.StorageSize += segment.size
+		stats.MemoryUsage += int64(len(segment.rows)) * lsmRowSize
+	}
+	stats.FileCount += len(l.segments)
+
+	used := 0
+	now := time.Now()
+	_ = eachVector(views, l.segments, func(_ string, versions []lsmVersion) error {
+		chain := l.chain(versions, now)
+		switch {
+		case len(chain) == 0:
+		case chain[len(chain)-1].deleted:
+			// The tombstone of a deleted vector is one of its previous versions
+			stats.VersionCount += int64(len(chain))
+		default:
+			stats.VersionCount += int64(len(chain) - 1)
+		}
+		for _, version := range chain {
+			if version.segment != nil {
+				used++
+			}
+		}
+		return nil
+	})
+	stats.Fragmentation = fragmentation(int64(rows), int64(rows-used))
+
+	return stats
+}
+
+// Close flushes every write to a segment and closes the store. Writes that
+// could not be flushed stay in the write-ahead log and are replayed when the
+// store is opened again.
+func (l *LSMStorage) Close() error {
+	// Background work is started under the lock, so none starts once closing is set
+	l.mutex.Lock()
+	closed := l.closing.Swap(true)
+	l.mutex.Unlock()
+	if closed {
+		return nil
+	}
+	l.background.Wait()
+	flushErr := l.flush(true)
+
+	l.flushMutex.Lock()
+	defer l.flushMutex.Unlock()
+	l.mergeMutex.Lock()
+	defer l.mergeMutex.Unlock()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.closeFiles(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// closeFiles closes the write-ahead logs and segments, removing the logs of an
+// empty memtable, and returns the first error
+func (l *LSMStorage) closeFiles() error {
+	var firstErr error
+	for _, memtable := range l.memtables() {
+		err := memtable.closeLog()
+		if err == nil && len(memtable.entries) == 0 {
+			err = memtable.removeLogs()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	l.active, l.flushing = nil, nil
+
+	for _, segment := range l.segments {
+		if err := segment.release(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	l.segments = nil
+	return firstErr
+}
+
+// segmentPath returns the file path of a segment
+func (l *LSMStorage) segmentPath(id uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%06d.seg", id))
+}
+
+// walPath returns the file path of the write-ahead log of a memtable
+func (l *LSMStorage) walPath(id uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%06d.wal", id))
+}
+
+// loadManifest reads the segment manifest; it returns nil for a new store
+func (l *LSMStorage) loadManifest() (*lsmManifest, error) {
+	data, err := os.ReadFile(filepath.Join(l.dir, lsmManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment manifest: %w", err)
+	}
+
+	var manifest lsmManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse segment manifest: %w", err)
+	}
+	if manifest.Version > lsmManifestVersion {
+		return nil, fmt.Errorf("unsupported segment manifest version %d", manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+// saveManifest atomically writes the segment manifest; the caller must hold the write lock
+func (l *LSMStorage) saveManifest() error {
+	manifest := lsmManifest{
+		Version:       1,
+		Segments:      make([]uint64, len(l.segments)),
+		NextSegmentID: l.nextID,
+	}
+	for i, segment := range l.segments {
+		manifest.Segments[i] = segment.id
+		if segment.remote != nil {
+			manifest.Remote = append(manifest.Remote, segment.id)
+		}
+	}
+	if len(manifest.Remote) > 0 {
+		manifest.Version = lsmManifestVersion
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode segment manifest: %w", err)
+	}
+
+	path := filepath.Join(l.dir, lsmManifestFile)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to write segment manifest: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to replace segment manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,478 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// Layout of an LSM segment file. A segment is written once, by a memtable flush
+// or a merge, and never changed. Its rows are sorted by vector ID, the rows of
+// one vector oldest first. The file holds four blocks and a footer:
+//
+//	components  the little-endian float64 components of every row, back to back
+//	ids         the IDs of the rows, back to back
+//	metadata    the JSON metadata of every row, back to back
+//	rows        a fixed-size entry per row
+//	footer      the offsets of the blocks and the row count
+//
+// A row entry holds:
+//
+//	offset  size  field
+//	0       8     write sequence number
+//	8       8     UpdatedAt of the vector in Unix nanoseconds, zero when unset
+//	16      8     offset of the first component in the components block, in components
+//	24      8     offset of the metadata in the metadata block
+//	32      8     offset of the ID in the ids block
+//	40      4     ID length
+//	44      4     dimension
+//	48      4     metadata length
+//	52      4     CRC32 of the ID, the components and the metadata
+//	56      1     flags (live or tombstone)
+//
+// and the footer:
+//
+//	0       8     magic
+//	8       4     format version
+//	12      4     row count
+//	16      8     offset of the ids block
+//	24      8     offset of the metadata block
+//	32      8     offset of the rows block
+//	40      4     CRC32 of the ids and rows blocks
+//	44      4     CRC32 of the footer up to here
+//
+// The ids and rows blocks are loaded into memory when a segment is opened; the
+// components and metadata of a row are read from the file when needed. A
+// segment offloaded to an object store is read the same way, through range
+// reads of the object named like the file.
+const (
+	lsmSegmentMagic   = "VJLSMSEG"
+	lsmSegmentVersion = 1
+
+	lsmRowSize    = 64
+	lsmFooterSize = 48
+
+	lsmRowFlagLive      byte = 1
+	lsmRowFlagTombstone byte = 2
+
+	// lsmReadBufferSize is the buffer size of the sequential readers of a scan
+	lsmReadBufferSize = 256 * 1024
+)
+
+// lsmRow is a row of a segment: a version of a vector, or a tombstone
+type lsmRow struct {
+	id        string
+	sequence  uint64
+	updated   time.Time
+	component int64 // offset of the first component in the components block
+	metadata  int64 // offset of the metadata in the metadata block
+	dimension int
+	metaLen   int
+	checksum  uint32
+	tombstone bool
+}
+
+// lsmSegmentData is the content of a segment: a local file, or an object read in ranges
+type lsmSegmentData interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// lsmSegment is an immutable, columnar file of vector rows
+type lsmSegment struct {
+	id   uint64
+	path string
+	data lsmSegmentData
+	size int64
+	rows []lsmRow
+
+	// remote is the object store holding the segment under the file name of
+	// path, or nil for a local segment
+	remote ObjectStore
+
+	// metadataOffset is the file offset of the metadata block
+	metadataOffset int64
+
+	// refs counts the segment list holding the segment and the scans reading it;
+	// the file or object is removed once a merge replaced it and the last
+	// reference is gone
+	refs     atomic.Int32
+	obsolete atomic.Bool
+}
+
+// openLSMSegment opens a segment file and loads its rows
+func openLSMSegment(path string, id uint64) (*lsmSegment, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to stat segment %d: %w", id, err)
+	}
+	return loadLSMSegment(&lsmSegment{id: id, path: path, data: file, size: info.Size()})
+}
+
+// openRemoteLSMSegment opens a segment held by an object store under the file
+// name of path and loads its rows
+func openRemoteLSMSegment(store ObjectStore, path string, id uint64) (*lsmSegment, error) {
+	key := filepath.Base(path)
+	info, err := store.Stat(context.Background(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment %d: %w", id, err)
+	}
+	return loadLSMSegment(&lsmSegment{id: id, path: path, data: objectReaderAt{store: store, key: key}, size: info.Size, remote: store})
+}
+
+// loadLSMSegment loads the rows of an opened segment, closing it on failure
+func loadLSMSegment(segment *lsmSegment) (*lsmSegment, error) {
+	segment.refs.Store(1)
+	if err := segment.load(); err != nil {
+		_ = segment.data.Close()
+		return nil, err
+	}
+	return segment, nil
+}
+
+// load reads the footer, the ids and the rows of the segment
+func (s *lsmSegment) load() error {
+	if s.size < lsmFooterSize {
+		return s.corruption("", "footer", "truncated segment")
+	}
+
+	footer := make([]byte, lsmFooterSize)
+	if _, err := s.data.ReadAt(footer, s.size-lsmFooterSize); err != nil {
+		return fmt.Errorf("failed to read footer of segment %d: %w", s.id, err)
+	}
+	if string(footer[:8]) != lsmSegmentMagic || crc32.ChecksumIEEE(footer[:44]) != binary.LittleEndian.Uint32(footer[44:]) {
+		return s.corruption("", "footer", "bad footer")
+	}
+	if version := binary.LittleEndian.Uint32(footer[8:]); version > lsmSegmentVersion {
+		return fmt.Errorf("unsupported format version %d of segment %d", version, s.id)
+	}
+
+	count := int64(binary.LittleEndian.Uint32(footer[12:]))
+	idsOffset := int64(binary.LittleEndian.Uint64(footer[16:]))
+	s.metadataOffset = int64(binary.LittleEndian.Uint64(footer[24:]))
+	rowsOffset := int64(binary.LittleEndian.Uint64(footer[32:]))
+	if idsOffset > s.metadataOffset || s.metadataOffset > rowsOffset || rowsOffset+count*lsmRowSize != s.size-lsmFooterSize {
+		return s.corruption("", "footer", "inconsistent block offsets")
+	}
+
+	ids := make([]byte, s.metadataOffset-idsOffset)
+	if _, err := s.data.ReadAt(ids, idsOffset); err != nil {
+		return fmt.Errorf("failed to read ids of segment %d: %w", s.id, err)
+	}
+	rows := make([]byte, count*lsmRowSize)
+	if _, err := s.data.ReadAt(rows, rowsOffset); err != nil {
+		return fmt.Errorf("failed to read rows of segment %d: %w", s.id, err)
+	}
+	if crc32.Update(crc32.ChecksumIEEE(ids), crc32.IEEETable, rows) != binary.LittleEndian.Uint32(footer[40:]) {
+		return s.corruption("", "rows", "checksum mismatch")
+	}
+
+	s.rows = make([]lsmRow, count)
+	for i := range s.rows {
+		entry := rows[int64(i)*lsmRowSize:]
+		idOffset := binary.LittleEndian.Uint64(entry[32:])
+		idLen := uint64(binary.LittleEndian.Uint32(entry[40:]))
+		if idOffset+idLen > uint64(len(ids)) {
+			return s.corruption("", fmt.Sprintf("row %d", i), "ID out of bounds")
+		}
+		s.rows[i] = lsmRow{
+			id:        string(ids[idOffset : idOffset+idLen]),
+			sequence:  binary.LittleEndian.Uint64(entry[0:]),
+			updated:   decodeUpdated(int64(binary.LittleEndian.Uint64(entry[8:]))),
+			component: int64(binary.LittleEndian.Uint64(entry[16:])),
+			metadata:  int64(binary.LittleEndian.Uint64(entry[24:])),
+			dimension: int(binary.LittleEndian.Uint32(entry[44:])),
+			metaLen:   int(binary.LittleEndian.Uint32(entry[48:])),
+			checksum:  binary.LittleEndian.Uint32(entry[52:]),
+			tombstone: entry[56] == lsmRowFlagTombstone,
+		}
+	}
+	return nil
+}
+
+// find returns the range of rows of vector id
+func (s *lsmSegment) find(id string) (int, int) {
+	start := sort.Search(len(s.rows), func(i int) bool { return s.rows[i].id >= id })
+	end := start
+	for end < len(s.rows) && s.rows[end].id == id {
+		end++
+	}
+	return start, end
+}
+
+// corruption describes a corrupt part of the segment
+func (s *lsmSegment) corruption(id, location, reason string) *CorruptionError {
+	return &CorruptionError{ID: id, Location: fmt.Sprintf("segment %d %s", s.id, location), Reason: reason}
+}
+
+// raw reads the components and metadata of row i and verifies its checksum
+func (s *lsmSegment) raw(i int, reader *lsmSegmentReader) ([]byte, []byte, error) {
+	row := &s.rows[i]
+	components, meta, err := s.rawUnchecked(i, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rowChecksum(row.id, components, meta) != row.checksum {
+		return nil, nil, s.corruption(row.id, fmt.Sprintf("row %d", i), "checksum mismatch")
+	}
+	return components, meta, nil
+}
+
+// rawUnchecked reads the components and metadata of row i, through reader when
+// it is set
+func (s *lsmSegment) rawUnchecked(i int, reader *lsmSegmentReader) ([]byte, []byte, error) {
+	row := &s.rows[i]
+	components := make([]byte, 8*row.dimension)
+	meta := make([]byte, row.metaLen)
+
+	var err error
+	if reader != nil {
+		err = reader.components.read(8*row.component, components)
+		if err == nil {
+			err = reader.metadata.read(s.metadataOffset+row.metadata, meta)
+		}
+	} else {
+		_, err = s.data.ReadAt(components, 8*row.component)
+		if err == nil {
+			_, err = s.data.ReadAt(meta, s.metadataOffset+row.metadata)
+		}
+	}
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, nil, s.corruption(row.id, fmt.Sprintf("row %d", i), "row out of bounds")
+		}
+		return nil, nil, fmt.Errorf("failed to read row %d of segment %d: %w", i, s.id, err)
+	}
+	return components, meta, nil
+}
+
+// read decodes row i after verifying its checksum
+func (s *lsmSegment) read(i int, reader *lsmSegmentReader) (*core.Vector, error) {
+	components, meta, err := s.raw(i, reader)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := decodeVector(s.rows[i].id, components, meta)
+	if err != nil {
+		return nil, s.corruption(s.rows[i].id, fmt.Sprintf("row %d", i), err.Error())
+	}
+	return vector, nil
+}
+
+// acquire takes a reference to the segment for a scan; the caller must hold
+// the lock under which the segment is listed
+func (s *lsmSegment) acquire() {
+	s.refs.Add(1)
+}
+
+// release drops a reference and removes the file or object of an obsolete
+// segment once the last reference is gone
+func (s *lsmSegment) release() error {
+	if s.refs.Add(-1) > 0 {
+		return nil
+	}
+	if err := s.data.Close(); err != nil {
+		return fmt.Errorf("failed to close segment %d: %w", s.id, err)
+	}
+	if !s.obsolete.Load() {
+		return nil
+	}
+	if s.remote != nil {
+		if err := s.remote.Delete(context.Background(), filepath.Base(s.path)); err != nil {
+			return fmt.Errorf("failed to remove segment %d: %w", s.id, err)
+		}
+		return nil
+	}
+	if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("failed to remove segment %d: %w", s.id, err)
+	}
+	return nil
+}
+
+// lsmSegmentReader reads the rows of a segment in order through buffers, so
+// that a scan reads the components and metadata blocks sequentially
+type lsmSegmentReader struct {
+	components sequentialReader
+	metadata   sequentialReader
+}
+
+// newLSMSegmentReader returns a sequential reader of a segment
+func newLSMSegmentReader(s *lsmSegment) *lsmSegmentReader {
+	return &lsmSegmentReader{
+		components: sequentialReader{file: s.data, size: s.size},
+		metadata:   sequentialReader{file: s.data, size: s.size},
+	}
+}
+
+// sequentialReader reads increasing offsets of a file through a buffer,
+// skipping the bytes in between
+type sequentialReader struct {
+	file     io.ReaderAt
+	size     int64
+	reader   *bufio.Reader
+	position int64
+}
+
+// read fills buf from offset
+func (r *sequentialReader) read(offset int64, buf []byte) error {
+	if r.reader == nil || offset < r.position {
+		r.reader = bufio.NewReaderSize(io.NewSectionReader(r.file, offset, r.size-offset), lsmReadBufferSize)
+		r.position = offset
+	}
+	if _, err := r.reader.Discard(int(offset - r.position)); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return err
+	}
+	r.position = offset + int64(len(buf))
+	return nil
+}
+
+// lsmSegmentWriter writes a segment file: components are streamed to the file
+// while the ids, metadata and rows blocks are buffered until finish
+type lsmSegmentWriter struct {
+	file       *os.File
+	path       string
+	components *bufio.Writer
+	count      int64 // components written
+	ids        bytes.Buffer
+	metadata   bytes.Buffer
+	rows       bytes.Buffer
+	rowCount   int
+	lastID     string
+}
+
+// createLSMSegment creates the file of a new segment
+func createLSMSegment(path string) (*lsmSegmentWriter, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create segment: %w", err)
+	}
+	return &lsmSegmentWriter{file: file, path: path, components: bufio.NewWriterSize(file, lsmReadBufferSize)}, nil
+}
+
+// add appends a row; rows must be added in ID order and, for one ID, in
+// sequence order. A tombstone has no components or metadata.
+func (w *lsmSegmentWriter) add(id string, sequence uint64, updated time.Time, components, meta []byte, tombstone bool) error {
+	if w.rowCount > 0 && id < w.lastID {
+		return fmt.Errorf("%w: row %s added out of order", ErrWriteFailed, id)
+	}
+	if w.rowCount == math.MaxUint32 || len(id) > math.MaxUint32 || len(meta) > math.MaxUint32 {
+		return fmt.Errorf("%w: segment row %s is too large", ErrWriteFailed, id)
+	}
+
+	entry := make([]byte, lsmRowSize)
+	binary.LittleEndian.PutUint64(entry[0:], sequence)
+	binary.LittleEndian.PutUint64(entry[8:], uint64(encodeUpdated(updated)))
+	binary.LittleEndian.PutUint64(entry[16:], uint64(w.count))
+	binary.LittleEndian.PutUint64(entry[24:], uint64(w.metadata.Len()))
+	binary.LittleEndian.PutUint64(entry[32:], uint64(w.ids.Len()))
+	binary.LittleEndian.PutUint32(entry[40:], uint32(len(id)))
+	binary.LittleEndian.PutUint32(entry[44:], uint32(len(components)/8))
+	binary.LittleEndian.PutUint32(entry[48:], uint32(len(meta)))
+	binary.LittleEndian.PutUint32(entry[52:], rowChecksum(id, components, meta))
+	entry[56] = lsmRowFlagLive
+	if tombstone {
+		entry[56] = lsmRowFlagTombstone
+	}
+
+	if _, err := w.components.Write(components); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+	w.count += int64(len(components) / 8)
+	w.ids.WriteString(id)
+	w.metadata.Write(meta)
+	w.rows.Write(entry)
+	w.rowCount++
+	w.lastID = id
+	return nil
+}
+
+// finish writes the buffered blocks and the footer and syncs the file
+func (w *lsmSegmentWriter) finish() error {
+	idsOffset := 8 * w.count
+	metadataOffset := idsOffset + int64(w.ids.Len())
+	rowsOffset := metadataOffset + int64(w.metadata.Len())
+
+	footer := make([]byte, lsmFooterSize)
+	copy(footer, lsmSegmentMagic)
+	binary.LittleEndian.PutUint32(footer[8:], lsmSegmentVersion)
+	binary.LittleEndian.PutUint32(footer[12:], uint32(w.rowCount))
+	binary.LittleEndian.PutUint64(footer[16:], uint64(idsOffset))
+	binary.LittleEndian.PutUint64(footer[24:], uint64(metadataOffset))
+	binary.LittleEndian.PutUint64(footer[32:], uint64(rowsOffset))
+	binary.LittleEndian.PutUint32(footer[40:], crc32.Update(crc32.ChecksumIEEE(w.ids.Bytes()), crc32.IEEETable, w.rows.Bytes()))
+	binary.LittleEndian.PutUint32(footer[44:], crc32.ChecksumIEEE(footer[:44]))
+
+	for _, block := range [][]byte{w.ids.Bytes(), w.metadata.Bytes(), w.rows.Bytes(), footer} {
+		if _, err := w.components.Write(block); err != nil {
+			return fmt.Errorf("failed to write segment: %w", err)
+		}
+	}
+	if err := w.components.Flush(); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync segment: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close segment: %w", err)
+	}
+	return nil
+}
+
+// abort closes and removes a segment file that was not finished
+func (w *lsmSegmentWriter) abort() {
+	_ = w.file.Close()
+	_ = os.Remove(w.path)
+}
+
+// encodeComponents serializes an embedding as little-endian float64 components
+func encodeComponents(embedding []float64) []byte {
+	components := make([]byte, 8*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint64(components[8*i:], math.Float64bits(value))
+	}
+	return components
+}
+
+// rowChecksum returns the checksum of the ID, components and metadata of a row
+func rowChecksum(id string, components, meta []byte) uint32 {
+	checksum := crc32.ChecksumIEEE([]byte(id))
+	checksum = crc32.Update(checksum, crc32.IEEETable, components)
+	return crc32.Update(checksum, crc32.IEEETable, meta)
+}
+
+// encodeUpdated returns the UpdatedAt of a row in Unix nanoseconds, zero when unset
+func encodeUpdated(updated time.Time) int64 {
+	if updated.IsZero() {
+		return 0
+	}
+	return updated.UnixNano()
+}
+
+// decodeUpdated returns the UpdatedAt of a row from Unix nanoseconds
+func decodeUpdated(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
@@ -0,0 +1,368 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func newTestLSMStorage(t *testing.T, dataPath string, memtableSize int64, mergeThreshold int) *LSMStorage {
+	t.Helper()
+
+	config := StorageConfig{
+		Type:           StorageTypeLSM,
+		DataPath:       dataPath,
+		MaxFileSize:    1024 * 1024, // 1MB
+		BatchSize:      100,
+		MemtableSize:   memtableSize,
+		MergeThreshold: mergeThreshold,
+	}
+
+	factory := &DefaultStorageFactory{}
+	engine, err := factory.CreateStorage(config)
+	if err != nil {
+		t.Fatalf("Failed to create LSM storage: %v", err)
+	}
+
+	return engine.(*LSMStorage)
+}
+
+// scanIDs returns the IDs of the vectors of a scan and checks that they come in order
+func scanIDs(t *testing.T, engine Scanner) []string {
+	t.Helper()
+
+	var ids []string
+	err := engine.Scan(context.Background(), func(vector *core.Vector) error {
+		if len(ids) > 0 && vector.ID <= ids[len(ids)-1] {
+			t.Errorf("Scan returned %s after %s", vector.ID, ids[len(ids)-1])
+		}
+		ids = append(ids, vector.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	return ids
+}
+
+func TestLSMStorage_FlushAndMerge(t *testing.T) {
+	dataPath := filepath.Join(t.TempDir(), "lsm")
+	lsm := newTestLSMStorage(t, dataPath, 4096, 3)
+
+	// Small memtables flush and merge in the background while writes go on
+	for i := 0; i < 10; i++ {
+		if err := lsm.Write(mmapTestVectors("v", 100, float64(i))); err != nil {
+			t.Fatalf("Failed to write vectors: %v", err)
+		}
+	}
+	if err := lsm.Delete([]string{"v0010", "v0020", "missing"}); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+
+	check := func(lsm *LSMStorage) {
+		t.Helper()
+
+		vectors, err := lsm.Read([]string{"v0000", "v0010", "v0099"})
+		if err != nil {
+			t.Fatalf("Failed to read vectors: %v", err)
+		}
+		if len(vectors) != 2 || vectors[0].Embedding[0] != 9 || vectors[1].Metadata["index"] != float64(99) {
+			t.Errorf("Unexpected vectors: %+v", vectors)
+		}
+		if ids := scanIDs(t, lsm); len(ids) != 98 {
+			t.Errorf("Expected to scan 98 vectors, got %d", len(ids))
+		}
+		if stats := lsm.GetStats(); stats.TotalVectors != 98 {
+			t.Errorf("Expected 98 vectors, got %d", stats.TotalVectors)
+		}
+	}
+	check(lsm)
+
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	stats := lsm.GetStats()
+	if stats.FileCount != 2 || stats.Fragmentation != 0 {
+		t.Errorf("Expected one segment and one log without garbage, got %+v", stats)
+	}
+	check(lsm)
+
+	// Writes after the compaction are flushed when the store is closed
+	if err := lsm.Delete([]string{"v0030"}); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if err := lsm.Write(mmapTestVectors("w", 1, 1)); err != nil {
+		t.Fatalf("Failed to write vector: %v", err)
+	}
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	lsm = newTestLSMStorage(t, dataPath, 4096, 3)
+	defer func() {
+		if err := lsm.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+	check(lsm)
+	if vectors, err := lsm.Read([]string{"v0030", "w0000"}); err != nil || len(vectors) != 1 || vectors[0].ID != "w0000" {
+		t.Errorf("Expected only w0000 after the reopen, got %+v (%v)", vectors, err)
+	}
+}
+
+func TestLSMStorage_Recovery(t *testing.T) {
+	dataPath := filepath.Join(t.TempDir(), "lsm")
+	lsm := newTestLSMStorage(t, dataPath, 0, 0)
+	if err := lsm.Write(mmapTestVectors("v", 5, 1)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	if err := lsm.flush(true); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := lsm.Write(mmapTestVectors("v", 2, 2)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	walPath := lsm.active.walPaths[0]
+
+	// Crash without flushing, leaving a torn append in the log and the output
+	// of an interrupted merge behind
+	if err := lsm.closeFiles(); err != nil {
+		t.Fatalf("Failed to close files: %v", err)
+	}
+	wal, err := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	if _, err := wal.Write([]byte{200, 0, 0, 0, 1, 2, 3}); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatalf("Failed to close log: %v", err)
+	}
+	orphan := filepath.Join(dataPath, "000099.seg")
+	if err := os.WriteFile(orphan, []byte("partial"), 0600); err != nil {
+		t.Fatalf("Failed to write orphan: %v", err)
+	}
+
+	lsm = newTestLSMStorage(t, dataPath, 0, 0)
+	defer func() {
+		if err := lsm.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+	vectors, err := lsm.Read([]string{"v0000", "v0001", "v0004"})
+	if err != nil || len(vectors) != 3 || vectors[0].Embedding[0] != 2 || vectors[2].Embedding[0] != 1 {
+		t.Fatalf("Expected the logged writes over the flushed ones, got %+v (%v)", vectors, err)
+	}
+	if _, err := os.Stat(orphan); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the orphaned segment to be removed, got %v", err)
+	}
+	if lsm.nextID <= 99 {
+		t.Errorf("Expected segment IDs to move past the orphan, got %d", lsm.nextID)
+	}
+	if stats := lsm.GetStats(); stats.TotalVectors != 5 {
+		t.Errorf("Expected 5 vectors, got %d", stats.TotalVectors)
+	}
+
+	// A clean close leaves every write in a segment
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+	if logs, _ := filepath.Glob(filepath.Join(dataPath, "*.wal")); len(logs) != 0 {
+		t.Errorf("Expected no write-ahead logs after a clean close, got %v", logs)
+	}
+	lsm = newTestLSMStorage(t, dataPath, 0, 0)
+	if vectors, err := lsm.Read([]string{"v0000"}); err != nil || len(vectors) != 1 || vectors[0].Embedding[0] != 2 {
+		t.Errorf("Expected the flushed write after the reopen, got %+v (%v)", vectors, err)
+	}
+}
+
+func TestLSMStorage_ScanDuringWrites(t *testing.T) {
+	lsm := newTestLSMStorage(t, filepath.Join(t.TempDir(), "lsm"), 2048, 2)
+	defer func() {
+		if err := lsm.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	if err := lsm.Write(mmapTestVectors("v", 50, 1)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	if err := lsm.flush(true); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	// Writes, flushes and merges made during a scan neither wait for it nor show up in it
+	scanned := 0
+	err := lsm.Scan(context.Background(), func(vector *core.Vector) error {
+		scanned++
+		if scanned == 10 {
+			if err := lsm.Write(mmapTestVectors("w", 50, 2)); err != nil {
+				return err
+			}
+			if err := lsm.Delete([]string{"v0049"}); err != nil {
+				return err
+			}
+			return lsm.Compact()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if scanned != 50 {
+		t.Errorf("Expected to scan the 50 vectors stored when the scan started, got %d", scanned)
+	}
+
+	if ids := scanIDs(t, lsm); len(ids) != 99 {
+		t.Errorf("Expected to scan 99 vectors after the writes, got %d", len(ids))
+	}
+	if stats := lsm.GetStats(); stats.FileCount != 2 {
+		t.Errorf("Expected the segments of the first scan to be removed, got %d files", stats.FileCount)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(lsm.dir, "*.seg")); len(matches) != 1 {
+		t.Errorf("Expected 1 segment file, got %v", matches)
+	}
+}
+
+func TestLSMStorage_InvalidConfig(t *testing.T) {
+	factory := &DefaultStorageFactory{}
+	for _, config := range []StorageConfig{
+		{MemtableSize: -1},
+		{MergeThreshold: 1},
+	} {
+		config.Type = StorageTypeLSM
+		config.DataPath = "/tmp/test"
+		config.MaxFileSize = 1024
+		config.BatchSize = 100
+		if err := factory.ValidateConfig(config); !errors.Is(err, ErrInvalidLSMConfig) {
+			t.Errorf("Expected ErrInvalidLSMConfig for %+v, got %v", config, err)
+		}
+	}
+}
+
+func TestLSMStorage_ObjectStore(t *testing.T) {
+	fake, objects := newFakeS3(t)
+	objects.Prefix = "collections/docs"
+	dataPath := filepath.Join(t.TempDir(), "lsm")
+	open := func(objects *ObjectStoreConfig) (*LSMStorage, error) {
+		factory := &DefaultStorageFactory{}
+		engine, err := factory.CreateStorage(StorageConfig{
+			Type:        StorageTypeLSM,
+			DataPath:    dataPath,
+			MaxFileSize: 1024 * 1024,
+			BatchSize:   100,
+			ObjectStore: objects,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return engine.(*LSMStorage), nil
+	}
+	lsm, err := open(&objects)
+	if err != nil {
+		t.Fatalf("Failed to create LSM storage: %v", err)
+	}
+
+	// Merged segments move to the object store; flushed ones stay local
+	if err := lsm.Write(mmapTestVectors("v", 50, 1)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	if err := lsm.flush(true); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := lsm.Write(mmapTestVectors("v", 10, 1)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if err := lsm.Write(mmapTestVectors("w", 5, 2)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	if err := lsm.flush(true); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	keys := fake.keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "collections/docs/") || !strings.HasSuffix(keys[0], ".seg") {
+		t.Fatalf("Expected one offloaded segment, got %v", keys)
+	}
+	if local, _ := filepath.Glob(filepath.Join(dataPath, "*.seg")); len(local) != 1 {
+		t.Errorf("Expected only the flushed segment on local disk, got %v", local)
+	}
+
+	check := func(lsm *LSMStorage) {
+		t.Helper()
+		vectors, err := lsm.Read([]string{"v0007", "w0001"})
+		if err != nil || len(vectors) != 2 || vectors[0].Metadata["index"] != float64(7) || vectors[1].Embedding[0] != 2 {
+			t.Fatalf("Unexpected vectors %+v (%v)", vectors, err)
+		}
+		if ids := scanIDs(t, lsm); len(ids) != 55 {
+			t.Errorf("Expected to scan 55 vectors, got %d", len(ids))
+		}
+		report, err := lsm.Scrub(context.Background(), ScrubOptions{})
+		if err != nil || report.Checked != 55 || len(report.Corrupt) != 0 {
+			t.Errorf("Unexpected scrub report %+v (%v)", report, err)
+		}
+	}
+	check(lsm)
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	// The offloaded segment is read back through the object store after a
+	// restart, and cannot be opened without it
+	if _, err := open(nil); err == nil || !strings.Contains(err.Error(), "object store") {
+		t.Errorf("Expected opening without the object store to fail, got %v", err)
+	}
+	gets := fake.count("get")
+	lsm, err = open(&objects)
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM storage: %v", err)
+	}
+	check(lsm)
+	if fake.count("get") != gets {
+		t.Errorf("Expected reads to be served by the local cache, got %d range reads", fake.count("get")-gets)
+	}
+
+	// A merge replaces the offloaded segment, and orphans of interrupted
+	// merges are removed when the store is opened
+	if err := lsm.Delete([]string{"v0000"}); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if err := lsm.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if err := lsm.objects.Put(context.Background(), "000900.seg", strings.NewReader("partial")); err != nil {
+		t.Fatalf("Failed to put orphan: %v", err)
+	}
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+	lsm, err = open(&objects)
+	if err != nil {
+		t.Fatalf("Failed to reopen LSM storage: %v", err)
+	}
+	defer func() {
+		if err := lsm.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+	if keys := fake.keys(); len(keys) != 1 || keys[0] == "collections/docs/000900.seg" {
+		t.Errorf("Expected only the merged segment to be left, got %v", keys)
+	}
+	if local, _ := filepath.Glob(filepath.Join(dataPath, "*.seg")); len(local) != 0 {
+		t.Errorf("Expected no local segments, got %v", local)
+	}
+	if lsm.nextID <= 900 {
+		t.Errorf("Expected segment IDs to move past the orphan, got %d", lsm.nextID)
+	}
+	if stats := lsm.GetStats(); stats.TotalVectors != 54 {
+		t.Errorf("Expected 54 vectors, got %d", stats.TotalVectors)
+	}
+}
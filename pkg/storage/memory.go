@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,9 +11,11 @@ import (
 
 // MemoryStorage provides in-memory vector storage
 type MemoryStorage struct {
-	config  StorageConfig
-	vectors map[string]*core.Vector
-	mutex   sync.RWMutex
+	config   StorageConfig
+	vectors  map[string]*core.Vector
+	history  map[string][]*core.Vector // previous versions, oldest first
+	versions versionPolicy
+	mutex    sync.RWMutex
 
 	// Statistics
 	stats     StorageStats
@@ -24,6 +27,8 @@ func NewMemoryStorage(config StorageConfig) (StorageEngine, error) {
 	storage := &MemoryStorage{
 		config:    config,
 		vectors:   make(map[string]*core.Vector),
+		history:   make(map[string][]*core.Vector),
+		versions:  newVersionPolicy(config),
 		startTime: time.Now(),
 	}
 
@@ -43,7 +48,16 @@ func (m *MemoryStorage) WriteWithContext(_ context.Context, vectors []*core.Vect
 	start := time.Now()
 
 	for _, vector := range vectors {
+		if vector.Deleted {
+			m.remove(vector.ID, vector.UpdatedAt, start)
+			continue
+		}
+		current, exists := m.vectors[vector.ID]
+		if exists && m.versions.enabled() && !current.UpdatedAt.Equal(vector.UpdatedAt) {
+			m.addVersion(current)
+		}
 		m.vectors[vector.ID] = vector
+		m.pruneVersions(vector.ID, start)
 	}
 
 	// Update statistics
@@ -83,7 +97,8 @@ func (m *MemoryStorage) Delete(ids []string) error {
 	return m.DeleteWithContext(context.Background(), ids)
 }
 
-// DeleteWithContext removes vectors with context support
+// DeleteWithContext removes vectors with context support. With version
+// history enabled, a deleted vector and a tombstone join its previous versions.
 func (m *MemoryStorage) DeleteWithContext(_ context.Context, ids []string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -91,7 +106,7 @@ func (m *MemoryStorage) DeleteWithContext(_ context.Context, ids []string) error
 	start := time.Now()
 
 	for _, id := range ids {
-		delete(m.vectors, id)
+		m.remove(id, start, start)
 	}
 
 	// Update statistics
@@ -101,9 +116,151 @@ func (m *MemoryStorage) DeleteWithContext(_ context.Context, ids []string) error
 	return nil
 }
 
-// Compact performs storage optimization and cleanup
+// ReadAsOf retrieves the versions of vectors that were current at the given time
+func (m *MemoryStorage) ReadAsOf(ids []string, asOf time.Time) ([]*core.Vector, error) {
+	return m.ReadAsOfWithContext(context.Background(), ids, asOf)
+}
+
+// ReadAsOfWithContext retrieves past versions of vectors with context support
+func (m *MemoryStorage) ReadAsOfWithContext(_ context.Context, ids []string, asOf time.Time) ([]*core.Vector, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	vectors := make([]*core.Vector, 0, len(ids))
+	for _, id := range ids {
+		if vector := VersionAsOf(m.versionsOf(id), asOf); vector != nil {
+			vectors = append(vectors, vector)
+		}
+	}
+
+	return vectors, nil
+}
+
+// remove deletes a stored vector as of deleted, keeping it in its history
+// before a tombstone when versions are kept; the caller must hold the write lock
+func (m *MemoryStorage) remove(id string, deleted, now time.Time) {
+	current, exists := m.vectors[id]
+	if !exists {
+		return
+	}
+	delete(m.vectors, id)
+	if !m.versions.enabled() {
+		delete(m.history, id)
+		return
+	}
+	m.addVersion(current)
+	m.addVersion(tombstone(id, current.UpdatedAt, deleted))
+	m.pruneVersions(id, now)
+}
+
+// versionsOf returns the previous versions of a vector followed by the current
+// one, if it is stored; the caller must hold the lock
+func (m *MemoryStorage) versionsOf(id string) []*core.Vector {
+	versions := append([]*core.Vector(nil), m.history[id]...)
+	if current, exists := m.vectors[id]; exists {
+		versions = append(versions, current)
+	}
+	return versions
+}
+
+// Compact prunes previous versions that fell outside the retention period
 func (m *MemoryStorage) Compact() error {
-	// Memory storage doesn't need compaction
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	for id := range m.history {
+		m.pruneVersions(id, now)
+	}
+	return nil
+}
+
+// addVersion adds a replaced vector to its history; the caller must hold the write lock
+func (m *MemoryStorage) addVersion(vector *core.Vector) {
+	history := m.history[vector.ID]
+	i := sort.Search(len(history), func(i int) bool {
+		return history[i].UpdatedAt.After(vector.UpdatedAt)
+	})
+	history = append(history, nil)
+	copy(history[i+1:], history[i:])
+	history[i] = vector
+	m.history[vector.ID] = history
+}
+
+// pruneVersions drops the previous versions of a vector that fall outside the
+// version policy, and the whole history of a deleted vector once it is
+// forgotten; the caller must hold the write lock
+func (m *MemoryStorage) pruneVersions(id string, now time.Time) {
+	history := m.history[id]
+	if len(history) == 0 {
+		return
+	}
+
+	versions := m.versionsOf(id)
+	updated := make([]time.Time, len(versions))
+	for i, version := range versions {
+		updated[i] = version.UpdatedAt
+	}
+
+	drop := m.versions.prunable(updated, now)
+	if _, exists := m.vectors[id]; !exists && m.versions.forgotten(updated[len(updated)-1], now) {
+		drop = len(history)
+	}
+	if drop == len(history) {
+		delete(m.history, id)
+		return
+	}
+	m.history[id] = history[drop:]
+}
+
+// Scan calls fn for every stored vector in ID order
+func (m *MemoryStorage) Scan(ctx context.Context, fn func(*core.Vector) error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ids := make([]string, 0, len(m.vectors))
+	for id := range m.vectors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(m.vectors[id]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ScanVersions calls fn with the versions of every stored or deleted vector in ID order
+func (m *MemoryStorage) ScanVersions(ctx context.Context, fn func([]*core.Vector) error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ids := make([]string, 0, len(m.vectors)+len(m.history))
+	for id := range m.vectors {
+		ids = append(ids, id)
+	}
+	for id := range m.history {
+		if _, exists := m.vectors[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(m.versionsOf(id)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -113,6 +270,9 @@ func (m *MemoryStorage) GetStats() StorageStats {
 	defer m.mutex.RUnlock()
 
 	stats := m.stats
+	for _, history := range m.history {
+		stats.VersionCount += int64(len(history))
+	}
 	stats.StorageSize = int64(len(m.vectors) * 1024) // Rough estimate
 	stats.MemoryUsage = int64(len(m.vectors) * 1024) // Rough estimate
 	stats.FileCount = 0                              // Memory storage has no files
@@ -127,6 +287,7 @@ func (m *MemoryStorage) Close() error {
 
 	// Clear data structures
 	m.vectors = nil
+	m.history = nil
 
 	return nil
 }
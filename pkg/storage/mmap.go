@@ -2,41 +2,238 @@ package storage
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vijaynallagatla/vjvector/pkg/core"
 )
 
-// MMapStorage provides memory-mapped file storage for vectors
+const (
+	mmapManifestVersion = 1
+
+	// compactionChunkSize is the number of records copied per read lock during compaction
+	compactionChunkSize = 1024
+
+	// quarantineFileSuffix is appended to DataPath to name the file to which
+	// Scrub copies corrupt records, one JSON document per line
+	quarantineFileSuffix = ".quarantine"
+)
+
+// mmapManifest is the on-disk list of the segments that make up an mmap store
+type mmapManifest struct {
+	Version       int      `json:"version"`
+	Segments      []uint64 `json:"segments"`
+	NextSegmentID uint64   `json:"next_segment_id"`
+}
+
+// mmapLocation is the position of a live record
+type mmapLocation struct {
+	segment  *mmapSegment
+	offset   int64
+	size     int64
+	sequence uint64
+	updated  time.Time // UpdatedAt of the vector; only tracked with version history enabled
+	deleted  bool      // the record is the tombstone of a deleted vector
+}
+
+// SegmentStats describes the space usage of a single mmap segment
+type SegmentStats struct {
+	ID            uint64  `json:"id"`
+	Size          int64   `json:"size_bytes"`
+	LiveBytes     int64   `json:"live_bytes"`
+	DeadBytes     int64   `json:"dead_bytes"`
+	Fragmentation float64 `json:"fragmentation_percent"`
+}
+
+// MMapStorage provides memory-mapped file storage for vectors.
+// Records are appended to segment files named after DataPath; deleted and
+// overwritten records stay in place as dead bytes until compaction rewrites
+// the live records of a segment into a new one.
 type MMapStorage struct {
 	config   StorageConfig
-	filePath string
-	mmapFile *MMapFile // Real memory-mapped file
+	basePath string
+	segments []*mmapSegment // ordered by ID; the last one receives appends
+	index    map[string]mmapLocation
+	history  map[string][]mmapLocation // previous versions, oldest first
+	versions versionPolicy
+	nextID   uint64
+	sequence uint64
 	mutex    sync.RWMutex
 
-	// Statistics
-	stats     StorageStats
-	startTime time.Time
+	// Compaction runs one at a time, in the background when triggered by the threshold
+	compactMutex sync.Mutex
+	compacting   atomic.Bool
+	closing      atomic.Bool
+	background   sync.WaitGroup
+
+	// Statistics; reads run concurrently, so their timing is kept outside stats
+	stats       StorageStats
+	avgReadTime atomic.Uint64 // float64 bits
+	startTime   time.Time
 }
 
+// segmentFilePattern matches the file name suffix of a segment
+var segmentFilePattern = regexp.MustCompile(`^\.(\d{6,})$`)
+
 // NewMMapStorage creates a new memory-mapped file storage engine
 func NewMMapStorage(config StorageConfig) (StorageEngine, error) {
-	// Create real memory-mapped file
-	mmapFile, err := NewMMapFile(config.DataPath, config.PageSize, config.Compression)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create mmap file: %w", err)
+	if err := os.MkdirAll(filepath.Dir(config.DataPath), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	storage := &MMapStorage{
+	m := &MMapStorage{
 		config:    config,
-		filePath:  config.DataPath,
-		mmapFile:  mmapFile,
+		basePath:  config.DataPath,
+		index:     make(map[string]mmapLocation),
+		history:   make(map[string][]mmapLocation),
+		versions:  newVersionPolicy(config),
+		nextID:    1,
 		startTime: time.Now(),
 	}
 
-	return storage, nil
+	if err := m.open(); err != nil {
+		if closeErr := m.closeSegments(); closeErr != nil {
+			return nil, fmt.Errorf("failed to open mmap storage and close: %w, close error: %v", err, closeErr)
+		}
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// open loads the manifest, maps every segment and rebuilds the offset index
+func (m *MMapStorage) open() error {
+	manifest, err := m.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	if manifest == nil {
+		// A new store starts with a single empty segment
+		if _, err := m.addSegment(); err != nil {
+			return err
+		}
+		return m.removeOrphans()
+	}
+
+	m.nextID = manifest.NextSegmentID
+	for _, id := range manifest.Segments {
+		segment, err := openSegment(m.segmentPath(id), id, m.segmentCapacity())
+		if err != nil {
+			return err
+		}
+		m.segments = append(m.segments, segment)
+		if err := m.loadSegment(segment); err != nil {
+			return err
+		}
+	}
+
+	// The newest record of a deleted vector is its tombstone
+	for id, location := range m.index {
+		if location.deleted {
+			delete(m.index, id)
+			m.addVersion(id, location)
+		}
+	}
+	now := time.Now()
+	for id := range m.history {
+		m.pruneVersions(id, now)
+	}
+
+	if len(m.segments) == 0 {
+		if _, err := m.addSegment(); err != nil {
+			return err
+		}
+	}
+
+	return m.removeOrphans()
+}
+
+// loadSegment adds the records of a segment to the index.
+// When a vector has several live records, the one with the highest sequence wins;
+// with version history enabled the others become its previous versions.
+func (m *MMapStorage) loadSegment(segment *mmapSegment) error {
+	var err error
+	segment.scan(func(record segmentRecord) {
+		if record.sequence > m.sequence {
+			m.sequence = record.sequence
+		}
+		if !record.valid || !record.live || err != nil {
+			segment.dead += record.size
+			return
+		}
+
+		location := mmapLocation{segment: segment, offset: record.offset, size: record.size, sequence: record.sequence}
+		// Tombstones have no components, so only such records need to be read
+		// to find them without version history
+		if m.versions.enabled() || binary.LittleEndian.Uint32(segment.data[record.offset+16:]) == 0 {
+			vector, readErr := segment.read(record.id, record.offset, record.size)
+			if readErr != nil {
+				err = fmt.Errorf("failed to load vector %s: %w", record.id, readErr)
+				return
+			}
+			location.updated = vector.UpdatedAt
+			location.deleted = vector.Deleted
+		}
+		segment.live += record.size
+
+		existing, exists := m.index[record.id]
+		if !exists {
+			m.index[record.id] = location
+			return
+		}
+		if existing.sequence > record.sequence {
+			existing, location = location, existing
+		}
+		m.index[record.id] = location
+		if m.versions.enabled() && !existing.updated.Equal(location.updated) {
+			m.addVersion(record.id, existing)
+			return
+		}
+		existing.segment.live -= existing.size
+		existing.segment.dead += existing.size
+	})
+	return err
+}
+
+// removeOrphans deletes segment files that are not part of the manifest,
+// such as the output of a compaction interrupted before its swap
+func (m *MMapStorage) removeOrphans() error {
+	known := make(map[uint64]bool, len(m.segments))
+	for _, segment := range m.segments {
+		known[segment.id] = true
+	}
+
+	matches, err := filepath.Glob(m.basePath + ".*")
+	if err != nil {
+		return fmt.Errorf("failed to list segments: %w", err)
+	}
+	for _, match := range matches {
+		suffix := segmentFilePattern.FindStringSubmatch(match[len(m.basePath):])
+		if suffix == nil {
+			continue
+		}
+		id, err := strconv.ParseUint(suffix[1], 10, 64)
+		if err != nil || known[id] {
+			continue
+		}
+		if err := os.Remove(match); err != nil {
+			return fmt.Errorf("failed to remove orphaned segment: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // Write stores multiple vectors to memory-mapped file storage
@@ -44,28 +241,91 @@ func (m *MMapStorage) Write(vectors []*core.Vector) error {
 	return m.WriteWithContext(context.Background(), vectors)
 }
 
-// WriteWithContext stores multiple vectors with context support
+// WriteWithContext stores multiple vectors with context support.
+// Writing an existing ID appends a new record and marks the old one as dead,
+// unless version history is enabled and the old record is a previous version.
 func (m *MMapStorage) WriteWithContext(_ context.Context, vectors []*core.Vector) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	start := time.Now()
 
-	// Write vectors to memory-mapped file
 	for _, vector := range vectors {
-		if err := m.mmapFile.Write(vector); err != nil {
-			return fmt.Errorf("failed to write vector %s: %w", vector.ID, err)
+		if vector.Deleted {
+			if err := m.remove(vector.ID, vector.UpdatedAt, start); err != nil {
+				return err
+			}
+			continue
+		}
+
+		location, err := m.appendRecord(vector)
+		if err != nil {
+			return err
+		}
+
+		existing, exists := m.index[vector.ID]
+		m.index[vector.ID] = location
+		if exists {
+			if m.versions.enabled() && !existing.updated.Equal(vector.UpdatedAt) {
+				m.addVersion(vector.ID, existing)
+			} else {
+				existing.segment.markDeleted(existing.offset, existing.size)
+			}
+		}
+		m.pruneVersions(vector.ID, start)
+	}
+
+	if m.config.SyncOnWrite {
+		if err := m.syncSegments(); err != nil {
+			return err
 		}
 	}
 
 	// Update statistics
-	stats := m.mmapFile.GetStats()
-	m.stats.TotalVectors = stats.TotalVectors
+	m.stats.TotalVectors = int64(len(m.index))
 	m.stats.AvgWriteTime = float64(time.Since(start).Microseconds()) / float64(len(vectors))
 
+	m.maybeCompact()
 	return nil
 }
 
+// appendRecord appends the record of a vector to the active segment, adding a
+// segment when it is full; the caller must hold the write lock
+func (m *MMapStorage) appendRecord(vector *core.Vector) (mmapLocation, error) {
+	m.sequence++
+	record, err := encodeRecord(vector, m.sequence)
+	if err != nil {
+		return mmapLocation{}, err
+	}
+
+	active := m.segments[len(m.segments)-1]
+	if active.size > 0 && active.size+int64(len(record)) > m.config.MaxFileSize {
+		if active, err = m.addSegment(); err != nil {
+			return mmapLocation{}, err
+		}
+	}
+
+	offset, err := active.append(record)
+	if err != nil {
+		return mmapLocation{}, fmt.Errorf("failed to write vector %s: %w", vector.ID, err)
+	}
+	size := int64(len(record))
+	active.live += size
+
+	return mmapLocation{
+		segment:  active,
+		offset:   offset,
+		size:     size,
+		sequence: m.sequence,
+		updated:  vector.UpdatedAt,
+		deleted:  vector.Deleted,
+	}, nil
+}
+
 // Read retrieves vectors by their IDs
 func (m *MMapStorage) Read(ids []string) ([]*core.Vector, error) {
 	return m.ReadWithContext(context.Background(), ids)
@@ -78,19 +338,24 @@ func (m *MMapStorage) ReadWithContext(_ context.Context, ids []string) ([]*core.
 
 	start := time.Now()
 
-	// Read vectors from memory-mapped file
+	// Read vectors from memory-mapped segments, skipping unknown IDs
 	vectors := make([]*core.Vector, 0, len(ids))
 	for _, id := range ids {
-		vector, err := m.mmapFile.Read(id)
-		if err != nil {
-			// Skip vectors that can't be read
+		location, exists := m.index[id]
+		if !exists {
 			continue
 		}
+		vector, err := location.segment.read(id, location.offset, location.size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vector %s: %w", id, err)
+		}
 		vectors = append(vectors, vector)
 	}
 
 	// Update statistics
-	m.stats.AvgReadTime = float64(time.Since(start).Microseconds()) / float64(len(ids))
+	if len(ids) > 0 {
+		m.avgReadTime.Store(math.Float64bits(float64(time.Since(start).Microseconds()) / float64(len(ids))))
+	}
 
 	return vectors, nil
 }
@@ -100,61 +365,901 @@ func (m *MMapStorage) Delete(ids []string) error {
 	return m.DeleteWithContext(context.Background(), ids)
 }
 
-// DeleteWithContext removes vectors with context support
+// DeleteWithContext removes vectors with context support.
+// Deleted records are flagged in place and reclaimed by compaction. With
+// version history enabled, a deleted record stays live as a previous version
+// and a tombstone record is appended after it.
 func (m *MMapStorage) DeleteWithContext(_ context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	start := time.Now()
 
-	// Delete vectors from memory-mapped file
 	for _, id := range ids {
-		if err := m.mmapFile.Delete(id); err != nil {
-			// Continue with other deletions even if one fails
-			continue
+		if err := m.remove(id, start, start); err != nil {
+			return err
+		}
+	}
+
+	if m.config.SyncOnWrite {
+		if err := m.syncSegments(); err != nil {
+			return err
 		}
 	}
 
 	// Update statistics
-	stats := m.mmapFile.GetStats()
-	m.stats.TotalVectors = stats.TotalVectors
+	m.stats.TotalVectors = int64(len(m.index))
 	m.stats.AvgDeleteTime = float64(time.Since(start).Microseconds()) / float64(len(ids))
 
+	m.maybeCompact()
+	return nil
+}
+
+// remove deletes a stored vector as of deleted the way DeleteWithContext
+// describes; the caller must hold the write lock
+func (m *MMapStorage) remove(id string, deleted, now time.Time) error {
+	location, exists := m.index[id]
+	if !exists {
+		return nil
+	}
+
+	if !m.versions.enabled() {
+		delete(m.index, id)
+		location.segment.markDeleted(location.offset, location.size)
+		for _, version := range m.history[id] {
+			version.segment.markDeleted(version.offset, version.size)
+		}
+		delete(m.history, id)
+		return nil
+	}
+
+	stone, err := m.appendRecord(tombstone(id, location.updated, deleted))
+	if err != nil {
+		return err
+	}
+	delete(m.index, id)
+	m.addVersion(id, location)
+	m.addVersion(id, stone)
+	m.pruneVersions(id, now)
+	return nil
+}
+
+// ReadAsOf retrieves the versions of vectors that were current at the given time
+func (m *MMapStorage) ReadAsOf(ids []string, asOf time.Time) ([]*core.Vector, error) {
+	return m.ReadAsOfWithContext(context.Background(), ids, asOf)
+}
+
+// ReadAsOfWithContext retrieves past versions of vectors with context support
+func (m *MMapStorage) ReadAsOfWithContext(_ context.Context, ids []string, asOf time.Time) ([]*core.Vector, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	vectors := make([]*core.Vector, 0, len(ids))
+	for _, id := range ids {
+		current, exists := m.index[id]
+		if !exists && len(m.history[id]) == 0 {
+			continue
+		}
+		if !m.versions.enabled() {
+			// Without history the current record is not timestamped in the index
+			if !exists {
+				continue
+			}
+			vector, err := current.segment.read(id, current.offset, current.size)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read vector %s: %w", id, err)
+			}
+			if !vector.UpdatedAt.After(asOf) {
+				vectors = append(vectors, vector)
+			}
+			continue
+		}
+
+		versions := m.versionsOf(id)
+		updated := make([]time.Time, len(versions))
+		for i, version := range versions {
+			updated[i] = version.updated
+		}
+		i := versionAsOf(updated, asOf)
+		if i < 0 || versions[i].deleted {
+			continue
+		}
+		vector, err := versions[i].segment.read(id, versions[i].offset, versions[i].size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vector %s: %w", id, err)
+		}
+		vectors = append(vectors, vector)
+	}
+
+	return vectors, nil
+}
+
+// versionsOf returns the locations of the previous versions of a vector
+// followed by the current one, if it is stored; the caller must hold the lock
+func (m *MMapStorage) versionsOf(id string) []mmapLocation {
+	versions := append([]mmapLocation(nil), m.history[id]...)
+	if current, exists := m.index[id]; exists {
+		versions = append(versions, current)
+	}
+	return versions
+}
+
+// addVersion adds a replaced record to the history of a vector; the caller must hold the write lock
+func (m *MMapStorage) addVersion(id string, location mmapLocation) {
+	history := m.history[id]
+	i := sort.Search(len(history), func(i int) bool {
+		return history[i].updated.After(location.updated)
+	})
+	history = append(history, mmapLocation{})
+	copy(history[i+1:], history[i:])
+	history[i] = location
+	m.history[id] = history
+}
+
+// pruneVersions marks the previous versions of a vector that fall outside the
+// version policy as deleted, and the whole history of a deleted vector once it
+// is forgotten; the caller must hold the write lock
+func (m *MMapStorage) pruneVersions(id string, now time.Time) {
+	history := m.history[id]
+	if len(history) == 0 {
+		return
+	}
+
+	versions := m.versionsOf(id)
+	updated := make([]time.Time, len(versions))
+	for i, version := range versions {
+		updated[i] = version.updated
+	}
+
+	drop := m.versions.prunable(updated, now)
+	if _, exists := m.index[id]; !exists && m.versions.forgotten(updated[len(updated)-1], now) {
+		drop = len(history)
+	}
+	for _, version := range history[:drop] {
+		version.segment.markDeleted(version.offset, version.size)
+	}
+	if drop == len(history) {
+		delete(m.history, id)
+		return
+	}
+	m.history[id] = history[drop:]
+}
+
+// located reports whether the index entry or a previous version of id is stored
+// at from; the caller must hold the lock
+func (m *MMapStorage) located(id string, from mmapLocation) bool {
+	if current, exists := m.index[id]; exists && current.segment == from.segment && current.offset == from.offset {
+		return true
+	}
+	for _, version := range m.history[id] {
+		if version.segment == from.segment && version.offset == from.offset {
+			return true
+		}
+	}
+	return false
+}
+
+// relocate points the index entry or previous version of id stored at from to
+// another location and reports whether it was found; the caller must hold the write lock
+func (m *MMapStorage) relocate(id string, from, to mmapLocation) bool {
+	if current, exists := m.index[id]; exists && current.segment == from.segment && current.offset == from.offset {
+		m.index[id] = to
+		return true
+	}
+	history := m.history[id]
+	for i := range history {
+		if history[i].segment == from.segment && history[i].offset == from.offset {
+			history[i] = to
+			return true
+		}
+	}
+	return false
+}
+
+// Scan calls fn for every stored vector in ID order
+func (m *MMapStorage) Scan(ctx context.Context, fn func(*core.Vector) error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ids := make([]string, 0, len(m.index))
+	for id := range m.index {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		location := m.index[id]
+		vector, err := location.segment.read(id, location.offset, location.size)
+		if err != nil {
+			return fmt.Errorf("failed to read vector %s: %w", id, err)
+		}
+		if err := fn(vector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ScanVersions calls fn with the versions of every stored or deleted vector in ID order
+func (m *MMapStorage) ScanVersions(ctx context.Context, fn func([]*core.Vector) error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ids := make([]string, 0, len(m.index)+len(m.history))
+	for id := range m.index {
+		ids = append(ids, id)
+	}
+	for id := range m.history {
+		if _, exists := m.index[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		locations := m.versionsOf(id)
+		versions := make([]*core.Vector, len(locations))
+		for i, location := range locations {
+			vector, err := location.segment.read(id, location.offset, location.size)
+			if err != nil {
+				return fmt.Errorf("failed to read vector %s: %w", id, err)
+			}
+			versions[i] = vector
+		}
+		if err := fn(versions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quarantinedRecord is a corrupt record copied to the quarantine file
+type quarantinedRecord struct {
+	ID            string    `json:"id,omitempty"`
+	Location      string    `json:"location"`
+	Reason        string    `json:"reason"`
+	Data          []byte    `json:"data"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
+
+// corruptLocation is the position of a corrupt record found by Scrub
+type corruptLocation struct {
+	segment *mmapSegment
+	offset  int64
+	size    int64
+	id      string // ID of the index entry or previous version stored there, if any
+	torn    bool
+}
+
+// Scrub verifies the checksum of every live record of every segment, including
+// previous versions and records that were left out of the index as corrupt when
+// the store was opened, and looks for a torn record after the last one. Corrupt
+// records are copied to the quarantine file and deleted when opts asks for it;
+// quarantining the current record of a vector deletes its previous versions too.
+func (m *MMapStorage) Scrub(ctx context.Context, opts ScrubOptions) (*ScrubReport, error) {
+	report, found, err := m.scrubSegments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) == 0 || !opts.quarantines() {
+		return report, nil
+	}
+	if err := m.quarantine(found, report); err != nil {
+		return nil, err
+	}
+	if opts.Repair != nil {
+		if err := repairRecords(ctx, m, opts.Repair, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// scrubSegments verifies the records of every segment and returns the report
+// together with the positions of the corrupt records it lists
+func (m *MMapStorage) scrubSegments(ctx context.Context) (*ScrubReport, []corruptLocation, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// Name the records by the index entries and previous versions pointing at them
+	type position struct {
+		segment *mmapSegment
+		offset  int64
+	}
+	owners := make(map[position]string, len(m.index))
+	for id, location := range m.index {
+		owners[position{location.segment, location.offset}] = id
+	}
+	versions := make(map[position]bool)
+	for id, history := range m.history {
+		for _, location := range history {
+			owners[position{location.segment, location.offset}] = id
+			versions[position{location.segment, location.offset}] = true
+		}
+	}
+
+	report := &ScrubReport{}
+	var found []corruptLocation
+	for _, segment := range m.segments {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		segment.walk(segment.size, func(record segmentRecord) {
+			if !record.live {
+				return
+			}
+			report.Checked++
+
+			at := position{segment, record.offset}
+			id, indexed := owners[at]
+			var corruption *CorruptionError
+			switch {
+			case !record.valid:
+				if !indexed {
+					id = recordID(segment.record(record.offset, record.size))
+				}
+				corruption = segment.corruption(id, record.offset, "checksum mismatch")
+			case indexed:
+				if _, err := segment.read(id, record.offset, record.size); err != nil {
+					errors.As(err, &corruption)
+				}
+			}
+			if corruption == nil {
+				return
+			}
+
+			// A record the index does not know is only repaired when no other record of its vector is current
+			_, current := m.index[id]
+			report.add(corruption, versions[at] || (!indexed && current))
+			found = append(found, corruptLocation{segment: segment, offset: record.offset, size: record.size, id: owners[at]})
+		})
+
+		if size := segment.tornTail(); size > 0 {
+			report.add(segment.corruption("", segment.size, "truncated record"), false)
+			found = append(found, corruptLocation{segment: segment, offset: segment.size, size: size, torn: true})
+		}
+	}
+
+	return report, found, nil
+}
+
+// quarantine copies the records at found, which match the corrupt records of
+// report, to the quarantine file and deletes them unless they changed since
+func (m *MMapStorage) quarantine(found []corruptLocation, report *ScrubReport) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	file, err := os.OpenFile(filepath.Clean(m.basePath+quarantineFileSuffix), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open quarantine file: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+
+	now := time.Now()
+	for i, location := range found {
+		if !m.stillCorrupt(location) {
+			continue
+		}
+
+		segment := location.segment
+		corrupt := &report.Corrupt[i]
+		err := encoder.Encode(quarantinedRecord{
+			ID:            corrupt.ID,
+			Location:      corrupt.Location,
+			Reason:        corrupt.Reason,
+			Data:          segment.record(location.offset, location.size),
+			QuarantinedAt: now,
+		})
+		if err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to quarantine record: %w", err)
+		}
+
+		switch {
+		case location.torn:
+			// Appends continue at the used size, so the tail only has to be cleared
+			clear(segment.data[location.offset : location.offset+location.size])
+		case location.id != "":
+			m.deleteLocation(location.id, mmapLocation{segment: segment, offset: location.offset, size: location.size})
+		default:
+			// Records left out of the index are already accounted as dead bytes
+			segment.data[location.offset+8] = recordFlagDeleted
+		}
+		corrupt.Action = ScrubActionQuarantined
+		report.Quarantined++
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync quarantine file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close quarantine file: %w", err)
+	}
+	if err := m.syncSegments(); err != nil {
+		return err
+	}
+
+	m.stats.TotalVectors = int64(len(m.index))
 	return nil
 }
 
-// Compact performs storage optimization and cleanup
+// stillCorrupt reports whether a record found by Scrub is still in place and
+// unchanged; the caller must hold the lock
+func (m *MMapStorage) stillCorrupt(location corruptLocation) bool {
+	known := false
+	for _, segment := range m.segments {
+		known = known || segment == location.segment
+	}
+	if !known {
+		// Compacted since the scrub
+		return false
+	}
+
+	segment := location.segment
+	if location.torn {
+		return segment.size == location.offset && segment.tornTail() == location.size
+	}
+	if segment.data[location.offset+8] != recordFlagLive {
+		return false
+	}
+	return location.id == "" || m.located(location.id, mmapLocation{segment: segment, offset: location.offset})
+}
+
+// deleteLocation deletes the record of id stored at location, which is either
+// its current record, together with its previous versions, or a previous
+// version; the caller must hold the write lock
+func (m *MMapStorage) deleteLocation(id string, location mmapLocation) {
+	location.segment.markDeleted(location.offset, location.size)
+
+	if current, exists := m.index[id]; exists && current.segment == location.segment && current.offset == location.offset {
+		delete(m.index, id)
+		for _, version := range m.history[id] {
+			version.segment.markDeleted(version.offset, version.size)
+		}
+		delete(m.history, id)
+		return
+	}
+
+	history := m.history[id]
+	for i := range history {
+		if history[i].segment == location.segment && history[i].offset == location.offset {
+			history = append(history[:i], history[i+1:]...)
+			break
+		}
+	}
+	if len(history) == 0 {
+		delete(m.history, id)
+		return
+	}
+	m.history[id] = history
+}
+
+// Compact rewrites the live records of every segment with dead bytes into new
+// segments and swaps them in. Reads continue while records are copied; writes
+// wait only while a chunk of records is copied and while the segments are swapped.
 func (m *MMapStorage) Compact() error {
-	// TODO: Implement mmap compaction
-	// This is a placeholder - actual implementation will be added in Week 7-8
+	m.compactMutex.Lock()
+	defer m.compactMutex.Unlock()
+
+	// Seal the segment receiving appends so that every victim is immutable
+	m.mutex.Lock()
+	if m.closing.Load() {
+		m.mutex.Unlock()
+		return ErrStorageNotInitialized
+	}
+	now := time.Now()
+	for id := range m.history {
+		m.pruneVersions(id, now)
+	}
+	if active := m.segments[len(m.segments)-1]; active.dead > 0 {
+		if _, err := m.addSegment(); err != nil {
+			m.mutex.Unlock()
+			return err
+		}
+	}
+	var victims []*mmapSegment
+	for _, segment := range m.segments[:len(m.segments)-1] {
+		if segment.dead > 0 {
+			victims = append(victims, segment)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, victim := range victims {
+		if m.closing.Load() {
+			return ErrStorageNotInitialized
+		}
+		if err := m.compactSegment(victim); err != nil {
+			return fmt.Errorf("failed to compact segment %d: %w", victim.id, err)
+		}
+	}
+
+	return nil
+}
+
+// segmentMove tracks a live record copied out of a segment being compacted
+type segmentMove struct {
+	id   string
+	from mmapLocation
+	to   mmapLocation
+}
+
+// compactSegment copies the live records of a sealed segment, including previous
+// versions, into a new segment and replaces it in the manifest and the offset index
+func (m *MMapStorage) compactSegment(victim *mmapSegment) error {
+	// Collect the live records of the victim and reserve an ID for the replacement
+	m.mutex.Lock()
+	var moves []segmentMove
+	for id, location := range m.index {
+		if location.segment == victim {
+			moves = append(moves, segmentMove{id: id, from: location})
+		}
+	}
+	for id, history := range m.history {
+		for _, location := range history {
+			if location.segment == victim {
+				moves = append(moves, segmentMove{id: id, from: location})
+			}
+		}
+	}
+	id := m.nextID
+	m.nextID++
+	m.mutex.Unlock()
+
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].from.offset < moves[j].from.offset
+	})
+
+	var replacement *mmapSegment
+	if len(moves) > 0 {
+		capacity := victim.live
+		if capacity < minSegmentCapacity {
+			capacity = minSegmentCapacity
+		}
+		segment, err := openSegment(m.segmentPath(id), id, capacity)
+		if err != nil {
+			return err
+		}
+		replacement = segment
+
+		// Copy in chunks so that writers are never blocked for long
+		for start := 0; start < len(moves); start += compactionChunkSize {
+			end := start + compactionChunkSize
+			if end > len(moves) {
+				end = len(moves)
+			}
+			if err := m.copyRecords(victim, replacement, moves[start:end]); err != nil {
+				_ = replacement.remove()
+				return err
+			}
+		}
+		if err := replacement.sync(); err != nil {
+			_ = replacement.remove()
+			return err
+		}
+	}
+
+	// Swap the replacement in for the victim
+	m.mutex.Lock()
+	swapped := make([]segmentMove, 0, len(moves))
+	for _, moved := range moves {
+		if moved.to.segment == nil {
+			continue
+		}
+		if m.relocate(moved.id, moved.from, moved.to) {
+			swapped = append(swapped, moved)
+			continue
+		}
+		// Deleted or overwritten while its record was being copied
+		replacement.markDeleted(moved.to.offset, moved.to.size)
+	}
+
+	previous := m.segments
+	segments := make([]*mmapSegment, 0, len(m.segments))
+	for _, segment := range m.segments {
+		if segment != victim {
+			segments = append(segments, segment)
+		}
+	}
+	if replacement != nil && replacement.live > 0 {
+		segments = append(segments, replacement)
+	}
+	m.segments = m.orderSegments(segments)
+
+	if err := m.saveManifest(); err != nil {
+		m.segments = previous
+		for _, moved := range swapped {
+			m.relocate(moved.id, moved.to, moved.from)
+		}
+		m.mutex.Unlock()
+		if replacement != nil {
+			_ = replacement.remove()
+		}
+		return err
+	}
+	m.mutex.Unlock()
+
+	if replacement != nil && replacement.live == 0 {
+		if err := replacement.remove(); err != nil {
+			return err
+		}
+	}
+	return victim.remove()
+}
+
+// copyRecords appends the records of moves that are still live to the replacement segment
+func (m *MMapStorage) copyRecords(victim, replacement *mmapSegment, moves []segmentMove) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for i := range moves {
+		from := moves[i].from
+		if !m.located(moves[i].id, from) {
+			continue
+		}
+
+		offset, err := replacement.append(victim.record(from.offset, from.size))
+		if err != nil {
+			return err
+		}
+		replacement.live += from.size
+		moves[i].to = from
+		moves[i].to.segment, moves[i].to.offset = replacement, offset
+	}
+
 	return nil
 }
 
+// orderSegments sorts segments by ID, keeping the segment receiving appends last
+func (m *MMapStorage) orderSegments(segments []*mmapSegment) []*mmapSegment {
+	if len(segments) < 2 {
+		return segments
+	}
+	active := m.segments[len(m.segments)-1]
+	sorted := make([]*mmapSegment, 0, len(segments))
+	for _, segment := range segments {
+		if segment != active {
+			sorted = append(sorted, segment)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].id < sorted[j].id })
+	return append(sorted, active)
+}
+
+// maybeCompact starts a background compaction when fragmentation crosses the
+// configured threshold; the caller must hold the write lock
+func (m *MMapStorage) maybeCompact() {
+	if !m.needsCompaction() || !m.compacting.CompareAndSwap(false, true) {
+		return
+	}
+
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+		defer m.compacting.Store(false)
+
+		// Keep going while writes made during a pass push fragmentation back over the threshold
+		for {
+			if err := m.Compact(); err != nil {
+				return
+			}
+			m.mutex.RLock()
+			again := m.needsCompaction()
+			m.mutex.RUnlock()
+			if !again {
+				return
+			}
+		}
+	}()
+}
+
+// needsCompaction reports whether fragmentation is over the configured threshold
+func (m *MMapStorage) needsCompaction() bool {
+	threshold := m.config.CompactionThreshold
+	if threshold <= 0 || m.closing.Load() {
+		return false
+	}
+
+	used, dead := m.usage()
+	return dead >= int64(m.config.PageSize) && fragmentation(used, dead) >= threshold
+}
+
+// usage returns the bytes used by records and the dead bytes among them
+func (m *MMapStorage) usage() (used, dead int64) {
+	for _, segment := range m.segments {
+		used += segment.size
+		dead += segment.dead
+	}
+	return used, dead
+}
+
+// fragmentation returns dead bytes as a percentage of used bytes
+func fragmentation(used, dead int64) float64 {
+	if used == 0 {
+		return 0
+	}
+	return float64(dead) * 100 / float64(used)
+}
+
+// SegmentStats returns the space usage of every segment
+func (m *MMapStorage) SegmentStats() []SegmentStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stats := make([]SegmentStats, len(m.segments))
+	for i, segment := range m.segments {
+		stats[i] = SegmentStats{
+			ID:            segment.id,
+			Size:          segment.size,
+			LiveBytes:     segment.live,
+			DeadBytes:     segment.dead,
+			Fragmentation: fragmentation(segment.size, segment.dead),
+		}
+	}
+	return stats
+}
+
 // GetStats returns storage performance and usage statistics
 func (m *MMapStorage) GetStats() StorageStats {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
+	used, dead := m.usage()
+	mapped := int64(0)
+	for _, segment := range m.segments {
+		mapped += int64(len(segment.data))
+	}
+
 	stats := m.stats
-	stats.StorageSize = m.mmapFile.GetStats().StorageSize
-	stats.MemoryUsage = m.mmapFile.GetStats().MemoryUsage
+	stats.TotalVectors = int64(len(m.index))
+	for _, history := range m.history {
+		stats.VersionCount += int64(len(history))
+	}
+	stats.AvgReadTime = math.Float64frombits(m.avgReadTime.Load())
+	stats.StorageSize = used
+	stats.MemoryUsage = mapped
+	stats.Fragmentation = fragmentation(used, dead)
 	stats.PageSize = m.config.PageSize
-	stats.FileCount = 1 // Single mmap file
+	stats.FileCount = len(m.segments)
 
 	return stats
 }
 
 // Close performs cleanup and resource management
 func (m *MMapStorage) Close() error {
+	if m.closing.Swap(true) {
+		return nil
+	}
+	m.background.Wait()
+
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	// Close the memory-mapped file
-	if m.mmapFile != nil {
-		if err := m.mmapFile.Close(); err != nil {
-			return fmt.Errorf("failed to close mmap file: %w", err)
-		}
+	if err := m.syncSegments(); err != nil {
+		return err
+	}
+	return m.closeSegments()
+}
+
+// addSegment creates a new segment that receives appends; the caller must hold the write lock
+func (m *MMapStorage) addSegment() (*mmapSegment, error) {
+	id := m.nextID
+	segment, err := openSegment(m.segmentPath(id), id, m.segmentCapacity())
+	if err != nil {
+		return nil, err
+	}
+
+	m.nextID++
+	m.segments = append(m.segments, segment)
+	if err := m.saveManifest(); err != nil {
+		m.segments = m.segments[:len(m.segments)-1]
+		_ = segment.remove()
+		return nil, err
+	}
+
+	return segment, nil
+}
+
+// segmentCapacity returns the initial file size of a new segment
+func (m *MMapStorage) segmentCapacity() int64 {
+	capacity := int64(minSegmentCapacity)
+	if m.config.MaxFileSize > 0 && m.config.MaxFileSize < capacity {
+		capacity = m.config.MaxFileSize
+	}
+	return capacity
+}
+
+// segmentPath returns the file path of a segment
+func (m *MMapStorage) segmentPath(id uint64) string {
+	return fmt.Sprintf("%s.%06d", m.basePath, id)
+}
+
+// manifestPath returns the file path of the segment manifest
+func (m *MMapStorage) manifestPath() string {
+	return m.basePath + ".manifest"
+}
+
+// loadManifest reads the segment manifest; it returns nil for a new store
+func (m *MMapStorage) loadManifest() (*mmapManifest, error) {
+	data, err := os.ReadFile(m.manifestPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment manifest: %w", err)
+	}
+
+	var manifest mmapManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse segment manifest: %w", err)
+	}
+	if manifest.Version > mmapManifestVersion {
+		return nil, fmt.Errorf("unsupported segment manifest version %d", manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+// saveManifest atomically writes the segment manifest; the caller must hold the write lock
+func (m *MMapStorage) saveManifest() error {
+	manifest := mmapManifest{
+		Version:       mmapManifestVersion,
+		Segments:      make([]uint64, len(m.segments)),
+		NextSegmentID: m.nextID,
+	}
+	for i, segment := range m.segments {
+		manifest.Segments[i] = segment.id
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode segment manifest: %w", err)
 	}
 
+	tmpPath := m.manifestPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write segment manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.manifestPath()); err != nil {
+		return fmt.Errorf("failed to replace segment manifest: %w", err)
+	}
+
+	return nil
+}
+
+// syncSegments flushes every segment to disk
+func (m *MMapStorage) syncSegments() error {
+	for _, segment := range m.segments {
+		if err := segment.sync(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// closeSegments unmaps and closes every segment and returns the first error
+func (m *MMapStorage) closeSegments() error {
+	var firstErr error
+	for _, segment := range m.segments {
+		if err := segment.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	m.segments = nil
+	return firstErr
+}
@@ -0,0 +1,385 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// Record layout of an mmap segment. Every record starts with a fixed header:
+//
+//	offset  size  field
+//	0       4     total record size including the header
+//	4       4     CRC32 of the record from byte 12 to the end
+//	8       1     flags (live or deleted)
+//	12      4     ID length
+//	16      4     dimension
+//	20      4     metadata length
+//	24      8     write sequence number
+//
+// followed by the ID, the little-endian float64 components and the JSON metadata.
+// The flags byte is outside the checksum so records can be deleted in place.
+const (
+	recordHeaderSize = 32
+
+	recordFlagLive    byte = 1
+	recordFlagDeleted byte = 2
+
+	// minSegmentCapacity is the initial size of a new segment file
+	minSegmentCapacity = 64 * 1024
+)
+
+// recordMeta holds the vector fields stored as JSON in a record
+type recordMeta struct {
+	Collection string                 `json:"collection,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Text       string                 `json:"text,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+	ExpiresAt  *time.Time             `json:"expires_at,omitempty"`
+	Version    uint64                 `json:"version,omitempty"`
+	Deleted    bool                   `json:"deleted,omitempty"`
+}
+
+// mmapSegment is a memory-mapped, append-only file of vector records
+type mmapSegment struct {
+	id   uint64
+	path string
+	file *os.File
+	data []byte // mapped region; its length is the file size
+
+	size int64 // bytes used by records
+	live int64 // bytes of live records
+	dead int64 // bytes of deleted or superseded records
+}
+
+// segmentRecord describes a record found while scanning a segment
+type segmentRecord struct {
+	offset   int64
+	size     int64
+	id       string
+	sequence uint64
+	live     bool
+	valid    bool
+}
+
+// openSegment opens or creates a segment file and maps it into memory
+func openSegment(path string, id uint64, capacity int64) (*mmapSegment, error) {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to stat segment: %w", err)
+	}
+
+	segment := &mmapSegment{id: id, path: path, file: file}
+	fileSize := info.Size()
+	if fileSize == 0 {
+		fileSize = capacity
+		if err := file.Truncate(fileSize); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to initialize segment: %w", err)
+		}
+	}
+
+	if err := segment.mmap(fileSize); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return segment, nil
+}
+
+// mmap maps size bytes of the segment file
+func (s *mmapSegment) mmap(size int64) error {
+	data, err := syscall.Mmap(int(s.file.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("failed to memory map segment: %w", err)
+	}
+	s.data = data
+	return nil
+}
+
+// scan calls fn for every record and sets the used size of the segment.
+// A record with a bad checksum is reported as invalid and skipped; scanning
+// stops at the first zero size, which marks the end of the written data.
+func (s *mmapSegment) scan(fn func(segmentRecord)) {
+	s.size = s.walk(int64(len(s.data)), fn)
+}
+
+// walk calls fn for every record before end without changing the segment and
+// returns the offset at which it stopped
+func (s *mmapSegment) walk(end int64, fn func(segmentRecord)) int64 {
+	offset := int64(0)
+	for offset+recordHeaderSize <= end {
+		size := int64(binary.LittleEndian.Uint32(s.data[offset:]))
+		if size == 0 {
+			break
+		}
+		if size < recordHeaderSize || offset+size > end {
+			// A torn append at the tail
+			break
+		}
+
+		record := segmentRecord{offset: offset, size: size}
+		header := s.data[offset : offset+size]
+		record.live = header[8] == recordFlagLive
+		if crc32.ChecksumIEEE(header[12:]) == binary.LittleEndian.Uint32(header[4:]) {
+			idLen := int64(binary.LittleEndian.Uint32(header[12:]))
+			if recordHeaderSize+idLen <= size {
+				record.valid = true
+				record.id = string(header[recordHeaderSize : recordHeaderSize+idLen])
+				record.sequence = binary.LittleEndian.Uint64(header[24:])
+			}
+		}
+
+		fn(record)
+		offset += size
+	}
+
+	return offset
+}
+
+// tornTail returns the number of bytes of a record that was only partly
+// appended at the end of the used size, if there is one
+func (s *mmapSegment) tornTail() int64 {
+	end := int64(len(s.data))
+	if s.size+4 > end {
+		return 0
+	}
+	size := int64(binary.LittleEndian.Uint32(s.data[s.size:]))
+	if size == 0 {
+		return 0
+	}
+	if s.size+size > end || size < recordHeaderSize {
+		return end - s.size
+	}
+	return size
+}
+
+// recordID returns the ID stored in the header of a record that failed its
+// checksum, or an empty string when the header does not hold one
+func recordID(record []byte) string {
+	if len(record) < recordHeaderSize {
+		return ""
+	}
+	idLen := int64(binary.LittleEndian.Uint32(record[12:]))
+	if recordHeaderSize+idLen > int64(len(record)) {
+		return ""
+	}
+	return string(record[recordHeaderSize : recordHeaderSize+idLen])
+}
+
+// corruption describes a corrupt record of the segment
+func (s *mmapSegment) corruption(id string, offset int64, reason string) *CorruptionError {
+	return &CorruptionError{ID: id, Location: fmt.Sprintf("segment %d offset %d", s.id, offset), Reason: reason}
+}
+
+// append copies a record to the end of the segment, growing the file when needed
+func (s *mmapSegment) append(record []byte) (int64, error) {
+	needed := s.size + int64(len(record))
+	if needed > int64(len(s.data)) {
+		capacity := int64(len(s.data)) * 2
+		if capacity < needed {
+			capacity = needed
+		}
+		if err := s.grow(capacity); err != nil {
+			return 0, err
+		}
+	}
+
+	offset := s.size
+	copy(s.data[offset:], record)
+	s.size = needed
+	return offset, nil
+}
+
+// grow remaps the segment with a larger file size
+func (s *mmapSegment) grow(capacity int64) error {
+	if err := syscall.Munmap(s.data); err != nil {
+		return fmt.Errorf("failed to unmap segment: %w", err)
+	}
+	s.data = nil
+	if err := s.file.Truncate(capacity); err != nil {
+		return fmt.Errorf("failed to grow segment: %w", err)
+	}
+	return s.mmap(capacity)
+}
+
+// record returns the bytes of the record at offset
+func (s *mmapSegment) record(offset, size int64) []byte {
+	return s.data[offset : offset+size]
+}
+
+// markDeleted flags the record at offset as deleted and accounts for its bytes
+func (s *mmapSegment) markDeleted(offset, size int64) {
+	s.data[offset+8] = recordFlagDeleted
+	s.live -= size
+	s.dead += size
+}
+
+// read decodes the record of vector id at offset after verifying its checksum
+func (s *mmapSegment) read(id string, offset, size int64) (*core.Vector, error) {
+	record := s.record(offset, size)
+	if crc32.ChecksumIEEE(record[12:]) != binary.LittleEndian.Uint32(record[4:]) {
+		return nil, s.corruption(id, offset, "checksum mismatch")
+	}
+	vector, err := decodeRecord(record)
+	if err != nil {
+		return nil, s.corruption(id, offset, err.Error())
+	}
+	return vector, nil
+}
+
+// sync flushes the mapped pages of the segment to disk
+func (s *mmapSegment) sync() error {
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync segment %d: %w", s.id, err)
+	}
+	return nil
+}
+
+// close unmaps the segment and trims the file to its used size
+func (s *mmapSegment) close() error {
+	var firstErr error
+	if s.data != nil {
+		if err := syscall.Munmap(s.data); err != nil {
+			firstErr = fmt.Errorf("failed to unmap segment %d: %w", s.id, err)
+		}
+		s.data = nil
+	}
+	if err := s.file.Truncate(s.size); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("failed to trim segment %d: %w", s.id, err)
+	}
+	if err := s.file.Close(); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("failed to close segment %d: %w", s.id, err)
+	}
+	return firstErr
+}
+
+// remove closes the segment and deletes its file
+func (s *mmapSegment) remove() error {
+	if s.data != nil {
+		if err := syscall.Munmap(s.data); err != nil {
+			return fmt.Errorf("failed to unmap segment %d: %w", s.id, err)
+		}
+		s.data = nil
+	}
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close segment %d: %w", s.id, err)
+	}
+	if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("failed to remove segment %d: %w", s.id, err)
+	}
+	return nil
+}
+
+// encodeMeta serializes the fields of a vector stored as JSON in a record
+func encodeMeta(vector *core.Vector) ([]byte, error) {
+	meta, err := json.Marshal(recordMeta{
+		Collection: vector.Collection,
+		Metadata:   vector.Metadata,
+		Text:       vector.Text,
+		CreatedAt:  vector.CreatedAt,
+		UpdatedAt:  vector.UpdatedAt,
+		ExpiresAt:  vector.ExpiresAt,
+		Version:    vector.Version,
+		Deleted:    vector.Deleted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata of vector %s: %w", vector.ID, err)
+	}
+	return meta, nil
+}
+
+// encodeRecord serializes a vector into a live record
+func encodeRecord(vector *core.Vector, sequence uint64) ([]byte, error) {
+	meta, err := encodeMeta(vector)
+	if err != nil {
+		return nil, err
+	}
+
+	size := recordHeaderSize + len(vector.ID) + 8*len(vector.Embedding) + len(meta)
+	if size > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: vector %s is too large", ErrWriteFailed, vector.ID)
+	}
+
+	record := make([]byte, size)
+	binary.LittleEndian.PutUint32(record[0:], uint32(size))
+	record[8] = recordFlagLive
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(vector.ID)))
+	binary.LittleEndian.PutUint32(record[16:], uint32(len(vector.Embedding)))
+	binary.LittleEndian.PutUint32(record[20:], uint32(len(meta)))
+	binary.LittleEndian.PutUint64(record[24:], sequence)
+
+	offset := recordHeaderSize
+	offset += copy(record[offset:], vector.ID)
+	for _, value := range vector.Embedding {
+		binary.LittleEndian.PutUint64(record[offset:], math.Float64bits(value))
+		offset += 8
+	}
+	copy(record[offset:], meta)
+
+	binary.LittleEndian.PutUint32(record[4:], crc32.ChecksumIEEE(record[12:]))
+	return record, nil
+}
+
+// decodeRecord deserializes a record into a vector
+func decodeRecord(record []byte) (*core.Vector, error) {
+	idLen := int(binary.LittleEndian.Uint32(record[12:]))
+	dimension := int(binary.LittleEndian.Uint32(record[16:]))
+	metaLen := int(binary.LittleEndian.Uint32(record[20:]))
+	if recordHeaderSize+idLen+8*dimension+metaLen != len(record) {
+		return nil, errors.New("malformed record")
+	}
+
+	offset := recordHeaderSize
+	id := string(record[offset : offset+idLen])
+	offset += idLen
+	return decodeVector(id, record[offset:offset+8*dimension], record[offset+8*dimension:])
+}
+
+// decodeVector deserializes a vector from its little-endian float64 components
+// and its JSON metadata
+func decodeVector(id string, components, meta []byte) (*core.Vector, error) {
+	dimension := len(components) / 8
+	embedding := make([]float64, dimension)
+	magnitude := 0.0
+	for i := range embedding {
+		embedding[i] = math.Float64frombits(binary.LittleEndian.Uint64(components[8*i:]))
+		magnitude += embedding[i] * embedding[i]
+	}
+
+	var decoded recordMeta
+	if err := json.Unmarshal(meta, &decoded); err != nil {
+		return nil, fmt.Errorf("undecodable metadata: %w", err)
+	}
+
+	return &core.Vector{
+		ID:         id,
+		Collection: decoded.Collection,
+		Embedding:  embedding,
+		Metadata:   decoded.Metadata,
+		Text:       decoded.Text,
+		CreatedAt:  decoded.CreatedAt,
+		UpdatedAt:  decoded.UpdatedAt,
+		ExpiresAt:  decoded.ExpiresAt,
+		Version:    decoded.Version,
+		Deleted:    decoded.Deleted,
+		Dimension:  dimension,
+		Magnitude:  math.Sqrt(magnitude),
+	}, nil
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func newTestMMapStorage(t *testing.T, dataPath string, threshold float64) *MMapStorage {
+	t.Helper()
+
+	config := StorageConfig{
+		Type:                StorageTypeMMap,
+		DataPath:            dataPath,
+		MaxFileSize:         1024 * 1024, // 1MB
+		PageSize:            4096,
+		BatchSize:           100,
+		CompactionThreshold: threshold,
+	}
+
+	factory := &DefaultStorageFactory{}
+	engine, err := factory.CreateStorage(config)
+	if err != nil {
+		t.Fatalf("Failed to create mmap storage: %v", err)
+	}
+
+	return engine.(*MMapStorage)
+}
+
+func mmapTestVectors(prefix string, count int, value float64) []*core.Vector {
+	vectors := make([]*core.Vector, count)
+	for i := range vectors {
+		vectors[i] = &core.Vector{
+			ID:         fmt.Sprintf("%s%04d", prefix, i),
+			Collection: "test",
+			Embedding:  []float64{value, float64(i), 3, 4, 5, 6, 7, 8},
+			Metadata:   map[string]interface{}{"index": float64(i)},
+			CreatedAt:  time.Now().UTC(),
+		}
+	}
+	return vectors
+}
+
+func TestMMapStorage_ReadWriteDelete(t *testing.T) {
+	dataPath := filepath.Join(t.TempDir(), "vectors.mmap")
+	mmap := newTestMMapStorage(t, dataPath, 0)
+
+	written := mmapTestVectors("v", 10, 1)
+	written[5].SetTTL(time.Hour)
+	if err := mmap.Write(written); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	if err := mmap.Write(mmapTestVectors("v", 2, 9)); err != nil {
+		t.Fatalf("Failed to overwrite vectors: %v", err)
+	}
+	if err := mmap.Delete([]string{"v0009", "missing"}); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+
+	vectors, err := mmap.Read([]string{"v0000", "v0005", "v0009"})
+	if err != nil {
+		t.Fatalf("Failed to read vectors: %v", err)
+	}
+	if len(vectors) != 2 {
+		t.Fatalf("Expected 2 vectors, got %d", len(vectors))
+	}
+	if vectors[0].Embedding[0] != 9 || vectors[1].Embedding[0] != 1 {
+		t.Errorf("Unexpected embeddings: %v, %v", vectors[0].Embedding, vectors[1].Embedding)
+	}
+	if vectors[1].Metadata["index"] != float64(5) || vectors[1].Collection != "test" {
+		t.Errorf("Metadata was not preserved: %+v", vectors[1])
+	}
+
+	if err := mmap.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	// Reopen and verify that overwrites and deletes persisted
+	mmap = newTestMMapStorage(t, dataPath, 0)
+	defer func() {
+		if err := mmap.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	stats := mmap.GetStats()
+	if stats.TotalVectors != 9 {
+		t.Errorf("Expected 9 vectors after reopen, got %d", stats.TotalVectors)
+	}
+	if stats.Fragmentation <= 0 {
+		t.Errorf("Expected fragmentation after reopen, got %f", stats.Fragmentation)
+	}
+
+	vectors, err = mmap.Read([]string{"v0001", "v0009"})
+	if err != nil {
+		t.Fatalf("Failed to read vectors: %v", err)
+	}
+	if len(vectors) != 1 || vectors[0].Embedding[0] != 9 {
+		t.Errorf("Unexpected vectors after reopen: %+v", vectors)
+	}
+
+	vectors, err = mmap.Read([]string{"v0005"})
+	if err != nil {
+		t.Fatalf("Failed to read vectors: %v", err)
+	}
+	if len(vectors) != 1 || vectors[0].ExpiresAt == nil {
+		t.Errorf("Expiry was not preserved: %+v", vectors)
+	}
+}
+
+func TestMMapStorage_Fragmentation(t *testing.T) {
+	mmap := newTestMMapStorage(t, filepath.Join(t.TempDir(), "vectors.mmap"), 0)
+	defer func() {
+		if err := mmap.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	vectors := mmapTestVectors("v", 100, 1)
+	if err := mmap.Write(vectors); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	if stats := mmap.GetStats(); stats.Fragmentation != 0 {
+		t.Errorf("Expected no fragmentation, got %f", stats.Fragmentation)
+	}
+
+	ids := make([]string, 0, 50)
+	for _, vector := range vectors[:50] {
+		ids = append(ids, vector.ID)
+	}
+	if err := mmap.Delete(ids); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+
+	stats := mmap.GetStats()
+	if stats.Fragmentation < 49 || stats.Fragmentation > 51 {
+		t.Errorf("Expected about 50%% fragmentation, got %f", stats.Fragmentation)
+	}
+
+	segments := mmap.SegmentStats()
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(segments))
+	}
+	if segments[0].LiveBytes+segments[0].DeadBytes != segments[0].Size {
+		t.Errorf("Segment bytes do not add up: %+v", segments[0])
+	}
+}
+
+func TestMMapStorage_Compact(t *testing.T) {
+	dataPath := filepath.Join(t.TempDir(), "vectors.mmap")
+	mmap := newTestMMapStorage(t, dataPath, 0)
+
+	vectors := mmapTestVectors("v", 200, 1)
+	if err := mmap.Write(vectors); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	ids := make([]string, 0, 150)
+	for _, vector := range vectors[:150] {
+		ids = append(ids, vector.ID)
+	}
+	if err := mmap.Delete(ids); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+
+	before := mmap.GetStats()
+	if err := mmap.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	after := mmap.GetStats()
+
+	if after.Fragmentation != 0 {
+		t.Errorf("Expected no fragmentation after compaction, got %f", after.Fragmentation)
+	}
+	if after.StorageSize >= before.StorageSize {
+		t.Errorf("Expected compaction to reclaim space: before %d, after %d", before.StorageSize, after.StorageSize)
+	}
+	if after.TotalVectors != 50 {
+		t.Errorf("Expected 50 vectors, got %d", after.TotalVectors)
+	}
+
+	if err := mmap.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	// The compacted segments must survive a reopen
+	mmap = newTestMMapStorage(t, dataPath, 0)
+	defer func() {
+		if err := mmap.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	remaining := make([]string, 0, 50)
+	for _, vector := range vectors[150:] {
+		remaining = append(remaining, vector.ID)
+	}
+	read, err := mmap.Read(remaining)
+	if err != nil {
+		t.Fatalf("Failed to read vectors: %v", err)
+	}
+	if len(read) != 50 {
+		t.Fatalf("Expected 50 vectors after reopen, got %d", len(read))
+	}
+	for i, vector := range read {
+		if vector.ID != remaining[i] || vector.Embedding[1] != float64(150+i) {
+			t.Errorf("Unexpected vector after compaction: %+v", vector)
+		}
+	}
+
+	files, err := filepath.Glob(dataPath + ".0*")
+	if err != nil {
+		t.Fatalf("Failed to list segments: %v", err)
+	}
+	if len(files) != len(mmap.SegmentStats()) {
+		t.Errorf("Expected %d segment files, found %v", len(mmap.SegmentStats()), files)
+	}
+}
+
+func TestMMapStorage_AutoCompaction(t *testing.T) {
+	mmap := newTestMMapStorage(t, filepath.Join(t.TempDir(), "vectors.mmap"), 25)
+	defer func() {
+		if err := mmap.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	// Repeatedly overwrite the same vectors to build up dead bytes
+	for round := 0; round < 5; round++ {
+		if err := mmap.Write(mmapTestVectors("v", 100, float64(round))); err != nil {
+			t.Fatalf("Failed to write vectors: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mmap.GetStats().Fragmentation >= 25 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Compaction did not run, fragmentation is %f", mmap.GetStats().Fragmentation)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	vectors, err := mmap.Read([]string{"v0042"})
+	if err != nil {
+		t.Fatalf("Failed to read vector: %v", err)
+	}
+	if len(vectors) != 1 || vectors[0].Embedding[0] != 4 {
+		t.Errorf("Expected the latest version of v0042, got %+v", vectors)
+	}
+}
+
+func TestMMapStorage_ReadsDuringCompaction(t *testing.T) {
+	mmap := newTestMMapStorage(t, filepath.Join(t.TempDir(), "vectors.mmap"), 0)
+	defer func() {
+		if err := mmap.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	vectors := mmapTestVectors("v", 3000, 1)
+	if err := mmap.Write(vectors); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	ids := make([]string, 0, 1500)
+	for i := 0; i < len(vectors); i += 2 {
+		ids = append(ids, vectors[i].ID)
+	}
+	if err := mmap.Delete(ids); err != nil {
+		t.Fatalf("Failed to delete vectors: %v", err)
+	}
+
+	stop := make(chan struct{})
+	errs := make(chan error, 4)
+	var wg sync.WaitGroup
+	for reader := 0; reader < 4; reader++ {
+		wg.Add(1)
+		go func(reader int) {
+			defer wg.Done()
+			for i := 1; ; i += 2 {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				id := vectors[(i+reader*2)%len(vectors)].ID
+				read, err := mmap.Read([]string{id})
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(read) != 1 || read[0].ID != id {
+					errs <- errors.New("vector " + id + " disappeared during compaction")
+					return
+				}
+			}
+		}(reader)
+	}
+
+	compactErr := mmap.Compact()
+	close(stop)
+	wg.Wait()
+	close(errs)
+
+	if compactErr != nil {
+		t.Fatalf("Failed to compact: %v", compactErr)
+	}
+	for err := range errs {
+		t.Error(err)
+	}
+	if stats := mmap.GetStats(); stats.TotalVectors != 1500 || stats.Fragmentation != 0 {
+		t.Errorf("Unexpected stats after compaction: %+v", stats)
+	}
+}
+
+func TestMMapStorage_InvalidCompactionThreshold(t *testing.T) {
+	factory := &DefaultStorageFactory{}
+	err := factory.ValidateConfig(StorageConfig{
+		Type:                StorageTypeMMap,
+		DataPath:            "/tmp/test",
+		MaxFileSize:         1024,
+		PageSize:            4096,
+		BatchSize:           100,
+		CompactionThreshold: 150,
+	})
+	if !errors.Is(err, ErrInvalidCompactionThreshold) {
+		t.Errorf("Expected ErrInvalidCompactionThreshold, got %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultObjectPartSize is the multipart upload part size used when the
+	// configuration leaves it at zero; S3 requires at least 5MB per part
+	defaultObjectPartSize = 8 * 1024 * 1024
+	minObjectPartSize     = 5 * 1024 * 1024
+
+	// defaultObjectCacheSize is the local disk cache size used when the
+	// configuration leaves it at zero
+	defaultObjectCacheSize = 256 * 1024 * 1024
+
+	defaultObjectRegion = "us-east-1"
+)
+
+// ObjectStore stores immutable objects, such as cold segments and backup
+// archives, under string keys
+type ObjectStore interface {
+	// Put stores the content of r under key, replacing any object with that key.
+	// The object is only visible once r is read to the end without error.
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Get returns a reader of the content of an object, which the caller must close
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// ReadRange returns length bytes of an object starting at offset, or fewer
+	// when the object ends first
+	ReadRange(ctx context.Context, key string, offset, length int64) ([]byte, error)
+
+	// Stat returns the size and modification time of an object
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+
+	// Delete removes an object; removing a missing object is not an error
+	Delete(ctx context.Context, key string) error
+
+	// List returns the objects whose keys start with prefix, in key order
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ObjectStoreConfig configures an S3-compatible object store, such as AWS S3
+// or MinIO. Objects are addressed path-style, as Endpoint/Bucket/Prefix/key.
+type ObjectStoreConfig struct {
+	Endpoint string `json:"endpoint"`
+	Region   string `json:"region,omitempty"`
+	Bucket   string `json:"bucket"`
+	Prefix   string `json:"prefix,omitempty"`
+
+	// Credentials default to the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+	// environment variables
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"-"`
+
+	// PartSize is the part size of multipart uploads; objects up to one part
+	// long are uploaded in a single request. Zero selects 8MB.
+	PartSize int64 `json:"part_size_bytes,omitempty"`
+
+	// CacheSize bounds the local disk cache of engines reading objects in
+	// ranges. Zero selects 256MB.
+	CacheSize int64 `json:"cache_size_bytes,omitempty"`
+
+	// Store, when set, is used instead of connecting to Endpoint, such as an
+	// in-process MemoryObjectStore; Prefix still applies
+	Store ObjectStore `json:"-"`
+}
+
+// ObjectStoreConfigFromEnv returns the object store configured by the
+// VJVECTOR_S3_ENDPOINT, VJVECTOR_S3_BUCKET, VJVECTOR_S3_REGION and
+// VJVECTOR_S3_PREFIX environment variables, or nil when no endpoint is set
+func ObjectStoreConfigFromEnv() *ObjectStoreConfig {
+	endpoint := os.Getenv("VJVECTOR_S3_ENDPOINT")
+	if endpoint == "" {
+		return nil
+	}
+	return &ObjectStoreConfig{
+		Endpoint: endpoint,
+		Bucket:   os.Getenv("VJVECTOR_S3_BUCKET"),
+		Region:   os.Getenv("VJVECTOR_S3_REGION"),
+		Prefix:   os.Getenv("VJVECTOR_S3_PREFIX"),
+	}
+}
+
+// validateObjectStoreConfig validates object store configuration
+func validateObjectStoreConfig(config ObjectStoreConfig) error {
+	if config.PartSize != 0 && config.PartSize < minObjectPartSize {
+		return fmt.Errorf("%w: part size must be at least %d bytes", ErrInvalidObjectStoreConfig, minObjectPartSize)
+	}
+	if config.CacheSize < 0 {
+		return fmt.Errorf("%w: negative cache size", ErrInvalidObjectStoreConfig)
+	}
+	if config.Store != nil {
+		return nil
+	}
+	if config.Bucket == "" {
+		return fmt.Errorf("%w: bucket is required", ErrInvalidObjectStoreConfig)
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("%w: endpoint must be an http or https URL", ErrInvalidObjectStoreConfig)
+	}
+	return nil
+}
+
+// OpenObjectStore returns the object store described by config
+func OpenObjectStore(config ObjectStoreConfig) (ObjectStore, error) {
+	if err := validateObjectStoreConfig(config); err != nil {
+		return nil, err
+	}
+
+	store := config.Store
+	if store == nil {
+		s3, err := NewS3ObjectStore(config)
+		if err != nil {
+			return nil, err
+		}
+		store = s3
+	}
+	return WithObjectPrefix(store, config.Prefix), nil
+}
+
+// RemoveObjects deletes every object of store whose key starts with prefix
+func RemoveObjects(ctx context.Context, store ObjectStore, prefix string) error {
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := store.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinObjectKey joins a key prefix and a key with a slash
+func joinObjectKey(prefix, key string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
+
+// prefixedObjectStore stores the objects of an object store under a key prefix
+type prefixedObjectStore struct {
+	store  ObjectStore
+	prefix string
+}
+
+// WithObjectPrefix returns a view of store that keeps its objects under
+// prefix, so that stores sharing a bucket do not see each other's objects
+func WithObjectPrefix(store ObjectStore, prefix string) ObjectStore {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return store
+	}
+	if inner, ok := store.(*prefixedObjectStore); ok {
+		return &prefixedObjectStore{store: inner.store, prefix: inner.prefix + "/" + prefix}
+	}
+	return &prefixedObjectStore{store: store, prefix: prefix}
+}
+
+// Put stores an object under the prefix
+func (p *prefixedObjectStore) Put(ctx context.Context, key string, r io.Reader) error {
+	return p.store.Put(ctx, p.prefix+"/"+key, r)
+}
+
+// Get reads an object under the prefix
+func (p *prefixedObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return p.store.Get(ctx, p.prefix+"/"+key)
+}
+
+// ReadRange reads part of an object under the prefix
+func (p *prefixedObjectStore) ReadRange(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	return p.store.ReadRange(ctx, p.prefix+"/"+key, offset, length)
+}
+
+// Stat describes an object under the prefix
+func (p *prefixedObjectStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := p.store.Stat(ctx, p.prefix+"/"+key)
+	info.Key = key
+	return info, err
+}
+
+// Delete removes an object under the prefix
+func (p *prefixedObjectStore) Delete(ctx context.Context, key string) error {
+	return p.store.Delete(ctx, p.prefix+"/"+key)
+}
+
+// List returns the objects under the prefix with keys relative to it
+func (p *prefixedObjectStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects, err := p.store.List(ctx, p.prefix+"/"+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		objects[i].Key = strings.TrimPrefix(objects[i].Key, p.prefix+"/")
+	}
+	return objects, nil
+}
+
+// MemoryObjectStore is an in-process object store, for tests and for
+// embedding without an object storage service
+type MemoryObjectStore struct {
+	mutex   sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// memoryObject is an object held by a MemoryObjectStore
+type memoryObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryObjectStore creates an empty in-process object store
+func NewMemoryObjectStore() *MemoryObjectStore {
+	return &MemoryObjectStore{objects: make(map[string]memoryObject)}
+}
+
+// Put stores the content of r under key
+func (m *MemoryObjectStore) Put(ctx context.Context, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.objects[key] = memoryObject{data: data, modTime: time.Now().UTC()}
+	return nil
+}
+
+// Get returns a reader of an object
+func (m *MemoryObjectStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	object, exists := m.objects[key]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return io.NopCloser(bytes.NewReader(object.data)), nil
+}
+
+// ReadRange returns part of an object
+func (m *MemoryObjectStore) ReadRange(_ context.Context, key string, offset, length int64) ([]byte, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	object, exists := m.objects[key]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("%w: invalid range %d+%d of %s", ErrObjectStoreRequest, offset, length, key)
+	}
+	size := int64(len(object.data))
+	if offset >= size {
+		return []byte{}, nil
+	}
+	end := offset + length
+	if end > size {
+		end = size
+	}
+	return append([]byte(nil), object.data[offset:end]...), nil
+}
+
+// Stat describes an object
+func (m *MemoryObjectStore) Stat(_ context.Context, key string) (ObjectInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	object, exists := m.objects[key]
+	if !exists {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return ObjectInfo{Key: key, Size: int64(len(object.data)), ModTime: object.modTime}, nil
+}
+
+// Delete removes an object
+func (m *MemoryObjectStore) Delete(_ context.Context, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.objects, key)
+	return nil
+}
+
+// List returns the objects whose keys start with prefix
+func (m *MemoryObjectStore) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var objects []ObjectInfo
+	for key, object := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: int64(len(object.data)), ModTime: object.modTime})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// objectReaderAt reads an object through range reads
+type objectReaderAt struct {
+	store ObjectStore
+	key   string
+}
+
+// ReadAt reads len(p) bytes of the object from offset
+func (r objectReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	data, err := r.store.ReadRange(context.Background(), r.key, offset, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close releases nothing; objects are read one request at a time
+func (r objectReaderAt) Close() error {
+	return nil
+}
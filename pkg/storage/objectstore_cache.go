@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// objectCacheBlockSize is the unit in which range reads are fetched and cached
+const objectCacheBlockSize = 1024 * 1024
+
+// CachedObjectStore serves the range reads of an object store from a local
+// disk cache. Objects are fetched and cached in aligned 1MB blocks, one file
+// per block, and the least recently used blocks are evicted once the cache
+// exceeds its capacity. Blocks survive restarts; the modification time of a
+// block file records its last use. Writes and deletes through the
+// cache drop the cached blocks of the object; objects changed by other
+// writers must use new keys, as segments and backups do.
+type CachedObjectStore struct {
+	store    ObjectStore
+	dir      string
+	capacity int64
+
+	mutex  sync.Mutex
+	blocks map[string]*list.Element // of *cachedBlock, by file name
+	lru    *list.List               // most recently used first
+	size   int64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// cachedBlock is a block file of the cache
+type cachedBlock struct {
+	name string
+	size int64
+}
+
+// ObjectCacheStats reports the usage of an object cache
+type ObjectCacheStats struct {
+	Blocks int    `json:"blocks"`
+	Size   int64  `json:"size_bytes"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// NewCachedObjectStore creates a cache of store in dir holding up to capacity
+// bytes, keeping the blocks cached there before
+func NewCachedObjectStore(store ObjectStore, dir string, capacity int64) (*CachedObjectStore, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("%w: cache capacity must be positive", ErrInvalidObjectStoreConfig)
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create object cache directory: %w", err)
+	}
+
+	c := &CachedObjectStore{
+		store:    store,
+		dir:      dir,
+		capacity: capacity,
+		blocks:   make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load indexes the blocks in the cache directory, the most recently written
+// first, and removes blocks that were not completely written
+func (c *CachedObjectStore) load() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list object cache: %w", err)
+	}
+
+	var blocks []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if filepath.Ext(entry.Name()) == ".tmp" {
+			_ = os.Remove(filepath.Join(c.dir, entry.Name()))
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		blocks = append(blocks, info)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].ModTime().After(blocks[j].ModTime()) })
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, info := range blocks {
+		c.blocks[info.Name()] = c.lru.PushBack(&cachedBlock{name: info.Name(), size: info.Size()})
+		c.size += info.Size()
+	}
+	return c.evict()
+}
+
+// Put stores an object and drops its cached blocks
+func (c *CachedObjectStore) Put(ctx context.Context, key string, r io.Reader) error {
+	err := c.store.Put(ctx, key, r)
+	c.invalidate(key)
+	return err
+}
+
+// Get reads an object from the store, bypassing the cache
+func (c *CachedObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return c.store.Get(ctx, key)
+}
+
+// ReadRange reads part of an object through the cached blocks it spans
+func (c *CachedObjectStore) ReadRange(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("%w: invalid range %d+%d of %s", ErrObjectStoreRequest, offset, length, key)
+	}
+
+	data := make([]byte, 0, length)
+	end := offset + length
+	for position := offset; position < end; {
+		index := position / objectCacheBlockSize
+		block, err := c.block(ctx, key, index)
+		if err != nil {
+			return nil, err
+		}
+
+		start := position - index*objectCacheBlockSize
+		if start >= int64(len(block)) {
+			break
+		}
+		stop := int64(len(block))
+		if stop > start+end-position {
+			stop = start + end - position
+		}
+		data = append(data, block[start:stop]...)
+		position += stop - start
+		if len(block) < objectCacheBlockSize && stop == int64(len(block)) {
+			// The object ends in this block
+			break
+		}
+	}
+	return data, nil
+}
+
+// Stat describes an object
+func (c *CachedObjectStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	return c.store.Stat(ctx, key)
+}
+
+// Delete removes an object and its cached blocks
+func (c *CachedObjectStore) Delete(ctx context.Context, key string) error {
+	err := c.store.Delete(ctx, key)
+	c.invalidate(key)
+	return err
+}
+
+// List returns the objects whose keys start with prefix
+func (c *CachedObjectStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	return c.store.List(ctx, prefix)
+}
+
+// Stats returns the usage of the cache
+func (c *CachedObjectStore) Stats() ObjectCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return ObjectCacheStats{
+		Blocks: len(c.blocks),
+		Size:   c.size,
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// block returns block index of an object, from the cache when it is there
+func (c *CachedObjectStore) block(ctx context.Context, key string, index int64) ([]byte, error) {
+	name := objectCacheName(key) + "." + strconv.FormatInt(index, 10)
+
+	c.mutex.Lock()
+	element, cached := c.blocks[name]
+	if cached {
+		c.lru.MoveToFront(element)
+	}
+	c.mutex.Unlock()
+	if cached {
+		path := filepath.Join(c.dir, name)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			now := time.Now()
+			_ = os.Chtimes(path, now, now)
+			c.hits.Add(1)
+			return data, nil
+		}
+		// Evicted since, or lost; fetch it again
+		c.forget(name)
+	}
+
+	c.misses.Add(1)
+	data, err := c.store.ReadRange(ctx, key, index*objectCacheBlockSize, objectCacheBlockSize)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	c.add(name, data)
+	return data, nil
+}
+
+// add writes a fetched block to the cache; a block that cannot be written is
+// only left out of the cache
+func (c *CachedObjectStore) add(name string, data []byte) {
+	path := filepath.Join(c.dir, name)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, exists := c.blocks[name]; exists {
+		c.size -= element.Value.(*cachedBlock).size
+		c.lru.Remove(element)
+	}
+	c.blocks[name] = c.lru.PushFront(&cachedBlock{name: name, size: int64(len(data))})
+	c.size += int64(len(data))
+	_ = c.evict()
+}
+
+// evict removes the least recently used blocks until the cache fits its
+// capacity; the caller must hold the lock
+func (c *CachedObjectStore) evict() error {
+	var firstErr error
+	for c.size > c.capacity && c.lru.Len() > 0 {
+		block := c.lru.Remove(c.lru.Back()).(*cachedBlock)
+		delete(c.blocks, block.name)
+		c.size -= block.size
+		if err := os.Remove(filepath.Join(c.dir, block.name)); err != nil && !errors.Is(err, os.ErrNotExist) && firstErr == nil {
+			firstErr = fmt.Errorf("failed to evict cached block: %w", err)
+		}
+	}
+	return firstErr
+}
+
+// forget drops a block from the index of the cache
+func (c *CachedObjectStore) forget(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.blocks[name]; exists {
+		c.size -= element.Value.(*cachedBlock).size
+		c.lru.Remove(element)
+		delete(c.blocks, name)
+	}
+}
+
+// invalidate removes the cached blocks of an object
+func (c *CachedObjectStore) invalidate(key string) {
+	prefix := objectCacheName(key) + "."
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for name, element := range c.blocks {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		c.size -= element.Value.(*cachedBlock).size
+		c.lru.Remove(element)
+		delete(c.blocks, name)
+		_ = os.Remove(filepath.Join(c.dir, name))
+	}
+}
+
+// objectCacheName returns the file name prefix of the cached blocks of an object
+func objectCacheName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:16])
+}
@@ -0,0 +1,461 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm  = "AWS4-HMAC-SHA256"
+	s3DateFormat = "20060102T150405Z"
+
+	// s3MaxErrorBody bounds the error response read from the service
+	s3MaxErrorBody = 64 * 1024
+)
+
+// S3ObjectStore is an ObjectStore backed by an S3-compatible service. Requests
+// are signed with AWS Signature Version 4 and address the bucket path-style,
+// which MinIO and AWS S3 both accept. Objects larger than one part are
+// uploaded with multipart uploads that are aborted when the upload fails.
+type S3ObjectStore struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	partSize  int64
+	client    *http.Client
+}
+
+// NewS3ObjectStore creates a client of the bucket described by config. The
+// Prefix and CacheSize of config are applied by OpenObjectStore and the engines.
+func NewS3ObjectStore(config ObjectStoreConfig) (*S3ObjectStore, error) {
+	config.Store = nil
+	if err := validateObjectStoreConfig(config); err != nil {
+		return nil, err
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidObjectStoreConfig, err)
+	}
+
+	s := &S3ObjectStore{
+		endpoint:  endpoint,
+		region:    config.Region,
+		bucket:    config.Bucket,
+		accessKey: config.AccessKeyID,
+		secretKey: config.SecretAccessKey,
+		partSize:  config.PartSize,
+		client:    &http.Client{},
+	}
+	if s.region == "" {
+		s.region = defaultObjectRegion
+	}
+	if s.accessKey == "" && s.secretKey == "" {
+		s.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		s.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if s.partSize == 0 {
+		s.partSize = defaultObjectPartSize
+	}
+	return s, nil
+}
+
+// s3Error is an error response of the service
+type s3Error struct {
+	Status  int    `xml:"-"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+	method  string
+	key     string
+}
+
+// Error describes the failed request
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s %s: status %d", e.method, e.key, e.Status)
+	}
+	return fmt.Sprintf("%s %s: status %d: %s: %s", e.method, e.key, e.Status, e.Code, e.Message)
+}
+
+// Unwrap returns ErrObjectNotFound for missing objects and ErrObjectStoreRequest otherwise
+func (e *s3Error) Unwrap() error {
+	if e.Status == http.StatusNotFound {
+		return ErrObjectNotFound
+	}
+	return ErrObjectStoreRequest
+}
+
+// Put stores the content of r, in parts when it is longer than one part
+func (s *S3ObjectStore) Put(ctx context.Context, key string, r io.Reader) error {
+	reader := bufio.NewReader(r)
+	buffer := make([]byte, s.partSize)
+	n, err := io.ReadFull(reader, buffer)
+	if err == nil {
+		// An object of exactly one part still goes in a single request
+		if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+			err = io.EOF
+		}
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		response, err := s.do(ctx, http.MethodPut, key, nil, nil, buffer[:n])
+		if err != nil {
+			return err
+		}
+		return response.Body.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+
+	uploadID, err := s.createMultipartUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := s.uploadParts(ctx, key, uploadID, reader, buffer); err != nil {
+		// The upload failed already; a failed abort leaves the parts to the
+		// lifecycle rules of the bucket
+		if response, abortErr := s.do(context.WithoutCancel(ctx), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil); abortErr == nil {
+			_ = response.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+// s3Part is a part of a completed multipart upload
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// createMultipartUpload starts a multipart upload and returns its ID
+func (s *S3ObjectStore) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	response, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(response.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("%w: POST %s: invalid multipart upload response", ErrObjectStoreRequest, key)
+	}
+	return result.UploadID, nil
+}
+
+// uploadParts uploads the full part in buffer and the rest of r as the parts
+// of a multipart upload, then completes it
+func (s *S3ObjectStore) uploadParts(ctx context.Context, key, uploadID string, r io.Reader, buffer []byte) error {
+	var parts []s3Part
+	part := buffer
+	for len(part) > 0 {
+		number := len(parts) + 1
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		response, err := s.do(ctx, http.MethodPut, key, query, nil, part)
+		if err != nil {
+			return err
+		}
+		_ = response.Body.Close()
+		parts = append(parts, s3Part{PartNumber: number, ETag: response.Header.Get("ETag")})
+
+		n, err := io.ReadFull(r, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read object %s: %w", key, err)
+		}
+		part = buffer[:n]
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("failed to encode multipart upload: %w", err)
+	}
+	header := http.Header{"Content-Type": {"application/xml"}}
+	response, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, header, body)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	// The service reports failures that happen after it started responding
+	// in the body of a successful response
+	data, err := io.ReadAll(io.LimitReader(response.Body, s3MaxErrorBody))
+	if err != nil {
+		return fmt.Errorf("%w: POST %s: %v", ErrObjectStoreRequest, key, err)
+	}
+	failure := &s3Error{Status: response.StatusCode, method: http.MethodPost, key: key}
+	var root struct{ XMLName xml.Name }
+	if xml.Unmarshal(data, &root) == nil && root.XMLName.Local == "Error" {
+		_ = xml.Unmarshal(data, failure)
+		return failure
+	}
+	return nil
+}
+
+// Get returns a reader of an object
+func (s *S3ObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	response, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// ReadRange reads part of an object with a range request
+func (s *S3ObjectStore) ReadRange(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("%w: invalid range %d+%d of %s", ErrObjectStoreRequest, offset, length, key)
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}}
+	response, err := s.do(ctx, http.MethodGet, key, nil, header, nil)
+	var failure *s3Error
+	if errors.As(err, &failure) && failure.Status == http.StatusRequestedRangeNotSatisfiable {
+		// The range starts at or after the end of the object
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	// A service ignoring the range answers with the whole object
+	body := io.Reader(response.Body)
+	if response.StatusCode == http.StatusOK {
+		if _, err := io.CopyN(io.Discard, body, offset); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("%w: GET %s: %v", ErrObjectStoreRequest, key, err)
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(body, length))
+	if err != nil {
+		return nil, fmt.Errorf("%w: GET %s: %v", ErrObjectStoreRequest, key, err)
+	}
+	return data, nil
+}
+
+// Stat describes an object
+func (s *S3ObjectStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	response, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	_ = response.Body.Close()
+
+	info := ObjectInfo{Key: key, Size: response.ContentLength}
+	if modified, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modified
+	}
+	return info, nil
+}
+
+// Delete removes an object
+func (s *S3ObjectStore) Delete(ctx context.Context, key string) error {
+	response, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// List returns the objects whose keys start with prefix, following continuation tokens
+func (s *S3ObjectStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		response, err := s.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		_ = response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid object listing: %v", ErrObjectStoreRequest, err)
+		}
+
+		for _, object := range result.Contents {
+			objects = append(objects, ObjectInfo{Key: object.Key, Size: object.Size, ModTime: object.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do sends a signed request for an object, or for the bucket when key is
+// empty, and returns the response of a successful request
+func (s *S3ObjectStore) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	path := s.endpoint.EscapedPath() + "/" + s3Escape(s.bucket, false)
+	if key != "" {
+		path += "/" + s3Escape(key, false)
+	}
+	target := s.endpoint.Scheme + "://" + s.endpoint.Host + path
+	if len(query) > 0 {
+		target += "?" + s3CanonicalQuery(query)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrObjectStoreRequest, err)
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	if len(body) == 0 {
+		// Requests without a body must not announce one
+		request.Body, request.ContentLength = http.NoBody, 0
+	}
+	payload := sha256.Sum256(body)
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	request.Header.Set("X-Amz-Date", time.Now().UTC().Format(s3DateFormat))
+	if s.accessKey != "" {
+		request.Header.Set("Authorization", s3Authorization(request, request.URL.Host, s3SignedHeaders(request.Header), s.accessKey, s.secretKey, s.region))
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %s: %v", ErrObjectStoreRequest, method, key, err)
+	}
+	if response.StatusCode < 300 {
+		return response, nil
+	}
+
+	failure := &s3Error{Status: response.StatusCode, method: method, key: key}
+	data, _ := io.ReadAll(io.LimitReader(response.Body, s3MaxErrorBody))
+	_ = response.Body.Close()
+	_ = xml.Unmarshal(data, failure)
+	return nil, failure
+}
+
+// s3SignedHeaders returns the names of the headers a request signature covers
+func s3SignedHeaders(header http.Header) []string {
+	signed := []string{"host"}
+	for name := range header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "range" || name == "content-type" || name == "content-md5" {
+			signed = append(signed, name)
+		}
+	}
+	sort.Strings(signed)
+	return signed
+}
+
+// s3Authorization returns the Authorization header of a request signed with
+// AWS Signature Version 4 over the signed headers, taking the request time from
+// its X-Amz-Date header and the payload hash from its X-Amz-Content-Sha256 header
+func s3Authorization(request *http.Request, host string, signed []string, accessKey, secretKey, region string) string {
+	amzDate := request.Header.Get("X-Amz-Date")
+	date := amzDate
+	if len(date) > 8 {
+		date = date[:8]
+	}
+
+	var canonical strings.Builder
+	canonical.WriteString(request.Method + "\n")
+	canonical.WriteString(request.URL.EscapedPath() + "\n")
+	canonical.WriteString(s3CanonicalQuery(request.URL.Query()) + "\n")
+	for _, name := range signed {
+		value := host
+		if name != "host" {
+			value = strings.Join(request.Header.Values(name), ",")
+		}
+		canonical.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	canonical.WriteString("\n" + strings.Join(signed, ";") + "\n")
+	canonical.WriteString(request.Header.Get("X-Amz-Content-Sha256"))
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("%s Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		s3Algorithm, accessKey, scope, strings.Join(signed, ";"), signature)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery encodes query parameters sorted by name, as signatures expect
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes every byte of s but unreserved characters and,
+// unless encodeSlash is set, slashes
+func s3Escape(s string, encodeSlash bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			escaped.WriteByte(c)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// CorruptionError reports a stored record that failed verification, such as a
+// checksum mismatch or a record that cannot be decoded. It matches both
+// ErrCorruptRecord and ErrReadFailed with errors.Is.
+type CorruptionError struct {
+	// ID is the ID of the vector, when it is known
+	ID string
+
+	// Location is where the record is stored, such as a key or a segment offset
+	Location string
+
+	// Reason describes what is wrong with the record
+	Reason string
+}
+
+// Error implements the error interface
+func (e *CorruptionError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("%v at %s: %s", ErrCorruptRecord, e.Location, e.Reason)
+	}
+	return fmt.Sprintf("%v of vector %s at %s: %s", ErrCorruptRecord, e.ID, e.Location, e.Reason)
+}
+
+// Is reports whether target is ErrCorruptRecord or ErrReadFailed
+func (e *CorruptionError) Is(target error) bool {
+	return target == ErrCorruptRecord || target == ErrReadFailed
+}
+
+// Actions taken by a scrub on a corrupt record
+const (
+	ScrubActionReported    = "reported"
+	ScrubActionQuarantined = "quarantined"
+	ScrubActionRepaired    = "repaired"
+)
+
+// VectorSource supplies vectors by ID. Every StorageEngine is a VectorSource,
+// so a replica or the storage of a restored backup can repair another store.
+type VectorSource interface {
+	ReadWithContext(ctx context.Context, ids []string) ([]*core.Vector, error)
+}
+
+// ScrubOptions controls what a scrub does with the corrupt records it finds
+type ScrubOptions struct {
+	// Quarantine moves corrupt records out of the store, where they are kept for
+	// inspection, so that reads no longer fail on them. A quarantined vector
+	// reads as missing.
+	Quarantine bool
+
+	// Repair, when set, supplies good copies of vectors whose current record is
+	// corrupt. Corrupt records are quarantined first; vectors the source does
+	// not have stay quarantined.
+	Repair VectorSource
+}
+
+// quarantines reports whether corrupt records are moved out of the store
+func (o ScrubOptions) quarantines() bool {
+	return o.Quarantine || o.Repair != nil
+}
+
+// CorruptRecord describes a corrupt or truncated record found by a scrub
+type CorruptRecord struct {
+	ID       string `json:"id,omitempty"`
+	Location string `json:"location"`
+	Reason   string `json:"reason"`
+	Version  bool   `json:"version,omitempty"` // a previous version rather than the current record
+	Action   string `json:"action"`
+}
+
+// ScrubReport summarizes a scrub
+type ScrubReport struct {
+	Checked     int64           `json:"checked"`
+	Corrupt     []CorruptRecord `json:"corrupt,omitempty"`
+	Quarantined int             `json:"quarantined"`
+	Repaired    int             `json:"repaired"`
+}
+
+// add records a corrupt record found by the scrub
+func (r *ScrubReport) add(err *CorruptionError, version bool) {
+	r.Corrupt = append(r.Corrupt, CorruptRecord{
+		ID:       err.ID,
+		Location: err.Location,
+		Reason:   err.Reason,
+		Version:  version,
+		Action:   ScrubActionReported,
+	})
+}
+
+// repairRecords rewrites the quarantined current records of report with the
+// copies held by source; the engine must not be locked by the caller
+func repairRecords(ctx context.Context, engine StorageEngine, source VectorSource, report *ScrubReport) error {
+	var ids []string
+	for _, record := range report.Corrupt {
+		if record.Action == ScrubActionQuarantined && !record.Version && record.ID != "" {
+			ids = append(ids, record.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	copies, err := source.ReadWithContext(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to read repair copies: %w", err)
+	}
+	if len(copies) == 0 {
+		return nil
+	}
+	if err := engine.WriteWithContext(ctx, copies); err != nil {
+		return fmt.Errorf("failed to write repair copies: %w", err)
+	}
+
+	repaired := make(map[string]bool, len(copies))
+	for _, vector := range copies {
+		repaired[vector.ID] = true
+	}
+	for i := range report.Corrupt {
+		record := &report.Corrupt[i]
+		if record.Action == ScrubActionQuarantined && !record.Version && repaired[record.ID] {
+			record.Action = ScrubActionRepaired
+			report.Repaired++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// corruptors damage the stored record of a vector in place, as bit rot would
+var corruptors = map[StorageType]func(*testing.T, StorageEngine, string){
+	StorageTypeLevelDB: func(t *testing.T, engine StorageEngine, id string) {
+		l := engine.(*LevelDBStorage)
+		key := []byte(vectorKeyPrefix + id)
+		data, err := l.db.Get(key, nil)
+		if err != nil {
+			t.Fatalf("Failed to get record %s: %v", id, err)
+		}
+		var record VectorRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			t.Fatalf("Failed to decode record %s: %v", id, err)
+		}
+		record.Data[0]++
+		if data, err = json.Marshal(record); err != nil {
+			t.Fatalf("Failed to encode record %s: %v", id, err)
+		}
+		if err := l.db.Put(key, data, nil); err != nil {
+			t.Fatalf("Failed to put record %s: %v", id, err)
+		}
+	},
+	StorageTypeMMap: func(_ *testing.T, engine StorageEngine, id string) {
+		m := engine.(*MMapStorage)
+		location := m.index[id]
+		location.segment.data[location.offset+recordHeaderSize+int64(len(id))] ^= 0xff
+	},
+	StorageTypeLSM: func(t *testing.T, engine StorageEngine, id string) {
+		l := engine.(*LSMStorage)
+		if err := l.flush(true); err != nil {
+			t.Fatalf("Failed to flush: %v", err)
+		}
+		version, _ := newest(l.lookup(id))
+		file, err := os.OpenFile(version.segment.path, os.O_RDWR, 0600)
+		if err != nil {
+			t.Fatalf("Failed to open segment: %v", err)
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				t.Errorf("Failed to close segment: %v", err)
+			}
+		}()
+		offset := 8 * version.segment.rows[version.row].component
+		data := make([]byte, 1)
+		if _, err := file.ReadAt(data, offset); err != nil {
+			t.Fatalf("Failed to read segment: %v", err)
+		}
+		data[0] ^= 0xff
+		if _, err := file.WriteAt(data, offset); err != nil {
+			t.Fatalf("Failed to write segment: %v", err)
+		}
+	},
+}
+
+func scrub(t *testing.T, engine StorageEngine, opts ScrubOptions) *ScrubReport {
+	t.Helper()
+
+	report, err := engine.(Scrubber).Scrub(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Failed to scrub: %v", err)
+	}
+	return report
+}
+
+func TestStorage_ChecksumVerification(t *testing.T) {
+	engines := versionedEngines(t, 0, 0)
+
+	for name, corrupt := range corruptors {
+		t.Run(string(name), func(t *testing.T) {
+			engine := engines[name](t)
+			defer func() {
+				if err := engine.Close(); err != nil {
+					t.Errorf("Failed to close storage: %v", err)
+				}
+			}()
+
+			if err := engine.Write(mmapTestVectors("v", 5, 1)); err != nil {
+				t.Fatalf("Failed to write vectors: %v", err)
+			}
+			corrupt(t, engine, "v0002")
+
+			_, err := engine.Read([]string{"v0001", "v0002"})
+			var corruption *CorruptionError
+			if !errors.As(err, &corruption) || corruption.ID != "v0002" || !errors.Is(err, ErrCorruptRecord) || !errors.Is(err, ErrReadFailed) {
+				t.Fatalf("Expected a corruption error for v0002, got %v", err)
+			}
+			if err := engine.(Scanner).Scan(context.Background(), func(*core.Vector) error { return nil }); !errors.Is(err, ErrCorruptRecord) {
+				t.Errorf("Expected scanning to fail on the corrupt record, got %v", err)
+			}
+
+			// Without options a scrub only reports
+			report := scrub(t, engine, ScrubOptions{})
+			if report.Checked != 5 || len(report.Corrupt) != 1 || report.Corrupt[0].ID != "v0002" ||
+				report.Corrupt[0].Action != ScrubActionReported || report.Quarantined != 0 {
+				t.Fatalf("Unexpected report: %+v", report)
+			}
+
+			// A replica holding good copies repairs the record
+			replica, err := NewMemoryStorage(StorageConfig{Type: StorageTypeMemory})
+			if err != nil {
+				t.Fatalf("Failed to create replica: %v", err)
+			}
+			if err := replica.Write(mmapTestVectors("v", 5, 1)); err != nil {
+				t.Fatalf("Failed to write replica: %v", err)
+			}
+			report = scrub(t, engine, ScrubOptions{Repair: replica})
+			if report.Quarantined != 1 || report.Repaired != 1 || report.Corrupt[0].Action != ScrubActionRepaired {
+				t.Fatalf("Unexpected repair report: %+v", report)
+			}
+			vectors, err := engine.Read([]string{"v0002"})
+			if err != nil || len(vectors) != 1 || vectors[0].Embedding[0] != 1 {
+				t.Fatalf("Expected the repaired vector, got %+v (%v)", vectors, err)
+			}
+			if report := scrub(t, engine, ScrubOptions{}); len(report.Corrupt) != 0 || report.Checked != 5 {
+				t.Errorf("Expected a clean scrub after repair, got %+v", report)
+			}
+
+			// Quarantining without a copy makes the vector read as missing
+			corrupt(t, engine, "v0003")
+			report = scrub(t, engine, ScrubOptions{Quarantine: true})
+			if report.Quarantined != 1 || report.Corrupt[0].Action != ScrubActionQuarantined {
+				t.Fatalf("Unexpected quarantine report: %+v", report)
+			}
+			vectors, err = engine.Read([]string{"v0001", "v0003"})
+			if err != nil || len(vectors) != 1 {
+				t.Errorf("Expected the quarantined vector to be missing, got %+v (%v)", vectors, err)
+			}
+			if stats := engine.GetStats(); stats.TotalVectors != 4 {
+				t.Errorf("Expected 4 vectors after quarantine, got %d", stats.TotalVectors)
+			}
+		})
+	}
+}
+
+func TestMMapStorage_ScrubAfterReopen(t *testing.T) {
+	dataPath := filepath.Join(t.TempDir(), "vectors.mmap")
+	mmap := newTestMMapStorage(t, dataPath, 0)
+	if err := mmap.Write(mmapTestVectors("v", 3, 1)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+	location := mmap.index["v0001"]
+	segmentPath := location.segment.path
+	if err := mmap.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	// Flip a byte of v0001 and leave a torn append behind the last record
+	data, err := os.ReadFile(segmentPath)
+	if err != nil {
+		t.Fatalf("Failed to read segment: %v", err)
+	}
+	data[location.offset+recordHeaderSize+int64(len("v0001"))] ^= 0xff
+	torn := binary.LittleEndian.AppendUint32(nil, 500)
+	data = append(data, append(torn, bytes.Repeat([]byte{0xab}, 40)...)...)
+	if err := os.WriteFile(segmentPath, data, 0600); err != nil {
+		t.Fatalf("Failed to write segment: %v", err)
+	}
+
+	mmap = newTestMMapStorage(t, dataPath, 0)
+	defer func() {
+		if err := mmap.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	// The corrupt record was left out of the index on open, but a scrub still finds it
+	if vectors, err := mmap.Read([]string{"v0001"}); err != nil || len(vectors) != 0 {
+		t.Fatalf("Expected v0001 to be missing, got %+v (%v)", vectors, err)
+	}
+	report := scrub(t, mmap, ScrubOptions{Quarantine: true})
+	if len(report.Corrupt) != 2 || report.Quarantined != 2 {
+		t.Fatalf("Expected 2 quarantined records, got %+v", report)
+	}
+	if record := report.Corrupt[0]; record.ID != "v0001" || record.Reason != "checksum mismatch" || record.Version {
+		t.Errorf("Unexpected corrupt record: %+v", record)
+	}
+	if record := report.Corrupt[1]; record.ID != "" || record.Reason != "truncated record" {
+		t.Errorf("Unexpected truncated record: %+v", record)
+	}
+
+	quarantined, err := os.ReadFile(dataPath + quarantineFileSuffix)
+	if err != nil {
+		t.Fatalf("Failed to read quarantine file: %v", err)
+	}
+	if lines := bytes.Count(quarantined, []byte("\n")); lines != 2 {
+		t.Errorf("Expected 2 quarantined records on disk, got %d", lines)
+	}
+
+	if report := scrub(t, mmap, ScrubOptions{}); len(report.Corrupt) != 0 || report.Checked != 2 {
+		t.Errorf("Expected a clean scrub after quarantine, got %+v", report)
+	}
+	if err := mmap.Write(mmapTestVectors("w", 1, 2)); err != nil {
+		t.Fatalf("Failed to write after quarantine: %v", err)
+	}
+}
@@ -0,0 +1,551 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// Tiered storage defaults, used when the configuration leaves a parameter at zero
+const (
+	defaultHotTierBudget         = 64 * 1024 * 1024 // 64MB
+	defaultPromotionThreshold    = 2
+	defaultAccessHalfLifeSeconds = 300
+
+	// coldAccessFloor is the access score below which the statistics of a
+	// vector outside the hot tier are forgotten on Compact
+	coldAccessFloor = 1.0 / 16
+)
+
+// Tier names reported in TierStats
+const (
+	TierHot  = "hot"
+	TierCold = "cold"
+)
+
+// TierStats describes the usage and effectiveness of one tier of tiered storage
+type TierStats struct {
+	Tier         string  `json:"tier"`
+	TotalVectors int64   `json:"total_vectors"`
+	MemoryUsage  int64   `json:"memory_usage_bytes"`
+	Lookups      uint64  `json:"lookups"`
+	Hits         uint64  `json:"hits"`
+	HitRate      float64 `json:"hit_rate"`
+}
+
+// tierAccess is the access statistic of a vector. The score counts reads and
+// halves every half-life, so it reflects how often the vector was read recently.
+// The statistics of a vector outside the hot tier sit in the cold LRU list.
+type tierAccess struct {
+	score    float64
+	accessed time.Time
+	hot      bool
+	size     int64
+	element  *list.Element
+}
+
+// decayed returns the access score at now, halved for every full half-life
+// since the last read so that reads within one half-life count fully
+func (a *tierAccess) decayed(now time.Time, halfLife time.Duration) float64 {
+	halvings := now.Sub(a.accessed) / halfLife
+	if halvings <= 0 {
+		return a.score
+	}
+	if halvings > 64 {
+		return 0
+	}
+	return math.Ldexp(a.score, -int(halvings))
+}
+
+// TieredStorage keeps frequently read vectors in a hot memory tier on top of a
+// cold LevelDB tier that holds every vector. Writes go through to LevelDB before
+// they are acknowledged, so the hot tier is only a cache. Vectors outside the
+// hot tier are promoted once they are read PromotionThreshold times within
+// about one access half-life; when the hot tier exceeds its memory budget the
+// vectors with the lowest access scores are demoted. Statistics are kept for
+// the hot vectors and for the most recently read cold vectors that would fit
+// in the hot tier together; the others are forgotten.
+type TieredStorage struct {
+	config    StorageConfig
+	hot       *MemoryStorage
+	cold      *LevelDBStorage
+	budget    int64
+	threshold float64
+	halfLife  time.Duration
+
+	// mutex keeps writes and deletes from interleaving with a read that
+	// promotes the vector it read from the cold tier
+	mutex sync.RWMutex
+
+	// accessMutex guards the access statistics, hot tier membership and counters
+	accessMutex sync.Mutex
+	access      map[string]*tierAccess
+	coldLRU     *list.List // IDs of the cold vectors with statistics, most recently read first
+	coldBytes   int64
+	hotBytes    int64
+	hotVectors  int64
+	hotLookups  uint64
+	hotHits     uint64
+	coldLookups uint64
+	coldHits    uint64
+	avgReadTime float64
+}
+
+// NewTieredStorage creates a tiered storage engine with a LevelDB cold tier at DataPath
+func NewTieredStorage(config StorageConfig) (StorageEngine, error) {
+	cold, err := NewLevelDBStorage(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Previous versions are kept by the cold tier only
+	hotConfig := config
+	hotConfig.MaxVersions = 0
+	hot, err := NewMemoryStorage(hotConfig)
+	if err != nil {
+		_ = cold.Close()
+		return nil, err
+	}
+
+	t := &TieredStorage{
+		config:    config,
+		hot:       hot.(*MemoryStorage),
+		cold:      cold.(*LevelDBStorage),
+		budget:    config.HotTierBudget,
+		threshold: float64(config.PromotionThreshold),
+		halfLife:  time.Duration(config.AccessHalfLifeSeconds) * time.Second,
+		access:    make(map[string]*tierAccess),
+		coldLRU:   list.New(),
+	}
+	if t.budget == 0 {
+		t.budget = defaultHotTierBudget
+	}
+	if t.threshold == 0 {
+		t.threshold = defaultPromotionThreshold
+	}
+	if t.halfLife == 0 {
+		t.halfLife = defaultAccessHalfLifeSeconds * time.Second
+	}
+
+	return t, nil
+}
+
+// Write stores multiple vectors to tiered storage
+func (t *TieredStorage) Write(vectors []*core.Vector) error {
+	return t.WriteWithContext(context.Background(), vectors)
+}
+
+// WriteWithContext writes vectors through to the cold tier and refreshes the
+// copies of vectors that are in the hot tier, dropping those of tombstones
+func (t *TieredStorage) WriteWithContext(ctx context.Context, vectors []*core.Vector) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.cold.WriteWithContext(ctx, vectors); err != nil {
+		return err
+	}
+
+	t.accessMutex.Lock()
+	defer t.accessMutex.Unlock()
+
+	var refreshed []*core.Vector
+	var removed []string
+	for _, vector := range vectors {
+		access, exists := t.access[vector.ID]
+		switch {
+		case !exists || !access.hot:
+		case vector.Deleted:
+			t.forget(vector.ID)
+			removed = append(removed, vector.ID)
+		default:
+			t.hotBytes += vectorFootprint(vector) - access.size
+			access.size = vectorFootprint(vector)
+			refreshed = append(refreshed, vector)
+		}
+	}
+	if len(removed) > 0 {
+		if err := t.hot.DeleteWithContext(ctx, removed); err != nil {
+			return err
+		}
+		// Leave out the copies of vectors a later tombstone of the batch demoted
+		kept := refreshed[:0]
+		for _, vector := range refreshed {
+			if _, hot := t.access[vector.ID]; hot {
+				kept = append(kept, vector)
+			}
+		}
+		refreshed = kept
+	}
+	if len(refreshed) == 0 {
+		return nil
+	}
+	if err := t.hot.WriteWithContext(ctx, refreshed); err != nil {
+		return err
+	}
+	return t.enforceBudget(ctx, time.Now(), "")
+}
+
+// Read retrieves vectors by their IDs
+func (t *TieredStorage) Read(ids []string) ([]*core.Vector, error) {
+	return t.ReadWithContext(context.Background(), ids)
+}
+
+// ReadWithContext reads vectors from the hot tier, falls back to the cold tier
+// for the rest and promotes cold vectors that are read often enough
+func (t *TieredStorage) ReadWithContext(ctx context.Context, ids []string) ([]*core.Vector, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	start := time.Now()
+
+	hot, err := t.hot.ReadWithContext(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]*core.Vector, len(ids))
+	for _, vector := range hot {
+		found[vector.ID] = vector
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if _, exists := found[id]; !exists {
+			missing = append(missing, id)
+		}
+	}
+	var cold []*core.Vector
+	if len(missing) > 0 {
+		if cold, err = t.cold.ReadWithContext(ctx, missing); err != nil {
+			return nil, err
+		}
+		for _, vector := range cold {
+			found[vector.ID] = vector
+		}
+	}
+
+	if err := t.recordReads(ctx, start, ids, hot, cold, len(missing)); err != nil {
+		return nil, err
+	}
+
+	// Keep the order of the requested IDs, skipping unknown ones
+	vectors := make([]*core.Vector, 0, len(found))
+	for _, id := range ids {
+		if vector, exists := found[id]; exists {
+			vectors = append(vectors, vector)
+		}
+	}
+	return vectors, nil
+}
+
+// recordReads updates the access statistics and counters after a read and
+// promotes the cold vectors whose access score reached the threshold
+func (t *TieredStorage) recordReads(ctx context.Context, start time.Time, ids []string, hot, cold []*core.Vector, coldLookups int) error {
+	t.accessMutex.Lock()
+	defer t.accessMutex.Unlock()
+
+	now := time.Now()
+	for _, vector := range hot {
+		t.touch(vector, now)
+	}
+
+	var promoted []*core.Vector
+	for _, vector := range cold {
+		access := t.touch(vector, now)
+		if access.hot || access.score < t.threshold || access.size > t.budget {
+			continue
+		}
+		t.coldLRU.Remove(access.element)
+		t.coldBytes -= access.size
+		access.element = nil
+		access.hot = true
+		t.hotBytes += access.size
+		t.hotVectors++
+		promoted = append(promoted, vector)
+	}
+	t.trimCold()
+
+	t.hotLookups += uint64(len(ids))
+	t.hotHits += uint64(len(hot))
+	t.coldLookups += uint64(coldLookups)
+	t.coldHits += uint64(len(cold))
+	if len(ids) > 0 {
+		t.avgReadTime = float64(time.Since(start).Microseconds()) / float64(len(ids))
+	}
+
+	if len(promoted) == 0 {
+		return nil
+	}
+	if err := t.hot.WriteWithContext(ctx, promoted); err != nil {
+		return err
+	}
+	for _, vector := range promoted {
+		if err := t.enforceBudget(ctx, now, vector.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touch counts a read of vector, moving the statistics of a cold vector to
+// the front of the cold list; the caller must hold the access lock
+func (t *TieredStorage) touch(vector *core.Vector, now time.Time) *tierAccess {
+	access, exists := t.access[vector.ID]
+	if !exists {
+		access = &tierAccess{element: t.coldLRU.PushFront(vector.ID)}
+		t.access[vector.ID] = access
+	} else if !access.hot {
+		t.coldLRU.MoveToFront(access.element)
+	}
+	if !access.hot {
+		size := vectorFootprint(vector)
+		t.coldBytes += size - access.size
+		access.size = size
+	}
+	access.score = access.decayed(now, t.halfLife) + 1
+	access.accessed = now
+	return access
+}
+
+// trimCold forgets the statistics of the least recently read cold vectors
+// until those left would fit in the hot tier; the caller must hold the access lock
+func (t *TieredStorage) trimCold() {
+	for t.coldBytes > t.budget && t.coldLRU.Len() > 0 {
+		t.forget(t.coldLRU.Back().Value.(string))
+	}
+}
+
+// enforceBudget demotes the hot vectors with the lowest access scores, other
+// than keep, until the hot tier fits its memory budget; the caller must hold
+// the access lock
+func (t *TieredStorage) enforceBudget(ctx context.Context, now time.Time, keep string) error {
+	if t.hotBytes <= t.budget {
+		return nil
+	}
+
+	type candidate struct {
+		id    string
+		score float64
+	}
+	candidates := make([]candidate, 0, t.hotVectors)
+	for id, access := range t.access {
+		if access.hot && id != keep {
+			candidates = append(candidates, candidate{id: id, score: access.decayed(now, t.halfLife)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score < candidates[j].score
+	})
+
+	var demoted []string
+	for _, c := range candidates {
+		if t.hotBytes <= t.budget {
+			break
+		}
+		demoted = append(demoted, c.id)
+		t.demote(c.id)
+	}
+	t.trimCold()
+	return t.hot.DeleteWithContext(ctx, demoted)
+}
+
+// demote moves id from the hot tier to the front of the cold list, keeping
+// its access statistics; the caller must hold the access lock, delete the
+// vector from the hot tier and trim the cold list
+func (t *TieredStorage) demote(id string) {
+	access := t.access[id]
+	access.hot = false
+	access.element = t.coldLRU.PushFront(id)
+	t.hotBytes -= access.size
+	t.hotVectors--
+	t.coldBytes += access.size
+}
+
+// forget drops the access statistics of id, taking it out of the hot tier if
+// it was there; the caller must hold the access lock and delete a hot vector
+// from the hot tier
+func (t *TieredStorage) forget(id string) {
+	access, exists := t.access[id]
+	if !exists {
+		return
+	}
+	delete(t.access, id)
+	if access.hot {
+		t.hotBytes -= access.size
+		t.hotVectors--
+		return
+	}
+	t.coldLRU.Remove(access.element)
+	t.coldBytes -= access.size
+}
+
+// Delete removes vectors by their IDs
+func (t *TieredStorage) Delete(ids []string) error {
+	return t.DeleteWithContext(context.Background(), ids)
+}
+
+// DeleteWithContext removes vectors from both tiers
+func (t *TieredStorage) DeleteWithContext(ctx context.Context, ids []string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.cold.DeleteWithContext(ctx, ids); err != nil {
+		return err
+	}
+
+	t.accessMutex.Lock()
+	defer t.accessMutex.Unlock()
+
+	for _, id := range ids {
+		t.forget(id)
+	}
+	return t.hot.DeleteWithContext(ctx, ids)
+}
+
+// ReadAsOf retrieves the versions of vectors that were current at the given time
+func (t *TieredStorage) ReadAsOf(ids []string, asOf time.Time) ([]*core.Vector, error) {
+	return t.ReadAsOfWithContext(context.Background(), ids, asOf)
+}
+
+// ReadAsOfWithContext reads past versions from the cold tier, which keeps the version history
+func (t *TieredStorage) ReadAsOfWithContext(ctx context.Context, ids []string, asOf time.Time) ([]*core.Vector, error) {
+	return t.cold.ReadAsOfWithContext(ctx, ids, asOf)
+}
+
+// Scan calls fn for every stored vector in ID order
+func (t *TieredStorage) Scan(ctx context.Context, fn func(*core.Vector) error) error {
+	return t.cold.Scan(ctx, fn)
+}
+
+// ScanVersions calls fn with the versions of every stored or deleted vector
+// from the cold tier, which keeps the version history
+func (t *TieredStorage) ScanVersions(ctx context.Context, fn func([]*core.Vector) error) error {
+	return t.cold.ScanVersions(ctx, fn)
+}
+
+// Scrub verifies the records of the cold tier, which holds every vector, and
+// drops the hot copies of the vectors it quarantined or repaired
+func (t *TieredStorage) Scrub(ctx context.Context, opts ScrubOptions) (*ScrubReport, error) {
+	report, err := t.cold.Scrub(ctx, opts)
+	if report == nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, record := range report.Corrupt {
+		if record.Action != ScrubActionReported && !record.Version && record.ID != "" {
+			ids = append(ids, record.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return report, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.accessMutex.Lock()
+	defer t.accessMutex.Unlock()
+
+	for _, id := range ids {
+		t.forget(id)
+	}
+	if deleteErr := t.hot.DeleteWithContext(ctx, ids); deleteErr != nil && err == nil {
+		err = deleteErr
+	}
+	return report, err
+}
+
+// Compact compacts the cold tier, demotes hot vectors that have not been read
+// for a while and forgets the access statistics of cold vectors that went quiet
+func (t *TieredStorage) Compact() error {
+	if err := t.cold.Compact(); err != nil {
+		return err
+	}
+
+	t.accessMutex.Lock()
+	defer t.accessMutex.Unlock()
+
+	now := time.Now()
+	var demoted []string
+	for id, access := range t.access {
+		score := access.decayed(now, t.halfLife)
+		switch {
+		case access.hot && score < 1:
+			// Not read within the last half-life
+			t.forget(id)
+			demoted = append(demoted, id)
+		case !access.hot && score < coldAccessFloor:
+			t.forget(id)
+		}
+	}
+	return t.hot.DeleteWithContext(context.Background(), demoted)
+}
+
+// GetStats returns the statistics of the cold tier, which holds every vector,
+// with the memory usage and hit rates of both tiers
+func (t *TieredStorage) GetStats() StorageStats {
+	stats := t.cold.GetStats()
+	coldStats := stats
+
+	t.accessMutex.Lock()
+	defer t.accessMutex.Unlock()
+
+	stats.MemoryUsage += t.hotBytes
+	stats.AvgReadTime = t.avgReadTime
+	stats.Tiers = []TierStats{
+		{
+			Tier:         TierHot,
+			TotalVectors: t.hotVectors,
+			MemoryUsage:  t.hotBytes,
+			Lookups:      t.hotLookups,
+			Hits:         t.hotHits,
+			HitRate:      hitRate(t.hotHits, t.hotLookups),
+		},
+		{
+			Tier:         TierCold,
+			TotalVectors: coldStats.TotalVectors,
+			MemoryUsage:  coldStats.MemoryUsage,
+			Lookups:      t.coldLookups,
+			Hits:         t.coldHits,
+			HitRate:      hitRate(t.coldHits, t.coldLookups),
+		},
+	}
+
+	return stats
+}
+
+// hitRate returns hits as a fraction of lookups
+func hitRate(hits, lookups uint64) float64 {
+	if lookups == 0 {
+		return 0
+	}
+	return float64(hits) / float64(lookups)
+}
+
+// Close closes both tiers
+func (t *TieredStorage) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	hotErr := t.hot.Close()
+	if err := t.cold.Close(); err != nil {
+		return err
+	}
+	return hotErr
+}
+
+// vectorFootprint estimates the memory used by a vector in the hot tier
+func vectorFootprint(vector *core.Vector) int64 {
+	size := int64(128 + len(vector.ID) + len(vector.Collection) + len(vector.Text) + 8*len(vector.Embedding))
+	for key, value := range vector.Metadata {
+		size += int64(len(key)) + 16
+		if s, ok := value.(string); ok {
+			size += int64(len(s))
+		}
+	}
+	return size
+}
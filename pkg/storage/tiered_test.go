@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func newTestTieredStorage(t *testing.T, dataPath string, budget int64) *TieredStorage {
+	t.Helper()
+
+	config := StorageConfig{
+		Type:            StorageTypeTiered,
+		DataPath:        dataPath,
+		MaxFileSize:     1024 * 1024,
+		BatchSize:       100,
+		CacheSize:       8 * 1024 * 1024,
+		WriteBufferSize: 4 * 1024 * 1024,
+		MaxOpenFiles:    100,
+		HotTierBudget:   budget,
+	}
+
+	factory := &DefaultStorageFactory{}
+	engine, err := factory.CreateStorage(config)
+	if err != nil {
+		t.Fatalf("Failed to create tiered storage: %v", err)
+	}
+
+	return engine.(*TieredStorage)
+}
+
+func readTiered(t *testing.T, tiered *TieredStorage, ids ...string) []*core.Vector {
+	t.Helper()
+
+	vectors, err := tiered.Read(ids)
+	if err != nil {
+		t.Fatalf("Failed to read vectors: %v", err)
+	}
+	return vectors
+}
+
+func tierStats(t *testing.T, tiered *TieredStorage) (hot, cold TierStats) {
+	t.Helper()
+
+	stats := tiered.GetStats()
+	if len(stats.Tiers) != 2 || stats.Tiers[0].Tier != TierHot || stats.Tiers[1].Tier != TierCold {
+		t.Fatalf("Unexpected tiers: %+v", stats.Tiers)
+	}
+	return stats.Tiers[0], stats.Tiers[1]
+}
+
+func TestTieredStorage_PromotionAndWriteThrough(t *testing.T) {
+	dataPath := filepath.Join(t.TempDir(), "tiered")
+	tiered := newTestTieredStorage(t, dataPath, 0)
+
+	if err := tiered.Write(mmapTestVectors("v", 10, 1)); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+
+	// The first read is served by the cold tier and does not promote yet
+	readTiered(t, tiered, "v0001", "missing")
+	hot, cold := tierStats(t, tiered)
+	if hot.TotalVectors != 0 || hot.Lookups != 2 || hot.Hits != 0 {
+		t.Errorf("Unexpected hot tier after one read: %+v", hot)
+	}
+	if cold.TotalVectors != 10 || cold.Lookups != 2 || cold.Hits != 1 || cold.HitRate != 0.5 {
+		t.Errorf("Unexpected cold tier after one read: %+v", cold)
+	}
+
+	// The second read promotes, the third is a hot hit
+	readTiered(t, tiered, "v0001")
+	vectors := readTiered(t, tiered, "v0001", "v0002")
+	if len(vectors) != 2 || vectors[0].ID != "v0001" || vectors[1].ID != "v0002" {
+		t.Fatalf("Expected vectors in request order, got %+v", vectors)
+	}
+	hot, _ = tierStats(t, tiered)
+	if hot.TotalVectors != 1 || hot.Hits != 1 || hot.MemoryUsage <= 0 {
+		t.Errorf("Expected v0001 in the hot tier, got %+v", hot)
+	}
+
+	// Writes go through to LevelDB and refresh the hot copy
+	if err := tiered.Write(mmapTestVectors("v", 2, 7)); err != nil {
+		t.Fatalf("Failed to overwrite vectors: %v", err)
+	}
+	if vectors := readTiered(t, tiered, "v0001"); len(vectors) != 1 || vectors[0].Embedding[0] != 7 {
+		t.Errorf("Expected the hot tier to serve the new version, got %+v", vectors)
+	}
+
+	if err := tiered.Delete([]string{"v0001"}); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	if vectors := readTiered(t, tiered, "v0001"); len(vectors) != 0 {
+		t.Errorf("Expected the deleted vector to be gone, got %+v", vectors)
+	}
+	if hot, _ := tierStats(t, tiered); hot.TotalVectors != 0 || hot.MemoryUsage != 0 {
+		t.Errorf("Expected an empty hot tier after delete, got %+v", hot)
+	}
+
+	if err := tiered.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	tiered = newTestTieredStorage(t, dataPath, 0)
+	defer func() {
+		if err := tiered.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+	vectors = readTiered(t, tiered, "v0000", "v0001", "v0009")
+	if len(vectors) != 2 || vectors[0].Embedding[0] != 7 || vectors[1].Embedding[0] != 1 {
+		t.Errorf("Unexpected vectors after reopen: %+v", vectors)
+	}
+}
+
+func TestTieredStorage_MemoryBudget(t *testing.T) {
+	vectors := mmapTestVectors("v", 5, 1)
+	budget := 3 * vectorFootprint(vectors[0])
+	tiered := newTestTieredStorage(t, filepath.Join(t.TempDir(), "tiered"), budget)
+	defer func() {
+		if err := tiered.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	if err := tiered.Write(vectors); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+
+	// v0000 is read most often, v0004 last; every vector gets promoted once
+	for _, vector := range vectors {
+		readTiered(t, tiered, vector.ID, vector.ID)
+	}
+	for i := 0; i < 3; i++ {
+		readTiered(t, tiered, "v0000")
+	}
+	readTiered(t, tiered, "v0004")
+
+	hot, _ := tierStats(t, tiered)
+	if hot.MemoryUsage > budget || hot.TotalVectors != 3 {
+		t.Errorf("Expected 3 hot vectors within %d bytes, got %+v", budget, hot)
+	}
+
+	before := hot.Hits
+	readTiered(t, tiered, "v0000", "v0004")
+	if hot, _ := tierStats(t, tiered); hot.Hits != before+2 {
+		t.Errorf("Expected the most read vectors to stay hot, got %d new hits", hot.Hits-before)
+	}
+
+	// Hot vectors that have not been read for a while are demoted on Compact
+	tiered.accessMutex.Lock()
+	for _, access := range tiered.access {
+		access.accessed = access.accessed.Add(-time.Hour)
+	}
+	tiered.accessMutex.Unlock()
+
+	if err := tiered.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	if hot, _ := tierStats(t, tiered); hot.TotalVectors != 0 || hot.MemoryUsage != 0 {
+		t.Errorf("Expected idle vectors to be demoted, got %+v", hot)
+	}
+	if vectors := readTiered(t, tiered, "v0000"); len(vectors) != 1 {
+		t.Errorf("Expected demoted vectors to remain in the cold tier, got %+v", vectors)
+	}
+}
+
+func TestTieredStorage_BoundedAccessStatistics(t *testing.T) {
+	vectors := mmapTestVectors("v", 50, 1)
+	budget := 3 * vectorFootprint(vectors[0])
+	tiered := newTestTieredStorage(t, filepath.Join(t.TempDir(), "tiered"), budget)
+	defer func() {
+		if err := tiered.Close(); err != nil {
+			t.Errorf("Failed to close storage: %v", err)
+		}
+	}()
+
+	if err := tiered.Write(vectors); err != nil {
+		t.Fatalf("Failed to write vectors: %v", err)
+	}
+
+	// Reading every vector once promotes none and must not track them all
+	for _, vector := range vectors {
+		readTiered(t, tiered, vector.ID)
+	}
+	tiered.accessMutex.Lock()
+	tracked, coldBytes := len(tiered.access), tiered.coldBytes
+	tiered.accessMutex.Unlock()
+	if tracked != 3 || coldBytes > budget {
+		t.Errorf("Expected statistics of 3 vectors within %d bytes, got %d in %d bytes", budget, tracked, coldBytes)
+	}
+
+	// The most recently read vectors are the ones still counted
+	readTiered(t, tiered, "v0049")
+	if hot, _ := tierStats(t, tiered); hot.TotalVectors != 1 {
+		t.Errorf("Expected the recently read vector to be promoted, got %+v", hot)
+	}
+	readTiered(t, tiered, "v0000")
+	if hot, _ := tierStats(t, tiered); hot.TotalVectors != 1 {
+		t.Errorf("Expected the forgotten vector to stay cold, got %+v", hot)
+	}
+}
+
+func TestTieredStorage_InvalidConfig(t *testing.T) {
+	factory := &DefaultStorageFactory{}
+	err := factory.ValidateConfig(StorageConfig{
+		Type:            StorageTypeTiered,
+		DataPath:        "/tmp/test",
+		MaxFileSize:     1024,
+		BatchSize:       100,
+		CacheSize:       1024,
+		WriteBufferSize: 1024,
+		MaxOpenFiles:    10,
+		HotTierBudget:   -1,
+	})
+	if !errors.Is(err, ErrInvalidTierConfig) {
+		t.Errorf("Expected ErrInvalidTierConfig, got %v", err)
+	}
+}
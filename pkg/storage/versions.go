@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// VersionScanner is implemented by storage engines that can iterate over the
+// version history of every vector, including deleted vectors
+type VersionScanner interface {
+	// ScanVersions calls fn in ID order with the kept versions of every
+	// vector, ordered by version key. The last version is the current vector
+	// or, for a deleted vector, the tombstone of its deletion.
+	ScanVersions(ctx context.Context, fn func(versions []*core.Vector) error) error
+}
+
+// VersionAsOf returns the version of versions, ordered by version key, that
+// was current at asOf, or nil when the vector was not stored then
+func VersionAsOf(versions []*core.Vector, asOf time.Time) *core.Vector {
+	updated := make([]time.Time, len(versions))
+	for i, version := range versions {
+		updated[i] = version.UpdatedAt
+	}
+	i := versionAsOf(updated, asOf)
+	if i < 0 || versions[i].Deleted {
+		return nil
+	}
+	return versions[i]
+}
+
+// versionPolicy decides which previous versions of a vector are kept.
+// Versions are keyed by the UpdatedAt time of the vector; writing a vector whose
+// UpdatedAt differs from the stored one moves the stored vector into its history.
+// With version history enabled, deleting a vector moves it into its history as
+// well, followed by a tombstone keyed by the time of the deletion.
+type versionPolicy struct {
+	maxVersions int
+	retention   time.Duration
+}
+
+// newVersionPolicy returns the version policy of a storage configuration
+func newVersionPolicy(config StorageConfig) versionPolicy {
+	return versionPolicy{
+		maxVersions: config.MaxVersions,
+		retention:   time.Duration(config.VersionRetentionSeconds) * time.Second,
+	}
+}
+
+// enabled reports whether previous versions are kept at all
+func (p versionPolicy) enabled() bool {
+	return p.maxVersions > 0
+}
+
+// forgotten reports whether a deleted vector, whose tombstone has the version
+// key deleted, is dropped together with its history: without version history,
+// or once it was deleted longer ago than the retention period
+func (p versionPolicy) forgotten(deleted, now time.Time) bool {
+	return !p.enabled() || (p.retention > 0 && deleted.Before(now.Add(-p.retention)))
+}
+
+// prunable returns how many of the oldest previous versions fall outside the policy.
+// updated holds the version keys of the previous versions in ascending order,
+// followed by the version key of the current vector or of the tombstone of a
+// deleted vector.
+//
+// A previous version is kept while it is one of the newest maxVersions and the
+// version that replaced it is younger than the retention period, so that reads
+// as of any time within the retention period find the version valid back then.
+func (p versionPolicy) prunable(updated []time.Time, now time.Time) int {
+	previous := len(updated) - 1
+	if previous <= 0 {
+		return 0
+	}
+	if !p.enabled() {
+		return previous
+	}
+
+	drop := 0
+	if previous > p.maxVersions {
+		drop = previous - p.maxVersions
+	}
+	if p.retention > 0 {
+		cutoff := now.Add(-p.retention)
+		for drop < previous && updated[drop+1].Before(cutoff) {
+			drop++
+		}
+	}
+
+	return drop
+}
+
+// versionAsOf returns the index of the latest version key that is not after
+// asOf, or -1 when every version is newer
+func versionAsOf(updated []time.Time, asOf time.Time) int {
+	best := -1
+	for i, key := range updated {
+		if key.After(asOf) {
+			continue
+		}
+		if best < 0 || !key.Before(updated[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// tombstone returns the version that records the deletion of a vector whose
+// latest version key is updated. It is keyed by the time of the deletion,
+// which always follows that key.
+func tombstone(id string, updated, deleted time.Time) *core.Vector {
+	if !deleted.After(updated) {
+		deleted = updated.Add(time.Nanosecond)
+	}
+	return &core.Vector{ID: id, UpdatedAt: deleted, Deleted: true}
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// versionedEngines returns a constructor per storage type that opens the same
+// store again on every call
+func versionedEngines(t *testing.T, maxVersions int, retention time.Duration) map[StorageType]func(*testing.T) StorageEngine {
+	t.Helper()
+
+	dir := t.TempDir()
+	configs := map[StorageType]StorageConfig{
+		StorageTypeMemory: {
+			Type:        StorageTypeMemory,
+			DataPath:    dir,
+			MaxFileSize: 1024 * 1024,
+			BatchSize:   100,
+		},
+		StorageTypeMMap: {
+			Type:        StorageTypeMMap,
+			DataPath:    filepath.Join(dir, "vectors.mmap"),
+			MaxFileSize: 1024 * 1024,
+			PageSize:    4096,
+			BatchSize:   100,
+		},
+		StorageTypeLevelDB: {
+			Type:            StorageTypeLevelDB,
+			DataPath:        filepath.Join(dir, "leveldb"),
+			MaxFileSize:     1024 * 1024,
+			BatchSize:       100,
+			CacheSize:       8 * 1024 * 1024,
+			WriteBufferSize: 4 * 1024 * 1024,
+			MaxOpenFiles:    100,
+		},
+		StorageTypeLSM: {
+			Type:        StorageTypeLSM,
+			DataPath:    filepath.Join(dir, "lsm"),
+			MaxFileSize: 1024 * 1024,
+			BatchSize:   100,
+		},
+	}
+
+	factory := &DefaultStorageFactory{}
+	engines := make(map[StorageType]func(*testing.T) StorageEngine, len(configs))
+	for name, config := range configs {
+		config.MaxVersions = maxVersions
+		config.VersionRetentionSeconds = int64(retention / time.Second)
+		engines[name] = func(t *testing.T) StorageEngine {
+			t.Helper()
+			engine, err := factory.CreateStorage(config)
+			if err != nil {
+				t.Fatalf("Failed to create %s storage: %v", config.Type, err)
+			}
+			return engine
+		}
+	}
+	return engines
+}
+
+func versionedVector(value float64, updated time.Time) *core.Vector {
+	return &core.Vector{
+		ID:         "doc",
+		Collection: "test",
+		Embedding:  []float64{value, 1, 2, 3},
+		CreatedAt:  updated,
+		UpdatedAt:  updated,
+	}
+}
+
+func readValueAsOf(t *testing.T, engine StorageEngine, asOf time.Time) (float64, bool) {
+	t.Helper()
+
+	vectors, err := engine.ReadAsOf([]string{"doc", "missing"}, asOf)
+	if err != nil {
+		t.Fatalf("Failed to read as of %v: %v", asOf, err)
+	}
+	switch len(vectors) {
+	case 0:
+		return 0, false
+	case 1:
+		return vectors[0].Embedding[0], true
+	default:
+		t.Fatalf("Expected at most 1 vector, got %d", len(vectors))
+		return 0, false
+	}
+}
+
+func TestStorage_ReadAsOf(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for name, open := range versionedEngines(t, 2, 0) {
+		t.Run(string(name), func(t *testing.T) {
+			engine := open(t)
+
+			for i := 1; i <= 4; i++ {
+				updated := base.Add(time.Duration(i) * time.Minute)
+				if err := engine.Write([]*core.Vector{versionedVector(float64(i), updated)}); err != nil {
+					t.Fatalf("Failed to write version %d: %v", i, err)
+				}
+			}
+			// Rewriting a vector with the same UpdatedAt does not add a version
+			if err := engine.Write([]*core.Vector{versionedVector(5, base.Add(4*time.Minute))}); err != nil {
+				t.Fatalf("Failed to rewrite vector: %v", err)
+			}
+
+			check := func(engine StorageEngine) {
+				t.Helper()
+
+				// The oldest version was pruned, so nothing is known before the second
+				if _, found := readValueAsOf(t, engine, base.Add(90*time.Second)); found {
+					t.Error("Expected the pruned version to be gone")
+				}
+				for asOf, expected := range map[time.Duration]float64{
+					2*time.Minute + 30*time.Second: 2,
+					3 * time.Minute:                3,
+					time.Hour:                      5,
+				} {
+					value, found := readValueAsOf(t, engine, base.Add(asOf))
+					if !found || value != expected {
+						t.Errorf("As of +%v: expected %v, got %v (found %v)", asOf, expected, value, found)
+					}
+				}
+				if stats := engine.GetStats(); stats.VersionCount != 2 || stats.TotalVectors != 1 {
+					t.Errorf("Expected 1 vector with 2 versions, got %d with %d", stats.TotalVectors, stats.VersionCount)
+				}
+
+				current, err := engine.Read([]string{"doc"})
+				if err != nil {
+					t.Fatalf("Failed to read vector: %v", err)
+				}
+				if len(current) != 1 || current[0].Embedding[0] != 5 {
+					t.Errorf("Expected the current version, got %+v", current)
+				}
+			}
+			check(engine)
+
+			if name != StorageTypeMemory {
+				if err := engine.Close(); err != nil {
+					t.Fatalf("Failed to close storage: %v", err)
+				}
+				engine = open(t)
+				check(engine)
+			}
+
+			if err := engine.Compact(); err != nil {
+				t.Fatalf("Failed to compact: %v", err)
+			}
+			check(engine)
+
+			if err := engine.Close(); err != nil {
+				t.Errorf("Failed to close storage: %v", err)
+			}
+		})
+	}
+}
+
+func TestStorage_ReadAsOfDeleted(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for name, open := range versionedEngines(t, 2, 0) {
+		t.Run(string(name), func(t *testing.T) {
+			engine := open(t)
+
+			for i := 1; i <= 3; i++ {
+				updated := base.Add(time.Duration(i) * time.Minute)
+				if err := engine.Write([]*core.Vector{versionedVector(float64(i), updated)}); err != nil {
+					t.Fatalf("Failed to write version %d: %v", i, err)
+				}
+			}
+			if err := engine.Delete([]string{"doc"}); err != nil {
+				t.Fatalf("Failed to delete vector: %v", err)
+			}
+			deleted := time.Now()
+
+			check := func(engine StorageEngine) {
+				t.Helper()
+
+				// The deleted version and the previous one stay readable as of
+				// the times before the deletion
+				for asOf, expected := range map[time.Duration]float64{
+					2 * time.Minute:         2,
+					time.Hour - time.Second: 3,
+				} {
+					value, found := readValueAsOf(t, engine, base.Add(asOf))
+					if !found || value != expected {
+						t.Errorf("As of +%v: expected %v, got %v (found %v)", asOf, expected, value, found)
+					}
+				}
+				if _, found := readValueAsOf(t, engine, deleted); found {
+					t.Error("Expected nothing as of the deletion")
+				}
+				if _, found := readValueAsOf(t, engine, base.Add(90*time.Second)); found {
+					t.Error("Expected the pruned version to be gone")
+				}
+				if current, err := engine.Read([]string{"doc"}); err != nil || len(current) != 0 {
+					t.Errorf("Expected the deleted vector to be gone, got %+v (%v)", current, err)
+				}
+
+				// The tombstone ends the history of the vector
+				var history []*core.Vector
+				err := engine.(VersionScanner).ScanVersions(context.Background(), func(versions []*core.Vector) error {
+					history = append(history, versions...)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("Failed to scan versions: %v", err)
+				}
+				if len(history) != 3 || history[1].Embedding[0] != 3 || !history[2].Deleted {
+					t.Errorf("Expected 2 versions and a tombstone, got %+v", history)
+				}
+			}
+			check(engine)
+
+			if name != StorageTypeMemory {
+				if err := engine.Close(); err != nil {
+					t.Fatalf("Failed to close storage: %v", err)
+				}
+				engine = open(t)
+				check(engine)
+			}
+			if err := engine.Compact(); err != nil {
+				t.Fatalf("Failed to compact: %v", err)
+			}
+			check(engine)
+
+			// Writing the vector again starts a new version after the tombstone
+			if err := engine.Write([]*core.Vector{versionedVector(4, time.Now())}); err != nil {
+				t.Fatalf("Failed to write vector again: %v", err)
+			}
+			if _, found := readValueAsOf(t, engine, deleted); found {
+				t.Error("Expected nothing as of the deletion after writing the vector again")
+			}
+			if value, found := readValueAsOf(t, engine, time.Now()); !found || value != 4 {
+				t.Errorf("Expected the new version, got %v (found %v)", value, found)
+			}
+
+			if err := engine.Close(); err != nil {
+				t.Errorf("Failed to close storage: %v", err)
+			}
+		})
+	}
+}
+
+func TestStorage_WriteTombstone(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	for name, open := range versionedEngines(t, 5, 0) {
+		t.Run(string(name), func(t *testing.T) {
+			engine := open(t)
+			defer func() {
+				if err := engine.Close(); err != nil {
+					t.Errorf("Failed to close storage: %v", err)
+				}
+			}()
+
+			// A history written in one batch ends with the deletion at its time
+			deleted := base.Add(3 * time.Minute)
+			err := engine.Write([]*core.Vector{
+				versionedVector(1, base.Add(time.Minute)),
+				versionedVector(2, base.Add(2*time.Minute)),
+				{ID: "doc", UpdatedAt: deleted, Deleted: true},
+				{ID: "ghost", UpdatedAt: deleted, Deleted: true},
+			})
+			if err != nil {
+				t.Fatalf("Failed to write history: %v", err)
+			}
+
+			if value, found := readValueAsOf(t, engine, deleted.Add(-time.Second)); !found || value != 2 {
+				t.Errorf("Expected version 2 before the deletion, got %v (found %v)", value, found)
+			}
+			if _, found := readValueAsOf(t, engine, deleted); found {
+				t.Error("Expected nothing as of the deletion")
+			}
+			if current, err := engine.Read([]string{"doc", "ghost"}); err != nil || len(current) != 0 {
+				t.Errorf("Expected no stored vectors, got %+v (%v)", current, err)
+			}
+
+			var history []*core.Vector
+			err = engine.(VersionScanner).ScanVersions(context.Background(), func(versions []*core.Vector) error {
+				if versions[0].ID == "doc" {
+					history = versions
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to scan versions: %v", err)
+			}
+			if len(history) != 3 || !history[2].Deleted || !history[2].UpdatedAt.Equal(deleted) {
+				t.Errorf("Expected 2 versions and the tombstone at %v, got %+v", deleted, history)
+			}
+		})
+	}
+}
+
+func TestVersionPolicy_Forgotten(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		policy    versionPolicy
+		deleted   time.Duration
+		forgotten bool
+	}{
+		{versionPolicy{}, 0, true},
+		{versionPolicy{maxVersions: 2}, 100 * time.Hour, false},
+		{versionPolicy{maxVersions: 2, retention: time.Hour}, 30 * time.Minute, false},
+		{versionPolicy{maxVersions: 2, retention: time.Hour}, 2 * time.Hour, true},
+	} {
+		if forgotten := test.policy.forgotten(now.Add(-test.deleted), now); forgotten != test.forgotten {
+			t.Errorf("%+v deleted %v ago: expected forgotten %v, got %v", test.policy, test.deleted, test.forgotten, forgotten)
+		}
+	}
+}
+
+func TestStorage_InvalidVersionRetention(t *testing.T) {
+	factory := &DefaultStorageFactory{}
+	err := factory.ValidateConfig(StorageConfig{
+		Type:        StorageTypeMemory,
+		DataPath:    "/tmp/test",
+		MaxFileSize: 1024,
+		BatchSize:   100,
+		MaxVersions: -1,
+	})
+	if !errors.Is(err, ErrInvalidVersionRetention) {
+		t.Errorf("Expected ErrInvalidVersionRetention, got %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package tenant
+
+import "errors"
+
+// Tenant-related errors
+var (
+	ErrTenantNotFound  = errors.New("tenant not found")
+	ErrTenantExists    = errors.New("tenant already exists")
+	ErrInvalidTenant   = errors.New("invalid tenant")
+	ErrTenantSuspended = errors.New("tenant is suspended")
+	ErrTenantInactive  = errors.New("tenant is not active")
+	ErrNoTenantContext = errors.New("no tenant in context")
+	ErrTenantMismatch  = errors.New("resource belongs to another tenant")
+)
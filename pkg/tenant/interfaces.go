@@ -51,6 +51,7 @@ type TenantSettings struct {
 
 	// Integration Settings
 	WebhookURLs    []string `json:"webhook_urls"`
+	WebhookSecret  string   `json:"webhook_secret,omitempty"` // signs the events sent to the webhook URLs
 	OAuthProviders []string `json:"oauth_providers"`
 	LDAPEnabled    bool     `json:"ldap_enabled"`
 
@@ -0,0 +1,117 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// tenantContextKey is the context key of the tenant a request is scoped to
+type tenantContextKey struct{}
+
+// DefaultTenantIsolation implements the tenant isolation service on top of a
+// tenant service. Resources are isolated by name: the collections of a tenant
+// are stored under names prefixed with its ID, see CollectionName.
+type DefaultTenantIsolation struct {
+	tenants TenantService
+}
+
+var _ TenantIsolationService = (*DefaultTenantIsolation)(nil)
+
+// NewDefaultTenantIsolation creates a tenant isolation service for the tenants
+// of a tenant service
+func NewDefaultTenantIsolation(tenants TenantService) *DefaultTenantIsolation {
+	return &DefaultTenantIsolation{tenants: tenants}
+}
+
+// CreateTenantContext creates the context of a request of an active tenant
+func (s *DefaultTenantIsolation) CreateTenantContext(ctx context.Context, tenantID, userID, apiKey, ipAddress, userAgent, requestID string) (*TenantContext, error) {
+	tenant, err := s.activeTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TenantContext{
+		TenantID:   tenant.ID,
+		TenantName: tenant.Name,
+		UserID:     userID,
+		APIKey:     apiKey,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		RequestID:  requestID,
+		Timestamp:  time.Now(),
+	}, nil
+}
+
+// ValidateTenantContext checks that the tenant of a context is still active
+func (s *DefaultTenantIsolation) ValidateTenantContext(ctx context.Context, tenantContext *TenantContext) error {
+	if tenantContext == nil {
+		return ErrNoTenantContext
+	}
+	_, err := s.activeTenant(ctx, tenantContext.TenantID)
+	return err
+}
+
+// IsolateCollection checks that a stored collection name belongs to a tenant
+func (s *DefaultTenantIsolation) IsolateCollection(ctx context.Context, tenantID, collectionID string) error {
+	return s.ValidateTenantAccess(ctx, tenantID, "collection", collectionID)
+}
+
+// IsolateVector checks that a vector ID is valid for a tenant. Vectors are
+// isolated by their collection, so any ID of an active tenant is.
+func (s *DefaultTenantIsolation) IsolateVector(ctx context.Context, tenantID, vectorID string) error {
+	if vectorID == "" {
+		return fmt.Errorf("vector ID is required")
+	}
+	return nil
+}
+
+// ValidateTenantAccess checks that a tenant may access a resource. The tenant
+// must match the tenant the context is scoped to, if any, and collections
+// must be in the namespace of the tenant.
+func (s *DefaultTenantIsolation) ValidateTenantAccess(ctx context.Context, tenantID, resourceType, resourceID string) error {
+	if scoped, err := s.GetTenantFromContext(ctx); err == nil && scoped != tenantID {
+		return fmt.Errorf("%w: %s is scoped to %s", ErrTenantMismatch, tenantID, scoped)
+	}
+	if resourceType == "collection" {
+		if owner, _ := SplitCollectionName(resourceID); owner != tenantID {
+			return fmt.Errorf("%w: collection %s", ErrTenantMismatch, resourceID)
+		}
+	}
+	return nil
+}
+
+// ScopeToTenant returns a context scoped to a tenant
+func (s *DefaultTenantIsolation) ScopeToTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// GetTenantFromContext returns the tenant a context is scoped to
+func (s *DefaultTenantIsolation) GetTenantFromContext(ctx context.Context) (string, error) {
+	if tenantID, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return tenantID, nil
+	}
+	return "", ErrNoTenantContext
+}
+
+// HealthCheck checks the tenant service
+func (s *DefaultTenantIsolation) HealthCheck(ctx context.Context) error {
+	return s.tenants.HealthCheck(ctx)
+}
+
+// Close closes the isolation service; the tenant service is left open
+func (s *DefaultTenantIsolation) Close() error {
+	return nil
+}
+
+// activeTenant returns a tenant when it may serve requests
+func (s *DefaultTenantIsolation) activeTenant(ctx context.Context, tenantID string) (*Tenant, error) {
+	tenant, err := s.tenants.GetTenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.CheckStatus(); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
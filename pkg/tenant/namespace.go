@@ -0,0 +1,60 @@
+package tenant
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultTenantID is the tenant of requests that name no tenant. Its
+// collections keep their names unprefixed.
+const DefaultTenantID = "default"
+
+// NamespaceSeparator separates the tenant from the name of a collection of
+// another tenant than the default one. Unqualified catalog names cannot
+// contain it, so no collection of the default tenant, such as one named
+// acme.docs, is ever taken for a collection of another tenant.
+const NamespaceSeparator = "~"
+
+// tenantIDPattern matches the valid tenant IDs, which cannot contain the
+// namespace separator
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,63}$`)
+
+// ValidTenantID reports whether an ID is a valid tenant ID
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
+
+// CollectionName returns the name a collection of a tenant is stored under
+func CollectionName(tenantID, name string) string {
+	if tenantID == "" || tenantID == DefaultTenantID {
+		return name
+	}
+	return tenantID + NamespaceSeparator + name
+}
+
+// SplitCollectionName returns the tenant of a stored collection name and the
+// name of the collection within the tenant
+func SplitCollectionName(name string) (string, string) {
+	tenantID, local, found := strings.Cut(name, NamespaceSeparator)
+	if !found || !ValidTenantID(tenantID) {
+		return DefaultTenantID, name
+	}
+	return tenantID, local
+}
+
+// CheckStatus returns an error unless the tenant may serve requests
+func (t *Tenant) CheckStatus() error {
+	switch t.Status {
+	case TenantStatusActive:
+	case TenantStatusSuspended:
+		return fmt.Errorf("%w: %s", ErrTenantSuspended, t.ID)
+	default:
+		return fmt.Errorf("%w: %s is %s", ErrTenantInactive, t.ID, t.Status)
+	}
+	if t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt) {
+		return fmt.Errorf("%w: %s expired", ErrTenantInactive, t.ID)
+	}
+	return nil
+}
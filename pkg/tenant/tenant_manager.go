@@ -3,14 +3,21 @@ package tenant
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sort"
 	"sync"
 	"time"
 )
 
-// DefaultTenantManager implements the tenant management service
+// DefaultTenantManager implements the tenant management service. It is safe
+// for concurrent use; the tenants it returns are copies.
 type DefaultTenantManager struct {
 	tenants map[string]*Tenant
 	mu      sync.RWMutex
+
+	// path is the file the tenants are saved to, by OpenTenantManager; the
+	// tenants only live in memory without one
+	path string
 }
 
 // NewDefaultTenantManager creates a new default tenant manager
@@ -89,16 +96,20 @@ func (m *DefaultTenantManager) CreateTenant(ctx context.Context, tenant *Tenant)
 
 	// Validate tenant
 	if err := m.validateTenant(tenant); err != nil {
-		return fmt.Errorf("invalid tenant: %w", err)
+		return fmt.Errorf("%w: %v", ErrInvalidTenant, err)
 	}
 
 	// Check if tenant already exists
 	if _, exists := m.tenants[tenant.ID]; exists {
-		return fmt.Errorf("tenant already exists: %s", tenant.ID)
+		return fmt.Errorf("%w: %s", ErrTenantExists, tenant.ID)
 	}
 
 	// Store tenant
-	m.tenants[tenant.ID] = tenant
+	m.tenants[tenant.ID] = tenant.clone()
+	if err := m.save(); err != nil {
+		delete(m.tenants, tenant.ID)
+		return err
+	}
 
 	return nil
 }
@@ -110,10 +121,10 @@ func (m *DefaultTenantManager) GetTenant(ctx context.Context, tenantID string) (
 
 	tenant, exists := m.tenants[tenantID]
 	if !exists {
-		return nil, fmt.Errorf("tenant not found: %s", tenantID)
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
 	}
 
-	return tenant, nil
+	return tenant.clone(), nil
 }
 
 // GetTenantByDomain retrieves a tenant by domain
@@ -122,12 +133,12 @@ func (m *DefaultTenantManager) GetTenantByDomain(ctx context.Context, domain str
 	defer m.mu.RUnlock()
 
 	for _, tenant := range m.tenants {
-		if tenant.Domain == domain {
-			return tenant, nil
+		if tenant.Domain != "" && tenant.Domain == domain {
+			return tenant.clone(), nil
 		}
 	}
 
-	return nil, fmt.Errorf("tenant not found for domain: %s", domain)
+	return nil, fmt.Errorf("%w for domain: %s", ErrTenantNotFound, domain)
 }
 
 // UpdateTenant updates an existing tenant
@@ -135,18 +146,23 @@ func (m *DefaultTenantManager) UpdateTenant(ctx context.Context, tenant *Tenant)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.tenants[tenant.ID]; !exists {
-		return fmt.Errorf("tenant not found: %s", tenant.ID)
+	previous, exists := m.tenants[tenant.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, tenant.ID)
 	}
 
 	tenant.UpdatedAt = time.Now()
 
 	// Validate tenant
 	if err := m.validateTenant(tenant); err != nil {
-		return fmt.Errorf("invalid tenant: %w", err)
+		return fmt.Errorf("%w: %v", ErrInvalidTenant, err)
 	}
 
-	m.tenants[tenant.ID] = tenant
+	m.tenants[tenant.ID] = tenant.clone()
+	if err := m.save(); err != nil {
+		m.tenants[tenant.ID] = previous
+		return err
+	}
 	return nil
 }
 
@@ -155,11 +171,16 @@ func (m *DefaultTenantManager) DeleteTenant(ctx context.Context, tenantID string
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.tenants[tenantID]; !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
+	previous, exists := m.tenants[tenantID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
 	}
 
 	delete(m.tenants, tenantID)
+	if err := m.save(); err != nil {
+		m.tenants[tenantID] = previous
+		return err
+	}
 	return nil
 }
 
@@ -170,9 +191,17 @@ func (m *DefaultTenantManager) ListTenants(ctx context.Context, limit, offset in
 
 	tenantList := make([]*Tenant, 0, len(m.tenants))
 	for _, tenant := range m.tenants {
-		tenantList = append(tenantList, tenant)
+		tenantList = append(tenantList, tenant.clone())
 	}
 
+	// Sort oldest first so that pages are stable
+	sort.Slice(tenantList, func(i, j int) bool {
+		if !tenantList[i].CreatedAt.Equal(tenantList[j].CreatedAt) {
+			return tenantList[i].CreatedAt.Before(tenantList[j].CreatedAt)
+		}
+		return tenantList[i].ID < tenantList[j].ID
+	})
+
 	// Simple pagination (in production, use database pagination)
 	if offset >= len(tenantList) {
 		return []*Tenant{}, nil
@@ -188,97 +217,69 @@ func (m *DefaultTenantManager) ListTenants(ctx context.Context, limit, offset in
 
 // ActivateTenant activates a tenant
 func (m *DefaultTenantManager) ActivateTenant(ctx context.Context, tenantID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	tenant, exists := m.tenants[tenantID]
-	if !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
-	}
-
-	tenant.Status = TenantStatusActive
-	tenant.UpdatedAt = time.Now()
-
-	return nil
+	return m.update(tenantID, func(tenant *Tenant) error {
+		tenant.Status = TenantStatusActive
+		return nil
+	})
 }
 
 // SuspendTenant suspends a tenant
 func (m *DefaultTenantManager) SuspendTenant(ctx context.Context, tenantID string, reason string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	tenant, exists := m.tenants[tenantID]
-	if !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
-	}
-
-	tenant.Status = TenantStatusSuspended
-	tenant.UpdatedAt = time.Now()
-	if tenant.Metadata == nil {
-		tenant.Metadata = make(map[string]string)
-	}
-	tenant.Metadata["suspension_reason"] = reason
-	tenant.Metadata["suspended_at"] = time.Now().Format(time.RFC3339)
-
-	return nil
+	return m.update(tenantID, func(tenant *Tenant) error {
+		tenant.Status = TenantStatusSuspended
+		if tenant.Metadata == nil {
+			tenant.Metadata = make(map[string]string)
+		}
+		tenant.Metadata["suspension_reason"] = reason
+		tenant.Metadata["suspended_at"] = time.Now().Format(time.RFC3339)
+		return nil
+	})
 }
 
 // DeactivateTenant deactivates a tenant
 func (m *DefaultTenantManager) DeactivateTenant(ctx context.Context, tenantID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	tenant, exists := m.tenants[tenantID]
-	if !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
-	}
-
-	tenant.Status = TenantStatusInactive
-	tenant.UpdatedAt = time.Now()
-
-	return nil
+	return m.update(tenantID, func(tenant *Tenant) error {
+		tenant.Status = TenantStatusInactive
+		return nil
+	})
 }
 
 // UpdateTenantSettings updates tenant settings
 func (m *DefaultTenantManager) UpdateTenantSettings(ctx context.Context, tenantID string, settings *TenantSettings) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	tenant, exists := m.tenants[tenantID]
-	if !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
+	if settings != nil {
+		if err := validateWebhooks(settings); err != nil {
+			return err
+		}
+		settings = settings.clone()
 	}
-
-	tenant.Settings = settings
-	tenant.UpdatedAt = time.Now()
-
-	return nil
+	return m.update(tenantID, func(tenant *Tenant) error {
+		tenant.Settings = settings
+		return nil
+	})
 }
 
 // GetTenantSettings gets tenant settings
 func (m *DefaultTenantManager) GetTenantSettings(ctx context.Context, tenantID string) (*TenantSettings, error) {
-	tenant, err := m.GetTenant(ctx, tenantID)
-	if err != nil {
-		return nil, err
-	}
-
-	return tenant.Settings, nil
-}
-
-// UpdateTenantQuotas updates tenant quotas
-func (m *DefaultTenantManager) UpdateTenantQuotas(ctx context.Context, tenantID string, quotas *TenantQuotas) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
 	tenant, exists := m.tenants[tenantID]
 	if !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
+	}
+	if tenant.Settings == nil {
+		return nil, nil
 	}
 
-	tenant.Quotas = quotas
-	tenant.UpdatedAt = time.Now()
+	return tenant.Settings.clone(), nil
+}
 
-	return nil
+// UpdateTenantQuotas updates tenant quotas
+func (m *DefaultTenantManager) UpdateTenantQuotas(ctx context.Context, tenantID string, quotas *TenantQuotas) error {
+	return m.update(tenantID, func(tenant *Tenant) error {
+		tenant.Quotas = quotas
+		return nil
+	})
 }
 
 // GetTenantQuotas gets tenant quotas
@@ -316,19 +317,11 @@ func (m *DefaultTenantManager) CheckQuota(ctx context.Context, tenantID string,
 
 // UpdateTenantUsage updates tenant usage
 func (m *DefaultTenantManager) UpdateTenantUsage(ctx context.Context, tenantID string, usage *TenantUsage) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	tenant, exists := m.tenants[tenantID]
-	if !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
-	}
-
-	usage.LastUpdated = time.Now()
-	tenant.Usage = usage
-	tenant.UpdatedAt = time.Now()
-
-	return nil
+	return m.update(tenantID, func(tenant *Tenant) error {
+		usage.LastUpdated = time.Now()
+		tenant.Usage = usage
+		return nil
+	})
 }
 
 // GetTenantUsage gets tenant usage
@@ -343,22 +336,13 @@ func (m *DefaultTenantManager) GetTenantUsage(ctx context.Context, tenantID stri
 
 // ResetUsageCounters resets tenant usage counters
 func (m *DefaultTenantManager) ResetUsageCounters(ctx context.Context, tenantID string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	tenant, exists := m.tenants[tenantID]
-	if !exists {
-		return fmt.Errorf("tenant not found: %s", tenantID)
-	}
-
-	now := time.Now()
-	tenant.Usage.APICallsToday = 0
-	tenant.Usage.APICallsThisHour = 0
-	tenant.Usage.APICallsThisMin = 0
-	tenant.Usage.LastUpdated = now
-	tenant.UpdatedAt = now
-
-	return nil
+	return m.update(tenantID, func(tenant *Tenant) error {
+		tenant.Usage.APICallsToday = 0
+		tenant.Usage.APICallsThisHour = 0
+		tenant.Usage.APICallsThisMin = 0
+		tenant.Usage.LastUpdated = time.Now()
+		return nil
+	})
 }
 
 // HealthCheck performs a health check on the service
@@ -381,8 +365,34 @@ func (m *DefaultTenantManager) Close() error {
 
 // Helper methods
 
+// update changes a stored tenant and saves the tenants, restoring the tenant
+// when they cannot be saved
+func (m *DefaultTenantManager) update(tenantID string, change func(tenant *Tenant) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tenant, exists := m.tenants[tenantID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
+	}
+	previous := tenant.clone()
+	if err := change(tenant); err != nil {
+		m.tenants[tenantID] = previous
+		return err
+	}
+	tenant.UpdatedAt = time.Now()
+	if err := m.save(); err != nil {
+		m.tenants[tenantID] = previous
+		return err
+	}
+	return nil
+}
+
 // validateTenant validates tenant data
 func (m *DefaultTenantManager) validateTenant(tenant *Tenant) error {
+	if !ValidTenantID(tenant.ID) {
+		return fmt.Errorf("tenant ID %q must be 1 to 64 letters, digits, '_' or '-'", tenant.ID)
+	}
 	if tenant.Name == "" {
 		return fmt.Errorf("tenant name is required")
 	}
@@ -394,6 +404,9 @@ func (m *DefaultTenantManager) validateTenant(tenant *Tenant) error {
 		if tenant.Settings.MaxConcurrentUsers <= 0 {
 			return fmt.Errorf("max concurrent users must be positive")
 		}
+		if err := validateWebhooks(tenant.Settings); err != nil {
+			return err
+		}
 	}
 
 	if tenant.Quotas != nil {
@@ -411,8 +424,59 @@ func (m *DefaultTenantManager) validateTenant(tenant *Tenant) error {
 	return nil
 }
 
+// validateWebhooks checks that the webhook URLs of settings are absolute HTTP
+// URLs, and that there is a secret to sign the events sent to them
+func validateWebhooks(settings *TenantSettings) error {
+	for _, raw := range settings.WebhookURLs {
+		target, err := url.Parse(raw)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("%w: webhook URL %q must be an absolute http or https URL", ErrInvalidTenant, raw)
+		}
+	}
+	if len(settings.WebhookURLs) > 0 && settings.WebhookSecret == "" {
+		return fmt.Errorf("%w: webhook URLs need a webhook secret to sign events", ErrInvalidTenant)
+	}
+	return nil
+}
+
 // generateTenantID generates a unique tenant ID
 func (m *DefaultTenantManager) generateTenantID() string {
 	// Simple ID generation (in production, use UUID or similar)
 	return fmt.Sprintf("tenant_%d", time.Now().UnixNano())
 }
+
+// clone returns a copy of a tenant that shares no mutable state with it
+func (t *Tenant) clone() *Tenant {
+	copied := *t
+	if t.ExpiresAt != nil {
+		expiresAt := *t.ExpiresAt
+		copied.ExpiresAt = &expiresAt
+	}
+	if t.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(t.Metadata))
+		for name, value := range t.Metadata {
+			copied.Metadata[name] = value
+		}
+	}
+	if t.Settings != nil {
+		copied.Settings = t.Settings.clone()
+	}
+	if t.Quotas != nil {
+		quotas := *t.Quotas
+		copied.Quotas = &quotas
+	}
+	if t.Usage != nil {
+		usage := *t.Usage
+		copied.Usage = &usage
+	}
+	return &copied
+}
+
+// clone returns a copy of tenant settings
+func (s *TenantSettings) clone() *TenantSettings {
+	copied := *s
+	copied.IPWhitelist = append([]string(nil), s.IPWhitelist...)
+	copied.WebhookURLs = append([]string(nil), s.WebhookURLs...)
+	copied.OAuthProviders = append([]string(nil), s.OAuthProviders...)
+	return &copied
+}
@@ -0,0 +1,82 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// tenantFileVersion is the format version of the tenant file
+const tenantFileVersion = 1
+
+// tenantFile is the on-disk form of the tenants of a tenant manager
+type tenantFile struct {
+	Version int       `json:"version"`
+	Tenants []*Tenant `json:"tenants"`
+}
+
+// OpenTenantManager creates a tenant manager that saves its tenants, with
+// their status, settings and quotas, to the file at path after every change,
+// starting with the tenants saved there
+func OpenTenantManager(path string) (*DefaultTenantManager, error) {
+	m := NewDefaultTenantManager()
+	m.path = path
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+
+	var file tenantFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode tenants: %w", err)
+	}
+	if file.Version != tenantFileVersion {
+		return nil, fmt.Errorf("unsupported tenant file version %d", file.Version)
+	}
+	for _, tenant := range file.Tenants {
+		if tenant == nil || !ValidTenantID(tenant.ID) {
+			return nil, fmt.Errorf("%w in tenant file", ErrInvalidTenant)
+		}
+		m.tenants[tenant.ID] = tenant
+	}
+	return m, nil
+}
+
+// save writes the tenants to the file of the manager, if it has one; the
+// caller must hold the lock
+func (m *DefaultTenantManager) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	file := tenantFile{Version: tenantFileVersion, Tenants: make([]*Tenant, 0, len(m.tenants))}
+	for _, tenant := range m.tenants {
+		file.Tenants = append(file.Tenants, tenant)
+	}
+	sort.Slice(file.Tenants, func(i, j int) bool {
+		return file.Tenants[i].ID < file.Tenants[j].ID
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tenants: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0750); err != nil {
+		return fmt.Errorf("failed to create tenant directory: %w", err)
+	}
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(filepath.Clean(tmpPath), data, 0600); err != nil {
+		return fmt.Errorf("failed to write tenants: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return fmt.Errorf("failed to replace tenants: %w", err)
+	}
+	return nil
+}
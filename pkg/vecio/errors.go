@@ -0,0 +1,13 @@
+package vecio
+
+import "errors"
+
+// Format-related errors
+var (
+	ErrUnsupportedFormat     = errors.New("unsupported vector file format")
+	ErrInvalidDimension      = errors.New("invalid vector dimension")
+	ErrInconsistentDimension = errors.New("vector dimension differs from previous vectors")
+	ErrValueOutOfRange       = errors.New("vector value cannot be represented in the format")
+	ErrMalformedFile         = errors.New("malformed vector file")
+	ErrSeekRequired          = errors.New("npy output requires a seekable writer")
+)
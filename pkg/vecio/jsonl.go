@@ -0,0 +1,92 @@
+package vecio
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// jsonlRecord is a single line of a JSONL vector file
+type jsonlRecord struct {
+	ID        string                 `json:"id,omitempty"`
+	Embedding []float64              `json:"embedding"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Text      string                 `json:"text,omitempty"`
+	ExpiresAt *time.Time             `json:"expires_at,omitempty"`
+}
+
+// jsonlReader reads JSONL vector records
+type jsonlReader struct {
+	decoder   *json.Decoder
+	prefix    string
+	row       int64
+	dimension int
+}
+
+// newJSONLReader creates a JSONL reader
+func newJSONLReader(r *bufio.Reader, opts ReaderOptions) *jsonlReader {
+	return &jsonlReader{decoder: json.NewDecoder(r), prefix: opts.IDPrefix}
+}
+
+// Read reads the next record. Records without an ID are numbered like rows of the binary formats.
+func (j *jsonlReader) Read() (*core.Vector, error) {
+	var record jsonlRecord
+	if err := j.decoder.Decode(&record); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("%w: record %d: %v", ErrMalformedFile, j.row, err)
+	}
+
+	dimension := len(record.Embedding)
+	if dimension == 0 {
+		return nil, fmt.Errorf("%w: record %d has no embedding", ErrInvalidDimension, j.row)
+	}
+	if j.dimension != 0 && dimension != j.dimension {
+		return nil, fmt.Errorf("%w: record %d has dimension %d, expected %d", ErrInconsistentDimension, j.row, dimension, j.dimension)
+	}
+	j.dimension = dimension
+
+	id := record.ID
+	if id == "" {
+		id = rowID(j.prefix, j.row)
+	}
+
+	j.row++
+	vector := newVector(id, record.Embedding, record.Metadata, record.Text)
+	vector.ExpiresAt = record.ExpiresAt
+	return vector, nil
+}
+
+// jsonlWriter writes JSONL vector records
+type jsonlWriter struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+// newJSONLWriter creates a JSONL writer
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buffered := bufio.NewWriterSize(w, 1<<20)
+	return &jsonlWriter{w: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+// Write writes a vector as a single line
+func (j *jsonlWriter) Write(vector *core.Vector) error {
+	return j.encoder.Encode(jsonlRecord{
+		ID:        vector.ID,
+		Embedding: vector.Embedding,
+		Metadata:  vector.Metadata,
+		Text:      vector.Text,
+		ExpiresAt: vector.ExpiresAt,
+	})
+}
+
+// Close flushes buffered records
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,301 @@
+package vecio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+const (
+	npyMagic = "\x93NUMPY"
+
+	// npyHeaderSize is the fixed size of the headers written by this package.
+	// It leaves room for any shape so the header can be patched in place on Close.
+	npyHeaderSize = 128
+
+	// maxNpyHeaderLen guards against reading a corrupt header length as a huge allocation
+	maxNpyHeaderLen = 1 << 20
+)
+
+var (
+	npyDescrPattern   = regexp.MustCompile(`'descr'\s*:\s*'([<>|=]?)([a-z])(\d+)'`)
+	npyFortranPattern = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShapePattern   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// npyReader reads 1-D and 2-D C-ordered float32 or float64 NumPy arrays.
+// Every row of a 2-D array is a vector; a 1-D array is a single vector.
+type npyReader struct {
+	r         *bufio.Reader
+	order     binary.ByteOrder
+	itemSize  int
+	rows      int64
+	dimension int
+	prefix    string
+	row       int64
+	buf       []byte
+}
+
+// newNpyReader parses the array header and returns a reader positioned at the first row
+func newNpyReader(r *bufio.Reader, opts ReaderOptions) (*npyReader, error) {
+	header, err := readNpyHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	descr := npyDescrPattern.FindStringSubmatch(header)
+	if descr == nil {
+		return nil, fmt.Errorf("%w: npy header has no descr", ErrMalformedFile)
+	}
+	if descr[2] != "f" || (descr[3] != "4" && descr[3] != "8") {
+		return nil, fmt.Errorf("%w: npy dtype %s%s%s, expected float32 or float64", ErrUnsupportedFormat, descr[1], descr[2], descr[3])
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if descr[1] == ">" {
+		order = binary.BigEndian
+	}
+
+	fortran := npyFortranPattern.FindStringSubmatch(header)
+	if fortran != nil && fortran[1] == "True" {
+		return nil, fmt.Errorf("%w: Fortran-ordered npy arrays", ErrUnsupportedFormat)
+	}
+
+	shape, err := parseNpyShape(header)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &npyReader{r: r, order: order, prefix: opts.IDPrefix}
+	reader.itemSize, _ = strconv.Atoi(descr[3])
+
+	switch len(shape) {
+	case 1:
+		reader.rows, reader.dimension = 1, int(shape[0])
+	case 2:
+		reader.rows, reader.dimension = shape[0], int(shape[1])
+	default:
+		return nil, fmt.Errorf("%w: npy array has %d dimensions, expected 1 or 2", ErrUnsupportedFormat, len(shape))
+	}
+	if reader.dimension <= 0 && reader.rows > 0 {
+		return nil, fmt.Errorf("%w: npy rows have dimension %d", ErrInvalidDimension, reader.dimension)
+	}
+
+	return reader, nil
+}
+
+// readNpyHeader validates the magic string and returns the header dictionary
+func readNpyHeader(r io.Reader) (string, error) {
+	var preamble [8]byte
+	if _, err := io.ReadFull(r, preamble[:]); err != nil {
+		return "", fmt.Errorf("%w: truncated npy preamble", ErrMalformedFile)
+	}
+	if string(preamble[:6]) != npyMagic {
+		return "", fmt.Errorf("%w: missing npy magic string", ErrMalformedFile)
+	}
+
+	var length int
+	switch major := preamble[6]; major {
+	case 1:
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return "", fmt.Errorf("%w: truncated npy header length", ErrMalformedFile)
+		}
+		length = int(binary.LittleEndian.Uint16(size[:]))
+	case 2, 3:
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return "", fmt.Errorf("%w: truncated npy header length", ErrMalformedFile)
+		}
+		length = int(binary.LittleEndian.Uint32(size[:]))
+	default:
+		return "", fmt.Errorf("%w: npy version %d", ErrUnsupportedFormat, major)
+	}
+	if length > maxNpyHeaderLen {
+		return "", fmt.Errorf("%w: npy header of %d bytes", ErrMalformedFile, length)
+	}
+
+	header := make([]byte, length)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", fmt.Errorf("%w: truncated npy header", ErrMalformedFile)
+	}
+
+	return string(header), nil
+}
+
+// parseNpyShape extracts the shape tuple of an npy header
+func parseNpyShape(header string) ([]int64, error) {
+	match := npyShapePattern.FindStringSubmatch(header)
+	if match == nil {
+		return nil, fmt.Errorf("%w: npy header has no shape", ErrMalformedFile)
+	}
+
+	var shape []int64
+	for _, field := range strings.Split(match[1], ",") {
+		field = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(field), "L"))
+		if field == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("%w: npy shape (%s)", ErrMalformedFile, match[1])
+		}
+		shape = append(shape, value)
+	}
+
+	return shape, nil
+}
+
+// Read reads the next row
+func (n *npyReader) Read() (*core.Vector, error) {
+	if n.row >= n.rows {
+		return nil, io.EOF
+	}
+
+	size := n.dimension * n.itemSize
+	if cap(n.buf) < size {
+		n.buf = make([]byte, size)
+	}
+	buf := n.buf[:size]
+	if _, err := io.ReadFull(n.r, buf); err != nil {
+		return nil, fmt.Errorf("%w: row %d: truncated data", ErrMalformedFile, n.row)
+	}
+
+	embedding := make([]float64, n.dimension)
+	for i := range embedding {
+		if n.itemSize == 4 {
+			embedding[i] = float64(math.Float32frombits(n.order.Uint32(buf[i*4:])))
+		} else {
+			embedding[i] = math.Float64frombits(n.order.Uint64(buf[i*8:]))
+		}
+	}
+
+	vector := newVector(rowID(n.prefix, n.row), embedding, nil, "")
+	n.row++
+	return vector, nil
+}
+
+// npyWriter writes a 2-D C-ordered little-endian float32 or float64 array
+type npyWriter struct {
+	file      io.WriteSeeker
+	w         *bufio.Writer
+	double    bool
+	rows      int64
+	dimension int
+	buf       []byte
+}
+
+// newNpyWriter reserves space for the header, which is written on Close
+func newNpyWriter(w io.WriteSeeker, opts WriterOptions) (*npyWriter, error) {
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if start != 0 {
+		return nil, fmt.Errorf("%w: npy output must start at offset 0", ErrSeekRequired)
+	}
+
+	writer := &npyWriter{file: w, w: bufio.NewWriterSize(w, 1<<20), double: opts.Float64}
+	if _, err := writer.w.Write(make([]byte, npyHeaderSize)); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Write appends a row. IDs, metadata and text are not stored in .npy files.
+func (n *npyWriter) Write(vector *core.Vector) error {
+	dimension := len(vector.Embedding)
+	if dimension == 0 {
+		return fmt.Errorf("%w: vector %s is empty", ErrInvalidDimension, vector.ID)
+	}
+	if n.dimension != 0 && dimension != n.dimension {
+		return fmt.Errorf("%w: vector %s has dimension %d, expected %d", ErrInconsistentDimension, vector.ID, dimension, n.dimension)
+	}
+	n.dimension = dimension
+
+	itemSize := 4
+	if n.double {
+		itemSize = 8
+	}
+	size := dimension * itemSize
+	if cap(n.buf) < size {
+		n.buf = make([]byte, size)
+	}
+	buf := n.buf[:size]
+
+	for i, value := range vector.Embedding {
+		if n.double {
+			binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(value))
+		} else {
+			binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(value)))
+		}
+	}
+
+	if _, err := n.w.Write(buf); err != nil {
+		return err
+	}
+	n.rows++
+	return nil
+}
+
+// Close flushes the rows and writes the header with the final shape
+func (n *npyWriter) Close() error {
+	if err := n.w.Flush(); err != nil {
+		return err
+	}
+
+	header, err := n.header()
+	if err != nil {
+		return err
+	}
+
+	end, err := n.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := n.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := n.file.Write(header); err != nil {
+		return err
+	}
+	_, err = n.file.Seek(end, io.SeekStart)
+	return err
+}
+
+// header encodes a version 1.0 header padded to npyHeaderSize bytes
+func (n *npyWriter) header() ([]byte, error) {
+	descr := "<f4"
+	if n.double {
+		descr = "<f8"
+	}
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d), }", descr, n.rows, n.dimension)
+
+	prefixSize := len(npyMagic) + 4
+	padding := npyHeaderSize - prefixSize - len(dict) - 1
+	if padding < 0 {
+		return nil, errors.New("npy header does not fit the reserved space")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0})
+	var length [2]byte
+	binary.LittleEndian.PutUint16(length[:], uint16(npyHeaderSize-prefixSize))
+	buf.Write(length[:])
+	buf.WriteString(dict)
+	buf.WriteString(strings.Repeat(" ", padding))
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,212 @@
+// Package vecio reads and writes vectors in common dataset formats.
+// It supports the fvecs, bvecs and ivecs formats of the standard ANN benchmark
+// datasets, NumPy .npy float32/float64 arrays and JSONL records.
+package vecio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// Format identifies a vector file format
+type Format string
+
+// Format constants define the supported file formats
+const (
+	FormatFvecs Format = "fvecs" // little-endian int32 dimension followed by float32 components
+	FormatBvecs Format = "bvecs" // little-endian int32 dimension followed by uint8 components
+	FormatIvecs Format = "ivecs" // little-endian int32 dimension followed by int32 components
+	FormatNpy   Format = "npy"   // NumPy array of shape (n, dimension) with float32 or float64 values
+	FormatJSONL Format = "jsonl" // one JSON record with id, embedding, metadata and text per line
+)
+
+// Formats lists every supported format
+var Formats = []Format{FormatFvecs, FormatBvecs, FormatIvecs, FormatNpy, FormatJSONL}
+
+// ParseFormat converts a format name into a Format
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(strings.TrimPrefix(name, ".")))
+	if format == "ndjson" {
+		return FormatJSONL, nil
+	}
+	for _, supported := range Formats {
+		if format == supported {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, name)
+}
+
+// DetectFormat determines the format of a file from its extension
+func DetectFormat(path string) (Format, error) {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return "", fmt.Errorf("%w: %s has no extension", ErrUnsupportedFormat, path)
+	}
+	return ParseFormat(ext)
+}
+
+// Reader reads vectors one at a time.
+// Read returns io.EOF once every vector has been read.
+type Reader interface {
+	Read() (*core.Vector, error)
+}
+
+// Writer writes vectors one at a time.
+// Close flushes buffered data but does not close the underlying writer.
+type Writer interface {
+	Write(vector *core.Vector) error
+	Close() error
+}
+
+// ReaderOptions controls how vectors are read
+type ReaderOptions struct {
+	// IDPrefix is prepended to the row number to form the ID of vectors
+	// whose format carries no ID (all formats but JSONL)
+	IDPrefix string
+}
+
+// WriterOptions controls how vectors are written
+type WriterOptions struct {
+	// Float64 writes .npy files as float64 instead of float32
+	Float64 bool
+}
+
+// NewReader returns a reader for the given format
+func NewReader(r io.Reader, format Format, opts ReaderOptions) (Reader, error) {
+	buffered := bufio.NewReaderSize(r, 1<<20)
+
+	switch format {
+	case FormatFvecs, FormatBvecs, FormatIvecs:
+		return newVecsReader(buffered, format, opts), nil
+	case FormatNpy:
+		return newNpyReader(buffered, opts)
+	case FormatJSONL:
+		return newJSONLReader(buffered, opts), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// NewWriter returns a writer for the given format.
+// The .npy format records the row count in its header, so it requires an
+// io.WriteSeeker that is rewound to patch the header on Close.
+func NewWriter(w io.Writer, format Format, opts WriterOptions) (Writer, error) {
+	switch format {
+	case FormatFvecs, FormatBvecs, FormatIvecs:
+		return newVecsWriter(w, format), nil
+	case FormatNpy:
+		seeker, ok := w.(io.WriteSeeker)
+		if !ok {
+			return nil, ErrSeekRequired
+		}
+		return newNpyWriter(seeker, opts)
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// FileReader is a Reader over an open file
+type FileReader struct {
+	Reader
+	file *os.File
+}
+
+// Close closes the underlying file
+func (r *FileReader) Close() error {
+	return r.file.Close()
+}
+
+// Size returns the size of the underlying file in bytes
+func (r *FileReader) Size() (int64, error) {
+	info, err := r.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Open opens a vector file. An empty format is detected from the file extension.
+func Open(path string, format Format, opts ReaderOptions) (*FileReader, error) {
+	if format == "" {
+		detected, err := DetectFormat(path)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := NewReader(file, format, opts)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &FileReader{Reader: reader, file: file}, nil
+}
+
+// FileWriter is a Writer over a file it owns
+type FileWriter struct {
+	Writer
+	file *os.File
+}
+
+// Close flushes the writer and closes the underlying file
+func (w *FileWriter) Close() error {
+	writeErr := w.Writer.Close()
+	if err := w.file.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	return writeErr
+}
+
+// Create creates a vector file, truncating it if it exists.
+// An empty format is detected from the file extension.
+func Create(path string, format Format, opts WriterOptions) (*FileWriter, error) {
+	if format == "" {
+		detected, err := DetectFormat(path)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := NewWriter(file, format, opts)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &FileWriter{Writer: writer, file: file}, nil
+}
+
+// rowID returns the ID of the row-th vector of a file without IDs
+func rowID(prefix string, row int64) string {
+	return prefix + strconv.FormatInt(row, 10)
+}
+
+// newVector builds a vector read from a file
+func newVector(id string, embedding []float64, metadata map[string]interface{}, text string) *core.Vector {
+	vector := core.NewVector("", embedding, text, metadata)
+	vector.ID = id
+	return vector
+}
@@ -0,0 +1,232 @@
+package vecio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+func testVectors() []*core.Vector {
+	return []*core.Vector{
+		{ID: "a", Embedding: []float64{1, 2, 3}, Metadata: map[string]interface{}{"label": "first"}, Text: "alpha"},
+		{ID: "b", Embedding: []float64{4, 5, 6}},
+		{ID: "c", Embedding: []float64{7, 8, 255}},
+	}
+}
+
+func readAll(t *testing.T, reader Reader) []*core.Vector {
+	t.Helper()
+
+	var vectors []*core.Vector
+	for {
+		vector, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return vectors
+		}
+		if err != nil {
+			t.Fatalf("Failed to read vector: %v", err)
+		}
+		vectors = append(vectors, vector)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vectors."+string(format))
+
+			writer, err := Create(path, "", WriterOptions{})
+			if err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+			for _, vector := range testVectors() {
+				if err := writer.Write(vector); err != nil {
+					t.Fatalf("Failed to write vector: %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Failed to close writer: %v", err)
+			}
+
+			reader, err := Open(path, "", ReaderOptions{IDPrefix: "row_"})
+			if err != nil {
+				t.Fatalf("Failed to open file: %v", err)
+			}
+			defer func() {
+				if err := reader.Close(); err != nil {
+					t.Errorf("Failed to close reader: %v", err)
+				}
+			}()
+
+			vectors := readAll(t, reader)
+			expected := testVectors()
+			if len(vectors) != len(expected) {
+				t.Fatalf("Expected %d vectors, got %d", len(expected), len(vectors))
+			}
+
+			for i, vector := range vectors {
+				for j, value := range expected[i].Embedding {
+					if vector.Embedding[j] != value {
+						t.Errorf("Vector %d component %d: expected %v, got %v", i, j, value, vector.Embedding[j])
+					}
+				}
+				if vector.Dimension != 3 || vector.Magnitude == 0 {
+					t.Errorf("Vector %d was not initialized: %+v", i, vector)
+				}
+
+				if format == FormatJSONL {
+					if vector.ID != expected[i].ID || vector.Text != expected[i].Text {
+						t.Errorf("Vector %d lost its ID or text: %+v", i, vector)
+					}
+				} else if vector.ID != rowID("row_", int64(i)) {
+					t.Errorf("Vector %d: expected generated ID, got %s", i, vector.ID)
+				}
+			}
+
+			if format == FormatJSONL && vectors[0].Metadata["label"] != "first" {
+				t.Errorf("Metadata was not preserved: %+v", vectors[0].Metadata)
+			}
+		})
+	}
+}
+
+func TestFvecsLayout(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, FormatFvecs, WriterOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.Write(&core.Vector{ID: "x", Embedding: []float64{0.5, -1}}); err != nil {
+		t.Fatalf("Failed to write vector: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	data := buf.Bytes()
+	if len(data) != 12 {
+		t.Fatalf("Expected 12 bytes, got %d", len(data))
+	}
+	if binary.LittleEndian.Uint32(data) != 2 {
+		t.Errorf("Expected dimension prefix 2, got %d", binary.LittleEndian.Uint32(data))
+	}
+	if math.Float32frombits(binary.LittleEndian.Uint32(data[8:])) != -1 {
+		t.Errorf("Unexpected second component")
+	}
+}
+
+func TestNpyNumPyHeader(t *testing.T) {
+	// A header as written by numpy.save for a (2, 2) float64 array
+	dict := "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 2), }"
+	headerLen := 128 - 10
+	header := dict + string(bytes.Repeat([]byte(" "), headerLen-len(dict)-1)) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0, byte(headerLen), 0})
+	buf.WriteString(header)
+	for _, value := range []float64{1.5, 2.5, 3.5, 4.5} {
+		var item [8]byte
+		binary.LittleEndian.PutUint64(item[:], math.Float64bits(value))
+		buf.Write(item[:])
+	}
+
+	reader, err := NewReader(&buf, FormatNpy, ReaderOptions{})
+	if err != nil {
+		t.Fatalf("Failed to parse header: %v", err)
+	}
+	vectors := readAll(t, reader)
+	if len(vectors) != 2 || vectors[1].Embedding[0] != 3.5 || vectors[1].ID != "1" {
+		t.Errorf("Unexpected vectors: %+v", vectors)
+	}
+}
+
+func TestNpyFloat64Writer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.npy")
+
+	writer, err := Create(path, FormatNpy, WriterOptions{Float64: true})
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	precise := []float64{0.1, math.Pi}
+	if err := writer.Write(&core.Vector{ID: "p", Embedding: precise}); err != nil {
+		t.Fatalf("Failed to write vector: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	reader, err := Open(path, "", ReaderOptions{})
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	vectors := readAll(t, reader)
+	if len(vectors) != 1 || vectors[0].Embedding[1] != math.Pi {
+		t.Errorf("float64 values were not preserved: %+v", vectors)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := DetectFormat("vectors.csv"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+	if format, err := DetectFormat("data.ndjson"); err != nil || format != FormatJSONL {
+		t.Errorf("Expected ndjson to map to jsonl, got %s, %v", format, err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := NewWriter(&buf, FormatNpy, WriterOptions{}); !errors.Is(err, ErrSeekRequired) {
+		t.Errorf("Expected ErrSeekRequired, got %v", err)
+	}
+
+	writer, err := NewWriter(&buf, FormatBvecs, WriterOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.Write(&core.Vector{ID: "x", Embedding: []float64{0.5}}); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Expected ErrValueOutOfRange, got %v", err)
+	}
+
+	writer, err = NewWriter(&buf, FormatJSONL, WriterOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if err := writer.Write(&core.Vector{ID: "x", Embedding: []float64{1, 2}}); err != nil {
+		t.Fatalf("Failed to write vector: %v", err)
+	}
+	if err := writer.Write(&core.Vector{ID: "y", Embedding: []float64{1}}); err != nil {
+		t.Fatalf("Failed to write vector: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	reader, err := NewReader(&buf, FormatJSONL, ReaderOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	if _, err := reader.Read(); err != nil {
+		t.Fatalf("Failed to read first record: %v", err)
+	}
+	if _, err := reader.Read(); !errors.Is(err, ErrInconsistentDimension) {
+		t.Errorf("Expected ErrInconsistentDimension, got %v", err)
+	}
+
+	truncated := []byte{3, 0, 0, 0, 1, 2}
+	reader, err = NewReader(bytes.NewReader(truncated), FormatBvecs, ReaderOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	if _, err := reader.Read(); !errors.Is(err, ErrMalformedFile) {
+		t.Errorf("Expected ErrMalformedFile, got %v", err)
+	}
+}
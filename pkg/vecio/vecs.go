@@ -0,0 +1,140 @@
+package vecio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+)
+
+// maxVecsDimension guards against reading a corrupt dimension as a huge allocation
+const maxVecsDimension = 1 << 20
+
+// vecsReader reads the fvecs, bvecs and ivecs formats
+type vecsReader struct {
+	r         *bufio.Reader
+	format    Format
+	prefix    string
+	row       int64
+	dimension int
+	buf       []byte
+}
+
+// newVecsReader creates a reader for one of the *vecs formats
+func newVecsReader(r *bufio.Reader, format Format, opts ReaderOptions) *vecsReader {
+	return &vecsReader{r: r, format: format, prefix: opts.IDPrefix}
+}
+
+// componentSize returns the number of bytes per component of a *vecs format
+func componentSize(format Format) int {
+	if format == FormatBvecs {
+		return 1
+	}
+	return 4
+}
+
+// Read reads the next vector
+func (v *vecsReader) Read() (*core.Vector, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(v.r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("%w: row %d: truncated dimension", ErrMalformedFile, v.row)
+	}
+
+	dimension := int(int32(binary.LittleEndian.Uint32(header[:])))
+	if dimension <= 0 || dimension > maxVecsDimension {
+		return nil, fmt.Errorf("%w: row %d has dimension %d", ErrInvalidDimension, v.row, dimension)
+	}
+	if v.dimension != 0 && dimension != v.dimension {
+		return nil, fmt.Errorf("%w: row %d has dimension %d, expected %d", ErrInconsistentDimension, v.row, dimension, v.dimension)
+	}
+	v.dimension = dimension
+
+	size := dimension * componentSize(v.format)
+	if cap(v.buf) < size {
+		v.buf = make([]byte, size)
+	}
+	buf := v.buf[:size]
+	if _, err := io.ReadFull(v.r, buf); err != nil {
+		return nil, fmt.Errorf("%w: row %d: truncated components", ErrMalformedFile, v.row)
+	}
+
+	embedding := make([]float64, dimension)
+	for i := range embedding {
+		switch v.format {
+		case FormatFvecs:
+			embedding[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:])))
+		case FormatIvecs:
+			embedding[i] = float64(int32(binary.LittleEndian.Uint32(buf[i*4:])))
+		default:
+			embedding[i] = float64(buf[i])
+		}
+	}
+
+	vector := newVector(rowID(v.prefix, v.row), embedding, nil, "")
+	v.row++
+	return vector, nil
+}
+
+// vecsWriter writes the fvecs, bvecs and ivecs formats
+type vecsWriter struct {
+	w         *bufio.Writer
+	format    Format
+	dimension int
+	buf       []byte
+}
+
+// newVecsWriter creates a writer for one of the *vecs formats
+func newVecsWriter(w io.Writer, format Format) *vecsWriter {
+	return &vecsWriter{w: bufio.NewWriterSize(w, 1<<20), format: format}
+}
+
+// Write writes a vector. The formats store no IDs or metadata, so only the embedding is kept.
+func (v *vecsWriter) Write(vector *core.Vector) error {
+	dimension := len(vector.Embedding)
+	if dimension == 0 {
+		return fmt.Errorf("%w: vector %s is empty", ErrInvalidDimension, vector.ID)
+	}
+	if v.dimension != 0 && dimension != v.dimension {
+		return fmt.Errorf("%w: vector %s has dimension %d, expected %d", ErrInconsistentDimension, vector.ID, dimension, v.dimension)
+	}
+	v.dimension = dimension
+
+	size := 4 + dimension*componentSize(v.format)
+	if cap(v.buf) < size {
+		v.buf = make([]byte, size)
+	}
+	buf := v.buf[:size]
+	binary.LittleEndian.PutUint32(buf, uint32(dimension))
+
+	for i, value := range vector.Embedding {
+		switch v.format {
+		case FormatFvecs:
+			binary.LittleEndian.PutUint32(buf[4+i*4:], math.Float32bits(float32(value)))
+		case FormatIvecs:
+			if value != math.Trunc(value) || value < math.MinInt32 || value > math.MaxInt32 {
+				return fmt.Errorf("%w: vector %s component %d is %v, ivecs holds int32", ErrValueOutOfRange, vector.ID, i, value)
+			}
+			binary.LittleEndian.PutUint32(buf[4+i*4:], uint32(int32(value)))
+		default:
+			if value != math.Trunc(value) || value < 0 || value > math.MaxUint8 {
+				return fmt.Errorf("%w: vector %s component %d is %v, bvecs holds uint8", ErrValueOutOfRange, vector.ID, i, value)
+			}
+			buf[4+i] = byte(value)
+		}
+	}
+
+	_, err := v.w.Write(buf)
+	return err
+}
+
+// Close flushes buffered vectors
+func (v *vecsWriter) Close() error {
+	return v.w.Flush()
+}
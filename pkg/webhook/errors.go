@@ -0,0 +1,17 @@
+package webhook
+
+import "errors"
+
+// Webhook-related errors
+var (
+	ErrInvalidConfig    = errors.New("invalid webhook dispatcher configuration")
+	ErrDispatcherClosed = errors.New("webhook dispatcher is closed")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrTargetRemoved    = errors.New("webhook URL is no longer configured")
+	ErrQueueFull        = errors.New("webhook delivery queue is full")
+	ErrInterrupted      = errors.New("webhook delivery was interrupted by a shutdown")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp is outside the tolerance")
+	ErrDeliveryRejected = errors.New("webhook receiver rejected the delivery")
+	ErrDeliveryFailed   = errors.New("webhook receiver failed the delivery")
+)
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request headers of every delivery
+const (
+	// SignatureHeader holds the time a delivery was sent and the HMAC-SHA256
+	// of the time and body, as "t=<unix seconds>,v1=<hex digest>"
+	SignatureHeader = "X-VJVector-Signature"
+
+	// EventHeader holds the type of the delivered event
+	EventHeader = "X-VJVector-Event"
+
+	// DeliveryHeader holds the ID of the delivery, which stays the same
+	// across its attempts
+	DeliveryHeader = "X-VJVector-Delivery"
+)
+
+// Sign returns the signature header of a body sent at a time, which is the
+// HMAC-SHA256 with the secret of "<unix seconds>.<body>"
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(digest(secret, unix, body))
+}
+
+// Verify checks the signature header of a body received from the dispatcher.
+// A positive tolerance also rejects signatures made longer ago than it, so
+// that a captured delivery cannot be replayed later.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidSignature)
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, digest(secret, unix, body)) {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(seconds, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+// digest returns the HMAC-SHA256 of a signed timestamp and body
+func digest(secret, unix string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
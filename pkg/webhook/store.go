@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// deadLetterFile is the name of the file dead letters are kept in
+const deadLetterFile = "dead_letters.json"
+
+// save atomically writes the dead letters; the caller must hold the mutex
+func (d *Dispatcher) save() error {
+	if d.config.Dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(d.dead)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letters: %w", err)
+	}
+
+	path := filepath.Join(d.config.Dir, deadLetterFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write dead letters: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace dead letters: %w", err)
+	}
+	return nil
+}
+
+// load reads the dead letters kept in the directory
+func (d *Dispatcher) load() error {
+	data, err := os.ReadFile(filepath.Join(d.config.Dir, deadLetterFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	if err := json.Unmarshal(data, &d.dead); err != nil {
+		return fmt.Errorf("failed to decode dead letters: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,550 @@
+// Package webhook delivers events to the HTTP endpoints of tenants. Every
+// event is a JSON document signed with an HMAC of the secret of the tenant.
+// Failed deliveries are retried with exponential backoff until they run out
+// of attempts, when they are kept in a dead-letter store to be redelivered.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types sent by the API server
+const (
+	// EventVectorsBulk reports a bulk write of vectors to a collection
+	EventVectorsBulk = "vectors.bulk"
+
+	// EventJobCompleted reports a job that finished, whatever its outcome
+	EventJobCompleted = "job.completed"
+
+	// EventQuotaExceeded reports a request rejected by a quota of the tenant
+	EventQuotaExceeded = "quota.exceeded"
+
+	// EventIndexRebuilt reports a collection whose index was rebuilt
+	EventIndexRebuilt = "index.rebuilt"
+)
+
+// Event is the body of a delivery
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	TenantID  string          `json:"tenant_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Target is a webhook endpoint of a tenant with the secret its events are
+// signed with
+type Target struct {
+	URL    string
+	Secret string
+}
+
+// TargetFunc returns the webhook endpoints of a tenant. It is called for every
+// attempt, so that changes to them apply to deliveries being retried.
+type TargetFunc func(tenantID string) ([]Target, error)
+
+// DeliveryState is the stage of its lifecycle a delivery is in
+type DeliveryState string
+
+// Delivery states
+const (
+	DeliveryPending      DeliveryState = "pending"
+	DeliveryDelivered    DeliveryState = "delivered"
+	DeliveryDeadLettered DeliveryState = "dead_lettered"
+)
+
+// Delivery is the record of an event sent to one endpoint
+type Delivery struct {
+	ID        string        `json:"id"`
+	EventID   string        `json:"event_id"`
+	EventType string        `json:"event_type"`
+	TenantID  string        `json:"tenant_id"`
+	URL       string        `json:"url"`
+	State     DeliveryState `json:"state"`
+	Attempts  int           `json:"attempts"`
+
+	// StatusCode is the HTTP status of the last attempt, and Error why the
+	// last attempt failed
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	Payload json.RawMessage `json:"payload"`
+
+	CreatedAt     time.Time  `json:"created_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// clone returns a copy of the delivery that shares none of its mutable fields
+func (d *Delivery) clone() *Delivery {
+	copied := *d
+	for _, at := range []**time.Time{&copied.LastAttemptAt, &copied.NextAttemptAt, &copied.DeliveredAt} {
+		if *at != nil {
+			value := **at
+			*at = &value
+		}
+	}
+	return &copied
+}
+
+// Config holds configuration parameters for the webhook dispatcher
+type Config struct {
+	// Dir is the directory dead letters are kept in; they are only kept in
+	// memory when it is empty
+	Dir string `json:"dir"`
+
+	// Workers is the number of deliveries attempted at once
+	Workers int `json:"workers"`
+
+	// QueueSize is the number of deliveries waiting for a worker; deliveries
+	// beyond it are dead-lettered at once
+	QueueSize int `json:"queue_size"`
+
+	// MaxAttempts is the number of attempts of a delivery before it is
+	// dead-lettered
+	MaxAttempts int `json:"max_attempts"`
+
+	// InitialBackoff is the wait before the first retry, which doubles with
+	// every further retry up to MaxBackoff
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+
+	// Timeout bounds every attempt
+	Timeout time.Duration `json:"timeout"`
+
+	// LogSize is the number of recent deliveries kept in the delivery log, and
+	// MaxDeadLetters the number of dead letters kept
+	LogSize        int `json:"log_size"`
+	MaxDeadLetters int `json:"max_dead_letters"`
+}
+
+// DefaultConfig returns the dispatcher configuration used by the API server,
+// keeping dead letters in dir
+func DefaultConfig(dir string) Config {
+	return Config{
+		Dir:            dir,
+		Workers:        4,
+		QueueSize:      1000,
+		MaxAttempts:    6,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Minute,
+		Timeout:        10 * time.Second,
+		LogSize:        1000,
+		MaxDeadLetters: 10000,
+	}
+}
+
+// Dispatcher delivers the events of tenants to their webhook endpoints
+type Dispatcher struct {
+	config  Config
+	targets TargetFunc
+	client  *http.Client
+	logger  *slog.Logger
+
+	// ctx is canceled when the dispatcher is closed, which stops every
+	// worker and retry
+	ctx   context.Context
+	stop  context.CancelFunc
+	queue chan *Delivery
+	wg    sync.WaitGroup
+
+	mutex   sync.Mutex
+	log     []*Delivery
+	pending map[string]*Delivery
+	dead    []*Delivery
+	closed  bool
+}
+
+// NewDispatcher creates a webhook dispatcher sending events to the endpoints
+// returned by targets, loading the dead letters kept in the configured
+// directory
+func NewDispatcher(config Config, targets TargetFunc, logger *slog.Logger) (*Dispatcher, error) {
+	if config.Workers <= 0 || config.QueueSize <= 0 || config.MaxAttempts <= 0 || config.InitialBackoff < 0 ||
+		config.MaxBackoff < config.InitialBackoff || config.Timeout <= 0 || config.LogSize <= 0 ||
+		config.MaxDeadLetters <= 0 || targets == nil {
+		return nil, ErrInvalidConfig
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		config:  config,
+		targets: targets,
+		client:  &http.Client{Timeout: config.Timeout},
+		logger:  logger,
+		ctx:     ctx,
+		stop:    stop,
+		queue:   make(chan *Delivery, config.QueueSize),
+		pending: make(map[string]*Delivery),
+	}
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0700); err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create webhook directory: %w", err)
+		}
+		if err := d.load(); err != nil {
+			stop()
+			return nil, err
+		}
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d, nil
+}
+
+// Publish sends an event of a tenant with data, which must encode to JSON, to
+// every webhook endpoint of the tenant. It returns once the deliveries are
+// queued.
+func (d *Dispatcher) Publish(tenantID, eventType string, data interface{}) (*Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event data: %w", err)
+	}
+	event := &Event{
+		ID:        "evt_" + uuid.NewString(),
+		Type:      eventType,
+		TenantID:  tenantID,
+		CreatedAt: time.Now().UTC(),
+		Data:      encoded,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	targets, err := d.targets(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return nil, ErrDispatcherClosed
+	}
+	for _, target := range targets {
+		delivery := &Delivery{
+			ID:        "dlv_" + uuid.NewString(),
+			EventID:   event.ID,
+			EventType: event.Type,
+			TenantID:  tenantID,
+			URL:       target.URL,
+			State:     DeliveryPending,
+			Payload:   payload,
+			CreatedAt: event.CreatedAt,
+		}
+		d.record(delivery)
+		d.enqueue(delivery)
+	}
+	return event, nil
+}
+
+// Deliveries returns the recent deliveries of a tenant, newest first, with
+// only those in a state unless it is empty
+func (d *Dispatcher) Deliveries(tenantID string, state DeliveryState, limit, offset int) []*Delivery {
+	d.mutex.Lock()
+	deliveries := make([]*Delivery, 0, len(d.log))
+	for i := len(d.log) - 1; i >= 0; i-- {
+		if delivery := d.log[i]; delivery.TenantID == tenantID && (state == "" || delivery.State == state) {
+			deliveries = append(deliveries, delivery.clone())
+		}
+	}
+	d.mutex.Unlock()
+	return page(deliveries, limit, offset)
+}
+
+// DeadLetters returns the dead-lettered deliveries of a tenant, newest first
+func (d *Dispatcher) DeadLetters(tenantID string, limit, offset int) []*Delivery {
+	d.mutex.Lock()
+	deliveries := make([]*Delivery, 0, len(d.dead))
+	for i := len(d.dead) - 1; i >= 0; i-- {
+		if delivery := d.dead[i]; delivery.TenantID == tenantID {
+			deliveries = append(deliveries, delivery.clone())
+		}
+	}
+	d.mutex.Unlock()
+	return page(deliveries, limit, offset)
+}
+
+// Redeliver takes a dead letter of a tenant out of the dead-letter store and
+// attempts its delivery again, with a fresh set of attempts
+func (d *Dispatcher) Redeliver(tenantID, id string) (*Delivery, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.closed {
+		return nil, ErrDispatcherClosed
+	}
+	index := -1
+	for i, delivery := range d.dead {
+		if delivery.ID == id && delivery.TenantID == tenantID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	}
+
+	delivery := d.dead[index]
+	d.dead = append(d.dead[:index], d.dead[index+1:]...)
+	if err := d.save(); err != nil {
+		d.logger.Error("Failed to save webhook dead letters", "error", err)
+	}
+
+	delivery.State = DeliveryPending
+	delivery.Attempts = 0
+	delivery.StatusCode = 0
+	delivery.Error = ""
+	delivery.NextAttemptAt = nil
+	d.record(delivery)
+	d.enqueue(delivery)
+	return delivery.clone(), nil
+}
+
+// Close stops the dispatcher, waiting for the attempts in flight. Deliveries
+// that were not made by then are dead-lettered, so that they can be
+// redelivered once the dead letters are loaded again.
+func (d *Dispatcher) Close() error {
+	d.mutex.Lock()
+	if d.closed {
+		d.mutex.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mutex.Unlock()
+
+	d.stop()
+	d.wg.Wait()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, delivery := range d.pending {
+		d.deadLetter(delivery, ErrInterrupted.Error())
+	}
+	return nil
+}
+
+// record adds a delivery to the delivery log unless it is in it, dropping the
+// oldest deliveries beyond the log size; the caller must hold the mutex
+func (d *Dispatcher) record(delivery *Delivery) {
+	for _, logged := range d.log {
+		if logged == delivery {
+			return
+		}
+	}
+	d.log = append(d.log, delivery)
+	if excess := len(d.log) - d.config.LogSize; excess > 0 {
+		d.log = append(d.log[:0:0], d.log[excess:]...)
+	}
+}
+
+// enqueue hands a pending delivery to the workers, dead-lettering it when the
+// queue is full; the caller must hold the mutex
+func (d *Dispatcher) enqueue(delivery *Delivery) {
+	d.pending[delivery.ID] = delivery
+	select {
+	case d.queue <- delivery:
+	default:
+		d.deadLetter(delivery, ErrQueueFull.Error())
+	}
+}
+
+// deadLetter moves a delivery to the dead-letter store; the caller must hold
+// the mutex
+func (d *Dispatcher) deadLetter(delivery *Delivery, reason string) {
+	delete(d.pending, delivery.ID)
+	delivery.State = DeliveryDeadLettered
+	delivery.Error = reason
+	delivery.NextAttemptAt = nil
+
+	d.dead = append(d.dead, delivery)
+	if excess := len(d.dead) - d.config.MaxDeadLetters; excess > 0 {
+		d.dead = append(d.dead[:0:0], d.dead[excess:]...)
+	}
+	if err := d.save(); err != nil {
+		d.logger.Error("Failed to save webhook dead letters", "error", err)
+	}
+	d.logger.Warn("Webhook delivery dead-lettered", "delivery", delivery.ID, "tenant_id", delivery.TenantID,
+		"url", delivery.URL, "attempts", delivery.Attempts, "error", reason)
+}
+
+// work attempts queued deliveries until the dispatcher is closed
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case delivery := <-d.queue:
+			d.attempt(delivery)
+		}
+	}
+}
+
+// attempt sends a delivery once, and then records it as delivered, schedules
+// a retry or dead-letters it
+func (d *Dispatcher) attempt(delivery *Delivery) {
+	status, err := d.send(delivery)
+	if d.ctx.Err() != nil {
+		// Close dead-letters the delivery without counting the aborted attempt
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.StatusCode = status
+	if err == nil {
+		delete(d.pending, delivery.ID)
+		delivery.State = DeliveryDelivered
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.Error = err.Error()
+	if !retryable(status, err) || delivery.Attempts >= d.config.MaxAttempts {
+		d.deadLetter(delivery, err.Error())
+		return
+	}
+	wait := d.backoff(delivery.Attempts)
+	next := now.Add(wait)
+	delivery.NextAttemptAt = &next
+
+	d.wg.Add(1)
+	go d.retry(delivery, wait)
+}
+
+// retry queues a delivery again after a wait
+func (d *Dispatcher) retry(delivery *Delivery, wait time.Duration) {
+	defer d.wg.Done()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-d.ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if !d.closed {
+		delivery.NextAttemptAt = nil
+		d.enqueue(delivery)
+	}
+}
+
+// send posts the payload of a delivery to its endpoint, signed with the
+// current secret of the endpoint, and returns the status of the response
+func (d *Dispatcher) send(delivery *Delivery) (int, error) {
+	targets, err := d.targets(delivery.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	var target *Target
+	for i := range targets {
+		if targets[i].URL == delivery.URL {
+			target = &targets[i]
+			break
+		}
+	}
+	if target == nil {
+		return 0, ErrTargetRemoved
+	}
+
+	request, err := http.NewRequestWithContext(d.ctx, http.MethodPost, target.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "vjvector-webhooks/1.0")
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	request.Header.Set(SignatureHeader, Sign(target.Secret, time.Now(), delivery.Payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return response.StatusCode, nil
+	case response.StatusCode >= 500:
+		return response.StatusCode, fmt.Errorf("%w: %s", ErrDeliveryFailed, response.Status)
+	default:
+		return response.StatusCode, fmt.Errorf("%w: %s", ErrDeliveryRejected, response.Status)
+	}
+}
+
+// backoff returns the wait before the retry that follows an attempt: the
+// initial backoff doubled for every earlier retry up to the maximum, of which
+// a random half is waited so that retries of many deliveries spread out
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.config.InitialBackoff
+	for i := 1; i < attempts && wait < d.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, d.config.MaxBackoff)
+	if wait < 2 {
+		return wait
+	}
+	return wait/2 + rand.N(wait/2)
+}
+
+// retryable reports whether a failed attempt is worth retrying: network
+// errors, timeouts, throttling and server errors are, while a receiver that
+// rejects a delivery will reject it again
+func retryable(status int, err error) bool {
+	switch {
+	case status == 0:
+		return !errors.Is(err, ErrTargetRemoved)
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= 500
+	}
+}
+
+// page returns a page of deliveries sorted newest first
+func page(deliveries []*Delivery, limit, offset int) []*Delivery {
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+
+	if offset >= len(deliveries) {
+		return []*Delivery{}
+	}
+	end := len(deliveries)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return deliveries[offset:end]
+}
@@ -0,0 +1,261 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cret"
+
+// receiver is an httptest endpoint answering deliveries with the statuses it
+// is given in turn, and then with 204
+type receiver struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mutex.Lock()
+		r.bodies = append(r.bodies, body)
+		r.headers = append(r.headers, req.Header.Clone())
+		status := http.StatusNoContent
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mutex.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// received returns the number of requests the receiver got
+func (r *receiver) received() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.bodies)
+}
+
+func newTestDispatcher(t *testing.T, dir string, targets TargetFunc) *Dispatcher {
+	t.Helper()
+
+	config := DefaultConfig(dir)
+	config.MaxAttempts = 3
+	config.InitialBackoff = time.Millisecond
+	config.MaxBackoff = 4 * time.Millisecond
+	d, err := NewDispatcher(config, targets, nil)
+	if err != nil {
+		t.Fatalf("Failed to create dispatcher: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+// targetsOf returns a TargetFunc sending the events of acme to urls
+func targetsOf(urls ...string) TargetFunc {
+	return func(tenantID string) ([]Target, error) {
+		if tenantID != "acme" {
+			return nil, nil
+		}
+		targets := make([]Target, len(urls))
+		for i, url := range urls {
+			targets[i] = Target{URL: url, Secret: testSecret}
+		}
+		return targets, nil
+	}
+}
+
+// waitState polls the deliveries of acme until one is in a state
+func waitState(t *testing.T, d *Dispatcher, state DeliveryState) *Delivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if deliveries := d.Deliveries("acme", state, 1, 0); len(deliveries) > 0 {
+			return deliveries[0]
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("No delivery became %s", state)
+	return nil
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	d := newTestDispatcher(t, "", targetsOf(r.URL))
+
+	event, err := d.Publish("acme", EventJobCompleted, map[string]string{"job_id": "job_1"})
+	if err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+	if _, err := d.Publish("other", EventJobCompleted, nil); err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+
+	// The delivery is retried past the failures of the receiver
+	delivery := waitState(t, d, DeliveryDelivered)
+	if delivery.Attempts != 3 || delivery.StatusCode != http.StatusNoContent || delivery.EventID != event.ID {
+		t.Errorf("Unexpected delivery %+v", delivery)
+	}
+	if r.received() != 3 {
+		t.Fatalf("Expected 3 requests, got %d", r.received())
+	}
+
+	body, header := r.bodies[2], r.headers[2]
+	if err := Verify(testSecret, header.Get(SignatureHeader), body, time.Minute); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+	if err := Verify("wrong", header.Get(SignatureHeader), body, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for another secret, got %v", err)
+	}
+	if header.Get(EventHeader) != EventJobCompleted || header.Get(DeliveryHeader) != delivery.ID {
+		t.Errorf("Unexpected headers %v", header)
+	}
+
+	var received Event
+	if err := json.Unmarshal(body, &received); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if received.ID != event.ID || received.TenantID != "acme" || string(received.Data) != `{"job_id":"job_1"}` {
+		t.Errorf("Unexpected event %+v", received)
+	}
+
+	// Tenants without endpoints have no deliveries
+	if deliveries := d.Deliveries("other", "", 10, 0); len(deliveries) != 0 {
+		t.Errorf("Expected no deliveries for other, got %v", deliveries)
+	}
+}
+
+func TestDispatcher_DeadLetters(t *testing.T) {
+	r := newReceiver(t, http.StatusBadRequest, http.StatusInternalServerError, http.StatusInternalServerError,
+		http.StatusInternalServerError)
+	dir := t.TempDir()
+	d := newTestDispatcher(t, dir, targetsOf(r.URL))
+
+	// A receiver rejecting a delivery is not retried
+	if _, err := d.Publish("acme", EventVectorsBulk, nil); err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+	rejected := waitState(t, d, DeliveryDeadLettered)
+	if rejected.Attempts != 1 || rejected.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a rejected delivery after 1 attempt, got %+v", rejected)
+	}
+
+	// A failing receiver is retried up to the maximum attempts
+	if _, err := d.Publish("acme", EventIndexRebuilt, nil); err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.DeadLetters("acme", 0, 0)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(2 * time.Millisecond)
+	}
+	dead := d.DeadLetters("acme", 0, 0)
+	if len(dead) != 2 || dead[0].Attempts != 3 || dead[0].EventType != EventIndexRebuilt {
+		t.Fatalf("Expected 2 dead letters, newest after 3 attempts, got %+v", dead)
+	}
+
+	// Dead letters outlive the dispatcher and can be redelivered
+	if err := d.Close(); err != nil {
+		t.Fatalf("Failed to close dispatcher: %v", err)
+	}
+	d = newTestDispatcher(t, dir, targetsOf(r.URL))
+	if _, err := d.Redeliver("other", dead[0].ID); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("Expected ErrDeliveryNotFound for another tenant, got %v", err)
+	}
+	redelivered, err := d.Redeliver("acme", dead[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to redeliver: %v", err)
+	}
+	if redelivered.State != DeliveryPending || redelivered.Attempts != 0 {
+		t.Errorf("Unexpected redelivery %+v", redelivered)
+	}
+	if delivered := waitState(t, d, DeliveryDelivered); delivered.ID != dead[0].ID || delivered.Attempts != 1 {
+		t.Errorf("Unexpected delivery %+v", delivered)
+	}
+	if dead := d.DeadLetters("acme", 0, 0); len(dead) != 1 || dead[0].ID != rejected.ID {
+		t.Errorf("Expected the rejected delivery to stay dead-lettered, got %+v", dead)
+	}
+}
+
+func TestDispatcher_RemovedTargetsAndShutdown(t *testing.T) {
+	var removed atomic.Bool
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-blocked
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	defer close(blocked)
+
+	targets := targetsOf(server.URL)
+	d := newTestDispatcher(t, "", func(tenantID string) ([]Target, error) {
+		if removed.Load() {
+			return nil, nil
+		}
+		return targets(tenantID)
+	})
+
+	// Deliveries in flight when the dispatcher closes are dead-lettered
+	if _, err := d.Publish("acme", EventQuotaExceeded, nil); err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Failed to close dispatcher: %v", err)
+	}
+	dead := d.DeadLetters("acme", 0, 0)
+	if len(dead) != 1 || dead[0].Error != ErrInterrupted.Error() {
+		t.Fatalf("Expected an interrupted dead letter, got %+v", dead)
+	}
+	if _, err := d.Publish("acme", EventQuotaExceeded, nil); !errors.Is(err, ErrDispatcherClosed) {
+		t.Errorf("Expected ErrDispatcherClosed, got %v", err)
+	}
+
+	// Deliveries to endpoints that were removed are not retried
+	d = newTestDispatcher(t, "", func(tenantID string) ([]Target, error) {
+		if removed.Swap(true) {
+			return nil, nil
+		}
+		return targets(tenantID)
+	})
+	if _, err := d.Publish("acme", EventQuotaExceeded, nil); err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+	if delivery := waitState(t, d, DeliveryDeadLettered); delivery.Error != ErrTargetRemoved.Error() {
+		t.Errorf("Expected a removed target, got %+v", delivery)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	header := Sign(testSecret, time.Now().Add(-time.Hour), body)
+
+	if err := Verify(testSecret, header, body, 0); err != nil {
+		t.Errorf("Expected a valid signature without tolerance, got %v", err)
+	}
+	if err := Verify(testSecret, header, body, time.Minute); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Expected ErrSignatureExpired, got %v", err)
+	}
+	if err := Verify(testSecret, header, []byte(`{"id":"evt_2"}`), 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for a tampered body, got %v", err)
+	}
+	if err := Verify(testSecret, "v1=00", body, 0); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature without timestamp, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package vjvector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding/providers"
+	"github.com/vijaynallagatla/vjvector/pkg/index"
+	"github.com/vijaynallagatla/vjvector/pkg/rag"
+)
+
+// collectionEngine is the RAG engine of a collection, for the dimension the
+// collection had when the engine was created
+type collectionEngine struct {
+	engine    rag.Engine
+	dimension int
+}
+
+// Query answers a RAG query over a collection: the query text is embedded,
+// expanded and matched against the collection, and the results are reranked
+// as configured by Options.RAG. Results scoring below query.MinScore are
+// left out. The query stops between its stages once ctx is done, and reports
+// them to the query trace of ctx set with rag.WithQueryTrace.
+func (db *DB) Query(ctx context.Context, collection string, query *rag.Query) (*rag.QueryResponse, error) {
+	if err := db.acquire(); err != nil {
+		return nil, err
+	}
+	defer db.release()
+
+	if query == nil || query.Text == "" {
+		return nil, fmt.Errorf("%w: query text is required", ErrInvalidQuery)
+	}
+	spec, err := db.catalog.Spec(collection)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := db.engine(collection, spec.Collection.Dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := engine.ProcessQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if query.MinScore <= 0 {
+		return response, nil
+	}
+
+	// The engine may cache the response, so it is filtered into a copy
+	filtered := *response
+	filtered.Results = make([]*rag.QueryResult, 0, len(response.Results))
+	for _, result := range response.Results {
+		if result.Score >= query.MinScore {
+			filtered.Results = append(filtered.Results, result)
+		}
+	}
+	filtered.TotalResults = len(filtered.Results)
+	return &filtered, nil
+}
+
+// engine returns the RAG engine of a collection, creating it on first use
+func (db *DB) engine(collection string, dimension int) (rag.Engine, error) {
+	db.enginesMutex.Lock()
+	defer db.enginesMutex.Unlock()
+
+	if existing, exists := db.engines[collection]; exists {
+		if existing.dimension == dimension {
+			return existing.engine, nil
+		}
+		// The collection was dropped and created again with another dimension
+		_ = existing.engine.Close()
+		delete(db.engines, collection)
+	}
+
+	service, err := db.embedder(dimension)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := rag.NewEngine(db.ragConfig, service, &collectionIndex{catalog: db.catalog, name: collection})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RAG engine: %w", err)
+	}
+	db.engines[collection] = collectionEngine{engine: engine, dimension: dimension}
+	return engine, nil
+}
+
+// dropEngine closes the RAG engine of a dropped collection
+func (db *DB) dropEngine(collection string) {
+	db.enginesMutex.Lock()
+	defer db.enginesMutex.Unlock()
+
+	if existing, exists := db.engines[collection]; exists {
+		_ = existing.engine.Close()
+		delete(db.engines, collection)
+	}
+}
+
+// embed returns the embeddings of texts for a collection of the given dimension
+func (db *DB) embed(ctx context.Context, dimension int, texts []string) ([][]float64, error) {
+	db.enginesMutex.Lock()
+	service, err := db.embedder(dimension)
+	db.enginesMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := service.GenerateEmbeddings(ctx, &embedding.EmbeddingRequest{Texts: texts})
+	if err != nil {
+		return nil, fmt.Errorf("embedding generation failed: %w", err)
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding generation returned %d embeddings for %d texts",
+			len(response.Embeddings), len(texts))
+	}
+	for _, vector := range response.Embeddings {
+		if len(vector) != dimension {
+			return nil, fmt.Errorf("%w: embedding has dimension %d, collection expects %d",
+				catalog.ErrDimensionMismatch, len(vector), dimension)
+		}
+	}
+	return response.Embeddings, nil
+}
+
+// embedder returns the embedding service for collections of a dimension: the
+// configured service, or a local hash embedding service created on first use.
+// The caller must hold enginesMutex.
+func (db *DB) embedder(dimension int) (embedding.Service, error) {
+	if db.embedding != nil {
+		return db.embedding, nil
+	}
+	if service, exists := db.embedders[dimension]; exists {
+		return service, nil
+	}
+
+	service, err := providers.NewHashService(dimension)
+	if err != nil {
+		return nil, err
+	}
+	db.embedders[dimension] = service
+	return service, nil
+}
+
+// collectionIndex lets the RAG engine search a collection through the catalog,
+// so that queries see every write and expired vectors are left out
+type collectionIndex struct {
+	catalog *catalog.Catalog
+	name    string
+}
+
+var _ index.VectorIndex = (*collectionIndex)(nil)
+
+// Insert is not supported; vectors are written through the DB
+func (c *collectionIndex) Insert(_ *core.Vector) error {
+	return ErrReadOnlyIndex
+}
+
+// Search finds the k most similar vectors of the collection
+func (c *collectionIndex) Search(query []float64, k int) ([]core.VectorSearchResult, error) {
+	return c.catalog.Search(context.Background(), c.name, query, k)
+}
+
+// SearchWithContext finds the k most similar vectors of the collection
+func (c *collectionIndex) SearchWithContext(ctx context.Context, query []float64, k int) ([]core.VectorSearchResult, error) {
+	return c.catalog.Search(ctx, c.name, query, k)
+}
+
+// Delete is not supported; vectors are deleted through the DB
+func (c *collectionIndex) Delete(_ string) error {
+	return ErrReadOnlyIndex
+}
+
+// Optimize does nothing; the catalog owns the index of the collection
+func (c *collectionIndex) Optimize() error {
+	return nil
+}
+
+// GetStats reports the size of the collection
+func (c *collectionIndex) GetStats() index.IndexStats {
+	stats := index.IndexStats{}
+	if collection, err := c.catalog.Get(c.name); err == nil {
+		stats.TotalVectors = collection.Count
+	}
+	return stats
+}
+
+// Close does nothing; the catalog owns the index of the collection
+func (c *collectionIndex) Close() error {
+	return nil
+}
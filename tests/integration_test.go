@@ -92,6 +92,7 @@ func TestStorageIntegration(t *testing.T) {
 		storage.StorageTypeMemory,
 		storage.StorageTypeMMap,
 		storage.StorageTypeLevelDB,
+		storage.StorageTypeTiered,
 	}
 
 	for _, storageType := range storageTypes {
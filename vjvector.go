@@ -0,0 +1,213 @@
+// Package vjvector embeds a VJVector database in a Go program, without running
+// a server. Open returns a DB handle for a data directory; the handle owns the
+// collection catalog, the storage and index of every collection, the embedding
+// of texts and the RAG engine:
+//
+//	db, err := vjvector.Open("/var/lib/myservice/vectors", nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer db.Close()
+//
+//	_, err = db.CreateCollection("docs", 384, nil)
+//	err = db.Upsert(ctx, "docs", &core.Vector{ID: "a", Text: "vector databases store embeddings"})
+//	response, err := db.Query(ctx, "docs", &rag.Query{Text: "what stores embeddings?", MaxResults: 5})
+//
+// Like SQLite, a database is a directory that one process opens at a time.
+// Every write is in storage before it returns, though it is only synced to
+// disk with Options.Storage.SyncOnWrite set, so an operating system crash can
+// lose the latest writes without it. Open rebuilds the indexes from storage,
+// and Close waits for the operations in progress before flushing and
+// releasing the directory. A DB is safe for concurrent use.
+package vjvector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/embedding"
+	"github.com/vijaynallagatla/vjvector/pkg/rag"
+	"github.com/vijaynallagatla/vjvector/pkg/storage"
+)
+
+// Options configures an embedded database
+type Options struct {
+	// Storage, when set, replaces the LevelDB storage configuration of
+	// catalog.DefaultConfig, such as to select LSM storage or an object store.
+	// Its DataPath is ignored. A directory must be reopened with the storage
+	// type it was created with.
+	Storage *storage.StorageConfig
+
+	// Embedding embeds the texts of vectors written without an embedding, and
+	// the text of RAG queries; its embeddings must match the dimension of the
+	// collections. When nil, texts are embedded by a deterministic local
+	// bag-of-words model of each collection's dimension.
+	// The caller keeps ownership of the service; Close does not close it.
+	Embedding embedding.Service
+
+	// RAG configures the RAG engine of every collection. When nil, query
+	// expansion, reranking and context-aware retrieval are enabled and results
+	// are not cached, so that queries see every write.
+	RAG *rag.Config
+
+	// RebuildWorkers is the number of collection indexes Open rebuilds at once;
+	// GOMAXPROCS when zero
+	RebuildWorkers int
+}
+
+// DB is an embedded VJVector database
+type DB struct {
+	dir       string
+	catalog   *catalog.Catalog
+	lock      *os.File
+	embedding embedding.Service
+	ragConfig *rag.Config
+
+	// mutex is held shared by every operation and exclusively by Close
+	mutex  sync.RWMutex
+	closed bool
+
+	// engines holds the RAG engine of each queried collection, and embedders
+	// the default embedding service of each collection dimension
+	enginesMutex sync.Mutex
+	engines      map[string]collectionEngine
+	embedders    map[int]embedding.Service
+}
+
+// Open opens the database in dir, creating it if needed. The directory is
+// locked until Close; opening it again, from this process or another, fails
+// with ErrLocked. The index of every collection is rebuilt from storage
+// before Open returns, so that it holds every write that reached storage
+// before a crash.
+func Open(dir string, opts *Options) (*DB, error) {
+	if dir == "" {
+		return nil, ErrInvalidDirectory
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	lock, err := lockDirectory(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	config := catalog.DefaultConfig(dir)
+	if opts.Storage != nil {
+		config.Storage = *opts.Storage
+	}
+	collections, err := catalog.New(config)
+	if err != nil {
+		unlockDirectory(lock)
+		return nil, err
+	}
+
+	err = collections.RebuildIndexes(context.Background(), catalog.RebuildOptions{Workers: opts.RebuildWorkers})
+	if err != nil {
+		if closeErr := collections.Close(); closeErr != nil {
+			err = fmt.Errorf("%w, close error: %v", err, closeErr)
+		}
+		unlockDirectory(lock)
+		return nil, fmt.Errorf("failed to rebuild indexes: %w", err)
+	}
+
+	ragConfig := opts.RAG
+	if ragConfig == nil {
+		ragConfig = defaultRAGConfig()
+	}
+
+	return &DB{
+		dir:       dir,
+		catalog:   collections,
+		lock:      lock,
+		embedding: opts.Embedding,
+		ragConfig: ragConfig,
+		engines:   make(map[string]collectionEngine),
+		embedders: make(map[int]embedding.Service),
+	}, nil
+}
+
+// defaultRAGConfig returns the RAG configuration used when Options.RAG is nil
+func defaultRAGConfig() *rag.Config {
+	return &rag.Config{
+		EnableQueryExpansion: true,
+		EnableReranking:      true,
+		EnableContextAware:   true,
+		MaxQueryLength:       1000,
+		MaxExpansionTerms:    5,
+		MaxConcurrentQueries: 10,
+		QueryTimeout:         30 * time.Second,
+		BatchSize:            100,
+	}
+}
+
+// Dir returns the directory of the database
+func (db *DB) Dir() string {
+	return db.dir
+}
+
+// Catalog returns the collection catalog of the database, for the operations
+// the DB does not wrap, such as backups and change streams. It is closed by
+// Close and must not be closed by the caller.
+func (db *DB) Catalog() *catalog.Catalog {
+	return db.catalog
+}
+
+// Close waits for the operations in progress, persists the catalog, closes
+// every collection and releases the directory. Operations on a closed
+// database fail with ErrClosed; closing it again does nothing.
+func (db *DB) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+
+	var errs []error
+	db.enginesMutex.Lock()
+	for name, engine := range db.engines {
+		if err := engine.engine.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close RAG engine of %s: %w", name, err))
+		}
+	}
+	for dimension, service := range db.embedders {
+		if err := service.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %d-dimensional embedding service: %w", dimension, err))
+		}
+	}
+	db.engines = nil
+	db.embedders = nil
+	db.enginesMutex.Unlock()
+
+	if err := db.catalog.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	unlockDirectory(db.lock)
+	return errors.Join(errs...)
+}
+
+// acquire holds the database open for an operation; the caller must call
+// release once the operation is done
+func (db *DB) acquire() error {
+	db.mutex.RLock()
+	if db.closed {
+		db.mutex.RUnlock()
+		return ErrClosed
+	}
+	return nil
+}
+
+// release ends an operation started by acquire
+func (db *DB) release() {
+	db.mutex.RUnlock()
+}
@@ -0,0 +1,178 @@
+package vjvector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vijaynallagatla/vjvector/pkg/catalog"
+	"github.com/vijaynallagatla/vjvector/pkg/core"
+	"github.com/vijaynallagatla/vjvector/pkg/rag"
+)
+
+func openTestDB(t *testing.T, dir string) *DB {
+	t.Helper()
+
+	db, err := Open(dir, nil)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db
+}
+
+func TestDB_InsertUpsertDeleteSearch(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, t.TempDir())
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close database: %v", err)
+		}
+	}()
+
+	if _, err := db.CreateCollection("points", 3, nil); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	err := db.Insert(ctx, "points",
+		&core.Vector{ID: "x", Embedding: []float64{1, 0, 0}},
+		&core.Vector{ID: "y", Embedding: []float64{0, 1, 0}},
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert vectors: %v", err)
+	}
+
+	// Inserting an existing ID fails, and inserts nothing of the batch
+	err = db.Insert(ctx, "points",
+		&core.Vector{ID: "z", Embedding: []float64{0, 0, 1}},
+		&core.Vector{ID: "x", Embedding: []float64{0, 0, 1}},
+	)
+	if !errors.Is(err, catalog.ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict, got %v", err)
+	}
+	if vectors, err := db.Get(ctx, "points", "z"); err != nil || len(vectors) != 0 {
+		t.Fatalf("Expected no vector z, got %v (%v)", vectors, err)
+	}
+
+	// Upserting replaces the vector
+	if err := db.Upsert(ctx, "points", &core.Vector{ID: "x", Embedding: []float64{0, 0, 1}}); err != nil {
+		t.Fatalf("Failed to upsert vector: %v", err)
+	}
+	results, err := db.Search(ctx, "points", []float64{0, 0, 1}, 1)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Vector.ID != "x" {
+		t.Fatalf("Expected x nearest to the upserted embedding, got %v", results)
+	}
+
+	if err := db.Delete(ctx, "points", "x"); err != nil {
+		t.Fatalf("Failed to delete vector: %v", err)
+	}
+	vectors, err := db.Get(ctx, "points", "x", "y")
+	if err != nil {
+		t.Fatalf("Failed to get vectors: %v", err)
+	}
+	if len(vectors) != 1 || vectors[0].ID != "y" {
+		t.Errorf("Expected only y after the delete, got %d vectors", len(vectors))
+	}
+}
+
+func TestDB_TextAndQuery(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, t.TempDir())
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close database: %v", err)
+		}
+	}()
+
+	if _, err := db.CreateCollection("docs", 64, nil); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+
+	err := db.Upsert(ctx, "docs",
+		&core.Vector{ID: "leveldb", Text: "leveldb stores sorted keys on disk"},
+		&core.Vector{ID: "hnsw", Text: "hnsw graphs find approximate nearest neighbors"},
+		&core.Vector{Text: "raft replicates a log across nodes"},
+	)
+	if err != nil {
+		t.Fatalf("Failed to upsert texts: %v", err)
+	}
+
+	collection, err := db.Collection("docs")
+	if err != nil {
+		t.Fatalf("Failed to get collection: %v", err)
+	}
+	if collection.Count != 3 {
+		t.Errorf("Expected 3 vectors, got %d", collection.Count)
+	}
+
+	results, err := db.SearchText(ctx, "docs", "nearest neighbors graphs", 1)
+	if err != nil {
+		t.Fatalf("Failed to search text: %v", err)
+	}
+	if len(results) != 1 || results[0].Vector.ID != "hnsw" {
+		t.Fatalf("Expected hnsw to match, got %v", results)
+	}
+
+	response, err := db.Query(ctx, "docs", &rag.Query{Text: "sorted keys on disk", MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	found := false
+	for _, result := range response.Results {
+		if result.Vector != nil && result.Vector.ID == "leveldb" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected leveldb among the query results, got %d results", len(response.Results))
+	}
+
+	if _, err := db.Query(ctx, "docs", &rag.Query{}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery, got %v", err)
+	}
+}
+
+func TestDB_ReopenAndLock(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	db := openTestDB(t, dir)
+	if _, err := db.CreateCollection("points", 3, nil); err != nil {
+		t.Fatalf("Failed to create collection: %v", err)
+	}
+	if err := db.Insert(ctx, "points", &core.Vector{ID: "x", Embedding: []float64{1, 0, 0}}); err != nil {
+		t.Fatalf("Failed to insert vector: %v", err)
+	}
+
+	if _, err := Open(dir, nil); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked while the database is open, got %v", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Expected a second close to succeed, got %v", err)
+	}
+	if _, err := db.Search(ctx, "points", []float64{1, 0, 0}, 1); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after close, got %v", err)
+	}
+
+	// The vectors are indexed again when the database is reopened
+	db = openTestDB(t, dir)
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("Failed to close database: %v", err)
+		}
+	}()
+
+	results, err := db.Search(ctx, "points", []float64{1, 0, 0}, 1)
+	if err != nil {
+		t.Fatalf("Failed to search reopened database: %v", err)
+	}
+	if len(results) != 1 || results[0].Vector.ID != "x" {
+		t.Fatalf("Expected x after reopening, got %v", results)
+	}
+}